@@ -36,6 +36,7 @@ func blockchainCmd() *cli.Command {
 		byID       bool
 		inputPath  string
 		aidMode    bool
+		force      bool
 	)
 	return &cli.Command{
 		Name:        "blockchain",
@@ -225,6 +226,54 @@ func blockchainCmd() *cli.Command {
 					return upgradeBlockChain(cfg, db, interrupt, inputPath, endPoint, byID, aidMode)
 				},
 			},
+			&cli.Command{
+				Name:        "dumputxo",
+				Usage:       "Write the UTXO set at the best block as a snapshot for use with 'blockchain loadutxo'",
+				Description: "Dump the UTXO set snapshot which is tied to the order and hash of the best block",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "path",
+						Aliases:     []string{"p"},
+						Usage:       "Path to output snapshot",
+						Destination: &outputPath,
+					},
+				},
+				Action: func(ctx *cli.Context) error {
+					if len(outputPath) <= 0 {
+						outputPath = config.Cfg.HomeDir
+					}
+					return runBlockChain(func(cons model.Consensus) error {
+						return dumpUtxoSnapshot(cons, outputPath)
+					})
+				},
+			},
+			&cli.Command{
+				Name:        "loadutxo",
+				Usage:       "Replace the UTXO set by the snapshot of 'blockchain dumputxo'",
+				Description: "Load the UTXO set snapshot, it must be pinned by the network params. The best block must not be past the block of snapshot, the blocks up to it are connected without the UTXO set",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "path",
+						Aliases:     []string{"p"},
+						Usage:       "Path to input snapshot",
+						Destination: &inputPath,
+					},
+					&cli.BoolFlag{
+						Name:        "force",
+						Aliases:     []string{"f"},
+						Usage:       "Load the snapshot that isn't pinned by the network params",
+						Destination: &force,
+					},
+				},
+				Action: func(ctx *cli.Context) error {
+					if len(inputPath) <= 0 {
+						inputPath = config.Cfg.HomeDir
+					}
+					return runBlockChain(func(cons model.Consensus) error {
+						return loadUtxoSnapshot(cons, inputPath, force)
+					})
+				},
+			},
 		},
 	}
 }
@@ -310,6 +359,15 @@ func exportBlockChain(consensus model.Consensus, outputPath string, end string,
 		bhs = append(bhs, blockHash)
 	}
 	bar.Finish()
+	prunedOrder := bc.PrunedOrder()
+	if prunedOrder > 0 {
+		for _, blockHash := range bhs {
+			ib := bc.BlockDAG().GetBlock(blockHash)
+			if ib != nil && ib.IsOrdered() && bc.IsPruned(uint64(ib.GetOrder())) {
+				return fmt.Errorf("Can't export block %s (order %d): the blocks up to order %d have been pruned, only an archival node can export them", blockHash, ib.GetOrder(), prunedOrder)
+			}
+		}
+	}
 	bar.ChangeMax(len(bhs))
 	bar.Set(0)
 	var maxNum [4]byte
@@ -690,6 +748,94 @@ func upgradeBlockChain(cfg *config.Config, cdb model.DataBase, interrupt <-chan
 	return nil
 }
 
+// runBlockChain starts the block chain of database and calls fn
+func runBlockChain(fn func(cons model.Consensus) error) error {
+	cfg := config.Cfg
+	defer func() {
+		if log.LogWrite() != nil {
+			log.LogWrite().Close()
+		}
+	}()
+	interrupt := system.InterruptListener()
+	log.Info("System info", "QNG Version", version.String(), "Go version", runtime.Version())
+	log.Info("System info", "Home dir", cfg.HomeDir)
+	if cfg.NoFileLogging {
+		log.Info("File logging disabled")
+	}
+	db, err := database.New(cfg, interrupt)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	//
+	cfg.InvalidTxIndex = false
+	cfg.AddrIndex = false
+	cons := consensus.New(cfg, db, interrupt, make(chan struct{}))
+	err = cons.Init()
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	err = cons.BlockChain().Start()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = cons.BlockChain().Stop()
+		if err != nil {
+			log.Error(err.Error())
+		}
+	}()
+	return fn(cons)
+}
+
+func dumpUtxoSnapshot(consensus model.Consensus, outputPath string) error {
+	bc := consensus.BlockChain().(*blockchain.BlockChain)
+	outFilePath, err := GetUtxoSnapshotFilePath(outputPath)
+	if err != nil {
+		return err
+	}
+	outFile, err := os.OpenFile(outFilePath, os.O_CREATE|os.O_TRUNC|os.O_RDWR, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer outFile.Close()
+
+	info, err := bc.DumpUtxoSnapshot(outFile)
+	if err != nil {
+		return err
+	}
+	log.Info(fmt.Sprintf("Finish dump UTXO set snapshot:%s", outFilePath), "order", info.Order, "hash", info.Hash,
+		"txouts", info.TxOuts, "muhash", info.MuHash)
+	return nil
+}
+
+func loadUtxoSnapshot(consensus model.Consensus, inputPath string, force bool) error {
+	bc := consensus.BlockChain().(*blockchain.BlockChain)
+	inFilePath, err := GetUtxoSnapshotFilePath(inputPath)
+	if err != nil {
+		return err
+	}
+	info, err := bc.LoadUtxoSnapshot(inFilePath, force)
+	if err != nil {
+		return err
+	}
+	log.Info(fmt.Sprintf("Finish load UTXO set snapshot:%s", inFilePath), "order", info.Order, "hash", info.Hash,
+		"txouts", info.TxOuts, "muhash", info.MuHash)
+	return nil
+}
+
+func GetUtxoSnapshotFilePath(path string) (string, error) {
+	if len(path) <= 0 {
+		return "", fmt.Errorf("Path error")
+	}
+	if strings.HasSuffix(path, ".utxo") {
+		return path, nil
+	}
+	const defaultFileName = "snapshot.utxo"
+	return strings.TrimRight(strings.TrimRight(path, "/"), "\\") + "/" + defaultFileName, nil
+}
+
 func GetIBDFilePath(path string) (string, error) {
 	if len(path) <= 0 {
 		return "", fmt.Errorf("Path error")
@@ -76,12 +76,20 @@ entropy (seed) & mnemoic & hd & ec :
 addr & tx & sign :
     ec-to-addr            convert an EC public key to a payment address. default is qx address
     ec-to-pkaddr          convert an EC public key to a payment public key address. default is qx address
+    ec-to-taprootaddr     convert an EC public key to a taproot address (BIP86 key path only).
     ec-to-ethaddr         convert an EC public key to a ethereum address.
     pkaddr-to-public      convert an pkaddress to EC public key (the uncompressed format by default )
     pkaddr-to-ethaddr     convert an pkaddress to ethereum address
     tx-encode             encode a unsigned transaction.
     tx-decode             decode a transaction in base16 to json format.
     tx-sign               sign a transactions using a private key.
+    psbt-create           create a partially signed transaction from an unsigned transaction.
+    psbt-update           add the previous outputs and redeem scripts to a partially signed transaction.
+    psbt-sign             sign a partially signed transaction using private keys.
+    psbt-combine          combine the signatures of partially signed transactions.
+    psbt-finalize         finalize the signature scripts of a partially signed transaction.
+    psbt-extract          extract the signed transaction from a finalized partially signed transaction.
+    psbt-decode           decode a partially signed transaction to json format.
     msg-sign              create a message signature
     msg-verify            validate a message signature
     signature-decode      decode a ECDSA signature
@@ -134,6 +142,8 @@ var txOutputs qx.TxOutputsFlag
 var txVersion qx.TxVersionFlag
 var txLockTime qx.TxLockTimeFlag
 var privateKeys qx.TxPrivateKey
+var psbtPrevOuts qx.PsbtPrevOutsFlag
+var psbtRedeemScripts qx.PsbtRedeemScriptsFlag
 var msgSignatureMode string
 
 func main() {
@@ -396,6 +406,13 @@ func main() {
 	}
 	ecToPKAddrCmd.Var(&base58checkVersion, "v", "base58check `version` [mainnet|testnet|privnet]")
 
+	// TaprootAddress
+	ecToTaprootAddrCmd := flag.NewFlagSet("ec-to-taprootaddr", flag.ExitOnError)
+	ecToTaprootAddrCmd.Usage = func() {
+		cmdUsage(ecToTaprootAddrCmd, "Usage: qx ec-to-taprootaddr [ec_public_key] \n")
+	}
+	ecToTaprootAddrCmd.Var(&base58checkVersion, "v", "address `version` [mainnet|testnet|privnet|mixnet]")
+
 	// ETHAddress
 	ecToETHAddrCmd := flag.NewFlagSet("ec-to-ethaddr", flag.ExitOnError)
 	ecToETHAddrCmd.Usage = func() {
@@ -436,6 +453,7 @@ SCRIPTTYPE is unlock script type
 - pubkeyhash PayToAddrScript(pkh)
 - pubkey PayToAddrScript(pk)
 - cltvpubkeyhash PayToCLTVPubKeyHashScript(pkh, args)
+- witness_v1_taproot PayToAddrScript(taproot), key path spend
 - crossimport the special script, the index need 4294967294 and sequence need 258
 example: 
 -i 5fdad6bb6781416b0361a10eb6183dec45fb31edcf2da10d22893ee7bb6502ca:0:4294967295:pubkeyhash
@@ -449,6 +467,8 @@ SCRIPTTYPE is lock script type
 - pubkeyhash PayToAddrScript(pkh)
 - pubkey PayToAddrScript(pk)
 - cltvpubkeyhash PayToCLTVPubKeyHashScript(pkh, LOCKTIME)
+- witness_v1_taproot PayToAddrScript(taproot)
+- scripthash PayToAddrScript(p2sh)
 example: 
 -o TnTTMZANDBhjeoxbPMAVKb5sM7KuvNpRo2b:9.9999:0:pubkeyhash
 -o TnTTMZANDBhjeoxbPMAVKb5sM7KuvNpRo2b:9.9999:0:cltvpubkeyhash:1667298670
@@ -461,6 +481,54 @@ example:
 	txSignCmd.Var(&privateKeys, "k", "the ec private key to sign the raw transaction")
 	txSignCmd.StringVar(&network, "n", "mainnet", "decode rawtx for the target network. (mainnet, testnet, privnet)")
 
+	// Partially signed transaction
+	psbtCreateCmd := flag.NewFlagSet("psbt-create", flag.ExitOnError)
+	psbtCreateCmd.Usage = func() {
+		cmdUsage(psbtCreateCmd, "Usage: qx psbt-create [raw_tx_base16_string] \n")
+	}
+
+	psbtUpdateCmd := flag.NewFlagSet("psbt-update", flag.ExitOnError)
+	psbtUpdateCmd.Usage = func() {
+		cmdUsage(psbtUpdateCmd, "Usage: qx psbt-update [-p prev-output] [-r redeem-script] [psbt_base64_string] \n")
+	}
+	psbtUpdateCmd.Var(&psbtPrevOuts, "p", `The previous output of input encoded as INDEX:TARGET:AMOUNT:COINID:[LOCKTIME].
+INDEX is the input index in the transaction.
+TARGET is the address or the base16 pkscript of the previous output.
+AMOUNT is the amount of the previous output in qitmeer.
+LOCKTIME is the lock time of cltvpubkeyhash previous output.
+example: 
+-p 0:TmbsdsjwzuGboFQ9GcKg6EUmrr3tokzozyF:10:0
+-p 1:TnTTMZANDBhjeoxbPMAVKb5sM7KuvNpRo2b:9.9999:0:1667298670
+`)
+	psbtUpdateCmd.Var(&psbtRedeemScripts, "r", "the base16 redeem script of the pay-to-script-hash inputs")
+
+	psbtSignCmd := flag.NewFlagSet("psbt-sign", flag.ExitOnError)
+	psbtSignCmd.Usage = func() {
+		cmdUsage(psbtSignCmd, "Usage: qx psbt-sign [-k private-key] [psbt_base64_string] \n")
+	}
+	psbtSignCmd.Var(&privateKeys, "k", "the ec private key to sign the partially signed transaction")
+
+	psbtCombineCmd := flag.NewFlagSet("psbt-combine", flag.ExitOnError)
+	psbtCombineCmd.Usage = func() {
+		cmdUsage(psbtCombineCmd, "Usage: qx psbt-combine [psbt_base64_string] [psbt_base64_string]... \n")
+	}
+
+	psbtFinalizeCmd := flag.NewFlagSet("psbt-finalize", flag.ExitOnError)
+	psbtFinalizeCmd.Usage = func() {
+		cmdUsage(psbtFinalizeCmd, "Usage: qx psbt-finalize [psbt_base64_string] \n")
+	}
+
+	psbtExtractCmd := flag.NewFlagSet("psbt-extract", flag.ExitOnError)
+	psbtExtractCmd.Usage = func() {
+		cmdUsage(psbtExtractCmd, "Usage: qx psbt-extract [psbt_base64_string] \n")
+	}
+
+	psbtDecodeCmd := flag.NewFlagSet("psbt-decode", flag.ExitOnError)
+	psbtDecodeCmd.Usage = func() {
+		cmdUsage(psbtDecodeCmd, "Usage: qx psbt-decode [psbt_base64_string] \n")
+	}
+	psbtDecodeCmd.StringVar(&network, "n", "mainnet", "decode psbt for the target network. (mainnet, testnet, privnet)")
+
 	msgSignCmd := flag.NewFlagSet("msg-sign", flag.ExitOnError)
 	msgSignCmd.Usage = func() {
 		cmdUsage(msgSignCmd, "Usage: msg-sign [wif] [message] \n")
@@ -525,12 +593,20 @@ example:
 		wifToPubCmd,
 		ecToAddrCmd,
 		ecToPKAddrCmd,
+		ecToTaprootAddrCmd,
 		ecToETHAddrCmd,
 		pkaddrToPubCmd,
 		pkaddrToETHAddrCmd,
 		txEncodeCmd,
 		txDecodeCmd,
 		txSignCmd,
+		psbtCreateCmd,
+		psbtUpdateCmd,
+		psbtSignCmd,
+		psbtCombineCmd,
+		psbtFinalizeCmd,
+		psbtExtractCmd,
+		psbtDecodeCmd,
 		msgSignCmd,
 		msgVerifyCmd,
 		scriptDecodeCmd,
@@ -1330,6 +1406,24 @@ example:
 		}
 	}
 
+	if ecToTaprootAddrCmd.Parsed() {
+		stat, _ := os.Stdin.Stat()
+		if (stat.Mode() & os.ModeNamedPipe) == 0 {
+			if len(os.Args) == 2 || os.Args[2] == "help" || os.Args[2] == "--help" {
+				ecToTaprootAddrCmd.Usage()
+			} else {
+				qx.EcPubKeyToTaprootAddressSTDO(base58checkVersion.String(), os.Args[len(os.Args)-1])
+			}
+		} else { //try from STDIN
+			src, err := ioutil.ReadAll(os.Stdin)
+			if err != nil {
+				errExit(err)
+			}
+			str := strings.TrimSpace(string(src))
+			qx.EcPubKeyToTaprootAddressSTDO(base58checkVersion.String(), str)
+		}
+	}
+
 	if ecToETHAddrCmd.Parsed() {
 		stat, _ := os.Stdin.Stat()
 		if (stat.Mode() & os.ModeNamedPipe) == 0 {
@@ -1440,6 +1534,122 @@ example:
 		}
 	}
 
+	if psbtCreateCmd.Parsed() {
+		stat, _ := os.Stdin.Stat()
+		if (stat.Mode() & os.ModeNamedPipe) == 0 {
+			if len(os.Args) == 2 || os.Args[2] == "help" || os.Args[2] == "--help" {
+				psbtCreateCmd.Usage()
+			} else {
+				qx.PsbtCreateSTDO(os.Args[len(os.Args)-1])
+			}
+		} else { //try from STDIN
+			src, err := ioutil.ReadAll(os.Stdin)
+			if err != nil {
+				errExit(err)
+			}
+			str := strings.TrimSpace(string(src))
+			qx.PsbtCreateSTDO(str)
+		}
+	}
+
+	if psbtUpdateCmd.Parsed() {
+		stat, _ := os.Stdin.Stat()
+		if (stat.Mode() & os.ModeNamedPipe) == 0 {
+			if len(os.Args) == 2 || os.Args[2] == "help" || os.Args[2] == "--help" {
+				psbtUpdateCmd.Usage()
+			} else {
+				qx.PsbtUpdateSTDO(os.Args[len(os.Args)-1], psbtPrevOuts, psbtRedeemScripts)
+			}
+		} else { //try from STDIN
+			src, err := ioutil.ReadAll(os.Stdin)
+			if err != nil {
+				errExit(err)
+			}
+			str := strings.TrimSpace(string(src))
+			qx.PsbtUpdateSTDO(str, psbtPrevOuts, psbtRedeemScripts)
+		}
+	}
+
+	if psbtSignCmd.Parsed() {
+		stat, _ := os.Stdin.Stat()
+		if (stat.Mode() & os.ModeNamedPipe) == 0 {
+			if len(os.Args) == 2 || os.Args[2] == "help" || os.Args[2] == "--help" {
+				psbtSignCmd.Usage()
+			} else {
+				qx.PsbtSignSTDO(privateKeys, os.Args[len(os.Args)-1])
+			}
+		} else { //try from STDIN
+			src, err := ioutil.ReadAll(os.Stdin)
+			if err != nil {
+				errExit(err)
+			}
+			str := strings.TrimSpace(string(src))
+			qx.PsbtSignSTDO(privateKeys, str)
+		}
+	}
+
+	if psbtCombineCmd.Parsed() {
+		if len(os.Args) == 2 || os.Args[2] == "help" || os.Args[2] == "--help" {
+			psbtCombineCmd.Usage()
+		} else {
+			qx.PsbtCombineSTDO(psbtCombineCmd.Args())
+		}
+	}
+
+	if psbtFinalizeCmd.Parsed() {
+		stat, _ := os.Stdin.Stat()
+		if (stat.Mode() & os.ModeNamedPipe) == 0 {
+			if len(os.Args) == 2 || os.Args[2] == "help" || os.Args[2] == "--help" {
+				psbtFinalizeCmd.Usage()
+			} else {
+				qx.PsbtFinalizeSTDO(os.Args[len(os.Args)-1])
+			}
+		} else { //try from STDIN
+			src, err := ioutil.ReadAll(os.Stdin)
+			if err != nil {
+				errExit(err)
+			}
+			str := strings.TrimSpace(string(src))
+			qx.PsbtFinalizeSTDO(str)
+		}
+	}
+
+	if psbtExtractCmd.Parsed() {
+		stat, _ := os.Stdin.Stat()
+		if (stat.Mode() & os.ModeNamedPipe) == 0 {
+			if len(os.Args) == 2 || os.Args[2] == "help" || os.Args[2] == "--help" {
+				psbtExtractCmd.Usage()
+			} else {
+				qx.PsbtExtractSTDO(os.Args[len(os.Args)-1])
+			}
+		} else { //try from STDIN
+			src, err := ioutil.ReadAll(os.Stdin)
+			if err != nil {
+				errExit(err)
+			}
+			str := strings.TrimSpace(string(src))
+			qx.PsbtExtractSTDO(str)
+		}
+	}
+
+	if psbtDecodeCmd.Parsed() {
+		stat, _ := os.Stdin.Stat()
+		if (stat.Mode() & os.ModeNamedPipe) == 0 {
+			if len(os.Args) == 2 || os.Args[2] == "help" || os.Args[2] == "--help" {
+				psbtDecodeCmd.Usage()
+			} else {
+				qx.PsbtDecode(network, os.Args[len(os.Args)-1])
+			}
+		} else { //try from STDIN
+			src, err := ioutil.ReadAll(os.Stdin)
+			if err != nil {
+				errExit(err)
+			}
+			str := strings.TrimSpace(string(src))
+			qx.PsbtDecode(network, str)
+		}
+	}
+
 	if msgSignCmd.Parsed() {
 		stat, _ := os.Stdin.Stat()
 		if (stat.Mode() & os.ModeNamedPipe) == 0 {
@@ -296,7 +296,7 @@ func (node *Node) RegisterRpcService() error {
 	}()
 
 	api := node.api()
-	if err := rpcServer.RegisterService(api.NameSpace, api.Service); err != nil {
+	if err := rpcServer.RegisterAPI(api); err != nil {
 		return err
 	}
 	log.Debug(fmt.Sprintf("RPC Service API registered. NameSpace:%s     %s", api.NameSpace, reflect.TypeOf(api.Service)))
@@ -1,6 +1,8 @@
 package bloom
 
 import (
+	"fmt"
+
 	chainhash "github.com/Qitmeer/qng/common/hash"
 	"github.com/Qitmeer/qng/core/merkle"
 	"github.com/Qitmeer/qng/core/types"
@@ -118,3 +120,122 @@ func NewMerkleBlock(block *types.SerializedBlock, filter *Filter) (*types.MsgMer
 	}
 	return &msgMerkleBlock, matchedIndices
 }
+
+// partialMerkleTree is used to walk the partial merkle tree carried by a
+// types.MsgMerkleBlock in order to recompute its merkle root and collect the
+// matched transaction hashes.
+type partialMerkleTree struct {
+	numTx      uint32
+	hashes     []*chainhash.Hash
+	flags      []byte
+	bitsUsed   uint32
+	hashesUsed uint32
+	matched    []*chainhash.Hash
+	bad        bool
+}
+
+// calcTreeWidth calculates and returns the the number of nodes (width) or a
+// merkle tree at the given depth-first height.
+func (p *partialMerkleTree) calcTreeWidth(height uint32) uint32 {
+	return (p.numTx + (1 << height) - 1) >> height
+}
+
+// nextBit returns the next flag bit of the depth-first traversal.
+func (p *partialMerkleTree) nextBit() byte {
+	if p.bitsUsed >= uint32(len(p.flags))*8 {
+		p.bad = true
+		return 0
+	}
+	bit := (p.flags[p.bitsUsed/8] >> (p.bitsUsed % 8)) & 0x01
+	p.bitsUsed++
+	return bit
+}
+
+// nextHash returns the next hash of the depth-first traversal.
+func (p *partialMerkleTree) nextHash() *chainhash.Hash {
+	if p.hashesUsed >= uint32(len(p.hashes)) {
+		p.bad = true
+		return &chainhash.Hash{}
+	}
+	h := p.hashes[p.hashesUsed]
+	p.hashesUsed++
+	return h
+}
+
+// traverseAndExtract is the inverse of merkleBlock.traverseAndBuild.  It
+// consumes the flag bits and hashes in the same depth-first order and
+// returns the hash of the sub-tree at the given height and position.
+func (p *partialMerkleTree) traverseAndExtract(height, pos uint32) *chainhash.Hash {
+	isParent := p.nextBit()
+	if p.bad {
+		return &chainhash.Hash{}
+	}
+	if height == 0 || isParent == 0x00 {
+		h := p.nextHash()
+		if height == 0 && isParent != 0x00 {
+			p.matched = append(p.matched, h)
+		}
+		return h
+	}
+
+	left := p.traverseAndExtract(height-1, pos*2)
+	var right *chainhash.Hash
+	if pos*2+1 < p.calcTreeWidth(height-1) {
+		right = p.traverseAndExtract(height-1, pos*2+1)
+		// A right branch identical to the left one would allow an attacker
+		// to forge a different transaction set with the same merkle root.
+		if right.IsEqual(left) {
+			p.bad = true
+		}
+	} else {
+		right = left
+	}
+	return merkle.HashMerkleBranches(left, right)
+}
+
+// ExtractMatches validates the partial merkle tree of the passed merkle block
+// against the transaction root of its header and returns the hashes of the
+// transactions that matched the filter which produced it.
+func ExtractMatches(msg *types.MsgMerkleBlock) ([]*chainhash.Hash, error) {
+	if msg.Transactions == 0 {
+		return nil, fmt.Errorf("merkle block has no transactions")
+	}
+	if msg.Transactions > types.MaxTxPerBlock {
+		return nil, fmt.Errorf("merkle block has too many transactions: %d",
+			msg.Transactions)
+	}
+	if uint32(len(msg.Hashes)) > msg.Transactions {
+		return nil, fmt.Errorf("merkle block has more hashes(%d) than "+
+			"transactions(%d)", len(msg.Hashes), msg.Transactions)
+	}
+	if len(msg.Flags)*8 < len(msg.Hashes) {
+		return nil, fmt.Errorf("merkle block has fewer flag bits than hashes")
+	}
+	p := partialMerkleTree{
+		numTx:  msg.Transactions,
+		hashes: msg.Hashes,
+		flags:  msg.Flags,
+	}
+	height := uint32(0)
+	for p.calcTreeWidth(height) > 1 {
+		height++
+	}
+	root := p.traverseAndExtract(height, 0)
+	if p.bad {
+		return nil, fmt.Errorf("malformed partial merkle tree")
+	}
+	// All hashes and all but the padding bits of the last flag byte must
+	// have been consumed.
+	if p.hashesUsed != uint32(len(p.hashes)) {
+		return nil, fmt.Errorf("partial merkle tree has %d unused hashes",
+			uint32(len(p.hashes))-p.hashesUsed)
+	}
+	if (p.bitsUsed+7)/8 != uint32(len(p.flags)) {
+		return nil, fmt.Errorf("partial merkle tree has unused flag bytes")
+	}
+	if !root.IsEqual(&msg.Header.TxRoot) {
+		return nil, fmt.Errorf("partial merkle tree root %s does not match "+
+			"header tx root %s", root, msg.Header.TxRoot)
+	}
+	return p.matched, nil
+}
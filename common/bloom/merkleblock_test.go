@@ -2,7 +2,79 @@ package bloom_test
 
 import (
 	"testing"
+
+	"github.com/Qitmeer/qng/common/bloom"
+	chainhash "github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/core/merkle"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/core/types/pow"
 )
 
 func TestMerkleBlock3(t *testing.T) {
 }
+
+// newTestBlock builds a block with numTx distinct transactions and a header
+// committing to them.
+func newTestBlock(numTx int) *types.SerializedBlock {
+	block := &types.Block{}
+	for i := 0; i < numTx; i++ {
+		tx := types.NewTransaction()
+		prev := chainhash.Hash{byte(i), byte(i >> 8)}
+		tx.AddTxIn(types.NewTxInput(types.NewOutPoint(&prev, uint32(i)), []byte{0x51}))
+		tx.AddTxOut(types.NewTxOutput(types.Amount{Value: int64(i + 1)}, []byte{0x51}))
+		block.AddTransaction(tx)
+	}
+	block.Header.TxRoot = *merkle.CalcMerkleRoot(block.Transactions)
+	block.Header.Pow = pow.GetInstance(pow.BLAKE2BD, 0, []byte{})
+	return types.NewBlock(block)
+}
+
+// TestExtractMatches ensures the partial merkle tree produced by
+// NewMerkleBlock can be verified and yields the matched transactions.
+func TestExtractMatches(t *testing.T) {
+	for _, numTx := range []int{1, 2, 3, 7, 16} {
+		block := newTestBlock(numTx)
+		txs := block.Transactions()
+		want := []*chainhash.Hash{txs[0].Hash(), txs[numTx-1].Hash()}
+
+		f := bloom.NewFilter(10, 0, 0.000001, types.BloomUpdateNone)
+		for _, h := range want {
+			f.AddHash(h)
+		}
+		mBlock, indices := bloom.NewMerkleBlock(block, f)
+		got, err := bloom.ExtractMatches(mBlock)
+		if err != nil {
+			t.Fatalf("numTx=%d: ExtractMatches: %v", numTx, err)
+		}
+		if len(got) != len(indices) {
+			t.Fatalf("numTx=%d: got %d matches, want %d", numTx, len(got),
+				len(indices))
+		}
+		for i, idx := range indices {
+			if !got[i].IsEqual(txs[idx].Hash()) {
+				t.Errorf("numTx=%d: match %d is %s, want %s", numTx, i,
+					got[i], txs[idx].Hash())
+			}
+		}
+	}
+}
+
+// TestExtractMatchesBadRoot ensures a partial merkle tree which does not
+// commit to the header transaction root is rejected.
+func TestExtractMatchesBadRoot(t *testing.T) {
+	block := newTestBlock(5)
+	f := bloom.NewFilter(10, 0, 0.000001, types.BloomUpdateNone)
+	f.AddHash(block.Transactions()[2].Hash())
+	mBlock, _ := bloom.NewMerkleBlock(block, f)
+
+	mBlock.Header.TxRoot = chainhash.Hash{0x01}
+	if _, err := bloom.ExtractMatches(mBlock); err == nil {
+		t.Fatal("ExtractMatches: expected error for mismatched root")
+	}
+
+	mBlock.Header.TxRoot = block.Block().Header.TxRoot
+	mBlock.Hashes = append(mBlock.Hashes, &chainhash.Hash{})
+	if _, err := bloom.ExtractMatches(mBlock); err == nil {
+		t.Fatal("ExtractMatches: expected error for unused hashes")
+	}
+}
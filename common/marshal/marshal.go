@@ -84,7 +84,7 @@ func MarshJsonVin(tx *types.Transaction) []json.Vin {
 		vinEntry.Coinbase = hex.EncodeToString(txIn.SignScript)
 		vinEntry.Sequence = txIn.Sequence
 		return vinList
-	} else if types.IsTokenTx(tx) {
+	} else if types.IsTokenTx(tx) || types.IsStakeTx(tx) {
 		for i, txIn := range tx.TxIn {
 			disbuf, _ := txscript.DisasmString(txIn.SignScript)
 
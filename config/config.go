@@ -2,6 +2,7 @@ package config
 
 import (
 	"path/filepath"
+	"time"
 
 	"github.com/Qitmeer/qng/core/types"
 )
@@ -21,6 +22,9 @@ type Config struct {
 	DisableListen      bool     `long:"nolisten" description:"Disable listening for incoming connections"`
 	RPCUser            string   `short:"u" long:"rpcuser" description:"Username for RPC connections"`
 	RPCPass            string   `short:"P" long:"rpcpass" default-mask:"-" description:"Password for RPC connections"`
+	RPCLimitUser       string   `long:"rpclimituser" description:"Username for limited RPC connections, which can only call the public APIs"`
+	RPCLimitPass       string   `long:"rpclimitpass" default-mask:"-" description:"Password for limited RPC connections"`
+	RPCAuth            []string `long:"rpcauth" description:"Add a salted RPC credential <user>:<salt>$<hmac-sha256(salt,password)>[:<role>], the role is admin (default), limited or the allowed namespaces joined by +"`
 	RPCCert            string   `long:"rpccert" description:"File containing the certificate file"`
 	RPCKey             string   `long:"rpckey" description:"File containing the certificate key"`
 	RPCMaxClients      int      `long:"rpcmaxclients" description:"Max number of RPC clients for standard connections"`
@@ -29,6 +33,7 @@ type Config struct {
 	Modules            []string `long:"modules" description:"Modules is a list of API modules(See GetNodeInfo) to expose via the HTTP RPC interface. If the module list is empty, all RPC API endpoints designated public will be exposed."`
 	DisableCheckpoints bool     `long:"nocheckpoints" description:"Disable built-in checkpoints.  Don't do this unless you know what you're doing."`
 	LightNode          bool     `long:"light" description:"start as a qitmeer light node"`
+	LightAddrs         []string `long:"lightaddr" description:"Add an address to be watched by the light node"`
 	SigCacheMaxSize    uint     `long:"sigcachemaxsize" description:"The maximum number of entries in the signature verification cache"`
 	TestNet            bool     `long:"testnet" description:"Use the test network"`
 	MixNet             bool     `long:"mixnet" description:"Use the test mix pow network"`
@@ -42,15 +47,18 @@ type Config struct {
 	DebugPrintOrigins  bool     `long:"printorigin" description:"Print log debug location (file:line) "`
 
 	// MemPool Config
-	NoRelayPriority  bool    `long:"norelaypriority" description:"Do not require free or low-fee transactions to have high priority for relaying"`
-	FreeTxRelayLimit float64 `long:"limitfreerelay" description:"Limit relay of transactions with no transaction fee to the given amount in thousands of bytes per minute"`
-	AcceptNonStd     bool    `long:"acceptnonstd" description:"Accept and relay non-standard transactions to the network regardless of the default settings for the active network."`
-	MaxOrphanTxs     int     `long:"maxorphantx" description:"Max number of orphan transactions to keep in memory"`
-	TxTimeScope      int64   `long:"txtimescope" description:"allow the mempool tx time scope(sec) with server time,default 0 will not check the time scope"`
-	MinTxFee         int64   `long:"mintxfee" description:"The minimum transaction fee in AtomMEER/kB."`
-	MempoolExpiry    int64   `long:"mempoolexpiry" description:"Do not keep transactions in the mempool more than mempoolexpiry"`
-	Persistmempool   bool    `long:"persistmempool" description:"Whether to save the mempool on shutdown and load on restart"`
-	NoMempoolBar     bool    `long:"nomempoolbar" description:"Whether to show progress bar when load mempool from file"`
+	NoRelayPriority      bool    `long:"norelaypriority" description:"Do not require free or low-fee transactions to have high priority for relaying"`
+	FreeTxRelayLimit     float64 `long:"limitfreerelay" description:"Limit relay of transactions with no transaction fee to the given amount in thousands of bytes per minute"`
+	AcceptNonStd         bool    `long:"acceptnonstd" description:"Accept and relay non-standard transactions to the network regardless of the default settings for the active network."`
+	RejectReplacement    bool    `long:"rejectreplacement" description:"Reject transactions that attempt to replace existing transactions within the mempool through the Replace-By-Fee (RBF) signaling policy."`
+	MaxOrphanTxs         int     `long:"maxorphantx" description:"Max number of orphan transactions to keep in memory"`
+	LimitAncestorCount   int64   `long:"limitancestorcount" description:"Do not accept transactions with more in-mempool ancestors than this, including the transaction itself (0 disables the limit)"`
+	LimitDescendantCount int64   `long:"limitdescendantcount" description:"Do not accept transactions if any in-mempool ancestor would have more descendants than this, including the ancestor itself (0 disables the limit)"`
+	TxTimeScope          int64   `long:"txtimescope" description:"allow the mempool tx time scope(sec) with server time,default 0 will not check the time scope"`
+	MinTxFee             int64   `long:"mintxfee" description:"The minimum transaction fee in AtomMEER/kB."`
+	MempoolExpiry        int64   `long:"mempoolexpiry" description:"Do not keep transactions in the mempool more than mempoolexpiry"`
+	Persistmempool       bool    `long:"persistmempool" description:"Whether to save the mempool on shutdown and load on restart"`
+	NoMempoolBar         bool    `long:"nomempoolbar" description:"Whether to show progress bar when load mempool from file"`
 	// Miner
 	Miner             bool     `long:"miner" description:"Enable miner module"`
 	Generate          bool     `long:"generate" description:"Generate (mine) coins using the CPU"`
@@ -65,9 +73,19 @@ type Config struct {
 	SubmitNoSynced    bool     `long:"allowsubmitwhennotsynced" description:"Allow the node to accept blocks from RPC while not synced (this flag is mainly used for testing)"`
 	GBTTimeOut        int      `long:"gbttimeout" description:"Build block template timeout by Millisecond.(Can limit the number of transactions included in the block)"`
 
+	// Stratum
+	StratumListen    string        `long:"stratumlisten" description:"Listen for stratum mining connections on the address (empty to disable)"`
+	StratumPass      string        `long:"stratumpass" description:"Password of stratum workers (empty to accept any worker)"`
+	StratumDiff      float64       `long:"stratumdiff" description:"Initial share difficulty of stratum workers"`
+	StratumShareTime time.Duration `long:"stratumsharetime" description:"Target interval between the shares of a stratum worker for the variable difficulty"`
+
 	//WebSocket support
 	RPCMaxWebsockets     int `long:"rpcmaxwebsockets" description:"Max number of RPC websocket connections"`
 	RPCMaxConcurrentReqs int `long:"rpcmaxconcurrentreqs" description:"Max number of concurrent RPC requests that may be processed concurrently"`
+	//RPC rate limit
+	RPCRateLimit   float64  `long:"rpcratelimit" description:"Max cost of RPC requests per second for each client IP and non-admin user (0 to disable)"`
+	RPCRateBurst   int      `long:"rpcrateburst" description:"Max cost of RPC requests that a client can burst"`
+	RPCMethodCosts []string `long:"rpcmethodcost" description:"Set the rate limit cost of RPC method <method>=<cost>, the method may be prefixed by its namespace"`
 	//P2P
 	BlocksOnly      bool     `long:"blocksonly" description:"Do not accept transactions from remote peers."`
 	MiningStateSync bool     `long:"miningstatesync" description:"Synchronizing the mining state with other nodes"`
@@ -77,7 +95,7 @@ type Config struct {
 	//P2P - server ban
 	Banning bool `long:"banning" description:"Enable banning of misbehaving peers"`
 
-	DAGType     string `short:"G" long:"dagtype" description:"DAG type {phantom,conflux,spectre} "`
+	DAGType     string `short:"G" long:"dagtype" description:"DAG type {phantom,conflux,spectre,ghostdag} or a registered one"`
 	Cleanup     bool   `short:"L" long:"cleanup" description:"Cleanup the block database "`
 	BuildLedger bool   `long:"buildledger" description:"Generate the genesis ledger for the next qitmeer version."`
 
@@ -87,35 +105,52 @@ type Config struct {
 	Zmqpubhashtx string `long:"zmqpubhashtx" description:"Enable publish hash transaction in <address>"`
 	Zmqpubrawtx  string `long:"zmqpubrawtx" description:"Enable publish raw transaction in <address>"`
 
+	// event bus
+	EventWebhooks      []string `long:"eventwebhook" description:"Post the events of event bus to the HTTP webhook <url>"`
+	EventWebhookSecret string   `long:"eventwebhooksecret" description:"The HMAC-SHA256 key signing the event webhook requests"`
+	EventFile          string   `long:"eventfile" description:"Append the events of event bus to the JSONL file"`
+	EventWSListen      string   `long:"eventwslisten" description:"Stream the events of event bus to the websocket clients in <address>, it must be the loopback address without --eventwstoken"`
+	EventWSToken       string   `long:"eventwstoken" description:"The token which the event bus websocket clients must present"`
+	EventNATS          []string `long:"eventnats" description:"Publish the events of event bus to the NATS server <url>"`
+	EventNATSSubject   string   `long:"eventnatssubject" description:"The subject prefix of the events in NATS (default qng)"`
+	EventTopics        []string `long:"eventtopic" description:"Only publish the events of the topic (default all topics)"`
+
 	// index
 	AddrIndex      bool `long:"addrindex" description:"Maintain a full address-based transaction index which makes the getrawtransactions RPC available"`
 	InvalidTxIndex bool `long:"invalidtxindex" description:"Cache invalid transactions."`
 	TxHashIndex    bool `long:"txhashindex" description:"Cache transaction full hash."`
+	CFIndex        bool `long:"cfindex" description:"Maintain the committed filters (BIP157/158) of the blocks for the light clients."`
+	ExplorerIndex  bool `long:"explorerindex" description:"Maintain the balance history and totals of the addresses for the block explorers."`
 	DropAddrIndex  bool `long:"dropaddrindex" description:"Deletes the address-based transaction index from the database on start up and then exits."`
 
 	NTP bool `long:"ntp" description:"Auto sync time."`
 
 	//net2.0
-	BootstrapNodes []string `long:"bootstrapnode" description:"The address of bootstrap node."`
-	NoDiscovery    bool     `long:"nodiscovery" description:"Enable only local network p2p and do not connect to cloud bootstrap nodes."`
-	MetaDataDir    string   `long:"metadatadir" description:"meta data dir for p2p"`
-	P2PUDPPort     int      `long:"p2pudpport" description:"The udp port used by P2P."`
-	P2PTCPPort     int      `long:"p2ptcpport" description:"The tcp port used by P2P."`
-	HostIP         string   `long:"externalip" description:"The IP address advertised by libp2p. This may be used to advertise an external IP."`
-	HostDNS        string   `long:"externaldns" description:"The DNS address advertised by libp2p. This may be used to advertise an external DNS."`
-	RelayNode      string   `long:"relaynode" description:"The address of relay node that routes traffic between two peers over a qitmeer “relay” peer."`
-	Whitelist      []string `long:"whitelist" description:"Add an IP network or IP,PeerID that will not be banned or ignore dual channel mode detection. (eg. 192.168.1.0/24 or ::1 or [peer id])"`
-	Blacklist      []string `long:"blacklist" description:"Add some IP network or IP that will be banned. (eg. 192.168.1.0/24 or ::1)"`
-	MaxBadResp     int      `long:"maxbadresp" description:"maxbadresp is the maximum number of bad responses from a peer before we stop talking to it."`
-	Circuit        bool     `long:"circuit" description:"All peers will ignore dual channel mode detection"`
-	Consistency    bool     `long:"consistency" description:"Detect data consistency through P2P"`
+	BootstrapNodes []string      `long:"bootstrapnode" description:"The address of bootstrap node."`
+	NoDiscovery    bool          `long:"nodiscovery" description:"Enable only local network p2p and do not connect to cloud bootstrap nodes."`
+	MetaDataDir    string        `long:"metadatadir" description:"meta data dir for p2p"`
+	P2PUDPPort     int           `long:"p2pudpport" description:"The udp port used by P2P."`
+	P2PTCPPort     int           `long:"p2ptcpport" description:"The tcp port used by P2P."`
+	HostIP         string        `long:"externalip" description:"The IP address advertised by libp2p. This may be used to advertise an external IP."`
+	HostDNS        string        `long:"externaldns" description:"The DNS address advertised by libp2p. This may be used to advertise an external DNS."`
+	RelayNode      string        `long:"relaynode" description:"The address of relay node that routes traffic between two peers over a qitmeer “relay” peer."`
+	Whitelist      []string      `long:"whitelist" description:"Add an IP network or IP,PeerID that will not be banned or ignore dual channel mode detection. (eg. 192.168.1.0/24 or ::1 or [peer id])"`
+	Blacklist      []string      `long:"blacklist" description:"Add some IP network or IP that will be banned. (eg. 192.168.1.0/24 or ::1)"`
+	MaxBadResp     int           `long:"maxbadresp" description:"maxbadresp is the maximum number of bad responses from a peer before we stop talking to it."`
+	BanThreshold   int           `long:"banthreshold" description:"Misbehavior score of a peer at which it is banned"`
+	BanDuration    time.Duration `long:"banduration" description:"How long to ban misbehaving peers. Valid time units are {s, m, h}. Minimum 1 second"`
+	Circuit        bool          `long:"circuit" description:"All peers will ignore dual channel mode detection"`
+	Consistency    bool          `long:"consistency" description:"Detect data consistency through P2P"`
+	Gossip         string        `long:"gossip" description:"How blocks, transactions and graph state are relayed {stream,both,gossip}"`
+	Dandelion      bool          `long:"dandelion" description:"Relay own transactions by Dandelion++ stem and fluff phases to hide their origin"`
 	// meerevm environment
 	EVMEnv string `long:"evmenv" description:"meer EVM environment"`
 
 	Estimatefee bool `long:"estimatefee" description:"Enable estimate fee"`
 
-	AcctMode   bool `long:"acctmode" description:"Enable support account system mode"`
-	IsArchival bool `long:"archival" description:"Archival tells the consensus if it should not prune old blocks"`
+	AcctMode   bool   `long:"acctmode" description:"Enable support account system mode"`
+	IsArchival bool   `long:"archival" description:"Archival tells the consensus if it should not prune old blocks"`
+	PruneDepth uint64 `long:"prunedepth" description:"The number of blocks below the latest checkpoint whose bodies are kept when the node is not archival, zero disables the pruning"`
 
 	DAGCacheSize       uint64 `long:"dagcachesize" description:"DAG block cache size"`
 	BlockDataCacheSize uint64 `long:"bdcachesize" description:"Block data cache size"`
@@ -123,8 +158,10 @@ type Config struct {
 	Amana    bool   `long:"amana" description:"Enable Amana"`
 	AmanaEnv string `long:"amanaenv" description:"Amana environment"`
 
-	Metrics          bool `long:"metrics" description:"Enable metrics collection and reporting"`
-	MetricsExpensive bool `long:"metrics.expensive" description:"Enable expensive metrics collection and reporting"`
+	Metrics          bool   `long:"metrics" description:"Enable metrics collection and reporting"`
+	MetricsExpensive bool   `long:"metrics.expensive" description:"Enable expensive metrics collection and reporting"`
+	MetricsAddr      string `long:"metrics.addr" description:"Enable the Prometheus metrics HTTP server on the given listening interface"`
+	MetricsPort      int    `long:"metrics.port" description:"Prometheus metrics HTTP server listening port"`
 
 	Minfreedisk uint64 `long:"minfreedisk" description:"Minimum free disk space in MB, once reached triggers auto shut down (default = 512M, 0 = disabled)"`
 
@@ -0,0 +1,6 @@
+package forks
+
+const (
+	// The amount paid by a stakebase from the stake reserve, at most the reserve
+	StakebaseReward = 100000000
+)
@@ -15,6 +15,8 @@ type BlockState interface {
 	SetWeight(weight uint64)
 	GetWeight() uint64
 	GetStatus() BlockStatus
+	SetStatusFlags(flags BlockStatus)
+	UnsetStatusFlags(flags BlockStatus)
 	Valid()
 	Invalid()
 	Root() *hash.Hash
@@ -12,6 +12,10 @@ const (
 
 	// StatusInvalid indicates that the block data has failed validation.
 	StatusInvalid BlockStatus = 1 << 2
+
+	// StatusInvalidated indicates that the block has been marked invalid by
+	// the operator, it fails validation until it's reconsidered.
+	StatusInvalidated BlockStatus = 1 << 3
 )
 
 func (status BlockStatus) IsBadSide() bool {
@@ -22,7 +26,14 @@ func (status BlockStatus) KnownInvalid() bool {
 	return status&StatusInvalid != 0
 }
 
+func (status BlockStatus) IsInvalidated() bool {
+	return status&StatusInvalidated != 0
+}
+
 func (status BlockStatus) String() string {
+	if status.IsInvalidated() {
+		return "invalidated"
+	}
 	if status.KnownInvalid() {
 		return "invalid"
 	}
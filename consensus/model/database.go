@@ -24,12 +24,16 @@ type DataBase interface {
 	GetTokenState(blockID uint) ([]byte, error)
 	PutTokenState(blockID uint, data []byte) error
 	DeleteTokenState(blockID uint) error
+	GetStakeState(blockID uint) ([]byte, error)
+	PutStakeState(blockID uint, data []byte) error
+	DeleteStakeState(blockID uint) error
 	GetBestChainState() ([]byte, error)
 	PutBestChainState(data []byte) error
 	GetBlock(hash *hash.Hash) (*types.SerializedBlock, error)
 	GetBlockBytes(hash *hash.Hash) ([]byte, error)
 	GetHeader(hash *hash.Hash) (*types.BlockHeader, error)
 	PutBlock(block *types.SerializedBlock) error
+	DeleteBlockBody(hash *hash.Hash) error
 	HasBlock(hash *hash.Hash) bool
 	GetDagInfo() ([]byte, error)
 	PutDagInfo(data []byte) error
@@ -72,11 +76,20 @@ type DataBase interface {
 	GetTxForAddress(addr types.Address, numToSkip, numRequested uint32, reverse bool) ([]*common.RetrievedTx, uint32, error)
 	DeleteAddrIdx(sblock *types.SerializedBlock, stxos [][]byte) error
 	CleanAddrIdx(finish bool) error
+	GetCFIdxTip() (*hash.Hash, uint, error)
+	PutCFIdxTip(bh *hash.Hash, order uint) error
+	PutCFilter(bh *hash.Hash, filter []byte, header *hash.Hash) error
+	GetCFilter(bh *hash.Hash) ([]byte, error)
+	GetCFHeader(bh *hash.Hash) (*hash.Hash, error)
+	DeleteCFilter(bh *hash.Hash) error
+	CleanCFIdx() error
 	IsLegacy() bool
 	TryUpgrade(di *common.DatabaseInfo, interrupt <-chan struct{}) error
 	GetEstimateFee() ([]byte, error)
 	PutEstimateFee(data []byte) error
 	DeleteEstimateFee() error
+	GetBanlist() ([]byte, error)
+	PutBanlist(data []byte) error
 	Snapshot() error
 	SnapshotInfo() string
 	DBEngine() string
@@ -18,4 +18,5 @@ type Notify interface {
 	TransactionConfirmed(tx *types.Tx)
 	TransactionsConfirmed(txs []*types.Tx)
 	AddRebroadcastInventory(newTxs []*types.TxDesc)
+	StemTransaction(tx *types.Tx) bool
 }
@@ -13,6 +13,7 @@ import (
 	"github.com/Qitmeer/qng/core/types"
 	"github.com/Qitmeer/qng/engine/txscript"
 	"github.com/Qitmeer/qng/meerdag"
+	"github.com/Qitmeer/qng/params"
 	rapi "github.com/Qitmeer/qng/rpc/api"
 	"github.com/Qitmeer/qng/rpc/client/cmds"
 	"strconv"
@@ -26,6 +27,11 @@ func (b *BlockChain) APIs() []rapi.API {
 			Service:   NewPublicBlockAPI(b),
 			Public:    true,
 		},
+		rapi.API{
+			NameSpace: cmds.TestNameSpace,
+			Service:   NewPrivateBlockAPI(b),
+			Public:    false,
+		},
 	}...)
 	return apis
 }
@@ -220,6 +226,22 @@ func (api *PublicBlockAPI) GetOrphansTotal() (interface{}, error) {
 	return api.chain.GetOrphansTotal(), nil
 }
 
+// Return the state of the consensus rule change deployments which are voted in by the miners
+func (api *PublicBlockAPI) GetDeploymentInfo() (interface{}, error) {
+	return api.chain.DeploymentInfo()
+}
+
+// Return the state of the block pruning, the bodies of the blocks up to the pruned order are removed.
+func (api *PublicBlockAPI) GetPruneInfo() (interface{}, error) {
+	return api.chain.PruneInfo(), nil
+}
+
+// Return the statistics of the UTXO set at the best block, including the MuHash commitment of the set.
+// It iterates the whole UTXO set.
+func (api *PublicBlockAPI) GetTxOutSetInfo() (interface{}, error) {
+	return api.chain.FetchUtxoSetInfo()
+}
+
 // Obsoleted GetBlockByID Method, since the confused naming, replaced by GetBlockByNum method
 func (api *PublicBlockAPI) GetBlockByID(id uint64, verbose *bool, inclTx *bool, fullTx *bool) (interface{}, error) {
 	blockHash := api.chain.BlockDAG().GetBlockHash(uint(id))
@@ -373,6 +395,73 @@ func (api *PublicBlockAPI) GetTokenInfo() (interface{}, error) {
 	return tbs, nil
 }
 
+func (api *PublicBlockAPI) GetStakeInfo() (interface{}, error) {
+	height := uint64(api.chain.BestSnapshot().GraphState.GetMainHeight()) + 1
+	enable, err := api.chain.IsDeploymentActive(params.DeploymentStake)
+	if err != nil {
+		return nil, err
+	}
+	state := api.chain.GetCurStakeState()
+	si := json.StakeInfo{
+		Enable:      enable,
+		Reserve:     state.Reserve,
+		TotalStaked: state.Stakes.Total(),
+		Stakes:      len(state.Stakes),
+		Maturity:    api.chain.StakeMaturity(),
+	}
+	tip := api.chain.GetStakeTipHash()
+	if tip != nil {
+		si.TipHash = tip.String()
+	}
+	for _, r := range state.Rewards(height, si.Maturity) {
+		si.Reward += r.Amount.Value
+	}
+	return si, nil
+}
+
+func (api *PublicBlockAPI) GetStakePool() (interface{}, error) {
+	height := uint64(api.chain.BestSnapshot().GraphState.GetMainHeight()) + 1
+	maturity := api.chain.StakeMaturity()
+	state := api.chain.GetCurStakeState()
+	ses := []json.StakeEntry{}
+	for _, v := range state.Stakes.Sorted() {
+		ses = append(ses, json.StakeEntry{
+			TxId:     v.TxHash.String(),
+			Amount:   v.Amount,
+			Height:   v.Height,
+			PkScript: hex.EncodeToString(v.PkScript),
+			Mature:   v.IsMature(height, maturity),
+		})
+	}
+	return ses, nil
+}
+
+type PrivateBlockAPI struct {
+	chain *BlockChain
+}
+
+func NewPrivateBlockAPI(bc *BlockChain) *PrivateBlockAPI {
+	return &PrivateBlockAPI{chain: bc}
+}
+
+// Mark the block and its future set as invalid, they are removed from the DAG order
+func (api *PrivateBlockAPI) InvalidateBlock(h hash.Hash) (interface{}, error) {
+	err := api.chain.InvalidateBlock(&h)
+	if err != nil {
+		return nil, err
+	}
+	return h.String(), nil
+}
+
+// Remove the invalid mark of invalidateBlock from the block and its future set, they are ordered again
+func (api *PrivateBlockAPI) ReconsiderBlock(h hash.Hash) (interface{}, error) {
+	err := api.chain.ReconsiderBlock(&h)
+	if err != nil {
+		return nil, err
+	}
+	return h.String(), nil
+}
+
 func internalError(err, context string) error {
 	return fmt.Errorf("%s : %s", context, err)
 }
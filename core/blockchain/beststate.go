@@ -5,6 +5,7 @@ import (
 	"github.com/Qitmeer/qng/common/hash"
 	"github.com/Qitmeer/qng/consensus/model"
 	"github.com/Qitmeer/qng/core/dbnamespace"
+	"github.com/Qitmeer/qng/crypto/muhash"
 	"github.com/Qitmeer/qng/database/legacydb"
 	"github.com/Qitmeer/qng/meerdag"
 	"math/big"
@@ -29,13 +30,14 @@ type BestState struct {
 	TotalTxns    uint64              // The total number of txns in the chain.
 	TotalSubsidy uint64              // The total subsidy for the chain.
 	TokenTipHash *hash.Hash          // The Hash of token state tip for the chain.
+	StakeTipHash *hash.Hash          // The Hash of stake state tip for the chain.
 	GraphState   *meerdag.GraphState // The graph state of dag
 	StateRoot    hash.Hash
 }
 
 // newBestState returns a new best stats instance for the given parameters.
 func newBestState(tipHash *hash.Hash, bits uint32, blockSize, numTxns uint64, medianTime time.Time,
-	totalTxns uint64, totalsubsidy uint64, gs *meerdag.GraphState, tokenTipHash *hash.Hash, stateRoot hash.Hash) *BestState {
+	totalTxns uint64, totalsubsidy uint64, gs *meerdag.GraphState, tokenTipHash *hash.Hash, stakeTipHash *hash.Hash, stateRoot hash.Hash) *BestState {
 	return &BestState{
 		Hash:         *tipHash,
 		Bits:         bits,
@@ -45,6 +47,7 @@ func newBestState(tipHash *hash.Hash, bits uint32, blockSize, numTxns uint64, me
 		TotalTxns:    totalTxns,
 		TotalSubsidy: totalsubsidy,
 		TokenTipHash: tokenTipHash,
+		StakeTipHash: stakeTipHash,
 		GraphState:   gs,
 		StateRoot:    stateRoot,
 	}
@@ -69,6 +72,8 @@ func newBestState(tipHash *hash.Hash, bits uint32, blockSize, numTxns uint64, me
 //   tokenTipHash      chainhash.Hash   chainhash.HashSize
 //   work sum length   uint32           4 bytes
 //   work sum          big.Int          work sum length
+//   stakeTipHash      chainhash.Hash   chainhash.HashSize (optional)
+//   utxo set MuHash   muhash.MuHash    muhash.ElementSize (optional)
 // -----------------------------------------------------------------------------
 
 // bestChainState represents the data to be stored the database for the current
@@ -79,6 +84,8 @@ type bestChainState struct {
 	totalTxns    uint64
 	tokenTipHash hash.Hash
 	workSum      *big.Int
+	stakeTipHash hash.Hash
+	utxoMuHash   []byte
 }
 
 func (bcs *bestChainState) GetTotal() uint64 {
@@ -87,18 +94,24 @@ func (bcs *bestChainState) GetTotal() uint64 {
 
 // dbPutBestState uses an existing database transaction to update the best chain
 // state with the given parameters.
-func dbPutBestState(db model.DataBase, snapshot *BestState, workSum *big.Int) error {
+func dbPutBestState(db model.DataBase, snapshot *BestState, workSum *big.Int, utxoMuHash *muhash.MuHash) error {
 	// Serialize the current best chain state.
 	tth := hash.ZeroHash
 	if snapshot.TokenTipHash != nil {
 		tth = *snapshot.TokenTipHash
 	}
+	sth := hash.ZeroHash
+	if snapshot.StakeTipHash != nil {
+		sth = *snapshot.StakeTipHash
+	}
 	serializedData := serializeBestChainState(bestChainState{
 		hash:         snapshot.Hash,
 		total:        uint64(snapshot.GraphState.GetTotal()),
 		totalTxns:    snapshot.TotalTxns,
 		workSum:      workSum,
 		tokenTipHash: tth,
+		stakeTipHash: sth,
+		utxoMuHash:   utxoMuHash.Serialize(),
 	})
 
 	// Store the current best chain state into the database.
@@ -111,7 +124,7 @@ func serializeBestChainState(state bestChainState) []byte {
 	// Calculate the full size needed to serialize the chain state.
 	workSumBytes := state.workSum.Bytes()
 	workSumBytesLen := uint32(len(workSumBytes))
-	serializedLen := hash.HashSize + 8 + 8 + hash.HashSize + 4 + workSumBytesLen
+	serializedLen := hash.HashSize + 8 + 8 + hash.HashSize + 4 + workSumBytesLen + hash.HashSize + uint32(len(state.utxoMuHash))
 
 	// Serialize the chain state.
 	serializedData := make([]byte, serializedLen)
@@ -126,6 +139,10 @@ func serializeBestChainState(state bestChainState) []byte {
 	dbnamespace.ByteOrder.PutUint32(serializedData[offset:], workSumBytesLen)
 	offset += 4
 	copy(serializedData[offset:], workSumBytes)
+	offset += workSumBytesLen
+	copy(serializedData[offset:], state.stakeTipHash[:])
+	offset += hash.HashSize
+	copy(serializedData[offset:], state.utxoMuHash)
 	return serializedData[:]
 }
 
@@ -167,5 +184,17 @@ func DeserializeBestChainState(serializedData []byte) (bestChainState, error) {
 	}
 	workSumBytes := serializedData[offset : offset+workSumBytesLen]
 	state.workSum = new(big.Int).SetBytes(workSumBytes)
+	offset += workSumBytesLen
+	// The stake state tip doesn't exist in the chain state stored before
+	// the stake state.
+	if uint32(len(serializedData[offset:])) >= hash.HashSize {
+		copy(state.stakeTipHash[:], serializedData[offset:offset+hash.HashSize])
+		offset += hash.HashSize
+	}
+	// The MuHash of UTXO set doesn't exist in the chain state stored before
+	// it's maintained.
+	if uint32(len(serializedData[offset:])) >= muhash.ElementSize {
+		state.utxoMuHash = serializedData[offset : offset+muhash.ElementSize]
+	}
 	return state, nil
 }
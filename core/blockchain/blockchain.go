@@ -24,6 +24,7 @@ import (
 	"github.com/Qitmeer/qng/core/types"
 	"github.com/Qitmeer/qng/core/types/pow"
 	"github.com/Qitmeer/qng/core/types/pow/difficultymanager"
+	"github.com/Qitmeer/qng/crypto/muhash"
 	"github.com/Qitmeer/qng/database/common"
 	"github.com/Qitmeer/qng/engine/txscript"
 	l "github.com/Qitmeer/qng/log"
@@ -128,6 +129,16 @@ type BlockChain struct {
 	// The ID of token state tip for the chain.
 	TokenTipID uint32
 
+	// The ID of stake state tip for the chain.
+	StakeTipID uint32
+
+	// The MuHash of the UTXO set, it's maintained by connecting and
+	// disconnecting the blocks.
+	utxoMuHash *muhash.MuHash
+
+	// The UTXO set snapshot loaded by LoadUtxoSnapshot
+	utxoSnapshot *utxoSnapshotState
+
 	Acct model.Acct
 
 	consensus model.Consensus
@@ -144,6 +155,10 @@ type BlockChain struct {
 	processQueueMap sync.Map
 
 	selfAdd atomic.Int64
+
+	// The threshold state caches of the rule change deployments, they are
+	// indexed by the deployment ID.
+	deploymentCaches []thresholdStateCache
 }
 
 func (b *BlockChain) Init() error {
@@ -277,14 +292,22 @@ func (b *BlockChain) initChainState() error {
 	numTxns := uint64(len(block.Block().Transactions))
 
 	b.TokenTipID = uint32(b.bd.GetBlockId(&state.tokenTipHash))
+	b.StakeTipID = uint32(meerdag.MaxId)
+	if !state.stakeTipHash.IsEqual(&hash.ZeroHash) {
+		b.StakeTipID = uint32(b.bd.GetBlockId(&state.stakeTipHash))
+	}
 	b.stateSnapshot = newBestState(mainTip.GetHash(), mainTipNode.Difficulty(), blockSize, numTxns,
 		b.CalcPastMedianTime(mainTip), state.totalTxns, b.bd.GetMainChainTip().GetState().GetWeight(),
-		b.bd.GetGraphState(), &state.tokenTipHash, *mainTip.GetState().Root())
+		b.bd.GetGraphState(), &state.tokenTipHash, b.GetStakeTipHash(), *mainTip.GetState().Root())
 	ts := b.GetTokenState(b.TokenTipID)
 	if ts == nil {
 		return fmt.Errorf("token state error")
 	}
-	return ts.Commit()
+	err = ts.Commit()
+	if err != nil {
+		return err
+	}
+	return b.initUtxoSetState(state.utxoMuHash)
 }
 
 // createChainState initializes both the database and the chain state to the
@@ -309,8 +332,9 @@ func (b *BlockChain) createChainState() error {
 	numTxns := uint64(len(genesisBlock.Block().Transactions))
 	blockSize := uint64(genesisBlock.Block().SerializeSize())
 	b.stateSnapshot = newBestState(node.GetHash(), node.Difficulty(), blockSize, numTxns,
-		time.Unix(node.GetTimestamp(), 0), numTxns, 0, b.bd.GetGraphState(), node.GetHash(), *ib.GetState().Root())
+		time.Unix(node.GetTimestamp(), 0), numTxns, 0, b.bd.GetGraphState(), node.GetHash(), nil, *ib.GetState().Root())
 	b.TokenTipID = 0
+	b.StakeTipID = uint32(meerdag.MaxId)
 	b.dbInfo = common.NewDatabaseInfo(common.CurrentDatabaseVersion, serialization.CurrentCompressionVersion, currentBlockIndexVersion, roughtime.Now())
 	err = b.DB().PutInfo(b.dbInfo)
 	if err != nil {
@@ -325,18 +349,18 @@ func (b *BlockChain) createChainState() error {
 	if err != nil {
 		return err
 	}
-	// Store the current best chain state into the database.
-	err = dbPutBestState(b.DB(), b.stateSnapshot, pow.CalcWork(header.Difficulty, header.Pow.GetPowType()))
+	// Add genesis utxo
+	err = b.dbPutUtxoViewByBlock(genesisBlock)
 	if err != nil {
 		return err
 	}
-	// Store the genesis block into the database.
-	err = b.DB().PutBlock(genesisBlock)
+	// Store the current best chain state into the database.
+	err = dbPutBestState(b.DB(), b.stateSnapshot, pow.CalcWork(header.Difficulty, header.Pow.GetPowType()), b.utxoMuHash)
 	if err != nil {
 		return err
 	}
-	// Add genesis utxo
-	err = b.dbPutUtxoViewByBlock(genesisBlock)
+	// Store the genesis block into the database.
+	err = b.DB().PutBlock(genesisBlock)
 	if err != nil {
 		return err
 	}
@@ -478,6 +502,10 @@ func (b *BlockChain) FetchSubsidyCache() *SubsidyCache {
 // This function MUST be called with the chain state lock held (for writes).
 
 func (b *BlockChain) reorganizeChain(ib meerdag.IBlock, detachNodes *list.List, attachNodes *list.List, newBlock *BlockNode, connectedBlocks *list.List) error {
+	err := b.checkDetachUtxoSnapshot(detachNodes)
+	if err != nil {
+		return err
+	}
 	oldBlocks := []*hash.Hash{}
 	for e := detachNodes.Front(); e != nil; e = e.Next() {
 		ob := e.Value.(*meerdag.BlockOrderHelp)
@@ -495,7 +523,6 @@ func (b *BlockChain) reorganizeChain(ib meerdag.IBlock, detachNodes *list.List,
 	// In the two case, the perspective is the same.In the other words, the future can not
 	// affect the past.
 	var block *BlockNode
-	var err error
 
 	for e := detachNodes.Back(); e != nil; e = e.Prev() {
 		n := e.Value.(*meerdag.BlockOrderHelp)
@@ -503,6 +530,7 @@ func (b *BlockChain) reorganizeChain(ib meerdag.IBlock, detachNodes *list.List,
 			panic(fmt.Errorf("No BlockOrderHelp"))
 		}
 		b.updateTokenState(n.Block, nil, true)
+		b.updateStakeState(n.Block, nil, true)
 		er := b.updateDefaultBlockState(n.Block)
 		if er != nil {
 			log.Error(er.Error())
@@ -519,7 +547,8 @@ func (b *BlockChain) reorganizeChain(ib meerdag.IBlock, detachNodes *list.List,
 		var stxos []utxo.SpentTxOut
 		view := utxo.NewUtxoViewpoint()
 		view.SetViewpoints([]*hash.Hash{block.Hash()})
-		if !n.Block.GetState().GetStatus().KnownInvalid() {
+		// The block connected without the UTXO set has nothing to restore
+		if !n.Block.GetState().GetStatus().KnownInvalid() && !b.isAssumedOrder(uint64(n.OldOrder)) {
 			b.SetDAGDuplicateTxs(block, n.Block)
 			err = b.fetchInputUtxos(block, view)
 			if err != nil {
@@ -591,12 +620,20 @@ func (b *BlockChain) reorganizeChain(ib meerdag.IBlock, detachNodes *list.List,
 		view := utxo.NewUtxoViewpoint()
 		view.SetViewpoints([]*hash.Hash{nodeBlock.GetHash()})
 		stxos := []utxo.SpentTxOut{}
-		err = b.checkConnectBlock(nodeBlock, block, view, &stxos)
-		if err != nil {
-			b.bd.InvalidBlock(nodeBlock)
-			stxos = []utxo.SpentTxOut{}
-			view.Clean()
-			log.Warn(err.Error(), "block", nodeBlock.GetHash().String(), "order", nodeBlock.GetOrder())
+		if b.isAssumedOrder(uint64(nodeBlock.GetOrder())) {
+			// The UTXO changes of block are in the pending snapshot
+			err = b.checkAssumedBlock(nodeBlock)
+			if err != nil {
+				return err
+			}
+		} else {
+			err = b.checkConnectBlock(nodeBlock, block, view, &stxos)
+			if err != nil {
+				b.bd.InvalidBlock(nodeBlock)
+				stxos = []utxo.SpentTxOut{}
+				view.Clean()
+				log.Warn(err.Error(), "block", nodeBlock.GetHash().String(), "order", nodeBlock.GetOrder())
+			}
 		}
 		err = b.connectBlock(nodeBlock, block, view, stxos, connectedBlocks)
 		if err != nil {
@@ -614,6 +651,10 @@ func (b *BlockChain) reorganizeChain(ib meerdag.IBlock, detachNodes *list.List,
 		if er != nil {
 			log.Error(er.Error())
 		}
+		err = b.swapUtxoSnapshotAt(nodeBlock)
+		if err != nil {
+			return err
+		}
 		log.Debug("attach block", "hash", nodeBlock.GetHash().String(), "order", nodeBlock.GetOrder(), "status", nodeBlock.GetState().GetStatus().String())
 	}
 
@@ -638,6 +679,8 @@ func (b *BlockChain) countSpentOutputs(block *types.SerializedBlock) int {
 			} else {
 				continue
 			}
+		} else if types.IsStakeTx(tx.Tx) {
+			numSpent--
 		} else if types.IsCrossChainImportTx(tx.Tx) {
 			numSpent++
 			continue
@@ -926,7 +969,11 @@ func (b *BlockChain) TimeSource() model.MedianTimeSource {
 }
 
 func (b *BlockChain) Rebuild() error {
+	if b.utxoSnapshot != nil {
+		return fmt.Errorf("The chain can't be rebuilt, the UTXO set is loaded from the snapshot at order %d", b.utxoSnapshot.order)
+	}
 	b.TokenTipID = 0
+	b.StakeTipID = uint32(meerdag.MaxId)
 	initTS := token.BuildGenesisTokenState()
 	err := initTS.Commit()
 	if err != nil {
@@ -1072,11 +1119,16 @@ func New(consensus model.Consensus) (*BlockChain, error) {
 		progressLogger:     progresslog.NewBlockProgressLogger("Processed", log),
 		msgChan:            make(chan *processMsg),
 		quit:               make(chan struct{}),
+		deploymentCaches:   newThresholdCaches(params.DefinedDeployments),
+		utxoMuHash:         muhash.New(),
 	}
 	b.selfAdd.Store(0)
 
 	b.subsidyCache = NewSubsidyCache(0, b.params)
 
+	if !meerdag.IsRegisteredConsensusAlgorithm(config.DAGType) {
+		return nil, fmt.Errorf("Unknown DAG type (%s), the registered types: %v", config.DAGType, meerdag.RegisteredConsensusAlgorithms())
+	}
 	b.bd = meerdag.New(config.DAGType, 1.0/float64(par.TargetTimePerBlock/time.Second), b.DB(), b.getBlockData)
 	b.bd.SetCacheSize(config.DAGCacheSize, config.BlockDataCacheSize)
 
@@ -1,6 +1,7 @@
 package blockchain
 
 import (
+	"github.com/Qitmeer/qng/config"
 	"github.com/Qitmeer/qng/params"
 	"testing"
 )
@@ -16,3 +17,58 @@ func TestBlockClone(t *testing.T) {
 		t.Fatalf("block hash:%s != %s (expect)", cbHash.String(), block.Hash().String())
 	}
 }
+
+func TestPruneTarget(t *testing.T) {
+	tests := []struct {
+		checkpoint uint64
+		depth      uint64
+		target     uint64
+	}{
+		{0, 10, 0},
+		{10, 10, 0},
+		{11, 10, 0},
+		{12, 10, 1},
+		{5000, 4096, 903},
+	}
+	for _, test := range tests {
+		target := pruneTarget(test.checkpoint, test.depth)
+		if target != test.target {
+			t.Errorf("pruneTarget(%d, %d) = %d, want %d", test.checkpoint, test.depth, target, test.target)
+		}
+	}
+}
+
+func TestPruneDisabledReason(t *testing.T) {
+	if reason := PruneDisabledReason(&config.Config{}); len(reason) <= 0 {
+		t.Fatal("the pruning is enabled by the zero depth")
+	}
+	if reason := PruneDisabledReason(&config.Config{PruneDepth: 10}); len(reason) > 0 {
+		t.Fatalf("the pruning is disabled:%s", reason)
+	}
+	for _, cfg := range []*config.Config{
+		{PruneDepth: 10, CFIndex: true},
+		{PruneDepth: 10, ExplorerIndex: true},
+		{PruneDepth: 10, AcctMode: true},
+	} {
+		if reason := PruneDisabledReason(cfg); len(reason) <= 0 {
+			t.Fatalf("the pruning is enabled by %+v", cfg)
+		}
+	}
+}
+
+func TestHoldPruning(t *testing.T) {
+	b := &BlockChain{pruner: &chainPruner{prunedOrder: 100, holds: map[string]uint64{}}}
+	if err := b.HoldPruning("job", 50); err == nil {
+		t.Fatal("the pruned blocks are held")
+	}
+	if err := b.HoldPruning("job", 101); err != nil {
+		t.Fatal(err)
+	}
+	if b.pruner.holds["job"] != 101 {
+		t.Fatalf("unexpected holds %v", b.pruner.holds)
+	}
+	b.ReleasePruning("job")
+	if len(b.pruner.holds) != 0 {
+		t.Fatalf("unexpected holds %v", b.pruner.holds)
+	}
+}
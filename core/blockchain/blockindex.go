@@ -317,6 +317,10 @@ func (b *BlockChain) fetchBlockByHash(hash *hash.Hash) (*types.SerializedBlock,
 	if err == nil && block != nil {
 		return block, nil
 	}
+	ib := b.bd.GetBlock(hash)
+	if ib != nil && ib.IsOrdered() && b.IsPruned(uint64(ib.GetOrder())) {
+		return nil, fmt.Errorf("block %v (order %d) has been pruned", hash, ib.GetOrder())
+	}
 	return nil, fmt.Errorf("unable to find block %v db", hash)
 }
 
@@ -325,6 +325,10 @@ const (
 	// ErrNoViewpoint
 	ErrNoViewpoint
 
+	// ErrInvalidatedBlock indicates the block has been marked invalid by the
+	// invalidateBlock RPC.
+	ErrInvalidatedBlock
+
 	// numErrorCodes is the maximum error code number used in tests.
 	numErrorCodes
 
@@ -403,6 +407,7 @@ var errorCodeStrings = map[ErrorCode]string{
 
 	ErrNoBlueCoinbase:         "ErrNoBlueCoinbase",
 	ErrNoViewpoint:            "ErrNoViewpoint",
+	ErrInvalidatedBlock:       "ErrInvalidatedBlock",
 	ErrorCoinbaseBlockVersion: "ErrorCoinbaseBlockVersion",
 }
 
@@ -0,0 +1,160 @@
+// Copyright (c) 2017-2018 The qitmeer developers
+package blockchain
+
+import (
+	"container/list"
+	"fmt"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/consensus/model"
+	"github.com/Qitmeer/qng/meerdag"
+)
+
+// InvalidateBlock marks the block and its future set as invalid, the marks
+// are persisted in the block status. The main chain is selected again from the
+// tips that aren't invalidated, and the marked blocks lose their orders, so the
+// UTXO, token, stake and EVM states are rolled back by the DAG reorganization.
+//
+// The blocks arriving later on top of the invalidated blocks are marked too,
+// the other blocks are validated as usual and can't spend the outputs of the
+// invalidated blocks.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) InvalidateBlock(h *hash.Hash) error {
+	return b.updateInvalidated(h, true)
+}
+
+// ReconsiderBlock removes the marks of InvalidateBlock from the block and its
+// future set, the blocks which still have an invalidated parent keep the mark.
+// The main chain is selected again, so the reconsidered blocks are ordered and
+// connected if they are on the bluest chain.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) ReconsiderBlock(h *hash.Hash) error {
+	return b.updateInvalidated(h, false)
+}
+
+func (b *BlockChain) updateInvalidated(h *hash.Hash, invalidate bool) error {
+	b.ChainLock()
+	connectedBlocks := list.New()
+	err := b.setInvalidated(h, invalidate, connectedBlocks)
+	b.ChainUnlock()
+	for e := connectedBlocks.Front(); e != nil; e = e.Next() {
+		b.sendNotification(BlockConnected, e.Value)
+	}
+	b.flushNotifications()
+	return err
+}
+
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) setInvalidated(h *hash.Hash, invalidate bool, connectedBlocks *list.List) error {
+	if b.bd.GetInstance().GetName() != meerdag.PHANTOM {
+		return fmt.Errorf("The DAG type (%s) doesn't support invalidating blocks", b.bd.GetInstance().GetName())
+	}
+	ib := b.bd.GetBlock(h)
+	if ib == nil {
+		return fmt.Errorf("No block:%s", h)
+	}
+	if ib.GetID() == meerdag.GenesisId {
+		return fmt.Errorf("The genesis block can't be invalidated")
+	}
+	if ib.GetState().GetStatus().IsInvalidated() == invalidate {
+		if invalidate {
+			return fmt.Errorf("The block %s has been invalidated", h)
+		}
+		return fmt.Errorf("The block %s isn't invalidated", h)
+	}
+	if !invalidate && b.hasInvalidatedParent(ib) {
+		return fmt.Errorf("The block %s has invalidated parents, reconsider them first", h)
+	}
+
+	fs := meerdag.NewIdSet()
+	b.bd.GetFutureSet(fs, ib)
+	fs.AddPair(ib.GetID(), ib)
+	// The parents always have the smaller ids, so they are updated first.
+	changed := []meerdag.IBlock{}
+	for _, id := range fs.SortList(false) {
+		block := b.bd.GetBlockById(id)
+		if block == nil {
+			return fmt.Errorf("No block:id=%d", id)
+		}
+		if block.GetState().GetStatus().IsInvalidated() == invalidate {
+			continue
+		}
+		if invalidate {
+			b.bd.SetBlockStatusFlags(block, model.StatusInvalidated)
+		} else {
+			if b.hasInvalidatedParent(block) {
+				continue
+			}
+			b.bd.UnsetBlockStatusFlags(block, model.StatusInvalidated)
+		}
+		changed = append(changed, block)
+	}
+
+	changeOrder := b.bd.GetMainChainChangeOrder()
+	if changeOrder != meerdag.MaxBlockOrder && b.IsPruned(uint64(changeOrder)) {
+		for _, block := range changed {
+			if invalidate {
+				b.bd.UnsetBlockStatusFlags(block, model.StatusInvalidated)
+			} else {
+				b.bd.SetBlockStatusFlags(block, model.StatusInvalidated)
+			}
+		}
+		return fmt.Errorf("Can't reorganize the blocks from order %d: they have been pruned", changeOrder)
+	}
+	if invalidate {
+		log.Info(fmt.Sprintf("Invalidate block %s and its %d future blocks", h, len(changed)-1))
+	} else {
+		log.Info(fmt.Sprintf("Reconsider block %s and its %d future blocks", h, len(changed)-1))
+	}
+
+	err := b.DB().StartTrack(h.String())
+	if err != nil {
+		return err
+	}
+	// The states are inconsistent if the reorganization fails in the middle.
+	newOrders, oldOrders, err := b.bd.UpdateMainChain()
+	if err != nil {
+		panic(err.Error())
+	}
+	if newOrders.Len() > 0 || oldOrders.Len() > 0 {
+		mainTip := b.bd.GetMainChainTip()
+		mainNode := b.GetBlockNode(mainTip)
+		if mainNode == nil {
+			panic(fmt.Errorf("No block node:%s", mainTip.GetHash()))
+		}
+		err = b.reorganizeChain(mainTip, oldOrders, newOrders, mainNode, connectedBlocks)
+		if err != nil {
+			panic(err.Error())
+		}
+		// The new main chain tip is an ancestor of the old one
+		if newOrders.Len() <= 0 {
+			err = b.meerChain.RewindTo(mainTip.GetState())
+			if err != nil {
+				panic(err.Error())
+			}
+		}
+		err = b.updateBestState(mainTip, mainNode.GetBody(), newOrders)
+	} else {
+		err = b.bd.Commit()
+	}
+	if err != nil {
+		panic(err.Error())
+	}
+	err = b.DB().StopTrack()
+	if err != nil {
+		panic(err.Error())
+	}
+	return nil
+}
+
+func (b *BlockChain) hasInvalidatedParent(ib meerdag.IBlock) bool {
+	for _, id := range ib.GetParents().List() {
+		parent := b.bd.GetBlockById(id)
+		if parent != nil && parent.GetState().GetStatus().IsInvalidated() {
+			return true
+		}
+	}
+	return false
+}
@@ -383,12 +383,20 @@ func (b *BlockChain) connectDagChain(ib meerdag.IBlock, block *BlockNode, newOrd
 			view := utxo.NewUtxoViewpoint()
 			view.SetViewpoints([]*hash.Hash{nodeBlock.GetHash()})
 			stxos := []utxo.SpentTxOut{}
-			err = b.checkConnectBlock(nodeBlock, sb, view, &stxos)
-			if err != nil {
-				b.bd.InvalidBlock(nodeBlock)
-				stxos = []utxo.SpentTxOut{}
-				view.Clean()
-				log.Warn(err.Error(), "block", nodeBlock.GetHash().String(), "order", nodeBlock.GetOrder())
+			if b.isAssumedOrder(uint64(nodeBlock.GetOrder())) {
+				// The UTXO changes of block are in the pending snapshot
+				err = b.checkAssumedBlock(nodeBlock)
+				if err != nil {
+					return false, err
+				}
+			} else {
+				err = b.checkConnectBlock(nodeBlock, sb, view, &stxos)
+				if err != nil {
+					b.bd.InvalidBlock(nodeBlock)
+					stxos = []utxo.SpentTxOut{}
+					view.Clean()
+					log.Warn(err.Error(), "block", nodeBlock.GetHash().String(), "order", nodeBlock.GetOrder())
+				}
 			}
 			err = b.connectBlock(nodeBlock, sb, view, stxos, connectedBlocks)
 			if err != nil {
@@ -406,6 +414,10 @@ func (b *BlockChain) connectDagChain(ib meerdag.IBlock, block *BlockNode, newOrd
 			if er != nil {
 				log.Error(er.Error())
 			}
+			err = b.swapUtxoSnapshotAt(nodeBlock)
+			if err != nil {
+				return false, err
+			}
 			log.Debug("Block connected to the main chain", "hash", nodeBlock.GetHash(), "order", nodeBlock.GetOrder())
 		}
 		return true, nil
@@ -480,6 +492,10 @@ func (b *BlockChain) connectBlock(node meerdag.IBlock, blockNode *BlockNode, vie
 		if err != nil {
 			return err
 		}
+		err = b.updateStakeState(node, block, false)
+		if err != nil {
+			return err
+		}
 	} else {
 		// Atomically insert info into the database.
 		err := b.indexManager.ConnectBlock(block, node, pkss)
@@ -545,7 +561,7 @@ func (bc *BlockChain) connectTransaction(tx *types.Tx, node *BlockNode, blockInd
 	// if a slice was provided for the spent txout details, append an entry
 	// to it.
 	for txInIndex, txIn := range msgTx.TxIn {
-		if txInIndex == 0 && types.IsTokenMintTx(tx.Tx) {
+		if txInIndex == 0 && (types.IsTokenMintTx(tx.Tx) || types.IsStakeTx(tx.Tx)) {
 			continue
 		}
 		entry := view.Entries()[txIn.PreviousOut]
@@ -663,7 +679,7 @@ func (bc *BlockChain) disconnectTransactions(block *types.SerializedBlock, stxos
 			continue
 		}
 		for txInIdx := len(tx.Tx.TxIn) - 1; txInIdx > -1; txInIdx-- {
-			if types.IsTokenMintTx(tx.Tx) && txInIdx == 0 {
+			if (types.IsTokenMintTx(tx.Tx) || types.IsStakeTx(tx.Tx)) && txInIdx == 0 {
 				continue
 			}
 			stxo := &stxos[stxoIdx]
@@ -711,11 +727,11 @@ func (b *BlockChain) updateBestState(ib meerdag.IBlock, block *types.SerializedB
 		return fmt.Errorf("No main tip node\n")
 	}
 	state := newBestState(mainTip.GetHash(), mainTipNode.Difficulty(), blockSize, numTxns, b.CalcPastMedianTime(mainTip), lastState.TotalTxns+numTxns,
-		b.bd.GetMainChainTip().GetState().GetWeight(), b.bd.GetGraphState(), b.GetTokenTipHash(), *mainTip.GetState().Root())
+		b.bd.GetMainChainTip().GetState().GetWeight(), b.bd.GetGraphState(), b.GetTokenTipHash(), b.GetStakeTipHash(), *mainTip.GetState().Root())
 
 	// Atomically insert info into the database.
 	// Update best block state.
-	err = dbPutBestState(b.DB(), state, pow.CalcWork(mainTipNode.Difficulty(), mainTipNode.Pow().GetPowType()))
+	err = dbPutBestState(b.DB(), state, pow.CalcWork(mainTipNode.Difficulty(), mainTipNode.Pow().GetPowType()), b.utxoMuHash)
 	if err != nil {
 		return err
 	}
@@ -2,27 +2,94 @@
 package blockchain
 
 import (
-	"github.com/Qitmeer/qng/common/roughtime"
+	"encoding/binary"
+	"fmt"
+	"sync"
 	"time"
+
+	"github.com/Qitmeer/qng/common/roughtime"
+	"github.com/Qitmeer/qng/config"
+	"github.com/Qitmeer/qng/core/json"
 )
 
 // pruningIntervalInMinutes is the interval in which to prune the blockchain's
 // nodes and restore memory to the garbage collector.
 const pruningIntervalInMinutes = 5
 
+// maxPruneBlocksPerRound is the maximum number of block bodies removed by one
+// pruning round, the chain lock is held while pruning.
+const maxPruneBlocksPerRound = 2000
+
+// prunedOrderKey is the database key of the highest pruned block order.
+var prunedOrderKey = []byte("prunedorder")
+
 // chainPruner is used to occasionally prune the blockchain of old nodes that
 // can be freed to the garbage collector.
+//
+// When the node is not archival, the bodies and spend journals of the blocks
+// older than the prune depth below the latest checkpoint are removed from the
+// database. The headers and the MeerDAG metadata are kept.
 type chainPruner struct {
 	chain              *BlockChain
 	lastNodeInsertTime time.Time
+
+	lock            sync.RWMutex
+	prunedOrder     uint64
+	checkpointOrder uint64
+	lastPruneTime   time.Time
+	// disabled is the reason why pruning is disabled, empty when enabled.
+	disabled string
+	// holds are the lowest block orders whose bodies are still read, such
+	// as by the rescan jobs.
+	holds map[string]uint64
+}
+
+// PruneDisabledReason returns the reason why the block bodies can't be pruned
+// by the config, or an empty string if they are pruned.
+func PruneDisabledReason(cfg *config.Config) string {
+	switch {
+	case cfg.IsArchival:
+		return "archival node"
+	case cfg.PruneDepth <= 0:
+		return "the prune depth is zero"
+	case cfg.AddrIndex:
+		return "the address index needs all blocks"
+	case cfg.CFIndex:
+		return "the committed filter index needs all blocks"
+	case cfg.ExplorerIndex:
+		return "the explorer index needs all blocks"
+	case cfg.AcctMode:
+		return "the account mode needs all blocks"
+	}
+	return ""
 }
 
 // newChainPruner returns a new chain pruner.
 func newChainPruner(chain *BlockChain) *chainPruner {
-	return &chainPruner{
+	cp := &chainPruner{
 		chain:              chain,
 		lastNodeInsertTime: roughtime.Now(),
+		disabled:           PruneDisabledReason(chain.consensus.Config()),
+		holds:              map[string]uint64{},
 	}
+	data, err := chain.DB().Get(prunedOrderKey)
+	if err == nil && len(data) == 8 {
+		cp.prunedOrder = binary.LittleEndian.Uint64(data)
+	}
+	if cp.prunedOrder > 0 {
+		log.Info(fmt.Sprintf("Block bodies are pruned up to order %d", cp.prunedOrder))
+	}
+	return cp
+}
+
+// pruneTarget returns the highest block order to prune, so that depth blocks
+// below the checkpoint are kept. Zero means nothing can be pruned, the genesis
+// is never pruned.
+func pruneTarget(checkpointOrder uint64, depth uint64) uint64 {
+	if checkpointOrder <= depth+1 {
+		return 0
+	}
+	return checkpointOrder - depth - 1
 }
 
 // pruneChainIfNeeded checks the current time versus the time of the last pruning.
@@ -36,4 +103,142 @@ func (c *chainPruner) pruneChainIfNeeded() {
 		return
 	}
 	c.lastNodeInsertTime = now
+
+	err := c.pruneBlocks()
+	if err != nil {
+		log.Warn(fmt.Sprintf("Prune blocks:%s", err))
+	}
+}
+
+// pruneBlocks removes the block bodies and spend journals which are older
+// than the prune depth below the latest known checkpoint.
+//
+// This function MUST be called with the chain lock held (for writes).
+func (c *chainPruner) pruneBlocks() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if len(c.disabled) > 0 {
+		return nil
+	}
+	checkpoint, err := c.chain.findPreviousCheckpoint()
+	if err != nil {
+		return err
+	}
+	if checkpoint == nil || !checkpoint.IsOrdered() {
+		return nil
+	}
+	c.checkpointOrder = uint64(checkpoint.GetOrder())
+	target := pruneTarget(c.checkpointOrder, c.chain.consensus.Config().PruneDepth)
+	if target <= c.prunedOrder {
+		return nil
+	}
+	for _, order := range c.holds {
+		if order == 0 {
+			target = 0
+		} else if order <= target {
+			target = order - 1
+		}
+	}
+	if target <= c.prunedOrder {
+		return nil
+	}
+	if target-c.prunedOrder > maxPruneBlocksPerRound {
+		target = c.prunedOrder + maxPruneBlocksPerRound
+	}
+	db := c.chain.DB()
+	start := c.prunedOrder + 1
+	for order := start; order <= target; order++ {
+		h := c.chain.bd.GetBlockHashByOrder(uint(order))
+		if h == nil {
+			return fmt.Errorf("No block at order %d", order)
+		}
+		err = db.DeleteBlockBody(h)
+		if err != nil {
+			c.disabled = err.Error()
+			return err
+		}
+		err = db.DeleteSpendJournal(h)
+		if err != nil {
+			return err
+		}
+		c.prunedOrder = order
+	}
+	var data [8]byte
+	binary.LittleEndian.PutUint64(data[:], c.prunedOrder)
+	err = db.Put(prunedOrderKey, data[:])
+	if err != nil {
+		return err
+	}
+	c.lastPruneTime = roughtime.Now()
+	log.Info(fmt.Sprintf("Pruned block bodies from order %d to %d", start, c.prunedOrder))
+	return nil
+}
+
+// PrunedOrder returns the highest block order whose body has been pruned.
+// Zero means no block has been pruned.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) PrunedOrder() uint64 {
+	if b.pruner == nil {
+		return 0
+	}
+	b.pruner.lock.RLock()
+	defer b.pruner.lock.RUnlock()
+	return b.pruner.prunedOrder
+}
+
+// HoldPruning keeps the bodies of the blocks from the order until the hold of
+// the key is released.  It fails if some of them are already pruned.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) HoldPruning(key string, order uint64) error {
+	c := b.pruner
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if order > 0 && order <= c.prunedOrder {
+		return fmt.Errorf("The blocks are pruned up to order %d", c.prunedOrder)
+	}
+	c.holds[key] = order
+	return nil
+}
+
+// ReleasePruning releases the hold of the key.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) ReleasePruning(key string) {
+	c := b.pruner
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.holds, key)
+}
+
+// IsPruned returns whether the body of the block at the order has been pruned.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) IsPruned(order uint64) bool {
+	return order > 0 && order <= b.PrunedOrder()
+}
+
+// PruneInfo returns the state of the block pruning.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) PruneInfo() *json.PruneInfo {
+	c := b.pruner
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	cfg := b.consensus.Config()
+	info := &json.PruneInfo{
+		Archival:        cfg.IsArchival,
+		Enabled:         len(c.disabled) <= 0,
+		PruneDepth:      cfg.PruneDepth,
+		CheckpointOrder: c.checkpointOrder,
+		PrunedOrder:     c.prunedOrder,
+		Reason:          c.disabled,
+	}
+	if !c.lastPruneTime.IsZero() {
+		info.LastPrune = c.lastPruneTime.String()
+	}
+	return info
 }
@@ -43,7 +43,7 @@ func (b *BlockChain) calcSequenceLock(tx *types.Tx, view *utxo.UtxoViewpoint, is
 	msgTx := tx.Transaction()
 	//TODO, revisit the tx version for lock time
 	enforce := isActive && msgTx.Version >= 2
-	if !enforce || msgTx.IsCoinBase() || tx.IsDuplicate || types.IsTokenTx(tx.Tx) {
+	if !enforce || msgTx.IsCoinBase() || tx.IsDuplicate || types.IsTokenTx(tx.Tx) || types.IsStakeTx(tx.Tx) {
 		return sequenceLock, nil
 
 	}
@@ -0,0 +1,195 @@
+package blockchain
+
+import (
+	"fmt"
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/core/blockchain/stake"
+	"github.com/Qitmeer/qng/core/blockchain/utxo"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/meerdag"
+	"github.com/Qitmeer/qng/params"
+)
+
+// StakeMaturity returns the number of blocks required before the stakes can
+// be disposed and rewarded.
+func (b *BlockChain) StakeMaturity() uint64 {
+	return uint64(b.params.CoinbaseMaturity)
+}
+
+// CheckStakeTransactionInputs checks the inputs of the stake_purchase and
+// stake_reserve, which must be MEER and equal to the outputs.
+func (b *BlockChain) CheckStakeTransactionInputs(tx *types.Tx, utxoView *utxo.UtxoViewpoint) error {
+	totalAtomIn, err := b.checkMeerTransactionInputs(tx, utxoView)
+	if err != nil {
+		return err
+	}
+	totalAtomOut := int64(0)
+	for idx, txOut := range tx.Transaction().TxOut {
+		if !txOut.Amount.Id.IsBase() {
+			return fmt.Errorf("Transaction(%s) output(%d) must be MEERA\n", tx.Hash(), idx)
+		}
+		totalAtomOut += txOut.Amount.Value
+	}
+	if totalAtomIn != totalAtomOut {
+		return fmt.Errorf("Utxo (%d) and output amount (%d) are inconsistent\n", totalAtomIn, totalAtomOut)
+	}
+	return nil
+}
+
+func (b *BlockChain) stakeUpdates(block *types.SerializedBlock) ([]*stake.StakeUpdate, error) {
+	updates := []*stake.StakeUpdate{}
+	for _, tx := range block.Transactions() {
+		if tx.IsDuplicate {
+			continue
+		}
+		if types.IsStakeTx(tx.Tx) {
+			update, err := stake.NewUpdateFromTx(tx.Tx)
+			if err != nil {
+				return nil, err
+			}
+			updates = append(updates, update)
+		}
+	}
+	return updates, nil
+}
+
+func (b *BlockChain) updateStakeState(node meerdag.IBlock, block *types.SerializedBlock, rollback bool) error {
+	if rollback {
+		if uint32(node.GetID()) == b.StakeTipID {
+			state := b.GetStakeState(b.StakeTipID)
+			if state != nil {
+				err := stake.DBRemoveStakeState(b.DB(), node.GetID())
+				if err != nil {
+					return err
+				}
+				b.StakeTipID = state.PrevStateID
+			}
+		}
+		return nil
+	}
+	updates, err := b.stakeUpdates(block)
+	if err != nil {
+		return err
+	}
+	if len(updates) <= 0 {
+		return nil
+	}
+	state := b.GetStakeState(b.StakeTipID)
+	if state == nil {
+		state = &stake.StakeState{PrevStateID: uint32(meerdag.MaxId), Updates: updates}
+	} else {
+		state.PrevStateID = b.StakeTipID
+		state.Updates = updates
+	}
+
+	err = state.Update(uint64(node.GetHeight()), b.StakeMaturity())
+	if err != nil {
+		return err
+	}
+
+	err = stake.DBPutStakeState(b.DB(), node.GetID(), state)
+	if err != nil {
+		return err
+	}
+	b.StakeTipID = uint32(node.GetID())
+	return nil
+}
+
+func (b *BlockChain) GetStakeState(bid uint32) *stake.StakeState {
+	if uint(bid) == meerdag.MaxId {
+		return nil
+	}
+	state, err := stake.DBFetchStakeState(b.DB(), uint(bid))
+	if err != nil {
+		log.Error(err.Error())
+		return nil
+	}
+	return state
+}
+
+func (b *BlockChain) GetCurStakeState() *stake.StakeState {
+	b.ChainRLock()
+	defer b.ChainRUnlock()
+	state := b.GetStakeState(b.StakeTipID)
+	if state == nil {
+		state = &stake.StakeState{PrevStateID: uint32(meerdag.MaxId), Stakes: stake.StakesMap{}}
+	}
+	return state
+}
+
+// CheckStakeState checks the stake transactions of the block, which are only
+// allowed after the stake deployment is active.
+func (b *BlockChain) CheckStakeState(ib meerdag.IBlock, block *types.SerializedBlock) error {
+	updates, err := b.stakeUpdates(block)
+	if err != nil {
+		return err
+	}
+	if len(updates) <= 0 {
+		return nil
+	}
+	active, err := b.isDeploymentActiveFor(ib, params.DeploymentStake)
+	if err != nil {
+		return err
+	}
+	if !active {
+		return fmt.Errorf("Stake transactions are not enabled at height %d\n", ib.GetHeight())
+	}
+	state := b.GetStakeState(b.StakeTipID)
+	if state == nil {
+		state = &stake.StakeState{PrevStateID: uint32(meerdag.MaxId), Updates: updates}
+	} else {
+		state.PrevStateID = b.StakeTipID
+		state.Updates = updates
+	}
+	return state.Update(uint64(ib.GetHeight()), b.StakeMaturity())
+}
+
+// CheckStakeTransaction checks the stake transaction against the current stake
+// state, which is used by the transaction pool.
+func (b *BlockChain) CheckStakeTransaction(tx *types.Tx, height uint64) error {
+	update, err := stake.NewUpdateFromTx(tx.Tx)
+	if err != nil {
+		return err
+	}
+	active, err := b.IsDeploymentActive(params.DeploymentStake)
+	if err != nil {
+		return err
+	}
+	if !active {
+		return fmt.Errorf("Stake transactions are not enabled at height %d\n", height)
+	}
+	state := b.GetCurStakeState()
+	state.Updates = []*stake.StakeUpdate{update}
+	return state.Update(height, b.StakeMaturity())
+}
+
+// GetCurStakeHolder returns the script of the holder of the stake which was
+// purchased by the transaction, the stake_dispose must be signed for it.
+func (b *BlockChain) GetCurStakeHolder(txHash *hash.Hash) ([]byte, error) {
+	b.ChainRLock()
+	defer b.ChainRUnlock()
+	return b.getStakeHolder(txHash)
+}
+
+func (b *BlockChain) getStakeHolder(txHash *hash.Hash) ([]byte, error) {
+	state := b.GetStakeState(b.StakeTipID)
+	if state == nil {
+		return nil, fmt.Errorf("It doesn't exist: Stake (%s)\n", txHash)
+	}
+	se, ok := state.Stakes[*txHash]
+	if !ok {
+		return nil, fmt.Errorf("It doesn't exist: Stake (%s)\n", txHash)
+	}
+	return se.PkScript, nil
+}
+
+func (b *BlockChain) GetStakeTipHash() *hash.Hash {
+	if uint(b.StakeTipID) == meerdag.MaxId {
+		return nil
+	}
+	ib := b.bd.GetBlockById(uint(b.StakeTipID))
+	if ib == nil {
+		return nil
+	}
+	return ib.GetHash()
+}
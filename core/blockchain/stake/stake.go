@@ -2,4 +2,57 @@
 // Use of this source code is governed by an ISC
 // license that can be found in the LICENSE file.
 
+// Package stake implements the state of the stake pool, which is updated by
+// the stake transactions: stake_purchase locks MEER into the tracked stake
+// set, stake_dispose releases it after its maturity, stake_reserve reserves
+// MEER into the stake reserve and stakebase pays the rewards of the matured
+// stakes from the reserve.
 package stake
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/Qitmeer/qng/common/hash"
+)
+
+// StakeEntry is a stake locked into the stake pool by a stake_purchase.
+type StakeEntry struct {
+	// The hash of the stake_purchase transaction
+	TxHash hash.Hash
+	// The locked MEER amount
+	Amount int64
+	// The height of the block which purchased the stake
+	Height uint64
+	// The script of the stake holder, which receives the rewards and the
+	// disposed stake
+	PkScript []byte
+}
+
+// IsMature returns whether or not the stake is mature at the height.
+func (se *StakeEntry) IsMature(height uint64, maturity uint64) bool {
+	return height >= se.Height+maturity
+}
+
+type StakesMap map[hash.Hash]*StakeEntry
+
+// Sorted returns the stakes in the order of their transaction hashes.
+func (sm StakesMap) Sorted() []*StakeEntry {
+	result := make([]*StakeEntry, 0, len(sm))
+	for _, se := range sm {
+		result = append(result, se)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return bytes.Compare(result[i].TxHash[:], result[j].TxHash[:]) < 0
+	})
+	return result
+}
+
+// Total returns the total locked amount of the stakes.
+func (sm StakesMap) Total() int64 {
+	total := int64(0)
+	for _, se := range sm {
+		total += se.Amount
+	}
+	return total
+}
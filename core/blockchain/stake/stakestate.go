@@ -0,0 +1,277 @@
+/*
+ * Copyright (c) 2017-2020 The qitmeer developers
+ */
+
+package stake
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/consensus/forks"
+	"github.com/Qitmeer/qng/consensus/model"
+	"github.com/Qitmeer/qng/core/dbnamespace"
+	"github.com/Qitmeer/qng/core/serialization"
+	"github.com/Qitmeer/qng/core/types"
+)
+
+// StakeState specifies the stake pool of the current block.
+// the updates are the stake transactions of the block in the same order, they
+// are only used to update the state and aren't stored.
+type StakeState struct {
+	PrevStateID uint32
+	Reserve     int64
+	Stakes      StakesMap
+	Updates     []*StakeUpdate
+}
+
+// Serialize function will serialize the stake state into byte slice
+func (ss *StakeState) Serialize() ([]byte, error) {
+	if ss.Reserve < 0 {
+		return nil, fmt.Errorf("invalid stake reserve %d", ss.Reserve)
+	}
+	stakes := ss.Stakes.Sorted()
+	// total number of bytes to serialize
+	serializeSize := serialization.SerializeSizeVLQ(uint64(ss.PrevStateID))
+	serializeSize += serialization.SerializeSizeVLQ(uint64(ss.Reserve))
+	serializeSize += serialization.SerializeSizeVLQ(uint64(len(stakes)))
+	for _, se := range stakes {
+		// sanity check
+		if se.Amount <= 0 {
+			return nil, fmt.Errorf("invalid stake {%s, %d}", se.TxHash, se.Amount)
+		}
+		serializeSize += hash.HashSize
+		serializeSize += serialization.SerializeSizeVLQ(uint64(se.Amount))
+		serializeSize += serialization.SerializeSizeVLQ(se.Height)
+		serializeSize += serialization.SerializeSizeVLQ(uint64(len(se.PkScript)))
+		serializeSize += len(se.PkScript)
+	}
+	serialized := make([]byte, serializeSize)
+	offset := serialization.PutVLQ(serialized, uint64(ss.PrevStateID))
+	offset += serialization.PutVLQ(serialized[offset:], uint64(ss.Reserve))
+	offset += serialization.PutVLQ(serialized[offset:], uint64(len(stakes)))
+	for _, se := range stakes {
+		copy(serialized[offset:], se.TxHash[:])
+		offset += hash.HashSize
+		offset += serialization.PutVLQ(serialized[offset:], uint64(se.Amount))
+		offset += serialization.PutVLQ(serialized[offset:], se.Height)
+		offset += serialization.PutVLQ(serialized[offset:], uint64(len(se.PkScript)))
+		copy(serialized[offset:], se.PkScript)
+		offset += len(se.PkScript)
+	}
+	return serialized, nil
+}
+
+// Deserialize function will deserializes stake state from the byte slice
+func (ss *StakeState) Deserialize(data []byte) (int, error) {
+	prevStateID, offset := serialization.DeserializeVLQ(data)
+	if offset == 0 {
+		return offset, fmt.Errorf("unexpected end of data while reading prevStateID")
+	}
+	reserve, bytesRead := serialization.DeserializeVLQ(data[offset:])
+	if bytesRead == 0 {
+		return offset, fmt.Errorf("unexpected end of data while reading reserve")
+	}
+	offset += bytesRead
+
+	numOfStakes, bytesRead := serialization.DeserializeVLQ(data[offset:])
+	if bytesRead == 0 {
+		return offset, fmt.Errorf("unexpected end of data while reading number of stakes")
+	}
+	offset += bytesRead
+
+	stakes := StakesMap{}
+	for i := uint64(0); i < numOfStakes; i++ {
+		if len(data[offset:]) < hash.HashSize {
+			return offset, fmt.Errorf("unexpected end of data while reading tx hash at stakes{%d}", i)
+		}
+		se := &StakeEntry{}
+		copy(se.TxHash[:], data[offset:offset+hash.HashSize])
+		offset += hash.HashSize
+
+		amount, bytesRead := serialization.DeserializeVLQ(data[offset:])
+		if bytesRead == 0 {
+			return offset, fmt.Errorf("unexpected end of data while reading amount at stakes{%d}", i)
+		}
+		offset += bytesRead
+
+		height, bytesRead := serialization.DeserializeVLQ(data[offset:])
+		if bytesRead == 0 {
+			return offset, fmt.Errorf("unexpected end of data while reading height at stakes{%d}", i)
+		}
+		offset += bytesRead
+
+		scriptLen, bytesRead := serialization.DeserializeVLQ(data[offset:])
+		if bytesRead == 0 || uint64(len(data[offset+bytesRead:])) < scriptLen {
+			return offset, fmt.Errorf("unexpected end of data while reading script at stakes{%d}", i)
+		}
+		offset += bytesRead
+		se.PkScript = make([]byte, scriptLen)
+		copy(se.PkScript, data[offset:offset+int(scriptLen)])
+		offset += int(scriptLen)
+
+		se.Amount = int64(amount)
+		se.Height = height
+		stakes[se.TxHash] = se
+	}
+
+	ss.PrevStateID = uint32(prevStateID)
+	ss.Reserve = int64(reserve)
+	ss.Stakes = stakes
+	ss.Updates = nil
+	return offset, nil
+}
+
+// Update applies the updates to the stake state at the block height, the
+// stakes can be disposed and rewarded once they are mature.
+func (ss *StakeState) Update(height uint64, maturity uint64) error {
+	if ss.Stakes == nil {
+		ss.Stakes = StakesMap{}
+	}
+	hasStakebase := false
+	for _, su := range ss.Updates {
+		switch su.Typ {
+		case types.TxTypeStakePurchase:
+			if _, ok := ss.Stakes[su.TxHash]; ok {
+				return fmt.Errorf("Stake (%s) already exists\n", su.TxHash)
+			}
+			ss.Stakes[su.TxHash] = &StakeEntry{
+				TxHash:   su.TxHash,
+				Amount:   su.Amount,
+				Height:   height,
+				PkScript: su.PkScript,
+			}
+		case types.TxTypeStakeDispose:
+			se, ok := ss.Stakes[su.TxHash]
+			if !ok {
+				return fmt.Errorf("It doesn't exist: Stake (%s)\n", su.TxHash)
+			}
+			if !se.IsMature(height, maturity) {
+				return fmt.Errorf("Stake (%s) is immature: height %d, maturity height %d\n", su.TxHash, height, se.Height+maturity)
+			}
+			if se.Amount != su.Amount {
+				return fmt.Errorf("Stake (%s) dispose amount (%d) is not the locked amount (%d)\n", su.TxHash, su.Amount, se.Amount)
+			}
+			if !bytes.Equal(se.PkScript, su.PkScript) {
+				return fmt.Errorf("Stake (%s) must be disposed to the stake holder\n", su.TxHash)
+			}
+			delete(ss.Stakes, su.TxHash)
+		case types.TyTypeStakeReserve:
+			if ss.Reserve+su.Amount > types.MaxAmount {
+				return fmt.Errorf("Stake reserve (%d) exceeds the maximum (%v)\n", ss.Reserve+su.Amount, types.MaxAmount)
+			}
+			ss.Reserve += su.Amount
+		case types.TxTypeStakebase:
+			if hasStakebase {
+				return fmt.Errorf("Block can only have one stakebase\n")
+			}
+			hasStakebase = true
+			if su.Height != height {
+				return fmt.Errorf("Stakebase height (%d) is not the block height (%d)\n", su.Height, height)
+			}
+			rewards := ss.Rewards(height, maturity)
+			if len(rewards) != len(su.Rewards) {
+				return fmt.Errorf("Stakebase pays %d rewards, but expected %d\n", len(su.Rewards), len(rewards))
+			}
+			for i, r := range rewards {
+				if r.Amount.Value != su.Rewards[i].Amount.Value ||
+					!bytes.Equal(r.PkScript, su.Rewards[i].PkScript) {
+					return fmt.Errorf("Stakebase reward (%d) is invalid\n", i)
+				}
+				ss.Reserve -= r.Amount.Value
+			}
+		default:
+			return fmt.Errorf("Not supported:%s\n", su.Typ)
+		}
+	}
+	return nil
+}
+
+// Rewards returns the outputs of the stakebase at the block height, which pays
+// the stakebase reward to the matured stakes in proportion to their amounts.
+// The remainder of the division is kept in the reserve.
+func (ss *StakeState) Rewards(height uint64, maturity uint64) []*types.TxOutput {
+	reward := int64(forks.StakebaseReward)
+	if ss.Reserve < reward {
+		reward = ss.Reserve
+	}
+	if reward <= 0 {
+		return nil
+	}
+	mature := []*StakeEntry{}
+	total := int64(0)
+	for _, se := range ss.Stakes.Sorted() {
+		if !se.IsMature(height, maturity) {
+			continue
+		}
+		mature = append(mature, se)
+		total += se.Amount
+	}
+	if total <= 0 {
+		return nil
+	}
+	result := []*types.TxOutput{}
+	bigReward := big.NewInt(reward)
+	bigTotal := big.NewInt(total)
+	for _, se := range mature {
+		share := new(big.Int).Mul(bigReward, big.NewInt(se.Amount))
+		share.Div(share, bigTotal)
+		if share.Sign() <= 0 {
+			continue
+		}
+		result = append(result, types.NewTxOutput(types.Amount{Value: share.Int64(), Id: types.MEERA}, se.PkScript))
+	}
+	return result
+}
+
+// BuildStakebase builds the stakebase transaction of the block at the height,
+// it returns nil when there is no reward.
+func (ss *StakeState) BuildStakebase(height uint64, maturity uint64) *types.Transaction {
+	rewards := ss.Rewards(height, maturity)
+	if len(rewards) <= 0 {
+		return nil
+	}
+	var marker hash.Hash
+	dbnamespace.ByteOrder.PutUint64(marker[0:8], height)
+	tx := types.NewTransaction()
+	txIn := types.NewTxInput(types.NewOutPoint(&marker, types.SupperPrevOutIndex), nil)
+	txIn.Sequence = uint32(types.TxTypeStakebase)
+	tx.AddTxIn(txIn)
+	for _, r := range rewards {
+		tx.AddTxOut(r)
+	}
+	return tx
+}
+
+// DBPutStakeState put a stake state record into the stake state database.
+// the key is the provided block id
+func DBPutStakeState(db model.DataBase, bid uint, ss *StakeState) error {
+	// Serialize the current stake state.
+	serializedData, err := ss.Serialize()
+	if err != nil {
+		return err
+	}
+	return db.PutStakeState(bid, serializedData)
+}
+
+// DBFetchStakeState fetch the stake state record from the stake state database.
+// the key is the input block id.
+func DBFetchStakeState(db model.DataBase, bid uint) (*StakeState, error) {
+	v, err := db.GetStakeState(bid)
+	if err != nil {
+		return nil, err
+	}
+	if len(v) <= 0 {
+		return nil, fmt.Errorf("No stake state:%d", bid)
+	}
+	// deserialize the fetched stake state record
+	ss := StakeState{}
+	_, err = ss.Deserialize(v)
+	return &ss, err
+}
+
+func DBRemoveStakeState(db model.DataBase, id uint) error {
+	return db.DeleteStakeState(id)
+}
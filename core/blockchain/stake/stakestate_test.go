@@ -0,0 +1,172 @@
+/*
+ * Copyright (c) 2017-2020 The qitmeer developers
+ */
+
+package stake
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/core/types"
+)
+
+var (
+	holderA = []byte{0x76, 0xa9, 0x14, 0x01}
+	holderB = []byte{0x76, 0xa9, 0x14, 0x02}
+)
+
+func stakeTx(tt types.TxType, prev hash.Hash, outs ...*types.TxOutput) *types.Transaction {
+	tx := types.NewTransaction()
+	txIn := types.NewTxInput(types.NewOutPoint(&prev, types.SupperPrevOutIndex), nil)
+	txIn.Sequence = uint32(tt)
+	tx.AddTxIn(txIn)
+	if tt == types.TxTypeStakePurchase || tt == types.TyTypeStakeReserve {
+		var utxo hash.Hash
+		utxo[0] = 1
+		tx.AddTxIn(types.NewTxInput(types.NewOutPoint(&utxo, 0), nil))
+	}
+	for _, out := range outs {
+		tx.AddTxOut(out)
+	}
+	return tx
+}
+
+func meer(v int64, pks []byte) *types.TxOutput {
+	return types.NewTxOutput(types.Amount{Value: v, Id: types.MEERA}, pks)
+}
+
+func applyTx(t *testing.T, ss *StakeState, tx *types.Transaction, height uint64) error {
+	update, err := NewUpdateFromTx(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := update.CheckSanity(); err != nil {
+		t.Fatal(err)
+	}
+	ss.Updates = []*StakeUpdate{update}
+	return ss.Update(height, 10)
+}
+
+func TestStakeStateSerialization(t *testing.T) {
+	ss := &StakeState{PrevStateID: 12, Reserve: 5e8, Stakes: StakesMap{}}
+	for i, pks := range [][]byte{holderA, holderB} {
+		se := &StakeEntry{Amount: int64(i+1) * 1e8, Height: uint64(i + 3), PkScript: pks}
+		se.TxHash[0] = byte(i + 1)
+		ss.Stakes[se.TxHash] = se
+	}
+	data, err := ss.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &StakeState{}
+	n, err := got.Deserialize(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(data) {
+		t.Fatalf("deserialize read %d bytes, expected %d", n, len(data))
+	}
+	if !reflect.DeepEqual(ss, got) {
+		t.Fatalf("stake state mismatch: got %v, expected %v", got, ss)
+	}
+	if _, err := got.Deserialize(data[:len(data)-1]); err == nil {
+		t.Fatal("expected error for truncated data")
+	}
+}
+
+func TestStakeStateUpdate(t *testing.T) {
+	ss := &StakeState{}
+	purchase := stakeTx(types.TxTypeStakePurchase, hash.ZeroHash, meer(3e8, holderA), meer(1e8, holderB))
+	if !types.IsStakeLockTx(purchase) {
+		t.Fatal("purchase should be a stake lock tx")
+	}
+	if err := applyTx(t, ss, purchase, 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyTx(t, ss, purchase, 6); err == nil {
+		t.Fatal("expected error for duplicate stake")
+	}
+	if ss.Stakes.Total() != 3e8 {
+		t.Fatalf("total staked %d, expected %d", ss.Stakes.Total(), int64(3e8))
+	}
+
+	dispose := stakeTx(types.TxTypeStakeDispose, purchase.TxHash(), meer(3e8, holderA))
+	if err := applyTx(t, ss, dispose, 14); err == nil {
+		t.Fatal("expected error for immature stake")
+	}
+	wrongHolder := stakeTx(types.TxTypeStakeDispose, purchase.TxHash(), meer(3e8, holderB))
+	if err := applyTx(t, ss, wrongHolder, 15); err == nil {
+		t.Fatal("expected error for wrong stake holder")
+	}
+	wrongAmount := stakeTx(types.TxTypeStakeDispose, purchase.TxHash(), meer(2e8, holderA))
+	if err := applyTx(t, ss, wrongAmount, 15); err == nil {
+		t.Fatal("expected error for wrong dispose amount")
+	}
+	if err := applyTx(t, ss, dispose, 15); err != nil {
+		t.Fatal(err)
+	}
+	if len(ss.Stakes) != 0 {
+		t.Fatalf("stake should be released, still have %d", len(ss.Stakes))
+	}
+}
+
+func TestStakeStateRewards(t *testing.T) {
+	ss := &StakeState{}
+	if err := applyTx(t, ss, stakeTx(types.TyTypeStakeReserve, hash.ZeroHash, meer(15e7, holderB)), 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyTx(t, ss, stakeTx(types.TxTypeStakePurchase, hash.ZeroHash, meer(1e8, holderA)), 1); err != nil {
+		t.Fatal(err)
+	}
+	var other hash.Hash
+	other[0] = 7
+	if err := applyTx(t, ss, stakeTx(types.TxTypeStakePurchase, other, meer(3e8, holderB)), 2); err != nil {
+		t.Fatal(err)
+	}
+	if ss.BuildStakebase(10, 10) != nil {
+		t.Fatal("immature stakes shouldn't be rewarded")
+	}
+
+	// Only the first stake is mature, so it takes the whole reward.
+	sb := ss.BuildStakebase(11, 10)
+	if sb == nil || !types.IsStakebaseTx(sb) {
+		t.Fatal("expected a stakebase")
+	}
+	if len(sb.TxOut) != 1 || sb.TxOut[0].Amount.Value != 1e8 {
+		t.Fatalf("unexpected stakebase rewards %v", sb.TxOut)
+	}
+	if err := applyTx(t, ss, sb, 12); err == nil {
+		t.Fatal("expected error for wrong stakebase height")
+	}
+	if err := applyTx(t, ss, sb, 11); err != nil {
+		t.Fatal(err)
+	}
+	if ss.Reserve != 5e7 {
+		t.Fatalf("reserve %d, expected %d", ss.Reserve, int64(5e7))
+	}
+
+	// The remaining reserve is split between both matured stakes.
+	rewards := ss.Rewards(12, 10)
+	if len(rewards) != 2 {
+		t.Fatalf("expected 2 rewards, got %d", len(rewards))
+	}
+	total := int64(0)
+	for _, r := range rewards {
+		total += r.Amount.Value
+		switch string(r.PkScript) {
+		case string(holderA):
+			if r.Amount.Value != 125e5 {
+				t.Fatalf("holder A reward %d", r.Amount.Value)
+			}
+		case string(holderB):
+			if r.Amount.Value != 375e5 {
+				t.Fatalf("holder B reward %d", r.Amount.Value)
+			}
+		}
+	}
+	if total != 5e7 {
+		t.Fatalf("total reward %d, expected %d", total, int64(5e7))
+	}
+}
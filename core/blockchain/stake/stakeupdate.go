@@ -0,0 +1,100 @@
+/*
+ * Copyright (c) 2017-2020 The qitmeer developers
+ */
+
+package stake
+
+import (
+	"fmt"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/core/dbnamespace"
+	"github.com/Qitmeer/qng/core/types"
+)
+
+// StakeUpdate is the update of the stake state from a stake transaction.
+type StakeUpdate struct {
+	Typ types.TxType
+	// The hash of the stake transaction, or the hash of the disposed
+	// stake_purchase for the stake_dispose
+	TxHash hash.Hash
+	// The locked, released or reserved amount, or the total reward of the
+	// stakebase
+	Amount int64
+	// The script of the stake holder for the stake_purchase and the
+	// stake_dispose
+	PkScript []byte
+	// The block height of the stakebase
+	Height uint64
+	// The rewards paid by the stakebase
+	Rewards []*types.TxOutput
+}
+
+func (su *StakeUpdate) GetType() types.TxType {
+	return su.Typ
+}
+
+func (su *StakeUpdate) CheckSanity() error {
+	if su.Amount <= 0 {
+		return fmt.Errorf("invalid stake update : wrong amount : %v", su.Amount)
+	}
+	if su.Amount > types.MaxAmount {
+		return fmt.Errorf("Stake amount value of %v is higher than max allowed value of %v\n", su.Amount, types.MaxAmount)
+	}
+	switch su.Typ {
+	case types.TxTypeStakePurchase, types.TxTypeStakeDispose:
+		if len(su.PkScript) <= 0 {
+			return fmt.Errorf("invalid stake update : no script of stake holder")
+		}
+	case types.TxTypeStakebase:
+		if len(su.Rewards) <= 0 {
+			return fmt.Errorf("invalid stake update : no rewards")
+		}
+	}
+	return nil
+}
+
+func NewUpdateFromTx(tx *types.Transaction) (*StakeUpdate, error) {
+	if !types.IsStakeTx(tx) {
+		return nil, fmt.Errorf("Not supported:%s\n", types.DetermineTxType(tx))
+	}
+	total := int64(0)
+	for idx, out := range tx.TxOut {
+		if !out.Amount.Id.IsBase() {
+			return nil, fmt.Errorf("Transaction(%s) output(%d) must be MEER\n", tx.TxHash(), idx)
+		}
+		if out.Amount.Value <= 0 {
+			return nil, fmt.Errorf("Transaction output value is less than or equal to zero\n")
+		}
+		if out.Amount.Value > types.MaxAmount {
+			return nil, fmt.Errorf("transaction output value of %v is "+
+				"higher than max allowed value of %v", out.Amount.Value,
+				types.MaxAmount)
+		}
+		total += out.Amount.Value
+	}
+	if total > types.MaxAmount {
+		return nil, fmt.Errorf("Total amount value of %v is higher than max allowed value of %v\n", total, types.MaxAmount)
+	}
+
+	su := &StakeUpdate{
+		Typ:    types.DetermineTxType(tx),
+		TxHash: tx.TxHash(),
+	}
+	switch su.Typ {
+	case types.TxTypeStakePurchase:
+		su.Amount = tx.TxOut[0].Amount.Value
+		su.PkScript = tx.TxOut[0].PkScript
+	case types.TyTypeStakeReserve:
+		su.Amount = tx.TxOut[0].Amount.Value
+	case types.TxTypeStakeDispose:
+		su.TxHash = tx.TxIn[0].PreviousOut.Hash
+		su.Amount = tx.TxOut[0].Amount.Value
+		su.PkScript = tx.TxOut[0].PkScript
+	case types.TxTypeStakebase:
+		su.Amount = total
+		su.Height = dbnamespace.ByteOrder.Uint64(tx.TxIn[0].PreviousOut.Hash[0:8])
+		su.Rewards = tx.TxOut
+	}
+	return su, nil
+}
@@ -0,0 +1,322 @@
+// Copyright (c) 2017-2018 The qitmeer developers
+// Copyright (c) 2016-2017 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/consensus/model"
+	"github.com/Qitmeer/qng/meerdag"
+)
+
+// ThresholdState define the various threshold states used when voting on
+// consensus changes.
+type ThresholdState byte
+
+// These constants are used to identify specific threshold states.
+const (
+	// ThresholdDefined is the first state for each deployment and is the
+	// state for the genesis block has by definition for all deployments.
+	ThresholdDefined ThresholdState = iota
+
+	// ThresholdStarted is the state for a deployment once its start height
+	// has been reached.
+	ThresholdStarted
+
+	// ThresholdLockedIn is the state for a deployment during the retarget
+	// window which follows the window in which the deployment was
+	// started and the number of blocks that signalled the rule change
+	// reached the activation threshold.
+	ThresholdLockedIn
+
+	// ThresholdActive is the state for a deployment for all blocks after a
+	// retarget window in which the deployment was in the ThresholdLockedIn
+	// state.
+	ThresholdActive
+
+	// ThresholdFailed is the state for a deployment once its timeout height
+	// has been reached and it did not reach the ThresholdLockedIn state.
+	ThresholdFailed
+
+	// numThresholdsStates is the maximum number of threshold states used in
+	// tests.
+	numThresholdsStates
+)
+
+// thresholdStateStrings is a map of ThresholdState values back to their
+// constant names for pretty printing.
+var thresholdStateStrings = map[ThresholdState]string{
+	ThresholdDefined:  "defined",
+	ThresholdStarted:  "started",
+	ThresholdLockedIn: "lockedin",
+	ThresholdActive:   "active",
+	ThresholdFailed:   "failed",
+}
+
+// String returns the ThresholdState as a human-readable name.
+func (t ThresholdState) String() string {
+	if s := thresholdStateStrings[t]; s != "" {
+		return s
+	}
+	return fmt.Sprintf("Unknown ThresholdState (%d)", int(t))
+}
+
+// thresholdConditionChecker provides a generic interface that is invoked to
+// determine when a consensus rule change threshold should be changed.
+type thresholdConditionChecker interface {
+	// BeginHeight returns the main height at which the condition starts
+	// being checked.
+	BeginHeight() uint64
+
+	// EndHeight returns the main height at which the condition fails if it
+	// has not been locked in yet.
+	EndHeight() uint64
+
+	// RuleChangeActivationThreshold is the number of blocks for which the
+	// condition must be true in order to lock in a rule change.
+	RuleChangeActivationThreshold() uint32
+
+	// MinerConfirmationWindow is the number of blocks in each threshold
+	// state retarget window.
+	MinerConfirmationWindow() uint32
+
+	// Condition returns whether or not the rule change activation condition
+	// has been met.  This typically involves checking whether or not the
+	// bit associated with the condition is set, but can be more complex as
+	// needed.
+	Condition(version uint32) bool
+}
+
+// thresholdStateCache provides a type to cache the threshold states of each
+// threshold window for a set of IDs.  The states are persisted in the
+// database, keyed by the hash of the last main chain block of the window, so
+// they survive restarts and the pruning of old block bodies.
+type thresholdStateCache struct {
+	lock    sync.Mutex
+	id      uint32
+	entries map[hash.Hash]ThresholdState
+}
+
+// key returns the database key of a cached threshold state.
+func (c *thresholdStateCache) key(h *hash.Hash) []byte {
+	key := make([]byte, 0, len(thresholdStatePrefix)+1+hash.HashSize)
+	key = append(key, thresholdStatePrefix...)
+	key = append(key, byte(c.id))
+	return append(key, h[:]...)
+}
+
+// Lookup returns the threshold state associated with the given hash along with
+// a boolean that indicates whether or not it is valid.
+func (c *thresholdStateCache) Lookup(b *BlockChain, h *hash.Hash) (ThresholdState, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	state, ok := c.entries[*h]
+	if ok {
+		return state, true
+	}
+	data, err := b.DB().Get(c.key(h))
+	if err != nil || len(data) != 1 || ThresholdState(data[0]) >= numThresholdsStates {
+		return ThresholdFailed, false
+	}
+	state = ThresholdState(data[0])
+	c.entries[*h] = state
+	return state, true
+}
+
+// Update updates the cache to contain the provided hash to threshold state
+// mapping.
+func (c *thresholdStateCache) Update(b *BlockChain, h *hash.Hash, state ThresholdState) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.entries[*h] = state
+	err := b.DB().Put(c.key(h), []byte{byte(state)})
+	if err != nil {
+		log.Warn(fmt.Sprintf("Failed to store threshold state:%v", err))
+	}
+}
+
+// thresholdStatePrefix is the database key prefix of the threshold states.
+var thresholdStatePrefix = []byte("thresholdstate")
+
+// newThresholdCaches returns a new array of caches to be used when calculating
+// threshold states.
+func newThresholdCaches(numCaches uint32) []thresholdStateCache {
+	caches := make([]thresholdStateCache, numCaches)
+	for i := 0; i < len(caches); i++ {
+		caches[i] = thresholdStateCache{
+			id:      uint32(i),
+			entries: make(map[hash.Hash]ThresholdState),
+		}
+	}
+	return caches
+}
+
+// thresholdState returns the current rule change threshold state for the block
+// AFTER the given main chain block based on the passed deployment ID.  The
+// cache is used to ensure the threshold states for previous windows are only
+// calculated once.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) thresholdState(prevNode meerdag.IBlock, checker thresholdConditionChecker, cache *thresholdStateCache) (ThresholdState, error) {
+	// The threshold state for the window that contains the genesis block is
+	// defined by definition.
+	confirmationWindow := uint64(checker.MinerConfirmationWindow())
+	if confirmationWindow <= 0 {
+		return ThresholdFailed, fmt.Errorf("The miner confirmation window is zero")
+	}
+	if prevNode == nil || uint64(prevNode.GetHeight())+1 < confirmationWindow {
+		return ThresholdDefined, nil
+	}
+
+	// Get the ancestor that is the last block of the previous confirmation
+	// window in order to get its threshold state.  This can be done because
+	// the state is the same for all blocks within a given window.
+	prevHeight := uint64(prevNode.GetHeight())
+	prevNode = b.bd.GetMainAncestor(prevNode, int64(prevHeight-(prevHeight+1)%confirmationWindow))
+
+	// Iterate backwards through each of the previous confirmation windows
+	// to find the most recently cached threshold state.
+	var neededStates []meerdag.IBlock
+	for prevNode != nil {
+		// Nothing more to do if the state of the block is already
+		// cached.
+		if _, ok := cache.Lookup(b, prevNode.GetHash()); ok {
+			break
+		}
+
+		// The state is simply defined if the start height hasn't been
+		// reached yet.
+		if uint64(prevNode.GetHeight())+1 < checker.BeginHeight() {
+			cache.Update(b, prevNode.GetHash(), ThresholdDefined)
+			break
+		}
+
+		// Add this node to the list of nodes that need the state
+		// calculated and cached.
+		neededStates = append(neededStates, prevNode)
+
+		// Get the ancestor that is the last block of the previous
+		// confirmation window.
+		prevNode = b.bd.RelativeMainAncestor(prevNode, int64(confirmationWindow))
+	}
+
+	// Start with the threshold state for the most recent confirmation
+	// window that has a cached state.
+	state := ThresholdDefined
+	if prevNode != nil {
+		var ok bool
+		state, ok = cache.Lookup(b, prevNode.GetHash())
+		if !ok {
+			return ThresholdFailed, model.AssertError(fmt.Sprintf("thresholdState: cache lookup failed for %v", prevNode.GetHash()))
+		}
+	}
+
+	// Since each threshold state depends on the state of the previous
+	// window, iterate starting from the oldest unknown window.
+	for neededNum := len(neededStates) - 1; neededNum >= 0; neededNum-- {
+		prevNode := neededStates[neededNum]
+		// The main height of the first block of the next window.
+		height := uint64(prevNode.GetHeight()) + 1
+
+		var err error
+		state, err = nextThresholdState(state, height, checker, func() (uint32, error) {
+			// Iterate backwards through the confirmation window to
+			// count all of the votes in it.
+			return b.countThresholdVotes(prevNode, checker, confirmationWindow)
+		})
+		if err != nil {
+			return ThresholdFailed, err
+		}
+
+		// Update the cache to avoid recalculating the state in the
+		// future.
+		cache.Update(b, prevNode.GetHash(), state)
+	}
+
+	return state, nil
+}
+
+// nextThresholdState returns the threshold state of the window starting at
+// the given main height, based on the state of the previous window.  The votes
+// of the previous window are only counted when the deployment is started.
+func nextThresholdState(state ThresholdState, height uint64, checker thresholdConditionChecker, countVotes func() (uint32, error)) (ThresholdState, error) {
+	switch state {
+	case ThresholdDefined:
+		// The deployment of the rule change fails if it expires
+		// before it is accepted and locked in.
+		if height >= checker.EndHeight() {
+			state = ThresholdFailed
+			break
+		}
+
+		// The state for the rule moves to the started state
+		// once its start height has been reached (and it hasn't
+		// already expired per the above).
+		if height >= checker.BeginHeight() {
+			state = ThresholdStarted
+		}
+
+	case ThresholdStarted:
+		// The deployment of the rule change fails if it expires
+		// before it is accepted and locked in.
+		if height >= checker.EndHeight() {
+			state = ThresholdFailed
+			break
+		}
+
+		// At this point, the rule change is still being voted
+		// on by the miners, so count all of the votes in the
+		// previous confirmation window.
+		count, err := countVotes()
+		if err != nil {
+			return ThresholdFailed, err
+		}
+
+		// The state is locked in if the number of blocks in the
+		// period that voted for the rule change meets the
+		// activation threshold.
+		if count >= checker.RuleChangeActivationThreshold() {
+			state = ThresholdLockedIn
+		}
+
+	case ThresholdLockedIn:
+		// The new rule becomes active when its previous state
+		// was locked in.
+		state = ThresholdActive
+
+	// Nothing to do if the previous state is active or failed since
+	// they are both terminal states.
+	case ThresholdActive:
+	case ThresholdFailed:
+	}
+
+	return state, nil
+}
+
+// countThresholdVotes returns the number of main chain blocks, walking back at
+// most num blocks from the given one, whose version meets the condition.
+func (b *BlockChain) countThresholdVotes(node meerdag.IBlock, checker thresholdConditionChecker, num uint64) (uint32, error) {
+	var count uint32
+	countNode := node
+	for i := uint64(0); i < num && countNode != nil; i++ {
+		header, err := b.fetchHeaderByHash(countNode.GetHash())
+		if err != nil {
+			return 0, err
+		}
+		if checker.Condition(header.Version) {
+			count++
+		}
+		if !countNode.HasParents() {
+			break
+		}
+		countNode = b.bd.GetBlockById(countNode.GetMainParent())
+	}
+	return count, nil
+}
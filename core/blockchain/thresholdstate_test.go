@@ -0,0 +1,120 @@
+// Copyright (c) 2017-2018 The qitmeer developers
+// Copyright (c) 2016-2017 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"testing"
+
+	"github.com/Qitmeer/qng/params"
+)
+
+// TestThresholdStateStringer tests the stringized output for the
+// ThresholdState type.
+func TestThresholdStateStringer(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in   ThresholdState
+		want string
+	}{
+		{ThresholdDefined, "defined"},
+		{ThresholdStarted, "started"},
+		{ThresholdLockedIn, "lockedin"},
+		{ThresholdActive, "active"},
+		{ThresholdFailed, "failed"},
+		{0xff, "Unknown ThresholdState (255)"},
+	}
+
+	// Detect additional threshold states that don't have the stringer added.
+	if len(tests)-1 != int(numThresholdsStates) {
+		t.Errorf("It appears a threshold state was added without " +
+			"adding an associated stringer test")
+	}
+
+	for i, test := range tests {
+		result := test.in.String()
+		if result != test.want {
+			t.Errorf("String #%d\n got: %s want: %s", i, result,
+				test.want)
+		}
+	}
+}
+
+// TestDeploymentCondition ensures only the versions using the version bits
+// scheme and setting the deployment bit signal for it.
+func TestDeploymentCondition(t *testing.T) {
+	t.Parallel()
+
+	checker := deploymentChecker{deployment: &params.ConsensusDeployment{BitNumber: 28}}
+	tests := []struct {
+		version uint32
+		want    bool
+	}{
+		{VBTopBits, false},
+		{VBTopBits | 1<<28, true},
+		{VBTopBits | 1<<27, false},
+		{0x40000000 | 1<<28, false},
+		{1 << 28, false},
+	}
+	for i, test := range tests {
+		if got := checker.Condition(test.version); got != test.want {
+			t.Errorf("Condition #%d (%x): got %v want %v", i, test.version, got, test.want)
+		}
+	}
+}
+
+// testChecker is a thresholdConditionChecker with fixed parameters.
+type testChecker struct {
+	begin, end uint64
+}
+
+func (c testChecker) BeginHeight() uint64                   { return c.begin }
+func (c testChecker) EndHeight() uint64                     { return c.end }
+func (c testChecker) RuleChangeActivationThreshold() uint32 { return 3 }
+func (c testChecker) MinerConfirmationWindow() uint32       { return 4 }
+func (c testChecker) Condition(version uint32) bool         { return version != 0 }
+
+// TestNextThresholdState walks the windows of a deployment through the
+// threshold states.
+func TestNextThresholdState(t *testing.T) {
+	t.Parallel()
+
+	checker := testChecker{begin: 8, end: 24}
+	tests := []struct {
+		name   string
+		state  ThresholdState
+		height uint64
+		votes  uint32
+		want   ThresholdState
+	}{
+		{"before start", ThresholdDefined, 4, 4, ThresholdDefined},
+		{"start", ThresholdDefined, 8, 0, ThresholdStarted},
+		{"timeout before start", ThresholdDefined, 24, 4, ThresholdFailed},
+		{"not enough votes", ThresholdStarted, 12, 2, ThresholdStarted},
+		{"lock in", ThresholdStarted, 12, 3, ThresholdLockedIn},
+		{"timeout", ThresholdStarted, 24, 4, ThresholdFailed},
+		{"activate", ThresholdLockedIn, 16, 0, ThresholdActive},
+		{"locked in after timeout", ThresholdLockedIn, 28, 0, ThresholdActive},
+		{"active is terminal", ThresholdActive, 28, 0, ThresholdActive},
+		{"failed is terminal", ThresholdFailed, 28, 4, ThresholdFailed},
+	}
+	for _, test := range tests {
+		counted := false
+		state, err := nextThresholdState(test.state, test.height, checker, func() (uint32, error) {
+			counted = true
+			return test.votes, nil
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if state != test.want {
+			t.Errorf("%s: got %v want %v", test.name, state, test.want)
+		}
+		if counted && test.state != ThresholdStarted {
+			t.Errorf("%s: votes were counted in the %v state", test.name, test.state)
+		}
+	}
+}
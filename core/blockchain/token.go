@@ -11,6 +11,49 @@ import (
 )
 
 func (b *BlockChain) CheckTokenTransactionInputs(tx *types.Tx, utxoView *utxo.UtxoViewpoint) error {
+	msgTx := tx.Transaction()
+	totalAtomIn, err := b.checkMeerTransactionInputs(tx, utxoView)
+	if err != nil {
+		return err
+	}
+
+	lockMeer := int64(dbnamespace.ByteOrder.Uint64(msgTx.TxIn[0].PreviousOut.Hash[0:8]))
+	if totalAtomIn != lockMeer {
+		return fmt.Errorf("Utxo (%d) and input amount (%d) are inconsistent\n", totalAtomIn, lockMeer)
+	}
+
+	totalAtomOut := int64(0)
+	state := b.GetTokenState(b.TokenTipID)
+	if state == nil {
+		return fmt.Errorf("Token state error\n")
+	}
+	coinId := msgTx.TxOut[0].Amount.Id
+	tt, ok := state.Types[coinId]
+	if !ok {
+		return fmt.Errorf("It doesn't exist: Coin id (%d)\n", coinId)
+	}
+	tokenAmount := int64(0)
+	tb, ok := state.Balances[coinId]
+	if ok {
+		tokenAmount = tb.Balance
+	}
+
+	for idx, txOut := range tx.Transaction().TxOut {
+		if txOut.Amount.Id != coinId {
+			return fmt.Errorf("Transaction(%s) output(%d) coin id is invalid\n", tx.Hash(), idx)
+		}
+		totalAtomOut += txOut.Amount.Value
+	}
+	if totalAtomOut+tokenAmount > int64(tt.UpLimit) {
+		return fmt.Errorf("Token transaction mint (%d) exceeds the maximum (%d)\n", totalAtomOut, tt.UpLimit)
+	}
+
+	return nil
+}
+
+// checkMeerTransactionInputs checks the MEER inputs of the transaction whose
+// first input is not a real output, and returns the total input amount.
+func (b *BlockChain) checkMeerTransactionInputs(tx *types.Tx, utxoView *utxo.UtxoViewpoint) (int64, error) {
 	msgTx := tx.Transaction()
 	totalAtomIn := int64(0)
 	targets := []uint{}
@@ -25,30 +68,30 @@ func (b *BlockChain) CheckTokenTransactionInputs(tx *types.Tx, utxoView *utxo.Ut
 				"transaction %s:%d either does not exist or "+
 				"has already been spent", txIn.PreviousOut,
 				tx.Hash(), idx)
-			return ruleError(ErrMissingTxOut, str)
+			return 0, ruleError(ErrMissingTxOut, str)
 		}
 		if !utxoEntry.Amount().Id.IsBase() {
-			return fmt.Errorf("Token transaction(%s) input (%s %d) must be MEERA\n", tx.Hash(), txIn.PreviousOut.Hash, txIn.PreviousOut.OutIndex)
+			return 0, fmt.Errorf("Transaction(%s) input (%s %d) must be MEERA\n", tx.Hash(), txIn.PreviousOut.Hash, txIn.PreviousOut.OutIndex)
 		}
 
 		originTxAtom := utxoEntry.Amount()
 		if originTxAtom.Value < 0 {
 			str := fmt.Sprintf("transaction output has negative "+
 				"value of %v", originTxAtom)
-			return ruleError(ErrInvalidTxOutValue, str)
+			return 0, ruleError(ErrInvalidTxOutValue, str)
 		}
 		if originTxAtom.Value > types.MaxAmount {
 			str := fmt.Sprintf("transaction output value of %v is "+
 				"higher than max allowed value of %v",
 				originTxAtom, types.MaxAmount)
-			return ruleError(ErrInvalidTxOutValue, str)
+			return 0, ruleError(ErrInvalidTxOutValue, str)
 		}
 
 		if utxoEntry.IsCoinBase() {
 			ubhIB := b.bd.GetBlock(utxoEntry.BlockHash())
 			if ubhIB == nil {
 				str := fmt.Sprintf("utxoEntry blockhash error:%s", utxoEntry.BlockHash())
-				return ruleError(ErrNoViewpoint, str)
+				return 0, ruleError(ErrNoViewpoint, str)
 			}
 			targets = append(targets, ubhIB.GetID())
 			if !utxoEntry.BlockHash().IsEqual(b.params.GenesisHash) {
@@ -65,11 +108,6 @@ func (b *BlockChain) CheckTokenTransactionInputs(tx *types.Tx, utxoView *utxo.Ut
 		totalAtomIn += originTxAtom.Value
 	}
 
-	lockMeer := int64(dbnamespace.ByteOrder.Uint64(msgTx.TxIn[0].PreviousOut.Hash[0:8]))
-	if totalAtomIn != lockMeer {
-		return fmt.Errorf("Utxo (%d) and input amount (%d) are inconsistent\n", totalAtomIn, lockMeer)
-	}
-
 	//
 	if len(targets) > 0 {
 		viewpoints := []uint{}
@@ -81,42 +119,14 @@ func (b *BlockChain) CheckTokenTransactionInputs(tx *types.Tx, utxoView *utxo.Ut
 		}
 		if len(viewpoints) == 0 {
 			str := fmt.Sprintf("transaction %s has no viewpoints", tx.Hash())
-			return ruleError(ErrNoViewpoint, str)
+			return 0, ruleError(ErrNoViewpoint, str)
 		}
 		err := b.bd.CheckBlueAndMatureMT(targets, viewpoints, uint(b.params.CoinbaseMaturity))
 		if err != nil {
-			return ruleError(ErrImmatureSpend, err.Error())
-		}
-	}
-	//
-
-	totalAtomOut := int64(0)
-	state := b.GetTokenState(b.TokenTipID)
-	if state == nil {
-		return fmt.Errorf("Token state error\n")
-	}
-	coinId := msgTx.TxOut[0].Amount.Id
-	tt, ok := state.Types[coinId]
-	if !ok {
-		return fmt.Errorf("It doesn't exist: Coin id (%d)\n", coinId)
-	}
-	tokenAmount := int64(0)
-	tb, ok := state.Balances[coinId]
-	if ok {
-		tokenAmount = tb.Balance
-	}
-
-	for idx, txOut := range tx.Transaction().TxOut {
-		if txOut.Amount.Id != coinId {
-			return fmt.Errorf("Transaction(%s) output(%d) coin id is invalid\n", tx.Hash(), idx)
+			return 0, ruleError(ErrImmatureSpend, err.Error())
 		}
-		totalAtomOut += txOut.Amount.Value
 	}
-	if totalAtomOut+tokenAmount > int64(tt.UpLimit) {
-		return fmt.Errorf("Token transaction mint (%d) exceeds the maximum (%d)\n", totalAtomOut, tt.UpLimit)
-	}
-
-	return nil
+	return totalAtomIn, nil
 }
 
 func (b *BlockChain) updateTokenState(node meerdag.IBlock, block *types.SerializedBlock, rollback bool) error {
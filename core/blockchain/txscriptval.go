@@ -195,6 +195,11 @@ func ValidateTransactionScripts(tx *types.Tx, utxoView *utxo.UtxoViewpoint, flag
 		if txIn.PreviousOut.OutIndex == math.MaxUint32 {
 			continue
 		}
+		// The first input of the stake transactions isn't signed, but the
+		// stake holder signs the one of the stake_dispose.
+		if txInIdx == 0 && types.IsStakeTx(tx.Tx) && !types.IsStakeDisposeTx(tx.Tx) {
+			continue
+		}
 		if forks.IsVaildEVMUTXOUnlockTx(tx.Tx, txIn, height) {
 			txIn.AmountIn.Value = types.MeerEVMForkInput
 		}
@@ -259,6 +264,11 @@ func (b *BlockChain) checkBlockScripts(block *types.SerializedBlock, utxoView *u
 			if txIn.PreviousOut.OutIndex == math.MaxUint32 {
 				continue
 			}
+			// The first input of the stake transactions isn't signed, but the
+			// stake holder signs the one of the stake_dispose.
+			if txInIdx == 0 && types.IsStakeTx(tx.Tx) && !types.IsStakeDisposeTx(tx.Tx) {
+				continue
+			}
 			if forks.IsVaildEVMUTXOUnlockTx(tx.Tx, txIn, height) {
 				txIn.AmountIn.Value = types.MeerEVMForkInput
 			}
@@ -284,6 +294,12 @@ func (b *BlockChain) checkBlockScripts(block *types.SerializedBlock, utxoView *u
 			} else {
 				utxoView.AddTokenTxOut(tx.Tx.TxIn[0].PreviousOut, nil)
 			}
+		} else if types.IsStakeDisposeTx(tx.Tx) {
+			pks, err := b.getStakeHolder(&tx.Tx.TxIn[0].PreviousOut.Hash)
+			if err != nil {
+				return err
+			}
+			utxoView.AddTokenTxOut(tx.Tx.TxIn[0].PreviousOut, pks)
 		}
 	}
 
@@ -70,7 +70,7 @@ func (bc *BlockChain) fetchInputUtxos(block *types.SerializedBlock, view *utxo.U
 		}
 
 		for txInIdx, txIn := range tx.Transaction().TxIn {
-			if txInIdx == 0 && types.IsTokenMintTx(tx.Tx) {
+			if txInIdx == 0 && (types.IsTokenMintTx(tx.Tx) || types.IsStakeTx(tx.Tx)) {
 				continue
 			}
 			// It is acceptable for a transaction input to reference
@@ -242,8 +242,11 @@ func (b *BlockChain) dbPutUtxoViewByBlock(block *types.SerializedBlock) error {
 	return b.dbUpdateUtxoView(view)
 }
 
+// dbUpdateUtxoView writes the modified entries of view to the UTXO set, and
+// rolls the MuHash of UTXO set by the replaced and the written entries.
 func (b *BlockChain) dbUpdateUtxoView(view *utxo.UtxoViewpoint) error {
 	opts := []*common.UtxoOpt{}
+	utxoMuHash := b.utxoMuHash.Clone()
 	for op, en := range view.Entries() {
 		outpoint := op
 		entry := en
@@ -251,9 +254,17 @@ func (b *BlockChain) dbUpdateUtxoView(view *utxo.UtxoViewpoint) error {
 		if entry == nil || !entry.IsModified() {
 			continue
 		}
+		key := utxo.OutpointKeyNoPool(outpoint)
+		old, err := b.DB().GetUtxo(key)
+		if err != nil {
+			return err
+		}
+		if len(old) > 0 {
+			utxoMuHash.Remove(utxoSetElement(key, old))
+		}
 		// Remove the utxo entry if it is spent.
 		if entry.IsSpent() {
-			opts = append(opts, &common.UtxoOpt{Add: false, Key: utxo.OutpointKeyNoPool(outpoint)})
+			opts = append(opts, &common.UtxoOpt{Add: false, Key: key})
 
 			if b.Acct != nil {
 				err := b.Acct.Apply(false, &outpoint, entry)
@@ -269,7 +280,8 @@ func (b *BlockChain) dbUpdateUtxoView(view *utxo.UtxoViewpoint) error {
 		if err != nil {
 			return err
 		}
-		opts = append(opts, &common.UtxoOpt{Add: true, Key: utxo.OutpointKeyNoPool(outpoint), Data: serialized})
+		opts = append(opts, &common.UtxoOpt{Add: true, Key: key, Data: serialized})
+		utxoMuHash.Add(utxoSetElement(key, serialized))
 		if b.Acct != nil {
 			err = b.Acct.Apply(true, &outpoint, entry)
 			if err != nil {
@@ -277,5 +289,10 @@ func (b *BlockChain) dbUpdateUtxoView(view *utxo.UtxoViewpoint) error {
 			}
 		}
 	}
-	return b.DB().UpdateUtxo(opts)
+	err := b.DB().UpdateUtxo(opts)
+	if err != nil {
+		return err
+	}
+	b.utxoMuHash = utxoMuHash
+	return nil
 }
@@ -42,7 +42,7 @@ func (view *UtxoViewpoint) GetEntry(outpoint types.TxOutPoint) *UtxoEntry {
 }
 
 func (view *UtxoViewpoint) AddTxOut(tx *types.Tx, txOutIdx uint32, blockHash *hash.Hash) {
-	if types.IsCrossChainExportTx(tx.Tx) {
+	if types.IsCrossChainExportTx(tx.Tx) || types.IsStakeLockTx(tx.Tx) {
 		if txOutIdx == 0 {
 			return
 		}
@@ -71,7 +71,7 @@ func (view *UtxoViewpoint) AddTxOuts(tx *types.Tx, blockHash *hash.Hash) {
 	isCoinBase := tx.Tx.IsCoinBase()
 	prevOut := types.TxOutPoint{Hash: *tx.Hash()}
 	for txOutIdx, txOut := range tx.Tx.TxOut {
-		if txOutIdx == 0 && types.IsCrossChainExportTx(tx.Tx) {
+		if txOutIdx == 0 && (types.IsCrossChainExportTx(tx.Tx) || types.IsStakeLockTx(tx.Tx)) {
 			continue
 		}
 		// Update existing entries.  All fields are updated because it's
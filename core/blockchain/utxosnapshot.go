@@ -0,0 +1,638 @@
+// Copyright (c) 2017-2018 The qitmeer developers
+package blockchain
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/consensus/model"
+	"github.com/Qitmeer/qng/core/blockchain/utxo"
+	"github.com/Qitmeer/qng/core/json"
+	"github.com/Qitmeer/qng/core/protocol"
+	"github.com/Qitmeer/qng/core/serialization"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/core/types/pow"
+	"github.com/Qitmeer/qng/crypto/muhash"
+	"github.com/Qitmeer/qng/database/common"
+	"github.com/Qitmeer/qng/meerdag"
+	"github.com/Qitmeer/qng/params"
+)
+
+// The UTXO set snapshot consists of:
+//
+//	magic "utxo" | version uint32 | network uint32 | order uint64 | block hash
+//	[ varbytes key | varbytes data ] ... | empty key
+//	count uint64 | MuHash commitment
+//
+// The entries are the raw records of the UTXO set, the commitment is the
+// MuHash of every key||data.
+const (
+	utxoSnapshotVersion = 1
+
+	// The max size of key and data of one entry
+	maxUtxoSnapshotEntrySize = 1 << 20
+
+	// The number of entries written to the database by one batch
+	utxoSnapshotBatchSize = 10000
+
+	// The file of data dir where the verified snapshot is staged until it
+	// replaces the UTXO set
+	utxoSnapshotStagedFile = "utxosnapshot.staged"
+)
+
+// The status of the loaded snapshot
+const (
+	// The snapshot is staged, the blocks up to its order are connected
+	// without the UTXO set.
+	utxoSnapshotPending byte = iota + 1
+
+	// The UTXO set is being replaced by the staged snapshot, it's redone on
+	// the next start if it's interrupted.
+	utxoSnapshotSwapping
+
+	// The UTXO set is replaced, the blocks up to the order of snapshot have
+	// no spend journal so they can't be disconnected.
+	utxoSnapshotLoaded
+)
+
+var utxoSnapshotMagic = [4]byte{'u', 't', 'x', 'o'}
+
+// utxoSnapshotStateKey is the database key of the state of loaded snapshot.
+var utxoSnapshotStateKey = []byte("utxosnapshot")
+
+// errUtxoBatchFull stops the iteration of UTXO set when a batch is full.
+var errUtxoBatchFull = errors.New("utxo batch is full")
+
+// UtxoSnapshotHeader is the block which the UTXO set snapshot is tied to.
+type UtxoSnapshotHeader struct {
+	Net   protocol.Network
+	Order uint64
+	Hash  hash.Hash
+}
+
+// utxoSetElement returns the element of MuHash, the key ends with a VLQ so it
+// can't be ambiguous.
+func utxoSetElement(key []byte, data []byte) []byte {
+	element := make([]byte, 0, len(key)+len(data))
+	element = append(element, key...)
+	return append(element, data...)
+}
+
+// utxoSetStats accumulates the statistics of UTXO entries, the MuHash is
+// skipped when it's nil.
+type utxoSetStats struct {
+	count   uint64
+	size    uint64
+	amounts map[types.CoinID]int64
+	muhash  *muhash.MuHash
+}
+
+func newUtxoSetStats() *utxoSetStats {
+	return &utxoSetStats{amounts: map[types.CoinID]int64{}, muhash: muhash.New()}
+}
+
+func (s *utxoSetStats) add(key []byte, data []byte) error {
+	entry, err := utxo.DeserializeUtxoEntry(data)
+	if err != nil {
+		return fmt.Errorf("corrupt utxo entry %x: %v", key, err)
+	}
+	amount := entry.Amount()
+	s.amounts[amount.Id] += amount.Value
+	s.count++
+	s.size += uint64(len(key) + len(data))
+	if s.muhash != nil {
+		s.muhash.Add(utxoSetElement(key, data))
+	}
+	return nil
+}
+
+func (s *utxoSetStats) info(order uint64, h *hash.Hash) *json.UtxoSetInfo {
+	info := &json.UtxoSetInfo{
+		Order:          order,
+		Hash:           h.String(),
+		TxOuts:         s.count,
+		SerializedSize: s.size,
+		Amounts:        map[string]int64{},
+	}
+	if s.muhash != nil {
+		info.MuHash = s.muhash.Finalize().String()
+	}
+	for id, v := range s.amounts {
+		info.Amounts[id.Name()] = v
+	}
+	return info
+}
+
+// FetchUtxoSetInfo returns the statistics and the MuHash commitment of the
+// UTXO set at the current best block.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) FetchUtxoSetInfo() (*json.UtxoSetInfo, error) {
+	b.ChainRLock()
+	defer b.ChainRUnlock()
+
+	return b.fetchUtxoSetInfo()
+}
+
+// The MuHash is the one maintained by connecting and disconnecting blocks.
+//
+// This function MUST be called with the chain state lock held (for reads).
+func (b *BlockChain) fetchUtxoSetInfo() (*json.UtxoSetInfo, error) {
+	best := b.BestSnapshot()
+	stats := &utxoSetStats{amounts: map[types.CoinID]int64{}}
+	err := b.DB().ForeachUtxo(stats.add)
+	if err != nil {
+		return nil, err
+	}
+	info := stats.info(uint64(best.GraphState.GetMainOrder()), &best.Hash)
+	info.MuHash = b.utxoMuHash.Finalize().String()
+	return info, nil
+}
+
+// DumpUtxoSnapshot writes the UTXO set at the current best block to w.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) DumpUtxoSnapshot(w io.Writer) (*json.UtxoSetInfo, error) {
+	b.ChainRLock()
+	defer b.ChainRUnlock()
+
+	best := b.BestSnapshot()
+	header := &UtxoSnapshotHeader{
+		Net:   b.params.Net,
+		Order: uint64(best.GraphState.GetMainOrder()),
+		Hash:  best.Hash,
+	}
+	info, err := writeUtxoSnapshot(w, header, b.DB().ForeachUtxo)
+	if err != nil {
+		return nil, err
+	}
+	commitment := b.utxoMuHash.Finalize()
+	if info.MuHash != commitment.String() {
+		return nil, fmt.Errorf("The UTXO set %s doesn't match its maintained MuHash %s", info.MuHash, commitment)
+	}
+	return info, nil
+}
+
+// writeUtxoSnapshot writes the snapshot of the entries iterated by foreach.
+func writeUtxoSnapshot(w io.Writer, header *UtxoSnapshotHeader,
+	foreach func(fn func(key []byte, data []byte) error) error) (*json.UtxoSetInfo, error) {
+	bw := bufio.NewWriter(w)
+	err := serialization.WriteElements(bw, utxoSnapshotMagic, uint32(utxoSnapshotVersion),
+		header.Net, header.Order, &header.Hash)
+	if err != nil {
+		return nil, err
+	}
+	stats := newUtxoSetStats()
+	err = foreach(func(key []byte, data []byte) error {
+		if len(key) <= 0 {
+			return fmt.Errorf("empty utxo key")
+		}
+		err := stats.add(key, data)
+		if err != nil {
+			return err
+		}
+		err = serialization.WriteVarBytes(bw, 0, key)
+		if err != nil {
+			return err
+		}
+		return serialization.WriteVarBytes(bw, 0, data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	commitment := stats.muhash.Finalize()
+	err = serialization.WriteVarBytes(bw, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = serialization.WriteElements(bw, stats.count, &commitment)
+	if err != nil {
+		return nil, err
+	}
+	err = bw.Flush()
+	if err != nil {
+		return nil, err
+	}
+	return stats.info(header.Order, &header.Hash), nil
+}
+
+// ReadUtxoSnapshot reads the snapshot of r and calls fn with every entry, the
+// count and the commitment of trailer are verified.
+func ReadUtxoSnapshot(r io.Reader, fn func(key []byte, data []byte) error) (*UtxoSnapshotHeader, *json.UtxoSetInfo, error) {
+	header, stats, err := readUtxoSnapshot(r, fn)
+	if err != nil {
+		return nil, nil, err
+	}
+	return header, stats.info(header.Order, &header.Hash), nil
+}
+
+func readUtxoSnapshot(r io.Reader, fn func(key []byte, data []byte) error) (*UtxoSnapshotHeader, *utxoSetStats, error) {
+	br := bufio.NewReader(r)
+	var magic [4]byte
+	var version uint32
+	header := &UtxoSnapshotHeader{}
+	err := serialization.ReadElements(br, &magic, &version, &header.Net, &header.Order, &header.Hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	if magic != utxoSnapshotMagic {
+		return nil, nil, fmt.Errorf("It's not a UTXO set snapshot")
+	}
+	if version != utxoSnapshotVersion {
+		return nil, nil, fmt.Errorf("Unsupported UTXO set snapshot version:%d", version)
+	}
+	stats := newUtxoSetStats()
+	for {
+		key, err := serialization.ReadVarBytes(br, 0, maxUtxoSnapshotEntrySize, "utxo key")
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(key) <= 0 {
+			break
+		}
+		data, err := serialization.ReadVarBytes(br, 0, maxUtxoSnapshotEntrySize, "utxo data")
+		if err != nil {
+			return nil, nil, err
+		}
+		err = stats.add(key, data)
+		if err != nil {
+			return nil, nil, err
+		}
+		if fn != nil {
+			err = fn(key, data)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	var count uint64
+	var commitment hash.Hash
+	err = serialization.ReadElements(br, &count, &commitment)
+	if err != nil {
+		return nil, nil, err
+	}
+	if count != stats.count {
+		return nil, nil, fmt.Errorf("The count of UTXO set snapshot is %d, but it has %d entries", count, stats.count)
+	}
+	if commitment != stats.muhash.Finalize() {
+		return nil, nil, fmt.Errorf("The commitment of UTXO set snapshot doesn't match its entries")
+	}
+	return header, stats, nil
+}
+
+// checkAssumeUTXO checks the snapshot against the pinned snapshots of params,
+// the snapshot that isn't pinned is only accepted by force.
+func checkAssumeUTXO(p *params.Params, info *json.UtxoSetInfo, force bool) error {
+	for _, au := range p.AssumeUTXOs {
+		if au.Order != info.Order {
+			continue
+		}
+		if au.Hash.String() != info.Hash || au.Count != info.TxOuts || au.MuHash.String() != info.MuHash {
+			return fmt.Errorf("The UTXO set snapshot at order %d doesn't match the pinned one (hash:%s muhash:%s count:%d)",
+				au.Order, au.Hash, au.MuHash, au.Count)
+		}
+		return nil
+	}
+	if force {
+		return nil
+	}
+	return fmt.Errorf("The UTXO set snapshot at order %d (%s) isn't pinned by the %s params", info.Order, info.Hash, p.Name)
+}
+
+// utxoSnapshotState is the state of the snapshot loaded by LoadUtxoSnapshot,
+// it's serialized as:
+//
+//	status byte | order uint64 | block hash | count uint64 | MuHash commitment
+type utxoSnapshotState struct {
+	status     byte
+	order      uint64
+	hash       hash.Hash
+	count      uint64
+	commitment hash.Hash
+}
+
+func (us *utxoSnapshotState) serialize() ([]byte, error) {
+	var buf bytes.Buffer
+	err := serialization.WriteElements(&buf, us.status, us.order, &us.hash, us.count, &us.commitment)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func deserializeUtxoSnapshotState(data []byte) (*utxoSnapshotState, error) {
+	us := &utxoSnapshotState{}
+	err := serialization.ReadElements(bytes.NewReader(data), &us.status, &us.order, &us.hash, &us.count, &us.commitment)
+	if err != nil {
+		return nil, fmt.Errorf("corrupt UTXO set snapshot state: %v", err)
+	}
+	if us.status < utxoSnapshotPending || us.status > utxoSnapshotLoaded {
+		return nil, fmt.Errorf("corrupt UTXO set snapshot state: unknown status %d", us.status)
+	}
+	return us, nil
+}
+
+// dbFetchUtxoSnapshotState returns the state of loaded snapshot, or nil if no
+// snapshot is loaded.
+func dbFetchUtxoSnapshotState(db model.DataBase) (*utxoSnapshotState, error) {
+	data, err := db.Get(utxoSnapshotStateKey)
+	if err != nil || len(data) <= 0 {
+		return nil, nil
+	}
+	return deserializeUtxoSnapshotState(data)
+}
+
+func dbPutUtxoSnapshotState(db model.DataBase, us *utxoSnapshotState) error {
+	data, err := us.serialize()
+	if err != nil {
+		return err
+	}
+	return db.Put(utxoSnapshotStateKey, data)
+}
+
+func (b *BlockChain) stagedUtxoSnapshotPath() string {
+	return b.consensus.Config().ResolveDataPath(utxoSnapshotStagedFile)
+}
+
+// initUtxoSetState restores the MuHash of UTXO set and the state of loaded
+// snapshot when the chain state is loaded. The MuHash is computed once if the
+// chain state was stored before it's maintained, and the replacing of UTXO set
+// interrupted by shutdown is redone.
+func (b *BlockChain) initUtxoSetState(serializedMuHash []byte) error {
+	if len(serializedMuHash) > 0 {
+		m, err := muhash.Deserialize(serializedMuHash)
+		if err != nil {
+			return err
+		}
+		b.utxoMuHash = m
+	} else {
+		log.Info("Computing the MuHash of UTXO set ...")
+		m := muhash.New()
+		err := b.DB().ForeachUtxo(func(key []byte, data []byte) error {
+			m.Add(utxoSetElement(key, data))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		b.utxoMuHash = m
+		err = b.dbPutBestStateOfTip()
+		if err != nil {
+			return err
+		}
+	}
+
+	us, err := dbFetchUtxoSnapshotState(b.DB())
+	if err != nil {
+		return err
+	}
+	b.utxoSnapshot = us
+	if us == nil {
+		return nil
+	}
+	switch us.status {
+	case utxoSnapshotPending:
+		log.Info("The blocks are connected without the UTXO set up to the UTXO set snapshot", "order", us.order, "hash", us.hash)
+	case utxoSnapshotSwapping:
+		log.Info("Resume replacing the UTXO set by the snapshot", "order", us.order, "hash", us.hash)
+		return b.swapUtxoSnapshot()
+	}
+	return nil
+}
+
+// dbPutBestStateOfTip stores the best chain state with the current MuHash of
+// UTXO set.
+func (b *BlockChain) dbPutBestStateOfTip() error {
+	mainTipNode := b.GetBlockNode(b.bd.GetMainChainTip())
+	if mainTipNode == nil {
+		return fmt.Errorf("No main tip node")
+	}
+	return dbPutBestState(b.DB(), b.BestSnapshot(), pow.CalcWork(mainTipNode.Difficulty(), mainTipNode.Pow().GetPowType()), b.utxoMuHash)
+}
+
+// stageUtxoSnapshot verifies the snapshot file of path and copies it to dst.
+func stageUtxoSnapshot(path string, dst string) (*UtxoSnapshotHeader, *json.UtxoSetInfo, error) {
+	r, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer r.Close()
+	w, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer w.Close()
+	header, info, err := ReadUtxoSnapshot(io.TeeReader(r, w), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = w.Sync()
+	if err != nil {
+		return nil, nil, err
+	}
+	return header, info, nil
+}
+
+// LoadUtxoSnapshot verifies the snapshot file of path and stages it in the
+// data dir, the snapshot must be pinned by params unless force is set.
+//
+// If the best block is the block of snapshot, the UTXO set is replaced at
+// once. If the best block is before it, such as on a new node, the blocks up
+// to the order of snapshot are connected without the UTXO set, and the UTXO
+// set is replaced when the block of snapshot is connected. The states of other
+// modules are still rebuilt by those blocks.
+func (b *BlockChain) LoadUtxoSnapshot(path string, force bool) (*json.UtxoSetInfo, error) {
+	stagingPath := b.stagedUtxoSnapshotPath() + ".tmp"
+	defer os.Remove(stagingPath)
+	header, info, err := stageUtxoSnapshot(path, stagingPath)
+	if err != nil {
+		return nil, err
+	}
+	if header.Net != b.params.Net {
+		return nil, fmt.Errorf("The UTXO set snapshot is for network %s, but the node is on %s", header.Net, b.params.Net)
+	}
+	err = checkAssumeUTXO(b.params, info, force)
+	if err != nil {
+		return nil, err
+	}
+
+	b.ChainLock()
+	defer b.ChainUnlock()
+
+	if b.utxoSnapshot != nil && b.utxoSnapshot.status != utxoSnapshotLoaded {
+		return nil, fmt.Errorf("The UTXO set snapshot at %s (order %d) is already being loaded", b.utxoSnapshot.hash, b.utxoSnapshot.order)
+	}
+	best := b.BestSnapshot()
+	bestOrder := uint64(best.GraphState.GetMainOrder())
+	if bestOrder > header.Order || (bestOrder == header.Order && !best.Hash.IsEqual(&header.Hash)) {
+		return nil, fmt.Errorf("The best block is %s (order %d), it's past the UTXO set snapshot at %s (order %d)",
+			best.Hash, bestOrder, header.Hash, header.Order)
+	}
+	err = os.Rename(stagingPath, b.stagedUtxoSnapshotPath())
+	if err != nil {
+		return nil, err
+	}
+	commitment, err := hash.NewHashFromStr(info.MuHash)
+	if err != nil {
+		return nil, err
+	}
+	us := &utxoSnapshotState{
+		status:     utxoSnapshotPending,
+		order:      header.Order,
+		hash:       header.Hash,
+		count:      info.TxOuts,
+		commitment: *commitment,
+	}
+	if bestOrder < header.Order {
+		err = dbPutUtxoSnapshotState(b.DB(), us)
+		if err != nil {
+			return nil, err
+		}
+		b.utxoSnapshot = us
+		log.Info("Staged the UTXO set snapshot, the blocks up to its order are connected without the UTXO set",
+			"order", info.Order, "hash", info.Hash, "count", info.TxOuts, "muhash", info.MuHash)
+		return info, nil
+	}
+	b.utxoSnapshot = us
+	err = b.swapUtxoSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// swapUtxoSnapshot replaces the UTXO set by the staged snapshot and takes the
+// MuHash of snapshot as the MuHash of UTXO set. The swapping status is stored
+// before the UTXO set is touched, so the replacing interrupted by a crash is
+// redone from the staged snapshot on the next start.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) swapUtxoSnapshot() error {
+	us := b.utxoSnapshot
+	us.status = utxoSnapshotSwapping
+	err := dbPutUtxoSnapshotState(b.DB(), us)
+	if err != nil {
+		return err
+	}
+
+	// The UTXO set is removed by batches, the iteration restarts after every
+	// batch so the keys aren't held in memory.
+	db := b.DB()
+	removed := 0
+	for {
+		opts := make([]*common.UtxoOpt, 0, utxoSnapshotBatchSize)
+		err = db.ForeachUtxo(func(key []byte, data []byte) error {
+			opts = append(opts, &common.UtxoOpt{Key: append([]byte{}, key...)})
+			if len(opts) >= utxoSnapshotBatchSize {
+				return errUtxoBatchFull
+			}
+			return nil
+		})
+		if err != nil && err != errUtxoBatchFull {
+			return err
+		}
+		if len(opts) <= 0 {
+			break
+		}
+		err = db.UpdateUtxo(opts)
+		if err != nil {
+			return err
+		}
+		removed += len(opts)
+	}
+	log.Info("Removed the UTXO set", "count", removed)
+
+	r, err := os.Open(b.stagedUtxoSnapshotPath())
+	if err != nil {
+		return fmt.Errorf("The staged UTXO set snapshot is lost (%v), load the snapshot again", err)
+	}
+	defer r.Close()
+	opts := make([]*common.UtxoOpt, 0, utxoSnapshotBatchSize)
+	header, stats, err := readUtxoSnapshot(r, func(key []byte, data []byte) error {
+		opts = append(opts, &common.UtxoOpt{Key: key, Data: data, Add: true})
+		if len(opts) < utxoSnapshotBatchSize {
+			return nil
+		}
+		err := db.UpdateUtxo(opts)
+		opts = opts[:0]
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	err = db.UpdateUtxo(opts)
+	if err != nil {
+		return err
+	}
+	if header.Order != us.order || !header.Hash.IsEqual(&us.hash) ||
+		stats.count != us.count || stats.muhash.Finalize() != us.commitment {
+		return fmt.Errorf("The staged UTXO set snapshot doesn't match the loaded one at %s (order %d)", us.hash, us.order)
+	}
+	b.utxoMuHash = stats.muhash
+	err = b.dbPutBestStateOfTip()
+	if err != nil {
+		return err
+	}
+	us.status = utxoSnapshotLoaded
+	err = dbPutUtxoSnapshotState(b.DB(), us)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(b.stagedUtxoSnapshotPath())
+	if err != nil {
+		log.Warn(fmt.Sprintf("Remove the staged UTXO set snapshot:%v", err))
+	}
+	log.Info("Loaded the UTXO set snapshot", "order", us.order, "hash", us.hash, "count", us.count, "muhash", us.commitment)
+	return nil
+}
+
+// isAssumedOrder returns true if the block of order is covered by the pending
+// snapshot, it's connected without the UTXO set.
+func (b *BlockChain) isAssumedOrder(order uint64) bool {
+	return b.utxoSnapshot != nil && b.utxoSnapshot.status == utxoSnapshotPending &&
+		order <= b.utxoSnapshot.order
+}
+
+// checkAssumedBlock checks that the block at the order of pending snapshot is
+// the block of snapshot.
+func (b *BlockChain) checkAssumedBlock(ib meerdag.IBlock) error {
+	us := b.utxoSnapshot
+	if uint64(ib.GetOrder()) == us.order && !ib.GetHash().IsEqual(&us.hash) {
+		return fmt.Errorf("The block of order %d is %s, but the UTXO set snapshot is at %s", us.order, ib.GetHash(), us.hash)
+	}
+	return nil
+}
+
+// swapUtxoSnapshotAt replaces the UTXO set by the pending snapshot when its
+// block is connected.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) swapUtxoSnapshotAt(ib meerdag.IBlock) error {
+	if !b.isAssumedOrder(uint64(ib.GetOrder())) || uint64(ib.GetOrder()) != b.utxoSnapshot.order {
+		return nil
+	}
+	return b.swapUtxoSnapshot()
+}
+
+// checkDetachUtxoSnapshot returns an error if any block of detachNodes was
+// connected before the loaded snapshot, such block has no spend journal.
+func (b *BlockChain) checkDetachUtxoSnapshot(detachNodes *list.List) error {
+	us := b.utxoSnapshot
+	if us == nil || us.status == utxoSnapshotPending {
+		return nil
+	}
+	for e := detachNodes.Front(); e != nil; e = e.Next() {
+		ob := e.Value.(*meerdag.BlockOrderHelp)
+		if uint64(ob.OldOrder) <= us.order {
+			return fmt.Errorf("The block %s (order %d) can't be disconnected, the UTXO set is loaded from the snapshot at order %d",
+				ob.Block.GetHash(), ob.OldOrder, us.order)
+		}
+	}
+	return nil
+}
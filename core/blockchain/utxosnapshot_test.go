@@ -0,0 +1,262 @@
+package blockchain
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/consensus/model"
+	"github.com/Qitmeer/qng/core/blockchain/utxo"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/crypto/muhash"
+	"github.com/Qitmeer/qng/database/common"
+	"github.com/Qitmeer/qng/params"
+)
+
+func testUtxoSet(t *testing.T) map[string][]byte {
+	set := map[string][]byte{}
+	blockHash := hash.MustHexToHash("0000000000000000000000000000000000000000000000000000000000000001")
+	for i := 0; i < 5; i++ {
+		id := types.MEERA
+		if i%2 == 1 {
+			id = types.MEERB
+		}
+		entry := utxo.NewUtxoEntry(types.Amount{Value: int64(i+1) * 100, Id: id}, []byte{0x51}, &blockHash, i == 0)
+		data, err := utxo.SerializeUtxoEntry(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		op := types.TxOutPoint{Hash: blockHash, OutIndex: uint32(i)}
+		set[string(utxo.OutpointKeyNoPool(op))] = data
+	}
+	return set
+}
+
+func foreachTestUtxo(set map[string][]byte) func(fn func(key []byte, data []byte) error) error {
+	return func(fn func(key []byte, data []byte) error) error {
+		for k, v := range set {
+			err := fn([]byte(k), v)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func TestUtxoSnapshot(t *testing.T) {
+	set := testUtxoSet(t)
+	header := &UtxoSnapshotHeader{
+		Net:   params.PrivNetParam.Net,
+		Order: 10,
+		Hash:  hash.MustHexToHash("00000000000000000000000000000000000000000000000000000000000000aa"),
+	}
+	var buf bytes.Buffer
+	info, err := writeUtxoSnapshot(&buf, header, foreachTestUtxo(set))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.TxOuts != 5 || info.Amounts[types.MEERA.Name()] != 900 || info.Amounts[types.MEERB.Name()] != 600 {
+		t.Fatalf("unexpected utxo set info %v", info)
+	}
+
+	loaded := map[string][]byte{}
+	rh, rinfo, err := ReadUtxoSnapshot(bytes.NewReader(buf.Bytes()), func(key []byte, data []byte) error {
+		loaded[string(key)] = data
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *rh != *header || rinfo.MuHash != info.MuHash || rinfo.SerializedSize != info.SerializedSize || len(loaded) != len(set) {
+		t.Fatalf("the snapshot isn't read back: %v", rinfo)
+	}
+	for k, v := range set {
+		if !bytes.Equal(loaded[k], v) {
+			t.Fatalf("the entry %x is different", k)
+		}
+	}
+
+	// The commitment doesn't depend on the order of entries
+	var buf2 bytes.Buffer
+	info2, err := writeUtxoSnapshot(&buf2, header, foreachTestUtxo(set))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info2.MuHash != info.MuHash {
+		t.Fatal("the commitment depends on the order of entries")
+	}
+
+	// Any corruption of the entries is detected
+	corrupt := append([]byte{}, buf.Bytes()...)
+	corrupt[len(corrupt)-50] ^= 0x01
+	if _, _, err := ReadUtxoSnapshot(bytes.NewReader(corrupt), nil); err == nil {
+		t.Fatal("the corrupt snapshot is accepted")
+	}
+
+	p := *params.PrivNetParam.Params
+	if checkAssumeUTXO(&p, info, false) == nil {
+		t.Fatal("the snapshot isn't pinned")
+	}
+	if checkAssumeUTXO(&p, info, true) != nil {
+		t.Fatal("the forced snapshot is rejected")
+	}
+	muhash, err := hash.NewHashFromStr(info.MuHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.AssumeUTXOs = []params.AssumeUTXO{{Order: 10, Hash: &header.Hash, Count: 5, MuHash: muhash}}
+	if checkAssumeUTXO(&p, info, false) != nil {
+		t.Fatal("the pinned snapshot is rejected")
+	}
+	p.AssumeUTXOs[0].Count = 4
+	if checkAssumeUTXO(&p, info, true) == nil {
+		t.Fatal("the snapshot that doesn't match the pinned one is accepted")
+	}
+}
+
+// testUtxoDB is the UTXO set of map, the other methods aren't implemented.
+type testUtxoDB struct {
+	model.DataBase
+	set map[string][]byte
+}
+
+func (db *testUtxoDB) GetUtxo(key []byte) ([]byte, error) {
+	return db.set[string(key)], nil
+}
+
+func (db *testUtxoDB) UpdateUtxo(opts []*common.UtxoOpt) error {
+	for _, opt := range opts {
+		if opt.Add {
+			db.set[string(opt.Key)] = opt.Data
+		} else {
+			delete(db.set, string(opt.Key))
+		}
+	}
+	return nil
+}
+
+type testUtxoConsensus struct {
+	model.Consensus
+	db model.DataBase
+}
+
+func (c *testUtxoConsensus) DatabaseContext() model.DataBase {
+	return c.db
+}
+
+func TestUtxoMuHashMaintained(t *testing.T) {
+	db := &testUtxoDB{set: testUtxoSet(t)}
+	b := &BlockChain{consensus: &testUtxoConsensus{db: db}, utxoMuHash: muhash.New()}
+	for k, v := range db.set {
+		b.utxoMuHash.Add(utxoSetElement([]byte(k), v))
+	}
+	check := func() {
+		if newUtxoSetStatsOf(t, db.set).muhash.Finalize() != b.utxoMuHash.Finalize() {
+			t.Fatal("the maintained MuHash doesn't match the UTXO set")
+		}
+	}
+
+	// Spend an entry and add a new one
+	blockHash := hash.MustHexToHash("0000000000000000000000000000000000000000000000000000000000000001")
+	spentOp := types.TxOutPoint{Hash: blockHash, OutIndex: 1}
+	spent, err := utxo.DBFetchUtxoEntry(db, spentOp)
+	if err != nil || spent == nil {
+		t.Fatalf("no entry %v", err)
+	}
+	newHash := hash.MustHexToHash("0000000000000000000000000000000000000000000000000000000000000002")
+	newOp := types.TxOutPoint{Hash: newHash, OutIndex: 0}
+	added := utxo.NewUtxoEntry(types.Amount{Value: 150, Id: types.MEERA}, []byte{0x52}, &newHash, false)
+	added.Modified()
+	view := utxo.NewUtxoViewpoint()
+	view.AddEntry(spentOp, spent.Clone())
+	view.LookupEntry(spentOp).Spend()
+	view.AddEntry(newOp, added)
+	err = b.dbUpdateUtxoView(view)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(db.set) != 5 {
+		t.Fatalf("the UTXO set has %d entries", len(db.set))
+	}
+	check()
+
+	// Restore the spent entry and remove the new one
+	view = utxo.NewUtxoViewpoint()
+	restored := spent.Clone()
+	restored.Modified()
+	view.AddEntry(spentOp, restored)
+	view.AddEntry(newOp, added.Clone())
+	view.LookupEntry(newOp).Spend()
+	err = b.dbUpdateUtxoView(view)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check()
+	if b.utxoMuHash.Finalize() != newUtxoSetStatsOf(t, testUtxoSet(t)).muhash.Finalize() {
+		t.Fatal("the UTXO set isn't restored")
+	}
+}
+
+func newUtxoSetStatsOf(t *testing.T, set map[string][]byte) *utxoSetStats {
+	stats := newUtxoSetStats()
+	err := foreachTestUtxo(set)(stats.add)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stats
+}
+
+func TestUtxoSnapshotState(t *testing.T) {
+	us := &utxoSnapshotState{
+		status:     utxoSnapshotSwapping,
+		order:      10,
+		hash:       hash.MustHexToHash("00000000000000000000000000000000000000000000000000000000000000aa"),
+		count:      5,
+		commitment: hash.MustHexToHash("00000000000000000000000000000000000000000000000000000000000000bb"),
+	}
+	data, err := us.serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rus, err := deserializeUtxoSnapshotState(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *rus != *us {
+		t.Fatalf("the state isn't read back: %v", rus)
+	}
+	data[0] = 0
+	if _, err := deserializeUtxoSnapshotState(data); err == nil {
+		t.Fatal("the unknown status is accepted")
+	}
+}
+
+func TestBestChainStateUtxoMuHash(t *testing.T) {
+	m := muhash.New()
+	m.Add([]byte{1})
+	state := bestChainState{
+		hash:       hash.MustHexToHash("00000000000000000000000000000000000000000000000000000000000000aa"),
+		total:      3,
+		workSum:    big.NewInt(1000),
+		utxoMuHash: m.Serialize(),
+	}
+	data := serializeBestChainState(state)
+	rstate, err := DeserializeBestChainState(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rstate.utxoMuHash, state.utxoMuHash) || rstate.total != state.total {
+		t.Fatal("the MuHash of UTXO set isn't read back")
+	}
+
+	// The chain state stored before the MuHash is maintained
+	rstate, err = DeserializeBestChainState(data[:len(data)-muhash.ElementSize])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rstate.utxoMuHash != nil || rstate.workSum.Cmp(state.workSum) != 0 {
+		t.Fatal("the old chain state isn't read")
+	}
+}
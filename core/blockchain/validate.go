@@ -18,6 +18,7 @@ import (
 	"github.com/Qitmeer/qng/consensus/model"
 	mmeer "github.com/Qitmeer/qng/consensus/model/meer"
 	"github.com/Qitmeer/qng/core/blockchain/opreturn"
+	"github.com/Qitmeer/qng/core/blockchain/stake"
 	"github.com/Qitmeer/qng/core/blockchain/token"
 	"github.com/Qitmeer/qng/core/blockchain/utxo"
 	"github.com/Qitmeer/qng/core/merkle"
@@ -165,10 +166,6 @@ func (b *BlockChain) checkBlockSanity(block *types.SerializedBlock, timeSource m
 	// Do some preliminary checks on each regular transaction to ensure they
 	// are sane before continuing.
 	for _, tx := range transactions {
-		if !b.IsValidTxType(types.DetermineTxType(tx.Tx)) {
-			errStr := fmt.Sprintf("%s is not support transaction type.", types.DetermineTxType(tx.Tx).String())
-			return ruleError(ErrIrregTxInRegularTree, errStr)
-		}
 		// A block must not have stake transactions in the regular
 		// transaction tree.
 		err := CheckTransactionSanity(tx, chainParams, transactions[0].Transaction(), b)
@@ -327,6 +324,15 @@ func CheckTransactionSanity(tx *types.Tx, params *params.Params, coinbase *types
 			return err
 		}
 		return update.CheckSanity()
+	} else if types.IsStakeTx(tx.Tx) {
+		update, err := stake.NewUpdateFromTx(tx.Tx)
+		if err != nil {
+			return err
+		}
+		err = update.CheckSanity()
+		if err != nil {
+			return err
+		}
 	} else if types.IsCrossChainVMTx(tx.Tx) {
 		vtx, err := mmeer.NewVMTx(tx.Tx, coinbase)
 		if err != nil {
@@ -589,6 +595,18 @@ func (b *BlockChain) checkBlockContext(block *types.SerializedBlock, mainParent
 	if err != nil {
 		return err
 	}
+
+	// The transaction types depend on the forks and the deployments which
+	// are active at the height of the block, they're decided once for all the
+	// transactions.
+	txTypes := b.validTxTypesAfter(mainParent)
+	for _, tx := range block.Transactions() {
+		tt := types.DetermineTxType(tx.Tx)
+		if !isTxTypeIn(tt, txTypes) {
+			errStr := fmt.Sprintf("%s is not support transaction type.", tt.String())
+			return ruleError(ErrIrregTxInRegularTree, errStr)
+		}
+	}
 	header := &block.Block().Header
 	if !flags.Has(BFFastAdd) {
 		// A block must not exceed the maximum allowed size as defined
@@ -849,6 +867,10 @@ func (b *BlockChain) checkConnectBlock(ib meerdag.IBlock, blockNode *BlockNode,
 		str := "the coinbase for the genesis block is not spendable"
 		return ruleError(ErrMissingTxOut, str)
 	}
+	if ib.GetState().GetStatus().IsInvalidated() {
+		str := fmt.Sprintf("block %s has been invalidated", ib.GetHash())
+		return ruleError(ErrInvalidatedBlock, str)
+	}
 	// Don't run scripts if this node is before the latest known good
 	// checkpoint since the validity is verified via the checkpoints (all
 	// transactions are included in the merkle root hash and any changes
@@ -863,7 +885,7 @@ func (b *BlockChain) checkConnectBlock(ib meerdag.IBlock, blockNode *BlockNode,
 	var scriptFlags txscript.ScriptFlags
 	var err error
 	if runScripts {
-		scriptFlags, err = b.consensusScriptVerifyFlags()
+		scriptFlags, err = b.consensusScriptVerifyFlags(ib)
 		if err != nil {
 			return err
 		}
@@ -936,6 +958,10 @@ func (b *BlockChain) checkConnectBlock(ib meerdag.IBlock, blockNode *BlockNode,
 	if err != nil {
 		return err
 	}
+	err = b.CheckStakeState(ib, block)
+	if err != nil {
+		return err
+	}
 	return b.meerCheckConnectBlock(blockNode)
 }
 
@@ -943,7 +969,7 @@ func (b *BlockChain) checkConnectBlock(ib meerdag.IBlock, blockNode *BlockNode,
 // executing transaction scripts to enforce the consensus rules. This includes
 // any flags required as the result of any agendas that have passed and become
 // active.
-func (b *BlockChain) consensusScriptVerifyFlags() (txscript.ScriptFlags, error) {
+func (b *BlockChain) consensusScriptVerifyFlags(ib meerdag.IBlock) (txscript.ScriptFlags, error) {
 	//TODO, refactor the txvm flag, the flag should decided by node.parent
 	scriptFlags := txscript.ScriptBip16 |
 		txscript.ScriptVerifyDERSignatures |
@@ -954,6 +980,13 @@ func (b *BlockChain) consensusScriptVerifyFlags() (txscript.ScriptFlags, error)
 
 	scriptFlags |= txscript.ScriptVerifyCheckSequenceVerify
 	scriptFlags |= txscript.ScriptVerifySHA256
+	taproot, err := b.isDeploymentActiveFor(ib, params.DeploymentTaproot)
+	if err != nil {
+		return 0, err
+	}
+	if taproot {
+		scriptFlags |= txscript.ScriptVerifyTaproot
+	}
 	return scriptFlags, nil
 }
 
@@ -1000,6 +1033,19 @@ func (b *BlockChain) checkTransactionsAndConnect(node *BlockNode, block *types.S
 			}
 			continue
 		}
+		if types.IsStakeTx(tx.Tx) {
+			if types.IsStakeLockTx(tx.Tx) {
+				err := b.CheckStakeTransactionInputs(tx, utxoView)
+				if err != nil {
+					return err
+				}
+			}
+			err := b.connectTransaction(tx, node, uint32(idx), stxos, utxoView)
+			if err != nil {
+				return err
+			}
+			continue
+		}
 		if types.IsCrossChainImportTx(tx.Tx) {
 			itx, err := mmeer.NewImportTx(tx.Tx)
 			if err != nil {
@@ -1438,8 +1484,16 @@ func IsFinalizedTransaction(tx *types.Tx, blockHeight uint64, blockTime time.Tim
 	return true
 }
 
+// IsValidTxType returns whether the transaction type is allowed in the block
+// after the end of the current main chain.
 func (b *BlockChain) IsValidTxType(tt types.TxType) bool {
-	txTypesCfg := types.StdTxs
+	return isTxTypeIn(tt, b.validTxTypesAfter(b.bd.GetMainChainTip()))
+}
+
+// validTxTypesAfter returns the transaction types allowed in the block after
+// the given main chain block.
+func (b *BlockChain) validTxTypesAfter(prevNode meerdag.IBlock) []types.TxType {
+	txTypesCfg := append([]types.TxType{}, types.StdTxs...)
 	if len(types.TokenTxs) > 0 {
 		txTypesCfg = append(txTypesCfg, types.TokenTxs...)
 	}
@@ -1452,7 +1506,14 @@ func (b *BlockChain) IsValidTxType(tt types.TxType) bool {
 	if ok && len(types.MeerEVMTxs) > 0 {
 		txTypesCfg = append(txTypesCfg, types.MeerEVMTxs...)
 	}
-	for _, txt := range txTypesCfg {
+	if stakeActive, err := b.IsDeploymentActiveAfter(prevNode, params.DeploymentStake); err == nil && stakeActive {
+		txTypesCfg = append(txTypesCfg, types.StakeTxs...)
+	}
+	return txTypesCfg
+}
+
+func isTxTypeIn(tt types.TxType, txTypes []types.TxType) bool {
+	for _, txt := range txTypes {
 		if txt == tt {
 			return true
 		}
@@ -5,7 +5,9 @@ import (
 	"encoding/hex"
 	"github.com/Qitmeer/qng/common/hash"
 	"github.com/Qitmeer/qng/core/address"
+	"github.com/Qitmeer/qng/core/blockchain/utxo"
 	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/crypto/ecc"
 	"github.com/Qitmeer/qng/engine/txscript"
 	"github.com/Qitmeer/qng/params"
 	"testing"
@@ -167,3 +169,55 @@ func createTx() *types.Transaction {
 	tx.AddTxOut(&types.TxOutput{Amount: types.Amount{Value: 1 * 1e8, Id: QITID}, PkScript: tokenChangeScript})
 	return tx
 }
+
+func TestStakeDisposeScript(t *testing.T) {
+	holderKey, holderPub := ecc.Secp256k1.PrivKeyFromBytes(privateKey)
+	holder, err := address.NewSecpPubKeyAddress(holderPub.SerializeCompressed(), &params.PrivNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	holderScript, err := txscript.PayToAddrScript(holder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	purchase := hash.HashH([]byte("stake_purchase"))
+	tx := types.NewTransaction()
+	tx.AddTxIn(&types.TxInput{
+		PreviousOut: types.TxOutPoint{Hash: purchase, OutIndex: types.SupperPrevOutIndex},
+		Sequence:    uint32(types.TxTypeStakeDispose),
+	})
+	tx.AddTxOut(&types.TxOutput{Amount: types.Amount{Value: 100000000, Id: types.MEERA}, PkScript: holderScript})
+	if !types.IsStakeDisposeTx(tx) {
+		t.Fatal("not a stake_dispose")
+	}
+	view := utxo.NewUtxoViewpoint()
+	view.AddTokenTxOut(tx.TxIn[0].PreviousOut, holderScript)
+	flags := txscript.ScriptBip16 | txscript.ScriptVerifyDERSignatures
+	validate := func() error {
+		return ValidateTransactionScripts(types.NewTx(tx), view, flags, nil, 1)
+	}
+
+	// Anyone could dispose the stake without the signature of the holder.
+	if validate() == nil {
+		t.Fatal("stake_dispose without signature is valid")
+	}
+	sign := func(key ecc.PrivateKey) {
+		var kdb txscript.KeyClosure = func(types.Address) (ecc.PrivateKey, bool, error) {
+			return key, true, nil
+		}
+		sigScript, err := txscript.SignTxOutput(&params.PrivNetParams, tx, 0, holderScript, txscript.SigHashAll, kdb, nil, nil, ecc.ECDSA_Secp256k1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx.TxIn[0].SignScript = sigScript
+	}
+	otherKey, _ := ecc.Secp256k1.PrivKeyFromBytes(hash.HashB([]byte("other")))
+	sign(otherKey)
+	if validate() == nil {
+		t.Fatal("stake_dispose signed by another key is valid")
+	}
+	sign(holderKey)
+	if err := validate(); err != nil {
+		t.Fatal(err)
+	}
+}
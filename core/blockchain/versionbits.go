@@ -4,12 +4,220 @@
 
 package blockchain
 
+import (
+	"github.com/Qitmeer/qng/core/json"
+	"github.com/Qitmeer/qng/meerdag"
+	"github.com/Qitmeer/qng/params"
+)
+
 const (
 	// VBTopBits defines the bits to set in the version to signal that the
 	// version bits scheme is being used.
 	VBTopBits = 0x20000000
+
+	// VBTopMask is the bitmask to use to determine whether or not the
+	// version bits scheme is in use.
+	VBTopMask = 0xe0000000
+
+	// VBNumBits is the total number of bits available for use with the
+	// version bits scheme.
+	VBNumBits = 29
 )
 
+// deploymentChecker provides a thresholdConditionChecker which can be used to
+// test a specific deployment rule.  This is required for properly detecting
+// and activating consensus rule changes.
+type deploymentChecker struct {
+	deployment *params.ConsensusDeployment
+	chain      *BlockChain
+}
+
+// Ensure the deploymentChecker type implements the thresholdConditionChecker
+// interface.
+var _ thresholdConditionChecker = deploymentChecker{}
+
+// BeginHeight returns the main height at which the miners start signalling
+// for the deployment.
+//
+// This is part of the thresholdConditionChecker interface implementation.
+func (c deploymentChecker) BeginHeight() uint64 {
+	return c.deployment.StartHeight
+}
+
+// EndHeight returns the main height at which the deployment fails when it
+// has not been locked in yet.
+//
+// This is part of the thresholdConditionChecker interface implementation.
+func (c deploymentChecker) EndHeight() uint64 {
+	return c.deployment.TimeoutHeight
+}
+
+// RuleChangeActivationThreshold is the number of blocks for which the condition
+// must be true in order to lock in a rule change.
+//
+// This implementation returns the value defined by the chain params the checker
+// is associated with.
+//
+// This is part of the thresholdConditionChecker interface implementation.
+func (c deploymentChecker) RuleChangeActivationThreshold() uint32 {
+	return c.chain.params.RuleChangeActivationThreshold
+}
+
+// MinerConfirmationWindow is the number of blocks in each threshold state
+// retarget window.
+//
+// This implementation returns the value defined by the chain params the checker
+// is associated with.
+//
+// This is part of the thresholdConditionChecker interface implementation.
+func (c deploymentChecker) MinerConfirmationWindow() uint32 {
+	return c.chain.params.MinerConfirmationWindow
+}
+
+// Condition returns true when the specific bit defined by the deployment
+// associated with the checker is set.
+//
+// This is part of the thresholdConditionChecker interface implementation.
+func (c deploymentChecker) Condition(version uint32) bool {
+	conditionMask := uint32(1) << c.deployment.BitNumber
+	return (version&VBTopMask == VBTopBits) && (version&conditionMask != 0)
+}
+
+// calcNextBlockVersion calculates the expected version of the block after the
+// passed main chain block based on the state of started and locked in
+// rule change deployments.
+//
+// This function differs from the exported CalcNextBlockVersion in that the
+// exported version uses the current main chain tip as the previous block
+// while this function accepts any block.
+func (b *BlockChain) calcNextBlockVersion(prevNode meerdag.IBlock) (uint32, error) {
+	// Set the appropriate bits for each actively defined rule deployment
+	// that is either in the process of being voted on, or locked in for the
+	// activation at the next threshold window change.
+	expectedVersion := uint32(VBTopBits)
+	for id := 0; id < len(b.params.Deployments); id++ {
+		deployment := &b.params.Deployments[id]
+		cache := &b.deploymentCaches[id]
+		checker := deploymentChecker{deployment: deployment, chain: b}
+		state, err := b.thresholdState(prevNode, checker, cache)
+		if err != nil {
+			return 0, err
+		}
+		if state == ThresholdStarted || state == ThresholdLockedIn {
+			expectedVersion |= uint32(1) << deployment.BitNumber
+		}
+	}
+	return expectedVersion, nil
+}
+
+// CalcNextBlockVersion calculates the expected version of the block after the
+// end of the current main chain, the miners signal the started and locked in
+// deployments through it.
+//
+// This function is safe for concurrent access.
 func (b *BlockChain) CalcNextBlockVersion() (uint32, error) {
-	return VBTopBits, nil
+	return b.calcNextBlockVersion(b.bd.GetMainChainTip())
+}
+
+// deploymentState returns the current rule change threshold for a given
+// deployment ID.  The threshold is evaluated from the point of view of the
+// block after the given main chain block.
+func (b *BlockChain) deploymentState(prevNode meerdag.IBlock, deploymentID uint32) (ThresholdState, error) {
+	if deploymentID >= uint32(len(b.params.Deployments)) {
+		return ThresholdFailed, DeploymentError(deploymentID)
+	}
+
+	deployment := &b.params.Deployments[deploymentID]
+	checker := deploymentChecker{deployment: deployment, chain: b}
+	cache := &b.deploymentCaches[deploymentID]
+
+	return b.thresholdState(prevNode, checker, cache)
+}
+
+// ThresholdState returns the current rule change threshold state of the given
+// deployment ID for the block AFTER the end of the current main chain.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) ThresholdState(deploymentID uint32) (ThresholdState, error) {
+	return b.deploymentState(b.bd.GetMainChainTip(), deploymentID)
+}
+
+// IsDeploymentActive returns true if the target deploymentID is active, and
+// false otherwise.  The consensus rule changes which are voted in by the
+// miners are gated by it.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) IsDeploymentActive(deploymentID uint32) (bool, error) {
+	state, err := b.ThresholdState(deploymentID)
+	if err != nil {
+		return false, err
+	}
+	return state == ThresholdActive, nil
+}
+
+// IsDeploymentActiveAfter returns whether the target deploymentID is active
+// for the block after the given main chain block.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) IsDeploymentActiveAfter(prevNode meerdag.IBlock, deploymentID uint32) (bool, error) {
+	state, err := b.deploymentState(prevNode, deploymentID)
+	if err != nil {
+		return false, err
+	}
+	return state == ThresholdActive, nil
+}
+
+// isDeploymentActiveFor returns whether the target deploymentID is active for
+// the given block, it's decided by the main parent of the block.
+func (b *BlockChain) isDeploymentActiveFor(ib meerdag.IBlock, deploymentID uint32) (bool, error) {
+	if !ib.HasParents() {
+		return false, nil
+	}
+	return b.IsDeploymentActiveAfter(b.bd.GetBlockById(ib.GetMainParent()), deploymentID)
+}
+
+// DeploymentInfo returns the state of every rule change deployment for the
+// block after the end of the current main chain.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) DeploymentInfo() (*json.DeploymentInfo, error) {
+	tip := b.bd.GetMainChainTip()
+	window := uint64(b.params.MinerConfirmationWindow)
+	result := &json.DeploymentInfo{
+		Hash:        tip.GetHash().String(),
+		Height:      uint64(tip.GetHeight()),
+		Window:      window,
+		Threshold:   b.params.RuleChangeActivationThreshold,
+		Deployments: map[string]*json.DeploymentDesc{},
+	}
+	for id := 0; id < len(b.params.Deployments); id++ {
+		deployment := &b.params.Deployments[id]
+		state, err := b.deploymentState(tip, uint32(id))
+		if err != nil {
+			return nil, err
+		}
+		desc := &json.DeploymentDesc{
+			Bit:           deployment.BitNumber,
+			StartHeight:   deployment.StartHeight,
+			TimeoutHeight: deployment.TimeoutHeight,
+			Status:        state.String(),
+		}
+		if state == ThresholdStarted && window > 0 {
+			// The votes of the blocks in the current window which
+			// are already on the main chain.
+			elapsed := (uint64(tip.GetHeight()) + 1) % window
+			checker := deploymentChecker{deployment: deployment, chain: b}
+			count, err := b.countThresholdVotes(tip, checker, elapsed)
+			if err != nil {
+				return nil, err
+			}
+			desc.Statistics = &json.DeploymentStatistics{
+				Elapsed:  elapsed,
+				Count:    count,
+				Possible: uint64(count)+window-elapsed >= uint64(b.params.RuleChangeActivationThreshold),
+			}
+		}
+		result.Deployments[deployment.Name] = desc
+	}
+	return result, nil
 }
@@ -35,4 +35,8 @@ var (
 	//TokenBucketName is the name of the db bucket used to house the token balance state
 	//The balance state is updated by the TOKEN_MINT/TOKEN_UNMINT transactions.
 	TokenBucketName = []byte("token")
+
+	//StakeBucketName is the name of the db bucket used to house the stake pool state
+	//The stake state is updated by the stake transactions.
+	StakeBucketName = []byte("stake")
 )
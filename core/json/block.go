@@ -87,6 +87,24 @@ type TokenState struct {
 	LockedMeer int64  `json:"lockedMEER,omitempty"`
 }
 
+type StakeInfo struct {
+	Enable      bool   `json:"enable"`
+	TipHash     string `json:"tiphash,omitempty"`
+	Reserve     int64  `json:"reserve"`
+	TotalStaked int64  `json:"totalstaked"`
+	Stakes      int    `json:"stakes"`
+	Maturity    uint64 `json:"maturity"`
+	Reward      int64  `json:"reward"`
+}
+
+type StakeEntry struct {
+	TxId     string `json:"txid"`
+	Amount   int64  `json:"amount"`
+	Height   uint64 `json:"height"`
+	PkScript string `json:"pkscript"`
+	Mature   bool   `json:"mature"`
+}
+
 type TipInfo struct {
 	ID          uint64 `json:"id"`
 	Hash        string `json:"hash"`
@@ -100,3 +118,44 @@ type TipsInfo struct {
 	Valid   []TipInfo `json:"valid"`
 	Invalid []TipInfo `json:"invalid,omitempty"`
 }
+
+type PruneInfo struct {
+	Archival        bool   `json:"archival"`
+	Enabled         bool   `json:"enabled"`
+	PruneDepth      uint64 `json:"prunedepth"`
+	CheckpointOrder uint64 `json:"checkpointorder,omitempty"`
+	PrunedOrder     uint64 `json:"prunedorder"`
+	LastPrune       string `json:"lastprune,omitempty"`
+	Reason          string `json:"reason,omitempty"`
+}
+
+type UtxoSetInfo struct {
+	Order          uint64           `json:"order"`
+	Hash           string           `json:"hash"`
+	TxOuts         uint64           `json:"txouts"`
+	SerializedSize uint64           `json:"serializedsize"`
+	Amounts        map[string]int64 `json:"amounts"`
+	MuHash         string           `json:"muhash"`
+}
+
+type DeploymentStatistics struct {
+	Elapsed  uint64 `json:"elapsed"`
+	Count    uint32 `json:"count"`
+	Possible bool   `json:"possible"`
+}
+
+type DeploymentDesc struct {
+	Bit           uint8                 `json:"bit"`
+	StartHeight   uint64                `json:"startheight"`
+	TimeoutHeight uint64                `json:"timeoutheight"`
+	Status        string                `json:"status"`
+	Statistics    *DeploymentStatistics `json:"statistics,omitempty"`
+}
+
+type DeploymentInfo struct {
+	Hash        string                     `json:"hash"`
+	Height      uint64                     `json:"height"`
+	Window      uint64                     `json:"window"`
+	Threshold   uint32                     `json:"threshold"`
+	Deployments map[string]*DeploymentDesc `json:"deployments"`
+}
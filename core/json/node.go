@@ -58,6 +58,9 @@ type GetPeerInfoResult struct {
 	MempoolReqTime string               `json:"mempoolreqtime,omitempty"`
 	Tasks          int                  `json:"tasks,omitempty"`
 	Broadcast      int                  `json:"broadcast,omitempty"`
+	Score          float64              `json:"score,omitempty"`
+	Scores         map[string]float64   `json:"scores,omitempty"`
+	BanUntil       string               `json:"banuntil,omitempty"`
 }
 
 // GetGraphStateResult data
@@ -69,8 +72,11 @@ type GetGraphStateResult struct {
 }
 
 type GetBanlistResult struct {
-	PeerID string         `json:"peerid"`
-	Bads   []*BadResponse `json:"bads"`
+	PeerID   string         `json:"peerid"`
+	Bads     []*BadResponse `json:"bads"`
+	Score    float64        `json:"score,omitempty"`
+	BanUntil string         `json:"banuntil,omitempty"`
+	Reason   string         `json:"reason,omitempty"`
 }
 
 type BadResponse struct {
@@ -143,3 +149,94 @@ type ChainInfoResult struct {
 	EmptyBlockRate   string  `json:"emptyblockrate"`
 	ProcessQueueSize int32   `json:"processqueuesize"`
 }
+
+type LightInfo struct {
+	TipOrder        uint64   `json:"tiporder"`
+	TipHash         string   `json:"tiphash"`
+	CheckpointOrder uint64   `json:"checkpointorder"`
+	Peers           int      `json:"peers"`
+	SyncPeer        string   `json:"syncpeer,omitempty"`
+	PeerOrder       uint64   `json:"peerorder"`
+	Addrs           []string `json:"addrs,omitempty"`
+	MatchedBlocks   int      `json:"matchedblocks"`
+}
+
+type LightHeader struct {
+	Hash       string `json:"hash"`
+	Order      uint64 `json:"order"`
+	Version    uint32 `json:"version"`
+	ParentRoot string `json:"parentroot"`
+	TxRoot     string `json:"txroot"`
+	StateRoot  string `json:"stateroot"`
+	Difficulty uint32 `json:"difficulty"`
+	Timestamp  int64  `json:"timestamp"`
+	PowType    string `json:"powtype"`
+	Nonce      uint64 `json:"nonce"`
+}
+
+type LightMatchedBlock struct {
+	Hash  string   `json:"hash"`
+	Order uint64   `json:"order"`
+	Txs   []string `json:"txs"`
+}
+
+// CFIndexInfo models the state of the committed filter index.
+type CFIndexInfo struct {
+	TipOrder uint64 `json:"tiporder"`
+	TipHash  string `json:"tiphash"`
+	Indexed  bool   `json:"indexed"`
+}
+
+// ExplorerCoinTotal models the totals of a coin in the explorer index.
+type ExplorerCoinTotal struct {
+	CoinId    uint16 `json:"coinid"`
+	CoinName  string `json:"coinname"`
+	Balance   int64  `json:"balance"`
+	Received  int64  `json:"received"`
+	Sent      int64  `json:"sent"`
+	Addresses uint64 `json:"addresses"`
+}
+
+// ExplorerIndexInfo models the state of the explorer index.
+type ExplorerIndexInfo struct {
+	TipOrder uint64              `json:"tiporder"`
+	TipHash  string              `json:"tiphash"`
+	Indexed  bool                `json:"indexed"`
+	Coins    []ExplorerCoinTotal `json:"coins"`
+}
+
+// AddressCoinSummary models the balance of an address in a coin.
+type AddressCoinSummary struct {
+	CoinId        uint16 `json:"coinid"`
+	CoinName      string `json:"coinname"`
+	Balance       int64  `json:"balance"`
+	Received      int64  `json:"received"`
+	Sent          int64  `json:"sent"`
+	ReceivedCount uint32 `json:"receivedcount"`
+	SentCount     uint32 `json:"sentcount"`
+	FirstOrder    uint64 `json:"firstorder"`
+	LastOrder     uint64 `json:"lastorder"`
+}
+
+// AddressSummary models the balances of an address in all coins.
+type AddressSummary struct {
+	Address string               `json:"address"`
+	Coins   []AddressCoinSummary `json:"coins"`
+}
+
+// BalanceAtOrder models the balance of an address after the block of the
+// order, the last order is the one of its last change at or before the order.
+type BalanceAtOrder struct {
+	Address   string  `json:"address"`
+	CoinId    uint16  `json:"coinid"`
+	Order     uint64  `json:"order"`
+	Balance   int64   `json:"balance"`
+	LastOrder *uint64 `json:"lastorder,omitempty"`
+}
+
+// RichListEntry models an address of the rich list.
+type RichListEntry struct {
+	Rank    int    `json:"rank"`
+	Address string `json:"address"`
+	Balance int64  `json:"balance"`
+}
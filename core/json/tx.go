@@ -225,3 +225,21 @@ type AmountV3 struct {
 type AdreesAmount map[string]Amout
 
 type AddressAmountV3 map[string]AmountV3
+
+// RescanJobResult models the data from the getRescanJob command.
+type RescanJobResult struct {
+	ID         uint64  `json:"id"`
+	Owner      string  `json:"owner"`
+	State      string  `json:"state"`
+	Addresses  int     `json:"addresses"`
+	OutPoints  int     `json:"outpoints"`
+	BeginOrder uint64  `json:"beginorder"`
+	EndOrder   uint64  `json:"endorder"`
+	NextOrder  uint64  `json:"nextorder"`
+	Progress   float64 `json:"progress"`
+	Rate       float64 `json:"rate"`
+	Matches    uint64  `json:"matches"`
+	Error      string  `json:"error,omitempty"`
+	Created    int64   `json:"created"`
+	Updated    int64   `json:"updated"`
+}
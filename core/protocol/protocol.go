@@ -24,8 +24,17 @@ const (
 	// Support continue block sync for DAG search
 	BroadcastblockProtocolVersion uint32 = 45
 
+	// Support compact block relay
+	CompactBlockProtocolVersion uint32 = 46
+
+	// Support Dandelion++ stem transaction relay
+	DandelionProtocolVersion uint32 = 47
+
+	// Support the block parents in merkle blocks
+	MerkleParentsProtocolVersion uint32 = 48
+
 	// ProtocolVersion is the latest protocol version this package supports.
-	ProtocolVersion uint32 = BroadcastblockProtocolVersion
+	ProtocolVersion uint32 = MerkleParentsProtocolVersion
 )
 
 // Network represents which qitmeer network a message belongs to.
@@ -14,6 +14,7 @@ var sfStrings = map[ServiceFlag]string{
 	Relay:    "Relay",
 	Observer: "Observer",
 	Unknown:  "Unknown",
+	Pruned:   "Pruned",
 }
 
 // orderedSFStrings is an ordered list of service flags from highest to
@@ -26,6 +27,7 @@ var orderedSFStrings = []ServiceFlag{
 	Relay,
 	Observer,
 	Unknown,
+	Pruned,
 }
 
 // ServiceFlag identifies services supported by a peer node.
@@ -52,6 +54,9 @@ const (
 
 	// None
 	Unknown
+
+	// a peer keeps the headers of all blocks but only the recent bodies.
+	Pruned
 )
 
 // String returns the ServiceFlag in human-readable form.
@@ -50,20 +50,20 @@ func (b *BlockState) GetWeight() uint64 {
 	return b.weight
 }
 
-func (b *BlockState) setStatusFlags(flags model.BlockStatus) {
+func (b *BlockState) SetStatusFlags(flags model.BlockStatus) {
 	b.status |= flags
 }
 
-func (b *BlockState) unsetStatusFlags(flags model.BlockStatus) {
+func (b *BlockState) UnsetStatusFlags(flags model.BlockStatus) {
 	b.status &^= flags
 }
 
 func (b *BlockState) Valid() {
-	b.unsetStatusFlags(model.StatusInvalid)
+	b.UnsetStatusFlags(model.StatusInvalid)
 }
 
 func (b *BlockState) Invalid() {
-	b.setStatusFlags(model.StatusInvalid)
+	b.SetStatusFlags(model.StatusInvalid)
 }
 
 func (b *BlockState) GetStatus() model.BlockStatus {
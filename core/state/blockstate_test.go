@@ -19,3 +19,21 @@ func TestBlockStatus(t *testing.T) {
 		t.Fatal("status", bs.status)
 	}
 }
+
+func TestBlockStatusInvalidated(t *testing.T) {
+	bs := &BlockState{status: model.StatusNone}
+	bs.SetStatusFlags(model.StatusInvalidated)
+	if !bs.status.IsInvalidated() || bs.status.KnownInvalid() {
+		t.Fatal("status", bs.status)
+	}
+	// The validation result doesn't clear the mark of operator
+	bs.Invalid()
+	bs.Valid()
+	if !bs.status.IsInvalidated() || bs.status.String() != "invalidated" {
+		t.Fatal("status", bs.status)
+	}
+	bs.UnsetStatusFlags(model.StatusInvalidated)
+	if bs.status != model.StatusNone {
+		t.Fatal("status", bs.status)
+	}
+}
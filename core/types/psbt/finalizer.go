@@ -0,0 +1,170 @@
+package psbt
+
+import (
+	"fmt"
+
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/engine/txscript"
+)
+
+// verifyFlags are the script flags which are used to verify the finalized
+// inputs, they're the consensus rules of the signature scripts.
+const verifyFlags = txscript.ScriptBip16 |
+	txscript.ScriptVerifyDERSignatures |
+	txscript.ScriptVerifyStrictEncoding |
+	txscript.ScriptVerifyCheckLockTimeVerify
+
+// InputStatus returns the script class of input, the number of signatures
+// which are collected and required.
+func (p *Packet) InputStatus(idx int) (txscript.ScriptClass, int, int) {
+	in := p.Inputs[idx]
+	if in.IsFinalized() {
+		class := txscript.NonStandardTy
+		if in.PrevOut != nil {
+			class = txscript.GetScriptClass(txscript.DefaultScriptVersion, in.PrevOut.PkScript)
+		}
+		return class, 0, 0
+	}
+	script, class, err := in.signScript()
+	if err != nil {
+		return class, 0, 0
+	}
+	required := 1
+	if class == txscript.MultiSigTy {
+		_, required, err = txscript.CalcMultiSigStats(script)
+		if err != nil {
+			return class, 0, 0
+		}
+	}
+	return class, len(in.PartialSigs), required
+}
+
+// finalSignScript builds the signature script of input from the partial
+// signatures.
+func (p *Packet) finalSignScript(idx int) ([]byte, error) {
+	in := p.Inputs[idx]
+	script, class, err := in.signScript()
+	if err != nil {
+		return nil, err
+	}
+	ss, err := signers(script, class)
+	if err != nil {
+		return nil, err
+	}
+	builder := txscript.NewScriptBuilder()
+	switch class {
+	case txscript.MultiSigTy:
+		_, required, err := txscript.CalcMultiSigStats(script)
+		if err != nil {
+			return nil, err
+		}
+		// The signatures must be in the order of the public keys
+		signed := 0
+		for _, pk := range ss {
+			ps := in.partialSig(pk)
+			if ps == nil {
+				continue
+			}
+			builder.AddData(ps.Signature)
+			signed++
+			if signed == required {
+				break
+			}
+		}
+		if signed < required {
+			return nil, fmt.Errorf("%d/%d signatures", signed, required)
+		}
+	case txscript.PubKeyTy:
+		ps := in.partialSig(ss[0])
+		if ps == nil {
+			return nil, fmt.Errorf("0/1 signatures")
+		}
+		builder.AddData(ps.Signature)
+	case txscript.PubKeyHashTy, txscript.CLTVPubKeyHashTy:
+		var sig *PartialSig
+		for _, ps := range in.PartialSigs {
+			if matchHash(ss[0], ps.PubKey) {
+				sig = ps
+				break
+			}
+		}
+		if sig == nil {
+			return nil, fmt.Errorf("0/1 signatures")
+		}
+		if class == txscript.CLTVPubKeyHashTy {
+			if p.UnsignedTx.TxIn[idx].Sequence == types.MaxTxInSequenceNum || p.UnsignedTx.LockTime == 0 {
+				return nil, fmt.Errorf("the lock time of transaction isn't enabled")
+			}
+		}
+		builder.AddData(sig.Signature).AddData(sig.PubKey)
+	}
+	if len(in.RedeemScript) > 0 {
+		builder.AddData(in.RedeemScript)
+	}
+	return builder.Script()
+}
+
+// Finalize builds the signature scripts of all the inputs which have enough
+// signatures, every finalized script is verified by the script engine. It
+// returns an error for the first input which can't be finalized, the inputs
+// before it are still finalized.
+func (p *Packet) Finalize() error {
+	for i, in := range p.Inputs {
+		if in.IsFinalized() {
+			continue
+		}
+		err := p.FinalizeInput(i)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FinalizeInput builds and verifies the signature script of input idx.
+func (p *Packet) FinalizeInput(idx int) error {
+	if idx < 0 || idx >= len(p.Inputs) {
+		return fmt.Errorf("input index %d out of range", idx)
+	}
+	in := p.Inputs[idx]
+	if in.IsFinalized() {
+		return nil
+	}
+	sigScript, err := p.finalSignScript(idx)
+	if err != nil {
+		return fmt.Errorf("input %d: %v", idx, err)
+	}
+	tx, err := p.copyTx()
+	if err != nil {
+		return err
+	}
+	tx.TxIn[idx].SignScript = sigScript
+	vm, err := txscript.NewEngine(in.PrevOut.PkScript, types.NewTx(tx), idx, verifyFlags,
+		txscript.DefaultScriptVersion, nil)
+	if err != nil {
+		return fmt.Errorf("input %d: %v", idx, err)
+	}
+	err = vm.Execute()
+	if err != nil {
+		return fmt.Errorf("input %d: %v", idx, err)
+	}
+	in.FinalSignScript = sigScript
+	in.PartialSigs = nil
+	in.RedeemScript = nil
+	return nil
+}
+
+// Extract returns the signed transaction, all the inputs must be finalized.
+func (p *Packet) Extract() (*types.Transaction, error) {
+	if !p.IsComplete() {
+		return nil, ErrNotFinalized
+	}
+	tx, err := p.copyTx()
+	if err != nil {
+		return nil, err
+	}
+	for i, in := range p.Inputs {
+		tx.TxIn[i].SignScript = in.FinalSignScript
+	}
+	return tx, nil
+}
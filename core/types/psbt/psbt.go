@@ -0,0 +1,403 @@
+// Copyright (c) 2017-2018 The qitmeer developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package psbt implements a partially signed transaction container which is
+// modeled after BIP174. It carries an unsigned transaction together with the
+// previous outputs, redeem scripts and partial signatures of its inputs, so
+// that the signers of a multisig input can work independently and pass the
+// container around until it can be finalized.
+package psbt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	s "github.com/Qitmeer/qng/core/serialization"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/engine/txscript"
+)
+
+// magic is the prefix of every serialized packet.
+var magic = []byte{'q', 'p', 's', 't', 0xff}
+
+// maxValueSize is the maximum size of a key or value in the packet.
+const maxValueSize = 4000000
+
+// The key types of the global map.
+const (
+	globalUnsignedTx = 0x00
+)
+
+// The key types of the input maps.
+const (
+	inputPrevOut         = 0x01
+	inputPartialSig      = 0x02
+	inputSigHashType     = 0x03
+	inputRedeemScript    = 0x04
+	inputFinalSignScript = 0x07
+)
+
+var (
+	// ErrInvalidMagic is returned if the data isn't a packet.
+	ErrInvalidMagic = errors.New("invalid partially signed transaction magic")
+
+	// ErrDuplicateKey is returned if a key appears twice in one map.
+	ErrDuplicateKey = errors.New("duplicate key in partially signed transaction")
+
+	// ErrSignedTx is returned if the transaction of packet has any signature
+	// script.
+	ErrSignedTx = errors.New("the transaction of packet must be unsigned")
+
+	// ErrNotFinalized is returned by Extract if any input isn't finalized.
+	ErrNotFinalized = errors.New("not all the inputs are finalized")
+
+	// ErrMismatchedTx is returned by Combine if the packets have different
+	// transactions.
+	ErrMismatchedTx = errors.New("the packets have different transactions")
+)
+
+// PrevOut is the output which is spent by an input.
+type PrevOut struct {
+	Amount   types.Amount
+	PkScript []byte
+}
+
+// PartialSig is a signature of one public key, the signature hash type is
+// appended to the signature.
+type PartialSig struct {
+	PubKey    []byte
+	Signature []byte
+}
+
+// Unknown is a key-value pair which isn't known by this implementation, it's
+// kept so that the packet can be passed through.
+type Unknown struct {
+	Key   []byte
+	Value []byte
+}
+
+// PInput is the signing data of an input.
+type PInput struct {
+	PrevOut         *PrevOut
+	PartialSigs     []*PartialSig
+	SigHashType     txscript.SigHashType
+	RedeemScript    []byte
+	FinalSignScript []byte
+	Unknowns        []*Unknown
+}
+
+// IsFinalized returns true if the signature script of input is completed.
+func (in *PInput) IsFinalized() bool {
+	return len(in.FinalSignScript) > 0
+}
+
+// addPartialSig adds the signature or replaces the one of the same public
+// key.
+func (in *PInput) addPartialSig(ps *PartialSig) {
+	for i, old := range in.PartialSigs {
+		if bytes.Equal(old.PubKey, ps.PubKey) {
+			in.PartialSigs[i] = ps
+			return
+		}
+	}
+	in.PartialSigs = append(in.PartialSigs, ps)
+}
+
+func (in *PInput) partialSig(pubKey []byte) *PartialSig {
+	for _, ps := range in.PartialSigs {
+		if bytes.Equal(ps.PubKey, pubKey) {
+			return ps
+		}
+	}
+	return nil
+}
+
+// Packet is a partially signed transaction.
+type Packet struct {
+	UnsignedTx *types.Transaction
+	Inputs     []*PInput
+	Unknowns   []*Unknown
+}
+
+// New returns a packet of the unsigned transaction.
+func New(tx *types.Transaction) (*Packet, error) {
+	for _, in := range tx.TxIn {
+		if len(in.SignScript) > 0 {
+			return nil, ErrSignedTx
+		}
+	}
+	p := &Packet{
+		UnsignedTx: tx,
+		Inputs:     make([]*PInput, len(tx.TxIn)),
+	}
+	for i := range p.Inputs {
+		p.Inputs[i] = &PInput{SigHashType: txscript.SigHashAll}
+	}
+	return p, nil
+}
+
+// IsComplete returns true if all the inputs are finalized.
+func (p *Packet) IsComplete() bool {
+	for _, in := range p.Inputs {
+		if !in.IsFinalized() {
+			return false
+		}
+	}
+	return true
+}
+
+// copyTx returns a deep copy of the unsigned transaction.
+func (p *Packet) copyTx() (*types.Transaction, error) {
+	raw, err := p.UnsignedTx.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	tx := &types.Transaction{}
+	err = tx.Deserialize(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func writeKV(w io.Writer, key []byte, value []byte) error {
+	err := s.WriteVarBytes(w, 0, key)
+	if err != nil {
+		return err
+	}
+	return s.WriteVarBytes(w, 0, value)
+}
+
+func writeUnknowns(w io.Writer, unknowns []*Unknown) error {
+	for _, u := range unknowns {
+		err := writeKV(w, u.Key, u.Value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readKV reads a key-value pair, the key is nil at the end of map.
+func readKV(r io.Reader) ([]byte, []byte, error) {
+	key, err := s.ReadVarBytes(r, 0, maxValueSize, "key")
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(key) == 0 {
+		return nil, nil, nil
+	}
+	value, err := s.ReadVarBytes(r, 0, maxValueSize, "value")
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, value, nil
+}
+
+// Serialize writes the binary encoding of packet.
+func (p *Packet) Serialize(w io.Writer) error {
+	_, err := w.Write(magic)
+	if err != nil {
+		return err
+	}
+	raw, err := p.UnsignedTx.Serialize()
+	if err != nil {
+		return err
+	}
+	err = writeKV(w, []byte{globalUnsignedTx}, raw)
+	if err != nil {
+		return err
+	}
+	err = writeUnknowns(w, p.Unknowns)
+	if err != nil {
+		return err
+	}
+	err = s.WriteVarBytes(w, 0, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, in := range p.Inputs {
+		if in.PrevOut != nil {
+			var buf bytes.Buffer
+			var b [10]byte
+			binary.LittleEndian.PutUint16(b[:2], uint16(in.PrevOut.Amount.Id))
+			binary.LittleEndian.PutUint64(b[2:], uint64(in.PrevOut.Amount.Value))
+			buf.Write(b[:])
+			err = s.WriteVarBytes(&buf, 0, in.PrevOut.PkScript)
+			if err != nil {
+				return err
+			}
+			err = writeKV(w, []byte{inputPrevOut}, buf.Bytes())
+			if err != nil {
+				return err
+			}
+		}
+		for _, ps := range in.PartialSigs {
+			key := append([]byte{inputPartialSig}, ps.PubKey...)
+			err = writeKV(w, key, ps.Signature)
+			if err != nil {
+				return err
+			}
+		}
+		if in.SigHashType != txscript.SigHashAll {
+			var b [4]byte
+			binary.LittleEndian.PutUint32(b[:], uint32(in.SigHashType))
+			err = writeKV(w, []byte{inputSigHashType}, b[:])
+			if err != nil {
+				return err
+			}
+		}
+		if len(in.RedeemScript) > 0 {
+			err = writeKV(w, []byte{inputRedeemScript}, in.RedeemScript)
+			if err != nil {
+				return err
+			}
+		}
+		if len(in.FinalSignScript) > 0 {
+			err = writeKV(w, []byte{inputFinalSignScript}, in.FinalSignScript)
+			if err != nil {
+				return err
+			}
+		}
+		err = writeUnknowns(w, in.Unknowns)
+		if err != nil {
+			return err
+		}
+		err = s.WriteVarBytes(w, 0, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Deserialize reads the binary encoding of packet.
+func Deserialize(r io.Reader) (*Packet, error) {
+	m := make([]byte, len(magic))
+	_, err := io.ReadFull(r, m)
+	if err != nil || !bytes.Equal(m, magic) {
+		return nil, ErrInvalidMagic
+	}
+
+	var tx *types.Transaction
+	unknowns := []*Unknown{}
+	seen := map[string]struct{}{}
+	for {
+		key, value, err := readKV(r)
+		if err != nil {
+			return nil, err
+		}
+		if key == nil {
+			break
+		}
+		if _, ok := seen[string(key)]; ok {
+			return nil, ErrDuplicateKey
+		}
+		seen[string(key)] = struct{}{}
+		if len(key) == 1 && key[0] == globalUnsignedTx {
+			tx = &types.Transaction{}
+			err = tx.Deserialize(bytes.NewReader(value))
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		unknowns = append(unknowns, &Unknown{Key: key, Value: value})
+	}
+	if tx == nil {
+		return nil, fmt.Errorf("no unsigned transaction in packet")
+	}
+	p, err := New(tx)
+	if err != nil {
+		return nil, err
+	}
+	if len(unknowns) > 0 {
+		p.Unknowns = unknowns
+	}
+
+	for _, in := range p.Inputs {
+		seen := map[string]struct{}{}
+		for {
+			key, value, err := readKV(r)
+			if err != nil {
+				return nil, err
+			}
+			if key == nil {
+				break
+			}
+			if _, ok := seen[string(key)]; ok {
+				return nil, ErrDuplicateKey
+			}
+			seen[string(key)] = struct{}{}
+			switch key[0] {
+			case inputPrevOut:
+				if len(value) < 10 {
+					return nil, fmt.Errorf("invalid previous output of input")
+				}
+				pkScript, err := s.ReadVarBytes(bytes.NewReader(value[10:]), 0, maxValueSize, "pkscript")
+				if err != nil {
+					return nil, err
+				}
+				in.PrevOut = &PrevOut{
+					Amount: types.Amount{
+						Id:    types.CoinID(binary.LittleEndian.Uint16(value[:2])),
+						Value: int64(binary.LittleEndian.Uint64(value[2:10])),
+					},
+					PkScript: pkScript,
+				}
+			case inputPartialSig:
+				in.PartialSigs = append(in.PartialSigs, &PartialSig{
+					PubKey:    key[1:],
+					Signature: value,
+				})
+			case inputSigHashType:
+				if len(value) != 4 {
+					return nil, fmt.Errorf("invalid signature hash type of input")
+				}
+				in.SigHashType = txscript.SigHashType(binary.LittleEndian.Uint32(value))
+			case inputRedeemScript:
+				in.RedeemScript = value
+			case inputFinalSignScript:
+				in.FinalSignScript = value
+			default:
+				in.Unknowns = append(in.Unknowns, &Unknown{Key: key, Value: value})
+			}
+		}
+	}
+	return p, nil
+}
+
+// Bytes returns the binary encoding of packet.
+func (p *Packet) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	err := p.Serialize(&buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// B64Encode returns the base64 encoding of packet, it's the form which is
+// passed between the signers.
+func (p *Packet) B64Encode() (string, error) {
+	raw, err := p.Bytes()
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(raw), nil
+}
+
+// NewFromBase64 decodes the packet from base64 encoding.
+func NewFromBase64(str string) (*Packet, error) {
+	raw, err := base64.StdEncoding.DecodeString(str)
+	if err != nil {
+		return nil, err
+	}
+	return Deserialize(bytes.NewReader(raw))
+}
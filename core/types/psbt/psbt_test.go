@@ -0,0 +1,215 @@
+package psbt
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/core/address"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/crypto/ecc"
+	"github.com/Qitmeer/qng/engine/txscript"
+	"github.com/Qitmeer/qng/params"
+)
+
+func testKeys(t *testing.T, n int) ([][]byte, []*address.SecpPubKeyAddress) {
+	keys := [][]byte{}
+	addrs := []*address.SecpPubKeyAddress{}
+	for i := 0; i < n; i++ {
+		key := bytes.Repeat([]byte{byte(i + 1)}, 32)
+		_, pub := ecc.Secp256k1.PrivKeyFromBytes(key)
+		addr, err := address.NewSecpPubKeyAddress(pub.SerializeCompressed(), &params.TestNetParams)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+		addrs = append(addrs, addr)
+	}
+	return keys, addrs
+}
+
+func testTx(inputs int, lockTime uint32, sequence uint32) *types.Transaction {
+	tx := types.NewTransaction()
+	for i := 0; i < inputs; i++ {
+		h := hash.HashH([]byte{byte(i)})
+		in := types.NewTxInput(types.NewOutPoint(&h, uint32(i)), nil)
+		in.Sequence = sequence
+		tx.AddTxIn(in)
+	}
+	tx.AddTxOut(types.NewTxOutput(types.Amount{Value: 1e8, Id: types.MEERA}, []byte{txscript.OP_TRUE}))
+	tx.LockTime = lockTime
+	return tx
+}
+
+func roundTrip(t *testing.T, p *Packet) *Packet {
+	str, err := p.B64Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := NewFromBase64(str)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestMultiSig(t *testing.T) {
+	keys, addrs := testKeys(t, 3)
+	redeemScript, err := txscript.MultiSigScript(addrs, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2sh, err := address.NewScriptHashAddress(redeemScript, &params.TestNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2shScript, err := txscript.PayToAddrScript(p2sh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	amount := types.Amount{Value: 2e8, Id: types.MEERA}
+
+	// The first input is a P2SH multisig, the second is a bare multisig
+	p, err := New(testTx(2, 0, types.MaxTxInSequenceNum))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.AddPrevOut(0, amount, p2shScript); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.AddPrevOut(1, amount, redeemScript); err != nil {
+		t.Fatal(err)
+	}
+	if p.AddRedeemScript(redeemScript) != 1 {
+		t.Fatal("the redeem script isn't matched")
+	}
+	created := roundTrip(t, p)
+
+	// Two signers sign their copies independently
+	first := roundTrip(t, created)
+	if n, err := first.Sign(keys[2]); err != nil || n != 2 {
+		t.Fatalf("signed %d inputs: %v", n, err)
+	}
+	second := roundTrip(t, created)
+	if n, err := second.Sign(keys[0]); err != nil || n != 2 {
+		t.Fatalf("signed %d inputs: %v", n, err)
+	}
+	if err := first.Finalize(); err == nil {
+		t.Fatal("the input with one signature is finalized")
+	}
+	if _, _, required := first.InputStatus(0); required != 2 {
+		t.Fatalf("unexpected required signatures %d", required)
+	}
+
+	combined := roundTrip(t, created)
+	if err := combined.Combine(roundTrip(t, first), roundTrip(t, second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, have, _ := combined.InputStatus(1); have != 2 {
+		t.Fatalf("unexpected signatures %d", have)
+	}
+	if _, err := combined.Extract(); err != ErrNotFinalized {
+		t.Fatal("the incomplete transaction is extracted")
+	}
+	if err := combined.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	final := roundTrip(t, combined)
+	tx, err := final.Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range tx.TxIn {
+		vm, err := txscript.NewEngine(final.Inputs[i].PrevOut.PkScript, types.NewTx(tx), i, verifyFlags,
+			txscript.DefaultScriptVersion, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := vm.Execute(); err != nil {
+			t.Fatalf("input %d: %v", i, err)
+		}
+	}
+
+	other, err := New(testTx(1, 0, types.MaxTxInSequenceNum))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := combined.Combine(other); err != ErrMismatchedTx {
+		t.Fatal("the packets of different transactions are combined")
+	}
+}
+
+func TestCLTVPubKeyHash(t *testing.T) {
+	keys, addrs := testKeys(t, 2)
+	lockTime := int64(1000)
+	pkh := addrs[0].PKHAddress()
+	pkScript, err := txscript.PayToCLTVPubKeyHashScript(pkh.Script(), lockTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	amount := types.Amount{Value: 2e8, Id: types.MEERA}
+
+	// The lock time of transaction must be enabled
+	p, err := New(testTx(1, uint32(lockTime), types.MaxTxInSequenceNum))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.AddPrevOut(0, amount, pkScript); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := p.Sign(keys[0]); err != nil || n != 1 {
+		t.Fatalf("signed %d inputs: %v", n, err)
+	}
+	if err := p.Finalize(); err == nil {
+		t.Fatal("the final sequence is finalized")
+	}
+
+	p, err = New(testTx(1, uint32(lockTime), types.MaxTxInSequenceNum-1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.AddPrevOut(0, amount, pkScript); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.AddPrevOut(0, types.Amount{Value: 1, Id: types.MEERA}, pkScript); err == nil {
+		t.Fatal("the previous output is replaced")
+	}
+	if n, err := p.Sign(keys[1]); err != nil || n != 0 {
+		t.Fatalf("signed %d inputs by other key: %v", n, err)
+	}
+	if n, err := p.Sign(keys[0]); err != nil || n != 1 {
+		t.Fatalf("signed %d inputs: %v", n, err)
+	}
+	p = roundTrip(t, p)
+	if err := p.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	if !p.IsComplete() {
+		t.Fatal("the packet isn't complete")
+	}
+	if _, err := p.Extract(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeserialize(t *testing.T) {
+	p, err := New(testTx(1, 0, types.MaxTxInSequenceNum))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Inputs[0].Unknowns = []*Unknown{{Key: []byte{0xf0, 1}, Value: []byte{2}}}
+	p.Inputs[0].SigHashType = txscript.SigHashAll | txscript.SigHashAnyOneCanPay
+	result := roundTrip(t, p)
+	if len(result.Inputs[0].Unknowns) != 1 || result.Inputs[0].SigHashType != p.Inputs[0].SigHashType {
+		t.Fatal("the input isn't restored")
+	}
+	if _, err := Deserialize(bytes.NewReader([]byte("psbt"))); err != ErrInvalidMagic {
+		t.Fatal("the invalid magic is accepted")
+	}
+
+	signed := testTx(1, 0, types.MaxTxInSequenceNum)
+	signed.TxIn[0].SignScript = []byte{txscript.OP_TRUE}
+	if _, err := New(signed); err != ErrSignedTx {
+		t.Fatal("the signed transaction is accepted")
+	}
+}
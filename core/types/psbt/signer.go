@@ -0,0 +1,182 @@
+package psbt
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/crypto/ecc"
+	"github.com/Qitmeer/qng/engine/txscript"
+)
+
+// signScript returns the script which is signed by the input, it's the
+// redeem script for the pay-to-script-hash output.
+func (in *PInput) signScript() ([]byte, txscript.ScriptClass, error) {
+	if in.PrevOut == nil {
+		return nil, txscript.NonStandardTy, fmt.Errorf("no previous output")
+	}
+	script := in.PrevOut.PkScript
+	class := txscript.GetScriptClass(txscript.DefaultScriptVersion, script)
+	if class == txscript.ScriptHashTy {
+		if len(in.RedeemScript) <= 0 {
+			return nil, class, fmt.Errorf("no redeem script")
+		}
+		script = in.RedeemScript
+		class = txscript.GetScriptClass(txscript.DefaultScriptVersion, script)
+	}
+	switch class {
+	case txscript.MultiSigTy, txscript.PubKeyTy, txscript.PubKeyHashTy,
+		txscript.CLTVPubKeyHashTy:
+	default:
+		return nil, class, fmt.Errorf("unsupported script %s", class)
+	}
+	return script, class, nil
+}
+
+// signers returns the public keys or public key hashes which can sign the
+// script in order.
+func signers(script []byte, class txscript.ScriptClass) ([][]byte, error) {
+	data, err := txscript.PushedData(script)
+	if err != nil {
+		return nil, err
+	}
+	switch class {
+	case txscript.PubKeyHashTy, txscript.CLTVPubKeyHashTy:
+		// The public key hash is the last push, the lock time of CLTV
+		// is pushed before it.
+		if len(data) <= 0 {
+			return nil, fmt.Errorf("no public key hash")
+		}
+		return data[len(data)-1:], nil
+	}
+	return data, nil
+}
+
+// matchKey returns the serialized public key which matches the signer, the
+// signer is either a public key or a public key hash.
+func matchKey(signer []byte, pub ecc.PublicKey) []byte {
+	for _, pk := range [][]byte{pub.SerializeCompressed(), pub.SerializeUncompressed()} {
+		if bytes.Equal(signer, pk) || bytes.Equal(signer, hash.Hash160(pk)) {
+			return pk
+		}
+	}
+	return nil
+}
+
+// matchHash returns true if the public key hash is of the public key.
+func matchHash(pkHash []byte, pubKey []byte) bool {
+	return bytes.Equal(pkHash, hash.Hash160(pubKey))
+}
+
+// AddPrevOut sets the output which is spent by input idx.
+func (p *Packet) AddPrevOut(idx int, amount types.Amount, pkScript []byte) error {
+	if idx < 0 || idx >= len(p.Inputs) {
+		return fmt.Errorf("input index %d out of range", idx)
+	}
+	in := p.Inputs[idx]
+	if in.PrevOut != nil && (in.PrevOut.Amount != amount || !bytes.Equal(in.PrevOut.PkScript, pkScript)) {
+		return fmt.Errorf("input %d has a different previous output", idx)
+	}
+	in.PrevOut = &PrevOut{Amount: amount, PkScript: pkScript}
+	return nil
+}
+
+// AddRedeemScript sets the redeem script to the pay-to-script-hash inputs
+// whose script hash matches. It returns the number of matched inputs.
+func (p *Packet) AddRedeemScript(script []byte) int {
+	scriptHash := hash.Hash160(script)
+	matched := 0
+	for _, in := range p.Inputs {
+		if in.PrevOut == nil || in.IsFinalized() ||
+			txscript.GetScriptClass(txscript.DefaultScriptVersion, in.PrevOut.PkScript) != txscript.ScriptHashTy {
+			continue
+		}
+		data, err := txscript.PushedData(in.PrevOut.PkScript)
+		if err != nil || len(data) != 1 || !bytes.Equal(data[0], scriptHash) {
+			continue
+		}
+		in.RedeemScript = script
+		matched++
+	}
+	return matched
+}
+
+// Sign adds the signatures of the private key to all the inputs which can be
+// signed by it. It returns the number of signed inputs.
+func (p *Packet) Sign(privKey []byte) (int, error) {
+	if len(privKey) != 32 {
+		return 0, fmt.Errorf("invalid ec private key bytes: %d", len(privKey))
+	}
+	key, pub := ecc.Secp256k1.PrivKeyFromBytes(privKey)
+	signed := 0
+	for i, in := range p.Inputs {
+		if in.IsFinalized() || in.PrevOut == nil {
+			continue
+		}
+		script, class, err := in.signScript()
+		if err != nil {
+			continue
+		}
+		ss, err := signers(script, class)
+		if err != nil {
+			return signed, err
+		}
+		for _, signer := range ss {
+			pk := matchKey(signer, pub)
+			if pk == nil {
+				continue
+			}
+			sig, err := txscript.RawTxInSignature(p.UnsignedTx, i, script, in.SigHashType, key)
+			if err != nil {
+				return signed, fmt.Errorf("input %d: %v", i, err)
+			}
+			in.addPartialSig(&PartialSig{PubKey: pk, Signature: sig})
+			signed++
+			break
+		}
+	}
+	return signed, nil
+}
+
+// Combine merges the data of other packets of the same transaction into the
+// packet.
+func (p *Packet) Combine(others ...*Packet) error {
+	txHash := p.UnsignedTx.TxHash()
+	for _, o := range others {
+		if o.UnsignedTx.TxHash() != txHash || len(o.Inputs) != len(p.Inputs) {
+			return ErrMismatchedTx
+		}
+		for i, oin := range o.Inputs {
+			in := p.Inputs[i]
+			if in.IsFinalized() {
+				continue
+			}
+			if oin.IsFinalized() {
+				p.Inputs[i] = oin
+				continue
+			}
+			if oin.PrevOut != nil {
+				err := p.AddPrevOut(i, oin.PrevOut.Amount, oin.PrevOut.PkScript)
+				if err != nil {
+					return err
+				}
+			}
+			if len(oin.RedeemScript) > 0 {
+				if len(in.RedeemScript) > 0 && !bytes.Equal(in.RedeemScript, oin.RedeemScript) {
+					return fmt.Errorf("input %d has a different redeem script", i)
+				}
+				in.RedeemScript = oin.RedeemScript
+			}
+			if oin.SigHashType != in.SigHashType {
+				return fmt.Errorf("input %d has a different signature hash type", i)
+			}
+			for _, ps := range oin.PartialSigs {
+				if in.partialSig(ps.PubKey) == nil {
+					in.PartialSigs = append(in.PartialSigs, ps)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -1163,3 +1163,22 @@ type AutoCollectUtxo struct {
 	Address string
 	Amount  uint64
 }
+
+// TxReplaced is sent by the mempool when a transaction replaces the
+// transactions which spend the same outputs, along with their descendants.
+type TxReplaced struct {
+	Tx       *Tx
+	Replaced []*Tx
+}
+
+// TxPoolAdded is sent by the mempool when a transaction is added to it.
+type TxPoolAdded struct {
+	Tx  *Tx
+	Fee int64
+}
+
+// TxPoolRemoved is sent by the mempool when a transaction is removed from
+// it, because it is mined, replaced, double spent or expired.
+type TxPoolRemoved struct {
+	Tx *Tx
+}
@@ -153,6 +153,18 @@ func DetermineTxType(tx *Transaction) TxType {
 	if IsTokenUnmintTx(tx) {
 		return TxTypeTokenUnmint
 	}
+	if IsStakebaseTx(tx) {
+		return TxTypeStakebase
+	}
+	if IsStakeReserveTx(tx) {
+		return TyTypeStakeReserve
+	}
+	if IsStakePurchaseTx(tx) {
+		return TxTypeStakePurchase
+	}
+	if IsStakeDisposeTx(tx) {
+		return TxTypeStakeDispose
+	}
 	if IsCrossChainExportTx(tx) {
 		return TxTypeCrossChainExport
 	}
@@ -228,8 +240,85 @@ func IsGenesisLockTx(tx *Transaction) bool {
 
 // --------------------------------------------------------------------------------
 // Stake_XXX Transaction
+//
+// The first input of a stake transaction is not a real output, its previous
+// out index is SupperPrevOutIndex and its sequence is the type of the stake
+// transaction. All the amounts of the stake transactions are MEER.
+//
+//  - stake_purchase  the stake holder locks MEER into the stake pool.
+//      TxIn[0]      : the marker input.
+//      TxIn[1...]   : the MEER outputs which are spent.
+//      TxOut[0]     : the locked amount and the script of the stake holder, it isn't a utxo.
+//      TxOut[1...]  : the changes.
+//  - stake_dispose   the stake holder releases the stake after its maturity.
+//      TxIn[0]      : the marker input whose previous out hash is the hash of the stake_purchase,
+//                     it is signed for the script of the stake holder.
+//      TxOut[0]     : the whole locked amount paid to the script of the stake holder.
+//  - stake_reserve   reserves MEER into the stake reserve which pays the rewards.
+//      TxIn[0]      : the marker input.
+//      TxIn[1...]   : the MEER outputs which are spent.
+//      TxOut[0]     : the reserved amount, it isn't a utxo.
+//      TxOut[1...]  : the changes.
+//  - stakebase       rewards the matured stake holders from the stake reserve, which
+//                    can only be created by the miner of the block.
+//      TxIn[0]      : the marker input whose previous out hash begins with the block height.
+//      TxOut[...]   : the rewards of the stake holders.
+//
+// NO fee is allowed for the stake transactions: inputs amount == outputs amount
 // --------------------------------------------------------------------------------
 
+func isStakeMarkerTx(tx *Transaction, tt TxType) bool {
+	if len(tx.TxIn) < 1 || len(tx.TxOut) < 1 {
+		return false
+	}
+	if tx.TxIn[0].PreviousOut.OutIndex != SupperPrevOutIndex {
+		return false
+	}
+	return TxType(tx.TxIn[0].Sequence) == tt
+}
+
+func IsStakebaseTx(tx *Transaction) bool {
+	if len(tx.TxIn) != 1 {
+		return false
+	}
+	return isStakeMarkerTx(tx, TxTypeStakebase)
+}
+
+func IsStakeReserveTx(tx *Transaction) bool {
+	if len(tx.TxIn) <= 1 {
+		return false
+	}
+	return isStakeMarkerTx(tx, TyTypeStakeReserve)
+}
+
+func IsStakePurchaseTx(tx *Transaction) bool {
+	if len(tx.TxIn) <= 1 {
+		return false
+	}
+	return isStakeMarkerTx(tx, TxTypeStakePurchase)
+}
+
+func IsStakeDisposeTx(tx *Transaction) bool {
+	if len(tx.TxIn) != 1 || len(tx.TxOut) != 1 {
+		return false
+	}
+	return isStakeMarkerTx(tx, TxTypeStakeDispose)
+}
+
+// IsStakeLockTx returns whether or not the first output of the transaction
+// locks its amount into the stake pool instead of being a utxo.
+func IsStakeLockTx(tx *Transaction) bool {
+	return IsStakePurchaseTx(tx) ||
+		IsStakeReserveTx(tx)
+}
+
+func IsStakeTx(tx *Transaction) bool {
+	return IsStakebaseTx(tx) ||
+		IsStakeReserveTx(tx) ||
+		IsStakePurchaseTx(tx) ||
+		IsStakeDisposeTx(tx)
+}
+
 // --------------------------------------------------------------------------------
 // Token_XXX Transaction
 //
@@ -385,6 +474,13 @@ var TokenTxs = []TxType{
 	TxTypeTokenMint,
 }
 
+var StakeTxs = []TxType{
+	TxTypeStakebase,
+	TyTypeStakeReserve,
+	TxTypeStakePurchase,
+	TxTypeStakeDispose,
+}
+
 var MeerEVMTxs = []TxType{
 	TxTypeCrossChainImport,
 	TxTypeCrossChainExport,
@@ -0,0 +1,172 @@
+// Copyright (c) 2017-2018 The qitmeer developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package schnorr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+
+	"github.com/Qitmeer/qng/crypto/ecc/secp256k1"
+)
+
+// The BIP340 schnorr signatures are used by the taproot outputs, they differ
+// from the signatures above in that the public keys are only encoded by their
+// x coordinate and the points are implicitly chosen with an even y.
+const (
+	// XOnlyPubKeyLen is the length of a BIP340 x-only public key.
+	XOnlyPubKeyLen = 32
+
+	// BIP340SignatureLen is the length of a BIP340 signature.
+	BIP340SignatureLen = 64
+)
+
+var (
+	bip340AuxTag       = []byte("BIP0340/aux")
+	bip340NonceTag     = []byte("BIP0340/nonce")
+	bip340ChallengeTag = []byte("BIP0340/challenge")
+)
+
+// TaggedHash implements the tagged hash scheme of BIP340:
+// sha256(sha256(tag) || sha256(tag) || msgs...)
+func TaggedHash(tag []byte, msgs ...[]byte) []byte {
+	tagHash := sha256.Sum256(tag)
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	for _, msg := range msgs {
+		h.Write(msg)
+	}
+	return h.Sum(nil)
+}
+
+// liftX returns the point with the x coordinate and an even y coordinate.
+func liftX(x *big.Int) (*big.Int, *big.Int, error) {
+	curve := secp256k1.S256()
+	if x.Cmp(curve.P) >= 0 {
+		return nil, nil, fmt.Errorf("x coordinate is not less than the field size")
+	}
+	// y^2 = x^3 + 7 (mod p)
+	c := new(big.Int).Mul(x, x)
+	c.Mul(c, x)
+	c.Add(c, curve.B)
+	c.Mod(c, curve.P)
+	y := new(big.Int).Exp(c, curve.QPlus1Div4(), curve.P)
+	if new(big.Int).Exp(y, big.NewInt(2), curve.P).Cmp(c) != 0 {
+		return nil, nil, fmt.Errorf("x coordinate is not on the curve")
+	}
+	if y.Bit(0) == 1 {
+		y.Sub(curve.P, y)
+	}
+	return x, y, nil
+}
+
+// ParseXOnlyPubKey parses the 32 bytes x-only public key of BIP340.
+func ParseXOnlyPubKey(pubKey []byte) (*secp256k1.PublicKey, error) {
+	if len(pubKey) != XOnlyPubKeyLen {
+		return nil, fmt.Errorf("malformed x-only public key: invalid length %d", len(pubKey))
+	}
+	x, y, err := liftX(new(big.Int).SetBytes(pubKey))
+	if err != nil {
+		return nil, err
+	}
+	return secp256k1.NewPublicKey(x, y), nil
+}
+
+// SerializeXOnlyPubKey serializes the public key into the 32 bytes x-only
+// format of BIP340.
+func SerializeXOnlyPubKey(pubKey *secp256k1.PublicKey) []byte {
+	return BigIntToEncodedBytes(pubKey.GetX())[:]
+}
+
+// SignBIP340 signs the message with the private key following BIP340, the aux
+// random data is optional and all zeros are used when it's nil.
+func SignBIP340(privKey *secp256k1.PrivateKey, msg []byte, auxRand []byte) ([]byte, error) {
+	curve := secp256k1.S256()
+	d := new(big.Int).Set(privKey.GetD())
+	if d.Sign() <= 0 || d.Cmp(curve.N) >= 0 {
+		return nil, fmt.Errorf("private key is out of range")
+	}
+	px, py := curve.ScalarBaseMult(BigIntToEncodedBytes(d)[:])
+	if py.Bit(0) == 1 {
+		d.Sub(curve.N, d)
+	}
+	if auxRand == nil {
+		auxRand = make([]byte, scalarSize)
+	}
+	pxBytes := BigIntToEncodedBytes(px)[:]
+
+	t := BigIntToEncodedBytes(d)[:]
+	auxHash := TaggedHash(bip340AuxTag, auxRand)
+	for i := range t {
+		t[i] ^= auxHash[i]
+	}
+	rand := TaggedHash(bip340NonceTag, t, pxBytes, msg)
+	k := new(big.Int).SetBytes(rand)
+	k.Mod(k, curve.N)
+	if k.Sign() == 0 {
+		return nil, fmt.Errorf("the nonce is zero")
+	}
+	rx, ry := curve.ScalarBaseMult(BigIntToEncodedBytes(k)[:])
+	if ry.Bit(0) == 1 {
+		k.Sub(curve.N, k)
+	}
+	rxBytes := BigIntToEncodedBytes(rx)[:]
+
+	e := new(big.Int).SetBytes(TaggedHash(bip340ChallengeTag, rxBytes, pxBytes, msg))
+	e.Mod(e, curve.N)
+	s := new(big.Int).Mul(e, d)
+	s.Add(s, k)
+	s.Mod(s, curve.N)
+
+	sig := make([]byte, 0, BIP340SignatureLen)
+	sig = append(sig, rxBytes...)
+	sig = append(sig, BigIntToEncodedBytes(s)[:]...)
+
+	if err := VerifyBIP340(pxBytes, msg, sig); err != nil {
+		return nil, fmt.Errorf("the signature can't be verified: %v", err)
+	}
+	return sig, nil
+}
+
+// VerifyBIP340 verifies the BIP340 signature of the message with the x-only
+// public key.
+func VerifyBIP340(pubKey []byte, msg []byte, sig []byte) error {
+	if len(sig) != BIP340SignatureLen {
+		return fmt.Errorf("malformed signature: invalid length %d", len(sig))
+	}
+	pk, err := ParseXOnlyPubKey(pubKey)
+	if err != nil {
+		return err
+	}
+	curve := secp256k1.S256()
+	r := new(big.Int).SetBytes(sig[:32])
+	if r.Cmp(curve.P) >= 0 {
+		return fmt.Errorf("signature r is not less than the field size")
+	}
+	s := new(big.Int).SetBytes(sig[32:])
+	if s.Cmp(curve.N) >= 0 {
+		return fmt.Errorf("signature s is not less than the curve order")
+	}
+	e := new(big.Int).SetBytes(TaggedHash(bip340ChallengeTag, sig[:32], pubKey, msg))
+	e.Mod(e, curve.N)
+	e.Sub(curve.N, e)
+
+	// R = s*G - e*P
+	sgx, sgy := curve.ScalarBaseMult(BigIntToEncodedBytes(s)[:])
+	epx, epy := curve.ScalarMult(pk.GetX(), pk.GetY(), BigIntToEncodedBytes(e)[:])
+	rx, ry := curve.Add(sgx, sgy, epx, epy)
+	if rx.Sign() == 0 && ry.Sign() == 0 {
+		return fmt.Errorf("signature R is the point at infinity")
+	}
+	if ry.Bit(0) == 1 {
+		return fmt.Errorf("signature R has an odd y coordinate")
+	}
+	if !bytes.Equal(BigIntToEncodedBytes(rx)[:], sig[:32]) {
+		return fmt.Errorf("signature is invalid")
+	}
+	return nil
+}
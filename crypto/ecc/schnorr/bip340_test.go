@@ -0,0 +1,117 @@
+// Copyright (c) 2017-2018 The qitmeer developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package schnorr
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/Qitmeer/qng/crypto/ecc/secp256k1"
+)
+
+func decodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// TestBIP340Vectors checks the signing and verification against the test
+// vectors of BIP340.
+func TestBIP340Vectors(t *testing.T) {
+	tests := []struct {
+		secKey  string
+		pubKey  string
+		auxRand string
+		msg     string
+		sig     string
+		valid   bool
+	}{
+		{
+			secKey:  "0000000000000000000000000000000000000000000000000000000000000003",
+			pubKey:  "F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9",
+			auxRand: "0000000000000000000000000000000000000000000000000000000000000000",
+			msg:     "0000000000000000000000000000000000000000000000000000000000000000",
+			sig:     "E907831F80848D1069A5371B402410364BDF1C5F8307B0084C55F1CE2DCA821525F66A4A85EA8B71E482A74F382D2CE5EBEEE8FDB2172F477DF4900D310536C0",
+			valid:   true,
+		},
+		{
+			secKey:  "B7E151628AED2A6ABF7158809CF4F3C762E7160F38B4DA56A784D9045190CFEF",
+			pubKey:  "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+			auxRand: "0000000000000000000000000000000000000000000000000000000000000001",
+			msg:     "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+			sig:     "6896BD60EEAE296DB48A229FF71DFE071BDE413E6D43F917DC8DCF8C78DE33418906D11AC976ABCCB20B091292BFF4EA897EFCB639EA871CFA95F6DE339E4B0A",
+			valid:   true,
+		},
+		{
+			// public key not on the curve
+			pubKey: "EEFDEA4CDB677750A420FEE807EACF21EB9898AE79B9768766E4FAA04A2D4A34",
+			msg:    "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+			sig:    "6CFF5C3BA86C69EA4B7376F31A9BCB4F74C1976089B2D9963DA2E5543E17776969E89B4C5564D00349106B8497785DD7D1D713A8AE82B32FA79D5F7FC407D39B",
+			valid:  false,
+		},
+		{
+			// has_even_y(R) is false
+			pubKey: "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+			msg:    "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+			sig:    "FFF97BD5755EEEA420453A14355235D382F6472F8568A18B2F057A14602975563CC27944640AC607CD107AE10923D9EF7A73C643E166BE5EBEAFA34B1AC553E2",
+			valid:  false,
+		},
+	}
+
+	for i, test := range tests {
+		pubKey := decodeHex(t, test.pubKey)
+		msg := decodeHex(t, test.msg)
+		sig := decodeHex(t, test.sig)
+		if test.secKey != "" {
+			privKey, pub := secp256k1.PrivKeyFromBytes(decodeHex(t, test.secKey))
+			if !bytes.Equal(SerializeXOnlyPubKey(pub), pubKey) {
+				t.Fatalf("#%d: public key mismatch: got %x", i, SerializeXOnlyPubKey(pub))
+			}
+			got, err := SignBIP340(privKey, msg, decodeHex(t, test.auxRand))
+			if err != nil {
+				t.Fatalf("#%d: sign failed: %v", i, err)
+			}
+			if !bytes.Equal(got, sig) {
+				t.Fatalf("#%d: signature mismatch: got %s", i, strings.ToUpper(hex.EncodeToString(got)))
+			}
+		}
+		err := VerifyBIP340(pubKey, msg, sig)
+		if test.valid && err != nil {
+			t.Fatalf("#%d: expected valid signature: %v", i, err)
+		}
+		if !test.valid && err == nil {
+			t.Fatalf("#%d: expected invalid signature", i)
+		}
+	}
+}
+
+func TestBIP340Tampered(t *testing.T) {
+	privKey, pub := secp256k1.PrivKeyFromBytes(decodeHex(t,
+		"C90FDAA22168C234C4C6628B80DC1CD129024E088A67CC74020BBEA63B14E5C9"))
+	msg := decodeHex(t, "7E2D58D8B3BCDF1ABADEC7829054F90DDA9805AAB56C77333024B9D0A508B75C")
+	sig, err := SignBIP340(privKey, msg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubKey := SerializeXOnlyPubKey(pub)
+	if err := VerifyBIP340(pubKey, msg, sig); err != nil {
+		t.Fatal(err)
+	}
+	for _, idx := range []int{0, 31, 32, 63} {
+		bad := append([]byte{}, sig...)
+		bad[idx] ^= 0x01
+		if VerifyBIP340(pubKey, msg, bad) == nil {
+			t.Fatalf("tampered signature at byte %d verified", idx)
+		}
+	}
+	msg[0] ^= 0x01
+	if VerifyBIP340(pubKey, msg, sig) == nil {
+		t.Fatal("signature verified for another message")
+	}
+}
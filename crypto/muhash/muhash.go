@@ -0,0 +1,143 @@
+// Copyright (c) 2017-2018 The qitmeer developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package muhash implements MuHash3072, a rolling hash of sets. The elements
+// can be added and removed in any order, the result only depends on the set.
+//
+// Every element is mapped to a number modulo the prime 2^3072 - 1103717 by the
+// ChaCha20 keystream keyed with the sha256 of element, the set is represented
+// by the product of its elements. The final digest is the sha256 of product.
+package muhash
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"golang.org/x/crypto/chacha20"
+)
+
+const (
+	// ElementSize is the size of the serialized number of MuHash3072
+	ElementSize = 384
+
+	// The prime is 2^3072 - primeDiff
+	primeDiff = 1103717
+)
+
+var prime = func() *big.Int {
+	p := new(big.Int).Lsh(big.NewInt(1), ElementSize*8)
+	return p.Sub(p, big.NewInt(primeDiff))
+}()
+
+// MuHash is the rolling hash of a set, the zero value isn't usable and it must
+// be created by New.
+type MuHash struct {
+	numerator   *big.Int
+	denominator *big.Int
+}
+
+// New returns the MuHash of empty set.
+func New() *MuHash {
+	return &MuHash{
+		numerator:   big.NewInt(1),
+		denominator: big.NewInt(1),
+	}
+}
+
+// Add adds the element data to the set.
+func (m *MuHash) Add(data []byte) {
+	m.numerator.Mul(m.numerator, toElement(data))
+	m.numerator.Mod(m.numerator, prime)
+}
+
+// Remove removes the element data from the set, the element doesn't need to
+// be added before.
+func (m *MuHash) Remove(data []byte) {
+	m.denominator.Mul(m.denominator, toElement(data))
+	m.denominator.Mod(m.denominator, prime)
+}
+
+// Combine adds all the elements of other set.
+func (m *MuHash) Combine(other *MuHash) {
+	m.numerator.Mul(m.numerator, other.numerator)
+	m.numerator.Mod(m.numerator, prime)
+	m.denominator.Mul(m.denominator, other.denominator)
+	m.denominator.Mod(m.denominator, prime)
+}
+
+// Clone returns a copy of the MuHash
+func (m *MuHash) Clone() *MuHash {
+	return &MuHash{
+		numerator:   new(big.Int).Set(m.numerator),
+		denominator: new(big.Int).Set(m.denominator),
+	}
+}
+
+// Finalize returns the digest of the set.
+func (m *MuHash) Finalize() hash.Hash {
+	n := m.normalize()
+	var buf [ElementSize]byte
+	n.FillBytes(buf[:])
+	reverse(buf[:])
+	return hash.Hash(sha256.Sum256(buf[:]))
+}
+
+// Serialize returns the 384 bytes little-endian number of the set, so the
+// MuHash can be stored and restored by Deserialize.
+func (m *MuHash) Serialize() []byte {
+	buf := make([]byte, ElementSize)
+	m.normalize().FillBytes(buf)
+	reverse(buf)
+	return buf
+}
+
+// Deserialize returns the MuHash of the number serialized by Serialize.
+func Deserialize(data []byte) (*MuHash, error) {
+	if len(data) != ElementSize {
+		return nil, fmt.Errorf("The size of MuHash is %d, but it must be %d", len(data), ElementSize)
+	}
+	buf := make([]byte, ElementSize)
+	copy(buf, data)
+	reverse(buf)
+	n := new(big.Int).SetBytes(buf)
+	if n.Sign() == 0 || n.Cmp(prime) >= 0 {
+		return nil, fmt.Errorf("The MuHash number is out of range")
+	}
+	return &MuHash{
+		numerator:   n,
+		denominator: big.NewInt(1),
+	}, nil
+}
+
+// normalize returns numerator / denominator modulo prime
+func (m *MuHash) normalize() *big.Int {
+	inv := new(big.Int).ModInverse(m.denominator, prime)
+	n := new(big.Int).Mul(m.numerator, inv)
+	return n.Mod(n, prime)
+}
+
+// toElement maps the data to a number by the 384 bytes little-endian
+// keystream of ChaCha20, the key is sha256(data) and the nonce is zero.
+func toElement(data []byte) *big.Int {
+	key := sha256.Sum256(data)
+	var nonce [chacha20.NonceSize]byte
+	cipher, err := chacha20.NewUnauthenticatedCipher(key[:], nonce[:])
+	if err != nil {
+		// The sizes of key and nonce are fixed
+		panic(err)
+	}
+	var buf [ElementSize]byte
+	cipher.XORKeyStream(buf[:], buf[:])
+	reverse(buf[:])
+	e := new(big.Int).SetBytes(buf[:])
+	return e.Mod(e, prime)
+}
+
+func reverse(b []byte) {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+}
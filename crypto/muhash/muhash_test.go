@@ -0,0 +1,102 @@
+package muhash
+
+import (
+	"testing"
+)
+
+func testElement(i byte) []byte {
+	data := make([]byte, 32)
+	data[0] = i
+	return data
+}
+
+// The vector of Bitcoin Core: ((0 * 1) / 2)
+func TestMuHashVector(t *testing.T) {
+	m := New()
+	m.Add(testElement(0))
+	m.Add(testElement(1))
+	m.Remove(testElement(2))
+	result := m.Finalize()
+	expect := "10d312b100cbd32ada024a6646e40d3482fcff103668d2625f10002a607d5863"
+	if result.String() != expect {
+		t.Fatalf("got %s, expected %s", result, expect)
+	}
+}
+
+func TestMuHashSet(t *testing.T) {
+	empty := New().Finalize()
+
+	a := New()
+	for i := 0; i < 10; i++ {
+		a.Add(testElement(byte(i)))
+	}
+	b := New()
+	for i := 9; i >= 0; i-- {
+		b.Add(testElement(byte(i)))
+	}
+	if a.Finalize() != b.Finalize() {
+		t.Fatal("the order of elements changes the result")
+	}
+	if a.Finalize() == empty {
+		t.Fatal("the set is equal to empty set")
+	}
+
+	// Removing all the elements returns to the empty set
+	c := a.Clone()
+	for i := 0; i < 10; i++ {
+		c.Remove(testElement(byte(i)))
+	}
+	if c.Finalize() != empty {
+		t.Fatal("the removed elements are still in the set")
+	}
+	if a.Finalize() != b.Finalize() {
+		t.Fatal("the clone shares the state")
+	}
+
+	// Combine the two halves
+	low, high := New(), New()
+	for i := 0; i < 10; i++ {
+		if i < 5 {
+			low.Add(testElement(byte(i)))
+		} else {
+			high.Add(testElement(byte(i)))
+		}
+	}
+	low.Combine(high)
+	if low.Finalize() != a.Finalize() {
+		t.Fatal("the combined set is different")
+	}
+}
+
+func TestMuHashSerialize(t *testing.T) {
+	m := New()
+	for i := 0; i < 10; i++ {
+		m.Add(testElement(byte(i)))
+	}
+	m.Remove(testElement(3))
+	data := m.Serialize()
+	if len(data) != ElementSize {
+		t.Fatalf("the size is %d", len(data))
+	}
+	restored, err := Deserialize(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Finalize() != m.Finalize() {
+		t.Fatal("the restored set is different")
+	}
+	// The restored MuHash keeps rolling
+	m.Add(testElement(20))
+	restored.Add(testElement(20))
+	if restored.Finalize() != m.Finalize() {
+		t.Fatal("the restored set is different after adding")
+	}
+	_, err = Deserialize(data[1:])
+	if err == nil {
+		t.Fatal("the short data is accepted")
+	}
+	_, err = Deserialize(make([]byte, ElementSize))
+	if err == nil {
+		t.Fatal("zero is accepted")
+	}
+}
@@ -200,6 +200,10 @@ func (cdb *ChainDB) Rebuild(mgr model.IndexManager) error {
 	if err != nil {
 		return err
 	}
+	err = cdb.CleanCFIdx()
+	if err != nil {
+		return err
+	}
 
 	err = rawdb.CleanSpendJournal(cdb.db)
 	if err != nil {
@@ -209,7 +213,11 @@ func (cdb *ChainDB) Rebuild(mgr model.IndexManager) error {
 	if err != nil {
 		return err
 	}
-	return rawdb.CleanTokenState(cdb.db)
+	err = rawdb.CleanTokenState(cdb.db)
+	if err != nil {
+		return err
+	}
+	return rawdb.CleanStakeState(cdb.db)
 }
 
 func (cdb *ChainDB) GetSpendJournal(bh *hash.Hash) ([]byte, error) {
@@ -308,6 +316,28 @@ func (cdb *ChainDB) DeleteTokenState(blockID uint) error {
 	return nil
 }
 
+func (cdb *ChainDB) GetStakeState(blockID uint) ([]byte, error) {
+	if cdb.diff != nil {
+		return cdb.diff.GetStakeState(blockID)
+	}
+	return rawdb.ReadStakeState(cdb.db, uint64(blockID)), nil
+}
+
+func (cdb *ChainDB) PutStakeState(blockID uint, data []byte) error {
+	if cdb.diff != nil {
+		return cdb.diff.PutStakeState(blockID, data)
+	}
+	return rawdb.WriteStakeState(cdb.db, uint64(blockID), data)
+}
+
+func (cdb *ChainDB) DeleteStakeState(blockID uint) error {
+	if cdb.diff != nil {
+		return cdb.diff.DeleteStakeState(blockID)
+	}
+	rawdb.DeleteStakeState(cdb.db, uint64(blockID))
+	return nil
+}
+
 func (cdb *ChainDB) GetBestChainState() ([]byte, error) {
 	if cdb.diff != nil {
 		return cdb.diff.GetBestChainState()
@@ -350,6 +380,16 @@ func (cdb *ChainDB) PutBlock(block *types.SerializedBlock) error {
 	return cdb.writeBlockToBatch(block)
 }
 
+// DeleteBlockBody removes the block body from the database, the header is
+// kept so the block is still known.
+func (cdb *ChainDB) DeleteBlockBody(hash *hash.Hash) error {
+	if cdb.diff != nil {
+		return cdb.diff.DeleteBlockBody(hash)
+	}
+	rawdb.DeleteBody(cdb.db, hash)
+	return nil
+}
+
 func (cdb *ChainDB) writeBlockToBatch(block *types.SerializedBlock) error {
 	batch := cdb.db.NewBatch()
 	err := rawdb.WriteBlock(batch, block)
@@ -607,6 +647,14 @@ func (cdb *ChainDB) DeleteEstimateFee() error {
 	return rawdb.DeleteEstimateFee(cdb.db)
 }
 
+func (cdb *ChainDB) GetBanlist() ([]byte, error) {
+	return rawdb.ReadBanlist(cdb.db), nil
+}
+
+func (cdb *ChainDB) PutBanlist(data []byte) error {
+	return rawdb.WriteBanlist(cdb.db, data)
+}
+
 func (cdb *ChainDB) StartTrack(info string) error {
 	if cdb.diff != nil {
 		return nil
@@ -2,6 +2,7 @@ package chaindb
 
 import (
 	"encoding/binary"
+	"fmt"
 	"github.com/Qitmeer/qng/common/hash"
 	"github.com/Qitmeer/qng/consensus/model"
 	"github.com/Qitmeer/qng/core/types"
@@ -221,6 +222,42 @@ func (cdb *ChainDB) CleanAddrIdx(finish bool) error {
 	return rawdb.CleanAddrIdx(cdb.DB())
 }
 
+func (cdb *ChainDB) GetCFIdxTip() (*hash.Hash, uint, error) {
+	return rawdb.ReadCFIdxTip(cdb.db)
+}
+
+func (cdb *ChainDB) PutCFIdxTip(bh *hash.Hash, order uint) error {
+	return rawdb.WriteCFIdxTip(cdb.db, bh, order)
+}
+
+func (cdb *ChainDB) PutCFilter(bh *hash.Hash, filter []byte, header *hash.Hash) error {
+	return rawdb.WriteCFilter(cdb.db, bh, filter, header)
+}
+
+func (cdb *ChainDB) GetCFilter(bh *hash.Hash) ([]byte, error) {
+	filter := rawdb.ReadCFilter(cdb.db, bh)
+	if filter == nil {
+		return nil, fmt.Errorf("No compact filter:%s", bh.String())
+	}
+	return filter, nil
+}
+
+func (cdb *ChainDB) GetCFHeader(bh *hash.Hash) (*hash.Hash, error) {
+	header := rawdb.ReadCFHeader(cdb.db, bh)
+	if header == nil {
+		return nil, fmt.Errorf("No compact filter header:%s", bh.String())
+	}
+	return header, nil
+}
+
+func (cdb *ChainDB) DeleteCFilter(bh *hash.Hash) error {
+	return rawdb.DeleteCFilter(cdb.db, bh)
+}
+
+func (cdb *ChainDB) CleanCFIdx() error {
+	return rawdb.CleanCFIdx(cdb.db)
+}
+
 type bucket struct {
 	db ethdb.Database
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/Qitmeer/qng/common/util"
 	l "github.com/Qitmeer/qng/log"
 	"github.com/Qitmeer/qng/meerevm/eth"
+	"github.com/Qitmeer/qng/params"
 	"github.com/Qitmeer/qng/services/common"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/ethdb/dbtest"
@@ -101,6 +102,43 @@ func TestChainDBBatch(t *testing.T) {
 	}
 }
 
+func TestChainDBDeleteBlockBody(t *testing.T) {
+	cfg := common.DefaultConfig("")
+	cfg.DataDir = ""
+	cdb, err := NewNaked(cfg)
+	if err != nil {
+		t.Fatal("node:", err)
+	}
+	defer cdb.Close()
+
+	block := params.PrivNetParam.GenesisBlock
+	if err = cdb.PutBlock(block); err != nil {
+		t.Fatal(err)
+	}
+	if err = cdb.PutSpendJournal(block.Hash(), []byte{1}); err != nil {
+		t.Fatal(err)
+	}
+	if err = cdb.DeleteBlockBody(block.Hash()); err != nil {
+		t.Fatal(err)
+	}
+	if err = cdb.DeleteSpendJournal(block.Hash()); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := cdb.GetBlock(block.Hash()); b != nil {
+		t.Fatalf("the block body still exists")
+	}
+	if sj, _ := cdb.GetSpendJournal(block.Hash()); len(sj) > 0 {
+		t.Fatalf("the spend journal still exists")
+	}
+	header, err := cdb.GetHeader(block.Hash())
+	if err != nil || header == nil {
+		t.Fatalf("the header was deleted:%v", err)
+	}
+	if header.BlockHash() != *block.Hash() {
+		t.Fatalf("header hash %s, want %s", header.BlockHash(), block.Hash())
+	}
+}
+
 func BenchmarkIOLevelDB(b *testing.B) {
 	doBenchmarkIO(b, "leveldb")
 }
@@ -28,6 +28,7 @@ type diffLayer struct {
 	spendJournal   map[hash.Hash][]byte
 	utxo           map[string][]byte
 	tokenState     map[uint][]byte
+	stakeState     map[uint][]byte
 	bestChainState []byte
 	blocks         map[hash.Hash]*types.SerializedBlock
 	dagBlocks      map[uint][]byte
@@ -143,6 +144,22 @@ func (dl *diffLayer) flatten() error {
 		}
 	}
 
+	if len(dl.stakeState) > 0 {
+		for k, v := range dl.stakeState {
+			if len(v) <= 0 {
+				rawdb.DeleteStakeState(batch, uint64(k))
+			} else {
+				err := rawdb.WriteStakeState(batch, uint64(k), v)
+				if err != nil {
+					log.Error(err.Error())
+				}
+			}
+		}
+		for k := range dl.stakeState {
+			delete(dl.stakeState, k)
+		}
+	}
+
 	if len(dl.bestChainState) > 0 {
 		err := rawdb.WriteBestChainState(batch, dl.bestChainState)
 		if err != nil {
@@ -439,6 +456,49 @@ func (dl *diffLayer) DeleteTokenState(blockID uint) error {
 	return nil
 }
 
+func (dl *diffLayer) GetStakeState(blockID uint) ([]byte, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	if len(dl.stakeState) > 0 {
+		data, ok := dl.stakeState[blockID]
+		if ok {
+			if len(data) > 0 {
+				return data, nil
+			} else {
+				return nil, nil
+			}
+		}
+	}
+	return rawdb.ReadStakeState(dl.db, uint64(blockID)), nil
+}
+
+func (dl *diffLayer) PutStakeState(blockID uint, data []byte) error {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	if dl.stakeState == nil {
+		dl.stakeState = map[uint][]byte{}
+	}
+	dl.stakeState[blockID] = data
+
+	dl.memory.Add(uint64(len(data)) + uint64(unsafe.Sizeof(blockID)))
+	return nil
+}
+
+func (dl *diffLayer) DeleteStakeState(blockID uint) error {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	if dl.stakeState == nil {
+		dl.stakeState = map[uint][]byte{}
+	}
+	dl.stakeState[blockID] = nil
+
+	dl.memory.Add(uint64(unsafe.Sizeof(blockID)))
+	return nil
+}
+
 func (dl *diffLayer) GetBestChainState() ([]byte, error) {
 	dl.lock.RLock()
 	defer dl.lock.RUnlock()
@@ -518,6 +578,23 @@ func (dl *diffLayer) PutBlock(block *types.SerializedBlock) error {
 	return nil
 }
 
+func (dl *diffLayer) DeleteBlockBody(hash *hash.Hash) error {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	block, ok := dl.blocks[*hash]
+	if ok {
+		// The header has not been flushed yet
+		err := rawdb.WriteHeader(dl.db, &block.Block().Header)
+		if err != nil {
+			return err
+		}
+		delete(dl.blocks, *hash)
+	}
+	rawdb.DeleteBody(dl.db, hash)
+	return nil
+}
+
 func (dl *diffLayer) HasBlock(hash *hash.Hash) bool {
 	dl.lock.RLock()
 	defer dl.lock.RUnlock()
@@ -867,7 +944,7 @@ func (dl *diffLayer) objects() int {
 	dl.lock.RLock()
 	defer dl.lock.RUnlock()
 
-	return len(dl.spendJournal) + len(dl.utxo) + len(dl.tokenState) + len(dl.blocks) + len(dl.dagBlocks) +
+	return len(dl.spendJournal) + len(dl.utxo) + len(dl.tokenState) + len(dl.stakeState) + len(dl.blocks) + len(dl.dagBlocks) +
 		len(dl.blockidByHash) + len(dl.mainchain) + len(dl.blockidByOrder) + len(dl.tips) + len(dl.txIdxEntrys)
 }
 
@@ -141,6 +141,10 @@ func (cdb *LegacyChainDB) Rebuild(mgr model.IndexManager) error {
 	if err != nil {
 		log.Info(err.Error())
 	}
+	err = cdb.CleanCFIdx()
+	if err != nil {
+		log.Info(err.Error())
+	}
 	//
 	err = cdb.db.Update(func(tx legacydb.Tx) error {
 		meta := tx.Metadata()
@@ -156,6 +160,9 @@ func (cdb *LegacyChainDB) Rebuild(mgr model.IndexManager) error {
 		if err != nil {
 			return err
 		}
+		if meta.Bucket(dbnamespace.StakeBucketName) != nil {
+			return meta.DeleteBucket(dbnamespace.StakeBucketName)
+		}
 		return nil
 	})
 	if err != nil {
@@ -309,6 +316,47 @@ func (cdb *LegacyChainDB) DeleteTokenState(blockID uint) error {
 	})
 }
 
+func (cdb *LegacyChainDB) GetStakeState(blockID uint) ([]byte, error) {
+	var data []byte
+	err := cdb.db.View(func(dbTx legacydb.Tx) error {
+		bucket := dbTx.Metadata().Bucket(dbnamespace.StakeBucketName)
+		if bucket == nil {
+			return nil
+		}
+		var serializedID [4]byte
+		dbnamespace.ByteOrder.PutUint32(serializedID[:], uint32(blockID))
+
+		data = bucket.Get(serializedID[:])
+		return nil
+	})
+	return data, err
+}
+
+func (cdb *LegacyChainDB) PutStakeState(blockID uint, data []byte) error {
+	return cdb.db.Update(func(dbTx legacydb.Tx) error {
+		// The bucket doesn't exist in the database created before the stake state.
+		bucket, err := dbTx.Metadata().CreateBucketIfNotExists(dbnamespace.StakeBucketName)
+		if err != nil {
+			return err
+		}
+		var serializedID [4]byte
+		dbnamespace.ByteOrder.PutUint32(serializedID[:], uint32(blockID))
+		return bucket.Put(serializedID[:], data)
+	})
+}
+
+func (cdb *LegacyChainDB) DeleteStakeState(blockID uint) error {
+	return cdb.db.Update(func(dbTx legacydb.Tx) error {
+		bucket := dbTx.Metadata().Bucket(dbnamespace.StakeBucketName)
+		if bucket == nil {
+			return nil
+		}
+		var serializedID [4]byte
+		dbnamespace.ByteOrder.PutUint32(serializedID[:], uint32(blockID))
+		return bucket.Delete(serializedID[:])
+	})
+}
+
 func (cdb *LegacyChainDB) GetBestChainState() ([]byte, error) {
 	var data []byte
 	err := cdb.db.View(func(dbTx legacydb.Tx) error {
@@ -398,6 +446,10 @@ func (cdb *LegacyChainDB) PutBlock(block *types.SerializedBlock) error {
 	return nil
 }
 
+func (cdb *LegacyChainDB) DeleteBlockBody(hash *hash.Hash) error {
+	return fmt.Errorf("Deleting block bodies is not supported by the legacy database")
+}
+
 func (cdb *LegacyChainDB) HasBlock(hash *hash.Hash) bool {
 	result := false
 	err := cdb.db.View(func(dbTx legacydb.Tx) error {
@@ -705,6 +757,23 @@ func (cdb *LegacyChainDB) DeleteEstimateFee() error {
 	})
 }
 
+func (cdb *LegacyChainDB) GetBanlist() ([]byte, error) {
+	var data []byte
+	err := cdb.db.View(func(dbTx legacydb.Tx) error {
+		metadata := dbTx.Metadata()
+		data = append([]byte{}, metadata.Get(rawdb.BanlistDatabaseKey)...)
+		return nil
+	})
+	return data, err
+}
+
+func (cdb *LegacyChainDB) PutBanlist(data []byte) error {
+	return cdb.db.Update(func(dbTx legacydb.Tx) error {
+		metadata := dbTx.Metadata()
+		return metadata.Put(rawdb.BanlistDatabaseKey, data)
+	})
+}
+
 func (cdb *LegacyChainDB) StartTrack(info string) error {
 	if cdb.shutdownTracker == nil {
 		return nil
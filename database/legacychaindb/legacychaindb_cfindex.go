@@ -0,0 +1,143 @@
+package legacychaindb
+
+import (
+	"fmt"
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/core/dbnamespace"
+	"github.com/Qitmeer/qng/database/legacydb"
+	"github.com/Qitmeer/qng/services/index"
+	"math"
+)
+
+var (
+	// cfIndexKey is the key of the committed filter index and the db bucket
+	// used to house it.
+	cfIndexKey = []byte("cfindex")
+
+	// cfilterKeyPrefix and cfheaderKeyPrefix are the prefixes of the keys
+	// of the filters and filter headers in the index bucket.
+	cfilterKeyPrefix  = []byte("f")
+	cfheaderKeyPrefix = []byte("h")
+)
+
+func cfIndexEntryKey(prefix []byte, bh *hash.Hash) []byte {
+	return append(append([]byte{}, prefix...), bh[:]...)
+}
+
+func (cdb *LegacyChainDB) GetCFIdxTip() (*hash.Hash, uint, error) {
+	err := cdb.db.Update(func(dbTx legacydb.Tx) error {
+		// Create the bucket for the current tips as needed.
+		meta := dbTx.Metadata()
+		_, err := meta.CreateBucketIfNotExists(dbnamespace.IndexTipsBucketName)
+		if err != nil {
+			return err
+		}
+		indexesBucket := meta.Bucket(dbnamespace.IndexTipsBucketName)
+		// Nothing to do if the index tip already exists.
+		if indexesBucket.Get(cfIndexKey) != nil {
+			return nil
+		}
+		if _, err := meta.CreateBucketIfNotExists(cfIndexKey); err != nil {
+			return err
+		}
+		return dbPutIndexerTip(dbTx, cfIndexKey, &hash.ZeroHash, math.MaxUint32)
+	})
+	if err != nil {
+		return nil, math.MaxUint32, err
+	}
+	var bh *hash.Hash
+	var order uint32
+	err = cdb.db.View(func(dbTx legacydb.Tx) error {
+		bh, order, err = dbFetchIndexerTip(dbTx, cfIndexKey)
+		return err
+	})
+	if err != nil {
+		return nil, math.MaxUint32, err
+	}
+	return bh, uint(order), nil
+}
+
+func (cdb *LegacyChainDB) PutCFIdxTip(bh *hash.Hash, order uint) error {
+	return cdb.db.Update(func(dbTx legacydb.Tx) error {
+		return dbPutIndexerTip(dbTx, cfIndexKey, bh, uint32(order))
+	})
+}
+
+func (cdb *LegacyChainDB) PutCFilter(bh *hash.Hash, filter []byte, header *hash.Hash) error {
+	return cdb.db.Update(func(dbTx legacydb.Tx) error {
+		bucket, err := dbTx.Metadata().CreateBucketIfNotExists(cfIndexKey)
+		if err != nil {
+			return err
+		}
+		err = bucket.Put(cfIndexEntryKey(cfilterKeyPrefix, bh), filter)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(cfIndexEntryKey(cfheaderKeyPrefix, bh), header.Bytes())
+	})
+}
+
+func (cdb *LegacyChainDB) GetCFilter(bh *hash.Hash) ([]byte, error) {
+	var filter []byte
+	err := cdb.db.View(func(dbTx legacydb.Tx) error {
+		bucket := dbTx.Metadata().Bucket(cfIndexKey)
+		if bucket == nil {
+			return nil
+		}
+		data := bucket.Get(cfIndexEntryKey(cfilterKeyPrefix, bh))
+		if len(data) > 0 {
+			filter = make([]byte, len(data))
+			copy(filter, data)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if filter == nil {
+		return nil, fmt.Errorf("No compact filter:%s", bh.String())
+	}
+	return filter, nil
+}
+
+func (cdb *LegacyChainDB) GetCFHeader(bh *hash.Hash) (*hash.Hash, error) {
+	var header *hash.Hash
+	err := cdb.db.View(func(dbTx legacydb.Tx) error {
+		bucket := dbTx.Metadata().Bucket(cfIndexKey)
+		if bucket == nil {
+			return nil
+		}
+		data := bucket.Get(cfIndexEntryKey(cfheaderKeyPrefix, bh))
+		if len(data) == hash.HashSize {
+			var h hash.Hash
+			copy(h[:], data)
+			header = &h
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("No compact filter header:%s", bh.String())
+	}
+	return header, nil
+}
+
+func (cdb *LegacyChainDB) DeleteCFilter(bh *hash.Hash) error {
+	return cdb.db.Update(func(dbTx legacydb.Tx) error {
+		bucket := dbTx.Metadata().Bucket(cfIndexKey)
+		if bucket == nil {
+			return nil
+		}
+		err := bucket.Delete(cfIndexEntryKey(cfilterKeyPrefix, bh))
+		if err != nil {
+			return err
+		}
+		return bucket.Delete(cfIndexEntryKey(cfheaderKeyPrefix, bh))
+	})
+}
+
+func (cdb *LegacyChainDB) CleanCFIdx() error {
+	return dropIndex(cdb.db, cfIndexKey, index.CFIndexName, cdb.interrupt)
+}
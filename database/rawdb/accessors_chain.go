@@ -348,3 +348,42 @@ func CleanTokenState(db ethdb.Database) error {
 	}
 	return nil
 }
+
+// stakeState
+
+func ReadStakeState(db ethdb.Reader, id uint64) []byte {
+	data, err := db.Get(stakeStateKey(id))
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	return data
+}
+
+func WriteStakeState(db ethdb.KeyValueWriter, id uint64, data []byte) error {
+	if len(data) <= 0 {
+		return nil
+	}
+	return db.Put(stakeStateKey(id), data)
+}
+
+func DeleteStakeState(db ethdb.KeyValueWriter, id uint64) {
+	if err := db.Delete(stakeStateKey(id)); err != nil {
+		log.Crit("Failed to delete id to stake state mapping", "err", err)
+	}
+}
+
+func CleanStakeState(db ethdb.Database) error {
+	it := db.NewIterator(stakeStatePrefix, nil)
+	total := 0
+	defer func() {
+		log.Debug("Clean stake state", "total", total)
+	}()
+	for it.Next() {
+		err := db.Delete(it.Key())
+		if err != nil {
+			return err
+		}
+		total++
+	}
+	return nil
+}
@@ -239,3 +239,87 @@ func CleanAddrIdx(db ethdb.Database) error {
 	}
 	return nil
 }
+
+// cf index
+func ReadCFIdxTip(db ethdb.Reader) (*hash.Hash, uint, error) {
+	serialized, _ := db.Get(cfidxTipKey)
+	if len(serialized) < hash.HashSize+4 {
+		return &hash.ZeroHash, math.MaxUint32, nil
+	}
+
+	var h hash.Hash
+	copy(h[:], serialized[:hash.HashSize])
+	order := uint32(binary.BigEndian.Uint32(serialized[hash.HashSize:]))
+	return &h, uint(order), nil
+}
+
+func WriteCFIdxTip(db ethdb.KeyValueWriter, bh *hash.Hash, order uint) error {
+	serialized := make([]byte, hash.HashSize+4)
+	copy(serialized, bh[:])
+	binary.BigEndian.PutUint32(serialized[hash.HashSize:], uint32(order))
+	return db.Put(cfidxTipKey, serialized)
+}
+
+func ReadCFilter(db ethdb.Reader, bh *hash.Hash) []byte {
+	data, err := db.Get(cfilterKey(bh))
+	if len(data) == 0 {
+		if err != nil {
+			log.Trace("compact filter", "err", err.Error())
+		}
+		return nil
+	}
+	return data
+}
+
+func ReadCFHeader(db ethdb.Reader, bh *hash.Hash) *hash.Hash {
+	data, err := db.Get(cfheaderKey(bh))
+	if len(data) != hash.HashSize {
+		if err != nil {
+			log.Trace("compact filter header", "err", err.Error())
+		}
+		return nil
+	}
+	var h hash.Hash
+	copy(h[:], data)
+	return &h
+}
+
+func WriteCFilter(db ethdb.KeyValueWriter, bh *hash.Hash, filter []byte, header *hash.Hash) error {
+	err := db.Put(cfilterKey(bh), filter)
+	if err != nil {
+		return err
+	}
+	return db.Put(cfheaderKey(bh), header.Bytes())
+}
+
+func DeleteCFilter(db ethdb.KeyValueWriter, bh *hash.Hash) error {
+	err := db.Delete(cfilterKey(bh))
+	if err != nil {
+		return err
+	}
+	return db.Delete(cfheaderKey(bh))
+}
+
+func CleanCFIdx(db ethdb.Database) error {
+	err := db.Delete(cfidxTipKey)
+	if err != nil {
+		return err
+	}
+	total := 0
+	defer func() {
+		log.Debug("Clean cf index", "total", total)
+	}()
+	for _, prefix := range [][]byte{cfilterPrefix, cfheaderPrefix} {
+		it := db.NewIterator(prefix, nil)
+		for it.Next() {
+			err := db.Delete(it.Key())
+			if err != nil {
+				it.Release()
+				return err
+			}
+			total++
+		}
+		it.Release()
+	}
+	return nil
+}
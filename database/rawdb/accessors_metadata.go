@@ -236,3 +236,17 @@ func WriteEstimateFee(db ethdb.KeyValueWriter, data []byte) error {
 func DeleteEstimateFee(db ethdb.KeyValueWriter) error {
 	return db.Delete(EstimateFeeDatabaseKey)
 }
+
+// banlist
+func ReadBanlist(db ethdb.Reader) []byte {
+	data, err := db.Get(BanlistDatabaseKey)
+	if err != nil {
+		log.Debug("banlist", "err", err.Error())
+		return nil
+	}
+	return data
+}
+
+func WriteBanlist(db ethdb.KeyValueWriter, data []byte) error {
+	return db.Put(BanlistDatabaseKey, data)
+}
@@ -371,6 +371,7 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		spendJournal        stat
 		utxo                stat
 		tokenState          stat
+		stakeState          stat
 		dagBlock            stat
 		blockID             stat
 		dagMainChain        stat
@@ -381,6 +382,8 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		SnapshotBlockOrder  stat
 		SnapshotBlockStatus stat
 		addridx             stat
+		cfilter             stat
+		cfheader            stat
 
 		// Meta- and unaccounted data
 		metadata    stat
@@ -406,6 +409,8 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 			utxo.Add(size)
 		case bytes.HasPrefix(key, tokenStatePrefix) && len(key) == (len(tokenStatePrefix)+8):
 			tokenState.Add(size)
+		case bytes.HasPrefix(key, stakeStatePrefix) && len(key) == (len(stakeStatePrefix)+8):
+			stakeState.Add(size)
 		case bytes.HasPrefix(key, dagBlockPrefix) && len(key) == (len(dagBlockPrefix)+8):
 			dagBlock.Add(size)
 		case bytes.HasPrefix(key, blockIDPrefix) && len(key) == (len(blockIDPrefix)+common.HashLength):
@@ -427,12 +432,16 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 			SnapshotBlockStatus.Add(size)
 		case bytes.HasPrefix(key, AddridxPrefix):
 			addridx.Add(size)
+		case bytes.HasPrefix(key, cfilterPrefix) && len(key) == (len(cfilterPrefix)+common.HashLength):
+			cfilter.Add(size)
+		case bytes.HasPrefix(key, cfheaderPrefix) && len(key) == (len(cfheaderPrefix)+common.HashLength):
+			cfheader.Add(size)
 		default:
 			var accounted bool
 			for _, meta := range [][]byte{VersionKey, CompressionVersionKey, BlockIndexVersionKey, CreatedKey,
 				snapshotDisabledKey, SnapshotRootKey, snapshotJournalKey, snapshotGeneratorKey, snapshotRecoveryKey, snapshotSyncStatusKey,
-				badBlockKey, uncleanShutdownKey, bestChainStateKey, dagInfoKey, mainchainTipKey, dagTipsKey, diffAnticoneKey, EstimateFeeDatabaseKey,
-				addridxTipKey,
+				badBlockKey, uncleanShutdownKey, bestChainStateKey, dagInfoKey, mainchainTipKey, dagTipsKey, diffAnticoneKey, EstimateFeeDatabaseKey, BanlistDatabaseKey,
+				addridxTipKey, cfidxTipKey,
 			} {
 				if bytes.Equal(key, meta) {
 					metadata.Add(size)
@@ -457,6 +466,7 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		{"Key-Value store", "SpendJournal", spendJournal.Size(), spendJournal.Count()},
 		{"Key-Value store", "UTXO", utxo.Size(), utxo.Count()},
 		{"Key-Value store", "TokenState", tokenState.Size(), tokenState.Count()},
+		{"Key-Value store", "StakeState", stakeState.Size(), stakeState.Count()},
 		{"Key-Value store", "DAGBlock", dagBlock.Size(), dagBlock.Count()},
 		{"Key-Value store", "BlockID", blockID.Size(), blockID.Count()},
 		{"Key-Value store", "DAGMainChain", dagMainChain.Size(), dagMainChain.Count()},
@@ -467,6 +477,8 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		{"Key-Value store", "SnapshotBlockOrder", SnapshotBlockOrder.Size(), SnapshotBlockOrder.Count()},
 		{"Key-Value store", "SnapshotBlockStatus", SnapshotBlockStatus.Size(), SnapshotBlockStatus.Count()},
 		{"Key-Value store", "Addridx", addridx.Size(), addridx.Count()},
+		{"Key-Value store", "CFilter", cfilter.Size(), cfilter.Count()},
+		{"Key-Value store", "CFHeader", cfheader.Size(), cfheader.Count()},
 	}
 	// Inspect all registered append-only file store then.
 	ancients, err := inspectFreezers(db)
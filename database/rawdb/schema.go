@@ -60,6 +60,7 @@ var (
 	spendJournalPrefix = []byte("j") // spendJournalPrefix + hash -> SpentTxOuts data
 	utxoPrefix         = []byte("u") // utxoPrefix + outpoint data -> UtxoEntry data
 	tokenStatePrefix   = []byte("t") // tokenStatePrefix + id (uint64 big endian) -> tokenState data
+	stakeStatePrefix   = []byte("k") // stakeStatePrefix + id (uint64 big endian) -> stakeState data
 	// dag
 	// DagInfoKey is the name of the db bucket used to house the
 	// dag information
@@ -85,6 +86,11 @@ var (
 	addridxTipKey = []byte("addrtip") // block hash+order
 	AddridxPrefix = []byte("A")
 
+	// cf index
+	cfidxTipKey    = []byte("cftip") // block hash+order
+	cfilterPrefix  = []byte("C")     // cfilterPrefix + hash -> compact filter
+	cfheaderPrefix = []byte("H")     // cfheaderPrefix + hash -> compact filter header
+
 	// snapshot
 	SnapshotBlockOrderPrefix  = []byte("o") // SnapshotBlockOrderPrefix + block order -> block id
 	SnapshotBlockStatusPrefix = []byte("s") // SnapshotBlockStatusPrefix + block id -> block status
@@ -92,6 +98,10 @@ var (
 	// EstimateFeeDatabaseKey is the key that we use to
 	// store the fee estimator in the database.
 	EstimateFeeDatabaseKey = []byte("estimatefee")
+
+	// BanlistDatabaseKey is the key that we use to
+	// store the banned peers in the database.
+	BanlistDatabaseKey = []byte("banlist")
 )
 
 // encodeBlockID encodes a block id as big endian uint64
@@ -150,6 +160,11 @@ func tokenStateKey(id uint64) []byte {
 	return append(tokenStatePrefix, encodeBlockID(id)...)
 }
 
+// stakeStateKey = stakeStatePrefix + id
+func stakeStateKey(id uint64) []byte {
+	return append(stakeStatePrefix, encodeBlockID(id)...)
+}
+
 // dagMainChainKey = dagMainChainPrefix + id (uint64 big endian)
 func dagMainChainKey(id uint64) []byte {
 	return append(dagMainChainPrefix, encodeBlockID(id)...)
@@ -160,6 +175,16 @@ func invalidtxLookupKey(hash *hash.Hash) []byte {
 	return append(invalidtxLookupPrefix, hash.Bytes()...)
 }
 
+// cfilterKey = cfilterPrefix + hash
+func cfilterKey(hash *hash.Hash) []byte {
+	return append(cfilterPrefix, hash.Bytes()...)
+}
+
+// cfheaderKey = cfheaderPrefix + hash
+func cfheaderKey(hash *hash.Hash) []byte {
+	return append(cfheaderPrefix, hash.Bytes()...)
+}
+
 // invalidtxFullHashKey = invalidtxFullHashPrefix + hash
 func invalidtxFullHashKey(hash *hash.Hash) []byte {
 	return append(invalidtxFullHashPrefix, hash.Bytes()...)
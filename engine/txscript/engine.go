@@ -71,6 +71,11 @@ const (
 	// OP_UNKNOWN192) as the OP_SHA256 opcode which consumes the top item of
 	// the data stack and replaces it with the sha256 of it.
 	ScriptVerifySHA256
+
+	// ScriptVerifyTaproot defines whether to validate the spends of the
+	// taproot outputs with the key path and the script path rules of
+	// BIP341 and BIP342.
+	ScriptVerifyTaproot
 )
 
 const (
@@ -114,6 +119,13 @@ type Engine struct {
 	flags       ScriptFlags
 	version     uint16
 	bip16       bool // treat execution as pay-to-script-hash
+
+	// witnessProgram is the x-only output key of the spent taproot output
+	// and prevScript is the spent script, which are only set when the
+	// taproot validation is enabled.
+	witnessProgram []byte
+	prevScript     []byte
+	taprootCtx     *taprootExecutionCtx
 }
 
 // hasFlag returns whether the script engine instance has the passed flag set.
@@ -147,7 +159,8 @@ func (vm *Engine) executeOpcode(pop *ParsedOpcode) error {
 	}
 
 	// Note that this includes OP_RESERVED which counts as a push operation.
-	if pop.opcode.value > OP_16 {
+	// The tapscripts are limited by the signature operations budget instead.
+	if pop.opcode.value > OP_16 && !vm.isTapscript() {
 		vm.numOps++
 		if vm.numOps > MaxOpsPerScript {
 			return ErrStackTooManyOperations
@@ -244,7 +257,7 @@ func (vm *Engine) CheckErrorCondition(finalScript bool) error {
 	if vm.scriptIdx < len(vm.scripts) {
 		return ErrStackScriptUnfinished
 	}
-	if finalScript && vm.hasFlag(ScriptVerifyCleanStack) &&
+	if finalScript && (vm.hasFlag(ScriptVerifyCleanStack) || vm.isTapscript()) &&
 		vm.dstack.Depth() != 1 {
 		return ErrStackCleanStack
 	} else if vm.dstack.Depth() < 1 {
@@ -358,6 +371,13 @@ func (vm *Engine) Execute() (err error) {
 		return nil
 	}
 
+	if vm.witnessProgram != nil {
+		done, err := vm.verifyTaproot()
+		if err != nil || done {
+			return err
+		}
+	}
+
 	done := false
 	for !done {
 		log.Trace("tx-engine executing", "step", newLogClosure(func() string {
@@ -675,6 +695,14 @@ func NewEngine(scriptPubKey []byte, tx *types.Tx, txIdx int,
 		}
 		vm.bip16 = true
 	}
+	if vm.hasFlag(ScriptVerifyTaproot) && isWitnessTaprootScript(vm.scripts[1]) {
+		// The signature script carries the witness stack.
+		if !isPushOnly(vm.scripts[0]) {
+			return nil, ErrStackNonPushOnly
+		}
+		vm.witnessProgram = vm.scripts[1][1].data
+		vm.prevScript = scriptPubKey
+	}
 	if vm.hasFlag(ScriptVerifyMinimalData) {
 		vm.dstack.verifyMinimalData = true
 		vm.astack.verifyMinimalData = true
@@ -136,6 +136,50 @@ var (
 	// is set and the script contains push operations that do not use
 	// the minimal opcode required.
 	ErrStackMinimalData = errors.New("non-minimally encoded script number")
+
+	// ErrMinimalIf is returned when the argument of OP_IF or OP_NOTIF in
+	// a tapscript isn't an empty vector or exactly 0x01.
+	ErrMinimalIf = errors.New("argument of OP_IF/NOTIF must be minimal in tapscript")
+
+	// ErrTapscriptCheckMultisig is returned when OP_CHECKMULTISIG or
+	// OP_CHECKMULTISIGVERIFY is executed in a tapscript.
+	ErrTapscriptCheckMultisig = errors.New("OP_CHECKMULTISIG and OP_CHECKMULTISIGVERIFY are disabled in tapscript")
+)
+
+// Taproot errors.
+var (
+	// ErrWitnessProgramEmpty is returned when a taproot output is spent
+	// without any witness.
+	ErrWitnessProgramEmpty = errors.New("witness program is empty")
+
+	// ErrControlBlockInvalid is returned when the control block of a
+	// taproot script path spend is malformed.
+	ErrControlBlockInvalid = errors.New("invalid taproot control block")
+
+	// ErrTaprootMerkleProofInvalid is returned when the revealed script and
+	// the control block don't commit to the taproot output key.
+	ErrTaprootMerkleProofInvalid = errors.New("taproot merkle proof is invalid")
+
+	// ErrTaprootSigInvalid is returned when a taproot signature is
+	// malformed or fails the BIP340 verification.
+	ErrTaprootSigInvalid = errors.New("invalid taproot signature")
+
+	// ErrInvalidTaprootSigHashType is returned when a taproot signature
+	// uses an undefined signature hash type.
+	ErrInvalidTaprootSigHashType = errors.New("invalid taproot signature hash type")
+
+	// ErrTaprootPubkeyIsEmpty is returned when a signature opcode in a
+	// tapscript is executed with an empty public key.
+	ErrTaprootPubkeyIsEmpty = errors.New("empty public key in tapscript")
+
+	// ErrTaprootMaxSigOps is returned when a tapscript exceeds the
+	// signature operations budget of its witness.
+	ErrTaprootMaxSigOps = errors.New("tapscript exceeds the signature operations budget")
+
+	// ErrDiscourageUpgradableTaprootVersion is returned when an unknown
+	// leaf version or an OP_SUCCESS opcode is used while the flag to
+	// discourage upgradable opcodes is set.
+	ErrDiscourageUpgradableTaprootVersion = errors.New("reserved for taproot upgrades")
 )
 
 // Engine script errors.
@@ -250,7 +250,7 @@ const (
 	OP_TOKEN_CHANGE        = 0xc8 // 200 Qitmeer token change
 	OP_TOKEN               = 0xc9 // 201 Qitmeer token manage operation
 	OP_MEER_EVM            = 0xca // 202 MeerEVM
-	OP_UNKNOWN203          = 0xcb // 203
+	OP_CHECKSIGADD         = 0xcb // 203 - tapscript only, see BIP342
 	OP_UNKNOWN204          = 0xcc // 204
 	OP_UNKNOWN205          = 0xcd // 205
 	OP_UNKNOWN206          = 0xce // 206
@@ -543,10 +543,13 @@ var opcodeArray = [256]Opcode{
 	OP_MEER_CHANGE:   {OP_MEER_CHANGE, "OP_MEER_CHANGE", 1, opcodeNop},
 	OP_TOKEN_CHANGE:  {OP_TOKEN_CHANGE, "OP_TOKEN_CHANGE", 1, opcodeNop},
 	OP_TOKEN:         {OP_TOKEN, "OP_TOKEN", 1, opcodeCheckTokenVerify},
+
+	// Tapscript opcodes, they are upgradable NOPs in the other scripts.
+	OP_CHECKSIGADD: {OP_CHECKSIGADD, "OP_CHECKSIGADD", 1, opcodeCheckSigAdd},
+
 	// Undefined opcodes.
 
 	OP_MEER_EVM:   {OP_MEER_EVM, "OP_MEER_EVM", 1, opcodeNop},
-	OP_UNKNOWN203: {OP_UNKNOWN203, "OP_UNKNOWN203", 1, opcodeNop},
 	OP_UNKNOWN204: {OP_UNKNOWN204, "OP_UNKNOWN204", 1, opcodeNop},
 	OP_UNKNOWN205: {OP_UNKNOWN205, "OP_UNKNOWN205", 1, opcodeNop},
 	OP_UNKNOWN206: {OP_UNKNOWN206, "OP_UNKNOWN206", 1, opcodeNop},
@@ -877,7 +880,7 @@ func opcodeN(op *ParsedOpcode, vm *Engine) error {
 func opcodeNop(op *ParsedOpcode, vm *Engine) error {
 	switch op.opcode.value {
 	case OP_NOP1, OP_NOP4, OP_NOP5, OP_NOP6,
-		OP_NOP7, OP_NOP8, OP_NOP9, OP_NOP10, OP_MEER_EVM, OP_UNKNOWN203,
+		OP_NOP7, OP_NOP8, OP_NOP9, OP_NOP10, OP_MEER_EVM, OP_CHECKSIGADD,
 		OP_UNKNOWN204, OP_UNKNOWN205, OP_UNKNOWN206, OP_UNKNOWN207,
 		OP_UNKNOWN208, OP_UNKNOWN209, OP_UNKNOWN210, OP_UNKNOWN211,
 		OP_UNKNOWN212, OP_UNKNOWN213, OP_UNKNOWN214, OP_UNKNOWN215,
@@ -917,7 +920,7 @@ func opcodeNop(op *ParsedOpcode, vm *Engine) error {
 func opcodeIf(op *ParsedOpcode, vm *Engine) error {
 	condVal := OpCondFalse
 	if vm.isBranchExecuting() {
-		ok, err := vm.dstack.PopBool()
+		ok, err := vm.popIfBool()
 		if err != nil {
 			return err
 		}
@@ -950,7 +953,7 @@ func opcodeIf(op *ParsedOpcode, vm *Engine) error {
 func opcodeNotIf(op *ParsedOpcode, vm *Engine) error {
 	condVal := OpCondFalse
 	if vm.isBranchExecuting() {
-		ok, err := vm.dstack.PopBool()
+		ok, err := vm.popIfBool()
 		if err != nil {
 			return err
 		}
@@ -2526,6 +2529,10 @@ func opcodeHash256(op *ParsedOpcode, vm *Engine) error {
 //
 // Stack transformation: [... signature pubkey] -> [... bool]
 func opcodeCheckSig(op *ParsedOpcode, vm *Engine) error {
+	if vm.isTapscript() {
+		return opcodeTapscriptCheckSig(vm)
+	}
+
 	pkBytes, err := vm.dstack.PopByteArray()
 	if err != nil {
 		return err
@@ -2672,6 +2679,10 @@ type parsedSigInfo struct {
 // Stack transformation:
 // [... dummy [sig ...] numsigs [pubkey ...] numpubkeys] -> [... bool]
 func opcodeCheckMultiSig(op *ParsedOpcode, vm *Engine) error {
+	if vm.isTapscript() {
+		return ErrTapscriptCheckMultisig
+	}
+
 	numKeys, err := vm.dstack.PopInt(mathOpCodeMaxScriptNumLen)
 	if err != nil {
 		return err
@@ -7,9 +7,11 @@
 package txscript
 
 import (
+	"crypto/sha256"
 	"encoding/binary"
 	"github.com/Qitmeer/qng/common/hash"
 	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/crypto/ecc/schnorr"
 	"github.com/Qitmeer/qng/params"
 	"math"
 )
@@ -25,6 +27,11 @@ const (
 	SigHashSingle       SigHashType = 0x3
 	SigHashAnyOneCanPay SigHashType = 0x80
 
+	// SigHashDefault is the default signature hash type of the taproot
+	// signatures, which commits to the whole transaction like SigHashAll
+	// and is implied by the 64 bytes signatures.
+	SigHashDefault SigHashType = 0x0
+
 	// sigHashMask defines the number of bits of the hash type which is used
 	// to identify which outputs are signed.
 	sigHashMask = 0x1f
@@ -460,3 +467,143 @@ func CalcSignatureHash(script []byte, hashType SigHashType, tx *types.Transactio
 
 	return calcSignatureHash(pops, hashType, tx, idx, cachedPrefix)
 }
+
+// isValidTaprootSigHashType returns whether or not the hash type is defined
+// for the taproot signatures.
+func isValidTaprootSigHashType(hashType SigHashType) bool {
+	switch hashType {
+	case SigHashDefault, SigHashAll, SigHashNone, SigHashSingle,
+		SigHashAll | SigHashAnyOneCanPay,
+		SigHashNone | SigHashAnyOneCanPay,
+		SigHashSingle | SigHashAnyOneCanPay:
+		return true
+	}
+	return false
+}
+
+// taprootOutputBytes serializes the output for the taproot signature hash.
+func taprootOutputBytes(txOut *types.TxOutput) []byte {
+	buf := make([]byte, 2+8+varIntSerializeSize(uint64(len(txOut.PkScript)))+len(txOut.PkScript))
+	offset := putUint16LE(buf, uint16(txOut.Amount.Id))
+	offset += putUint64LE(buf[offset:], uint64(txOut.Amount.Value))
+	offset += putVarInt(buf[offset:], uint64(len(txOut.PkScript)))
+	copy(buf[offset:], txOut.PkScript)
+	return buf
+}
+
+// calcTaprootSignatureHash computes the BIP341 signature hash of the input
+// spending the taproot output pkScript.  The tapLeafHash is nil for the key
+// path spends and it is the hash of the executed leaf for the tapscripts.
+//
+// The message follows BIP341 with the following differences for the
+// transaction format: the outputs commit to their coin ids, the transaction
+// expiry is committed after the lock time, and since the amounts of the spent
+// outputs aren't available to the script engine, only the spent script of
+// the signed input is committed instead of the amounts and scripts of all the
+// spent outputs.  OP_CODESEPARATOR is disabled, so the code separator
+// position of the tapscripts is always 0xffffffff.
+func calcTaprootSignatureHash(hashType SigHashType, tx *types.Transaction, idx int, pkScript []byte, annex []byte, tapLeafHash []byte) ([]byte, error) {
+	if !isValidTaprootSigHashType(hashType) {
+		return nil, ErrInvalidTaprootSigHashType
+	}
+	if idx < 0 || idx >= len(tx.TxIn) {
+		return nil, ErrInvalidIndex
+	}
+	anyoneCanPay := hashType&SigHashAnyOneCanPay != 0
+	outputType := hashType & sigHashMask
+	if outputType == SigHashSingle && idx >= len(tx.TxOut) {
+		return nil, ErrSighashSingleIdx
+	}
+
+	var buf []byte
+	var scratch [8]byte
+	// Signature hash epoch, hash type and the transaction data.
+	buf = append(buf, 0x00, byte(hashType))
+	putUint32LE(scratch[:], uint32(tx.Version))
+	buf = append(buf, scratch[:4]...)
+	putUint32LE(scratch[:], tx.LockTime)
+	buf = append(buf, scratch[:4]...)
+	putUint32LE(scratch[:], tx.Expire)
+	buf = append(buf, scratch[:4]...)
+
+	if !anyoneCanPay {
+		prevOuts := sha256.New()
+		sequences := sha256.New()
+		for _, txIn := range tx.TxIn {
+			prevOuts.Write(txIn.PreviousOut.Hash[:])
+			putUint32LE(scratch[:], txIn.PreviousOut.OutIndex)
+			prevOuts.Write(scratch[:4])
+			putUint32LE(scratch[:], txIn.Sequence)
+			sequences.Write(scratch[:4])
+		}
+		buf = append(buf, prevOuts.Sum(nil)...)
+		buf = append(buf, sequences.Sum(nil)...)
+	}
+	if outputType != SigHashNone && outputType != SigHashSingle {
+		outputs := sha256.New()
+		for _, txOut := range tx.TxOut {
+			outputs.Write(taprootOutputBytes(txOut))
+		}
+		buf = append(buf, outputs.Sum(nil)...)
+	}
+
+	// Data about the input being signed.
+	spendType := byte(0)
+	if tapLeafHash != nil {
+		spendType |= 0x02
+	}
+	if annex != nil {
+		spendType |= 0x01
+	}
+	buf = append(buf, spendType)
+	if anyoneCanPay {
+		txIn := tx.TxIn[idx]
+		buf = append(buf, txIn.PreviousOut.Hash[:]...)
+		putUint32LE(scratch[:], txIn.PreviousOut.OutIndex)
+		buf = append(buf, scratch[:4]...)
+		putUint32LE(scratch[:], txIn.Sequence)
+		buf = append(buf, scratch[:4]...)
+	} else {
+		putUint32LE(scratch[:], uint32(idx))
+		buf = append(buf, scratch[:4]...)
+	}
+	n := putVarInt(scratch[:], uint64(len(pkScript)))
+	buf = append(buf, scratch[:n]...)
+	buf = append(buf, pkScript...)
+	if annex != nil {
+		annexHash := sha256.New()
+		n := putVarInt(scratch[:], uint64(len(annex)))
+		annexHash.Write(scratch[:n])
+		annexHash.Write(annex)
+		buf = append(buf, annexHash.Sum(nil)...)
+	}
+
+	// Data about the output corresponding to the input.
+	if outputType == SigHashSingle {
+		output := sha256.Sum256(taprootOutputBytes(tx.TxOut[idx]))
+		buf = append(buf, output[:]...)
+	}
+
+	// Data about the executed tapscript.
+	if tapLeafHash != nil {
+		buf = append(buf, tapLeafHash...)
+		buf = append(buf, 0x00)
+		putUint32LE(scratch[:], math.MaxUint32)
+		buf = append(buf, scratch[:4]...)
+	}
+	return schnorr.TaggedHash(tapSighashTag, buf), nil
+}
+
+// CalcTaprootSignatureHash computes the signature hash of a taproot key path
+// spend for the input spending the taproot output pkScript.
+func CalcTaprootSignatureHash(hashType SigHashType, tx *types.Transaction, idx int, pkScript []byte) ([]byte, error) {
+	return calcTaprootSignatureHash(hashType, tx, idx, pkScript, nil, nil)
+}
+
+// CalcTapscriptSignatureHash computes the signature hash of a taproot script
+// path spend executing the leaf for the input spending the taproot output
+// pkScript.
+func CalcTapscriptSignatureHash(hashType SigHashType, tx *types.Transaction, idx int, pkScript []byte, leaf TapLeaf) ([]byte, error) {
+	leafHash := leaf.TapHash()
+	return calcTaprootSignatureHash(hashType, tx, idx, pkScript, nil, leafHash[:])
+}
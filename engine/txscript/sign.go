@@ -14,6 +14,7 @@ import (
 	"github.com/Qitmeer/qng/core/address"
 	"github.com/Qitmeer/qng/core/types"
 	"github.com/Qitmeer/qng/crypto/ecc"
+	"github.com/Qitmeer/qng/crypto/ecc/secp256k1"
 	"github.com/Qitmeer/qng/params"
 )
 
@@ -372,6 +373,26 @@ func sign(chainParams *params.Params, tx *types.Transaction, idx int,
 			return nil, class, nil, 0, err
 		}
 
+		return script, class, addresses, nrequired, nil
+
+	case WitnessTaprootTy:
+		// Only the key path spends of the outputs without scripts (BIP86)
+		// are signed here, the key of the address is the internal key.
+		key, _, err := kdb.GetKey(addresses[0])
+		if err != nil {
+			return nil, class, nil, 0, err
+		}
+		privKey, _ := secp256k1.PrivKeyFromBytes(key.Serialize())
+		sig, err := TaprootKeySpendSignature(tx, idx, subScript, hashType,
+			privKey, nil)
+		if err != nil {
+			return nil, class, nil, 0, err
+		}
+		script, err := TaprootWitnessSignatureScript([][]byte{sig})
+		if err != nil {
+			return nil, class, nil, 0, err
+		}
+
 		return script, class, addresses, nrequired, nil
 	default:
 		return nil, class, nil, 0,
@@ -131,6 +131,7 @@ var scriptClassToName = []string{
 	StakeSubChangeTy:  "sstxchange",
 	CLTVPubKeyHashTy:  "cltvpubkeyhash",
 	TokenPubKeyHashTy: "tokenpubkeyhash",
+	WitnessTaprootTy:  "witness_v1_taproot",
 }
 
 // String implements the Stringer interface by returning the name of
@@ -1189,6 +1190,12 @@ func PayToAddrScript(addr types.Address) ([]byte, error) {
 			return nil, ErrUnsupportedAddress
 		}
 		return payToSchnorrPubKeyScript(addr.Script())
+
+	case *address.AddressTaproot:
+		if addr == nil {
+			return nil, ErrUnsupportedAddress
+		}
+		return payToWitnessTaprootScript(addr.WitnessProgram())
 	}
 
 	return nil, ErrUnsupportedAddress
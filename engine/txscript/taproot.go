@@ -0,0 +1,603 @@
+// Copyright (c) 2017-2018 The qitmeer developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txscript
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/crypto/ecc/schnorr"
+	"github.com/Qitmeer/qng/crypto/ecc/secp256k1"
+)
+
+// The taproot outputs (BIP341) are spent either through the key path with a
+// single BIP340 signature of the output key, or through the script path by
+// revealing one of the leaf scripts committed in the output key with its
+// control block.
+//
+// The transactions don't have a segregated witness, so the witness stack of
+// the spend is carried by the signature script, which must only push data.
+// The last pushed item is the top of the witness stack as in BIP341.
+const (
+	// BaseLeafVersion is the leaf version of the tapscripts (BIP342).
+	BaseLeafVersion TapscriptLeafVersion = 0xc0
+
+	// TaprootLeafMask is the mask applied to the first byte of the control
+	// block to extract the leaf version.
+	TaprootLeafMask = 0xfe
+
+	// TaprootAnnexTag is the first byte of the optional annex, which is
+	// the last item of the witness stack.
+	TaprootAnnexTag = 0x50
+
+	// ControlBlockBaseSize is the size of the control block without any
+	// inclusion proof: the leaf version byte and the internal key.
+	ControlBlockBaseSize = 33
+
+	// ControlBlockNodeSize is the size of each node of the inclusion proof.
+	ControlBlockNodeSize = 32
+
+	// ControlBlockMaxNodeCount is the maximum depth of the script tree.
+	ControlBlockMaxNodeCount = 128
+
+	// ControlBlockMaxSize is the maximum size of a control block.
+	ControlBlockMaxSize = ControlBlockBaseSize +
+		ControlBlockNodeSize*ControlBlockMaxNodeCount
+
+	// sigOpsDelta is the signature operations budget consumed by each
+	// executed signature check of a tapscript.
+	sigOpsDelta = 50
+)
+
+var (
+	tapLeafTag    = []byte("TapLeaf")
+	tapBranchTag  = []byte("TapBranch")
+	tapTweakTag   = []byte("TapTweak")
+	tapSighashTag = []byte("TapSighash")
+)
+
+// TapscriptLeafVersion is the leaf version of a taproot leaf script.
+type TapscriptLeafVersion uint8
+
+// TapLeaf is a leaf of the taproot script tree.
+type TapLeaf struct {
+	LeafVersion TapscriptLeafVersion
+	Script      []byte
+}
+
+// NewBaseTapLeaf returns a tapscript leaf with the base leaf version.
+func NewBaseTapLeaf(script []byte) TapLeaf {
+	return TapLeaf{LeafVersion: BaseLeafVersion, Script: script}
+}
+
+// TapHash returns the leaf hash committed in the script tree.
+func (t TapLeaf) TapHash() hash.Hash {
+	buf := make([]byte, 1+varIntSerializeSize(uint64(len(t.Script)))+len(t.Script))
+	buf[0] = byte(t.LeafVersion)
+	offset := 1 + putVarInt(buf[1:], uint64(len(t.Script)))
+	copy(buf[offset:], t.Script)
+
+	var h hash.Hash
+	copy(h[:], schnorr.TaggedHash(tapLeafTag, buf))
+	return h
+}
+
+// tapBranchHash returns the hash of a script tree branch, the children are
+// sorted so the proofs don't need to tell the side of each node.
+func tapBranchHash(l, r []byte) hash.Hash {
+	if bytes.Compare(l, r) > 0 {
+		l, r = r, l
+	}
+	var h hash.Hash
+	copy(h[:], schnorr.TaggedHash(tapBranchTag, l, r))
+	return h
+}
+
+// TapscriptProof is the inclusion proof of a leaf in the script tree.
+type TapscriptProof struct {
+	TapLeaf
+	RootHash       hash.Hash
+	InclusionProof []byte
+}
+
+// AssembleTaprootScriptTree builds a balanced script tree of the leaves, it
+// returns the root hash and the inclusion proof of each leaf in the same
+// order.
+func AssembleTaprootScriptTree(leaves ...TapLeaf) (hash.Hash, []TapscriptProof) {
+	if len(leaves) == 0 {
+		return hash.Hash{}, nil
+	}
+	type node struct {
+		hash   hash.Hash
+		leaves []int
+	}
+	proofs := make([]TapscriptProof, len(leaves))
+	level := make([]node, len(leaves))
+	for i, leaf := range leaves {
+		proofs[i].TapLeaf = leaf
+		level[i] = node{hash: leaf.TapHash(), leaves: []int{i}}
+	}
+	for len(level) > 1 {
+		next := make([]node, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				break
+			}
+			l, r := level[i], level[i+1]
+			for _, idx := range l.leaves {
+				proofs[idx].InclusionProof = append(proofs[idx].InclusionProof, r.hash[:]...)
+			}
+			for _, idx := range r.leaves {
+				proofs[idx].InclusionProof = append(proofs[idx].InclusionProof, l.hash[:]...)
+			}
+			next = append(next, node{
+				hash:   tapBranchHash(l.hash[:], r.hash[:]),
+				leaves: append(append([]int{}, l.leaves...), r.leaves...),
+			})
+		}
+		level = next
+	}
+	for i := range proofs {
+		proofs[i].RootHash = level[0].hash
+	}
+	return level[0].hash, proofs
+}
+
+// ControlBlock is the control block of a taproot script path spend, it proves
+// that the revealed leaf script is committed in the output key.
+type ControlBlock struct {
+	InternalKey     *secp256k1.PublicKey
+	OutputKeyYIsOdd bool
+	LeafVersion     TapscriptLeafVersion
+	InclusionProof  []byte
+}
+
+// ParseControlBlock parses the serialized control block.
+func ParseControlBlock(cb []byte) (*ControlBlock, error) {
+	if len(cb) < ControlBlockBaseSize || len(cb) > ControlBlockMaxSize ||
+		(len(cb)-ControlBlockBaseSize)%ControlBlockNodeSize != 0 {
+		return nil, ErrControlBlockInvalid
+	}
+	internalKey, err := schnorr.ParseXOnlyPubKey(cb[1:ControlBlockBaseSize])
+	if err != nil {
+		return nil, ErrControlBlockInvalid
+	}
+	return &ControlBlock{
+		InternalKey:     internalKey,
+		OutputKeyYIsOdd: cb[0]&0x01 == 0x01,
+		LeafVersion:     TapscriptLeafVersion(cb[0] & TaprootLeafMask),
+		InclusionProof:  cb[ControlBlockBaseSize:],
+	}, nil
+}
+
+// ToBytes serializes the control block.
+func (c *ControlBlock) ToBytes() []byte {
+	buf := make([]byte, 0, ControlBlockBaseSize+len(c.InclusionProof))
+	leafVersion := byte(c.LeafVersion)
+	if c.OutputKeyYIsOdd {
+		leafVersion |= 0x01
+	}
+	buf = append(buf, leafVersion)
+	buf = append(buf, schnorr.SerializeXOnlyPubKey(c.InternalKey)...)
+	return append(buf, c.InclusionProof...)
+}
+
+// RootHash returns the root of the script tree proven by the control block
+// for the revealed script.
+func (c *ControlBlock) RootHash(script []byte) hash.Hash {
+	h := TapLeaf{LeafVersion: c.LeafVersion, Script: script}.TapHash()
+	for i := 0; i < len(c.InclusionProof); i += ControlBlockNodeSize {
+		h = tapBranchHash(h[:], c.InclusionProof[i:i+ControlBlockNodeSize])
+	}
+	return h
+}
+
+// tapTweak returns the tweak of the internal key for the script root, the
+// internal key is lifted to its even y point.
+func tapTweak(internalKey *secp256k1.PublicKey, scriptRoot []byte) (*big.Int, error) {
+	t := new(big.Int).SetBytes(schnorr.TaggedHash(tapTweakTag,
+		schnorr.SerializeXOnlyPubKey(internalKey), scriptRoot))
+	if t.Cmp(secp256k1.S256().N) >= 0 {
+		return nil, fmt.Errorf("taproot tweak is out of range")
+	}
+	return t, nil
+}
+
+// ComputeTaprootOutputKey returns the output key Q = P + t*G of the internal
+// key P committing to the script root.  The script root is empty for outputs
+// without scripts.
+func ComputeTaprootOutputKey(internalKey *secp256k1.PublicKey, scriptRoot []byte) (*secp256k1.PublicKey, error) {
+	p, err := schnorr.ParseXOnlyPubKey(schnorr.SerializeXOnlyPubKey(internalKey))
+	if err != nil {
+		return nil, err
+	}
+	t, err := tapTweak(p, scriptRoot)
+	if err != nil {
+		return nil, err
+	}
+	curve := secp256k1.S256()
+	tx, ty := curve.ScalarBaseMult(t.Bytes())
+	qx, qy := curve.Add(p.GetX(), p.GetY(), tx, ty)
+	if qx.Sign() == 0 && qy.Sign() == 0 {
+		return nil, fmt.Errorf("taproot output key is the point at infinity")
+	}
+	return secp256k1.NewPublicKey(qx, qy), nil
+}
+
+// ComputeTaprootKeyNoScript returns the output key of the internal key which
+// doesn't commit to any script as recommended by BIP86.
+func ComputeTaprootKeyNoScript(internalKey *secp256k1.PublicKey) (*secp256k1.PublicKey, error) {
+	return ComputeTaprootOutputKey(internalKey, nil)
+}
+
+// TweakTaprootPrivKey returns the private key of the output key committing to
+// the script root.
+func TweakTaprootPrivKey(privKey *secp256k1.PrivateKey, scriptRoot []byte) (*secp256k1.PrivateKey, error) {
+	curve := secp256k1.S256()
+	d := new(big.Int).Set(privKey.GetD())
+	if privKey.PubKey().GetY().Bit(0) == 1 {
+		d.Sub(curve.N, d)
+	}
+	t, err := tapTweak(privKey.PubKey(), scriptRoot)
+	if err != nil {
+		return nil, err
+	}
+	d.Add(d, t)
+	d.Mod(d, curve.N)
+	if d.Sign() == 0 {
+		return nil, fmt.Errorf("tweaked taproot private key is zero")
+	}
+	return secp256k1.NewPrivateKey(d), nil
+}
+
+// VerifyTaprootLeafCommitment verifies that the control block and the leaf
+// script commit to the witness program, which is the x-only output key.
+func VerifyTaprootLeafCommitment(cb *ControlBlock, witnessProgram []byte, script []byte) error {
+	root := cb.RootHash(script)
+	outputKey, err := ComputeTaprootOutputKey(cb.InternalKey, root[:])
+	if err != nil {
+		return ErrTaprootMerkleProofInvalid
+	}
+	if !bytes.Equal(schnorr.SerializeXOnlyPubKey(outputKey), witnessProgram) {
+		return ErrTaprootMerkleProofInvalid
+	}
+	if (outputKey.GetY().Bit(0) == 1) != cb.OutputKeyYIsOdd {
+		return ErrTaprootMerkleProofInvalid
+	}
+	return nil
+}
+
+// PayToTaprootScript returns the script paying to the x-only output key.
+func PayToTaprootScript(outputKey *secp256k1.PublicKey) ([]byte, error) {
+	return payToWitnessTaprootScript(schnorr.SerializeXOnlyPubKey(outputKey))
+}
+
+// taprootExecutionCtx houses the state of a taproot spend.
+type taprootExecutionCtx struct {
+	annex        []byte
+	tapLeafHash  []byte
+	sigOpsBudget int
+}
+
+// isTapscript returns whether or not the engine executes a tapscript.
+func (vm *Engine) isTapscript() bool {
+	return vm.taprootCtx != nil && vm.taprootCtx.tapLeafHash != nil
+}
+
+// isOpSuccess returns whether or not the opcode is one of the OP_SUCCESSx
+// opcodes of BIP342, which make the tapscript succeed unconditionally.  The
+// opcode 192 is excluded since it's already used by OP_SHA256, and the opcode
+// 203 is OP_CHECKSIGADD, since 186 is already used by OP_SSTX.
+func isOpSuccess(op byte) bool {
+	switch {
+	case op == 80 || op == 98:
+		return true
+	case op >= 126 && op <= 129:
+		return true
+	case op >= 131 && op <= 134:
+		return true
+	case op == 137 || op == 138:
+		return true
+	case op == 141 || op == 142:
+		return true
+	case op >= 149 && op <= 153:
+		return true
+	case op >= 187 && op <= 254:
+		return op != OP_SHA256 && op != OP_CHECKSIGADD
+	}
+	return false
+}
+
+// taprootWitness decodes the witness stack carried by the signature script.
+func taprootWitness(pops []ParsedOpcode) ([][]byte, error) {
+	witness := make([][]byte, 0, len(pops))
+	for _, pop := range pops {
+		switch {
+		case pop.opcode.value == OP_0:
+			witness = append(witness, nil)
+		case pop.opcode.value <= OP_PUSHDATA4:
+			witness = append(witness, pop.data)
+		case pop.opcode.value == OP_1NEGATE:
+			witness = append(witness, scriptNum(-1).Bytes())
+		case pop.opcode.value >= OP_1 && pop.opcode.value <= OP_16:
+			witness = append(witness, scriptNum(pop.opcode.value-(OP_1-1)).Bytes())
+		default:
+			return nil, ErrStackNonPushOnly
+		}
+	}
+	return witness, nil
+}
+
+// verifyTaprootSignature verifies the BIP340 signature of the spend with the
+// x-only public key.  The signatures of 64 bytes imply SigHashDefault, or the
+// hash type is appended as the 65th byte.
+func (vm *Engine) verifyTaprootSignature(pubKey []byte, rawSig []byte) error {
+	hashType := SigHashDefault
+	sig := rawSig
+	switch len(rawSig) {
+	case schnorr.BIP340SignatureLen:
+	case schnorr.BIP340SignatureLen + 1:
+		hashType = SigHashType(rawSig[schnorr.BIP340SignatureLen])
+		if hashType == SigHashDefault {
+			return ErrInvalidTaprootSigHashType
+		}
+		sig = rawSig[:schnorr.BIP340SignatureLen]
+	default:
+		return ErrTaprootSigInvalid
+	}
+	h, err := calcTaprootSignatureHash(hashType, vm.tx.Tx, vm.txIdx,
+		vm.prevScript, vm.taprootCtx.annex, vm.taprootCtx.tapLeafHash)
+	if err != nil {
+		return err
+	}
+	if err := schnorr.VerifyBIP340(pubKey, h, sig); err != nil {
+		return ErrTaprootSigInvalid
+	}
+	return nil
+}
+
+// verifyTaproot validates the spend of the taproot output.  The key path
+// spends are fully validated here, while the script path spends set up the
+// engine to execute the revealed tapscript.  It returns true when the
+// validation is done.
+func (vm *Engine) verifyTaproot() (bool, error) {
+	witness, err := taprootWitness(vm.scripts[0])
+	if err != nil {
+		return true, err
+	}
+	if len(witness) == 0 {
+		return true, ErrWitnessProgramEmpty
+	}
+	vm.taprootCtx = &taprootExecutionCtx{
+		sigOpsBudget: sigOpsDelta + len(vm.tx.Tx.TxIn[vm.txIdx].SignScript),
+	}
+	if len(witness) >= 2 && len(witness[len(witness)-1]) > 0 &&
+		witness[len(witness)-1][0] == TaprootAnnexTag {
+		vm.taprootCtx.annex = witness[len(witness)-1]
+		witness = witness[:len(witness)-1]
+	}
+
+	// Key path spend.
+	if len(witness) == 1 {
+		return true, vm.verifyTaprootSignature(vm.witnessProgram, witness[0])
+	}
+
+	// Script path spend.
+	cb, err := ParseControlBlock(witness[len(witness)-1])
+	if err != nil {
+		return true, err
+	}
+	script := witness[len(witness)-2]
+	if err := VerifyTaprootLeafCommitment(cb, vm.witnessProgram, script); err != nil {
+		return true, err
+	}
+	if cb.LeafVersion != BaseLeafVersion {
+		// The unknown leaf versions are reserved for the upgrades.
+		if vm.hasFlag(ScriptDiscourageUpgradableNops) {
+			return true, ErrDiscourageUpgradableTaprootVersion
+		}
+		return true, nil
+	}
+	pops, parseErr := parseScript(script)
+	for _, pop := range pops {
+		if isOpSuccess(pop.opcode.value) {
+			if vm.hasFlag(ScriptDiscourageUpgradableNops) {
+				return true, ErrDiscourageUpgradableTaprootVersion
+			}
+			return true, nil
+		}
+	}
+	if parseErr != nil {
+		return true, parseErr
+	}
+
+	stack := witness[:len(witness)-2]
+	if len(stack) > maxStackSize {
+		return true, ErrStackOverflow
+	}
+	for _, item := range stack {
+		if len(item) > MaxScriptElementSize {
+			return true, ErrStackElementTooBig
+		}
+	}
+	leafHash := TapLeaf{LeafVersion: cb.LeafVersion, Script: script}.TapHash()
+	vm.taprootCtx.tapLeafHash = leafHash[:]
+	vm.scripts = [][]ParsedOpcode{pops}
+	vm.scriptIdx = 0
+	vm.scriptOff = 0
+	vm.bip16 = false
+	vm.SetStack(stack)
+	if len(pops) == 0 {
+		vm.scriptIdx++
+		return true, vm.CheckErrorCondition(true)
+	}
+	return false, nil
+}
+
+// popIfBool pops the argument of OP_IF and OP_NOTIF, which must be minimal in
+// tapscript: an empty vector or exactly 0x01.
+func (vm *Engine) popIfBool() (bool, error) {
+	if !vm.isTapscript() {
+		return vm.dstack.PopBool()
+	}
+	so, err := vm.dstack.PopByteArray()
+	if err != nil {
+		return false, err
+	}
+	if len(so) > 1 || (len(so) == 1 && so[0] != 0x01) {
+		return false, ErrMinimalIf
+	}
+	return len(so) == 1, nil
+}
+
+// tapscriptCheckSig executes the signature check of a tapscript and returns
+// whether or not the signature is valid.  An empty signature is a failed
+// check, while any other invalid signature fails the script.
+func (vm *Engine) tapscriptCheckSig(pubKey []byte, sig []byte) (bool, error) {
+	if len(sig) == 0 {
+		return false, nil
+	}
+	vm.taprootCtx.sigOpsBudget -= sigOpsDelta
+	if vm.taprootCtx.sigOpsBudget < 0 {
+		return false, ErrTaprootMaxSigOps
+	}
+	switch len(pubKey) {
+	case 0:
+		return false, ErrTaprootPubkeyIsEmpty
+	case schnorr.XOnlyPubKeyLen:
+		if err := vm.verifyTaprootSignature(pubKey, sig); err != nil {
+			return false, err
+		}
+	default:
+		// The unknown public key types are reserved for the upgrades.
+		if vm.hasFlag(ScriptDiscourageUpgradableNops) {
+			return false, ErrDiscourageUpgradableTaprootVersion
+		}
+	}
+	return true, nil
+}
+
+// opcodeTapscriptCheckSig is OP_CHECKSIG in tapscript, which verifies a BIP340
+// signature of the x-only public key.
+//
+// Stack transformation: [... signature pubkey] -> [... bool]
+func opcodeTapscriptCheckSig(vm *Engine) error {
+	pkBytes, err := vm.dstack.PopByteArray()
+	if err != nil {
+		return err
+	}
+	sigBytes, err := vm.dstack.PopByteArray()
+	if err != nil {
+		return err
+	}
+	valid, err := vm.tapscriptCheckSig(pkBytes, sigBytes)
+	if err != nil {
+		return err
+	}
+	vm.dstack.PushBool(valid)
+	return nil
+}
+
+// opcodeCheckSigAdd is OP_CHECKSIGADD of tapscript, which replaces the
+// OP_CHECKMULTISIG, it increments the counter when the signature is valid.
+//
+// Stack transformation: [... signature n pubkey] -> [... n+success]
+func opcodeCheckSigAdd(op *ParsedOpcode, vm *Engine) error {
+	// It's an upgradable NOP outside tapscript.
+	if !vm.isTapscript() {
+		return opcodeNop(op, vm)
+	}
+
+	pkBytes, err := vm.dstack.PopByteArray()
+	if err != nil {
+		return err
+	}
+	n, err := vm.dstack.PopInt(mathOpCodeMaxScriptNumLen)
+	if err != nil {
+		return err
+	}
+	sigBytes, err := vm.dstack.PopByteArray()
+	if err != nil {
+		return err
+	}
+	valid, err := vm.tapscriptCheckSig(pkBytes, sigBytes)
+	if err != nil {
+		return err
+	}
+	if valid {
+		n++
+	}
+	vm.dstack.PushInt(n)
+	return nil
+}
+
+// TaprootKeySpendSignature returns the key path signature of the input
+// spending the taproot output pkScript, the private key is the internal key
+// and the script root is nil for the outputs without scripts.
+func TaprootKeySpendSignature(tx *types.Transaction, idx int, pkScript []byte,
+	hashType SigHashType, privKey *secp256k1.PrivateKey, scriptRoot []byte) ([]byte, error) {
+
+	tweaked, err := TweakTaprootPrivKey(privKey, scriptRoot)
+	if err != nil {
+		return nil, err
+	}
+	h, err := CalcTaprootSignatureHash(hashType, tx, idx, pkScript)
+	if err != nil {
+		return nil, err
+	}
+	return taprootSignature(tweaked, h, hashType)
+}
+
+// TapscriptSignature returns the signature of the input spending the taproot
+// output pkScript by executing the leaf, which is checked by the signature
+// opcodes of the tapscript.
+func TapscriptSignature(tx *types.Transaction, idx int, pkScript []byte,
+	leaf TapLeaf, hashType SigHashType, privKey *secp256k1.PrivateKey) ([]byte, error) {
+
+	h, err := CalcTapscriptSignatureHash(hashType, tx, idx, pkScript, leaf)
+	if err != nil {
+		return nil, err
+	}
+	return taprootSignature(privKey, h, hashType)
+}
+
+func taprootSignature(privKey *secp256k1.PrivateKey, h []byte, hashType SigHashType) ([]byte, error) {
+	sig, err := schnorr.SignBIP340(privKey, h, nil)
+	if err != nil {
+		return nil, err
+	}
+	if hashType != SigHashDefault {
+		sig = append(sig, byte(hashType))
+	}
+	return sig, nil
+}
+
+// TaprootWitnessSignatureScript returns the signature script carrying the
+// witness stack of a taproot spend.  The control blocks and the leaf scripts
+// can be larger than MaxScriptElementSize since the witness items aren't
+// executed as data pushes.
+func TaprootWitnessSignatureScript(witness [][]byte) ([]byte, error) {
+	builder := NewScriptBuilder()
+	for _, item := range witness {
+		// The canonical push of 0x00 is OP_0, which is the empty item.
+		if len(item) == 1 && item[0] == 0 {
+			builder.AddOps([]byte{OP_DATA_1, 0})
+			continue
+		}
+		builder.addData(item)
+	}
+	script, err := builder.Script()
+	if err != nil {
+		return nil, err
+	}
+	if len(script) > maxScriptSize {
+		return nil, ErrStackLongScript
+	}
+	return script, nil
+}
@@ -0,0 +1,285 @@
+// Copyright (c) 2017-2020 The qitmeer developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+package txscript
+
+import (
+	"testing"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/crypto/ecc"
+	"github.com/Qitmeer/qng/crypto/ecc/schnorr"
+	"github.com/Qitmeer/qng/crypto/ecc/secp256k1"
+	"github.com/Qitmeer/qng/params"
+)
+
+const taprootTestFlags = ScriptBip16 | ScriptVerifyCleanStack |
+	ScriptVerifyMinimalData | ScriptVerifyTaproot
+
+func taprootTestKey(b byte) *secp256k1.PrivateKey {
+	seed := make([]byte, 32)
+	seed[31] = b
+	seed[0] = 0x42
+	privKey, _ := secp256k1.PrivKeyFromBytes(seed)
+	return privKey
+}
+
+func taprootTestTx() *types.Transaction {
+	tx := types.NewTransaction()
+	var prev hash.Hash
+	prev[0] = 0x01
+	tx.AddTxIn(types.NewTxInput(types.NewOutPoint(&prev, 0), nil))
+	tx.AddTxOut(types.NewTxOutput(types.Amount{Value: 1e8, Id: types.MEERA}, []byte{OP_TRUE}))
+	return tx
+}
+
+func executeTaproot(tx *types.Transaction, pkScript []byte, flags ScriptFlags) error {
+	vm, err := NewEngine(pkScript, types.NewTx(tx), 0, flags, 0, nil)
+	if err != nil {
+		return err
+	}
+	return vm.Execute()
+}
+
+func TestTaprootKeySpend(t *testing.T) {
+	privKey := taprootTestKey(1)
+	outputKey, err := ComputeTaprootKeyNoScript(privKey.PubKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkScript, err := PayToTaprootScript(outputKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if GetScriptClass(0, pkScript) != WitnessTaprootTy {
+		t.Fatalf("unexpected script class %v", GetScriptClass(0, pkScript))
+	}
+
+	for _, hashType := range []SigHashType{SigHashDefault, SigHashAll,
+		SigHashSingle | SigHashAnyOneCanPay} {
+
+		tx := taprootTestTx()
+		sig, err := TaprootKeySpendSignature(tx, 0, pkScript, hashType, privKey, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx.TxIn[0].SignScript, err = TaprootWitnessSignatureScript([][]byte{sig})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := executeTaproot(tx, pkScript, taprootTestFlags); err != nil {
+			t.Fatalf("hash type %v: %v", hashType, err)
+		}
+
+		// The signature commits to the outputs.
+		if hashType&sigHashMask != SigHashNone {
+			tx.TxOut[0].Amount.Value--
+			if err := executeTaproot(tx, pkScript, taprootTestFlags); err != ErrTaprootSigInvalid {
+				t.Fatalf("hash type %v: expected invalid signature, got %v", hashType, err)
+			}
+		}
+	}
+
+	// The taproot outputs aren't spendable by a signature before the fork.
+	tx := taprootTestTx()
+	sig, err := TaprootKeySpendSignature(tx, 0, pkScript, SigHashDefault, privKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.TxIn[0].SignScript, _ = TaprootWitnessSignatureScript([][]byte{sig})
+	if err := executeTaproot(tx, pkScript, taprootTestFlags&^ScriptVerifyTaproot); err == nil {
+		t.Fatal("expected the legacy validation to fail")
+	}
+
+	// The witness must not be empty.
+	tx.TxIn[0].SignScript = nil
+	if err := executeTaproot(tx, pkScript, taprootTestFlags); err != ErrWitnessProgramEmpty {
+		t.Fatalf("expected empty witness error, got %v", err)
+	}
+}
+
+func TestTaprootScriptSpend(t *testing.T) {
+	internalKey := taprootTestKey(1)
+	keyA, keyB := taprootTestKey(2), taprootTestKey(3)
+
+	// 2-of-2 with OP_CHECKSIGADD
+	multisig, err := NewScriptBuilder().
+		AddData(schnorr.SerializeXOnlyPubKey(keyA.PubKey())).AddOp(OP_CHECKSIG).
+		AddData(schnorr.SerializeXOnlyPubKey(keyB.PubKey())).AddOp(OP_CHECKSIGADD).
+		AddOp(OP_2).AddOp(OP_NUMEQUAL).Script()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewScriptBuilder().AddOp(OP_TRUE).Script()
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaves := []TapLeaf{NewBaseTapLeaf(multisig), NewBaseTapLeaf(other), NewBaseTapLeaf([]byte{OP_FALSE})}
+	root, proofs := AssembleTaprootScriptTree(leaves...)
+	outputKey, err := ComputeTaprootOutputKey(internalKey.PubKey(), root[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkScript, err := PayToTaprootScript(outputKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	controlBlock := func(proof TapscriptProof) []byte {
+		cb := ControlBlock{
+			InternalKey:     internalKey.PubKey(),
+			OutputKeyYIsOdd: outputKey.GetY().Bit(0) == 1,
+			LeafVersion:     proof.LeafVersion,
+			InclusionProof:  proof.InclusionProof,
+		}
+		return cb.ToBytes()
+	}
+	spend := func(tx *types.Transaction, witness ...[]byte) error {
+		var err error
+		tx.TxIn[0].SignScript, err = TaprootWitnessSignatureScript(witness)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return executeTaproot(tx, pkScript, taprootTestFlags)
+	}
+
+	tx := taprootTestTx()
+	sigA, err := TapscriptSignature(tx, 0, pkScript, leaves[0], SigHashDefault, keyA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigB, err := TapscriptSignature(tx, 0, pkScript, leaves[0], SigHashAll, keyB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb := controlBlock(proofs[0])
+	if err := spend(tx, sigB, sigA, multisig, cb); err != nil {
+		t.Fatalf("2-of-2 spend failed: %v", err)
+	}
+
+	// A missing signature leaves the counter at 1.
+	if err := spend(tx, nil, sigA, multisig, cb); err != ErrStackScriptFailed {
+		t.Fatalf("expected script failure, got %v", err)
+	}
+
+	// A tampered signature fails the script.
+	bad := append([]byte{}, sigB...)
+	bad[10] ^= 0x01
+	if err := spend(tx, bad, sigA, multisig, cb); err != ErrTaprootSigInvalid {
+		t.Fatalf("expected invalid signature, got %v", err)
+	}
+
+	// The signatures commit to the executed leaf.
+	otherSig, err := TapscriptSignature(tx, 0, pkScript, leaves[1], SigHashDefault, keyB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := spend(tx, otherSig, sigA, multisig, cb); err != ErrTaprootSigInvalid {
+		t.Fatalf("expected invalid signature of another leaf, got %v", err)
+	}
+
+	// The control block must prove the revealed script.
+	if err := spend(tx, sigB, sigA, multisig, controlBlock(proofs[1])); err != ErrTaprootMerkleProofInvalid {
+		t.Fatalf("expected invalid merkle proof, got %v", err)
+	}
+	wrongParity := append([]byte{}, cb...)
+	wrongParity[0] ^= 0x01
+	if err := spend(tx, sigB, sigA, multisig, wrongParity); err != ErrTaprootMerkleProofInvalid {
+		t.Fatalf("expected invalid parity, got %v", err)
+	}
+	if err := spend(tx, sigB, sigA, multisig, cb[:len(cb)-1]); err != ErrControlBlockInvalid {
+		t.Fatalf("expected invalid control block, got %v", err)
+	}
+
+	// The other leaves, the annex is skipped.
+	if err := spend(tx, other, controlBlock(proofs[1]), []byte{TaprootAnnexTag, 0x01}); err != nil {
+		t.Fatalf("leaf spend with annex failed: %v", err)
+	}
+	if err := spend(tx, []byte{OP_FALSE}, controlBlock(proofs[2])); err != ErrStackScriptFailed {
+		t.Fatalf("expected script failure, got %v", err)
+	}
+}
+
+func TestTapscriptRules(t *testing.T) {
+	internalKey := taprootTestKey(1)
+	tests := []struct {
+		name    string
+		script  []byte
+		witness [][]byte
+		err     error
+	}{
+		{"op_success", []byte{OP_RETURN, 0xbb}, nil, nil},
+		{"checkmultisig", []byte{OP_0, OP_0, OP_CHECKMULTISIG}, nil, ErrTapscriptCheckMultisig},
+		{"minimal if", []byte{OP_IF, OP_TRUE, OP_ENDIF}, [][]byte{{0x02}}, ErrMinimalIf},
+		{"minimal if ok", []byte{OP_IF, OP_TRUE, OP_ENDIF}, [][]byte{{0x01}}, nil},
+		{"clean stack", []byte{OP_TRUE, OP_TRUE}, nil, ErrStackCleanStack},
+		{"empty pubkey", []byte{OP_0, OP_CHECKSIG}, [][]byte{{0x01}}, ErrTaprootPubkeyIsEmpty},
+		{"unknown pubkey type", []byte{OP_1, OP_CHECKSIG}, [][]byte{{0x01}}, nil},
+		{"checksigadd isn't op_success", []byte{OP_CHECKSIGADD, OP_TRUE}, nil, ErrStackUnderflow},
+		{"op_sstx isn't checksigadd", []byte{OP_TRUE, OP_SSTX}, nil, nil},
+	}
+	for _, test := range tests {
+		leaf := NewBaseTapLeaf(test.script)
+		root, proofs := AssembleTaprootScriptTree(leaf)
+		outputKey, err := ComputeTaprootOutputKey(internalKey.PubKey(), root[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkScript, _ := PayToTaprootScript(outputKey)
+		cb := ControlBlock{
+			InternalKey:     internalKey.PubKey(),
+			OutputKeyYIsOdd: outputKey.GetY().Bit(0) == 1,
+			LeafVersion:     leaf.LeafVersion,
+			InclusionProof:  proofs[0].InclusionProof,
+		}
+		tx := taprootTestTx()
+		witness := append(test.witness, test.script, cb.ToBytes())
+		tx.TxIn[0].SignScript, err = TaprootWitnessSignatureScript(witness)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := executeTaproot(tx, pkScript, taprootTestFlags); err != test.err {
+			t.Errorf("%s: got %v, expected %v", test.name, err, test.err)
+		}
+	}
+}
+
+func TestCheckSigAddOutsideTapscript(t *testing.T) {
+	if OP_CHECKSIGADD == OP_SSTX {
+		t.Fatal("OP_CHECKSIGADD collides with OP_SSTX")
+	}
+	if isOpSuccess(OP_CHECKSIGADD) || isOpSuccess(OP_SSTX) {
+		t.Fatal("OP_CHECKSIGADD and OP_SSTX aren't OP_SUCCESSx")
+	}
+
+	// It's an upgradable NOP in the other scripts.
+	pkScript := []byte{OP_TRUE, OP_CHECKSIGADD}
+	tx := taprootTestTx()
+	if err := executeTaproot(tx, pkScript, taprootTestFlags); err != nil {
+		t.Fatalf("expected OP_CHECKSIGADD to be a NOP, got %v", err)
+	}
+	if err := executeTaproot(tx, pkScript, taprootTestFlags|ScriptDiscourageUpgradableNops); err == nil {
+		t.Fatal("expected OP_CHECKSIGADD to be discouraged")
+	}
+}
+
+func TestSignTxOutputTaproot(t *testing.T) {
+	privKey := taprootTestKey(7)
+	outputKey, err := ComputeTaprootKeyNoScript(privKey.PubKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkScript, _ := PayToTaprootScript(outputKey)
+	tx := taprootTestTx()
+	var kdb KeyClosure = func(types.Address) (ecc.PrivateKey, bool, error) {
+		return privKey, true, nil
+	}
+	tx.TxIn[0].SignScript, err = SignTxOutput(&params.PrivNetParams, tx, 0,
+		pkScript, SigHashAll, kdb, nil, nil, ecc.ECDSA_Secp256k1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := executeTaproot(tx, pkScript, taprootTestFlags); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -25,12 +25,20 @@ func (bd *MeerDAG) Load(blockTotal uint, genesis *hash.Hash) error {
 	bd.blockTotal = blockTotal
 	bd.blocks = map[uint]IBlock{}
 	bd.tips = NewIdSet()
-	return bd.instance.Load()
+	err = bd.instance.Load()
+	if err != nil {
+		return err
+	}
+	bd.updateMetrics()
+	return nil
 }
 
 func (bd *MeerDAG) Encode(w io.Writer) error {
-	dagTypeIndex := GetDAGTypeIndex(bd.instance.GetName())
-	err := s.WriteElements(w, dagTypeIndex)
+	dagTypeIndex, err := GetDAGTypeIndex(bd.instance.GetName())
+	if err != nil {
+		return err
+	}
+	err = s.WriteElements(w, dagTypeIndex)
 	if err != nil {
 		return err
 	}
@@ -44,7 +52,11 @@ func (bd *MeerDAG) Decode(r io.Reader) error {
 	if err != nil {
 		return err
 	}
-	if GetDAGTypeIndex(bd.instance.GetName()) != dagTypeIndex {
+	expectIndex, err := GetDAGTypeIndex(bd.instance.GetName())
+	if err != nil {
+		return err
+	}
+	if expectIndex != dagTypeIndex {
 		return fmt.Errorf("The dag type is %s, but read is %s", bd.instance.GetName(), GetDAGTypeByIndex(dagTypeIndex))
 	}
 	return bd.instance.Decode(r)
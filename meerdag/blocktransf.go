@@ -3,6 +3,7 @@ package meerdag
 import (
 	"fmt"
 	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/consensus/model"
 	"sort"
 	"time"
 )
@@ -269,6 +270,22 @@ func (bd *MeerDAG) InvalidBlock(block IBlock) {
 	bd.commitBlock.AddPair(block.GetID(), block)
 }
 
+func (bd *MeerDAG) SetBlockStatusFlags(block IBlock, flags model.BlockStatus) {
+	bd.stateLock.Lock()
+	defer bd.stateLock.Unlock()
+
+	block.GetState().SetStatusFlags(flags)
+	bd.commitBlock.AddPair(block.GetID(), block)
+}
+
+func (bd *MeerDAG) UnsetBlockStatusFlags(block IBlock, flags model.BlockStatus) {
+	bd.stateLock.Lock()
+	defer bd.stateLock.Unlock()
+
+	block.GetState().UnsetStatusFlags(flags)
+	bd.commitBlock.AddPair(block.GetID(), block)
+}
+
 // GetIdSet
 func (bd *MeerDAG) GetIdSet(hs []*hash.Hash) *IdSet {
 	result := NewIdSet()
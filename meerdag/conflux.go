@@ -333,7 +333,7 @@ func (con *Conflux) isVirtualBlock(b IBlock) bool {
 }
 
 // Query whether a given block is on the main chain.
-func (con *Conflux) isOnMainChain(id uint) bool {
+func (con *Conflux) IsOnMainChain(id uint) bool {
 	b := con.bd.getBlockById(id)
 	for p := con.privotTip; p != nil; p = con.bd.getBlockById(p.GetMainParent()) {
 		if p.GetHash().IsEqual(b.GetHash()) {
@@ -390,8 +390,8 @@ func (con *Conflux) IsBlue(id uint) bool {
 	return false
 }
 
-// getMaxParents
-func (con *Conflux) getMaxParents() int {
+// GetMaxParents
+func (con *Conflux) GetMaxParents() int {
 	return 0
 }
 
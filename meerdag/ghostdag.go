@@ -98,7 +98,7 @@ func (gd *GhostDAG) GetTipsList() []IBlock {
 }
 
 // Query whether a given block is on the main chain.
-func (gd *GhostDAG) isOnMainChain(id uint) bool {
+func (gd *GhostDAG) IsOnMainChain(id uint) bool {
 	b := gd.bd.getBlockById(id)
 	for cur := gd.mainChainTip; cur != nil; cur = gd.bd.getBlockById(cur.GetMainParent()) {
 		if cur.GetHash().IsEqual(b.GetHash()) {
@@ -184,7 +184,7 @@ func (gd *GhostDAG) GetMainParentConcurrency(b IBlock) int {
 	return 0
 }
 
-func (gd *GhostDAG) getMaxParents() int {
+func (gd *GhostDAG) GetMaxParents() int {
 	return 0
 }
 
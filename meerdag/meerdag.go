@@ -60,45 +60,27 @@ const MinBlockPruneSize = 2000
 
 // It will create different BlockDAG instances
 func NewBlockDAG(dagType string) ConsensusAlgorithm {
-	switch dagType {
-	case PHANTOM:
-		return &Phantom{}
-	case CONFLUX:
-		return &Conflux{}
-	case SPECTRE:
-		return &Spectre{}
-	case GHOSTDAG:
-		return &GhostDAG{}
+	ca := getConsensusAlgorithm(dagType)
+	if ca == nil {
+		return nil
 	}
-	return nil
+	return ca.creator()
 }
 
-func GetDAGTypeIndex(dagType string) byte {
-	switch dagType {
-	case PHANTOM:
-		return 0
-	case CONFLUX:
-		return 2
-	case SPECTRE:
-		return 3
-	case GHOSTDAG:
-		return 4
+func GetDAGTypeIndex(dagType string) (byte, error) {
+	ca := getConsensusAlgorithm(dagType)
+	if ca == nil {
+		return 0, fmt.Errorf("The DAG type (%s) isn't registered", dagType)
 	}
-	return 0
+	return ca.index, nil
 }
 
 func GetDAGTypeByIndex(dagType byte) string {
-	switch dagType {
-	case 0:
+	ca := getConsensusAlgorithmByIndex(dagType)
+	if ca == nil {
 		return PHANTOM
-	case 2:
-		return CONFLUX
-	case 3:
-		return SPECTRE
-	case 4:
-		return GHOSTDAG
-	}
-	return PHANTOM
+	}
+	return ca.name
 }
 
 // The abstract inferface is used to build and manager DAG consensus algorithm
@@ -119,7 +101,7 @@ type ConsensusAlgorithm interface {
 	GetTipsList() []IBlock
 
 	// Query whether a given block is on the main chain.
-	isOnMainChain(id uint) bool
+	IsOnMainChain(id uint) bool
 
 	// return the tip of main chain
 	GetMainChainTip() IBlock
@@ -151,8 +133,8 @@ type ConsensusAlgorithm interface {
 	// IsBlue
 	IsBlue(id uint) bool
 
-	// getMaxParents
-	getMaxParents() int
+	// The max number of parents of a block
+	GetMaxParents() int
 }
 
 type GetBlockData func(*hash.Hash) IBlockData
@@ -243,6 +225,9 @@ func (bd *MeerDAG) init(dagType string, blockRate float64, db model.DataBase, ge
 		bd.blockRate = anticone.DefaultBlockRate
 	}
 	bd.instance = NewBlockDAG(dagType)
+	if viewer, ok := bd.instance.(DAGViewer); ok {
+		viewer.SetDAGView(&DAGView{bd: bd})
+	}
 	bd.instance.Init(bd)
 
 	serializedData, err := bd.db.GetDagInfo()
@@ -370,6 +355,10 @@ func (bd *MeerDAG) AddBlock(b IBlockData) (*list.List, *list.List, IBlock, bool)
 			if maxLayer == 0 || maxLayer < parent.GetLayer() {
 				maxLayer = parent.GetLayer()
 			}
+			// The block is invalidated with its parent
+			if isInvalidated(parent) {
+				ib.GetState().SetStatusFlags(model.StatusInvalidated)
+			}
 		}
 		block.SetLayer(maxLayer + 1)
 	}
@@ -390,16 +379,15 @@ func (bd *MeerDAG) AddBlock(b IBlockData) (*list.List, *list.List, IBlock, bool)
 	if olds == nil {
 		olds = list.New()
 	}
+	// Some algorithms don't maintain a main chain
 	curMT := bd.getMainChainTip()
+	mainChanged := curMT != nil && lastMT != curMT.GetID()
 
-	mainOrderGauge.Update(int64(curMT.GetOrder()))
-	mainHeightGauge.Update(int64(curMT.GetHeight()))
-	mainLayerGauge.Update(int64(curMT.GetLayer()))
-	tipsTotalGauge.Update(int64(bd.tips.Size()))
+	bd.updateMetrics()
 	if olds.Len() > 0 {
 		reorganizeGauge.Update(int64(olds.Len()))
 	}
-	return news, olds, ib, lastMT != curMT.GetID()
+	return news, olds, ib, mainChanged
 }
 
 // Acquire the genesis block of chain
@@ -474,7 +462,7 @@ func (bd *MeerDAG) IsOnMainChain(id uint) bool {
 // Query whether a given block is on the main chain.
 // Note that some DAG protocols may not support this feature.
 func (bd *MeerDAG) isOnMainChain(id uint) bool {
-	return bd.instance.isOnMainChain(id)
+	return bd.instance.IsOnMainChain(id)
 }
 
 // return the tip of main chain
@@ -490,6 +478,72 @@ func (bd *MeerDAG) getMainChainTip() IBlock {
 	return bd.instance.GetMainChainTip()
 }
 
+// Return the first order that UpdateMainChain will change, it's MaxBlockOrder
+// if the main chain tip stays the same.
+func (bd *MeerDAG) GetMainChainChangeOrder() uint {
+	bd.stateLock.Lock()
+	defer bd.stateLock.Unlock()
+
+	ph, ok := bd.instance.(*Phantom)
+	if !ok {
+		return MaxBlockOrder
+	}
+	return ph.getMainChainChangeOrder()
+}
+
+// UpdateMainChain selects the main chain again after the status of blocks is
+// changed by the operator, the invalidated blocks are removed from the order.
+// It returns the blocks whose orders changed and their old orders, the new
+// orders are persisted by the next commit.
+func (bd *MeerDAG) UpdateMainChain() (*list.List, *list.List, error) {
+	bd.stateLock.Lock()
+	defer bd.stateLock.Unlock()
+
+	ph, ok := bd.instance.(*Phantom)
+	if !ok {
+		return nil, nil, fmt.Errorf("The DAG type (%s) can't update main chain", bd.instance.GetName())
+	}
+	bd.lastSnapshot.Clean()
+	lastMT := ph.mainChain.tip
+	lastDiffAnticone := ph.diffAnticone.Clone()
+
+	news, olds := ph.reselectMainChain()
+	bd.optimizeReorganizeResult(news, olds)
+
+	if lastMT != ph.mainChain.tip {
+		if bd.tips.Has(lastMT) {
+			err := DBPutDAGTip(bd.db, lastMT, false)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		err := DBPutDAGTip(bd.db, ph.mainChain.tip, true)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	for k := range ph.diffAnticone.GetMap() {
+		if lastDiffAnticone.Has(k) {
+			continue
+		}
+		err := DBPutDiffAnticone(bd.db, k)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	for k := range lastDiffAnticone.GetMap() {
+		if ph.diffAnticone.Has(k) {
+			continue
+		}
+		err := DBDelDiffAnticone(bd.db, k)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	bd.updateMetrics()
+	return news, olds, nil
+}
+
 // return the main parent in the parents
 func (bd *MeerDAG) GetMainParent(parents *IdSet) IBlock {
 	bd.stateLock.Lock()
@@ -1077,7 +1131,7 @@ func (bd *MeerDAG) GetMaturity(target uint, views []uint) uint {
 
 // Get path intersection from block to main chain.
 func (bd *MeerDAG) getMainFork(ib IBlock, backward bool) IBlock {
-	if bd.instance.isOnMainChain(ib.GetID()) {
+	if bd.instance.IsOnMainChain(ib.GetID()) {
 		return ib
 	}
 
@@ -1089,7 +1143,7 @@ func (bd *MeerDAG) getMainFork(ib IBlock, backward bool) IBlock {
 		cur := queue[0]
 		queue = queue[1:]
 
-		if bd.instance.isOnMainChain(cur.GetID()) {
+		if bd.instance.IsOnMainChain(cur.GetID()) {
 			return cur
 		}
 
@@ -1121,7 +1175,7 @@ func (bd *MeerDAG) getMainFork(ib IBlock, backward bool) IBlock {
 
 // MaxParentsPerBlock
 func (bd *MeerDAG) getMaxParents() int {
-	return bd.instance.getMaxParents()
+	return bd.instance.GetMaxParents()
 }
 
 // The main parent concurrency of block
@@ -13,3 +13,15 @@ var (
 	unsequencedGauge = metrics.NewRegisteredGauge("meerdag/unsequenced", nil)
 	reorganizeGauge  = metrics.NewRegisteredGauge("meerdag/reorganize", nil)
 )
+
+// updateMetrics updates the gauges of the main chain tip and the tips.
+func (bd *MeerDAG) updateMetrics() {
+	curMT := bd.getMainChainTip()
+	if curMT == nil {
+		return
+	}
+	mainOrderGauge.Update(int64(curMT.GetOrder()))
+	mainHeightGauge.Update(int64(curMT.GetHeight()))
+	mainLayerGauge.Update(int64(curMT.GetLayer()))
+	tipsTotalGauge.Update(int64(bd.tips.Size()))
+}
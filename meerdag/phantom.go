@@ -59,7 +59,7 @@ func (ph *Phantom) AddBlock(ib IBlock) (*list.List, *list.List) {
 	ph.updateBlockColor(pb)
 	ph.updateBlockOrder(pb)
 
-	changeBlock, oldOrders := ph.updateMainChain(ph.getBluest(ph.bd.tips), pb)
+	changeBlock, oldOrders := ph.updateMainChain(ph.getBluest(ph.bd.getCandidateTips()), pb)
 	ph.preUpdateVirtualBlock()
 	return ph.getOrderChangeList(changeBlock), oldOrders
 }
@@ -279,7 +279,9 @@ func (ph *Phantom) updateMainChain(buestTip *PhantomBlock, pb *PhantomBlock) (*P
 
 	ph.virtualBlock.SetOrder(MaxBlockOrder)
 	if !ph.isMaxMainTip(buestTip) {
-		ph.diffAnticone.AddPair(pb.GetID(), pb)
+		if !isInvalidated(pb) {
+			ph.diffAnticone.AddPair(pb.GetID(), pb)
+		}
 		return nil, nil
 	}
 	if ph.mainChain.tip == MaxId {
@@ -314,7 +316,7 @@ func (ph *Phantom) updateMainChain(buestTip *PhantomBlock, pb *PhantomBlock) (*P
 	ph.updateMainOrder(path, intersection)
 	ph.mainChain.tip = buestTip.GetID()
 
-	ph.diffAnticone = ph.bd.getAnticone(ph.bd.getBlockById(ph.mainChain.tip), nil)
+	ph.updateDiffAnticone()
 
 	changeOrder := intersectionBlock.GetOrder() + 1
 
@@ -325,6 +327,75 @@ func (ph *Phantom) updateMainChain(buestTip *PhantomBlock, pb *PhantomBlock) (*P
 	return coPB, oldOrders
 }
 
+// The diff anticone is the anticone of main chain tip without the invalidated blocks.
+func (ph *Phantom) updateDiffAnticone() {
+	ph.diffAnticone = ph.bd.getAnticone(ph.bd.getBlockById(ph.mainChain.tip), nil)
+	for k, v := range ph.diffAnticone.GetMap() {
+		if isInvalidated(v.(IBlock)) {
+			ph.diffAnticone.Remove(k)
+		}
+	}
+}
+
+// Return the first order that will be changed by reselectMainChain, if the main chain
+// tip doesn't change it returns MaxBlockOrder.
+func (ph *Phantom) getMainChainChangeOrder() uint {
+	tip := ph.getBluest(ph.bd.getCandidateTips())
+	if tip == nil || tip.GetID() == ph.mainChain.tip {
+		return MaxBlockOrder
+	}
+	intersection, _ := ph.getIntersectionPathWithMainChain(tip)
+	return ph.getBlock(intersection).GetOrder() + 1
+}
+
+// Select the main chain again after the blocks are invalidated or reconsidered.
+// The main chain tip becomes the bluest candidate tip, and the invalidated blocks
+// lose their orders. It returns the blocks whose orders changed and their old orders.
+func (ph *Phantom) reselectMainChain() (*list.List, *list.List) {
+	newOrders := list.New()
+	oldOrders := list.New()
+
+	ph.virtualBlock.SetOrder(MaxBlockOrder)
+	for k := range ph.diffAnticone.GetMap() {
+		dab := ph.getBlock(k)
+		dab.SetOrder(MaxBlockOrder)
+		ph.bd.commitBlock.AddPair(dab.GetID(), dab)
+	}
+
+	tip := ph.getBluest(ph.bd.getCandidateTips())
+	if tip != nil && tip.GetID() != ph.mainChain.tip {
+		intersection, path := ph.getIntersectionPathWithMainChain(tip)
+		intersectionBlock := ph.bd.getBlockById(intersection)
+		if intersectionBlock == nil {
+			panic("DAG can't find intersection!")
+		}
+		for i := intersectionBlock.GetOrder() + 1; i <= ph.GetMainChainTip().GetOrder(); i++ {
+			ib := ph.bd.getBlockByOrder(i)
+			if ib == nil {
+				panic(fmt.Errorf("DAG can't find block in order(%d)\n", i))
+			}
+			oldOrders.PushBack(&BlockOrderHelp{OldOrder: i, Block: ib})
+		}
+		// The blocks that aren't ordered again by the new main chain are invalidated.
+		for e := oldOrders.Front(); e != nil; e = e.Next() {
+			ib := e.Value.(*BlockOrderHelp).Block
+			ib.SetOrder(MaxBlockOrder)
+			ph.bd.commitBlock.AddPair(ib.GetID(), ib)
+		}
+
+		ph.rollBackMainChain(intersection)
+		ph.updateMainOrder(path, intersection)
+		ph.mainChain.tip = tip.GetID()
+
+		for i := intersectionBlock.GetOrder() + 1; i <= tip.GetOrder(); i++ {
+			newOrders.PushBack(ph.bd.getBlockByOrder(i))
+		}
+	}
+	ph.updateDiffAnticone()
+	ph.preUpdateVirtualBlock()
+	return newOrders, oldOrders
+}
+
 func (ph *Phantom) isMaxMainTip(pb *PhantomBlock) bool {
 	if ph.mainChain.tip == MaxId {
 		return true
@@ -413,7 +484,7 @@ func (ph *Phantom) UpdateVirtualBlockOrder() *PhantomBlock {
 	}
 	ph.virtualBlock.parents = NewIdSet()
 	var maxLayer uint = 0
-	for k := range ph.bd.tips.GetMap() {
+	for k := range ph.bd.getCandidateTips().GetMap() {
 		parent := ph.bd.getBlockById(k)
 		ph.virtualBlock.parents.AddPair(k, parent)
 
@@ -502,7 +573,7 @@ func (ph *Phantom) GetTipsList() []IBlock {
 }
 
 // Query whether a given block is on the main chain.
-func (ph *Phantom) isOnMainChain(id uint) bool {
+func (ph *Phantom) IsOnMainChain(id uint) bool {
 	if ph.mainChain.Has(id) {
 		return true
 	}
@@ -612,9 +683,13 @@ func (ph *Phantom) Load() error {
 		if tip == nil {
 			return fmt.Errorf("Can't find tip:%d", v)
 		}
+		// The main chain tip may have the invalidated children only.
+		if v == ph.mainChain.tip && tip.HasChildren() {
+			continue
+		}
 		ph.bd.updateTips(tip)
 	}
-	if !ph.bd.tips.Has(ph.mainChain.tip) {
+	if !ph.bd.getCandidateTips().Has(ph.mainChain.tip) {
 		return fmt.Errorf("Main chain tip and tips is mismatch")
 	}
 
@@ -766,7 +841,7 @@ func (ph *Phantom) GetMainParentConcurrency(b IBlock) int {
 	return result
 }
 
-func (ph *Phantom) getMaxParents() int {
+func (ph *Phantom) GetMaxParents() int {
 	dagMax := ph.anticoneSize + 1
 	if dagMax < types.MaxParentsPerBlock {
 		return dagMax
@@ -0,0 +1,149 @@
+package meerdag
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ConsensusAlgorithmCreator creates a new instance of the DAG consensus
+// algorithm, every MeerDAG owns its own instance.
+type ConsensusAlgorithmCreator func() ConsensusAlgorithm
+
+type consensusAlgorithmEntry struct {
+	name    string
+	index   byte
+	creator ConsensusAlgorithmCreator
+}
+
+var (
+	consensusAlgorithmsLock    sync.RWMutex
+	consensusAlgorithms        = map[string]*consensusAlgorithmEntry{}
+	consensusAlgorithmsByIndex = map[byte]*consensusAlgorithmEntry{}
+)
+
+// RegisterConsensusAlgorithm registers a DAG consensus algorithm, so that
+// it can be selected by its name (the DAG type) and persisted by its index.
+// Both the name and the index must be unique, and the name must be the one
+// returned by GetName of the created instance.
+func RegisterConsensusAlgorithm(name string, index byte, creator ConsensusAlgorithmCreator) error {
+	if len(name) <= 0 {
+		return fmt.Errorf("The name of DAG consensus algorithm is empty")
+	}
+	if creator == nil {
+		return fmt.Errorf("The creator of DAG consensus algorithm (%s) is nil", name)
+	}
+	instance := creator()
+	if instance == nil {
+		return fmt.Errorf("The creator of DAG consensus algorithm (%s) returns nil", name)
+	}
+	if instance.GetName() != name {
+		return fmt.Errorf("The DAG consensus algorithm name (%s) doesn't match %s", instance.GetName(), name)
+	}
+
+	consensusAlgorithmsLock.Lock()
+	defer consensusAlgorithmsLock.Unlock()
+
+	if _, ok := consensusAlgorithms[name]; ok {
+		return fmt.Errorf("The DAG consensus algorithm (%s) is already registered", name)
+	}
+	if exist, ok := consensusAlgorithmsByIndex[index]; ok {
+		return fmt.Errorf("The DAG type index %d of %s is already used by %s", index, name, exist.name)
+	}
+	entry := &consensusAlgorithmEntry{name: name, index: index, creator: creator}
+	consensusAlgorithms[name] = entry
+	consensusAlgorithmsByIndex[index] = entry
+	return nil
+}
+
+// MustRegisterConsensusAlgorithm is like RegisterConsensusAlgorithm, but it
+// panics when the registration fails. It's intended to be called in init.
+func MustRegisterConsensusAlgorithm(name string, index byte, creator ConsensusAlgorithmCreator) {
+	if err := RegisterConsensusAlgorithm(name, index, creator); err != nil {
+		panic(err)
+	}
+}
+
+// IsRegisteredConsensusAlgorithm returns whether the DAG type is registered.
+func IsRegisteredConsensusAlgorithm(name string) bool {
+	return getConsensusAlgorithm(name) != nil
+}
+
+// RegisteredConsensusAlgorithms returns the names of all registered DAG
+// consensus algorithms in the order of their indexes.
+func RegisteredConsensusAlgorithms() []string {
+	consensusAlgorithmsLock.RLock()
+	defer consensusAlgorithmsLock.RUnlock()
+
+	entries := make([]*consensusAlgorithmEntry, 0, len(consensusAlgorithms))
+	for _, entry := range consensusAlgorithms {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].index < entries[j].index
+	})
+	result := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, entry.name)
+	}
+	return result
+}
+
+func getConsensusAlgorithm(name string) *consensusAlgorithmEntry {
+	consensusAlgorithmsLock.RLock()
+	defer consensusAlgorithmsLock.RUnlock()
+	return consensusAlgorithms[name]
+}
+
+func getConsensusAlgorithmByIndex(index byte) *consensusAlgorithmEntry {
+	consensusAlgorithmsLock.RLock()
+	defer consensusAlgorithmsLock.RUnlock()
+	return consensusAlgorithmsByIndex[index]
+}
+
+func init() {
+	MustRegisterConsensusAlgorithm(PHANTOM, 0, func() ConsensusAlgorithm { return &Phantom{} })
+	MustRegisterConsensusAlgorithm(CONFLUX, 2, func() ConsensusAlgorithm { return &Conflux{} })
+	MustRegisterConsensusAlgorithm(SPECTRE, 3, func() ConsensusAlgorithm { return &Spectre{} })
+	MustRegisterConsensusAlgorithm(GHOSTDAG, 4, func() ConsensusAlgorithm { return &GhostDAG{} })
+}
+
+// DAGViewer is implemented by the algorithms outside this package that need
+// to access the DAG. MeerDAG hands the view over before calling Init.
+type DAGViewer interface {
+	SetDAGView(view *DAGView)
+}
+
+// DAGView lets the implementations of ConsensusAlgorithm outside this package
+// access the DAG. The algorithm is always called with the state lock held, so
+// the view doesn't lock and must only be used inside the algorithm callbacks.
+type DAGView struct {
+	bd *MeerDAG
+}
+
+// BlockById returns the block by id.
+func (v *DAGView) BlockById(id uint) IBlock {
+	return v.bd.getBlockById(id)
+}
+
+// BlockTotal returns the total of blocks.
+func (v *DAGView) BlockTotal() uint {
+	return v.bd.blockTotal
+}
+
+// Genesis returns the genesis block.
+func (v *DAGView) Genesis() IBlock {
+	return v.bd.getGenesis()
+}
+
+// Tips returns a copy of the current tips.
+func (v *DAGView) Tips() *IdSet {
+	return v.bd.tips.Clone()
+}
+
+// SetBlockOrder sets the order of block, and it will be committed with the
+// block.
+func (v *DAGView) SetBlockOrder(ib IBlock, order uint) {
+	ib.SetOrder(order)
+	v.bd.commitOrder[order] = ib.GetID()
+}
@@ -801,7 +801,7 @@ func (sp *Spectre) voteByUniqueFutureSet(b1 IBlock, b2 IBlock) {
 }
 
 // Currently not supported
-func (sp *Spectre) isOnMainChain(id uint) bool {
+func (sp *Spectre) IsOnMainChain(id uint) bool {
 	return false
 }
 
@@ -848,8 +848,8 @@ func (sp *Spectre) IsBlue(id uint) bool {
 	return false
 }
 
-// getMaxParents
-func (sp *Spectre) getMaxParents() int {
+// GetMaxParents
+func (sp *Spectre) GetMaxParents() int {
 	return 0
 }
 
@@ -0,0 +1,338 @@
+package test
+
+import (
+	"container/list"
+	"fmt"
+	"io"
+	"sort"
+	"testing"
+
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/meerdag"
+)
+
+// The graphs of testData.json that every registered algorithm runs against
+var conformanceGraphs = []string{
+	"PH_fig1-blocks",
+	"PH_fig2-blocks",
+	"PH_fig4-blocks",
+	"CO_Blocks",
+	"SP_Blocks",
+	"CP_Blocks",
+}
+
+// An algorithm registered outside meerdag, it only uses the exported API and
+// the DAG view. Every block is ordered by its arrival, the main parent is the
+// latest parent and the main chain ends at the latest block.
+type externalOrder struct {
+	view      *meerdag.DAGView
+	mainChain map[uint]struct{}
+	tip       meerdag.IBlock
+}
+
+const externalOrderName = "external-order"
+
+func (eo *externalOrder) GetName() string {
+	return externalOrderName
+}
+
+func (eo *externalOrder) SetDAGView(view *meerdag.DAGView) {
+	eo.view = view
+}
+
+func (eo *externalOrder) Init(bd *meerdag.MeerDAG) bool {
+	eo.mainChain = map[uint]struct{}{}
+	return eo.view != nil
+}
+
+func (eo *externalOrder) AddBlock(ib meerdag.IBlock) (*list.List, *list.List) {
+	eo.view.SetBlockOrder(ib, ib.GetID())
+	eo.tip = ib
+	eo.mainChain = map[uint]struct{}{}
+	for cur := ib; cur != nil; cur = eo.view.BlockById(cur.GetMainParent()) {
+		eo.mainChain[cur.GetID()] = struct{}{}
+		if !cur.HasParents() {
+			break
+		}
+	}
+	news := list.New()
+	news.PushBack(ib)
+	return news, nil
+}
+
+func (eo *externalOrder) CreateBlock(b *meerdag.Block) meerdag.IBlock {
+	return b
+}
+
+func (eo *externalOrder) GetTipsList() []meerdag.IBlock {
+	return nil
+}
+
+func (eo *externalOrder) IsOnMainChain(id uint) bool {
+	_, ok := eo.mainChain[id]
+	return ok
+}
+
+func (eo *externalOrder) GetMainChainTip() meerdag.IBlock {
+	return eo.tip
+}
+
+func (eo *externalOrder) GetMainChainTipId() uint {
+	if eo.tip == nil {
+		return meerdag.MaxId
+	}
+	return eo.tip.GetID()
+}
+
+func (eo *externalOrder) GetMainParent(parents *meerdag.IdSet) meerdag.IBlock {
+	var mp meerdag.IBlock
+	for _, id := range parents.List() {
+		if mp == nil || id > mp.GetID() {
+			mp = eo.view.BlockById(id)
+		}
+	}
+	return mp
+}
+
+func (eo *externalOrder) Encode(w io.Writer) error {
+	return nil
+}
+
+func (eo *externalOrder) Decode(r io.Reader) error {
+	return nil
+}
+
+func (eo *externalOrder) Load() error {
+	return nil
+}
+
+func (eo *externalOrder) IsDAG(parents []meerdag.IBlock) bool {
+	return true
+}
+
+func (eo *externalOrder) GetMainParentConcurrency(b meerdag.IBlock) int {
+	return 0
+}
+
+func (eo *externalOrder) GetBlues(parents *meerdag.IdSet) uint {
+	return 0
+}
+
+func (eo *externalOrder) IsBlue(id uint) bool {
+	return true
+}
+
+func (eo *externalOrder) GetMaxParents() int {
+	return types.MaxParentsPerBlock
+}
+
+func init() {
+	meerdag.MustRegisterConsensusAlgorithm(externalOrderName, 100, func() meerdag.ConsensusAlgorithm {
+		return &externalOrder{}
+	})
+}
+
+// The result of one graph that must be reproducible
+type conformanceResult struct {
+	mainTip  string
+	orders   map[string]uint
+	sequence []string
+}
+
+// The algorithms that can't build any graph, because they don't choose a main
+// parent for the blocks.
+var unbuildableAlgorithms = map[string]struct{}{
+	meerdag.CONFLUX: {},
+	meerdag.SPECTRE: {},
+}
+
+// The expected orders of the graphs that have no order in testData.json
+var conformanceOrders = map[string]map[string][]string{
+	meerdag.PHANTOM: {
+		"CO_Blocks": {"Gen", "A", "B", "C", "F", "J", "I", "D", "E", "G", "H", "K"},
+		"SP_Blocks": {"Gen", "b3", "b1", "b2", "b4", "b5", "b8", "b7", "b11", "b6", "b9", "b13", "b10", "b12"},
+		"CP_Blocks": {"A", "D", "C", "G", "E", "B", "J", "F", "I", "H", "K"},
+	},
+	meerdag.GHOSTDAG: {
+		"CO_Blocks": {"Gen", "A", "B", "C", "D", "F", "E", "G", "J", "I", "H", "K"},
+		"SP_Blocks": {"Gen", "b3", "b2", "b1", "b4", "b5", "b8", "b7", "b11", "b6", "b9", "b13", "b10", "b12"},
+		"CP_Blocks": {"A", "D", "C", "G", "E", "B", "J", "F", "I", "H", "K"},
+	},
+}
+
+func expectedOrder(dagType string, graph string) []string {
+	if dagType == externalOrderName {
+		// Ordered by arrival
+		tags := make([]string, 0, len(tbMap))
+		for tag := range tbMap {
+			tags = append(tags, tag)
+		}
+		sort.Slice(tags, func(i, j int) bool {
+			return tbMap[tags[i]].GetID() < tbMap[tags[j]].GetID()
+		})
+		return tags
+	}
+	figOrders := map[string]map[string]TestInOutData{
+		meerdag.PHANTOM: {
+			"PH_fig1-blocks": testData.PH_OrderFig1,
+			"PH_fig2-blocks": testData.PH_OrderFig2,
+			"PH_fig4-blocks": testData.PH_OrderFig4,
+		},
+		meerdag.GHOSTDAG: {
+			"PH_fig1-blocks": testData.GD_OrderFig1,
+			"PH_fig2-blocks": testData.GD_OrderFig2,
+			"PH_fig4-blocks": testData.GD_OrderFig4,
+		},
+	}
+	if data, ok := figOrders[dagType][graph]; ok {
+		return data.Output
+	}
+	return conformanceOrders[dagType][graph]
+}
+
+func Test_RegisterConsensusAlgorithm(t *testing.T) {
+	names := meerdag.RegisteredConsensusAlgorithms()
+	expect := []string{meerdag.PHANTOM, meerdag.CONFLUX, meerdag.SPECTRE, meerdag.GHOSTDAG, externalOrderName}
+	if fmt.Sprint(names) != fmt.Sprint(expect) {
+		t.Fatalf("registered algorithms %v, expected %v", names, expect)
+	}
+	index, err := meerdag.GetDAGTypeIndex(externalOrderName)
+	if err != nil || index != 100 || meerdag.GetDAGTypeByIndex(100) != externalOrderName {
+		t.Fatal("the DAG type index of the external algorithm is wrong")
+	}
+	if _, err := meerdag.GetDAGTypeIndex("unknown"); err == nil {
+		t.Fatal("expected error for the index of unknown DAG type")
+	}
+	if meerdag.NewBlockDAG("unknown") != nil || meerdag.IsRegisteredConsensusAlgorithm("unknown") {
+		t.Fatal("the unknown DAG type is registered")
+	}
+
+	creator := func() meerdag.ConsensusAlgorithm { return &externalOrder{} }
+	tests := []struct {
+		name    string
+		index   byte
+		creator meerdag.ConsensusAlgorithmCreator
+	}{
+		{"", 101, creator},
+		{externalOrderName, 101, nil},
+		{externalOrderName, 101, creator},
+		{meerdag.PHANTOM, 101, func() meerdag.ConsensusAlgorithm { return &meerdag.Phantom{} }},
+		{"mismatch", 101, creator},
+		{externalOrderName, 0, creator},
+	}
+	for _, test := range tests {
+		if err := meerdag.RegisterConsensusAlgorithm(test.name, test.index, test.creator); err == nil {
+			t.Fatalf("expected error when registering (%s, %d)", test.name, test.index)
+		}
+	}
+}
+
+func Test_ConsensusAlgorithmConformance(t *testing.T) {
+	for _, dagType := range meerdag.RegisteredConsensusAlgorithms() {
+		for _, graph := range conformanceGraphs {
+			dagType, graph := dagType, graph
+			t.Run(dagType+"/"+graph, func(t *testing.T) {
+				if _, ok := unbuildableAlgorithms[dagType]; ok {
+					if InitBlockDAG(dagType, graph) != nil {
+						t.Fatalf("%s is expected to fail building the graph %s", dagType, graph)
+					}
+					return
+				}
+				first := checkConformance(t, dagType, graph)
+				second := checkConformance(t, dagType, graph)
+				if first.mainTip != second.mainTip {
+					t.Fatalf("main chain tip isn't deterministic: %s != %s", first.mainTip, second.mainTip)
+				}
+				if fmt.Sprint(first.orders) != fmt.Sprint(second.orders) {
+					t.Fatalf("orders aren't deterministic: %v != %v", first.orders, second.orders)
+				}
+				expect := expectedOrder(dagType, graph)
+				if expect == nil {
+					t.Fatalf("no expected order of %s for the graph %s", dagType, graph)
+				}
+				if fmt.Sprint(first.sequence) != fmt.Sprint(expect) {
+					t.Fatalf("order %v, expected %v", first.sequence, expect)
+				}
+			})
+		}
+	}
+}
+
+func checkConformance(t *testing.T, dagType string, graph string) *conformanceResult {
+	instance := InitBlockDAG(dagType, graph)
+	if instance == nil {
+		t.Fatalf("%s can't build the graph %s", dagType, graph)
+	}
+	if instance.GetName() != dagType {
+		t.Fatalf("the instance name is %s", instance.GetName())
+	}
+	if bd.GetBlockTotal() != uint(len(tbMap)) {
+		t.Fatalf("block total %d, expected %d", bd.GetBlockTotal(), len(tbMap))
+	}
+	// The tips are ordered on demand
+	switch ca := instance.(type) {
+	case *meerdag.Phantom:
+		ca.UpdateVirtualBlockOrder()
+	case *meerdag.GhostDAG:
+		if err := ca.UpdateOrders(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mainTip := bd.GetMainChainTip()
+	if mainTip == nil {
+		t.Fatalf("%s has no main chain tip", dagType)
+	}
+	result := &conformanceResult{mainTip: getBlockTag(mainTip.GetID()), orders: map[string]uint{}}
+
+	// The main chain goes from the tip back to genesis
+	genesis := bd.GetBlockById(meerdag.GenesisId)
+	if genesis == nil || !bd.IsOnMainChain(genesis.GetID()) {
+		t.Fatal("genesis isn't on the main chain")
+	}
+	cur := mainTip
+	for cur.GetID() != genesis.GetID() {
+		if !bd.IsOnMainChain(cur.GetID()) {
+			t.Fatalf("%s isn't on the main chain", getBlockTag(cur.GetID()))
+		}
+		if cur.GetMainParent() == meerdag.MaxId {
+			t.Fatalf("%s has no main parent", getBlockTag(cur.GetID()))
+		}
+		cur = tbMap[getBlockTag(cur.GetMainParent())]
+	}
+
+	orders := map[uint]string{}
+	for tag, block := range tbMap {
+		if block.HasParents() {
+			// The main parent is chosen by the algorithm
+			mp := bd.GetMainParent(block.GetParents())
+			if mp == nil || mp.GetID() != block.GetMainParent() {
+				t.Fatalf("main parent of %s isn't reproducible", tag)
+			}
+		}
+		if !block.IsOrdered() {
+			continue
+		}
+		if exist, ok := orders[block.GetOrder()]; ok {
+			t.Fatalf("%s and %s have the same order %d", tag, exist, block.GetOrder())
+		}
+		orders[block.GetOrder()] = tag
+		result.orders[tag] = block.GetOrder()
+		if !block.HasParents() {
+			continue
+		}
+		for _, pid := range block.GetParents().List() {
+			parent := tbMap[getBlockTag(pid)]
+			if !parent.IsOrdered() || parent.GetOrder() >= block.GetOrder() {
+				t.Fatalf("parent %s isn't ordered before %s", getBlockTag(pid), tag)
+			}
+		}
+	}
+	for i := uint(0); i < uint(len(orders)); i++ {
+		tag, ok := orders[i]
+		if !ok {
+			t.Fatalf("the order %d is missing", i)
+		}
+		result.sequence = append(result.sequence, tag)
+	}
+	return result
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/Qitmeer/qng/common/hash"
 	"github.com/Qitmeer/qng/common/system"
+	"github.com/Qitmeer/qng/consensus/model"
 	"github.com/Qitmeer/qng/database"
 	"github.com/Qitmeer/qng/meerdag"
 	"github.com/Qitmeer/qng/services/common"
@@ -584,3 +585,93 @@ func Test_LastBlock(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func Test_InvalidateMainChain(t *testing.T) {
+	ibd := InitBlockDAG(meerdag.PHANTOM, "PH_fig2-blocks")
+	if ibd == nil {
+		t.FailNow()
+	}
+	orders := map[uint]uint{}
+	for _, ib := range tbMap {
+		orders[ib.GetID()] = ib.GetOrder()
+	}
+	oldTip := bd.GetMainChainTip()
+	target := bd.GetBlockById(oldTip.GetMainParent())
+	if target.GetID() == meerdag.GenesisId {
+		target = oldTip
+	}
+
+	// invalidate
+	fs := meerdag.NewIdSet()
+	bd.GetFutureSet(fs, target)
+	fs.AddPair(target.GetID(), target)
+	for _, id := range fs.SortList(false) {
+		bd.SetBlockStatusFlags(bd.GetBlockById(id), model.StatusInvalidated)
+	}
+	_, _, err := bd.UpdateMainChain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = bd.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mainTip := bd.GetMainChainTip()
+	if fs.Has(mainTip.GetID()) {
+		t.Fatalf("The main chain tip %s is invalidated", getBlockTag(mainTip.GetID()))
+	}
+	for id := range fs.GetMap() {
+		if bd.GetBlockById(id).IsOrdered() {
+			t.Fatalf("The invalidated block %s is ordered", getBlockTag(id))
+		}
+	}
+	for i := uint(0); i <= mainTip.GetOrder(); i++ {
+		ib := bd.GetBlockByOrder(i)
+		if ib == nil || ib.GetOrder() != i {
+			t.Fatalf("The order %d is inconsistent", i)
+		}
+		if fs.Has(ib.GetID()) {
+			t.Fatalf("The invalidated block %s has order %d", getBlockTag(ib.GetID()), i)
+		}
+	}
+
+	// The descendant is invalidated with its parent
+	_, err = buildBlock("L", []*hash.Hash{oldTip.GetHash()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	child := tbMap["L"]
+	if !child.GetState().GetStatus().IsInvalidated() || child.IsOrdered() {
+		t.Fatalf("The descendant of invalidated block should be invalidated")
+	}
+	if bd.GetMainChainTip().GetID() != mainTip.GetID() {
+		t.Fatalf("The invalidated block changed the main chain tip")
+	}
+	for _, tip := range bd.GetValidTips(meerdag.MaxPriority) {
+		if bd.GetBlock(tip).GetState().GetStatus().IsInvalidated() {
+			t.Fatalf("The invalidated tip %s is a valid tip", getBlockTag(bd.GetBlock(tip).GetID()))
+		}
+	}
+
+	// reconsider
+	fs.AddPair(child.GetID(), child)
+	for _, id := range fs.SortList(false) {
+		bd.UnsetBlockStatusFlags(bd.GetBlockById(id), model.StatusInvalidated)
+	}
+	_, _, err = bd.UpdateMainChain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = bd.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bd.GetMainChainTip().GetID() != child.GetID() {
+		t.Fatalf("The main chain tip is %s, expect L", getBlockTag(bd.GetMainChainTip().GetID()))
+	}
+	for id, order := range orders {
+		if bd.GetBlockById(id).GetOrder() != order {
+			t.Fatalf("The order of %s is %d, expect %d", getBlockTag(id), bd.GetBlockById(id).GetOrder(), order)
+		}
+	}
+}
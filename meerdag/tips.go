@@ -72,7 +72,7 @@ func (bd *MeerDAG) GetValidTips(expectPriority int) []*hash.Hash {
 }
 
 func (bd *MeerDAG) getValidTips(limit bool) []IBlock {
-	temp := bd.tips.Clone()
+	temp := bd.getCandidateTips().Clone()
 	mainParent := bd.getMainChainTip()
 	temp.Remove(mainParent.GetID())
 	var parents []uint
@@ -106,6 +106,62 @@ func (bd *MeerDAG) getValidTips(limit bool) []IBlock {
 	return tips
 }
 
+// Whether the block has been invalidated by the operator.
+func isInvalidated(ib IBlock) bool {
+	return ib.GetState() != nil && ib.GetState().GetStatus().IsInvalidated()
+}
+
+// Acquire the tips which can be the main chain tip or the parents of new block.
+// The invalidated tips are replaced by their closest ancestors that are valid and
+// have no valid children.
+func (bd *MeerDAG) getCandidateTips() *IdSet {
+	result := NewIdSet()
+	queue := []IBlock{}
+	for k, v := range bd.tips.GetMap() {
+		ib := v.(IBlock)
+		if isInvalidated(ib) {
+			queue = append(queue, ib)
+		} else {
+			result.AddPair(k, ib)
+		}
+	}
+	if len(queue) <= 0 {
+		return bd.tips
+	}
+	visited := NewIdSet()
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for k, v := range bd.getParents(cur).GetMap() {
+			if visited.Has(k) {
+				continue
+			}
+			visited.Add(k)
+			parent := v.(IBlock)
+			if isInvalidated(parent) {
+				queue = append(queue, parent)
+				continue
+			}
+			if !bd.hasValidChildren(parent) {
+				result.AddPair(k, parent)
+			}
+		}
+	}
+	return result
+}
+
+func (bd *MeerDAG) hasValidChildren(ib IBlock) bool {
+	if !ib.HasChildren() {
+		return false
+	}
+	for _, v := range bd.getChildren(ib).GetMap() {
+		if !isInvalidated(v.(IBlock)) {
+			return true
+		}
+	}
+	return false
+}
+
 // build merkle tree form current DAG tips
 func (bd *MeerDAG) BuildMerkleTreeStoreFromTips() []*hash.Hash {
 	parents := bd.GetTips().SortList(false)
@@ -243,6 +299,10 @@ func (bd *MeerDAG) CanPrune(block IBlock, mainTip IBlock) bool {
 	if block.IsOrdered() {
 		return false
 	}
+	// Keep the invalidated blocks, so they can be reconsidered.
+	if isInvalidated(block) {
+		return false
+	}
 	gap := int64(mainTip.GetHeight()) - int64(block.GetHeight())
 	if gap <= bd.tipsDisLimit {
 		return false
@@ -678,6 +678,22 @@ func (m *MeerPool) GetTxs() ([]*qtypes.Tx, []*hash.Hash, error) {
 	return result, mtxhs, nil
 }
 
+// GetRemoteTxs returns the remote transactions which are still in the pool,
+// the ones which aren't in the snapshot are included.
+func (m *MeerPool) GetRemoteTxs() []*qtypes.Tx {
+	m.remoteMu.RLock()
+	defer m.remoteMu.RUnlock()
+
+	result := make([]*qtypes.Tx, 0, len(m.remoteQTxsM))
+	for _, stx := range m.remoteQTxsM {
+		if stx == nil || !m.eth.TxPool().Has(stx.eHash) {
+			continue
+		}
+		result = append(result, stx.tx)
+	}
+	return result
+}
+
 // all: contain txs in pending and queue
 func (m *MeerPool) HasTx(h *hash.Hash, all bool) bool {
 	m.snapshotMu.RLock()
@@ -0,0 +1,162 @@
+// Copyright (c) 2017-2019 The Qitmeer developers
+//
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	emetrics "github.com/ethereum/go-ethereum/metrics"
+)
+
+// quantiles are the quantiles exported for the histograms and the timers.
+var quantiles = []float64{0.5, 0.75, 0.95, 0.99, 0.999, 0.9999}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// MetricName converts a name of the metrics registry to a valid Prometheus
+// metric name, for example "meerdag/tips/total" becomes "meerdag_tips_total".
+func MetricName(name string) string {
+	var b strings.Builder
+	for i, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_', c == ':':
+			b.WriteRune(c)
+		case c >= '0' && c <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(c)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// promWriter writes the samples of the Prometheus text exposition format.
+type promWriter struct {
+	w *bufio.Writer
+	// labels is the formatted constant labels, without braces.
+	labels string
+}
+
+func newPromWriter(w io.Writer, labels map[string]string) *promWriter {
+	return &promWriter{w: bufio.NewWriter(w), labels: formatLabels(labels)}
+}
+
+// formatLabels returns the labels sorted by name as name="value" pairs.
+func formatLabels(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, MetricName(name)+`="`+labelValueReplacer.Replace(labels[name])+`"`)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (p *promWriter) writeType(name string, kind string) {
+	p.w.WriteString("# TYPE " + name + " " + kind + "\n")
+}
+
+// writeSample writes one sample with the constant labels and the extra ones.
+func (p *promWriter) writeSample(name string, extra string, value string) {
+	p.w.WriteString(name)
+	labels := p.labels
+	if len(extra) > 0 {
+		if len(labels) > 0 {
+			labels = extra + "," + labels
+		} else {
+			labels = extra
+		}
+	}
+	if len(labels) > 0 {
+		p.w.WriteString("{" + labels + "}")
+	}
+	p.w.WriteString(" " + value + "\n")
+}
+
+func (p *promWriter) writeValue(name string, kind string, value string) {
+	p.writeType(name, kind)
+	p.writeSample(name, "", value)
+}
+
+func (p *promWriter) writeSummary(name string, count int64, sum float64, values []float64) {
+	p.writeType(name, "summary")
+	for i, q := range quantiles {
+		p.writeSample(name, `quantile="`+strconv.FormatFloat(q, 'f', -1, 64)+`"`, formatFloat(values[i]))
+	}
+	p.writeSample(name+"_sum", "", formatFloat(sum))
+	p.writeSample(name+"_count", "", strconv.FormatInt(count, 10))
+}
+
+// add writes the metric, it returns false if the type of the metric is not
+// supported.
+func (p *promWriter) add(name string, i interface{}) bool {
+	name = MetricName(name)
+	switch m := i.(type) {
+	case emetrics.Counter:
+		p.writeValue(name, "counter", strconv.FormatInt(m.Snapshot().Count(), 10))
+	case emetrics.CounterFloat64:
+		p.writeValue(name, "counter", formatFloat(m.Snapshot().Count()))
+	case emetrics.Gauge:
+		p.writeValue(name, "gauge", strconv.FormatInt(m.Snapshot().Value(), 10))
+	case emetrics.GaugeFloat64:
+		p.writeValue(name, "gauge", formatFloat(m.Snapshot().Value()))
+	case emetrics.GaugeInfo:
+		p.writeType(name, "gauge")
+		p.writeSample(name, formatLabels(m.Snapshot().Value()), "1")
+	case emetrics.Meter:
+		p.writeValue(name, "counter", strconv.FormatInt(m.Snapshot().Count(), 10))
+	case emetrics.Histogram:
+		s := m.Snapshot()
+		p.writeSummary(name, s.Count(), float64(s.Sum()), s.Percentiles(quantiles))
+	case emetrics.Timer:
+		s := m.Snapshot()
+		p.writeSummary(name, s.Count(), float64(s.Sum()), s.Percentiles(quantiles))
+	case emetrics.ResettingTimer:
+		s := m.Snapshot()
+		if s.Count() <= 0 {
+			return true
+		}
+		p.writeSummary(name, int64(s.Count()), s.Mean()*float64(s.Count()), s.Percentiles(quantiles))
+	default:
+		return false
+	}
+	return true
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// WritePrometheus writes all the metrics of the registry in the Prometheus
+// text exposition format, the labels are added to every sample.  The metrics
+// of unknown types are skipped.
+func WritePrometheus(w io.Writer, reg emetrics.Registry, labels map[string]string) error {
+	var names []string
+	reg.Each(func(name string, i interface{}) {
+		names = append(names, name)
+	})
+	sort.Strings(names)
+
+	p := newPromWriter(w, labels)
+	for _, name := range names {
+		i := reg.Get(name)
+		if i == nil {
+			continue
+		}
+		p.add(name, i)
+	}
+	return p.w.Flush()
+}
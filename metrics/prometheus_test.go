@@ -0,0 +1,64 @@
+// Copyright (c) 2017-2019 The Qitmeer developers
+//
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	emetrics "github.com/ethereum/go-ethereum/metrics"
+)
+
+func TestMetricName(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"meerdag/tips/total", "meerdag_tips_total"},
+		{"p2p/serves/qng", "p2p_serves_qng"},
+		{"chain/head.block-time", "chain_head_block_time"},
+		{"1m/rate", "_1m_rate"},
+	}
+	for _, test := range tests {
+		if got := MetricName(test.in); got != test.want {
+			t.Errorf("MetricName(%q): got %q want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestWritePrometheus(t *testing.T) {
+	enabled := emetrics.Enabled
+	emetrics.Enabled = true
+	defer func() { emetrics.Enabled = enabled }()
+
+	reg := emetrics.NewRegistry()
+	emetrics.NewRegisteredGauge("meerdag/mainheight", reg).Update(42)
+	emetrics.NewRegisteredCounter("p2p/dials", reg).Inc(3)
+	emetrics.NewRegisteredTimer("mempool/accept", reg).Update(2)
+
+	buf := &bytes.Buffer{}
+	labels := map[string]string{"network": "privnet", "node": `16U"x`}
+	if err := WritePrometheus(buf, reg, labels); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	want := []string{
+		"# TYPE meerdag_mainheight gauge\n",
+		"meerdag_mainheight{network=\"privnet\",node=\"16U\\\"x\"} 42\n",
+		"# TYPE p2p_dials counter\n",
+		"p2p_dials{network=\"privnet\",node=\"16U\\\"x\"} 3\n",
+		"# TYPE mempool_accept summary\n",
+		"mempool_accept{quantile=\"0.5\",network=\"privnet\",node=\"16U\\\"x\"} 2\n",
+		"mempool_accept_sum{network=\"privnet\",node=\"16U\\\"x\"} 2\n",
+		"mempool_accept_count{network=\"privnet\",node=\"16U\\\"x\"} 1\n",
+	}
+	for _, w := range want {
+		if !strings.Contains(out, w) {
+			t.Errorf("missing %q in:\n%s", w, out)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2017-2019 The Qitmeer developers
+//
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Qitmeer/qng/config"
+	"github.com/Qitmeer/qng/log"
+	"github.com/Qitmeer/qng/node/service"
+	emetrics "github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// NodeInfoName is the name of the metric which carries the node labels.
+	NodeInfoName = "qng_node_info"
+
+	// serverTimeout is the read and write timeout of the metrics requests.
+	serverTimeout = 10 * time.Second
+)
+
+// Server serves the metrics registry in the Prometheus text exposition format
+// at "/metrics".
+type Server struct {
+	service.Service
+
+	addr string
+	reg  emetrics.Registry
+
+	lock   sync.RWMutex
+	labels map[string]string
+	server *http.Server
+}
+
+// NewServer returns the metrics server of the configuration, it returns nil
+// when the metrics collection or the HTTP server is not enabled.
+func NewServer(cfg *config.Config) *Server {
+	if !cfg.Metrics || len(cfg.MetricsAddr) <= 0 {
+		return nil
+	}
+	return &Server{
+		addr:   net.JoinHostPort(cfg.MetricsAddr, strconv.Itoa(cfg.MetricsPort)),
+		reg:    emetrics.DefaultRegistry,
+		labels: map[string]string{},
+	}
+}
+
+// SetLabel sets a label which is added to all the exported samples.
+func (s *Server) SetLabel(name string, value string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.labels[name] = value
+}
+
+// Labels returns a copy of the labels added to all the exported samples.
+func (s *Server) Labels() map[string]string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	labels := make(map[string]string, len(s.labels))
+	for k, v := range s.labels {
+		labels[k] = v
+	}
+	return labels
+}
+
+func (s *Server) Start() error {
+	if err := s.Service.Start(); err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", s)
+	s.server = &http.Server{
+		Handler:      mux,
+		ReadTimeout:  serverTimeout,
+		WriteTimeout: serverTimeout,
+	}
+	log.Info(fmt.Sprintf("Metrics server listening on %s", listener.Addr()))
+	go func() {
+		err := s.server.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			log.Error(fmt.Sprintf("Metrics server:%v", err))
+		}
+	}()
+	return nil
+}
+
+func (s *Server) Stop() error {
+	if err := s.Service.Stop(); err != nil {
+		return err
+	}
+	if s.server != nil {
+		return s.server.Close()
+	}
+	return nil
+}
+
+// ServeHTTP writes the node info and the metrics of the registry.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	labels := s.Labels()
+	buf := &bytes.Buffer{}
+	p := newPromWriter(buf, labels)
+	p.writeValue(NodeInfoName, "gauge", "1")
+	if err := p.w.Flush(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := WritePrometheus(buf, s.reg, labels); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.Write(buf.Bytes())
+}
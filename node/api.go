@@ -109,6 +109,19 @@ func (api *PublicBlockChainAPI) GetNodeInfo() (interface{}, error) {
 		ret.ConsensusDeployment["amana"] = &json.ConsensusDeploymentDesc{Status: "inactive"}
 	}
 
+	// version bits deployments
+	for id, deployment := range api.node.node.Params.Deployments {
+		state, err := api.node.GetBlockChain().ThresholdState(uint32(id))
+		if err != nil {
+			return nil, err
+		}
+		cdd := json.ConsensusDeploymentDesc{Status: state.String()}
+		if deployment.StartHeight != params.DeploymentNever {
+			cdd.StartHeight = int64(deployment.StartHeight)
+		}
+		ret.ConsensusDeployment[deployment.Name] = &cdd
+	}
+
 	return ret, nil
 }
 
@@ -17,6 +17,7 @@ import (
 	"github.com/Qitmeer/qng/engine/txscript"
 	"github.com/Qitmeer/qng/meerevm/amana"
 	"github.com/Qitmeer/qng/meerevm/meer"
+	"github.com/Qitmeer/qng/metrics"
 	"github.com/Qitmeer/qng/node/service"
 	"github.com/Qitmeer/qng/p2p"
 	"github.com/Qitmeer/qng/params"
@@ -24,6 +25,9 @@ import (
 	"github.com/Qitmeer/qng/rpc/api"
 	"github.com/Qitmeer/qng/services/acct"
 	"github.com/Qitmeer/qng/services/address"
+	"github.com/Qitmeer/qng/services/cf"
+	"github.com/Qitmeer/qng/services/eventbus"
+	"github.com/Qitmeer/qng/services/explorer"
 	"github.com/Qitmeer/qng/services/mempool"
 	"github.com/Qitmeer/qng/services/miner"
 	"github.com/Qitmeer/qng/services/mining"
@@ -61,6 +65,17 @@ func (qm *QitmeerFull) RegisterP2PService() error {
 	return qm.Services().RegisterService(peerServer)
 }
 
+func (qm *QitmeerFull) RegisterMetricsServer() error {
+	ser := metrics.NewServer(qm.node.Config)
+	if ser == nil {
+		return nil
+	}
+	ser.SetLabel("network", qm.node.Params.Name)
+	ser.SetLabel("dag", qm.node.Config.DAGType)
+	ser.SetLabel("node", qm.GetPeerServer().PeerID().String())
+	return qm.Services().RegisterService(ser)
+}
+
 func (qm *QitmeerFull) RegisterRpcService() ([]api.API, error) {
 	if qm.node.Config.DisableRPC {
 		return nil, nil
@@ -88,7 +103,7 @@ func (qm *QitmeerFull) RegisterRpcService() ([]api.API, error) {
 	// Register all the APIs exposed by the services
 	for _, api := range apis {
 		if whitelist[api.NameSpace] || (len(whitelist) == 0 && api.Public) {
-			if err := rpcServer.RegisterService(api.NameSpace, api.Service); err != nil {
+			if err := rpcServer.RegisterAPI(api); err != nil {
 				return nil, err
 			}
 			log.Debug(fmt.Sprintf("RPC Service API registered. NameSpace:%s     %s", api.NameSpace, reflect.TypeOf(api.Service)))
@@ -159,6 +174,31 @@ func (qm *QitmeerFull) RegisterWalletService(cfg *config.Config) error {
 	return nil
 }
 
+func (qm *QitmeerFull) RegisterCFService() error {
+	if !qm.node.Config.CFIndex {
+		return nil
+	}
+	return qm.Services().RegisterService(cf.New(qm.node.consensus))
+}
+
+func (qm *QitmeerFull) RegisterExplorerService() error {
+	if !qm.node.Config.ExplorerIndex {
+		return nil
+	}
+	return qm.Services().RegisterService(explorer.New(qm.node.consensus))
+}
+
+func (qm *QitmeerFull) RegisterEventBus(cfg *config.Config) error {
+	eb, err := eventbus.New(cfg, qm.node.consensus)
+	if err != nil {
+		return err
+	}
+	if !eb.IsEnable() {
+		return nil
+	}
+	return qm.Services().RegisterService(eb)
+}
+
 func (qm *QitmeerFull) RegisterAmana() error {
 	if !qm.node.Config.Amana ||
 		params.ActiveNetParams.Net == protocol.MainNet {
@@ -293,6 +333,9 @@ func newQitmeerFullNode(node *Node) (*QitmeerFull, error) {
 	if err := qm.RegisterP2PService(); err != nil {
 		return nil, err
 	}
+	if err := qm.RegisterMetricsServer(); err != nil {
+		return nil, err
+	}
 	if err := qm.RegisterNotifyMgr(); err != nil {
 		return nil, err
 	}
@@ -327,6 +370,15 @@ func newQitmeerFullNode(node *Node) (*QitmeerFull, error) {
 	if err := qm.RegisterWalletService(cfg); err != nil {
 		return nil, err
 	}
+	if err := qm.RegisterCFService(); err != nil {
+		return nil, err
+	}
+	if err := qm.RegisterExplorerService(); err != nil {
+		return nil, err
+	}
+	if err := qm.RegisterEventBus(cfg); err != nil {
+		return nil, err
+	}
 
 	apis, err := qm.RegisterRpcService()
 	if err != nil {
@@ -2,23 +2,100 @@
 package node
 
 import (
+	"fmt"
+	"reflect"
+
+	"github.com/Qitmeer/qng/common/system"
 	"github.com/Qitmeer/qng/config"
 	"github.com/Qitmeer/qng/consensus/model"
 	"github.com/Qitmeer/qng/node/service"
+	"github.com/Qitmeer/qng/rpc"
+	"github.com/Qitmeer/qng/rpc/api"
+	"github.com/Qitmeer/qng/services/light"
 )
 
 // QitmeerLight implements the qitmeer light node service.
 type QitmeerLight struct {
 	service.Service
+	// under node
+	node *Node
 	// database
 	db     model.DataBase
 	config *config.Config
 }
 
+func (ql *QitmeerLight) RegisterLightService() error {
+	chain, err := light.NewHeaderChain(ql.db)
+	if err != nil {
+		return err
+	}
+	ls, err := light.New(ql.config, chain)
+	if err != nil {
+		return err
+	}
+	return ql.Services().RegisterService(ls)
+}
+
+func (ql *QitmeerLight) RegisterRpcService() error {
+	if ql.config.DisableRPC {
+		return nil
+	}
+	// The light node has no block chain, so the consensus is not passed to
+	// the rpc server.
+	rpcServer, err := rpc.NewRPCServer(ql.config, nil)
+	if err != nil {
+		return err
+	}
+	ql.Services().RegisterService(rpcServer)
+
+	go func() {
+		<-rpcServer.RequestedProcessShutdown()
+		system.ShutdownRequestChannel <- struct{}{}
+	}()
+
+	// Generate the whitelist based on the allowed modules
+	whitelist := make(map[string]bool)
+	for _, module := range ql.config.Modules {
+		whitelist[module] = true
+	}
+	for _, api := range ql.APIs() {
+		if whitelist[api.NameSpace] || (len(whitelist) == 0 && api.Public) {
+			if err := rpcServer.RegisterAPI(api); err != nil {
+				return err
+			}
+			log.Debug(fmt.Sprintf("RPC Service API registered. NameSpace:%s     %s", api.NameSpace, reflect.TypeOf(api.Service)))
+		}
+	}
+	return nil
+}
+
+func (ql *QitmeerLight) APIs() []api.API {
+	return ql.Service.APIs()
+}
+
+// GetLightSync returns the sync service of the light node.
+func (ql *QitmeerLight) GetLightSync() *light.Sync {
+	var service *light.Sync
+	if err := ql.Services().FetchService(&service); err != nil {
+		log.Error(err.Error())
+		return nil
+	}
+	return service
+}
+
 func newQitmeerLight(n *Node) (*QitmeerLight, error) {
-	light := QitmeerLight{
+	ql := QitmeerLight{
+		node:   n,
 		config: n.Config,
 		db:     n.DB,
 	}
-	return &light, nil
+	ql.Service.InitServices()
+
+	if err := ql.RegisterLightService(); err != nil {
+		return nil, err
+	}
+	if err := ql.RegisterRpcService(); err != nil {
+		return nil, err
+	}
+	return &ql, nil
 }
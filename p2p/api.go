@@ -74,6 +74,13 @@ func (api *PublicP2PAPI) GetPeerInfo(verbose *bool, pid *string) (interface{}, e
 			Bads:      p.Bads,
 			ReConnect: p.ReConnect,
 			Active:    active,
+			Score:     p.Score,
+		}
+		if len(p.Scores) > 0 {
+			info.Scores = p.Scores
+		}
+		if !p.BannedUntil.IsZero() {
+			info.BanUntil = p.BannedUntil.String()
 		}
 		info.Protocol = p.Protocol
 		info.Services = p.Services.String()
@@ -303,12 +310,7 @@ func (api *PrivateP2PAPI) SetLibp2pLogLevel(level string) (interface{}, error) {
 
 // Banlist
 func (api *PrivateP2PAPI) Banlist() (interface{}, error) {
-	bl := api.s.GetBanlist()
-	bls := []*json.GetBanlistResult{}
-	for k, v := range bl {
-		bls = append(bls, &json.GetBanlistResult{PeerID: k.String(), Bads: v})
-	}
-	return bls, nil
+	return api.s.GetBanlist(), nil
 }
 
 // RemoveBan
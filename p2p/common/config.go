@@ -5,6 +5,7 @@
 package common
 
 import (
+	"fmt"
 	"github.com/Qitmeer/qng/core/protocol"
 	"github.com/Qitmeer/qng/params"
 	"os"
@@ -49,4 +50,37 @@ type Config struct {
 	LANPeers       []string
 	IsCircuit      bool
 	Consistency    bool
+	// GossipMode is how blocks, transactions and graph state are relayed.
+	GossipMode string
+	// Dandelion relays the own transactions by stem and fluff phases.
+	Dandelion bool
+}
+
+// The modes of relaying. The stream mode announces the inventory to every
+// peer by the request streams, the gossip mode publishes to the gossipsub
+// topics, and both of them are used during the migration.
+const (
+	GossipModeStream = "stream"
+	GossipModeBoth   = "both"
+	GossipModeGossip = "gossip"
+)
+
+// CheckGossipMode returns an error if the mode isn't supported.
+func CheckGossipMode(mode string) error {
+	switch mode {
+	case GossipModeStream, GossipModeBoth, GossipModeGossip:
+		return nil
+	}
+	return fmt.Errorf("unknown gossip mode %s (%s|%s|%s)", mode, GossipModeStream, GossipModeBoth, GossipModeGossip)
+}
+
+// StreamRelay returns true if the inventory is relayed by the request streams.
+func (c *Config) StreamRelay() bool {
+	return c.GossipMode != GossipModeGossip
+}
+
+// GossipRelay returns true if the inventory is published to the gossipsub
+// topics.
+func (c *Config) GossipRelay() bool {
+	return c.GossipMode == GossipModeBoth || c.GossipMode == GossipModeGossip
 }
@@ -19,4 +19,5 @@ var (
 	EgressConnectMeter  = metrics.NewRegisteredMeter("p2p/dials/qng", nil)
 	EgressTrafficMeter  = metrics.NewRegisteredMeter(egressMeterName, nil)
 	ActivePeerGauge     = metrics.NewRegisteredGauge("p2p/peers/qng", nil)
+	SyncPeerLagGauge    = metrics.NewRegisteredGauge("p2p/sync/peerlag", nil)
 )
\ No newline at end of file
@@ -10,6 +10,7 @@ import (
 	pb "github.com/Qitmeer/qng/p2p/proto/v1"
 	"github.com/Qitmeer/qng/p2p/qnode"
 	"github.com/Qitmeer/qng/services/mempool"
+	"github.com/libp2p/go-libp2p-pubsub"
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/peer"
 )
@@ -39,6 +40,7 @@ type P2P interface {
 	Peers() *Status
 	IsRunning() bool
 	Consensus() model.Consensus
+	PubSub() *pubsub.PubSub
 }
 
 type P2PRPC interface {
@@ -61,6 +61,8 @@ type Peer struct {
 	reconnect uint64
 
 	mempoolreq time.Time
+
+	score peerScore
 }
 
 func (p *Peer) GetID() peer.ID {
@@ -109,6 +111,9 @@ func (p *Peer) IsBad() bool {
 }
 
 func (p *Peer) isBad() bool {
+	if p.score.isBanned(time.Now()) {
+		return true
+	}
 	l := len(p.badResponses)
 	if l <= 0 {
 		return false
@@ -142,6 +147,9 @@ func (p *Peer) IncrementBadResponses(err *common.Error) {
 	if len(p.badResponses) > MaxBadResponses {
 		p.badResponses = p.badResponses[1:]
 	}
+	if kind, ok := misbehaviorOfError(err); ok {
+		p.misbehave(kind, err.Code.String())
+	}
 }
 
 func (p *Peer) ResetBad() {
@@ -149,6 +157,7 @@ func (p *Peer) ResetBad() {
 	defer p.lock.Unlock()
 
 	p.badResponses = []*BadResponse{}
+	p.score = peerScore{}
 
 	log.Debug(fmt.Sprintf("Bad responses reset:%s", p.pid.String()))
 }
@@ -362,6 +371,13 @@ func (p *Peer) StatsSnapshot() (*StatsSnap, error) {
 		MempoolReqTime: p.mempoolreq,
 		Tasks:          len(p.rateTasks),
 		Broadcast:      len(p.broadcast),
+		Scores:         p.scoreBreakdown(),
+	}
+	for _, v := range ss.Scores {
+		ss.Score += v
+	}
+	if p.score.isBanned(time.Now()) {
+		ss.BannedUntil = p.score.bannedUntil
 	}
 	n := p.node()
 	if n != nil {
@@ -0,0 +1,281 @@
+package peers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/Qitmeer/qng/p2p/common"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// Misbehavior is the kind of misbehavior of peer.
+type Misbehavior int
+
+const (
+	MisbehaviorInvalidBlock Misbehavior = iota
+	MisbehaviorInvalidTx
+	MisbehaviorProtocol
+	MisbehaviorStall
+	MisbehaviorUnsolicited
+)
+
+var misbehaviorStrings = map[Misbehavior]string{
+	MisbehaviorInvalidBlock: "invalidblock",
+	MisbehaviorInvalidTx:    "invalidtx",
+	MisbehaviorProtocol:     "protocol",
+	MisbehaviorStall:        "stall",
+	MisbehaviorUnsolicited:  "unsolicited",
+}
+
+func (m Misbehavior) String() string {
+	if s, ok := misbehaviorStrings[m]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown Misbehavior (%d)", int(m))
+}
+
+// MisbehaviorWeights is the score added by every kind of misbehavior.
+var MisbehaviorWeights = map[Misbehavior]float64{
+	MisbehaviorInvalidBlock: 100,
+	MisbehaviorInvalidTx:    10,
+	MisbehaviorProtocol:     20,
+	MisbehaviorStall:        5,
+	MisbehaviorUnsolicited:  5,
+}
+
+var (
+	// BanThreshold is the score at which the peer is banned.
+	BanThreshold = float64(100)
+
+	// BanDuration is how long the peer is banned.
+	BanDuration = time.Hour * 24
+)
+
+const (
+	// ScoreHalfLife is the time in which the score decays by half.
+	ScoreHalfLife = time.Minute * 30
+
+	// minScore is the score which is regarded as zero after decay.
+	minScore = 0.01
+)
+
+// peerScore is the misbehavior score of peer.
+type peerScore struct {
+	scores      map[Misbehavior]float64
+	updated     time.Time
+	bannedUntil time.Time
+	banReason   string
+}
+
+// current returns the scores which are decayed to now.
+func (ps *peerScore) current(now time.Time) map[Misbehavior]float64 {
+	factor := float64(1)
+	if !ps.updated.IsZero() && now.After(ps.updated) {
+		factor = math.Pow(0.5, float64(now.Sub(ps.updated))/float64(ScoreHalfLife))
+	}
+	result := map[Misbehavior]float64{}
+	for k, v := range ps.scores {
+		v *= factor
+		if v >= minScore {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+func (ps *peerScore) decay(now time.Time) {
+	ps.scores = ps.current(now)
+	ps.updated = now
+}
+
+func (ps *peerScore) total(now time.Time) float64 {
+	result := float64(0)
+	for _, v := range ps.current(now) {
+		result += v
+	}
+	return result
+}
+
+func (ps *peerScore) isBanned(now time.Time) bool {
+	return now.Before(ps.bannedUntil)
+}
+
+// misbehaviorOfError returns the misbehavior of a bad response.
+func misbehaviorOfError(err *common.Error) (Misbehavior, bool) {
+	switch {
+	case err.Code.IsStream():
+		return MisbehaviorStall, true
+	case err.Code == common.ErrMessage || err.Code == common.ErrSequence:
+		return MisbehaviorProtocol, true
+	}
+	return 0, false
+}
+
+// Misbehave adds the score of misbehavior to the peer, and returns true if
+// the peer is banned by it.
+func (p *Peer) Misbehave(kind Misbehavior, reason string) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.misbehave(kind, reason)
+}
+
+func (p *Peer) misbehave(kind Misbehavior, reason string) bool {
+	now := time.Now()
+	p.score.decay(now)
+	p.score.scores[kind] += MisbehaviorWeights[kind]
+	total := p.score.total(now)
+	log.Debug("Peer misbehavior", "peer", p.idWithAddress(), "kind", kind.String(), "reason", reason, "score", total)
+	if p.score.isBanned(now) || total < BanThreshold {
+		return false
+	}
+	p.score.bannedUntil = now.Add(BanDuration)
+	p.score.banReason = fmt.Sprintf("%s:%s", kind.String(), reason)
+	log.Warn("Ban peer", "peer", p.idWithAddress(), "score", total, "until", p.score.bannedUntil.Format(time.RFC3339), "reason", p.score.banReason)
+	return true
+}
+
+// Score returns the current misbehavior score of peer.
+func (p *Peer) Score() float64 {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.score.total(time.Now())
+}
+
+// ScoreBreakdown returns the current misbehavior score of every kind.
+func (p *Peer) ScoreBreakdown() map[string]float64 {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.scoreBreakdown()
+}
+
+func (p *Peer) scoreBreakdown() map[string]float64 {
+	result := map[string]float64{}
+	for k, v := range p.score.current(time.Now()) {
+		result[k.String()] = v
+	}
+	return result
+}
+
+// GossipScore returns the application specific score of peer for the gossip
+// router. It's between -1 and 0, and reaches -1 when the misbehavior score of
+// peer reaches the ban threshold.
+func (p *Status) GossipScore(pid peer.ID) float64 {
+	pe := p.Get(pid)
+	if pe == nil {
+		return 0
+	}
+	if pe.IsBanned() {
+		return -1
+	}
+	return -math.Min(pe.Score()/BanThreshold, 1)
+}
+
+// Ban bans the peer until the time.
+func (p *Peer) Ban(until time.Time, reason string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.score.bannedUntil = until
+	p.score.banReason = reason
+}
+
+// IsBanned returns true if the peer is banned now.
+func (p *Peer) IsBanned() bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.score.isBanned(time.Now())
+}
+
+// BannedUntil returns the end time and the reason of ban.
+func (p *Peer) BannedUntil() (time.Time, string) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.score.bannedUntil, p.score.banReason
+}
+
+// BanStore persists the bans.
+type BanStore interface {
+	GetBanlist() ([]byte, error)
+	PutBanlist(data []byte) error
+}
+
+type banEntry struct {
+	PeerID string `json:"peerid"`
+	Until  int64  `json:"until"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// SetBanStore sets the store of bans, and restores the bans which aren't
+// expired.
+func (p *Status) SetBanStore(store BanStore) error {
+	p.banLock.Lock()
+	p.banStore = store
+	p.banLock.Unlock()
+
+	data, err := store.GetBanlist()
+	if err != nil || len(data) <= 0 {
+		return err
+	}
+	entries := []*banEntry{}
+	err = json.Unmarshal(data, &entries)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, entry := range entries {
+		until := time.Unix(entry.Until, 0)
+		if !now.Before(until) {
+			continue
+		}
+		pid, err := peer.Decode(entry.PeerID)
+		if err != nil {
+			log.Warn("Invalid banned peer", "peer", entry.PeerID, "error", err)
+			continue
+		}
+		p.Fetch(pid).Ban(until, entry.Reason)
+		log.Debug("Restore ban", "peer", entry.PeerID, "until", until.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// SaveBans persists the bans which aren't expired if they are changed.
+func (p *Status) SaveBans() error {
+	p.banLock.Lock()
+	defer p.banLock.Unlock()
+
+	if p.banStore == nil {
+		return nil
+	}
+	entries := []*banEntry{}
+	for _, pe := range p.AllPeers() {
+		if !pe.IsBanned() {
+			continue
+		}
+		until, reason := pe.BannedUntil()
+		entries = append(entries, &banEntry{PeerID: pe.GetID().String(), Until: until.Unix(), Reason: reason})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].PeerID < entries[j].PeerID
+	})
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	if string(data) == string(p.savedBans) {
+		return nil
+	}
+	err = p.banStore.PutBanlist(data)
+	if err != nil {
+		return err
+	}
+	p.savedBans = data
+	return nil
+}
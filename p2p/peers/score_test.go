@@ -0,0 +1,116 @@
+package peers
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+func testPeerID(t *testing.T) peer.ID {
+	_, pub, err := crypto.GenerateEd25519Key(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, err := peer.IDFromPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pid
+}
+
+type testBanStore struct {
+	data []byte
+}
+
+func (s *testBanStore) GetBanlist() ([]byte, error) {
+	return s.data, nil
+}
+
+func (s *testBanStore) PutBanlist(data []byte) error {
+	s.data = data
+	return nil
+}
+
+func TestPeerScore(t *testing.T) {
+	pe := NewPeer(testPeerID(t), nil)
+	if pe.Misbehave(MisbehaviorInvalidTx, "tx") || pe.IsBad() {
+		t.Fatal("the peer is banned by one invalid tx")
+	}
+	if math.Abs(pe.ScoreBreakdown()[MisbehaviorInvalidTx.String()]-MisbehaviorWeights[MisbehaviorInvalidTx]) > 0.01 {
+		t.Fatalf("unexpected score breakdown %v", pe.ScoreBreakdown())
+	}
+
+	// The score decays by half in a half life
+	pe.score.updated = pe.score.updated.Add(-ScoreHalfLife)
+	score := pe.Score()
+	if score < 4.99 || score > 5.01 {
+		t.Fatalf("the score %f doesn't decay", score)
+	}
+
+	if !pe.Misbehave(MisbehaviorInvalidBlock, "block") {
+		t.Fatal("the peer isn't banned by invalid block")
+	}
+	if !pe.IsBanned() || !pe.IsBad() {
+		t.Fatal("the peer isn't bad after ban")
+	}
+	if pe.Misbehave(MisbehaviorProtocol, "protocol") {
+		t.Fatal("the banned peer is banned again")
+	}
+	pe.ResetBad()
+	if pe.IsBanned() || pe.Score() != 0 {
+		t.Fatal("the ban isn't removed")
+	}
+}
+
+func TestBanStore(t *testing.T) {
+	store := &testBanStore{}
+	ps := NewStatus(nil)
+	if err := ps.SetBanStore(store); err != nil {
+		t.Fatal(err)
+	}
+	banned := testPeerID(t)
+	expired := testPeerID(t)
+	until := time.Now().Add(time.Hour).Truncate(time.Second)
+	ps.Fetch(banned).Ban(until, "test")
+	ps.Fetch(expired).Ban(time.Now().Add(-time.Hour), "test")
+	if err := ps.SaveBans(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewStatus(nil)
+	if err := restored.SetBanStore(store); err != nil {
+		t.Fatal(err)
+	}
+	pe := restored.Get(banned)
+	if pe == nil || !pe.IsBanned() {
+		t.Fatal("the ban isn't restored")
+	}
+	ru, reason := pe.BannedUntil()
+	if !ru.Equal(until) || reason != "test" {
+		t.Fatalf("unexpected ban %s %s", ru, reason)
+	}
+	if restored.Get(expired) != nil {
+		t.Fatal("the expired ban is restored")
+	}
+}
+
+func TestGossipScore(t *testing.T) {
+	ps := NewStatus(nil)
+	pid := testPeerID(t)
+	if ps.GossipScore(pid) != 0 {
+		t.Fatal("the unknown peer has gossip score")
+	}
+	pe := ps.Fetch(pid)
+	pe.Misbehave(MisbehaviorProtocol, "protocol")
+	score := ps.GossipScore(pid)
+	if math.Abs(score+MisbehaviorWeights[MisbehaviorProtocol]/BanThreshold) > 0.01 {
+		t.Fatalf("unexpected gossip score %f", score)
+	}
+	pe.Ban(time.Now().Add(time.Hour), "test")
+	if ps.GossipScore(pid) != -1 {
+		t.Fatal("the banned peer isn't graylisted")
+	}
+}
@@ -39,6 +39,9 @@ type StatsSnap struct {
 	MempoolReqTime time.Time
 	Tasks          int
 	Broadcast      int
+	Score          float64
+	Scores         map[string]float64
+	BannedUntil    time.Time
 }
 
 func (p *StatsSnap) IsRelay() bool {
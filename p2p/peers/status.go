@@ -22,6 +22,10 @@ type Status struct {
 	peers map[peer.ID]*Peer
 
 	p2p P2PRPC
+
+	banLock   sync.Mutex
+	banStore  BanStore
+	savedBans []byte
 }
 
 // Bad returns the peers that are bad.
@@ -19,7 +19,8 @@ func retrieveIndicesFromBitfield(bitV bitfield.Bitvector64) []uint64 {
 
 func HasConsensusService(services protocol.ServiceFlag) bool {
 	if protocol.HasServices(protocol.ServiceFlag(services), protocol.Full) ||
-		protocol.HasServices(protocol.ServiceFlag(services), protocol.Light) {
+		protocol.HasServices(protocol.ServiceFlag(services), protocol.Light) ||
+		protocol.HasServices(protocol.ServiceFlag(services), protocol.Pruned) {
 		return true
 	}
 	return false
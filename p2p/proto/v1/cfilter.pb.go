@@ -0,0 +1,1510 @@
+// Code generated by protoc-gen-gogo. DO NOT EDIT.
+// source: cfilter.proto
+
+package qitmeer_p2p_v1
+
+import (
+	fmt "fmt"
+	_ "github.com/gogo/protobuf/gogoproto"
+	proto "github.com/golang/protobuf/proto"
+	io "io"
+	math "math"
+	math_bits "math/bits"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type GetCFilters struct {
+	FilterType           uint32   `protobuf:"varint,1,opt,name=filterType,proto3" json:"filterType,omitempty"`
+	StartOrder           uint64   `protobuf:"varint,2,opt,name=startOrder,proto3" json:"startOrder,omitempty"`
+	StopHash             *Hash    `protobuf:"bytes,3,opt,name=stopHash,proto3" json:"stopHash,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetCFilters) Reset()         { *m = GetCFilters{} }
+func (m *GetCFilters) String() string { return proto.CompactTextString(m) }
+func (*GetCFilters) ProtoMessage()    {}
+func (*GetCFilters) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0dcac866be37bc1f, []int{0}
+}
+func (m *GetCFilters) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *GetCFilters) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_GetCFilters.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *GetCFilters) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetCFilters.Merge(m, src)
+}
+func (m *GetCFilters) XXX_Size() int {
+	return m.Size()
+}
+func (m *GetCFilters) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetCFilters.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetCFilters proto.InternalMessageInfo
+
+func (m *GetCFilters) GetFilterType() uint32 {
+	if m != nil {
+		return m.FilterType
+	}
+	return 0
+}
+
+func (m *GetCFilters) GetStartOrder() uint64 {
+	if m != nil {
+		return m.StartOrder
+	}
+	return 0
+}
+
+func (m *GetCFilters) GetStopHash() *Hash {
+	if m != nil {
+		return m.StopHash
+	}
+	return nil
+}
+
+type CFilter struct {
+	BlockHash            *Hash    `protobuf:"bytes,1,opt,name=blockHash,proto3" json:"blockHash,omitempty"`
+	Data                 []byte   `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty" ssz-max:"1048576"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CFilter) Reset()         { *m = CFilter{} }
+func (m *CFilter) String() string { return proto.CompactTextString(m) }
+func (*CFilter) ProtoMessage()    {}
+func (*CFilter) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0dcac866be37bc1f, []int{1}
+}
+func (m *CFilter) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *CFilter) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_CFilter.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *CFilter) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CFilter.Merge(m, src)
+}
+func (m *CFilter) XXX_Size() int {
+	return m.Size()
+}
+func (m *CFilter) XXX_DiscardUnknown() {
+	xxx_messageInfo_CFilter.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CFilter proto.InternalMessageInfo
+
+func (m *CFilter) GetBlockHash() *Hash {
+	if m != nil {
+		return m.BlockHash
+	}
+	return nil
+}
+
+func (m *CFilter) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+type CFilters struct {
+	FilterType           uint32     `protobuf:"varint,1,opt,name=filterType,proto3" json:"filterType,omitempty"`
+	Filters              []*CFilter `protobuf:"bytes,2,rep,name=filters,proto3" json:"filters,omitempty" ssz-max:"1000"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *CFilters) Reset()         { *m = CFilters{} }
+func (m *CFilters) String() string { return proto.CompactTextString(m) }
+func (*CFilters) ProtoMessage()    {}
+func (*CFilters) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0dcac866be37bc1f, []int{2}
+}
+func (m *CFilters) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *CFilters) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_CFilters.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *CFilters) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CFilters.Merge(m, src)
+}
+func (m *CFilters) XXX_Size() int {
+	return m.Size()
+}
+func (m *CFilters) XXX_DiscardUnknown() {
+	xxx_messageInfo_CFilters.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CFilters proto.InternalMessageInfo
+
+func (m *CFilters) GetFilterType() uint32 {
+	if m != nil {
+		return m.FilterType
+	}
+	return 0
+}
+
+func (m *CFilters) GetFilters() []*CFilter {
+	if m != nil {
+		return m.Filters
+	}
+	return nil
+}
+
+type GetCFHeaders struct {
+	FilterType           uint32   `protobuf:"varint,1,opt,name=filterType,proto3" json:"filterType,omitempty"`
+	StartOrder           uint64   `protobuf:"varint,2,opt,name=startOrder,proto3" json:"startOrder,omitempty"`
+	StopHash             *Hash    `protobuf:"bytes,3,opt,name=stopHash,proto3" json:"stopHash,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetCFHeaders) Reset()         { *m = GetCFHeaders{} }
+func (m *GetCFHeaders) String() string { return proto.CompactTextString(m) }
+func (*GetCFHeaders) ProtoMessage()    {}
+func (*GetCFHeaders) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0dcac866be37bc1f, []int{3}
+}
+func (m *GetCFHeaders) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *GetCFHeaders) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_GetCFHeaders.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *GetCFHeaders) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetCFHeaders.Merge(m, src)
+}
+func (m *GetCFHeaders) XXX_Size() int {
+	return m.Size()
+}
+func (m *GetCFHeaders) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetCFHeaders.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetCFHeaders proto.InternalMessageInfo
+
+func (m *GetCFHeaders) GetFilterType() uint32 {
+	if m != nil {
+		return m.FilterType
+	}
+	return 0
+}
+
+func (m *GetCFHeaders) GetStartOrder() uint64 {
+	if m != nil {
+		return m.StartOrder
+	}
+	return 0
+}
+
+func (m *GetCFHeaders) GetStopHash() *Hash {
+	if m != nil {
+		return m.StopHash
+	}
+	return nil
+}
+
+type CFHeaders struct {
+	FilterType           uint32   `protobuf:"varint,1,opt,name=filterType,proto3" json:"filterType,omitempty"`
+	StopHash             *Hash    `protobuf:"bytes,2,opt,name=stopHash,proto3" json:"stopHash,omitempty"`
+	PrevFilterHeader     *Hash    `protobuf:"bytes,3,opt,name=prevFilterHeader,proto3" json:"prevFilterHeader,omitempty"`
+	FilterHashes         []*Hash  `protobuf:"bytes,4,rep,name=filterHashes,proto3" json:"filterHashes,omitempty" ssz-max:"2000"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CFHeaders) Reset()         { *m = CFHeaders{} }
+func (m *CFHeaders) String() string { return proto.CompactTextString(m) }
+func (*CFHeaders) ProtoMessage()    {}
+func (*CFHeaders) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0dcac866be37bc1f, []int{4}
+}
+func (m *CFHeaders) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *CFHeaders) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_CFHeaders.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *CFHeaders) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CFHeaders.Merge(m, src)
+}
+func (m *CFHeaders) XXX_Size() int {
+	return m.Size()
+}
+func (m *CFHeaders) XXX_DiscardUnknown() {
+	xxx_messageInfo_CFHeaders.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CFHeaders proto.InternalMessageInfo
+
+func (m *CFHeaders) GetFilterType() uint32 {
+	if m != nil {
+		return m.FilterType
+	}
+	return 0
+}
+
+func (m *CFHeaders) GetStopHash() *Hash {
+	if m != nil {
+		return m.StopHash
+	}
+	return nil
+}
+
+func (m *CFHeaders) GetPrevFilterHeader() *Hash {
+	if m != nil {
+		return m.PrevFilterHeader
+	}
+	return nil
+}
+
+func (m *CFHeaders) GetFilterHashes() []*Hash {
+	if m != nil {
+		return m.FilterHashes
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*GetCFilters)(nil), "qitmeer.p2p.v1.GetCFilters")
+	proto.RegisterType((*CFilter)(nil), "qitmeer.p2p.v1.CFilter")
+	proto.RegisterType((*CFilters)(nil), "qitmeer.p2p.v1.CFilters")
+	proto.RegisterType((*GetCFHeaders)(nil), "qitmeer.p2p.v1.GetCFHeaders")
+	proto.RegisterType((*CFHeaders)(nil), "qitmeer.p2p.v1.CFHeaders")
+}
+
+func init() { proto.RegisterFile("cfilter.proto", fileDescriptor_0dcac866be37bc1f) }
+
+var fileDescriptor_0dcac866be37bc1f = []byte{
+	// 367 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xc4, 0x52, 0xcd, 0x4e, 0xea, 0x40,
+	0x18, 0xbd, 0x03, 0xe4, 0x02, 0x1f, 0x3f, 0xe1, 0x4e, 0xee, 0xcd, 0x6d, 0x58, 0x94, 0xa6, 0xab,
+	0xba, 0xa0, 0x94, 0xfa, 0x1b, 0x57, 0x06, 0xa3, 0xb2, 0x30, 0x31, 0x69, 0x7c, 0x81, 0x16, 0x86,
+	0x42, 0xa4, 0x99, 0xd2, 0x19, 0x88, 0xba, 0xd1, 0xc7, 0xf0, 0x91, 0x5c, 0xfa, 0x04, 0xc4, 0xe0,
+	0xda, 0x0d, 0x4f, 0x60, 0x3a, 0x83, 0x5a, 0x30, 0x12, 0x76, 0xee, 0x7a, 0xbe, 0xef, 0x9c, 0xef,
+	0x9c, 0x9e, 0x0c, 0x94, 0x3a, 0xbd, 0xc1, 0x90, 0x93, 0xc8, 0x0c, 0x23, 0xca, 0x29, 0x2e, 0x8f,
+	0x06, 0x3c, 0x20, 0x31, 0xb4, 0x43, 0x73, 0xd2, 0xac, 0xd6, 0xfd, 0x01, 0xef, 0x8f, 0x3d, 0xb3,
+	0x43, 0x83, 0x86, 0x4f, 0x7d, 0xda, 0x10, 0x34, 0x6f, 0xdc, 0x13, 0x48, 0x00, 0xf1, 0x25, 0xe5,
+	0xd5, 0x52, 0x40, 0x18, 0x73, 0x7d, 0x22, 0xa1, 0x7e, 0x07, 0x85, 0x33, 0xc2, 0x8f, 0x4f, 0x85,
+	0x03, 0xc3, 0x2a, 0x80, 0x34, 0xbb, 0xbc, 0x09, 0x89, 0x82, 0x34, 0x64, 0x94, 0x9c, 0xc4, 0x24,
+	0xde, 0x33, 0xee, 0x46, 0xfc, 0x22, 0xea, 0x92, 0x48, 0x49, 0x69, 0xc8, 0xc8, 0x38, 0x89, 0x09,
+	0xb6, 0x20, 0xc7, 0x38, 0x0d, 0xdb, 0x2e, 0xeb, 0x2b, 0x69, 0x0d, 0x19, 0x05, 0xfb, 0xaf, 0xb9,
+	0x9c, 0xd7, 0x8c, 0x77, 0xce, 0x07, 0x4b, 0xef, 0x43, 0x76, 0xe1, 0x8e, 0x6d, 0xc8, 0x7b, 0x43,
+	0xda, 0xb9, 0x12, 0x6a, 0xb4, 0x46, 0xfd, 0x49, 0xc3, 0x5b, 0x90, 0xe9, 0xba, 0xdc, 0x15, 0x51,
+	0x8a, 0xad, 0x7f, 0xf3, 0x69, 0xed, 0x0f, 0x63, 0xb7, 0xf5, 0xc0, 0xbd, 0x3e, 0xd4, 0x9b, 0xd6,
+	0xce, 0xc1, 0xee, 0xfe, 0x9e, 0xee, 0x08, 0x8a, 0x3e, 0x82, 0xdc, 0xc6, 0xff, 0x79, 0x02, 0x59,
+	0x89, 0x98, 0x92, 0xd2, 0xd2, 0x46, 0xc1, 0xfe, 0xbf, 0x1a, 0x64, 0x71, 0xaa, 0x85, 0xe7, 0xd3,
+	0x5a, 0x39, 0x61, 0x69, 0x59, 0xba, 0xf3, 0xae, 0xd5, 0xef, 0x11, 0x14, 0x45, 0xbd, 0x6d, 0xe2,
+	0x76, 0x7f, 0xa6, 0xdf, 0x57, 0x04, 0xf9, 0xcd, 0xfd, 0x93, 0xf7, 0x53, 0x9b, 0xdc, 0xc7, 0x47,
+	0x50, 0x09, 0x23, 0x32, 0x91, 0x6d, 0x48, 0x9b, 0xb5, 0xc9, 0xbe, 0xb0, 0xf1, 0x39, 0x14, 0x65,
+	0x82, 0x78, 0x4f, 0x98, 0x92, 0xd1, 0xd2, 0xdf, 0xa9, 0x57, 0xda, 0xb6, 0x45, 0xdb, 0x4b, 0xea,
+	0x56, 0xe5, 0x71, 0xa6, 0xa2, 0xa7, 0x99, 0x8a, 0x9e, 0x67, 0x2a, 0x7a, 0x78, 0x51, 0x7f, 0x79,
+	0xbf, 0xc5, 0x4b, 0xdf, 0x7e, 0x1b, 0x00, 0x80, 0xe3, 0x62, 0xb0, 0x48, 0x03, 0x00, 0x00,
+}
+
+func (m *GetCFilters) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *GetCFilters) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *GetCFilters) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.StopHash != nil {
+		{
+			size, err := m.StopHash.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintCfilter(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x1a
+	}
+	if m.StartOrder != 0 {
+		i = encodeVarintCfilter(dAtA, i, uint64(m.StartOrder))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.FilterType != 0 {
+		i = encodeVarintCfilter(dAtA, i, uint64(m.FilterType))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *CFilter) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *CFilter) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *CFilter) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.Data) > 0 {
+		i -= len(m.Data)
+		copy(dAtA[i:], m.Data)
+		i = encodeVarintCfilter(dAtA, i, uint64(len(m.Data)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.BlockHash != nil {
+		{
+			size, err := m.BlockHash.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintCfilter(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *CFilters) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *CFilters) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *CFilters) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.Filters) > 0 {
+		for iNdEx := len(m.Filters) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Filters[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintCfilter(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x12
+		}
+	}
+	if m.FilterType != 0 {
+		i = encodeVarintCfilter(dAtA, i, uint64(m.FilterType))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *GetCFHeaders) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *GetCFHeaders) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *GetCFHeaders) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.StopHash != nil {
+		{
+			size, err := m.StopHash.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintCfilter(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x1a
+	}
+	if m.StartOrder != 0 {
+		i = encodeVarintCfilter(dAtA, i, uint64(m.StartOrder))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.FilterType != 0 {
+		i = encodeVarintCfilter(dAtA, i, uint64(m.FilterType))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *CFHeaders) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *CFHeaders) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *CFHeaders) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.FilterHashes) > 0 {
+		for iNdEx := len(m.FilterHashes) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.FilterHashes[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintCfilter(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x22
+		}
+	}
+	if m.PrevFilterHeader != nil {
+		{
+			size, err := m.PrevFilterHeader.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintCfilter(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x1a
+	}
+	if m.StopHash != nil {
+		{
+			size, err := m.StopHash.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintCfilter(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.FilterType != 0 {
+		i = encodeVarintCfilter(dAtA, i, uint64(m.FilterType))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func encodeVarintCfilter(dAtA []byte, offset int, v uint64) int {
+	offset -= sovCfilter(v)
+	base := offset
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return base
+}
+func (m *GetCFilters) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.FilterType != 0 {
+		n += 1 + sovCfilter(uint64(m.FilterType))
+	}
+	if m.StartOrder != 0 {
+		n += 1 + sovCfilter(uint64(m.StartOrder))
+	}
+	if m.StopHash != nil {
+		l = m.StopHash.Size()
+		n += 1 + l + sovCfilter(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *CFilter) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.BlockHash != nil {
+		l = m.BlockHash.Size()
+		n += 1 + l + sovCfilter(uint64(l))
+	}
+	l = len(m.Data)
+	if l > 0 {
+		n += 1 + l + sovCfilter(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *CFilters) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.FilterType != 0 {
+		n += 1 + sovCfilter(uint64(m.FilterType))
+	}
+	if len(m.Filters) > 0 {
+		for _, e := range m.Filters {
+			l = e.Size()
+			n += 1 + l + sovCfilter(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *GetCFHeaders) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.FilterType != 0 {
+		n += 1 + sovCfilter(uint64(m.FilterType))
+	}
+	if m.StartOrder != 0 {
+		n += 1 + sovCfilter(uint64(m.StartOrder))
+	}
+	if m.StopHash != nil {
+		l = m.StopHash.Size()
+		n += 1 + l + sovCfilter(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *CFHeaders) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.FilterType != 0 {
+		n += 1 + sovCfilter(uint64(m.FilterType))
+	}
+	if m.StopHash != nil {
+		l = m.StopHash.Size()
+		n += 1 + l + sovCfilter(uint64(l))
+	}
+	if m.PrevFilterHeader != nil {
+		l = m.PrevFilterHeader.Size()
+		n += 1 + l + sovCfilter(uint64(l))
+	}
+	if len(m.FilterHashes) > 0 {
+		for _, e := range m.FilterHashes {
+			l = e.Size()
+			n += 1 + l + sovCfilter(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func sovCfilter(x uint64) (n int) {
+	return (math_bits.Len64(x|1) + 6) / 7
+}
+func sozCfilter(x uint64) (n int) {
+	return sovCfilter(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (m *GetCFilters) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowCfilter
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: GetCFilters: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: GetCFilters: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FilterType", wireType)
+			}
+			m.FilterType = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCfilter
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.FilterType |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StartOrder", wireType)
+			}
+			m.StartOrder = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCfilter
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.StartOrder |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StopHash", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCfilter
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthCfilter
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthCfilter
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.StopHash == nil {
+				m.StopHash = &Hash{}
+			}
+			if err := m.StopHash.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipCfilter(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthCfilter
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthCfilter
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *CFilter) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowCfilter
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: CFilter: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: CFilter: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field BlockHash", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCfilter
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthCfilter
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthCfilter
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.BlockHash == nil {
+				m.BlockHash = &Hash{}
+			}
+			if err := m.BlockHash.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Data", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCfilter
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthCfilter
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthCfilter
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Data = append(m.Data[:0], dAtA[iNdEx:postIndex]...)
+			if m.Data == nil {
+				m.Data = []byte{}
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipCfilter(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthCfilter
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthCfilter
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *CFilters) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowCfilter
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: CFilters: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: CFilters: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FilterType", wireType)
+			}
+			m.FilterType = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCfilter
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.FilterType |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Filters", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCfilter
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthCfilter
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthCfilter
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Filters = append(m.Filters, &CFilter{})
+			if err := m.Filters[len(m.Filters)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipCfilter(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthCfilter
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthCfilter
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *GetCFHeaders) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowCfilter
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: GetCFHeaders: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: GetCFHeaders: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FilterType", wireType)
+			}
+			m.FilterType = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCfilter
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.FilterType |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StartOrder", wireType)
+			}
+			m.StartOrder = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCfilter
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.StartOrder |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StopHash", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCfilter
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthCfilter
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthCfilter
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.StopHash == nil {
+				m.StopHash = &Hash{}
+			}
+			if err := m.StopHash.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipCfilter(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthCfilter
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthCfilter
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *CFHeaders) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowCfilter
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: CFHeaders: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: CFHeaders: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FilterType", wireType)
+			}
+			m.FilterType = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCfilter
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.FilterType |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StopHash", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCfilter
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthCfilter
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthCfilter
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.StopHash == nil {
+				m.StopHash = &Hash{}
+			}
+			if err := m.StopHash.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PrevFilterHeader", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCfilter
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthCfilter
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthCfilter
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.PrevFilterHeader == nil {
+				m.PrevFilterHeader = &Hash{}
+			}
+			if err := m.PrevFilterHeader.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FilterHashes", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCfilter
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthCfilter
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthCfilter
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.FilterHashes = append(m.FilterHashes, &Hash{})
+			if err := m.FilterHashes[len(m.FilterHashes)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipCfilter(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthCfilter
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthCfilter
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func skipCfilter(dAtA []byte) (n int, err error) {
+	l := len(dAtA)
+	iNdEx := 0
+	depth := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return 0, ErrIntOverflowCfilter
+			}
+			if iNdEx >= l {
+				return 0, io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		wireType := int(wire & 0x7)
+		switch wireType {
+		case 0:
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowCfilter
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				iNdEx++
+				if dAtA[iNdEx-1] < 0x80 {
+					break
+				}
+			}
+		case 1:
+			iNdEx += 8
+		case 2:
+			var length int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowCfilter
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				length |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if length < 0 {
+				return 0, ErrInvalidLengthCfilter
+			}
+			iNdEx += length
+		case 3:
+			depth++
+		case 4:
+			if depth == 0 {
+				return 0, ErrUnexpectedEndOfGroupCfilter
+			}
+			depth--
+		case 5:
+			iNdEx += 4
+		default:
+			return 0, fmt.Errorf("proto: illegal wireType %d", wireType)
+		}
+		if iNdEx < 0 {
+			return 0, ErrInvalidLengthCfilter
+		}
+		if depth == 0 {
+			return iNdEx, nil
+		}
+	}
+	return 0, io.ErrUnexpectedEOF
+}
+
+var (
+	ErrInvalidLengthCfilter        = fmt.Errorf("proto: negative length found during unmarshaling")
+	ErrIntOverflowCfilter          = fmt.Errorf("proto: integer overflow")
+	ErrUnexpectedEndOfGroupCfilter = fmt.Errorf("proto: unexpected end of group")
+)
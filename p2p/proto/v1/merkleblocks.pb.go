@@ -75,6 +75,7 @@ type MerkleBlock struct {
 	Transactions         uint64   `protobuf:"varint,2,opt,name=transactions,proto3" json:"transactions,omitempty"`
 	Hashes               []*Hash  `protobuf:"bytes,3,rep,name=hashes,proto3" json:"hashes,omitempty" ssz-max:"104858"`
 	Flags                []byte   `protobuf:"bytes,4,opt,name=flags,proto3" json:"flags,omitempty" ssz-max:"256"`
+	Parents              []*Hash  `protobuf:"bytes,5,rep,name=parents,proto3" json:"parents,omitempty" ssz-max:"50"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -141,6 +142,13 @@ func (m *MerkleBlock) GetFlags() []byte {
 	return nil
 }
 
+func (m *MerkleBlock) GetParents() []*Hash {
+	if m != nil {
+		return m.Parents
+	}
+	return nil
+}
+
 type MerkleBlockResponse struct {
 	Data                 []*MerkleBlock `protobuf:"bytes,1,rep,name=data,proto3" json:"data,omitempty" ssz-max:"2000"`
 	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
@@ -197,28 +205,29 @@ func init() {
 func init() { proto.RegisterFile("merkleblocks.proto", fileDescriptor_1fca2b5267be08f9) }
 
 var fileDescriptor_1fca2b5267be08f9 = []byte{
-	// 327 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x12, 0xca, 0x4d, 0x2d, 0xca,
-	0xce, 0x49, 0x4d, 0xca, 0xc9, 0x4f, 0xce, 0x2e, 0xd6, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2,
-	0x2b, 0xcc, 0x2c, 0xc9, 0x4d, 0x4d, 0x2d, 0xd2, 0x2b, 0x30, 0x2a, 0xd0, 0x2b, 0x33, 0x94, 0xd2,
-	0x4d, 0xcf, 0x2c, 0xc9, 0x28, 0x4d, 0xd2, 0x4b, 0xce, 0xcf, 0xd5, 0x4f, 0xcf, 0x4f, 0xcf, 0xd7,
-	0x07, 0x2b, 0x4b, 0x2a, 0x4d, 0x03, 0xf3, 0xc0, 0x1c, 0x30, 0x0b, 0xa2, 0x5d, 0x8a, 0x37, 0x37,
-	0xb5, 0xb8, 0x38, 0x31, 0x3d, 0x15, 0xc2, 0x55, 0x0a, 0xe3, 0x12, 0xf2, 0x05, 0xdb, 0xe1, 0x04,
-	0xb2, 0x23, 0x28, 0xb5, 0xb0, 0x34, 0xb5, 0xb8, 0x44, 0xc8, 0x81, 0x8b, 0x2d, 0x23, 0xb1, 0x38,
-	0x23, 0xb5, 0x58, 0x82, 0x51, 0x81, 0x59, 0x83, 0xdb, 0x48, 0x44, 0x0f, 0xd5, 0x52, 0x3d, 0x8f,
-	0xc4, 0xe2, 0x0c, 0x27, 0xa1, 0x4f, 0xf7, 0xe4, 0xf9, 0x8a, 0x8b, 0xab, 0x74, 0x73, 0x13, 0x2b,
-	0xac, 0x94, 0x8c, 0x0c, 0x0c, 0x0c, 0x94, 0x82, 0xa0, 0xfa, 0x94, 0x4e, 0x32, 0x72, 0x71, 0x23,
-	0x19, 0x2c, 0xa4, 0xc9, 0xc5, 0x96, 0x91, 0x9a, 0x98, 0x92, 0x5a, 0x24, 0xc1, 0xa8, 0xc0, 0xa8,
-	0xc1, 0xe3, 0x24, 0xf8, 0xe9, 0x9e, 0x3c, 0x2f, 0x42, 0xaf, 0xa9, 0x19, 0x48, 0x2b, 0x58, 0x81,
-	0x90, 0x12, 0x17, 0x4f, 0x49, 0x51, 0x62, 0x5e, 0x71, 0x62, 0x72, 0x49, 0x66, 0x7e, 0x5e, 0xb1,
-	0x04, 0x93, 0x02, 0xa3, 0x06, 0x4b, 0x10, 0x8a, 0x98, 0x90, 0x13, 0xdc, 0x81, 0xcc, 0x78, 0x1c,
-	0x28, 0xf2, 0xe9, 0x9e, 0xbc, 0x00, 0xdc, 0x12, 0x43, 0x03, 0x13, 0x0b, 0x53, 0x0b, 0xb8, 0x13,
-	0x85, 0xd4, 0xb9, 0x58, 0xd3, 0x72, 0x12, 0xd3, 0x8b, 0x25, 0x58, 0x70, 0xb9, 0x08, 0x22, 0xaf,
-	0x14, 0xc5, 0x25, 0x8c, 0x12, 0x46, 0xc5, 0x05, 0xf9, 0x79, 0xc5, 0xa9, 0x42, 0xce, 0x5c, 0x2c,
-	0x29, 0x89, 0x25, 0x89, 0xd0, 0x20, 0x92, 0x46, 0x77, 0x01, 0x92, 0x16, 0xac, 0x21, 0x05, 0xd6,
-	0xec, 0x24, 0x70, 0xe2, 0x91, 0x1c, 0xe3, 0x85, 0x47, 0x72, 0x8c, 0x0f, 0x1e, 0xc9, 0x31, 0xce,
-	0x78, 0x2c, 0xc7, 0x90, 0xc4, 0x06, 0x8e, 0x18, 0x63, 0x40, 0x00, 0x00, 0x00, 0xff, 0xff, 0xef,
-	0x0e, 0x74, 0x08, 0xfc, 0x01, 0x00, 0x00,
+	// 350 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x91, 0xcf, 0x4e, 0xea, 0x40,
+	0x18, 0xc5, 0xef, 0xf0, 0xef, 0x26, 0xc3, 0x9f, 0x70, 0xe7, 0xb2, 0x68, 0x30, 0x69, 0xc9, 0x6c,
+	0xc4, 0x05, 0xa5, 0xa0, 0x18, 0xe2, 0x8a, 0xd4, 0x8d, 0x1b, 0x37, 0x5d, 0xb8, 0x70, 0x37, 0x85,
+	0xa1, 0x25, 0xd0, 0x4e, 0xe9, 0x37, 0x18, 0xe3, 0x2b, 0xf8, 0x02, 0x3e, 0x92, 0x4b, 0x9f, 0xa0,
+	0x31, 0xf8, 0x06, 0x7d, 0x02, 0xe3, 0x80, 0x15, 0x8c, 0xb2, 0x9b, 0x6f, 0xe6, 0xfb, 0x9d, 0x73,
+	0x72, 0x06, 0x93, 0x80, 0xc7, 0xf3, 0x05, 0x77, 0x17, 0x62, 0x3c, 0x07, 0x33, 0x8a, 0x85, 0x14,
+	0xa4, 0xb6, 0x9c, 0xc9, 0x80, 0xf3, 0xd8, 0x8c, 0xfa, 0x91, 0x79, 0xd7, 0x6b, 0x76, 0xbc, 0x99,
+	0xf4, 0x57, 0xae, 0x39, 0x16, 0x41, 0xd7, 0x13, 0x9e, 0xe8, 0xaa, 0x35, 0x77, 0x35, 0x55, 0x93,
+	0x1a, 0xd4, 0x69, 0x83, 0x37, 0xab, 0x01, 0x07, 0x60, 0x1e, 0xdf, 0x8c, 0xf4, 0x06, 0x93, 0x6b,
+	0xe5, 0x61, 0x7f, 0x78, 0x38, 0x7c, 0xb9, 0xe2, 0x20, 0xc9, 0x08, 0x97, 0x7c, 0x06, 0x3e, 0x07,
+	0x0d, 0xb5, 0xf2, 0xed, 0x72, 0xbf, 0x61, 0xee, 0x9b, 0x9a, 0x57, 0x0c, 0x7c, 0x9b, 0xa4, 0x89,
+	0x51, 0x03, 0x78, 0xe8, 0x04, 0xec, 0xfe, 0x82, 0xf6, 0x2d, 0xcb, 0xa2, 0xce, 0x96, 0xa3, 0x8f,
+	0x39, 0x5c, 0xde, 0x11, 0x26, 0x27, 0xb8, 0xe4, 0x73, 0x36, 0xe1, 0xb1, 0x86, 0x5a, 0xa8, 0x5d,
+	0xb1, 0xff, 0xa5, 0x89, 0x51, 0xfd, 0x62, 0x07, 0xe7, 0xd4, 0xd9, 0x2e, 0x10, 0x8a, 0x2b, 0x32,
+	0x66, 0x21, 0xb0, 0xb1, 0x9c, 0x89, 0x10, 0xb4, 0x5c, 0x0b, 0xb5, 0x0b, 0xce, 0xde, 0x1d, 0xb1,
+	0xb3, 0x80, 0xf9, 0x03, 0x01, 0x1b, 0x69, 0x62, 0xd4, 0x33, 0x93, 0x9e, 0x75, 0x36, 0x1c, 0x0c,
+	0xb3, 0x88, 0xe4, 0x18, 0x17, 0xa7, 0x0b, 0xe6, 0x81, 0x56, 0xf8, 0x2d, 0xd1, 0xe6, 0x9d, 0x8c,
+	0xf0, 0xdf, 0x88, 0xc5, 0x3c, 0x94, 0xa0, 0x15, 0x0f, 0xb8, 0xd5, 0xd3, 0xc4, 0xa8, 0x64, 0x02,
+	0x03, 0x8b, 0x3a, 0x9f, 0x18, 0xbd, 0xc5, 0xff, 0xf7, 0x5a, 0x86, 0x48, 0x84, 0xc0, 0xc9, 0x25,
+	0x2e, 0x4c, 0x98, 0x64, 0xdb, 0x92, 0x8f, 0xbe, 0xab, 0xee, 0x20, 0x3f, 0x76, 0xad, 0x60, 0xbb,
+	0xfe, 0xbc, 0xd6, 0xd1, 0xcb, 0x5a, 0x47, 0xaf, 0x6b, 0x1d, 0x3d, 0xbd, 0xe9, 0x7f, 0xdc, 0x92,
+	0xfa, 0xda, 0xd3, 0xf7, 0x01, 0x00, 0x54, 0xc9, 0x29, 0xb5, 0x3e, 0x02, 0x00, 0x00,
 }
 
 func (m *MerkleBlockRequest) Marshal() (dAtA []byte, err error) {
@@ -286,6 +295,20 @@ func (m *MerkleBlock) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
+	if len(m.Parents) > 0 {
+		for iNdEx := len(m.Parents) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Parents[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintMerkleblocks(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x2a
+		}
+	}
 	if len(m.Flags) > 0 {
 		i -= len(m.Flags)
 		copy(dAtA[i:], m.Flags)
@@ -415,6 +438,12 @@ func (m *MerkleBlock) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovMerkleblocks(uint64(l))
 	}
+	if len(m.Parents) > 0 {
+		for _, e := range m.Parents {
+			l = e.Size()
+			n += 1 + l + sovMerkleblocks(uint64(l))
+		}
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -683,6 +712,40 @@ func (m *MerkleBlock) Unmarshal(dAtA []byte) error {
 				m.Flags = []byte{}
 			}
 			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Parents", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMerkleblocks
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMerkleblocks
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthMerkleblocks
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Parents = append(m.Parents, &Hash{})
+			if err := m.Parents[len(m.Parents)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipMerkleblocks(dAtA[iNdEx:])
@@ -1,5 +1,5 @@
 // Code generated by fastssz. DO NOT EDIT.
-// Hash: 777c711a8bf3abf48991173bda18daa32a11d1f0ab49762cd43f940dde3fabe2
+// Hash: afb3d4a9b55bce169cce2518326c30d2164a2ae0c53df6f967f0a5865d2fbbe3
 // Version: 0.1.2
 package qitmeer_p2p_v1
 
@@ -7,201 +7,1473 @@ import (
 	ssz "github.com/ferranbt/fastssz"
 )
 
+// MarshalSSZ ssz marshals the GetCFilters object
+func (g *GetCFilters) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(g)
+}
+
+// MarshalSSZTo ssz marshals the GetCFilters object to a target array
+func (g *GetCFilters) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+
+	// Field (0) 'FilterType'
+	dst = ssz.MarshalUint32(dst, g.FilterType)
+
+	// Field (1) 'StartOrder'
+	dst = ssz.MarshalUint64(dst, g.StartOrder)
+
+	// Field (2) 'StopHash'
+	if g.StopHash == nil {
+		g.StopHash = new(Hash)
+	}
+	if dst, err = g.StopHash.MarshalSSZTo(dst); err != nil {
+		return
+	}
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the GetCFilters object
+func (g *GetCFilters) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size != 44 {
+		return ssz.ErrSize
+	}
+
+	// Field (0) 'FilterType'
+	g.FilterType = ssz.UnmarshallUint32(buf[0:4])
+
+	// Field (1) 'StartOrder'
+	g.StartOrder = ssz.UnmarshallUint64(buf[4:12])
+
+	// Field (2) 'StopHash'
+	if g.StopHash == nil {
+		g.StopHash = new(Hash)
+	}
+	if err = g.StopHash.UnmarshalSSZ(buf[12:44]); err != nil {
+		return err
+	}
+
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the GetCFilters object
+func (g *GetCFilters) SizeSSZ() (size int) {
+	size = 44
+	return
+}
+
+// HashTreeRoot ssz hashes the GetCFilters object
+func (g *GetCFilters) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(g)
+}
+
+// HashTreeRootWith ssz hashes the GetCFilters object with a hasher
+func (g *GetCFilters) HashTreeRootWith(hh ssz.HashWalker) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'FilterType'
+	hh.PutUint32(g.FilterType)
+
+	// Field (1) 'StartOrder'
+	hh.PutUint64(g.StartOrder)
+
+	// Field (2) 'StopHash'
+	if g.StopHash == nil {
+		g.StopHash = new(Hash)
+	}
+	if err = g.StopHash.HashTreeRootWith(hh); err != nil {
+		return
+	}
+
+	hh.Merkleize(indx)
+	return
+}
+
+// GetTree ssz hashes the GetCFilters object
+func (g *GetCFilters) GetTree() (*ssz.Node, error) {
+	return ssz.ProofTree(g)
+}
+
+// MarshalSSZ ssz marshals the CFilter object
+func (c *CFilter) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(c)
+}
+
+// MarshalSSZTo ssz marshals the CFilter object to a target array
+func (c *CFilter) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+	offset := int(36)
+
+	// Field (0) 'BlockHash'
+	if c.BlockHash == nil {
+		c.BlockHash = new(Hash)
+	}
+	if dst, err = c.BlockHash.MarshalSSZTo(dst); err != nil {
+		return
+	}
+
+	// Offset (1) 'Data'
+	dst = ssz.WriteOffset(dst, offset)
+	offset += len(c.Data)
+
+	// Field (1) 'Data'
+	if size := len(c.Data); size > 1048576 {
+		err = ssz.ErrBytesLengthFn("CFilter.Data", size, 1048576)
+		return
+	}
+	dst = append(dst, c.Data...)
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the CFilter object
+func (c *CFilter) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size < 36 {
+		return ssz.ErrSize
+	}
+
+	tail := buf
+	var o1 uint64
+
+	// Field (0) 'BlockHash'
+	if c.BlockHash == nil {
+		c.BlockHash = new(Hash)
+	}
+	if err = c.BlockHash.UnmarshalSSZ(buf[0:32]); err != nil {
+		return err
+	}
+
+	// Offset (1) 'Data'
+	if o1 = ssz.ReadOffset(buf[32:36]); o1 > size {
+		return ssz.ErrOffset
+	}
+
+	if o1 < 36 {
+		return ssz.ErrInvalidVariableOffset
+	}
+
+	// Field (1) 'Data'
+	{
+		buf = tail[o1:]
+		if len(buf) > 1048576 {
+			return ssz.ErrBytesLength
+		}
+		if cap(c.Data) == 0 {
+			c.Data = make([]byte, 0, len(buf))
+		}
+		c.Data = append(c.Data, buf...)
+	}
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the CFilter object
+func (c *CFilter) SizeSSZ() (size int) {
+	size = 36
+
+	// Field (1) 'Data'
+	size += len(c.Data)
+
+	return
+}
+
+// HashTreeRoot ssz hashes the CFilter object
+func (c *CFilter) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(c)
+}
+
+// HashTreeRootWith ssz hashes the CFilter object with a hasher
+func (c *CFilter) HashTreeRootWith(hh ssz.HashWalker) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'BlockHash'
+	if c.BlockHash == nil {
+		c.BlockHash = new(Hash)
+	}
+	if err = c.BlockHash.HashTreeRootWith(hh); err != nil {
+		return
+	}
+
+	// Field (1) 'Data'
+	{
+		elemIndx := hh.Index()
+		byteLen := uint64(len(c.Data))
+		if byteLen > 1048576 {
+			err = ssz.ErrIncorrectListSize
+			return
+		}
+		hh.PutBytes(c.Data)
+		hh.MerkleizeWithMixin(elemIndx, byteLen, (1048576+31)/32)
+	}
+
+	hh.Merkleize(indx)
+	return
+}
+
+// GetTree ssz hashes the CFilter object
+func (c *CFilter) GetTree() (*ssz.Node, error) {
+	return ssz.ProofTree(c)
+}
+
+// MarshalSSZ ssz marshals the CFilters object
+func (c *CFilters) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(c)
+}
+
+// MarshalSSZTo ssz marshals the CFilters object to a target array
+func (c *CFilters) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+	offset := int(8)
+
+	// Field (0) 'FilterType'
+	dst = ssz.MarshalUint32(dst, c.FilterType)
+
+	// Offset (1) 'Filters'
+	dst = ssz.WriteOffset(dst, offset)
+	for ii := 0; ii < len(c.Filters); ii++ {
+		offset += 4
+		offset += c.Filters[ii].SizeSSZ()
+	}
+
+	// Field (1) 'Filters'
+	if size := len(c.Filters); size > 1000 {
+		err = ssz.ErrListTooBigFn("CFilters.Filters", size, 1000)
+		return
+	}
+	{
+		offset = 4 * len(c.Filters)
+		for ii := 0; ii < len(c.Filters); ii++ {
+			dst = ssz.WriteOffset(dst, offset)
+			offset += c.Filters[ii].SizeSSZ()
+		}
+	}
+	for ii := 0; ii < len(c.Filters); ii++ {
+		if dst, err = c.Filters[ii].MarshalSSZTo(dst); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the CFilters object
+func (c *CFilters) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size < 8 {
+		return ssz.ErrSize
+	}
+
+	tail := buf
+	var o1 uint64
+
+	// Field (0) 'FilterType'
+	c.FilterType = ssz.UnmarshallUint32(buf[0:4])
+
+	// Offset (1) 'Filters'
+	if o1 = ssz.ReadOffset(buf[4:8]); o1 > size {
+		return ssz.ErrOffset
+	}
+
+	if o1 < 8 {
+		return ssz.ErrInvalidVariableOffset
+	}
+
+	// Field (1) 'Filters'
+	{
+		buf = tail[o1:]
+		num, err := ssz.DecodeDynamicLength(buf, 1000)
+		if err != nil {
+			return err
+		}
+		c.Filters = make([]*CFilter, num)
+		err = ssz.UnmarshalDynamic(buf, num, func(indx int, buf []byte) (err error) {
+			if c.Filters[indx] == nil {
+				c.Filters[indx] = new(CFilter)
+			}
+			if err = c.Filters[indx].UnmarshalSSZ(buf); err != nil {
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the CFilters object
+func (c *CFilters) SizeSSZ() (size int) {
+	size = 8
+
+	// Field (1) 'Filters'
+	for ii := 0; ii < len(c.Filters); ii++ {
+		size += 4
+		size += c.Filters[ii].SizeSSZ()
+	}
+
+	return
+}
+
+// HashTreeRoot ssz hashes the CFilters object
+func (c *CFilters) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(c)
+}
+
+// HashTreeRootWith ssz hashes the CFilters object with a hasher
+func (c *CFilters) HashTreeRootWith(hh ssz.HashWalker) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'FilterType'
+	hh.PutUint32(c.FilterType)
+
+	// Field (1) 'Filters'
+	{
+		subIndx := hh.Index()
+		num := uint64(len(c.Filters))
+		if num > 1000 {
+			err = ssz.ErrIncorrectListSize
+			return
+		}
+		for _, elem := range c.Filters {
+			if err = elem.HashTreeRootWith(hh); err != nil {
+				return
+			}
+		}
+		hh.MerkleizeWithMixin(subIndx, num, 1000)
+	}
+
+	hh.Merkleize(indx)
+	return
+}
+
+// GetTree ssz hashes the CFilters object
+func (c *CFilters) GetTree() (*ssz.Node, error) {
+	return ssz.ProofTree(c)
+}
+
+// MarshalSSZ ssz marshals the GetCFHeaders object
+func (g *GetCFHeaders) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(g)
+}
+
+// MarshalSSZTo ssz marshals the GetCFHeaders object to a target array
+func (g *GetCFHeaders) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+
+	// Field (0) 'FilterType'
+	dst = ssz.MarshalUint32(dst, g.FilterType)
+
+	// Field (1) 'StartOrder'
+	dst = ssz.MarshalUint64(dst, g.StartOrder)
+
+	// Field (2) 'StopHash'
+	if g.StopHash == nil {
+		g.StopHash = new(Hash)
+	}
+	if dst, err = g.StopHash.MarshalSSZTo(dst); err != nil {
+		return
+	}
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the GetCFHeaders object
+func (g *GetCFHeaders) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size != 44 {
+		return ssz.ErrSize
+	}
+
+	// Field (0) 'FilterType'
+	g.FilterType = ssz.UnmarshallUint32(buf[0:4])
+
+	// Field (1) 'StartOrder'
+	g.StartOrder = ssz.UnmarshallUint64(buf[4:12])
+
+	// Field (2) 'StopHash'
+	if g.StopHash == nil {
+		g.StopHash = new(Hash)
+	}
+	if err = g.StopHash.UnmarshalSSZ(buf[12:44]); err != nil {
+		return err
+	}
+
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the GetCFHeaders object
+func (g *GetCFHeaders) SizeSSZ() (size int) {
+	size = 44
+	return
+}
+
+// HashTreeRoot ssz hashes the GetCFHeaders object
+func (g *GetCFHeaders) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(g)
+}
+
+// HashTreeRootWith ssz hashes the GetCFHeaders object with a hasher
+func (g *GetCFHeaders) HashTreeRootWith(hh ssz.HashWalker) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'FilterType'
+	hh.PutUint32(g.FilterType)
+
+	// Field (1) 'StartOrder'
+	hh.PutUint64(g.StartOrder)
+
+	// Field (2) 'StopHash'
+	if g.StopHash == nil {
+		g.StopHash = new(Hash)
+	}
+	if err = g.StopHash.HashTreeRootWith(hh); err != nil {
+		return
+	}
+
+	hh.Merkleize(indx)
+	return
+}
+
+// GetTree ssz hashes the GetCFHeaders object
+func (g *GetCFHeaders) GetTree() (*ssz.Node, error) {
+	return ssz.ProofTree(g)
+}
+
+// MarshalSSZ ssz marshals the CFHeaders object
+func (c *CFHeaders) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(c)
+}
+
+// MarshalSSZTo ssz marshals the CFHeaders object to a target array
+func (c *CFHeaders) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+	offset := int(72)
+
+	// Field (0) 'FilterType'
+	dst = ssz.MarshalUint32(dst, c.FilterType)
+
+	// Field (1) 'StopHash'
+	if c.StopHash == nil {
+		c.StopHash = new(Hash)
+	}
+	if dst, err = c.StopHash.MarshalSSZTo(dst); err != nil {
+		return
+	}
+
+	// Field (2) 'PrevFilterHeader'
+	if c.PrevFilterHeader == nil {
+		c.PrevFilterHeader = new(Hash)
+	}
+	if dst, err = c.PrevFilterHeader.MarshalSSZTo(dst); err != nil {
+		return
+	}
+
+	// Offset (3) 'FilterHashes'
+	dst = ssz.WriteOffset(dst, offset)
+	offset += len(c.FilterHashes) * 32
+
+	// Field (3) 'FilterHashes'
+	if size := len(c.FilterHashes); size > 2000 {
+		err = ssz.ErrListTooBigFn("CFHeaders.FilterHashes", size, 2000)
+		return
+	}
+	for ii := 0; ii < len(c.FilterHashes); ii++ {
+		if dst, err = c.FilterHashes[ii].MarshalSSZTo(dst); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the CFHeaders object
+func (c *CFHeaders) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size < 72 {
+		return ssz.ErrSize
+	}
+
+	tail := buf
+	var o3 uint64
+
+	// Field (0) 'FilterType'
+	c.FilterType = ssz.UnmarshallUint32(buf[0:4])
+
+	// Field (1) 'StopHash'
+	if c.StopHash == nil {
+		c.StopHash = new(Hash)
+	}
+	if err = c.StopHash.UnmarshalSSZ(buf[4:36]); err != nil {
+		return err
+	}
+
+	// Field (2) 'PrevFilterHeader'
+	if c.PrevFilterHeader == nil {
+		c.PrevFilterHeader = new(Hash)
+	}
+	if err = c.PrevFilterHeader.UnmarshalSSZ(buf[36:68]); err != nil {
+		return err
+	}
+
+	// Offset (3) 'FilterHashes'
+	if o3 = ssz.ReadOffset(buf[68:72]); o3 > size {
+		return ssz.ErrOffset
+	}
+
+	if o3 < 72 {
+		return ssz.ErrInvalidVariableOffset
+	}
+
+	// Field (3) 'FilterHashes'
+	{
+		buf = tail[o3:]
+		num, err := ssz.DivideInt2(len(buf), 32, 2000)
+		if err != nil {
+			return err
+		}
+		c.FilterHashes = make([]*Hash, num)
+		for ii := 0; ii < num; ii++ {
+			if c.FilterHashes[ii] == nil {
+				c.FilterHashes[ii] = new(Hash)
+			}
+			if err = c.FilterHashes[ii].UnmarshalSSZ(buf[ii*32 : (ii+1)*32]); err != nil {
+				return err
+			}
+		}
+	}
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the CFHeaders object
+func (c *CFHeaders) SizeSSZ() (size int) {
+	size = 72
+
+	// Field (3) 'FilterHashes'
+	size += len(c.FilterHashes) * 32
+
+	return
+}
+
+// HashTreeRoot ssz hashes the CFHeaders object
+func (c *CFHeaders) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(c)
+}
+
+// HashTreeRootWith ssz hashes the CFHeaders object with a hasher
+func (c *CFHeaders) HashTreeRootWith(hh ssz.HashWalker) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'FilterType'
+	hh.PutUint32(c.FilterType)
+
+	// Field (1) 'StopHash'
+	if c.StopHash == nil {
+		c.StopHash = new(Hash)
+	}
+	if err = c.StopHash.HashTreeRootWith(hh); err != nil {
+		return
+	}
+
+	// Field (2) 'PrevFilterHeader'
+	if c.PrevFilterHeader == nil {
+		c.PrevFilterHeader = new(Hash)
+	}
+	if err = c.PrevFilterHeader.HashTreeRootWith(hh); err != nil {
+		return
+	}
+
+	// Field (3) 'FilterHashes'
+	{
+		subIndx := hh.Index()
+		num := uint64(len(c.FilterHashes))
+		if num > 2000 {
+			err = ssz.ErrIncorrectListSize
+			return
+		}
+		for _, elem := range c.FilterHashes {
+			if err = elem.HashTreeRootWith(hh); err != nil {
+				return
+			}
+		}
+		hh.MerkleizeWithMixin(subIndx, num, 2000)
+	}
+
+	hh.Merkleize(indx)
+	return
+}
+
+// GetTree ssz hashes the CFHeaders object
+func (c *CFHeaders) GetTree() (*ssz.Node, error) {
+	return ssz.ProofTree(c)
+}
+
 // MarshalSSZ ssz marshals the ChainState object
 func (c *ChainState) MarshalSSZ() ([]byte, error) {
 	return ssz.MarshalSSZ(c)
 }
 
-// MarshalSSZTo ssz marshals the ChainState object to a target array
-func (c *ChainState) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+// MarshalSSZTo ssz marshals the ChainState object to a target array
+func (c *ChainState) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+	offset := int(61)
+
+	// Field (0) 'GenesisHash'
+	if c.GenesisHash == nil {
+		c.GenesisHash = new(Hash)
+	}
+	if dst, err = c.GenesisHash.MarshalSSZTo(dst); err != nil {
+		return
+	}
+
+	// Field (1) 'ProtocolVersion'
+	dst = ssz.MarshalUint32(dst, c.ProtocolVersion)
+
+	// Field (2) 'Timestamp'
+	dst = ssz.MarshalUint64(dst, c.Timestamp)
+
+	// Field (3) 'Services'
+	dst = ssz.MarshalUint64(dst, c.Services)
+
+	// Field (4) 'DisableRelayTx'
+	dst = ssz.MarshalBool(dst, c.DisableRelayTx)
+
+	// Offset (5) 'GraphState'
+	dst = ssz.WriteOffset(dst, offset)
+	if c.GraphState == nil {
+		c.GraphState = new(GraphState)
+	}
+	offset += c.GraphState.SizeSSZ()
+
+	// Offset (6) 'UserAgent'
+	dst = ssz.WriteOffset(dst, offset)
+	offset += len(c.UserAgent)
+
+	// Field (5) 'GraphState'
+	if dst, err = c.GraphState.MarshalSSZTo(dst); err != nil {
+		return
+	}
+
+	// Field (6) 'UserAgent'
+	if size := len(c.UserAgent); size > 256 {
+		err = ssz.ErrBytesLengthFn("ChainState.UserAgent", size, 256)
+		return
+	}
+	dst = append(dst, c.UserAgent...)
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the ChainState object
+func (c *ChainState) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size < 61 {
+		return ssz.ErrSize
+	}
+
+	tail := buf
+	var o5, o6 uint64
+
+	// Field (0) 'GenesisHash'
+	if c.GenesisHash == nil {
+		c.GenesisHash = new(Hash)
+	}
+	if err = c.GenesisHash.UnmarshalSSZ(buf[0:32]); err != nil {
+		return err
+	}
+
+	// Field (1) 'ProtocolVersion'
+	c.ProtocolVersion = ssz.UnmarshallUint32(buf[32:36])
+
+	// Field (2) 'Timestamp'
+	c.Timestamp = ssz.UnmarshallUint64(buf[36:44])
+
+	// Field (3) 'Services'
+	c.Services = ssz.UnmarshallUint64(buf[44:52])
+
+	// Field (4) 'DisableRelayTx'
+	c.DisableRelayTx = ssz.UnmarshalBool(buf[52:53])
+
+	// Offset (5) 'GraphState'
+	if o5 = ssz.ReadOffset(buf[53:57]); o5 > size {
+		return ssz.ErrOffset
+	}
+
+	if o5 < 61 {
+		return ssz.ErrInvalidVariableOffset
+	}
+
+	// Offset (6) 'UserAgent'
+	if o6 = ssz.ReadOffset(buf[57:61]); o6 > size || o5 > o6 {
+		return ssz.ErrOffset
+	}
+
+	// Field (5) 'GraphState'
+	{
+		buf = tail[o5:o6]
+		if c.GraphState == nil {
+			c.GraphState = new(GraphState)
+		}
+		if err = c.GraphState.UnmarshalSSZ(buf); err != nil {
+			return err
+		}
+	}
+
+	// Field (6) 'UserAgent'
+	{
+		buf = tail[o6:]
+		if len(buf) > 256 {
+			return ssz.ErrBytesLength
+		}
+		if cap(c.UserAgent) == 0 {
+			c.UserAgent = make([]byte, 0, len(buf))
+		}
+		c.UserAgent = append(c.UserAgent, buf...)
+	}
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the ChainState object
+func (c *ChainState) SizeSSZ() (size int) {
+	size = 61
+
+	// Field (5) 'GraphState'
+	if c.GraphState == nil {
+		c.GraphState = new(GraphState)
+	}
+	size += c.GraphState.SizeSSZ()
+
+	// Field (6) 'UserAgent'
+	size += len(c.UserAgent)
+
+	return
+}
+
+// HashTreeRoot ssz hashes the ChainState object
+func (c *ChainState) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(c)
+}
+
+// HashTreeRootWith ssz hashes the ChainState object with a hasher
+func (c *ChainState) HashTreeRootWith(hh ssz.HashWalker) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'GenesisHash'
+	if c.GenesisHash == nil {
+		c.GenesisHash = new(Hash)
+	}
+	if err = c.GenesisHash.HashTreeRootWith(hh); err != nil {
+		return
+	}
+
+	// Field (1) 'ProtocolVersion'
+	hh.PutUint32(c.ProtocolVersion)
+
+	// Field (2) 'Timestamp'
+	hh.PutUint64(c.Timestamp)
+
+	// Field (3) 'Services'
+	hh.PutUint64(c.Services)
+
+	// Field (4) 'DisableRelayTx'
+	hh.PutBool(c.DisableRelayTx)
+
+	// Field (5) 'GraphState'
+	if err = c.GraphState.HashTreeRootWith(hh); err != nil {
+		return
+	}
+
+	// Field (6) 'UserAgent'
+	{
+		elemIndx := hh.Index()
+		byteLen := uint64(len(c.UserAgent))
+		if byteLen > 256 {
+			err = ssz.ErrIncorrectListSize
+			return
+		}
+		hh.PutBytes(c.UserAgent)
+		hh.MerkleizeWithMixin(elemIndx, byteLen, (256+31)/32)
+	}
+
+	hh.Merkleize(indx)
+	return
+}
+
+// GetTree ssz hashes the ChainState object
+func (c *ChainState) GetTree() (*ssz.Node, error) {
+	return ssz.ProofTree(c)
+}
+
+// MarshalSSZ ssz marshals the CompactBlock object
+func (c *CompactBlock) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(c)
+}
+
+// MarshalSSZTo ssz marshals the CompactBlock object to a target array
+func (c *CompactBlock) MarshalSSZTo(buf []byte) (dst []byte, err error) {
 	dst = buf
-	offset := int(61)
+	offset := int(24)
 
-	// Field (0) 'GenesisHash'
-	if c.GenesisHash == nil {
-		c.GenesisHash = new(Hash)
+	// Offset (0) 'Header'
+	dst = ssz.WriteOffset(dst, offset)
+	offset += len(c.Header)
+
+	// Offset (1) 'Parents'
+	dst = ssz.WriteOffset(dst, offset)
+	offset += len(c.Parents) * 32
+
+	// Field (2) 'Nonce'
+	dst = ssz.MarshalUint64(dst, c.Nonce)
+
+	// Offset (3) 'ShortIDs'
+	dst = ssz.WriteOffset(dst, offset)
+	offset += len(c.ShortIDs)
+
+	// Offset (4) 'PrefilledTxs'
+	dst = ssz.WriteOffset(dst, offset)
+	for ii := 0; ii < len(c.PrefilledTxs); ii++ {
+		offset += 4
+		offset += c.PrefilledTxs[ii].SizeSSZ()
 	}
-	if dst, err = c.GenesisHash.MarshalSSZTo(dst); err != nil {
+
+	// Field (0) 'Header'
+	if size := len(c.Header); size > 512 {
+		err = ssz.ErrBytesLengthFn("CompactBlock.Header", size, 512)
+		return
+	}
+	dst = append(dst, c.Header...)
+
+	// Field (1) 'Parents'
+	if size := len(c.Parents); size > 50 {
+		err = ssz.ErrListTooBigFn("CompactBlock.Parents", size, 50)
+		return
+	}
+	for ii := 0; ii < len(c.Parents); ii++ {
+		if dst, err = c.Parents[ii].MarshalSSZTo(dst); err != nil {
+			return
+		}
+	}
+
+	// Field (3) 'ShortIDs'
+	if size := len(c.ShortIDs); size > 1048576 {
+		err = ssz.ErrBytesLengthFn("CompactBlock.ShortIDs", size, 1048576)
+		return
+	}
+	dst = append(dst, c.ShortIDs...)
+
+	// Field (4) 'PrefilledTxs'
+	if size := len(c.PrefilledTxs); size > 20000 {
+		err = ssz.ErrListTooBigFn("CompactBlock.PrefilledTxs", size, 20000)
+		return
+	}
+	{
+		offset = 4 * len(c.PrefilledTxs)
+		for ii := 0; ii < len(c.PrefilledTxs); ii++ {
+			dst = ssz.WriteOffset(dst, offset)
+			offset += c.PrefilledTxs[ii].SizeSSZ()
+		}
+	}
+	for ii := 0; ii < len(c.PrefilledTxs); ii++ {
+		if dst, err = c.PrefilledTxs[ii].MarshalSSZTo(dst); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the CompactBlock object
+func (c *CompactBlock) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size < 24 {
+		return ssz.ErrSize
+	}
+
+	tail := buf
+	var o0, o1, o3, o4 uint64
+
+	// Offset (0) 'Header'
+	if o0 = ssz.ReadOffset(buf[0:4]); o0 > size {
+		return ssz.ErrOffset
+	}
+
+	if o0 < 24 {
+		return ssz.ErrInvalidVariableOffset
+	}
+
+	// Offset (1) 'Parents'
+	if o1 = ssz.ReadOffset(buf[4:8]); o1 > size || o0 > o1 {
+		return ssz.ErrOffset
+	}
+
+	// Field (2) 'Nonce'
+	c.Nonce = ssz.UnmarshallUint64(buf[8:16])
+
+	// Offset (3) 'ShortIDs'
+	if o3 = ssz.ReadOffset(buf[16:20]); o3 > size || o1 > o3 {
+		return ssz.ErrOffset
+	}
+
+	// Offset (4) 'PrefilledTxs'
+	if o4 = ssz.ReadOffset(buf[20:24]); o4 > size || o3 > o4 {
+		return ssz.ErrOffset
+	}
+
+	// Field (0) 'Header'
+	{
+		buf = tail[o0:o1]
+		if len(buf) > 512 {
+			return ssz.ErrBytesLength
+		}
+		if cap(c.Header) == 0 {
+			c.Header = make([]byte, 0, len(buf))
+		}
+		c.Header = append(c.Header, buf...)
+	}
+
+	// Field (1) 'Parents'
+	{
+		buf = tail[o1:o3]
+		num, err := ssz.DivideInt2(len(buf), 32, 50)
+		if err != nil {
+			return err
+		}
+		c.Parents = make([]*Hash, num)
+		for ii := 0; ii < num; ii++ {
+			if c.Parents[ii] == nil {
+				c.Parents[ii] = new(Hash)
+			}
+			if err = c.Parents[ii].UnmarshalSSZ(buf[ii*32 : (ii+1)*32]); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Field (3) 'ShortIDs'
+	{
+		buf = tail[o3:o4]
+		if len(buf) > 1048576 {
+			return ssz.ErrBytesLength
+		}
+		if cap(c.ShortIDs) == 0 {
+			c.ShortIDs = make([]byte, 0, len(buf))
+		}
+		c.ShortIDs = append(c.ShortIDs, buf...)
+	}
+
+	// Field (4) 'PrefilledTxs'
+	{
+		buf = tail[o4:]
+		num, err := ssz.DecodeDynamicLength(buf, 20000)
+		if err != nil {
+			return err
+		}
+		c.PrefilledTxs = make([]*PrefilledTx, num)
+		err = ssz.UnmarshalDynamic(buf, num, func(indx int, buf []byte) (err error) {
+			if c.PrefilledTxs[indx] == nil {
+				c.PrefilledTxs[indx] = new(PrefilledTx)
+			}
+			if err = c.PrefilledTxs[indx].UnmarshalSSZ(buf); err != nil {
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the CompactBlock object
+func (c *CompactBlock) SizeSSZ() (size int) {
+	size = 24
+
+	// Field (0) 'Header'
+	size += len(c.Header)
+
+	// Field (1) 'Parents'
+	size += len(c.Parents) * 32
+
+	// Field (3) 'ShortIDs'
+	size += len(c.ShortIDs)
+
+	// Field (4) 'PrefilledTxs'
+	for ii := 0; ii < len(c.PrefilledTxs); ii++ {
+		size += 4
+		size += c.PrefilledTxs[ii].SizeSSZ()
+	}
+
+	return
+}
+
+// HashTreeRoot ssz hashes the CompactBlock object
+func (c *CompactBlock) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(c)
+}
+
+// HashTreeRootWith ssz hashes the CompactBlock object with a hasher
+func (c *CompactBlock) HashTreeRootWith(hh ssz.HashWalker) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'Header'
+	{
+		elemIndx := hh.Index()
+		byteLen := uint64(len(c.Header))
+		if byteLen > 512 {
+			err = ssz.ErrIncorrectListSize
+			return
+		}
+		hh.PutBytes(c.Header)
+		hh.MerkleizeWithMixin(elemIndx, byteLen, (512+31)/32)
+	}
+
+	// Field (1) 'Parents'
+	{
+		subIndx := hh.Index()
+		num := uint64(len(c.Parents))
+		if num > 50 {
+			err = ssz.ErrIncorrectListSize
+			return
+		}
+		for _, elem := range c.Parents {
+			if err = elem.HashTreeRootWith(hh); err != nil {
+				return
+			}
+		}
+		hh.MerkleizeWithMixin(subIndx, num, 50)
+	}
+
+	// Field (2) 'Nonce'
+	hh.PutUint64(c.Nonce)
+
+	// Field (3) 'ShortIDs'
+	{
+		elemIndx := hh.Index()
+		byteLen := uint64(len(c.ShortIDs))
+		if byteLen > 1048576 {
+			err = ssz.ErrIncorrectListSize
+			return
+		}
+		hh.PutBytes(c.ShortIDs)
+		hh.MerkleizeWithMixin(elemIndx, byteLen, (1048576+31)/32)
+	}
+
+	// Field (4) 'PrefilledTxs'
+	{
+		subIndx := hh.Index()
+		num := uint64(len(c.PrefilledTxs))
+		if num > 20000 {
+			err = ssz.ErrIncorrectListSize
+			return
+		}
+		for _, elem := range c.PrefilledTxs {
+			if err = elem.HashTreeRootWith(hh); err != nil {
+				return
+			}
+		}
+		hh.MerkleizeWithMixin(subIndx, num, 20000)
+	}
+
+	hh.Merkleize(indx)
+	return
+}
+
+// GetTree ssz hashes the CompactBlock object
+func (c *CompactBlock) GetTree() (*ssz.Node, error) {
+	return ssz.ProofTree(c)
+}
+
+// MarshalSSZ ssz marshals the PrefilledTx object
+func (p *PrefilledTx) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(p)
+}
+
+// MarshalSSZTo ssz marshals the PrefilledTx object to a target array
+func (p *PrefilledTx) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+	offset := int(8)
+
+	// Field (0) 'Index'
+	dst = ssz.MarshalUint32(dst, p.Index)
+
+	// Offset (1) 'Tx'
+	dst = ssz.WriteOffset(dst, offset)
+	if p.Tx == nil {
+		p.Tx = new(Transaction)
+	}
+	offset += p.Tx.SizeSSZ()
+
+	// Field (1) 'Tx'
+	if dst, err = p.Tx.MarshalSSZTo(dst); err != nil {
+		return
+	}
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the PrefilledTx object
+func (p *PrefilledTx) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size < 8 {
+		return ssz.ErrSize
+	}
+
+	tail := buf
+	var o1 uint64
+
+	// Field (0) 'Index'
+	p.Index = ssz.UnmarshallUint32(buf[0:4])
+
+	// Offset (1) 'Tx'
+	if o1 = ssz.ReadOffset(buf[4:8]); o1 > size {
+		return ssz.ErrOffset
+	}
+
+	if o1 < 8 {
+		return ssz.ErrInvalidVariableOffset
+	}
+
+	// Field (1) 'Tx'
+	{
+		buf = tail[o1:]
+		if p.Tx == nil {
+			p.Tx = new(Transaction)
+		}
+		if err = p.Tx.UnmarshalSSZ(buf); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the PrefilledTx object
+func (p *PrefilledTx) SizeSSZ() (size int) {
+	size = 8
+
+	// Field (1) 'Tx'
+	if p.Tx == nil {
+		p.Tx = new(Transaction)
+	}
+	size += p.Tx.SizeSSZ()
+
+	return
+}
+
+// HashTreeRoot ssz hashes the PrefilledTx object
+func (p *PrefilledTx) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(p)
+}
+
+// HashTreeRootWith ssz hashes the PrefilledTx object with a hasher
+func (p *PrefilledTx) HashTreeRootWith(hh ssz.HashWalker) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'Index'
+	hh.PutUint32(p.Index)
+
+	// Field (1) 'Tx'
+	if err = p.Tx.HashTreeRootWith(hh); err != nil {
 		return
 	}
 
-	// Field (1) 'ProtocolVersion'
-	dst = ssz.MarshalUint32(dst, c.ProtocolVersion)
+	hh.Merkleize(indx)
+	return
+}
 
-	// Field (2) 'Timestamp'
-	dst = ssz.MarshalUint64(dst, c.Timestamp)
+// GetTree ssz hashes the PrefilledTx object
+func (p *PrefilledTx) GetTree() (*ssz.Node, error) {
+	return ssz.ProofTree(p)
+}
 
-	// Field (3) 'Services'
-	dst = ssz.MarshalUint64(dst, c.Services)
+// MarshalSSZ ssz marshals the GetBlockTxs object
+func (g *GetBlockTxs) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(g)
+}
 
-	// Field (4) 'DisableRelayTx'
-	dst = ssz.MarshalBool(dst, c.DisableRelayTx)
+// MarshalSSZTo ssz marshals the GetBlockTxs object to a target array
+func (g *GetBlockTxs) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+	offset := int(36)
 
-	// Offset (5) 'GraphState'
-	dst = ssz.WriteOffset(dst, offset)
-	if c.GraphState == nil {
-		c.GraphState = new(GraphState)
+	// Field (0) 'BlockHash'
+	if g.BlockHash == nil {
+		g.BlockHash = new(Hash)
+	}
+	if dst, err = g.BlockHash.MarshalSSZTo(dst); err != nil {
+		return
 	}
-	offset += c.GraphState.SizeSSZ()
 
-	// Offset (6) 'UserAgent'
+	// Offset (1) 'Indexes'
 	dst = ssz.WriteOffset(dst, offset)
-	offset += len(c.UserAgent)
+	offset += len(g.Indexes) * 8
 
-	// Field (5) 'GraphState'
-	if dst, err = c.GraphState.MarshalSSZTo(dst); err != nil {
+	// Field (1) 'Indexes'
+	if size := len(g.Indexes); size > 20000 {
+		err = ssz.ErrListTooBigFn("GetBlockTxs.Indexes", size, 20000)
 		return
 	}
-
-	// Field (6) 'UserAgent'
-	if size := len(c.UserAgent); size > 256 {
-		err = ssz.ErrBytesLengthFn("ChainState.UserAgent", size, 256)
-		return
+	for ii := 0; ii < len(g.Indexes); ii++ {
+		dst = ssz.MarshalUint64(dst, g.Indexes[ii])
 	}
-	dst = append(dst, c.UserAgent...)
 
 	return
 }
 
-// UnmarshalSSZ ssz unmarshals the ChainState object
-func (c *ChainState) UnmarshalSSZ(buf []byte) error {
+// UnmarshalSSZ ssz unmarshals the GetBlockTxs object
+func (g *GetBlockTxs) UnmarshalSSZ(buf []byte) error {
 	var err error
 	size := uint64(len(buf))
-	if size < 61 {
+	if size < 36 {
 		return ssz.ErrSize
 	}
 
 	tail := buf
-	var o5, o6 uint64
+	var o1 uint64
 
-	// Field (0) 'GenesisHash'
-	if c.GenesisHash == nil {
-		c.GenesisHash = new(Hash)
+	// Field (0) 'BlockHash'
+	if g.BlockHash == nil {
+		g.BlockHash = new(Hash)
 	}
-	if err = c.GenesisHash.UnmarshalSSZ(buf[0:32]); err != nil {
+	if err = g.BlockHash.UnmarshalSSZ(buf[0:32]); err != nil {
 		return err
 	}
 
-	// Field (1) 'ProtocolVersion'
-	c.ProtocolVersion = ssz.UnmarshallUint32(buf[32:36])
-
-	// Field (2) 'Timestamp'
-	c.Timestamp = ssz.UnmarshallUint64(buf[36:44])
-
-	// Field (3) 'Services'
-	c.Services = ssz.UnmarshallUint64(buf[44:52])
-
-	// Field (4) 'DisableRelayTx'
-	c.DisableRelayTx = ssz.UnmarshalBool(buf[52:53])
-
-	// Offset (5) 'GraphState'
-	if o5 = ssz.ReadOffset(buf[53:57]); o5 > size {
+	// Offset (1) 'Indexes'
+	if o1 = ssz.ReadOffset(buf[32:36]); o1 > size {
 		return ssz.ErrOffset
 	}
 
-	if o5 < 61 {
+	if o1 < 36 {
 		return ssz.ErrInvalidVariableOffset
 	}
 
-	// Offset (6) 'UserAgent'
-	if o6 = ssz.ReadOffset(buf[57:61]); o6 > size || o5 > o6 {
-		return ssz.ErrOffset
-	}
-
-	// Field (5) 'GraphState'
+	// Field (1) 'Indexes'
 	{
-		buf = tail[o5:o6]
-		if c.GraphState == nil {
-			c.GraphState = new(GraphState)
-		}
-		if err = c.GraphState.UnmarshalSSZ(buf); err != nil {
+		buf = tail[o1:]
+		num, err := ssz.DivideInt2(len(buf), 8, 20000)
+		if err != nil {
 			return err
 		}
+		g.Indexes = ssz.ExtendUint64(g.Indexes, num)
+		for ii := 0; ii < num; ii++ {
+			g.Indexes[ii] = ssz.UnmarshallUint64(buf[ii*8 : (ii+1)*8])
+		}
 	}
+	return err
+}
 
-	// Field (6) 'UserAgent'
+// SizeSSZ returns the ssz encoded size in bytes for the GetBlockTxs object
+func (g *GetBlockTxs) SizeSSZ() (size int) {
+	size = 36
+
+	// Field (1) 'Indexes'
+	size += len(g.Indexes) * 8
+
+	return
+}
+
+// HashTreeRoot ssz hashes the GetBlockTxs object
+func (g *GetBlockTxs) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(g)
+}
+
+// HashTreeRootWith ssz hashes the GetBlockTxs object with a hasher
+func (g *GetBlockTxs) HashTreeRootWith(hh ssz.HashWalker) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'BlockHash'
+	if g.BlockHash == nil {
+		g.BlockHash = new(Hash)
+	}
+	if err = g.BlockHash.HashTreeRootWith(hh); err != nil {
+		return
+	}
+
+	// Field (1) 'Indexes'
 	{
-		buf = tail[o6:]
-		if len(buf) > 256 {
-			return ssz.ErrBytesLength
+		if size := len(g.Indexes); size > 20000 {
+			err = ssz.ErrListTooBigFn("GetBlockTxs.Indexes", size, 20000)
+			return
 		}
-		if cap(c.UserAgent) == 0 {
-			c.UserAgent = make([]byte, 0, len(buf))
+		subIndx := hh.Index()
+		for _, i := range g.Indexes {
+			hh.AppendUint64(i)
 		}
-		c.UserAgent = append(c.UserAgent, buf...)
+		hh.FillUpTo32()
+		numItems := uint64(len(g.Indexes))
+		hh.MerkleizeWithMixin(subIndx, numItems, ssz.CalculateLimit(20000, numItems, 8))
 	}
-	return err
+
+	hh.Merkleize(indx)
+	return
 }
 
-// SizeSSZ returns the ssz encoded size in bytes for the ChainState object
-func (c *ChainState) SizeSSZ() (size int) {
-	size = 61
+// GetTree ssz hashes the GetBlockTxs object
+func (g *GetBlockTxs) GetTree() (*ssz.Node, error) {
+	return ssz.ProofTree(g)
+}
 
-	// Field (5) 'GraphState'
-	if c.GraphState == nil {
-		c.GraphState = new(GraphState)
+// MarshalSSZ ssz marshals the BlockTxs object
+func (b *BlockTxs) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(b)
+}
+
+// MarshalSSZTo ssz marshals the BlockTxs object to a target array
+func (b *BlockTxs) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+	offset := int(36)
+
+	// Field (0) 'BlockHash'
+	if b.BlockHash == nil {
+		b.BlockHash = new(Hash)
+	}
+	if dst, err = b.BlockHash.MarshalSSZTo(dst); err != nil {
+		return
 	}
-	size += c.GraphState.SizeSSZ()
 
-	// Field (6) 'UserAgent'
-	size += len(c.UserAgent)
+	// Offset (1) 'Txs'
+	dst = ssz.WriteOffset(dst, offset)
+	for ii := 0; ii < len(b.Txs); ii++ {
+		offset += 4
+		offset += b.Txs[ii].SizeSSZ()
+	}
+
+	// Field (1) 'Txs'
+	if size := len(b.Txs); size > 20000 {
+		err = ssz.ErrListTooBigFn("BlockTxs.Txs", size, 20000)
+		return
+	}
+	{
+		offset = 4 * len(b.Txs)
+		for ii := 0; ii < len(b.Txs); ii++ {
+			dst = ssz.WriteOffset(dst, offset)
+			offset += b.Txs[ii].SizeSSZ()
+		}
+	}
+	for ii := 0; ii < len(b.Txs); ii++ {
+		if dst, err = b.Txs[ii].MarshalSSZTo(dst); err != nil {
+			return
+		}
+	}
 
 	return
 }
 
-// HashTreeRoot ssz hashes the ChainState object
-func (c *ChainState) HashTreeRoot() ([32]byte, error) {
-	return ssz.HashWithDefaultHasher(c)
-}
+// UnmarshalSSZ ssz unmarshals the BlockTxs object
+func (b *BlockTxs) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size < 36 {
+		return ssz.ErrSize
+	}
 
-// HashTreeRootWith ssz hashes the ChainState object with a hasher
-func (c *ChainState) HashTreeRootWith(hh ssz.HashWalker) (err error) {
-	indx := hh.Index()
+	tail := buf
+	var o1 uint64
 
-	// Field (0) 'GenesisHash'
-	if c.GenesisHash == nil {
-		c.GenesisHash = new(Hash)
+	// Field (0) 'BlockHash'
+	if b.BlockHash == nil {
+		b.BlockHash = new(Hash)
 	}
-	if err = c.GenesisHash.HashTreeRootWith(hh); err != nil {
-		return
+	if err = b.BlockHash.UnmarshalSSZ(buf[0:32]); err != nil {
+		return err
 	}
 
-	// Field (1) 'ProtocolVersion'
-	hh.PutUint32(c.ProtocolVersion)
+	// Offset (1) 'Txs'
+	if o1 = ssz.ReadOffset(buf[32:36]); o1 > size {
+		return ssz.ErrOffset
+	}
 
-	// Field (2) 'Timestamp'
-	hh.PutUint64(c.Timestamp)
+	if o1 < 36 {
+		return ssz.ErrInvalidVariableOffset
+	}
 
-	// Field (3) 'Services'
-	hh.PutUint64(c.Services)
+	// Field (1) 'Txs'
+	{
+		buf = tail[o1:]
+		num, err := ssz.DecodeDynamicLength(buf, 20000)
+		if err != nil {
+			return err
+		}
+		b.Txs = make([]*Transaction, num)
+		err = ssz.UnmarshalDynamic(buf, num, func(indx int, buf []byte) (err error) {
+			if b.Txs[indx] == nil {
+				b.Txs[indx] = new(Transaction)
+			}
+			if err = b.Txs[indx].UnmarshalSSZ(buf); err != nil {
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return err
+}
 
-	// Field (4) 'DisableRelayTx'
-	hh.PutBool(c.DisableRelayTx)
+// SizeSSZ returns the ssz encoded size in bytes for the BlockTxs object
+func (b *BlockTxs) SizeSSZ() (size int) {
+	size = 36
 
-	// Field (5) 'GraphState'
-	if err = c.GraphState.HashTreeRootWith(hh); err != nil {
+	// Field (1) 'Txs'
+	for ii := 0; ii < len(b.Txs); ii++ {
+		size += 4
+		size += b.Txs[ii].SizeSSZ()
+	}
+
+	return
+}
+
+// HashTreeRoot ssz hashes the BlockTxs object
+func (b *BlockTxs) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(b)
+}
+
+// HashTreeRootWith ssz hashes the BlockTxs object with a hasher
+func (b *BlockTxs) HashTreeRootWith(hh ssz.HashWalker) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'BlockHash'
+	if b.BlockHash == nil {
+		b.BlockHash = new(Hash)
+	}
+	if err = b.BlockHash.HashTreeRootWith(hh); err != nil {
 		return
 	}
 
-	// Field (6) 'UserAgent'
+	// Field (1) 'Txs'
 	{
-		elemIndx := hh.Index()
-		byteLen := uint64(len(c.UserAgent))
-		if byteLen > 256 {
+		subIndx := hh.Index()
+		num := uint64(len(b.Txs))
+		if num > 20000 {
 			err = ssz.ErrIncorrectListSize
 			return
 		}
-		hh.PutBytes(c.UserAgent)
-		hh.MerkleizeWithMixin(elemIndx, byteLen, (256+31)/32)
+		for _, elem := range b.Txs {
+			if err = elem.HashTreeRootWith(hh); err != nil {
+				return
+			}
+		}
+		hh.MerkleizeWithMixin(subIndx, num, 20000)
 	}
 
 	hh.Merkleize(indx)
 	return
 }
 
-// GetTree ssz hashes the ChainState object
-func (c *ChainState) GetTree() (*ssz.Node, error) {
-	return ssz.ProofTree(c)
+// GetTree ssz hashes the BlockTxs object
+func (b *BlockTxs) GetTree() (*ssz.Node, error) {
+	return ssz.ProofTree(b)
 }
 
 // MarshalSSZ ssz marshals the FilterAddRequest object
@@ -1703,7 +2975,7 @@ func (m *MerkleBlock) MarshalSSZ() ([]byte, error) {
 // MarshalSSZTo ssz marshals the MerkleBlock object to a target array
 func (m *MerkleBlock) MarshalSSZTo(buf []byte) (dst []byte, err error) {
 	dst = buf
-	offset := int(20)
+	offset := int(24)
 
 	// Offset (0) 'Header'
 	dst = ssz.WriteOffset(dst, offset)
@@ -1720,6 +2992,10 @@ func (m *MerkleBlock) MarshalSSZTo(buf []byte) (dst []byte, err error) {
 	dst = ssz.WriteOffset(dst, offset)
 	offset += len(m.Flags)
 
+	// Offset (4) 'Parents'
+	dst = ssz.WriteOffset(dst, offset)
+	offset += len(m.Parents) * 32
+
 	// Field (0) 'Header'
 	if size := len(m.Header); size > 256 {
 		err = ssz.ErrBytesLengthFn("MerkleBlock.Header", size, 256)
@@ -1745,6 +3021,17 @@ func (m *MerkleBlock) MarshalSSZTo(buf []byte) (dst []byte, err error) {
 	}
 	dst = append(dst, m.Flags...)
 
+	// Field (4) 'Parents'
+	if size := len(m.Parents); size > 50 {
+		err = ssz.ErrListTooBigFn("MerkleBlock.Parents", size, 50)
+		return
+	}
+	for ii := 0; ii < len(m.Parents); ii++ {
+		if dst, err = m.Parents[ii].MarshalSSZTo(dst); err != nil {
+			return
+		}
+	}
+
 	return
 }
 
@@ -1752,19 +3039,19 @@ func (m *MerkleBlock) MarshalSSZTo(buf []byte) (dst []byte, err error) {
 func (m *MerkleBlock) UnmarshalSSZ(buf []byte) error {
 	var err error
 	size := uint64(len(buf))
-	if size < 20 {
+	if size < 24 {
 		return ssz.ErrSize
 	}
 
 	tail := buf
-	var o0, o2, o3 uint64
+	var o0, o2, o3, o4 uint64
 
 	// Offset (0) 'Header'
 	if o0 = ssz.ReadOffset(buf[0:4]); o0 > size {
 		return ssz.ErrOffset
 	}
 
-	if o0 < 20 {
+	if o0 < 24 {
 		return ssz.ErrInvalidVariableOffset
 	}
 
@@ -1781,6 +3068,11 @@ func (m *MerkleBlock) UnmarshalSSZ(buf []byte) error {
 		return ssz.ErrOffset
 	}
 
+	// Offset (4) 'Parents'
+	if o4 = ssz.ReadOffset(buf[20:24]); o4 > size || o3 > o4 {
+		return ssz.ErrOffset
+	}
+
 	// Field (0) 'Header'
 	{
 		buf = tail[o0:o2]
@@ -1813,7 +3105,7 @@ func (m *MerkleBlock) UnmarshalSSZ(buf []byte) error {
 
 	// Field (3) 'Flags'
 	{
-		buf = tail[o3:]
+		buf = tail[o3:o4]
 		if len(buf) > 256 {
 			return ssz.ErrBytesLength
 		}
@@ -1822,12 +3114,30 @@ func (m *MerkleBlock) UnmarshalSSZ(buf []byte) error {
 		}
 		m.Flags = append(m.Flags, buf...)
 	}
+
+	// Field (4) 'Parents'
+	{
+		buf = tail[o4:]
+		num, err := ssz.DivideInt2(len(buf), 32, 50)
+		if err != nil {
+			return err
+		}
+		m.Parents = make([]*Hash, num)
+		for ii := 0; ii < num; ii++ {
+			if m.Parents[ii] == nil {
+				m.Parents[ii] = new(Hash)
+			}
+			if err = m.Parents[ii].UnmarshalSSZ(buf[ii*32 : (ii+1)*32]); err != nil {
+				return err
+			}
+		}
+	}
 	return err
 }
 
 // SizeSSZ returns the ssz encoded size in bytes for the MerkleBlock object
 func (m *MerkleBlock) SizeSSZ() (size int) {
-	size = 20
+	size = 24
 
 	// Field (0) 'Header'
 	size += len(m.Header)
@@ -1838,6 +3148,9 @@ func (m *MerkleBlock) SizeSSZ() (size int) {
 	// Field (3) 'Flags'
 	size += len(m.Flags)
 
+	// Field (4) 'Parents'
+	size += len(m.Parents) * 32
+
 	return
 }
 
@@ -1893,6 +3206,22 @@ func (m *MerkleBlock) HashTreeRootWith(hh ssz.HashWalker) (err error) {
 		hh.MerkleizeWithMixin(elemIndx, byteLen, (256+31)/32)
 	}
 
+	// Field (4) 'Parents'
+	{
+		subIndx := hh.Index()
+		num := uint64(len(m.Parents))
+		if num > 50 {
+			err = ssz.ErrIncorrectListSize
+			return
+		}
+		for _, elem := range m.Parents {
+			if err = elem.HashTreeRootWith(hh); err != nil {
+				return
+			}
+		}
+		hh.MerkleizeWithMixin(subIndx, num, 50)
+	}
+
 	hh.Merkleize(indx)
 	return
 }
@@ -126,7 +126,7 @@ out:
 				nds = append(nds, &notify.NotifyData{Data: data})
 			}
 			if len(nds) > 0 {
-				r.s.RelayInventory(nds)
+				r.s.sy.FluffInventory(nds)
 			}
 
 			rt := int64(len(pendingInvs)/50) * int64(params.ActiveNetParams.TargetTimePerBlock)
@@ -48,7 +48,7 @@ import (
 
 const (
 	// the default services supported by the node
-	defaultServices = pv.Full | pv.CF | pv.Bloom
+	defaultServices = pv.Full | pv.Bloom
 )
 
 var (
@@ -95,6 +95,13 @@ func (s *Service) Start() error {
 	}
 	log.Info("P2P Service Start")
 
+	if s.BlockChain() != nil {
+		err := s.Peers().SetBanStore(s.BlockChain().DB())
+		if err != nil {
+			log.Warn(fmt.Sprintf("Failed to load bans:%v", err))
+		}
+	}
+
 	err := s.sy.Start()
 	if err != nil {
 		return err
@@ -183,6 +190,9 @@ func (s *Service) Stop() error {
 	}
 
 	s.rebroadcast.Stop()
+	if err := s.Peers().SaveBans(); err != nil {
+		log.Warn(fmt.Sprintf("Failed to save bans:%v", err))
+	}
 	return s.sy.Stop()
 }
 
@@ -473,7 +483,16 @@ func (s *Service) PeerSync() *synch.PeerSync {
 }
 
 func (s *Service) RelayInventory(nds []*notify.NotifyData) {
-	s.PeerSync().RelayInventory(nds)
+	s.sy.FluffInventory(nds)
+}
+
+// StemTransaction passes the own transaction to Dandelion++ stem, it returns
+// false if Dandelion++ is disabled or the transaction is already in stem phase.
+func (s *Service) StemTransaction(tx *types.Tx) bool {
+	if !s.cfg.Dandelion {
+		return false
+	}
+	return s.sy.StemTransaction(tx)
 }
 
 func (s *Service) BroadcastMessage(data interface{}) {
@@ -481,6 +500,18 @@ func (s *Service) BroadcastMessage(data interface{}) {
 }
 
 func (s *Service) BroadcastBlock(block *types.SerializedBlock, source *peer.ID) error {
+	if s.cfg.GossipRelay() {
+		if err := s.sy.PublishBlock(block); err != nil {
+			log.Debug(fmt.Sprintf("Failed to publish block %s:%v", block.Hash().String(), err))
+		}
+	}
+	if !s.cfg.StreamRelay() {
+		return nil
+	}
+	compact, err := synch.NewCompactBlock(block)
+	if err != nil {
+		return err
+	}
 	for _, pe := range s.Peers().CanSyncPeers() {
 		if source != nil {
 			if pe.GetID() == *source {
@@ -490,6 +521,15 @@ func (s *Service) BroadcastBlock(block *types.SerializedBlock, source *peer.ID)
 		if pe.ChainState().ProtocolVersion < uint32(pv.BroadcastblockProtocolVersion) {
 			continue
 		}
+		// The peers reconstruct the block from their pools
+		if pe.ChainState().ProtocolVersion >= uint32(pv.CompactBlockProtocolVersion) {
+			go func(pe *peers.Peer) {
+				if _, err := s.sy.Send(pe, synch.RPCCompactBlock, compact); err != nil {
+					log.Debug(fmt.Sprintf("Failed to send compact block %s to %s:%v", block.Hash().String(), pe.GetID(), err))
+				}
+			}(pe)
+			continue
+		}
 		go func(pe *peers.Peer) {
 			blockBytes, err := block.Bytes()
 			if err != nil {
@@ -504,8 +544,8 @@ func (s *Service) BroadcastBlock(block *types.SerializedBlock, source *peer.ID)
 	return nil
 }
 
-func (s *Service) GetBanlist() map[peer.ID][]*json.BadResponse {
-	result := map[peer.ID][]*json.BadResponse{}
+func (s *Service) GetBanlist() []*json.GetBanlistResult {
+	result := []*json.GetBanlistResult{}
 	bads := s.Peers().Bad()
 	for _, bad := range bads {
 		pe := s.Peers().Get(bad)
@@ -521,20 +561,33 @@ func (s *Service) GetBanlist() map[peer.ID][]*json.BadResponse {
 			}
 			brs = append(brs, brj)
 		}
-		result[pe.GetID()] = brs
+		bl := &json.GetBanlistResult{PeerID: pe.GetID().String(), Bads: brs, Score: pe.Score()}
+		if pe.IsBanned() {
+			until, reason := pe.BannedUntil()
+			bl.BanUntil = until.String()
+			bl.Reason = reason
+		}
+		result = append(result, bl)
 	}
 	return result
 }
 
+// RemoveBan removes the ban of the peer, or all peers if id is empty.
 func (s *Service) RemoveBan(id string) {
 	bads := s.Peers().Bad()
 	for _, bad := range bads {
+		if len(id) > 0 && bad.String() != id {
+			continue
+		}
 		pe := s.Peers().Get(bad)
 		if pe == nil {
 			continue
 		}
 		pe.ResetBad()
 	}
+	if err := s.Peers().SaveBans(); err != nil {
+		log.Warn(fmt.Sprintf("Failed to save bans:%v", err))
+	}
 }
 
 func (s *Service) ConnectTo(node *qnode.Node) {
@@ -661,6 +714,27 @@ func NewService(cfg *config.Config, consensus model.Consensus, param *params.Par
 	if cfg.MaxBadResp > 0 {
 		peers.MaxBadResponses = cfg.MaxBadResp
 	}
+	if cfg.BanThreshold > 0 {
+		peers.BanThreshold = float64(cfg.BanThreshold)
+	}
+	if cfg.BanDuration > 0 {
+		peers.BanDuration = cfg.BanDuration
+	}
+	gossipMode := cfg.Gossip
+	if len(gossipMode) <= 0 {
+		gossipMode = common.GossipModeStream
+	}
+	if err := common.CheckGossipMode(gossipMode); err != nil {
+		return nil, err
+	}
+	services := defaultServices
+	// The pruning node can't serve the old blocks.
+	if len(blockchain.PruneDisabledReason(cfg)) <= 0 {
+		services = services&^pv.Full | pv.Pruned
+	}
+	if cfg.CFIndex {
+		services |= pv.CF
+	}
 	s := &Service{
 		cfg: &common.Config{
 			NoDiscovery:          cfg.NoDiscovery,
@@ -676,7 +750,7 @@ func NewService(cfg *config.Config, consensus model.Consensus, param *params.Par
 			UDPPort:              uint(cfg.P2PUDPPort),
 			Encoding:             "ssz-snappy",
 			ProtocolVersion:      pv.ProtocolVersion,
-			Services:             defaultServices,
+			Services:             services,
 			UserAgent:            BuildUserAgent("QNG"),
 			DisableRelayTx:       cfg.BlocksOnly,
 			MaxOrphanTxs:         cfg.MaxOrphanTxs,
@@ -691,6 +765,8 @@ func NewService(cfg *config.Config, consensus model.Consensus, param *params.Par
 			LANPeers:             lanPeers,
 			IsCircuit:            cfg.Circuit,
 			Consistency:          cfg.Consistency,
+			GossipMode:           gossipMode,
+			Dandelion:            cfg.Dandelion,
 		},
 		exclusionList: cache,
 		isPreGenesis:  true,
@@ -739,10 +815,14 @@ func NewService(cfg *config.Config, consensus model.Consensus, param *params.Par
 
 	s.cfg.BootstrapNodeAddr = filterBootStrapAddrs(s.host.ID().String(), s.cfg.BootstrapNodeAddr)
 
+	s.sy = synch.NewSync(s)
+
 	psOpts := []pubsub.Option{
 		pubsub.WithMessageSigning(false),
 		pubsub.WithStrictSignatureVerification(false),
 		pubsub.WithMessageIdFn(msgIDFunction),
+		pubsub.WithMaxMessageSize(synch.MaxGossipMessageSize),
+		pubsub.WithPeerScore(synch.GossipScoreParams(s.sy.Peers())),
 	}
 
 	gs, err := pubsub.NewGossipSub(s.Context(), s.host, psOpts...)
@@ -752,7 +832,6 @@ func NewService(cfg *config.Config, consensus model.Consensus, param *params.Par
 	}
 	s.pubsub = gs
 
-	s.sy = synch.NewSync(s)
 	s.rebroadcast = NewRebroadcast(s)
 	return s, nil
 }
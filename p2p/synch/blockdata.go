@@ -7,9 +7,12 @@ package synch
 import (
 	"github.com/Qitmeer/qng/common/hash"
 	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/p2p/peers"
 )
 
 type BlockData struct {
 	Hash  *hash.Hash
 	Block *types.SerializedBlock
+	// The peer which the block is received from
+	Peer *peers.Peer
 }
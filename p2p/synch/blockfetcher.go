@@ -0,0 +1,274 @@
+/*
+ * Copyright (c) 2017-2020 The qitmeer developers
+ */
+
+package synch
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/core/protocol"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/p2p/peers"
+	pb "github.com/Qitmeer/qng/p2p/proto/v1"
+)
+
+const (
+	// maxBlocksInFlightPerPeer is the number of blocks requested from one
+	// peer by one getblockdatas request.
+	maxBlocksInFlightPerPeer = 64
+
+	// blockFetchTimeout is the maximum time for one getblockdatas request,
+	// the blocks of the peer which times out are reassigned to the others.
+	blockFetchTimeout = ReqTimeout + RespTimeout
+)
+
+type fetchBlocksFunc func(pe *peers.Peer, blocks []*hash.Hash) ([]*types.SerializedBlock, error)
+
+type misbehaveFunc func(pe *peers.Peer, kind peers.Misbehavior, reason string)
+
+type fetchResult struct {
+	blocks []*types.SerializedBlock
+	err    error
+}
+
+// blockFetcher downloads the block bodies from several peers in parallel.
+// Every peer has a window of blocks in flight, the blocks which a peer
+// fails to return are reassigned to the other peers.
+type blockFetcher struct {
+	fetch     fetchBlocksFunc
+	misbehave misbehaveFunc
+	window    int
+	timeout   time.Duration
+	quit      <-chan struct{}
+
+	lock    sync.Mutex
+	cond    *sync.Cond
+	pending [][]*hash.Hash
+	wanted  map[hash.Hash]struct{}
+	blocks  map[hash.Hash]*BlockData
+	workers int
+}
+
+func newBlockFetcher(fetch fetchBlocksFunc, misbehave misbehaveFunc, window int, timeout time.Duration, quit <-chan struct{}) *blockFetcher {
+	bf := &blockFetcher{
+		fetch:     fetch,
+		misbehave: misbehave,
+		window:    window,
+		timeout:   timeout,
+		quit:      quit,
+	}
+	bf.cond = sync.NewCond(&bf.lock)
+	return bf
+}
+
+// Fetch downloads the blocks from the peers, and returns the blocks which
+// are received. It returns when all blocks are received, all peers fail or
+// quit is closed.
+func (bf *blockFetcher) Fetch(pes []*peers.Peer, blocks []*hash.Hash) map[hash.Hash]*BlockData {
+	bf.pending = nil
+	bf.wanted = make(map[hash.Hash]struct{}, len(blocks))
+	bf.blocks = make(map[hash.Hash]*BlockData, len(blocks))
+	for start := 0; start < len(blocks); start += bf.window {
+		end := start + bf.window
+		if end > len(blocks) {
+			end = len(blocks)
+		}
+		bf.pending = append(bf.pending, blocks[start:end])
+	}
+	for _, b := range blocks {
+		bf.wanted[*b] = struct{}{}
+	}
+	if len(pes) <= 0 || len(blocks) <= 0 {
+		return bf.blocks
+	}
+
+	bf.workers = len(pes)
+	var wg sync.WaitGroup
+	for _, pe := range pes {
+		wg.Add(1)
+		go func(pe *peers.Peer) {
+			defer wg.Done()
+			bf.worker(pe)
+		}(pe)
+	}
+	wg.Wait()
+	return bf.blocks
+}
+
+// next waits for the blocks which aren't assigned yet. It returns nil when
+// there is nothing left to do.
+func (bf *blockFetcher) next() []*hash.Hash {
+	bf.lock.Lock()
+	defer bf.lock.Unlock()
+
+	for len(bf.pending) <= 0 {
+		// The other workers may still give back their blocks
+		if len(bf.wanted) <= 0 || bf.workers <= 1 || bf.isQuit() {
+			return nil
+		}
+		bf.cond.Wait()
+	}
+	chunk := bf.pending[0]
+	bf.pending = bf.pending[1:]
+	return chunk
+}
+
+// done records the blocks received for the chunk, the blocks which are
+// missing are given back to the other workers. It returns the number of
+// blocks which aren't requested.
+func (bf *blockFetcher) done(pe *peers.Peer, chunk []*hash.Hash, blocks []*types.SerializedBlock) int {
+	bf.lock.Lock()
+	defer bf.lock.Unlock()
+
+	requested := make(map[hash.Hash]struct{}, len(chunk))
+	for _, h := range chunk {
+		requested[*h] = struct{}{}
+	}
+	unsolicited := 0
+	for _, block := range blocks {
+		h := block.Hash()
+		if _, ok := requested[*h]; !ok {
+			unsolicited++
+			continue
+		}
+		if _, ok := bf.wanted[*h]; !ok {
+			continue
+		}
+		delete(bf.wanted, *h)
+		bf.blocks[*h] = &BlockData{Hash: h, Block: block, Peer: pe}
+	}
+	missing := []*hash.Hash{}
+	for _, h := range chunk {
+		if _, ok := bf.wanted[*h]; ok {
+			missing = append(missing, h)
+		}
+	}
+	if len(missing) > 0 {
+		bf.pending = append(bf.pending, missing)
+	}
+	bf.cond.Broadcast()
+	return unsolicited
+}
+
+func (bf *blockFetcher) exit() {
+	bf.lock.Lock()
+	defer bf.lock.Unlock()
+
+	bf.workers--
+	bf.cond.Broadcast()
+}
+
+func (bf *blockFetcher) isQuit() bool {
+	select {
+	case <-bf.quit:
+		return true
+	default:
+		return false
+	}
+}
+
+func (bf *blockFetcher) report(pe *peers.Peer, kind peers.Misbehavior, reason string) {
+	if bf.misbehave != nil {
+		bf.misbehave(pe, kind, reason)
+	}
+}
+
+func (bf *blockFetcher) worker(pe *peers.Peer) {
+	defer bf.exit()
+
+	for {
+		chunk := bf.next()
+		if chunk == nil {
+			return
+		}
+		ret := make(chan *fetchResult, 1)
+		go func() {
+			blocks, err := bf.fetch(pe, chunk)
+			ret <- &fetchResult{blocks: blocks, err: err}
+		}()
+		timer := time.NewTimer(bf.timeout)
+		var result *fetchResult
+		select {
+		case result = <-ret:
+		case <-timer.C:
+			result = &fetchResult{err: fmt.Errorf("timeout")}
+			bf.report(pe, peers.MisbehaviorStall, "getblockdatas timeout")
+		case <-bf.quit:
+			result = &fetchResult{err: fmt.Errorf("quit")}
+		}
+		timer.Stop()
+
+		unsolicited := bf.done(pe, chunk, result.blocks)
+		if unsolicited > 0 {
+			bf.report(pe, peers.MisbehaviorUnsolicited, fmt.Sprintf("%d blocks aren't requested", unsolicited))
+		}
+		if result.err != nil {
+			log.Debug("Stop fetching blocks from peer", "peer", pe.GetID(), "blocks", len(chunk), "error", result.err)
+			return
+		}
+		// The peer returns nothing which means it has none of them
+		if len(result.blocks) <= 0 {
+			log.Debug("Peer has no blocks to fetch", "peer", pe.GetID(), "blocks", len(chunk))
+			return
+		}
+	}
+}
+
+// fetchBlockDatas requests the blocks from the peer by getblockdatas. The
+// response may only contain the front of blocks because of the chunk size.
+func (ps *PeerSync) fetchBlockDatas(pe *peers.Peer, blocks []*hash.Hash) ([]*types.SerializedBlock, error) {
+	if !pe.IsConnected() {
+		return nil, fmt.Errorf("peer is disconnected")
+	}
+	ret, err := ps.sy.Send(pe, RPCGetBlockDatas, &pb.GetBlockDatas{Locator: changeHashsToPBHashs(blocks)})
+	if err != nil {
+		return nil, err
+	}
+	bd := ret.(*pb.BlockDatas)
+	result := make([]*types.SerializedBlock, 0, len(bd.Locator))
+	for _, b := range bd.Locator {
+		block, err := types.NewBlockFromBytes(b.BlockBytes)
+		if err != nil {
+			return result, err
+		}
+		result = append(result, block)
+	}
+	return result, nil
+}
+
+// getFetchPeers returns the sync peer and the other peers which are able to
+// serve the block bodies of the sync peer.
+func (ps *PeerSync) getFetchPeers(syncPeer *peers.Peer) []*peers.Peer {
+	gs := syncPeer.GraphState()
+	result := []*peers.Peer{syncPeer}
+	for _, sp := range ps.sy.peers.CanSyncPeers() {
+		if sp.GetID() == syncPeer.GetID() || !sp.IsConnected() {
+			continue
+		}
+		if !protocol.HasServices(sp.Services(), protocol.Full) {
+			continue
+		}
+		spgs := sp.GraphState()
+		if spgs == nil || gs == nil {
+			continue
+		}
+		if !spgs.IsExcellent(gs) && !spgs.IsEqual(gs) {
+			continue
+		}
+		result = append(result, sp)
+	}
+	return result
+}
+
+// fetchBlocksParallel downloads the block bodies from all the peers which
+// are able to serve them.
+func (ps *PeerSync) fetchBlocksParallel(pe *peers.Peer, blocks []*hash.Hash) map[hash.Hash]*BlockData {
+	pes := ps.getFetchPeers(pe)
+	log.Trace(fmt.Sprintf("Fetch %d blocks from %d peers", len(blocks), len(pes)), "processID", ps.processID)
+	bf := newBlockFetcher(ps.fetchBlockDatas, ps.sy.Misbehave, maxBlocksInFlightPerPeer, blockFetchTimeout, ps.quit)
+	return bf.Fetch(pes, blocks)
+}
@@ -0,0 +1,95 @@
+package synch
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/core/types/pow"
+	"github.com/Qitmeer/qng/p2p/peers"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+func testFetchBlocks(n int) ([]*hash.Hash, map[hash.Hash]*types.SerializedBlock) {
+	hashes := make([]*hash.Hash, 0, n)
+	blocks := map[hash.Hash]*types.SerializedBlock{}
+	for i := 0; i < n; i++ {
+		block := types.NewBlock(&types.Block{Header: types.BlockHeader{
+			Version: uint32(i),
+			Pow:     pow.GetInstance(pow.MEERXKECCAKV1, 0, []byte{}),
+		}})
+		hashes = append(hashes, block.Hash())
+		blocks[*block.Hash()] = block
+	}
+	return hashes, blocks
+}
+
+func TestBlockFetcher(t *testing.T) {
+	hashes, blocks := testFetchBlocks(100)
+	good := peers.NewPeer(peer.ID("good"), nil)
+	partial := peers.NewPeer(peer.ID("partial"), nil)
+	slow := peers.NewPeer(peer.ID("slow"), nil)
+	bad := peers.NewPeer(peer.ID("bad"), nil)
+
+	var lock sync.Mutex
+	served := map[peer.ID]int{}
+	fetch := func(pe *peers.Peer, req []*hash.Hash) ([]*types.SerializedBlock, error) {
+		if len(req) > 8 {
+			return nil, fmt.Errorf("the window is exceeded")
+		}
+		switch pe.GetID() {
+		case bad.GetID():
+			return nil, fmt.Errorf("bad peer")
+		case slow.GetID():
+			// The blocks are reassigned before the response
+			time.Sleep(time.Second)
+			return nil, fmt.Errorf("slow peer")
+		case partial.GetID():
+			// The response is limited by the chunk size
+			req = req[:1]
+		}
+		time.Sleep(time.Millisecond * 5)
+		lock.Lock()
+		served[pe.GetID()] += len(req)
+		lock.Unlock()
+		result := []*types.SerializedBlock{}
+		for _, h := range req {
+			result = append(result, blocks[*h])
+		}
+		return result, nil
+	}
+	misbehaviors := map[peer.ID][]peers.Misbehavior{}
+	misbehave := func(pe *peers.Peer, kind peers.Misbehavior, reason string) {
+		lock.Lock()
+		misbehaviors[pe.GetID()] = append(misbehaviors[pe.GetID()], kind)
+		lock.Unlock()
+	}
+	bf := newBlockFetcher(fetch, misbehave, 8, 100*time.Millisecond, make(chan struct{}))
+	result := bf.Fetch([]*peers.Peer{good, partial, slow, bad}, hashes)
+	if len(result) != len(hashes) {
+		t.Fatalf("fetched %d/%d blocks", len(result), len(hashes))
+	}
+	for _, h := range hashes {
+		if result[*h] == nil || result[*h].Block != blocks[*h] {
+			t.Fatalf("the block %s isn't fetched", h)
+		}
+	}
+	lock.Lock()
+	if served[good.GetID()] <= 0 || served[partial.GetID()] <= 0 {
+		t.Fatalf("the blocks aren't fetched in parallel: %v", served)
+	}
+	if len(misbehaviors[slow.GetID()]) != 1 || misbehaviors[slow.GetID()][0] != peers.MisbehaviorStall {
+		t.Fatalf("the timeout isn't reported: %v", misbehaviors)
+	}
+	lock.Unlock()
+
+	// The blocks are left when all peers fail
+	bf = newBlockFetcher(fetch, nil, 8, 100*time.Millisecond, make(chan struct{}))
+	result = bf.Fetch([]*peers.Peer{bad, slow}, hashes)
+	if len(result) != 0 {
+		t.Fatalf("fetched %d blocks from failed peers", len(result))
+	}
+}
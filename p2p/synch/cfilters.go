@@ -0,0 +1,185 @@
+/*
+ * Copyright (c) 2017-2020 The qitmeer developers
+ */
+
+package synch
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/p2p/common"
+	"github.com/Qitmeer/qng/p2p/peers"
+	pb "github.com/Qitmeer/qng/p2p/proto/v1"
+	"github.com/Qitmeer/qng/services/cf/gcs"
+	"github.com/Qitmeer/qng/services/index"
+	libp2pcore "github.com/libp2p/go-libp2p/core"
+	"github.com/libp2p/go-libp2p/core/network"
+)
+
+const (
+	// MaxGetCFilters is the maximum number of filters which can be requested
+	// at once.
+	MaxGetCFilters = 1000
+
+	// MaxGetCFHeaders is the maximum number of filter headers which can be
+	// requested at once.
+	MaxGetCFHeaders = 2000
+
+	// maxCFiltersPayload is the maximum size of the filters of a response,
+	// the filters beyond it are left out and have to be requested again.
+	maxCFiltersPayload = types.MaxBlockPayload / 2
+)
+
+func (s *Sync) cfIndex() *index.CFIndex {
+	im, ok := s.p2p.Consensus().IndexManager().(*index.Manager)
+	if !ok {
+		return nil
+	}
+	return im.CFIndex()
+}
+
+// cfBlockRange returns the hashes of the blocks from the start order to the
+// stop block in the order of the DAG, which are all in the committed filter
+// index.
+func (s *Sync) cfBlockRange(filterType uint32, startOrder uint64, stopHash *pb.Hash, max uint64) (*index.CFIndex, []*hash.Hash, *common.Error) {
+	if gcs.FilterType(filterType) != gcs.FilterTypeBasic {
+		return nil, nil, ErrMessage(fmt.Errorf("unsupported filter type %d", filterType))
+	}
+	cfIndex := s.cfIndex()
+	if cfIndex == nil {
+		return nil, nil, common.NewError(common.ErrGeneric, fmt.Errorf("committed filter index is disabled"))
+	}
+	stop := changePBHashToHash(stopHash)
+	if stop == nil {
+		return nil, nil, ErrMessage(fmt.Errorf("invalid stop hash"))
+	}
+	stopBlock := s.p2p.BlockChain().BlockDAG().GetBlock(stop)
+	if stopBlock == nil || !stopBlock.IsOrdered() {
+		return nil, nil, ErrMessage(fmt.Errorf("unknown stop block %s", stop))
+	}
+	stopOrder := uint64(stopBlock.GetOrder())
+	if startOrder > stopOrder {
+		return nil, nil, ErrMessage(fmt.Errorf("start order %d is after stop order %d", startOrder, stopOrder))
+	}
+	if stopOrder-startOrder >= max {
+		return nil, nil, ErrMessage(fmt.Errorf("too many filters requested: %d, max is %d", stopOrder-startOrder+1, max))
+	}
+	_, tipOrder, err := cfIndex.Tip()
+	if err != nil {
+		return nil, nil, common.NewError(common.ErrGeneric, err)
+	}
+	if tipOrder == math.MaxUint32 || stopOrder > uint64(tipOrder) {
+		return nil, nil, common.NewError(common.ErrGeneric, fmt.Errorf("block %s is not indexed yet", stop))
+	}
+
+	hashes := make([]*hash.Hash, 0, stopOrder-startOrder+1)
+	for order := startOrder; order <= stopOrder; order++ {
+		block := s.p2p.BlockChain().BlockDAG().GetBlockByOrder(uint(order))
+		if block == nil {
+			return nil, nil, ErrDAGConsensus(fmt.Errorf("no block at order %d", order))
+		}
+		hashes = append(hashes, block.GetHash())
+	}
+	return cfIndex, hashes, nil
+}
+
+func (s *Sync) sendGetCFiltersRequest(stream network.Stream, pe *peers.Peer) (*pb.CFilters, *common.Error) {
+	e := ReadRspCode(stream, s.p2p)
+	if !e.Code.IsSuccess() {
+		e.Add("get cfilters request rsp")
+		return nil, e
+	}
+	msg := &pb.CFilters{}
+	if err := DecodeMessage(stream, s.p2p, msg); err != nil {
+		return nil, common.NewError(common.ErrStreamRead, err)
+	}
+	return msg, nil
+}
+
+func (s *Sync) getCFiltersHandler(ctx context.Context, msg interface{}, stream libp2pcore.Stream, pe *peers.Peer) *common.Error {
+	m, ok := msg.(*pb.GetCFilters)
+	if !ok {
+		err := fmt.Errorf("message is not type *pb.GetCFilters")
+		return ErrMessage(err)
+	}
+	cfIndex, hashes, e := s.cfBlockRange(m.FilterType, m.StartOrder, m.StopHash, MaxGetCFilters)
+	if e != nil {
+		return e
+	}
+	rsp := &pb.CFilters{FilterType: m.FilterType, Filters: make([]*pb.CFilter, 0, len(hashes))}
+	payload := 0
+	for _, bh := range hashes {
+		filter, err := cfIndex.FilterByBlockHash(bh)
+		if err != nil {
+			return common.NewError(common.ErrGeneric, err)
+		}
+		payload += len(filter) + hash.HashSize
+		if payload > maxCFiltersPayload && len(rsp.Filters) > 0 {
+			break
+		}
+		rsp.Filters = append(rsp.Filters, &pb.CFilter{
+			BlockHash: &pb.Hash{Hash: bh.Bytes()},
+			Data:      filter,
+		})
+	}
+	return s.EncodeResponseMsg(stream, rsp)
+}
+
+func (s *Sync) sendGetCFHeadersRequest(stream network.Stream, pe *peers.Peer) (*pb.CFHeaders, *common.Error) {
+	e := ReadRspCode(stream, s.p2p)
+	if !e.Code.IsSuccess() {
+		e.Add("get cfheaders request rsp")
+		return nil, e
+	}
+	msg := &pb.CFHeaders{}
+	if err := DecodeMessage(stream, s.p2p, msg); err != nil {
+		return nil, common.NewError(common.ErrStreamRead, err)
+	}
+	return msg, nil
+}
+
+func (s *Sync) getCFHeadersHandler(ctx context.Context, msg interface{}, stream libp2pcore.Stream, pe *peers.Peer) *common.Error {
+	m, ok := msg.(*pb.GetCFHeaders)
+	if !ok {
+		err := fmt.Errorf("message is not type *pb.GetCFHeaders")
+		return ErrMessage(err)
+	}
+	cfIndex, hashes, e := s.cfBlockRange(m.FilterType, m.StartOrder, m.StopHash, MaxGetCFHeaders)
+	if e != nil {
+		return e
+	}
+
+	// The headers are rebuilt by the requester from the header of the
+	// block before the start order and the hashes of the filters.
+	prevHeader := &hash.ZeroHash
+	if m.StartOrder > 0 {
+		prevBlock := s.p2p.BlockChain().BlockDAG().GetBlockByOrder(uint(m.StartOrder - 1))
+		if prevBlock == nil {
+			return ErrDAGConsensus(fmt.Errorf("no block at order %d", m.StartOrder-1))
+		}
+		var err error
+		prevHeader, err = cfIndex.FilterHeaderByBlockHash(prevBlock.GetHash())
+		if err != nil {
+			return common.NewError(common.ErrGeneric, err)
+		}
+	}
+	rsp := &pb.CFHeaders{
+		FilterType:       m.FilterType,
+		StopHash:         m.StopHash,
+		PrevFilterHeader: &pb.Hash{Hash: prevHeader.Bytes()},
+		FilterHashes:     make([]*pb.Hash, 0, len(hashes)),
+	}
+	for _, bh := range hashes {
+		filter, err := cfIndex.FilterByBlockHash(bh)
+		if err != nil {
+			return common.NewError(common.ErrGeneric, err)
+		}
+		filterHash := gcs.FilterHash(filter)
+		rsp.FilterHashes = append(rsp.FilterHashes, &pb.Hash{Hash: filterHash.Bytes()})
+	}
+	return s.EncodeResponseMsg(stream, rsp)
+}
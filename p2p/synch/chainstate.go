@@ -61,7 +61,11 @@ func (s *Sync) chainStateHandler(ctx context.Context, msg interface{}, stream li
 		}
 		return e
 	}
-	if !s.bidirectionalChannelCapacity(pe, stream.Conn()) {
+	// Light nodes are usually not reachable, so they are not required to
+	// provide the bidirectional channel.
+	isLight := !protocol.HasServices(protocol.ServiceFlag(m.Services), protocol.Full) &&
+		protocol.HasServices(protocol.ServiceFlag(m.Services), protocol.Light)
+	if !isLight && !s.bidirectionalChannelCapacity(pe, stream.Conn()) {
 		s.UpdateChainState(pe, m, false)
 		if err := s.EncodeResponseMsgPro(stream, s.getChainState(), common.ErrDAGConsensus); err != nil {
 			return err
@@ -109,9 +113,9 @@ func (s *Sync) validateChainStateMessage(msg *pb.ChainState, pe *peers.Peer) *co
 		return common.NewErrorStr(common.ErrDAGConsensus, "invalid graph state")
 	}
 	if pe.Direction() == network.DirInbound {
-		// Reject outbound peers that are not full nodes.
+		// Reject inbound peers that are neither full nor light nodes.
 		wantServices := protocol.Full
-		if !protocol.HasServices(protocol.ServiceFlag(msg.Services), wantServices) {
+		if !peers.HasConsensusService(protocol.ServiceFlag(msg.Services)) {
 			// missingServices := wantServices & ^msg.Services
 			missingServices := protocol.MissingServices(protocol.ServiceFlag(msg.Services), wantServices)
 			return common.NewErrorStr(common.ErrDAGConsensus, fmt.Sprintf("Rejecting peer %s with services %v "+
@@ -0,0 +1,372 @@
+/*
+ * Copyright (c) 2017-2020 The qitmeer developers
+ */
+
+package synch
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/core/merkle"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/p2p/common"
+	"github.com/Qitmeer/qng/p2p/peers"
+	pb "github.com/Qitmeer/qng/p2p/proto/v1"
+	libp2pcore "github.com/libp2p/go-libp2p/core"
+	"github.com/libp2p/go-libp2p/core/network"
+)
+
+// ShortIDSize is the size of the short transaction id in the compact block.
+const ShortIDSize = 6
+
+// errCompactBlockMismatch means the reconstructed block doesn't match its
+// header, some of the short ids are collided with other transactions.
+var errCompactBlockMismatch = errors.New("the reconstructed block does not match the header")
+
+// shortIDKey returns the key of the short ids, it's salted by the nonce
+// so that collisions can't be made for all the peers.
+func shortIDKey(blockHash *hash.Hash, nonce uint64) []byte {
+	var buf [hash.HashSize + 8]byte
+	copy(buf[:], blockHash[:])
+	binary.LittleEndian.PutUint64(buf[hash.HashSize:], nonce)
+	return hash.HashB(buf[:])[:16]
+}
+
+// shortID returns the short id of transaction, the witness is included in
+// the id so the transaction in pool is exactly the one of block.
+func shortID(key []byte, tx *types.Transaction) uint64 {
+	txh := tx.TxHashFull()
+	var buf [8]byte
+	copy(buf[:], hash.HashB(append(append([]byte{}, key...), txh[:]...))[:ShortIDSize])
+	return binary.LittleEndian.Uint64(buf[:])
+}
+
+// NewCompactBlock returns the compact block of block with a random nonce.
+func NewCompactBlock(block *types.SerializedBlock) (*pb.CompactBlock, error) {
+	return newCompactBlock(block, rand.Uint64())
+}
+
+// newCompactBlock returns the compact block which carries the header, the
+// parents and the short ids of transactions, the coinbase is prefilled.
+func newCompactBlock(block *types.SerializedBlock, nonce uint64) (*pb.CompactBlock, error) {
+	var header bytes.Buffer
+	err := block.Block().Header.Serialize(&header)
+	if err != nil {
+		return nil, err
+	}
+	cb := &pb.CompactBlock{
+		Header:       header.Bytes(),
+		Parents:      changeHashsToPBHashs(block.Block().Parents),
+		Nonce:        nonce,
+		PrefilledTxs: []*pb.PrefilledTx{},
+	}
+	key := shortIDKey(block.Hash(), nonce)
+	txs := block.Block().Transactions
+	cb.ShortIDs = make([]byte, 0, (len(txs)-1)*ShortIDSize)
+	for i, tx := range txs {
+		if i == 0 {
+			txBytes, err := tx.Serialize()
+			if err != nil {
+				return nil, err
+			}
+			cb.PrefilledTxs = append(cb.PrefilledTxs, &pb.PrefilledTx{Index: 0, Tx: &pb.Transaction{TxBytes: txBytes}})
+			continue
+		}
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], shortID(key, tx))
+		cb.ShortIDs = append(cb.ShortIDs, buf[:ShortIDSize]...)
+	}
+	return cb, nil
+}
+
+// partialBlock is the block being reconstructed from a compact block.
+type partialBlock struct {
+	header   types.BlockHeader
+	hash     hash.Hash
+	parents  []*hash.Hash
+	key      []byte
+	shortIDs map[int]uint64
+	txs      []*types.Transaction
+	missing  []uint64
+}
+
+// decodeCompactBlock checks the compact block and places its prefilled
+// transactions, the others are left to reconstruct.
+func decodeCompactBlock(m *pb.CompactBlock) (*partialBlock, error) {
+	p := &partialBlock{parents: []*hash.Hash{}, shortIDs: map[int]uint64{}}
+	err := p.header.Deserialize(bytes.NewReader(m.Header))
+	if err != nil {
+		return nil, err
+	}
+	p.hash = p.header.BlockHash()
+	p.key = shortIDKey(&p.hash, m.Nonce)
+	for _, ph := range m.Parents {
+		h, err := hash.NewHash(ph.Hash)
+		if err != nil {
+			return nil, err
+		}
+		p.parents = append(p.parents, h)
+	}
+	if len(m.ShortIDs)%ShortIDSize != 0 {
+		return nil, fmt.Errorf("invalid short ids size %d", len(m.ShortIDs))
+	}
+	count := len(m.ShortIDs)/ShortIDSize + len(m.PrefilledTxs)
+	if count <= 0 || count > types.MaxTxPerBlock {
+		return nil, fmt.Errorf("invalid transaction count %d", count)
+	}
+	p.txs = make([]*types.Transaction, count)
+	for _, ptx := range m.PrefilledTxs {
+		if int(ptx.Index) >= count || p.txs[ptx.Index] != nil || ptx.Tx == nil {
+			return nil, fmt.Errorf("invalid prefilled transaction %d", ptx.Index)
+		}
+		tx := changePBTxToTx(ptx.Tx)
+		if tx == nil {
+			return nil, fmt.Errorf("invalid prefilled transaction %d", ptx.Index)
+		}
+		p.txs[ptx.Index] = tx
+	}
+	offset := 0
+	for i := range p.txs {
+		if p.txs[i] != nil {
+			continue
+		}
+		var buf [8]byte
+		copy(buf[:], m.ShortIDs[offset:offset+ShortIDSize])
+		p.shortIDs[i] = binary.LittleEndian.Uint64(buf[:])
+		offset += ShortIDSize
+	}
+	return p, nil
+}
+
+// reconstruct fills the transactions from the pool, the transactions whose
+// short ids aren't found or are collided in the pool are missing.
+func (p *partialBlock) reconstruct(pool []*types.Tx) {
+	ids := make(map[uint64]*types.Transaction, len(pool))
+	for _, tx := range pool {
+		id := shortID(p.key, tx.Tx)
+		if _, ok := ids[id]; ok {
+			ids[id] = nil
+			continue
+		}
+		ids[id] = tx.Tx
+	}
+	p.missing = []uint64{}
+	for i := range p.txs {
+		if p.txs[i] != nil {
+			continue
+		}
+		tx := ids[p.shortIDs[i]]
+		if tx == nil {
+			p.missing = append(p.missing, uint64(i))
+			continue
+		}
+		p.txs[i] = tx
+	}
+}
+
+// fill sets the missing transactions which are returned by the peer.
+func (p *partialBlock) fill(txs []*types.Transaction) error {
+	if len(txs) != len(p.missing) {
+		return fmt.Errorf("expected %d transactions, but got %d", len(p.missing), len(txs))
+	}
+	for i, tx := range txs {
+		if tx == nil {
+			return fmt.Errorf("invalid transaction %d", p.missing[i])
+		}
+		p.txs[p.missing[i]] = tx
+	}
+	p.missing = []uint64{}
+	return nil
+}
+
+// block returns the reconstructed block after its transaction root and
+// witness commitment are verified.
+func (p *partialBlock) block() (*types.SerializedBlock, error) {
+	if len(p.missing) > 0 {
+		return nil, fmt.Errorf("%d transactions are missing", len(p.missing))
+	}
+	block := types.NewBlock(&types.Block{
+		Header:       p.header,
+		Parents:      p.parents,
+		Transactions: p.txs,
+	})
+	merkles := merkle.BuildMerkleTreeStore(block.Transactions(), false)
+	if !merkles[len(merkles)-1].IsEqual(&p.header.TxRoot) {
+		return nil, errCompactBlockMismatch
+	}
+	coinbase := p.txs[0]
+	if len(coinbase.TxIn) > 0 && !coinbase.TxIn[0].PreviousOut.Hash.IsEqual(&hash.ZeroHash) {
+		if merkle.ValidateWitnessCommitment(block) != nil {
+			return nil, errCompactBlockMismatch
+		}
+	}
+	return block, nil
+}
+
+func (s *Sync) sendCompactBlockRequest(stream network.Stream, pe *peers.Peer) *common.Error {
+	e := ReadRspCode(stream, s.p2p)
+	if !e.Code.IsSuccess() {
+		e.Add("compact block request rsp")
+		return e
+	}
+	msg := new(uint64)
+	if err := DecodeMessage(stream, s.p2p, msg); err != nil {
+		return common.NewError(common.ErrStreamRead, err)
+	}
+	if *msg != 0 {
+		log.Trace("compact block is added")
+	}
+	return nil
+}
+
+func (s *Sync) sendGetBlockTxsRequest(stream network.Stream, pe *peers.Peer) (*pb.BlockTxs, *common.Error) {
+	e := ReadRspCode(stream, s.p2p)
+	if !e.Code.IsSuccess() {
+		e.Add("get block txs request rsp")
+		return nil, e
+	}
+	msg := &pb.BlockTxs{}
+	if err := DecodeMessage(stream, s.p2p, msg); err != nil {
+		return nil, common.NewError(common.ErrStreamRead, err)
+	}
+	return msg, nil
+}
+
+func (s *Sync) compactBlockHandler(ctx context.Context, msg interface{}, stream libp2pcore.Stream, pe *peers.Peer) *common.Error {
+	m, ok := msg.(*pb.CompactBlock)
+	if !ok {
+		err := fmt.Errorf("message is not type *pb.CompactBlock")
+		return ErrMessage(err)
+	}
+	partial, err := decodeCompactBlock(m)
+	if err != nil {
+		s.Misbehave(pe, peers.MisbehaviorProtocol, err.Error())
+		return ErrMessage(err)
+	}
+	ret := uint64(0)
+	if s.canProcessBroadcastBlock(&partial.hash, partial.parents) {
+		ret = 1
+		go s.completeCompactBlock(partial, pe)
+	}
+	return s.EncodeResponseMsg(stream, ret)
+}
+
+// completeCompactBlock reconstructs the block from the pool and asks the
+// peer for the missing transactions, the full block is requested if the
+// reconstructed block doesn't match.
+func (s *Sync) completeCompactBlock(partial *partialBlock, pe *peers.Peer) {
+	// The meer transactions which aren't in the template of the meer pool
+	// are looked up as well, the duplicates would hide each other.
+	pool := []*types.Tx{}
+	known := map[hash.Hash]struct{}{}
+	add := func(tx *types.Tx) {
+		if _, ok := known[*tx.Hash()]; ok {
+			return
+		}
+		known[*tx.Hash()] = struct{}{}
+		pool = append(pool, tx)
+	}
+	for _, desc := range s.p2p.TxMemPool().TxDescs() {
+		add(desc.Tx)
+	}
+	for _, tx := range s.p2p.TxMemPool().MeerTxs() {
+		add(tx)
+	}
+	partial.reconstruct(pool)
+	if len(partial.missing) > 0 {
+		log.Trace("Request missing transactions of compact block", "hash", partial.hash.String(), "missing", len(partial.missing), "peer", pe.GetID())
+		ret, err := s.Send(pe, RPCGetBlockTxs, &pb.GetBlockTxs{BlockHash: &pb.Hash{Hash: partial.hash.Bytes()}, Indexes: partial.missing})
+		if err != nil {
+			log.Debug(fmt.Sprintf("Failed to get transactions of block %s:%v", partial.hash.String(), err))
+			return
+		}
+		bt := ret.(*pb.BlockTxs)
+		if len(bt.Txs) == 0 {
+			// The peer doesn't have the block anymore.
+			log.Debug(fmt.Sprintf("Transactions of block %s are not found by %s", partial.hash.String(), pe.GetID()))
+			return
+		}
+		txs := []*types.Transaction{}
+		for _, tx := range bt.Txs {
+			txs = append(txs, changePBTxToTx(tx))
+		}
+		err = partial.fill(txs)
+		if err != nil {
+			s.Misbehave(pe, peers.MisbehaviorProtocol, err.Error())
+			return
+		}
+	}
+	block, err := partial.block()
+	if err == errCompactBlockMismatch {
+		log.Debug(fmt.Sprintf("Compact block %s mismatches, request the full block", partial.hash.String()))
+		block, err = s.getFullBlock(pe, &partial.hash)
+	}
+	if err != nil {
+		log.Debug(fmt.Sprintf("Failed to complete compact block %s:%v", partial.hash.String(), err))
+		return
+	}
+	s.processBroadcastBlock(block, pe.GetID())
+}
+
+// getFullBlock requests the serialized block from the peer.
+func (s *Sync) getFullBlock(pe *peers.Peer, h *hash.Hash) (*types.SerializedBlock, error) {
+	ret, err := s.Send(pe, RPCGetBlockDatas, &pb.GetBlockDatas{Locator: []*pb.Hash{{Hash: h.Bytes()}}})
+	if err != nil {
+		return nil, err
+	}
+	bd := ret.(*pb.BlockDatas)
+	if len(bd.Locator) <= 0 {
+		return nil, fmt.Errorf("no block data")
+	}
+	block, err := types.NewBlockFromBytes(bd.Locator[0].BlockBytes)
+	if err != nil {
+		return nil, err
+	}
+	if !block.Hash().IsEqual(h) {
+		return nil, fmt.Errorf("unexpected block %s", block.Hash().String())
+	}
+	return block, nil
+}
+
+// getBlockTxsHandler returns the requested transactions of the block, no
+// transaction is returned if the block isn't found.
+func (s *Sync) getBlockTxsHandler(ctx context.Context, msg interface{}, stream libp2pcore.Stream, pe *peers.Peer) *common.Error {
+	m, ok := msg.(*pb.GetBlockTxs)
+	if !ok {
+		err := fmt.Errorf("message is not type *pb.GetBlockTxs")
+		return ErrMessage(err)
+	}
+	blockHash := changePBHashToHash(m.BlockHash)
+	if blockHash == nil {
+		return ErrMessage(fmt.Errorf("invalid block hash"))
+	}
+	if len(m.Indexes) == 0 {
+		return ErrMessage(fmt.Errorf("no transaction index"))
+	}
+	bt := &pb.BlockTxs{BlockHash: m.BlockHash, Txs: []*pb.Transaction{}}
+	// The block which is unknown or pruned isn't the fault of peer, so it
+	// is answered with no transactions.
+	block, err := s.p2p.BlockChain().FetchBlockByHash(blockHash)
+	if err != nil {
+		log.Trace("Block of requested transactions is not found", "hash", blockHash.String(), "error", err)
+		return s.EncodeResponseMsg(stream, bt)
+	}
+	txs := block.Block().Transactions
+	for _, index := range m.Indexes {
+		if index >= uint64(len(txs)) {
+			return ErrMessage(fmt.Errorf("transaction index %d out of range", index))
+		}
+		txBytes, err := txs[index].Serialize()
+		if err != nil {
+			return ErrMessage(err)
+		}
+		bt.Txs = append(bt.Txs, &pb.Transaction{TxBytes: txBytes})
+	}
+	return s.EncodeResponseMsg(stream, bt)
+}
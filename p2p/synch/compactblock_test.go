@@ -0,0 +1,122 @@
+package synch
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/core/merkle"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/core/types/pow"
+)
+
+func testTx(index uint32) *types.Transaction {
+	tx := types.NewTransaction()
+	tx.AddTxIn(types.NewTxInput(types.NewOutPoint(&hash.ZeroHash, index), []byte{0x51}))
+	tx.AddTxOut(types.NewTxOutput(types.Amount{Value: int64(index) + 1, Id: types.MEERA}, []byte{0x51}))
+	return tx
+}
+
+func testCompactBlock() *types.SerializedBlock {
+	parent := hash.HashH([]byte{1})
+	txs := []*types.Transaction{testTx(math.MaxUint32)}
+	for i := uint32(0); i < 5; i++ {
+		txs = append(txs, testTx(i))
+	}
+	block := &types.Block{
+		Header: types.BlockHeader{
+			Version:    1,
+			ParentRoot: parent,
+			Difficulty: 0x03000001,
+			Timestamp:  time.Unix(time.Now().Unix(), 0),
+			Pow:        pow.GetInstance(pow.MEERXKECCAKV1, 0, []byte{}),
+		},
+		Parents:      []*hash.Hash{&parent},
+		Transactions: txs,
+	}
+	merkles := merkle.BuildMerkleTreeStore(types.NewBlock(block).Transactions(), false)
+	block.Header.TxRoot = *merkles[len(merkles)-1]
+	return types.NewBlock(block)
+}
+
+func TestCompactBlock(t *testing.T) {
+	block := testCompactBlock()
+	cb, err := newCompactBlock(block, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cb.PrefilledTxs) != 1 || len(cb.ShortIDs) != 5*ShortIDSize {
+		t.Fatalf("unexpected compact block %d %d", len(cb.PrefilledTxs), len(cb.ShortIDs))
+	}
+	partial, err := decodeCompactBlock(cb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !partial.hash.IsEqual(block.Hash()) || len(partial.parents) != 1 {
+		t.Fatalf("unexpected block %s", partial.hash.String())
+	}
+	// The third transaction isn't in the pool
+	txs := block.Block().Transactions
+	pool := []*types.Tx{types.NewTx(testTx(100))}
+	for i, tx := range txs[1:] {
+		if i != 2 {
+			pool = append(pool, types.NewTx(tx))
+		}
+	}
+	partial.reconstruct(pool)
+	if len(partial.missing) != 1 || partial.missing[0] != 3 {
+		t.Fatalf("unexpected missing transactions %v", partial.missing)
+	}
+	if _, err := partial.block(); err == nil {
+		t.Fatal("the block is built with missing transactions")
+	}
+	if err := partial.fill([]*types.Transaction{}); err == nil {
+		t.Fatal("the transactions are filled with wrong count")
+	}
+	if err := partial.fill([]*types.Transaction{txs[3]}); err != nil {
+		t.Fatal(err)
+	}
+	rblock, err := partial.block()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rbytes, _ := rblock.Bytes()
+	bytes, _ := block.Bytes()
+	if string(rbytes) != string(bytes) {
+		t.Fatal("the reconstructed block is different")
+	}
+
+	// The transaction of pool is different from the block
+	partial, err = decodeCompactBlock(cb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	partial.reconstruct(pool)
+	partial.fill([]*types.Transaction{testTx(101)})
+	if _, err := partial.block(); err != errCompactBlockMismatch {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestDecodeCompactBlock(t *testing.T) {
+	block := testCompactBlock()
+	cb, err := newCompactBlock(block, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb.ShortIDs = cb.ShortIDs[1:]
+	if _, err := decodeCompactBlock(cb); err == nil {
+		t.Fatal("the invalid short ids are decoded")
+	}
+	cb, _ = newCompactBlock(block, 7)
+	cb.PrefilledTxs = append(cb.PrefilledTxs, cb.PrefilledTxs[0])
+	if _, err := decodeCompactBlock(cb); err == nil {
+		t.Fatal("the duplicate prefilled transactions are decoded")
+	}
+	// The short ids are salted by nonce
+	other, _ := newCompactBlock(block, 8)
+	if string(other.ShortIDs) == string(cb.ShortIDs) {
+		t.Fatal("the short ids are the same with different nonces")
+	}
+}
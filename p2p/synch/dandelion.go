@@ -0,0 +1,535 @@
+/*
+ * Copyright (c) 2017-2020 The qitmeer developers
+ */
+
+package synch
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/core/blockchain/opreturn"
+	"github.com/Qitmeer/qng/core/protocol"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/p2p/common"
+	"github.com/Qitmeer/qng/p2p/peers"
+	pb "github.com/Qitmeer/qng/p2p/proto/v1"
+	"github.com/Qitmeer/qng/services/notifymgr/notify"
+	libp2pcore "github.com/libp2p/go-libp2p/core"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	// DandelionEpoch is the duration of epoch, the node chooses its relays
+	// and whether it diffuses the stem transactions again at every epoch.
+	DandelionEpoch = 10 * time.Minute
+
+	// dandelionFluffProbability is the probability in percent that the
+	// node diffuses the stem transactions of the other peers in an epoch.
+	dandelionFluffProbability = 10
+
+	// dandelionRelays is the number of outbound relays in an epoch.
+	dandelionRelays = 2
+
+	// dandelionEmbargo is the minimum time that a stem transaction waits
+	// to be diffused by the others, a random delay whose mean is
+	// dandelionEmbargoMean is added so the node which diffuses it first
+	// can't be told.
+	dandelionEmbargo     = 10 * time.Second
+	dandelionEmbargoMean = 20 * time.Second
+
+	// maxStemPoolSize is the maximum number of transactions of the peers
+	// in stem phase.
+	maxStemPoolSize = 5000
+
+	// maxStemTxsPerPeer is the maximum number of transactions of one peer
+	// in stem phase, so a peer can't fill the stem pool.
+	maxStemTxsPerPeer = 100
+
+	// maxOwnStemPoolSize is the maximum number of own transactions in stem
+	// phase, the others are diffused at once.
+	maxOwnStemPoolSize = 1000
+
+	dandelionTickerDur = time.Second
+)
+
+// stemTx is a transaction in stem phase.
+type stemTx struct {
+	desc *types.TxDesc
+	// source is the peer which sends the transaction, it's empty for the
+	// own transactions.
+	source  peer.ID
+	embargo time.Time
+}
+
+func (stx *stemTx) isOwn() bool {
+	return len(stx.source) <= 0
+}
+
+// stemPool holds the transactions in stem phase. They're kept out of the
+// TxPool so the node doesn't reveal them before they're diffused.
+type stemPool struct {
+	lock      sync.RWMutex
+	txs       map[hash.Hash]*stemTx
+	limit     int
+	peerLimit int
+	sources   map[peer.ID]int
+}
+
+// newStemPool returns the stem pool which holds limit transactions, and
+// peerLimit transactions of every source peer.
+func newStemPool(limit int, peerLimit int) *stemPool {
+	return &stemPool{
+		txs:       map[hash.Hash]*stemTx{},
+		limit:     limit,
+		peerLimit: peerLimit,
+		sources:   map[peer.ID]int{},
+	}
+}
+
+// add returns false if the transaction is known or the pool is full.
+func (sp *stemPool) add(stx *stemTx) bool {
+	sp.lock.Lock()
+	defer sp.lock.Unlock()
+
+	h := *stx.desc.Tx.Hash()
+	if _, ok := sp.txs[h]; ok || len(sp.txs) >= sp.limit {
+		return false
+	}
+	if !stx.isOwn() {
+		if sp.sources[stx.source] >= sp.peerLimit {
+			return false
+		}
+		sp.sources[stx.source]++
+	}
+	sp.txs[h] = stx
+	return true
+}
+
+func (sp *stemPool) remove(h *hash.Hash) *stemTx {
+	sp.lock.Lock()
+	defer sp.lock.Unlock()
+
+	stx, ok := sp.txs[*h]
+	if !ok {
+		return nil
+	}
+	sp.delete(h, stx)
+	return stx
+}
+
+// delete MUST be called with the lock held.
+func (sp *stemPool) delete(h *hash.Hash, stx *stemTx) {
+	delete(sp.txs, *h)
+	if stx.isOwn() {
+		return
+	}
+	sp.sources[stx.source]--
+	if sp.sources[stx.source] <= 0 {
+		delete(sp.sources, stx.source)
+	}
+}
+
+func (sp *stemPool) have(h *hash.Hash) bool {
+	sp.lock.RLock()
+	defer sp.lock.RUnlock()
+
+	_, ok := sp.txs[*h]
+	return ok
+}
+
+func (sp *stemPool) count() int {
+	sp.lock.RLock()
+	defer sp.lock.RUnlock()
+
+	return len(sp.txs)
+}
+
+// expired removes and returns the transactions whose embargo is over.
+func (sp *stemPool) expired(now time.Time) []*stemTx {
+	sp.lock.Lock()
+	defer sp.lock.Unlock()
+
+	result := []*stemTx{}
+	for h, stx := range sp.txs {
+		if now.Before(stx.embargo) {
+			continue
+		}
+		th := h
+		sp.delete(&th, stx)
+		result = append(result, stx)
+	}
+	return result
+}
+
+type stemFunc func(relay peer.ID, stx *stemTx)
+
+type fluffFunc func(stx *stemTx)
+
+// dandelion routes the transactions by Dandelion++. In every epoch the node
+// is either a diffuser, which diffuses the stem transactions of the others,
+// or a relayer, which passes them to one of its relays. The transactions of
+// one source always go through the same relay in an epoch, and the own
+// transactions are always stemmed. Every stem transaction has an embargo
+// timer, it's diffused by the node when nobody diffuses it in time. The own
+// transactions are kept apart from the ones of the peers, so the peers can't
+// make the node diffuse them at once by filling its stem pool.
+type dandelion struct {
+	stem  stemFunc
+	fluff fluffFunc
+	pool  *stemPool
+	own   *stemPool
+
+	lock     sync.Mutex
+	rand     *rand.Rand
+	epoch    time.Time
+	diffuser bool
+	relays   []peer.ID
+	routes   map[peer.ID]peer.ID
+
+	wg   sync.WaitGroup
+	quit chan struct{}
+}
+
+func newDandelion(stem stemFunc, fluff fluffFunc, r *rand.Rand) *dandelion {
+	return &dandelion{
+		stem:   stem,
+		fluff:  fluff,
+		pool:   newStemPool(maxStemPoolSize, maxStemTxsPerPeer),
+		own:    newStemPool(maxOwnStemPoolSize, 0),
+		rand:   r,
+		relays: []peer.ID{},
+		routes: map[peer.ID]peer.ID{},
+	}
+}
+
+// newEpoch chooses the mode of node and the relays from the candidates.
+func (d *dandelion) newEpoch(candidates []peer.ID, now time.Time) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.epoch = now
+	d.diffuser = d.rand.Intn(100) < dandelionFluffProbability
+	relays := append([]peer.ID{}, candidates...)
+	d.rand.Shuffle(len(relays), func(i, j int) {
+		relays[i], relays[j] = relays[j], relays[i]
+	})
+	if len(relays) > dandelionRelays {
+		relays = relays[:dandelionRelays]
+	}
+	d.relays = relays
+	d.routes = map[peer.ID]peer.ID{}
+	log.Trace("Dandelion new epoch", "diffuser", d.diffuser, "relays", len(d.relays))
+}
+
+// relay returns the relay of the transactions from source, it returns false
+// if they're diffused by the node.
+func (d *dandelion) relay(source peer.ID) (peer.ID, bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if len(d.relays) <= 0 || (len(source) > 0 && d.diffuser) {
+		return "", false
+	}
+	r, ok := d.routes[source]
+	if ok && d.isRelay(r) {
+		return r, true
+	}
+	r = d.relays[d.rand.Intn(len(d.relays))]
+	d.routes[source] = r
+	return r, true
+}
+
+func (d *dandelion) isRelay(pid peer.ID) bool {
+	for _, r := range d.relays {
+		if r == pid {
+			return true
+		}
+	}
+	return false
+}
+
+// removeRelay drops the relay which is gone, another one is chosen in the
+// next epoch.
+func (d *dandelion) removeRelay(pid peer.ID) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for i, r := range d.relays {
+		if r == pid {
+			d.relays = append(d.relays[:i:i], d.relays[i+1:]...)
+			return
+		}
+	}
+}
+
+func (d *dandelion) embargo(now time.Time) time.Time {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return now.Add(dandelionEmbargo + time.Duration(d.rand.ExpFloat64()*float64(dandelionEmbargoMean)))
+}
+
+// process passes the transaction of source to the relay, or diffuses it if
+// the node is a diffuser. It returns false if the transaction is already
+// in stem phase, or the stem pool refuses the transaction of peer, whose
+// sender diffuses it when its embargo is over.
+func (d *dandelion) process(desc *types.TxDesc, source peer.ID, now time.Time) bool {
+	stx := &stemTx{desc: desc, source: source, embargo: d.embargo(now)}
+	if d.have(desc.Tx.Hash()) {
+		return false
+	}
+	relay, ok := d.relay(source)
+	if !ok {
+		d.fluff(stx)
+		return true
+	}
+	pool := d.pool
+	if stx.isOwn() {
+		pool = d.own
+	}
+	if !pool.add(stx) {
+		if !stx.isOwn() {
+			return false
+		}
+		d.fluff(stx)
+		return true
+	}
+	log.Trace("Stem transaction", "hash", desc.Tx.Hash().String(), "relay", relay.String())
+	d.stem(relay, stx)
+	return true
+}
+
+func (d *dandelion) have(h *hash.Hash) bool {
+	return d.pool.have(h) || d.own.have(h)
+}
+
+// remove drops the transaction which is diffused by the others.
+func (d *dandelion) remove(h *hash.Hash) *stemTx {
+	stx := d.own.remove(h)
+	if stx != nil {
+		return stx
+	}
+	return d.pool.remove(h)
+}
+
+// stemFailed diffuses the transaction which can't be passed to the relay.
+func (d *dandelion) stemFailed(relay peer.ID, stx *stemTx) {
+	d.removeRelay(relay)
+	if d.remove(stx.desc.Tx.Hash()) == nil {
+		return
+	}
+	d.fluff(stx)
+}
+
+// tick starts a new epoch when it's over or the relays are gone and there
+// are candidates to replace them, and diffuses the stem transactions whose
+// embargo is over.
+func (d *dandelion) tick(candidates []peer.ID, now time.Time) {
+	d.lock.Lock()
+	over := now.Sub(d.epoch) >= DandelionEpoch || (len(d.relays) <= 0 && len(candidates) > 0)
+	d.lock.Unlock()
+	if over {
+		d.newEpoch(candidates, now)
+	} else {
+		cands := map[peer.ID]struct{}{}
+		for _, c := range candidates {
+			cands[c] = struct{}{}
+		}
+		d.lock.Lock()
+		relays := append([]peer.ID{}, d.relays...)
+		d.lock.Unlock()
+		for _, r := range relays {
+			if _, ok := cands[r]; !ok {
+				d.removeRelay(r)
+			}
+		}
+	}
+	for _, stx := range append(d.own.expired(now), d.pool.expired(now)...) {
+		log.Trace("Stem transaction embargo is over", "hash", stx.desc.Tx.Hash().String())
+		d.fluff(stx)
+	}
+}
+
+func (d *dandelion) start(candidates func() []peer.ID) {
+	d.quit = make(chan struct{})
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		ticker := time.NewTicker(dandelionTickerDur)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				d.tick(candidates(), time.Now())
+			case <-d.quit:
+				return
+			}
+		}
+	}()
+}
+
+func (d *dandelion) stop() {
+	if d.quit == nil {
+		return
+	}
+	close(d.quit)
+	d.wg.Wait()
+	d.quit = nil
+}
+
+// dandelionCandidates returns the outbound peers which can relay the stem
+// transactions.
+func (s *Sync) dandelionCandidates() []peer.ID {
+	result := []peer.ID{}
+	for _, pe := range s.peers.CanSyncPeers() {
+		if pe.Direction() != network.DirOutbound || pe.DisableRelayTx() {
+			continue
+		}
+		if pe.ChainState().ProtocolVersion < protocol.DandelionProtocolVersion {
+			continue
+		}
+		result = append(result, pe.GetID())
+	}
+	return result
+}
+
+// stemTx sends the stem transaction to the relay in background.
+func (s *Sync) stemTx(relay peer.ID, stx *stemTx) {
+	go func() {
+		err := s.sendStemTx(relay, stx.desc.Tx)
+		if err != nil {
+			log.Debug(fmt.Sprintf("Failed to stem transaction %s to %s:%v", stx.desc.Tx.Hash().String(), relay.String(), err))
+			s.dandelion.stemFailed(relay, stx)
+		}
+	}()
+}
+
+func (s *Sync) sendStemTx(relay peer.ID, tx *types.Tx) error {
+	pe := s.peers.Get(relay)
+	if pe == nil || !pe.IsConnected() {
+		return fmt.Errorf("relay is disconnected")
+	}
+	txBytes, err := tx.Tx.Serialize()
+	if err != nil {
+		return err
+	}
+	_, err = s.Send(pe, RPCStemTx, &pb.Transaction{TxBytes: txBytes})
+	return err
+}
+
+// fluffTx diffuses the stem transaction, the own transactions are added to
+// the mempool and announced now, and the others are processed as usual.
+func (s *Sync) fluffTx(stx *stemTx) {
+	if stx.isOwn() {
+		acceptedTxs, err := s.p2p.TxMemPool().ProcessTransaction(stx.desc.Tx, false, false, true)
+		if err != nil {
+			log.Debug(fmt.Sprintf("Failed to diffuse own transaction %s:%v", stx.desc.Tx.Hash().String(), err))
+			return
+		}
+		s.p2p.Notify().AnnounceNewTransactions(acceptedTxs, nil)
+		s.p2p.Notify().AddRebroadcastInventory(acceptedTxs)
+		return
+	}
+	_, err := s.processTx(stx.desc.Tx.Tx, stx.source)
+	if err != nil {
+		log.Trace(err.Error())
+	}
+}
+
+// IsStemTx returns true if the transaction is in stem phase.
+func (s *Sync) IsStemTx(h *hash.Hash) bool {
+	return s.dandelion.have(h)
+}
+
+// StemTransaction passes the own transaction, which is checked by the
+// mempool, to the stem relays. It's added to the mempool when it's diffused.
+// It returns false if the transaction is already in stem phase.
+func (s *Sync) StemTransaction(tx *types.Tx) bool {
+	now := time.Now()
+	return s.dandelion.process(&types.TxDesc{Tx: tx, Added: now}, "", now)
+}
+
+// FluffInventory announces the inventory to all the peers.
+func (s *Sync) FluffInventory(nds []*notify.NotifyData) {
+	if s.p2p.Config().GossipRelay() {
+		for _, nd := range nds {
+			txd, ok := nd.Data.(*types.TxDesc)
+			if !ok {
+				continue
+			}
+			if err := s.PublishTx(txd.Tx); err != nil {
+				log.Debug(fmt.Sprintf("Failed to publish transaction %s:%v", txd.Tx.Hash().String(), err))
+			}
+		}
+	}
+	if s.p2p.Config().StreamRelay() {
+		s.peerSync.RelayInventory(nds)
+	}
+}
+
+func (s *Sync) sendStemTxRequest(stream network.Stream, pe *peers.Peer) *common.Error {
+	e := ReadRspCode(stream, s.p2p)
+	if !e.Code.IsSuccess() {
+		e.Add("stem tx request rsp")
+		return e
+	}
+	msg := new(uint64)
+	if err := DecodeMessage(stream, s.p2p, msg); err != nil {
+		return common.NewError(common.ErrStreamRead, err)
+	}
+	return nil
+}
+
+func (s *Sync) stemTxHandler(ctx context.Context, msg interface{}, stream libp2pcore.Stream, pe *peers.Peer) *common.Error {
+	m, ok := msg.(*pb.Transaction)
+	if !ok {
+		err := fmt.Errorf("message is not type *pb.Transaction")
+		return ErrMessage(err)
+	}
+	tx := changePBTxToTx(m)
+	if tx == nil {
+		err := fmt.Errorf("undecodable stem tx")
+		s.Misbehave(pe, peers.MisbehaviorProtocol, err.Error())
+		return ErrMessage(err)
+	}
+	ret := uint64(0)
+	txh := tx.TxHash()
+	// The sender diffuses the transaction when its embargo is over if the
+	// node isn't synchronized.
+	if !s.p2p.IsCurrent() || s.p2p.TxMemPool().HaveTransaction(&txh) || s.IsStemTx(&txh) {
+		return s.EncodeResponseMsg(stream, ret)
+	}
+	if tx.IsCoinBase() {
+		err := fmt.Errorf("stem coinbase %s", txh.String())
+		s.Misbehave(pe, peers.MisbehaviorInvalidTx, err.Error())
+		return ErrMessage(err)
+	}
+	// The meerevm transactions can't be checked without adding them, they
+	// are processed at once.
+	if !s.p2p.Config().Dandelion || opreturn.IsMeerEVMTx(tx) {
+		ret = 1
+		go s.fluffTx(&stemTx{desc: &types.TxDesc{Tx: types.NewTx(tx)}, source: pe.GetID()})
+		return s.EncodeResponseMsg(stream, ret)
+	}
+	// The stem transaction must be accepted by the mempool, so the peers
+	// can't relay the junk along the stems.
+	err := s.p2p.TxMemPool().CheckTransaction(types.NewTx(tx), true, true)
+	if err != nil {
+		if isInvalidTxError(err) {
+			s.Misbehave(pe, peers.MisbehaviorInvalidTx, err.Error())
+		}
+		log.Trace(fmt.Sprintf("Rejected stem transaction %s:%v", txh.String(), err))
+		return s.EncodeResponseMsg(stream, ret)
+	}
+	now := time.Now()
+	if s.dandelion.process(&types.TxDesc{Tx: types.NewTx(tx), Added: now}, pe.GetID(), now) {
+		ret = 1
+	}
+	return s.EncodeResponseMsg(stream, ret)
+}
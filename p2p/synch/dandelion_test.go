@@ -0,0 +1,298 @@
+package synch
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// simNode is a node of the simulated network, the stem transactions are
+// passed to the relays directly.
+type simNode struct {
+	id        peer.ID
+	d         *dandelion
+	outbounds []peer.ID
+}
+
+type simNetwork struct {
+	nodes map[peer.ID]*simNode
+	order []peer.ID
+	// blackholes drop the stem transactions
+	blackholes map[peer.ID]bool
+	// fluffs are the diffused transactions, fluffBy are the nodes which
+	// diffuse them
+	fluffs  []*stemTx
+	fluffBy []peer.ID
+	stems   int
+}
+
+func newSimNetwork(size, outbounds int, r *rand.Rand) *simNetwork {
+	sn := &simNetwork{nodes: map[peer.ID]*simNode{}, blackholes: map[peer.ID]bool{}}
+	for i := 0; i < size; i++ {
+		sn.order = append(sn.order, peer.ID(fmt.Sprintf("node%d", i)))
+	}
+	for i, id := range sn.order {
+		node := &simNode{id: id}
+		node.d = newDandelion(
+			func(relay peer.ID, stx *stemTx) {
+				sn.stems++
+				if sn.blackholes[relay] {
+					return
+				}
+				sn.nodes[relay].d.process(stx.desc, node.id, time.Now())
+			},
+			func(stx *stemTx) {
+				sn.fluffs = append(sn.fluffs, stx)
+				sn.fluffBy = append(sn.fluffBy, node.id)
+				// The diffused transaction is seen by all nodes
+				for _, n := range sn.nodes {
+					n.d.remove(stx.desc.Tx.Hash())
+				}
+			},
+			rand.New(rand.NewSource(r.Int63())))
+		for _, j := range r.Perm(size)[:outbounds+1] {
+			if j != i && len(node.outbounds) < outbounds {
+				node.outbounds = append(node.outbounds, sn.order[j])
+			}
+		}
+		sn.nodes[id] = node
+	}
+	return sn
+}
+
+func (sn *simNetwork) tick(now time.Time) {
+	for _, id := range sn.order {
+		node := sn.nodes[id]
+		node.d.tick(node.outbounds, now)
+	}
+}
+
+func (sn *simNetwork) inStem(desc *types.TxDesc) int {
+	count := 0
+	for _, node := range sn.nodes {
+		if node.d.have(desc.Tx.Hash()) {
+			count++
+		}
+	}
+	return count
+}
+
+func TestDandelionSimulation(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	sn := newSimNetwork(50, 4, r)
+	start := time.Now()
+	sn.tick(start)
+
+	trials := 300
+	originFluffs := 0
+	stemmed := 0
+	for i := 0; i < trials; i++ {
+		origin := sn.nodes[sn.order[r.Intn(len(sn.order))]]
+		desc := &types.TxDesc{Tx: types.NewTx(testTx(uint32(i)))}
+		fluffs := len(sn.fluffs)
+		stems := sn.stems
+		origin.d.process(desc, "", time.Now())
+		if sn.stems == stems {
+			t.Fatal("the own transaction isn't stemmed")
+		}
+		stemmed += sn.stems - stems
+		// The stems which run into loops are diffused by the embargo
+		now := time.Now()
+		for s := 0; len(sn.fluffs) == fluffs && s < 3600; s++ {
+			now = now.Add(time.Second)
+			sn.tick(now)
+		}
+		if len(sn.fluffs) != fluffs+1 {
+			t.Fatalf("the transaction is diffused %d times", len(sn.fluffs)-fluffs)
+		}
+		if sn.inStem(desc) != 0 {
+			t.Fatal("the diffused transaction is still in stem phase")
+		}
+		if sn.fluffBy[fluffs] == origin.id {
+			originFluffs++
+		}
+	}
+	// The mean length of stems is 1/q without loops
+	mean := float64(stemmed) / float64(trials)
+	if mean < 3 || mean > 15 {
+		t.Fatalf("unexpected mean stem length %f", mean)
+	}
+	if originFluffs*5 > trials {
+		t.Fatalf("the origins diffuse %d of %d transactions", originFluffs, trials)
+	}
+}
+
+func TestDandelionEmbargo(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	sn := newSimNetwork(10, 2, r)
+	now := time.Now()
+	sn.tick(now)
+	origin := sn.nodes[sn.order[0]]
+	for _, id := range origin.outbounds {
+		sn.blackholes[id] = true
+	}
+	desc := &types.TxDesc{Tx: types.NewTx(testTx(1))}
+	origin.d.process(desc, "", now)
+	if !origin.d.own.have(desc.Tx.Hash()) {
+		t.Fatal("the own transaction isn't in stem pool")
+	}
+	sn.tick(now.Add(dandelionEmbargo - time.Second))
+	if len(sn.fluffs) != 0 {
+		t.Fatal("the transaction is diffused before its embargo is over")
+	}
+	sn.tick(now.Add(time.Hour))
+	if len(sn.fluffs) != 1 || sn.fluffBy[0] != origin.id || !sn.fluffs[0].isOwn() {
+		t.Fatalf("the embargo isn't over %v", sn.fluffBy)
+	}
+	if origin.d.own.count() != 0 {
+		t.Fatal("the diffused transaction is still in stem pool")
+	}
+}
+
+func TestDandelionEpoch(t *testing.T) {
+	fluffs := 0
+	d := newDandelion(func(relay peer.ID, stx *stemTx) {}, func(stx *stemTx) { fluffs++ }, rand.New(rand.NewSource(3)))
+	// No relay, the own transaction is diffused
+	d.tick(nil, time.Now())
+	d.process(&types.TxDesc{Tx: types.NewTx(testTx(1))}, "", time.Now())
+	if fluffs != 1 || d.own.count() != 0 {
+		t.Fatal("the transaction isn't diffused without relay")
+	}
+	// The epoch isn't renewed without candidates
+	epoch := d.epoch
+	d.tick(nil, time.Now().Add(time.Second))
+	if d.epoch != epoch {
+		t.Fatal("the epoch is renewed without candidates")
+	}
+
+	candidates := []peer.ID{"a", "b", "c", "d"}
+	diffusers := 0
+	epochs := 2000
+	now := time.Now()
+	for i := 0; i < epochs; i++ {
+		now = now.Add(DandelionEpoch)
+		d.tick(candidates, now)
+		if len(d.relays) != dandelionRelays {
+			t.Fatalf("unexpected relays %v", d.relays)
+		}
+		if d.diffuser {
+			diffusers++
+		}
+		// The own transactions are always stemmed
+		if _, ok := d.relay(""); !ok {
+			t.Fatal("the own transaction isn't stemmed")
+		}
+		relay, ok := d.relay("x")
+		if ok == d.diffuser {
+			t.Fatal("the diffuser stems the transaction")
+		}
+		if ok {
+			for j := 0; j < 10; j++ {
+				if r, _ := d.relay("x"); r != relay {
+					t.Fatal("the route changes in the epoch")
+				}
+			}
+		}
+	}
+	if diffusers*100 < epochs*(dandelionFluffProbability-4) || diffusers*100 > epochs*(dandelionFluffProbability+4) {
+		t.Fatalf("%d diffusers in %d epochs", diffusers, epochs)
+	}
+
+	// The relay which is gone is removed in the epoch
+	d.tick(candidates[:1], now.Add(time.Second))
+	if len(d.relays) > 1 || (len(d.relays) == 1 && d.relays[0] != "a") {
+		t.Fatalf("unexpected relays %v", d.relays)
+	}
+}
+
+func TestDandelionStemFailed(t *testing.T) {
+	var d *dandelion
+	fluffs := []*stemTx{}
+	d = newDandelion(func(relay peer.ID, stx *stemTx) {
+		d.stemFailed(relay, stx)
+	}, func(stx *stemTx) {
+		fluffs = append(fluffs, stx)
+	}, rand.New(rand.NewSource(4)))
+	d.tick([]peer.ID{"a"}, time.Now())
+	desc := &types.TxDesc{Tx: types.NewTx(testTx(1))}
+	if !d.process(desc, "", time.Now()) {
+		t.Fatal("the transaction isn't processed")
+	}
+	if len(fluffs) != 1 || len(d.relays) != 0 || d.own.count() != 0 {
+		t.Fatalf("the failed stem isn't diffused %d %v", len(fluffs), d.relays)
+	}
+}
+
+func TestStemPool(t *testing.T) {
+	sp := newStemPool(2, 1)
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		stx := &stemTx{desc: &types.TxDesc{Tx: types.NewTx(testTx(uint32(i)))}, embargo: now.Add(time.Duration(i) * time.Second)}
+		if sp.add(stx) != (i < 2) {
+			t.Fatalf("unexpected result of adding %d", i)
+		}
+	}
+	first := types.NewTx(testTx(0))
+	if sp.add(&stemTx{desc: &types.TxDesc{Tx: first}}) {
+		t.Fatal("the duplicate transaction is added")
+	}
+	if txs := sp.expired(now); len(txs) != 1 || !txs[0].desc.Tx.Hash().IsEqual(first.Hash()) {
+		t.Fatalf("unexpected expired transactions %d", len(txs))
+	}
+	if sp.have(first.Hash()) || sp.count() != 1 {
+		t.Fatal("the expired transaction is still in stem pool")
+	}
+}
+
+func TestStemPoolPeerLimit(t *testing.T) {
+	sp := newStemPool(3, 2)
+	for i := 0; i < 3; i++ {
+		stx := &stemTx{desc: &types.TxDesc{Tx: types.NewTx(testTx(uint32(i)))}, source: "a"}
+		if sp.add(stx) != (i < 2) {
+			t.Fatalf("unexpected result of adding %d of peer", i)
+		}
+	}
+	if !sp.add(&stemTx{desc: &types.TxDesc{Tx: types.NewTx(testTx(3))}, source: "b"}) {
+		t.Fatal("the transaction of the other peer isn't added")
+	}
+	if sp.remove(types.NewTx(testTx(0)).Hash()) == nil {
+		t.Fatal("the transaction isn't removed")
+	}
+	if !sp.add(&stemTx{desc: &types.TxDesc{Tx: types.NewTx(testTx(4))}, source: "a"}) {
+		t.Fatal("the peer can't stem after its transaction is removed")
+	}
+}
+
+func TestDandelionOwnPool(t *testing.T) {
+	fluffs := []*stemTx{}
+	d := newDandelion(func(relay peer.ID, stx *stemTx) {}, func(stx *stemTx) {
+		fluffs = append(fluffs, stx)
+	}, rand.New(rand.NewSource(5)))
+	d.pool = newStemPool(1, 1)
+	now := time.Now()
+	for {
+		d.tick([]peer.ID{"a", "b"}, now)
+		if !d.diffuser {
+			break
+		}
+		now = now.Add(DandelionEpoch)
+	}
+	if !d.process(&types.TxDesc{Tx: types.NewTx(testTx(1))}, "x", now) {
+		t.Fatal("the transaction of peer isn't stemmed")
+	}
+	// The full stem pool refuses the transactions of peers, but the own
+	// ones are still stemmed.
+	if d.process(&types.TxDesc{Tx: types.NewTx(testTx(2))}, "y", now) {
+		t.Fatal("the full stem pool accepts the transaction of peer")
+	}
+	if !d.process(&types.TxDesc{Tx: types.NewTx(testTx(3))}, "", now) {
+		t.Fatal("the own transaction isn't stemmed")
+	}
+	if len(fluffs) != 0 || d.own.count() != 1 || d.pool.count() != 1 {
+		t.Fatalf("unexpected stem pools %d %d %d", len(fluffs), d.own.count(), d.pool.count())
+	}
+}
@@ -36,8 +36,17 @@ func (s *Sync) sendGetBlockDataRequest(stream network.Stream, pe *peers.Peer) (*
 	return msg, nil
 }
 
-func (s *Sync) sendGetMerkleBlockDataRequest(ctx context.Context, id peer.ID, req *pb.MerkleBlockRequest) (*pb.MerkleBlockResponse, error) {
-	return nil, nil
+func (s *Sync) sendGetMerkleBlockDataRequest(stream network.Stream, pe *peers.Peer) (*pb.MerkleBlockResponse, *common.Error) {
+	e := ReadRspCode(stream, s.p2p)
+	if !e.Code.IsSuccess() {
+		e.Add("get merkle block data request rsp")
+		return nil, e
+	}
+	msg := &pb.MerkleBlockResponse{}
+	if err := DecodeMessage(stream, s.p2p, msg); err != nil {
+		return nil, common.NewError(common.ErrStreamRead, err)
+	}
+	return msg, nil
 }
 
 func (s *Sync) getBlockDataHandler(ctx context.Context, msg interface{}, stream libp2pcore.Stream, pe *peers.Peer) *common.Error {
@@ -75,10 +84,8 @@ func (s *Sync) getMerkleBlockDataHandler(ctx context.Context, msg interface{}, s
 		return ErrMessage(err)
 	}
 	filter := pe.Filter()
-	// Do not send a response if the peer doesn't have a filter loaded.
 	if !filter.IsLoaded() {
-		log.Warn("filter not loaded!")
-		return nil
+		return ErrMessage(fmt.Errorf("filter not loaded"))
 	}
 	bds := []*pb.MerkleBlock{}
 	bd := &pb.MerkleBlockResponse{Data: bds}
@@ -100,6 +107,7 @@ func (s *Sync) getMerkleBlockDataHandler(ctx context.Context, msg interface{}, s
 			Transactions: uint64(merkle.Transactions),
 			Hashes:       changeHashsToPBHashs(merkle.Hashes),
 			Flags:        merkle.Flags,
+			Parents:      changeHashsToPBHashs(block.Block().Parents),
 		}
 		bd.Data = append(bd.Data, &pbbd)
 	}
@@ -150,6 +158,27 @@ func (ps *PeerSync) processGetBlockDatas(pe *peers.Peer, blocks []*hash.Hash) *P
 	if blockDatasLen <= 0 {
 		return &ProcessResult{act: ProcessResultActionContinue, orphan: false}
 	}
+	// Download the block bodies from all the good peers, the sync peer only
+	// requests the blocks which they fail to return.
+	if len(blocksReady) > maxBlocksInFlightPerPeer {
+		fetched := ps.fetchBlocksParallel(pe, blocksReady)
+		missing := []*hash.Hash{}
+		for _, b := range blocksReady {
+			bd, ok := fetched[*b]
+			if !ok {
+				missing = append(missing, b)
+				continue
+			}
+			blockDataM[*b].Block = bd.Block
+			blockDataM[*b].Peer = bd.Peer
+			delete(blockDataM, *b)
+		}
+		log.Debug(fmt.Sprintf("processGetBlockDatas::fetchBlocksParallel received %d/%d", len(blocksReady)-len(missing), len(blocksReady)), "processID", ps.processID)
+		blocksReady = missing
+		if ps.IsInterrupt() {
+			return nil
+		}
+	}
 	readys := len(blocksReady)
 	packageNumber := 0
 	index := 0
@@ -183,6 +212,8 @@ func (ps *PeerSync) processGetBlockDatas(pe *peers.Peer, blocks []*hash.Hash) *P
 				if ok {
 					bdm.Block = block
 					delete(blockDataM, *block.Hash())
+				} else if !ps.sy.p2p.BlockChain().BlockDAG().HasBlock(block.Hash()) {
+					ps.sy.Misbehave(pe, peers.MisbehaviorUnsolicited, fmt.Sprintf("block %s isn't requested", block.Hash()))
 				}
 				if i+1 == len(bd.Locator) {
 					lastBlockHash = block.Hash()
@@ -231,10 +262,17 @@ func (ps *PeerSync) processGetBlockDatas(pe *peers.Peer, blocks []*hash.Hash) *P
 			block = b.Block
 		}
 		//
-		pid := pe.GetID()
+		source := pe
+		if b.Peer != nil {
+			source = b.Peer
+		}
+		pid := source.GetID()
 		_, IsOrphan, err := ps.sy.p2p.BlockChain().ProcessBlock(block, behaviorFlags, &pid)
 		if err != nil {
 			log.Error(fmt.Sprintf("Failed to process block:hash=%s err=%s", block.Hash(), err), "processID", ps.processID)
+			if isInvalidBlockError(err) {
+				ps.sy.Misbehave(source, peers.MisbehaviorInvalidBlock, err.Error())
+			}
 			continue
 		}
 		if IsOrphan {
@@ -285,12 +323,12 @@ func (ps *PeerSync) processGetMerkleBlockDatas(pe *peers.Peer, blocks []*hash.Ha
 		return nil
 	}
 
-	bd, err := ps.sy.sendGetMerkleBlockDataRequest(ps.sy.p2p.Context(), pe.GetID(), &pb.MerkleBlockRequest{Hashes: changeHashsToPBHashs(blocksReady)})
+	ret, err := ps.sy.Send(pe, RPCGetMerkleBlocks, &pb.MerkleBlockRequest{Hashes: changeHashsToPBHashs(blocksReady)})
 	if err != nil {
 		log.Warn(fmt.Sprintf("sendGetMerkleBlockDataRequest send:%v", err))
 		return err
 	}
-	log.Debug(fmt.Sprintf("sendGetMerkleBlockDataRequest:%d", len(bd.Data)))
+	log.Debug(fmt.Sprintf("sendGetMerkleBlockDataRequest:%d", len(ret.(*pb.MerkleBlockResponse).Data)))
 	return nil
 }
 
@@ -362,26 +400,51 @@ func (s *Sync) broadcastBlockHandler(ctx context.Context, msg interface{}, strea
 		return ErrMessage(err)
 	}
 	ret := uint64(0)
-	if s.p2p.BlockChain().BlockDAG().HasBlock(block.Hash()) ||
-		s.p2p.BlockChain().IsOrphan(block.Hash()) ||
-		s.p2p.BlockChain().HasBlockInDB(block.Hash()) {
-		return s.EncodeResponseMsg(stream, ret)
+	if s.processBroadcastBlock(block, pe.GetID()) {
+		ret = 1
 	}
-	for _, ph := range block.Block().Parents {
+	return s.EncodeResponseMsg(stream, ret)
+}
+
+// isKnownBlock returns true if the block is in the DAG, the orphans or the
+// database.
+func (s *Sync) isKnownBlock(h *hash.Hash) bool {
+	return s.p2p.BlockChain().BlockDAG().HasBlock(h) ||
+		s.p2p.BlockChain().IsOrphan(h) ||
+		s.p2p.BlockChain().HasBlockInDB(h)
+}
+
+// canProcessBroadcastBlock returns false if the block is known or its
+// parents are missing, these blocks are left to the synchronization.
+func (s *Sync) canProcessBroadcastBlock(h *hash.Hash, parents []*hash.Hash) bool {
+	if s.isKnownBlock(h) {
+		return false
+	}
+	for _, ph := range parents {
 		if !s.p2p.BlockChain().BlockDAG().HasBlock(ph) {
-			return s.EncodeResponseMsg(stream, ret)
+			return false
 		}
 	}
-	peid := pe.GetID()
+	return true
+}
+
+// processBroadcastBlock processes the block broadcast by the peer in
+// background. It returns false if the block can't be processed.
+func (s *Sync) processBroadcastBlock(block *types.SerializedBlock, pid peer.ID) bool {
+	if !s.canProcessBroadcastBlock(block.Hash(), block.Block().Parents) {
+		return false
+	}
 	go func() {
 		if s.p2p.BlockChain().BlockDAG().HasBlock(block.Hash()) {
 			return
 		}
-		_, _, err = s.p2p.BlockChain().ProcessBlock(block, blockchain.BFBroadcast, &peid)
+		_, _, err := s.p2p.BlockChain().ProcessBlock(block, blockchain.BFBroadcast, &pid)
 		if err != nil {
 			log.Trace("Failed to process block", "hash", block.Hash(), "error", err)
+			if isInvalidBlockError(err) {
+				s.Misbehave(s.peers.Get(pid), peers.MisbehaviorInvalidBlock, err.Error())
+			}
 		}
 	}()
-	ret = 1
-	return s.EncodeResponseMsg(stream, ret)
+	return true
 }
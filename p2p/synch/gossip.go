@@ -0,0 +1,366 @@
+/*
+ * Copyright (c) 2017-2020 The qitmeer developers
+ */
+
+package synch
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/Qitmeer/qng/core/blockchain"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/p2p/common"
+	"github.com/Qitmeer/qng/p2p/encoder"
+	"github.com/Qitmeer/qng/p2p/peers"
+	pb "github.com/Qitmeer/qng/p2p/proto/v1"
+	"github.com/golang/snappy"
+	"github.com/libp2p/go-libp2p-pubsub"
+	pubsub_pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"time"
+)
+
+const (
+	// GossipBlockTopic defines the gossip topic for the new blocks.
+	GossipBlockTopic = "/qitmeer/gossip/block/1"
+	// GossipTxTopic defines the gossip topic for the new transactions.
+	GossipTxTopic = "/qitmeer/gossip/tx/1"
+	// GossipGraphStateTopic defines the gossip topic for the graph state.
+	GossipGraphStateTopic = "/qitmeer/gossip/graphstate/1"
+)
+
+// MaxGossipMessageSize is the maximum size of gossip RPC, it leaves room for
+// the incompressible blocks and the envelope of RPC.
+var MaxGossipMessageSize = snappy.MaxEncodedLen(int(encoder.MaxGossipSize)) + 1024
+
+// The thresholds of gossip score. The application specific score of the peer
+// which reaches the ban threshold is -gossipAppSpecificWeight, its messages
+// are ignored by the router.
+const (
+	gossipAppSpecificWeight  = 100
+	gossipThreshold          = -50
+	gossipPublishThreshold   = -80
+	gossipGraylistThreshold  = -100
+	gossipAcceptPXThreshold  = 20
+	gossipOpportunisticGraft = 2
+	gossipTopicScoreCap      = 50
+)
+
+// GossipScoreParams returns the peer score parameters of gossip router, the
+// application specific score is the misbehavior score of the peer manager.
+func GossipScoreParams(status *peers.Status) (*pubsub.PeerScoreParams, *pubsub.PeerScoreThresholds) {
+	params := &pubsub.PeerScoreParams{
+		Topics:                      map[string]*pubsub.TopicScoreParams{},
+		TopicScoreCap:               gossipTopicScoreCap,
+		AppSpecificScore:            status.GossipScore,
+		AppSpecificWeight:           gossipAppSpecificWeight,
+		IPColocationFactorWeight:    -10,
+		IPColocationFactorThreshold: 10,
+		BehaviourPenaltyWeight:      -10,
+		BehaviourPenaltyThreshold:   6,
+		BehaviourPenaltyDecay:       pubsub.ScoreParameterDecay(10 * time.Minute),
+		DecayInterval:               pubsub.DefaultDecayInterval,
+		DecayToZero:                 pubsub.DefaultDecayToZero,
+		RetainScore:                 time.Hour,
+	}
+	thresholds := &pubsub.PeerScoreThresholds{
+		GossipThreshold:             gossipThreshold,
+		PublishThreshold:            gossipPublishThreshold,
+		GraylistThreshold:           gossipGraylistThreshold,
+		AcceptPXThreshold:           gossipAcceptPXThreshold,
+		OpportunisticGraftThreshold: gossipOpportunisticGraft,
+	}
+	return params, thresholds
+}
+
+// gossipTopicScoreParams returns the score parameters of the gossip topic.
+// The first deliveries of blocks are rewarded, so the mesh is grafted to the
+// peers which propagate the blocks fast.
+func gossipTopicScoreParams(base string) *pubsub.TopicScoreParams {
+	params := &pubsub.TopicScoreParams{
+		TopicWeight:                   1,
+		TimeInMeshWeight:              0.01,
+		TimeInMeshQuantum:             time.Second,
+		TimeInMeshCap:                 300,
+		InvalidMessageDeliveriesDecay: pubsub.ScoreParameterDecay(time.Hour),
+	}
+	switch base {
+	case GossipBlockTopic:
+		params.FirstMessageDeliveriesWeight = 1
+		params.FirstMessageDeliveriesDecay = pubsub.ScoreParameterDecay(time.Hour)
+		params.FirstMessageDeliveriesCap = 20
+		params.InvalidMessageDeliveriesWeight = -100
+	case GossipTxTopic:
+		params.TopicWeight = 0.5
+		params.FirstMessageDeliveriesWeight = 0.1
+		params.FirstMessageDeliveriesDecay = pubsub.ScoreParameterDecay(10 * time.Minute)
+		params.FirstMessageDeliveriesCap = 100
+		params.InvalidMessageDeliveriesWeight = -10
+	case GossipGraphStateTopic:
+		params.TopicWeight = 0.1
+		params.InvalidMessageDeliveriesWeight = -20
+	}
+	return params
+}
+
+// graphStateMsgID identifies the graph state by the publisher, the peers
+// which are synchronized publish the same data.
+func graphStateMsgID(pmsg *pubsub_pb.Message) string {
+	h := common.FastSum256(append(append([]byte{}, pmsg.From...), pmsg.Data...))
+	return base64.URLEncoding.EncodeToString(h[:])
+}
+
+// registerSubscribers joins the gossip topics and subscribes the ones which
+// are handled by the node.
+func (s *Sync) registerSubscribers() {
+	if !s.p2p.Config().GossipRelay() || s.p2p.PubSub() == nil {
+		return
+	}
+	s.gossipLock.Lock()
+	defer s.gossipLock.Unlock()
+
+	s.topics = map[string]*pubsub.Topic{}
+	s.subscribe(GossipBlockTopic, s.validateBlock, s.blockSubscriber)
+	// The transactions of node are published even if it doesn't accept
+	// the transactions of others.
+	if s.p2p.Config().DisableRelayTx {
+		s.subscribe(GossipTxTopic, s.validateTx, nil)
+	} else {
+		s.subscribe(GossipTxTopic, s.validateTx, s.txSubscriber)
+	}
+	s.subscribe(GossipGraphStateTopic, s.validateGraphState, func(*pubsub.Message) {},
+		pubsub.WithTopicMessageIdFn(graphStateMsgID))
+}
+
+func (s *Sync) subscribe(base string, validator pubsub.ValidatorEx, handle func(*pubsub.Message), opts ...pubsub.TopicOpt) {
+	ps := s.p2p.PubSub()
+	topic := getProtocol(s.p2p, base)
+	err := ps.RegisterTopicValidator(topic, validator)
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to register gossip validator %s:%v", topic, err))
+		return
+	}
+	t, err := ps.Join(topic, opts...)
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to join gossip topic %s:%v", topic, err))
+		return
+	}
+	err = t.SetScoreParams(gossipTopicScoreParams(base))
+	if err != nil {
+		log.Warn(fmt.Sprintf("Failed to set score params of gossip topic %s:%v", topic, err))
+	}
+	s.topics[base] = t
+	if handle == nil {
+		return
+	}
+	sub, err := t.Subscribe()
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to subscribe gossip topic %s:%v", topic, err))
+		return
+	}
+	s.subs = append(s.subs, sub)
+	log.Info(fmt.Sprintf("Subscribed gossip topic:%s", topic))
+
+	go func() {
+		for {
+			msg, err := sub.Next(s.p2p.Context())
+			if err != nil {
+				log.Trace("Gossip subscription done", "topic", topic, "error", err)
+				return
+			}
+			if msg.ReceivedFrom == s.p2p.Host().ID() {
+				continue
+			}
+			handle(msg)
+		}
+	}()
+}
+
+func (s *Sync) unregisterSubscribers() {
+	s.gossipLock.Lock()
+	defer s.gossipLock.Unlock()
+
+	for _, sub := range s.subs {
+		sub.Cancel()
+	}
+	s.subs = nil
+	for base, t := range s.topics {
+		err := t.Close()
+		if err != nil {
+			log.Debug("Failed to close gossip topic", "topic", base, "error", err)
+		}
+		err = s.p2p.PubSub().UnregisterTopicValidator(getProtocol(s.p2p, base))
+		if err != nil {
+			log.Debug("Failed to unregister gossip validator", "topic", base, "error", err)
+		}
+	}
+	s.topics = nil
+}
+
+// publish publishes the message to the gossip topic.
+func (s *Sync) publish(base string, msg interface{}) error {
+	s.gossipLock.RLock()
+	t := s.topics[base]
+	s.gossipLock.RUnlock()
+	if t == nil {
+		return fmt.Errorf("Gossip topic %s is not joined", base)
+	}
+	buf := new(bytes.Buffer)
+	_, err := s.p2p.Encoding().EncodeGossip(buf, msg)
+	if err != nil {
+		return err
+	}
+	return t.Publish(s.p2p.Context(), buf.Bytes())
+}
+
+// PublishBlock publishes the block to the gossip topic.
+func (s *Sync) PublishBlock(block *types.SerializedBlock) error {
+	blockBytes, err := block.Bytes()
+	if err != nil {
+		return err
+	}
+	return s.publish(GossipBlockTopic, &pb.BroadcastBlock{Block: &pb.BlockData{BlockBytes: blockBytes}})
+}
+
+// PublishTx publishes the transaction to the gossip topic.
+func (s *Sync) PublishTx(tx *types.Tx) error {
+	txBytes, err := tx.Tx.Serialize()
+	if err != nil {
+		return err
+	}
+	return s.publish(GossipTxTopic, &pb.Transaction{TxBytes: txBytes})
+}
+
+// PublishGraphState publishes the graph state of node to the gossip topic.
+func (s *Sync) PublishGraphState() error {
+	return s.publish(GossipGraphStateTopic, s.getGraphState())
+}
+
+// isSelf returns true if the message is published by the node.
+func (s *Sync) isSelf(pid peer.ID) bool {
+	return pid == s.p2p.Host().ID()
+}
+
+// reject rejects the gossip message and adds the misbehavior to the peer
+// which forwards it.
+func (s *Sync) reject(pid peer.ID, kind peers.Misbehavior, reason string) pubsub.ValidationResult {
+	log.Trace("Reject gossip message", "peer", pid.String(), "reason", reason)
+	s.Misbehave(s.peers.Get(pid), kind, reason)
+	return pubsub.ValidationReject
+}
+
+// validateBlock runs the context free checks of block before it's forwarded,
+// the known blocks aren't forwarded again.
+func (s *Sync) validateBlock(ctx context.Context, pid peer.ID, msg *pubsub.Message) pubsub.ValidationResult {
+	if s.isSelf(pid) {
+		return pubsub.ValidationAccept
+	}
+	m := &pb.BroadcastBlock{}
+	err := s.p2p.Encoding().DecodeGossip(msg.Data, m)
+	if err != nil || m.Block == nil {
+		return s.reject(pid, peers.MisbehaviorProtocol, fmt.Sprintf("gossip block:%v", err))
+	}
+	block, err := types.NewBlockFromBytes(m.Block.BlockBytes)
+	if err != nil {
+		return s.reject(pid, peers.MisbehaviorProtocol, err.Error())
+	}
+	if s.isKnownBlock(block.Hash()) {
+		return pubsub.ValidationIgnore
+	}
+	bc := s.p2p.BlockChain()
+	err = bc.CheckBlockSanity(block, bc.TimeSource(), blockchain.BFNone, bc.ChainParams())
+	if err != nil {
+		if !isInvalidBlockError(err) {
+			return pubsub.ValidationIgnore
+		}
+		return s.reject(pid, peers.MisbehaviorInvalidBlock, err.Error())
+	}
+	msg.ValidatorData = block
+	return pubsub.ValidationAccept
+}
+
+func (s *Sync) blockSubscriber(msg *pubsub.Message) {
+	block, ok := msg.ValidatorData.(*types.SerializedBlock)
+	if !ok {
+		return
+	}
+	s.processBroadcastBlock(block, msg.ReceivedFrom)
+}
+
+// validateTx runs the context free checks of transaction before it's
+// forwarded, the transactions in the mempool aren't forwarded again.
+func (s *Sync) validateTx(ctx context.Context, pid peer.ID, msg *pubsub.Message) pubsub.ValidationResult {
+	if s.isSelf(pid) {
+		return pubsub.ValidationAccept
+	}
+	// The transactions of others aren't forwarded when the relay is
+	// disabled.
+	if s.p2p.Config().DisableRelayTx {
+		return pubsub.ValidationIgnore
+	}
+	// The transactions can't be checked by the mempool before the node is
+	// synchronized.
+	if !s.p2p.IsCurrent() {
+		return pubsub.ValidationIgnore
+	}
+	m := &pb.Transaction{}
+	err := s.p2p.Encoding().DecodeGossip(msg.Data, m)
+	if err != nil {
+		return s.reject(pid, peers.MisbehaviorProtocol, fmt.Sprintf("gossip tx:%v", err))
+	}
+	tx := changePBTxToTx(m)
+	if tx == nil {
+		return s.reject(pid, peers.MisbehaviorProtocol, "undecodable gossip tx")
+	}
+	txh := tx.TxHash()
+	// The stem transaction is diffused by the others
+	s.dandelion.remove(&txh)
+	if s.p2p.TxMemPool().HaveTransaction(&txh) {
+		return pubsub.ValidationIgnore
+	}
+	if tx.IsCoinBase() {
+		return s.reject(pid, peers.MisbehaviorInvalidTx, fmt.Sprintf("gossip coinbase %s", txh.String()))
+	}
+	err = blockchain.CheckTransactionSanity(types.NewTx(tx), s.p2p.Config().Params, nil, s.p2p.BlockChain())
+	if err != nil {
+		return s.reject(pid, peers.MisbehaviorInvalidTx, err.Error())
+	}
+	msg.ValidatorData = m
+	return pubsub.ValidationAccept
+}
+
+func (s *Sync) txSubscriber(msg *pubsub.Message) {
+	m, ok := msg.ValidatorData.(*pb.Transaction)
+	if !ok {
+		return
+	}
+	_, err := s.handleTxMsg(m, msg.ReceivedFrom)
+	if err != nil {
+		log.Trace(err.Error())
+	}
+}
+
+// validateGraphState updates the graph state of the publisher. The graph state
+// describes the DAG of the direct peer, so it's never forwarded.
+func (s *Sync) validateGraphState(ctx context.Context, pid peer.ID, msg *pubsub.Message) pubsub.ValidationResult {
+	if s.isSelf(pid) {
+		return pubsub.ValidationAccept
+	}
+	if msg.GetFrom() != pid {
+		return pubsub.ValidationIgnore
+	}
+	pe := s.peers.Get(pid)
+	if pe == nil || !pe.IsConnected() {
+		return pubsub.ValidationIgnore
+	}
+	gs := &pb.GraphState{}
+	err := s.p2p.Encoding().DecodeGossip(msg.Data, gs)
+	if err != nil {
+		return s.reject(pid, peers.MisbehaviorProtocol, fmt.Sprintf("gossip graph state:%v", err))
+	}
+	pe.UpdateGraphState(gs)
+	go s.peerSync.PeerUpdate(pe, false)
+	return pubsub.ValidationIgnore
+}
@@ -0,0 +1,127 @@
+package synch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Qitmeer/qng/core/blockchain"
+	"github.com/Qitmeer/qng/p2p/peers"
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p-pubsub"
+	pubsub_pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+func testGossipSub(t *testing.T, ctx context.Context) (host.Host, *pubsub.PubSub) {
+	h, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		h.Close()
+	})
+	ps, err := pubsub.NewGossipSub(ctx, h,
+		pubsub.WithMessageSigning(false),
+		pubsub.WithStrictSignatureVerification(false),
+		pubsub.WithMaxMessageSize(MaxGossipMessageSize),
+		pubsub.WithPeerScore(GossipScoreParams(peers.NewStatus(nil))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h, ps
+}
+
+func TestGossipTopics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	h1, ps1 := testGossipSub(t, ctx)
+	h2, ps2 := testGossipSub(t, ctx)
+
+	topics := map[string]*pubsub.Topic{}
+	for _, base := range []string{GossipBlockTopic, GossipTxTopic, GossipGraphStateTopic} {
+		topic, err := ps1.Join(base)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := topic.SetScoreParams(gossipTopicScoreParams(base)); err != nil {
+			t.Fatalf("%s:%v", base, err)
+		}
+		topics[base] = topic
+	}
+
+	// The rejected messages aren't delivered
+	err := ps2.RegisterTopicValidator(GossipTxTopic, func(ctx context.Context, pid peer.ID, msg *pubsub.Message) pubsub.ValidationResult {
+		if string(msg.Data) == "invalid" {
+			return pubsub.ValidationReject
+		}
+		return pubsub.ValidationAccept
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	topic, err := ps2.Join(GossipTxTopic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, err := topic.Subscribe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h1.Connect(ctx, peer.AddrInfo{ID: h2.ID(), Addrs: h2.Addrs()}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(topics[GossipTxTopic].ListPeers()) <= 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the subscription isn't announced")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, data := range []string{"invalid", "valid"} {
+		if err := topics[GossipTxTopic].Publish(ctx, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rctx, rcancel := context.WithTimeout(ctx, 5*time.Second)
+	defer rcancel()
+	msg, err := sub.Next(rctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(msg.Data) != "valid" || msg.ReceivedFrom != h1.ID() {
+		t.Fatalf("unexpected message %s from %s", msg.Data, msg.ReceivedFrom)
+	}
+}
+
+func TestGraphStateMsgID(t *testing.T) {
+	data := []byte("graphstate")
+	first := graphStateMsgID(&pubsub_pb.Message{From: []byte("first"), Data: data})
+	second := graphStateMsgID(&pubsub_pb.Message{From: []byte("second"), Data: data})
+	if first == second {
+		t.Fatal("the graph states of different publishers have the same id")
+	}
+	if first != graphStateMsgID(&pubsub_pb.Message{From: []byte("first"), Data: data}) {
+		t.Fatal("the message id isn't deterministic")
+	}
+}
+
+func TestIsInvalidBlockError(t *testing.T) {
+	tests := []struct {
+		err     error
+		invalid bool
+	}{
+		{blockchain.RuleError{ErrorCode: blockchain.ErrBadMerkleRoot}, true},
+		{blockchain.RuleError{ErrorCode: blockchain.ErrTimeTooNew}, false},
+		{blockchain.RuleError{ErrorCode: blockchain.ErrDuplicateBlock}, false},
+		{blockchain.RuleError{ErrorCode: blockchain.ErrInvalidatedBlock}, false},
+		{blockchain.RuleError{ErrorCode: blockchain.ErrMissingParent}, false},
+		{context.Canceled, false},
+	}
+	for _, test := range tests {
+		if isInvalidBlockError(test.err) != test.invalid {
+			t.Errorf("unexpected result of %v", test.err)
+		}
+	}
+}
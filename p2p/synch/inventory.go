@@ -73,6 +73,8 @@ func (s *Sync) handleInventory(msg *pb.Inventory, pe *peers.Peer) error {
 		if InvType(inv.Type) == InvTypeBlock {
 			hasBlocks = true
 		} else if InvType(inv.Type) == InvTypeTx {
+			// The stem transaction is diffused by the others
+			s.dandelion.remove(h)
 			if s.p2p.Config().DisableRelayTx ||
 				!isCurrent {
 				continue
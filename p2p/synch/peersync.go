@@ -12,6 +12,7 @@ import (
 	"github.com/Qitmeer/qng/core/protocol"
 	"github.com/Qitmeer/qng/core/types"
 	"github.com/Qitmeer/qng/meerdag"
+	"github.com/Qitmeer/qng/p2p/common"
 	"github.com/Qitmeer/qng/p2p/peers"
 	pb "github.com/Qitmeer/qng/p2p/proto/v1"
 	"github.com/Qitmeer/qng/services/notifymgr/notify"
@@ -127,6 +128,7 @@ out:
 				if err != nil {
 					log.Trace(err.Error())
 				}
+				ps.updateSyncPeerLag()
 			case *syncDAGBlocksMsg:
 				ps.processSyncDAGBlocks(msg.pe)
 			case *PeerUpdateMsg:
@@ -143,6 +145,7 @@ out:
 			}
 
 		case <-stallTicker.C:
+			ps.updateSyncPeerLag()
 			ps.handleStallSample()
 
 		case <-ps.quit:
@@ -177,9 +180,30 @@ func (ps *PeerSync) handleStallSample() {
 	if ps.lastBlockID != lbid {
 		return
 	}
+	if ps.HasSyncPeer() && !ps.IsCurrent() {
+		ps.sy.Misbehave(ps.SyncPeer(), peers.MisbehaviorStall, "no block is added by sync")
+	}
 	ps.TryAgainUpdateSyncPeer(true)
 }
 
+// updateSyncPeerLag updates the number of main chain blocks by which the
+// local chain is behind the best peer it can sync from.
+func (ps *PeerSync) updateSyncPeerLag() {
+	best := ps.Chain().BestSnapshot()
+	lag := int64(0)
+	for _, sp := range ps.sy.peers.CanSyncPeers() {
+		gs := sp.GraphState()
+		if gs == nil {
+			continue
+		}
+		d := int64(gs.GetMainHeight()) - int64(best.GraphState.GetMainHeight())
+		if d > lag {
+			lag = d
+		}
+	}
+	common.SyncPeerLagGauge.Update(lag)
+}
+
 func (ps *PeerSync) Pause() bool {
 	c := make(chan bool)
 	ps.msgChan <- pauseMsg{c}
@@ -383,6 +407,10 @@ func (ps *PeerSync) getBestPeer() *peers.Peer {
 		// doesn't have a later block when it's equal, it will likely
 		// have one soon so it is a reasonable choice.  It also allows
 		// the case where both are at 0 such as during regression test.
+		// Light peers only keep headers, so they can not serve blocks.
+		if !protocol.HasServices(sp.Services(), protocol.Full) {
+			continue
+		}
 		gs := sp.GraphState()
 		if gs == nil {
 			continue
@@ -607,6 +635,14 @@ func (ps *PeerSync) RelayInventory(nds []*notify.NotifyData) {
 }
 
 func (ps *PeerSync) RelayGraphState() {
+	if ps.sy.p2p.Config().GossipRelay() {
+		if err := ps.sy.PublishGraphState(); err != nil {
+			log.Debug(fmt.Sprintf("Failed to publish graph state:%v", err))
+		}
+	}
+	if !ps.sy.p2p.Config().StreamRelay() {
+		return
+	}
 	for _, pe := range ps.sy.Peers().CanSyncPeers() {
 		ps.UpdateGraphState(pe)
 	}
@@ -7,6 +7,7 @@ package synch
 import (
 	"fmt"
 	"github.com/Qitmeer/qng/common/roughtime"
+	"github.com/Qitmeer/qng/core/blockchain"
 	"github.com/Qitmeer/qng/p2p/common"
 	"github.com/Qitmeer/qng/p2p/peers"
 	"github.com/Qitmeer/qng/p2p/runutil"
@@ -36,6 +37,13 @@ func (s *Sync) maintainPeerStatuses() {
 					return
 				}
 
+				if pe.IsBanned() && !s.IsWhitePeer(id) {
+					if err := s.sendGoodByeAndDisconnect(common.ErrBadPeer, pe); err != nil {
+						log.Debug(fmt.Sprintf("Error when disconnecting with banned peer: %v", err))
+					}
+					return
+				}
+
 				if !pe.CanConnectWithNetwork() {
 					if err := s.sendGoodByeAndDisconnect(common.ErrBadPeer, pe); err != nil {
 						log.Debug(fmt.Sprintf("Error when disconnecting with bad peer: %v", err))
@@ -78,9 +86,53 @@ func (s *Sync) maintainPeerStatuses() {
 			}
 			s.LookupNode(nil, node)
 		}
+		if err := s.peers.SaveBans(); err != nil {
+			log.Warn(fmt.Sprintf("Failed to save bans:%v", err))
+		}
 	})
 }
 
+// peerExemptBlockErrors are the rule errors of the blocks that aren't the
+// faults of peer, such as the blocks which are known or invalidated by the
+// operator and the blocks which may be valid later.
+var peerExemptBlockErrors = map[blockchain.ErrorCode]struct{}{
+	blockchain.ErrDuplicateBlock:      {},
+	blockchain.ErrInvalidatedBlock:    {},
+	blockchain.ErrTimeTooNew:          {},
+	blockchain.ErrMissingParent:       {},
+	blockchain.ErrParentsBlockUnknown: {},
+}
+
+// isInvalidBlockError returns true if the block from peer is invalid by the
+// consensus rules.
+func isInvalidBlockError(err error) bool {
+	rerr, ok := err.(blockchain.RuleError)
+	if !ok {
+		return false
+	}
+	_, exempt := peerExemptBlockErrors[rerr.ErrorCode]
+	return !exempt
+}
+
+// Misbehave adds the score of misbehavior to the peer, the peer is banned
+// and disconnected when its score reaches the threshold.
+func (s *Sync) Misbehave(pe *peers.Peer, kind peers.Misbehavior, reason string) {
+	if pe == nil || s.IsWhitePeer(pe.GetID()) {
+		return
+	}
+	if !pe.Misbehave(kind, reason) {
+		return
+	}
+	if err := s.peers.SaveBans(); err != nil {
+		log.Warn(fmt.Sprintf("Failed to save bans:%v", err))
+	}
+	go func() {
+		if err := s.sendGoodByeAndDisconnect(common.ErrBadPeer, pe); err != nil {
+			log.Debug(fmt.Sprintf("Error when disconnecting with banned peer: %v", err))
+		}
+	}()
+}
+
 func (s *Sync) reValidatePeer(pe *peers.Peer) error {
 	if _, err := s.Send(pe, RPCChainState, s.getChainState()); err != nil {
 		return err
@@ -12,13 +12,16 @@ import (
 	"github.com/Qitmeer/qng/p2p/peers"
 	pb "github.com/Qitmeer/qng/p2p/proto/v1"
 	"github.com/Qitmeer/qng/params"
+	"github.com/libp2p/go-libp2p-pubsub"
 	libp2pcore "github.com/libp2p/go-libp2p/core"
 	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/libp2p/go-libp2p/core/protocol"
 	"io"
+	"math/rand"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -64,6 +67,16 @@ const (
 	RPCStateRoot = "/qitmeer/req/stateroot/1"
 	// RPCBroadcastBlock defines the topic for the broadcast block rpc method.
 	RPCBroadcastBlock = "/qitmeer/req/broadcastblock/1"
+	// RPCGetCFilters defines the topic for the getcfilters rpc method.
+	RPCGetCFilters = "/qitmeer/req/getcfilters/1"
+	// RPCGetCFHeaders defines the topic for the getcfheaders rpc method.
+	RPCGetCFHeaders = "/qitmeer/req/getcfheaders/1"
+	// RPCCompactBlock defines the topic for the compact block rpc method.
+	RPCCompactBlock = "/qitmeer/req/compactblock/1"
+	// RPCGetBlockTxs defines the topic for the get block txs rpc method.
+	RPCGetBlockTxs = "/qitmeer/req/getblocktxs/1"
+	// RPCStemTx defines the topic for the stem tx rpc method.
+	RPCStemTx = "/qitmeer/req/stemtx/1"
 )
 
 // Time to first byte timeout. The maximum time to wait for first byte of
@@ -94,6 +107,12 @@ type Sync struct {
 
 	disconnectionNotify *network.NotifyBundle
 	connectionNotify    *network.NotifyBundle
+
+	gossipLock sync.RWMutex
+	topics     map[string]*pubsub.Topic
+	subs       []*pubsub.Subscription
+
+	dandelion *dandelion
 }
 
 func (s *Sync) Start() error {
@@ -104,10 +123,15 @@ func (s *Sync) Start() error {
 
 	s.maintainPeerStatuses()
 
+	if s.p2p.Config().Dandelion {
+		s.dandelion.start(s.dandelionCandidates)
+	}
 	return s.peerSync.Start()
 }
 
 func (s *Sync) Stop() error {
+	s.unregisterSubscribers()
+	s.dandelion.stop()
 	if s.connectionNotify != nil {
 		s.p2p.Host().Network().StopNotify(s.connectionNotify)
 	}
@@ -119,7 +143,7 @@ func (s *Sync) Stop() error {
 
 func (s *Sync) registerHandlers() {
 	s.registerRPCHandlers()
-	//s.registerSubscribers()
+	s.registerSubscribers()
 }
 
 // registerRPCHandlers for p2p RPC.
@@ -244,6 +268,36 @@ func (s *Sync) registerRPCHandlers() {
 		&pb.BroadcastBlock{},
 		s.broadcastBlockHandler,
 	)
+
+	s.registerRPC(
+		RPCGetCFilters,
+		&pb.GetCFilters{},
+		s.getCFiltersHandler,
+	)
+
+	s.registerRPC(
+		RPCGetCFHeaders,
+		&pb.GetCFHeaders{},
+		s.getCFHeadersHandler,
+	)
+
+	s.registerRPC(
+		RPCCompactBlock,
+		&pb.CompactBlock{},
+		s.compactBlockHandler,
+	)
+
+	s.registerRPC(
+		RPCGetBlockTxs,
+		&pb.GetBlockTxs{},
+		s.getBlockTxsHandler,
+	)
+
+	s.registerRPC(
+		RPCStemTx,
+		&pb.Transaction{},
+		s.stemTxHandler,
+	)
 }
 
 // registerRPC for a given topic with an expected protobuf message type.
@@ -278,6 +332,8 @@ func (s *Sync) Send(pe *peers.Peer, protocol string, message interface{}) (inter
 		ret, e = s.sendGetBlockDataRequest(stream, pe)
 	case RPCGetBlocks:
 		ret, e = s.sendGetBlocksRequest(stream, pe)
+	case RPCGetMerkleBlocks:
+		ret, e = s.sendGetMerkleBlockDataRequest(stream, pe)
 	case RPCGraphState:
 		ret, e = s.sendGraphStateRequest(stream, pe)
 	case RPCInventory:
@@ -300,6 +356,16 @@ func (s *Sync) Send(pe *peers.Peer, protocol string, message interface{}) (inter
 		ret, e = s.sendStateRootRequest(stream, pe)
 	case RPCBroadcastBlock:
 		e = s.sendBroadcastBlockRequest(stream, pe)
+	case RPCGetCFilters:
+		ret, e = s.sendGetCFiltersRequest(stream, pe)
+	case RPCGetCFHeaders:
+		ret, e = s.sendGetCFHeadersRequest(stream, pe)
+	case RPCCompactBlock:
+		e = s.sendCompactBlockRequest(stream, pe)
+	case RPCGetBlockTxs:
+		ret, e = s.sendGetBlockTxsRequest(stream, pe)
+	case RPCStemTx:
+		e = s.sendStemTxRequest(stream, pe)
 	default:
 		return nil, fmt.Errorf("Can't support:%s", protocol)
 	}
@@ -343,6 +409,7 @@ func NewSync(p2p peers.P2P) *Sync {
 		PeerInterval: params.ActiveNetParams.TargetTimePerBlock * 2,
 		LANPeers:     map[peer.ID]struct{}{}}
 	sy.peerSync = NewPeerSync(sy)
+	sy.dandelion = newDandelion(sy.stemTx, sy.fluffTx, rand.New(rand.NewSource(time.Now().UnixNano())))
 
 	for _, pid := range p2p.Config().LANPeers {
 		peid, err := peer.Decode(pid)
@@ -8,10 +8,12 @@ import (
 	"context"
 	"fmt"
 	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/core/message"
 	"github.com/Qitmeer/qng/core/types"
 	"github.com/Qitmeer/qng/p2p/common"
 	"github.com/Qitmeer/qng/p2p/peers"
 	pb "github.com/Qitmeer/qng/p2p/proto/v1"
+	"github.com/Qitmeer/qng/services/mempool"
 	libp2pcore "github.com/libp2p/go-libp2p/core"
 	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
@@ -71,19 +73,42 @@ func (s *Sync) handleTxMsg(msg *pb.Transaction, pid peer.ID) (*hash.Hash, error)
 	if tx == nil {
 		return nil, fmt.Errorf("message is not type *pb.Transaction")
 	}
+	return s.processTx(tx, pid)
+}
+
+// processTx adds the transaction of peer to the mempool and announces the
+// accepted transactions to the other peers.
+func (s *Sync) processTx(tx *types.Transaction, pid peer.ID) (*hash.Hash, error) {
 	txh := tx.TxHash()
 	// Process the transaction to include validation, insertion in the
 	// memory pool, orphan handling, etc.
 	allowOrphans := s.p2p.Config().MaxOrphanTxs > 0
 	acceptedTxs, err := s.p2p.TxMemPool().ProcessTransaction(types.NewTx(tx), allowOrphans, true, true)
 	if err != nil {
+		if isInvalidTxError(err) {
+			s.Misbehave(s.peers.Get(pid), peers.MisbehaviorInvalidTx, err.Error())
+		}
 		return &txh, fmt.Errorf("Failed to process transaction %v: %v\n", tx.TxHash().String(), err.Error())
 	}
+	// The transactions are diffused, their stems are done
+	for _, txd := range acceptedTxs {
+		s.dandelion.remove(txd.Tx.Hash())
+	}
 	s.p2p.Notify().AnnounceNewTransactions(acceptedTxs, []peer.ID{pid})
 
 	return &txh, nil
 }
 
+// isInvalidTxError returns true if the transaction is rejected by the
+// mempool for being invalid, the peer which sends it misbehaves.
+func isInvalidTxError(err error) bool {
+	if _, ok := err.(mempool.RuleError); !ok {
+		return false
+	}
+	code, _ := mempool.ErrToRejectErr(err)
+	return code == message.RejectInvalid || code == message.RejectMalformed
+}
+
 func (ps *PeerSync) processGetTxs(pe *peers.Peer, otxs []*hash.Hash) error {
 	if len(otxs) <= 0 {
 		return nil
@@ -52,6 +52,65 @@ type Checkpoint struct {
 	Hash  *hash.Hash
 }
 
+// AssumeUTXO pins the UTXO set snapshot at the block of order, a node can only
+// load the snapshot that matches the block hash, the count of outputs and the
+// MuHash commitment.
+type AssumeUTXO struct {
+	Order  uint64
+	Hash   *hash.Hash
+	Count  uint64
+	MuHash *hash.Hash
+}
+
+// ConsensusDeployment defines details related to a specific consensus rule
+// change that is voted in by the miners through the block version bits.
+//
+// The heights are main chain heights, the state of a deployment only changes
+// at the start of a confirmation window.
+type ConsensusDeployment struct {
+	// Name is the human-readable identifier of the deployment.
+	Name string
+
+	// BitNumber defines the specific bit number within the block version
+	// this particular soft-fork deployment refers to.
+	BitNumber uint8
+
+	// StartHeight is the main height from which the miners start signalling
+	// for the deployment.
+	StartHeight uint64
+
+	// TimeoutHeight is the main height at which the deployment fails when
+	// it has not been locked in yet.
+	TimeoutHeight uint64
+}
+
+// DeploymentNever is a main height which is never reached, a deployment
+// starting at it is never voted on and one timing out at it never fails.
+const DeploymentNever = ^uint64(0)
+
+// Constants that define the deployment offset in the deployments field of the
+// parameters for each deployment.  This is useful to be able to get the details
+// of a specific deployment by name.
+const (
+	// DeploymentTestDummy defines the rule change deployment ID for testing
+	// purposes.
+	DeploymentTestDummy = iota
+
+	// DeploymentTaproot defines the rule change deployment ID for the
+	// validation of the taproot spends.
+	DeploymentTaproot
+
+	// DeploymentStake defines the rule change deployment ID for the stake
+	// transactions.
+	DeploymentStake
+
+	// NOTE: DefinedDeployments must always come last since it is used to
+	// determine how many defined deployments there currently are.
+
+	// DefinedDeployments is the number of currently defined deployments.
+	DefinedDeployments
+)
+
 // Params defines a qitmeer network by its parameters.  These parameters may be
 // used by qitmeer applications to differentiate networks as well as addresses
 // and keys for one network from those intended for use on another network.
@@ -181,6 +240,25 @@ type Params struct {
 	// Checkpoints ordered from oldest to newest.
 	Checkpoints []Checkpoint
 
+	// The trusted UTXO set snapshots ordered from oldest to newest.
+	AssumeUTXOs []AssumeUTXO
+
+	// These fields are related to voting on consensus rule changes as
+	// defined by BIP0009.
+	//
+	// RuleChangeActivationThreshold is the number of main chain blocks in a
+	// confirmation window that must signal a rule change for it to be
+	// locked in.
+	//
+	// MinerConfirmationWindow is the number of main chain blocks in each
+	// threshold state retarget window.
+	//
+	// Deployments define the specific consensus rule changes to be voted
+	// on.
+	RuleChangeActivationThreshold uint32
+	MinerConfirmationWindow       uint32
+	Deployments                   [DefinedDeployments]ConsensusDeployment
+
 	// Mempool parameters
 	RelayNonStdTxs bool
 
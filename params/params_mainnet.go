@@ -100,6 +100,32 @@ var MainNetParams = Params{
 	// Checkpoints ordered from oldest to newest.
 	Checkpoints: []Checkpoint{},
 
+	// Consensus rule change deployments.
+	//
+	// The thresholds are measured over windows of main chain blocks.
+	RuleChangeActivationThreshold: 1916, // 95% of MinerConfirmationWindow
+	MinerConfirmationWindow:       2016, // (2016 * 30s) ~= 16.8 hours
+	Deployments: [DefinedDeployments]ConsensusDeployment{
+		DeploymentTestDummy: {
+			Name:          "testdummy",
+			BitNumber:     28,
+			StartHeight:   DeploymentNever,
+			TimeoutHeight: DeploymentNever,
+		},
+		DeploymentTaproot: {
+			Name:          "taproot",
+			BitNumber:     0,
+			StartHeight:   DeploymentNever,
+			TimeoutHeight: DeploymentNever,
+		},
+		DeploymentStake: {
+			Name:          "stake",
+			BitNumber:     1,
+			StartHeight:   DeploymentNever,
+			TimeoutHeight: DeploymentNever,
+		},
+	},
+
 	// Address encoding magics
 	NetworkAddressPrefix: "M",
 	Bech32HRPSegwit:      "m",
@@ -103,6 +103,32 @@ var MixNetParams = Params{
 	// Checkpoints ordered from oldest to newest.
 	Checkpoints: []Checkpoint{},
 
+	// Consensus rule change deployments.
+	//
+	// The thresholds are measured over windows of main chain blocks.
+	RuleChangeActivationThreshold: 1512, // 75% of MinerConfirmationWindow
+	MinerConfirmationWindow:       2016, // (2016 * 1s) ~= 34 minutes
+	Deployments: [DefinedDeployments]ConsensusDeployment{
+		DeploymentTestDummy: {
+			Name:          "testdummy",
+			BitNumber:     28,
+			StartHeight:   DeploymentNever,
+			TimeoutHeight: DeploymentNever,
+		},
+		DeploymentTaproot: {
+			Name:          "taproot",
+			BitNumber:     0,
+			StartHeight:   DeploymentNever,
+			TimeoutHeight: DeploymentNever,
+		},
+		DeploymentStake: {
+			Name:          "stake",
+			BitNumber:     1,
+			StartHeight:   DeploymentNever,
+			TimeoutHeight: DeploymentNever,
+		},
+	},
+
 	// Address encoding magics
 	NetworkAddressPrefix: "X",
 	Bech32HRPSegwit:      "x",
@@ -137,6 +137,32 @@ var PrivNetParams = Params{
 	// Checkpoints ordered from oldest to newest.
 	Checkpoints: nil,
 
+	// Consensus rule change deployments.
+	//
+	// The thresholds are measured over windows of main chain blocks.
+	RuleChangeActivationThreshold: 108, // 75% of MinerConfirmationWindow
+	MinerConfirmationWindow:       144, // Faster than normal for privnet (144 instead of 2016)
+	Deployments: [DefinedDeployments]ConsensusDeployment{
+		DeploymentTestDummy: {
+			Name:          "testdummy",
+			BitNumber:     28,
+			StartHeight:   0,
+			TimeoutHeight: DeploymentNever,
+		},
+		DeploymentTaproot: {
+			Name:          "taproot",
+			BitNumber:     0,
+			StartHeight:   0,
+			TimeoutHeight: DeploymentNever,
+		},
+		DeploymentStake: {
+			Name:          "stake",
+			BitNumber:     1,
+			StartHeight:   0,
+			TimeoutHeight: DeploymentNever,
+		},
+	},
+
 	// Address encoding magics
 	NetworkAddressPrefix: "R",
 	Bech32HRPSegwit:      "r",
@@ -90,3 +90,16 @@ func checkPrefix(t *testing.T, prefix string, targetString, networkName string,
 		t.FailNow()
 	}
 }
+
+// test the deployments of all qitmeer network params don't share bits
+func TestDeploymentBits(t *testing.T) {
+	for _, params := range []*Params{&MainNetParams, &TestNetParams, &MixNetParams, &PrivNetParams} {
+		bits := map[uint8]string{}
+		for _, deployment := range params.Deployments {
+			if exist, ok := bits[deployment.BitNumber]; ok {
+				t.Fatalf("%s: %s and %s share the bit %d", params.Name, exist, deployment.Name, deployment.BitNumber)
+			}
+			bits[deployment.BitNumber] = deployment.Name
+		}
+	}
+}
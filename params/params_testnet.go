@@ -103,6 +103,34 @@ var TestNetParams = Params{
 	// Checkpoints ordered from oldest to newest.
 	Checkpoints: []Checkpoint{},
 
+	// Consensus rule change deployments.
+	//
+	// The thresholds are measured over windows of main chain blocks.
+	RuleChangeActivationThreshold: 1512, // 75% of MinerConfirmationWindow
+	MinerConfirmationWindow:       2016, // (2016 * 3s) ~= 1.7 hours
+	Deployments: [DefinedDeployments]ConsensusDeployment{
+		DeploymentTestDummy: {
+			Name:          "testdummy",
+			BitNumber:     28,
+			StartHeight:   DeploymentNever,
+			TimeoutHeight: DeploymentNever,
+		},
+		DeploymentTaproot: {
+			Name:          "taproot",
+			BitNumber:     0,
+			StartHeight:   DeploymentNever,
+			TimeoutHeight: DeploymentNever,
+		},
+		// The stake transactions are activated once the upgraded miners
+		// signal it in a window.
+		DeploymentStake: {
+			Name:          "stake",
+			BitNumber:     1,
+			StartHeight:   0,
+			TimeoutHeight: DeploymentNever,
+		},
+	},
+
 	// Address encoding magics
 	NetworkAddressPrefix: "T",
 	Bech32HRPSegwit:      "t",
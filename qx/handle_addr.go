@@ -7,6 +7,9 @@ import (
 	"github.com/Qitmeer/qng/common/hash"
 	"github.com/Qitmeer/qng/core/address"
 	"github.com/Qitmeer/qng/crypto/ecc"
+	"github.com/Qitmeer/qng/crypto/ecc/schnorr"
+	"github.com/Qitmeer/qng/crypto/ecc/secp256k1"
+	"github.com/Qitmeer/qng/engine/txscript"
 	"github.com/Qitmeer/qng/meerevm/common"
 	"github.com/Qitmeer/qng/params"
 )
@@ -120,6 +123,44 @@ func EcPubKeyToPKAddressSTDO(version string, pubkey string) {
 	fmt.Printf("%s\n", addr.String())
 }
 
+// EcPubKeyToTaprootAddressSTDO prints the taproot address of the EC public
+// key, which is tweaked without any script as recommended by BIP86.
+func EcPubKeyToTaprootAddressSTDO(version string, pubkey string) {
+	data, err := hex.DecodeString(pubkey)
+	if err != nil {
+		ErrExit(err)
+	}
+
+	pubKey, err := secp256k1.ParsePubKey(data)
+	if err != nil {
+		ErrExit(err)
+	}
+	var param *params.Params
+	switch version {
+	case "mainnet":
+		param = &params.MainNetParams
+	case "privnet":
+		param = &params.PrivNetParams
+	case "testnet":
+		param = &params.TestNetParams
+	case "mixnet":
+		param = &params.MixNetParams
+	default:
+		param = &params.MainNetParams
+	}
+
+	outputKey, err := txscript.ComputeTaprootKeyNoScript(pubKey)
+	if err != nil {
+		ErrExit(err)
+	}
+	addr, err := address.NewAddressTaproot(schnorr.SerializeXOnlyPubKey(outputKey), param)
+	if err != nil {
+		ErrExit(err)
+	}
+
+	fmt.Printf("%s\n", addr.String())
+}
+
 func EcPubKeyToETHAddressSTDO(pubkey string) {
 	pubkeyHex, err := hex.DecodeString(pubkey)
 	if err != nil {
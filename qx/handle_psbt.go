@@ -0,0 +1,255 @@
+package qx
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/Qitmeer/qng/common/marshal"
+	"github.com/Qitmeer/qng/core/address"
+	"github.com/Qitmeer/qng/core/json"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/core/types/psbt"
+	"github.com/Qitmeer/qng/engine/txscript"
+	"github.com/Qitmeer/qng/params"
+)
+
+// PsbtCreate creates a partially signed transaction from the unsigned raw
+// transaction of tx-encode.
+func PsbtCreate(rawTxStr string) (string, error) {
+	rawTxStr = strings.Split(rawTxStr, MTX_STR_SEPERATE)[0]
+	if len(rawTxStr)%2 != 0 {
+		return "", fmt.Errorf("invaild raw transaction : %s", rawTxStr)
+	}
+	serializedTx, err := hex.DecodeString(rawTxStr)
+	if err != nil {
+		return "", err
+	}
+	var tx types.Transaction
+	err = tx.Deserialize(bytes.NewReader(serializedTx))
+	if err != nil {
+		return "", err
+	}
+	p, err := psbt.New(&tx)
+	if err != nil {
+		return "", err
+	}
+	return p.B64Encode()
+}
+
+// PsbtUpdate adds the previous outputs and the redeem scripts to the partially
+// signed transaction. The target of previous output is an address or a
+// base16 pkscript, the pkscript is cltvpubkeyhash if the lock time is set.
+func PsbtUpdate(psbtStr string, prevOuts PsbtPrevOutsFlag, redeemScripts PsbtRedeemScriptsFlag) (string, error) {
+	p, err := psbt.NewFromBase64(psbtStr)
+	if err != nil {
+		return "", err
+	}
+	for _, po := range prevOuts.prevOuts {
+		var pkScript []byte
+		addr, err := address.DecodeAddress(po.target)
+		if err == nil {
+			if po.lockTime > 0 {
+				pkScript, err = txscript.PayToCLTVPubKeyHashScript(addr.Script(), po.lockTime)
+			} else {
+				pkScript, err = txscript.PayToAddrScript(addr)
+			}
+		} else {
+			pkScript, err = hex.DecodeString(po.target)
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid previous output target %s: %v", po.target, err)
+		}
+		atomic, err := types.NewAmount(po.amount)
+		if err != nil {
+			return "", err
+		}
+		atomic.Id = types.CoinID(po.coinid)
+		err = p.AddPrevOut(po.index, *atomic, pkScript)
+		if err != nil {
+			return "", err
+		}
+	}
+	for _, rs := range redeemScripts {
+		script, err := hex.DecodeString(rs)
+		if err != nil {
+			return "", err
+		}
+		if p.AddRedeemScript(script) <= 0 {
+			return "", fmt.Errorf("no input matches the redeem script %s", rs)
+		}
+	}
+	return p.B64Encode()
+}
+
+// PsbtSign adds the signatures of the private keys to the partially signed
+// transaction.
+func PsbtSign(privkeyStrs []string, psbtStr string) (string, error) {
+	p, err := psbt.NewFromBase64(psbtStr)
+	if err != nil {
+		return "", err
+	}
+	for _, k := range privkeyStrs {
+		key, err := hex.DecodeString(k)
+		if err != nil {
+			return "", err
+		}
+		n, err := p.Sign(key)
+		if err != nil {
+			return "", err
+		}
+		if n <= 0 {
+			return "", fmt.Errorf("no input can be signed by the private key")
+		}
+	}
+	return p.B64Encode()
+}
+
+// PsbtCombine merges the partially signed transactions.
+func PsbtCombine(psbtStrs []string) (string, error) {
+	if len(psbtStrs) <= 0 {
+		return "", fmt.Errorf("no partially signed transaction")
+	}
+	packets := make([]*psbt.Packet, 0, len(psbtStrs))
+	for _, str := range psbtStrs {
+		p, err := psbt.NewFromBase64(str)
+		if err != nil {
+			return "", err
+		}
+		packets = append(packets, p)
+	}
+	err := packets[0].Combine(packets[1:]...)
+	if err != nil {
+		return "", err
+	}
+	return packets[0].B64Encode()
+}
+
+// PsbtFinalize builds the signature scripts of all the inputs.
+func PsbtFinalize(psbtStr string) (string, error) {
+	p, err := psbt.NewFromBase64(psbtStr)
+	if err != nil {
+		return "", err
+	}
+	err = p.Finalize()
+	if err != nil {
+		return "", err
+	}
+	return p.B64Encode()
+}
+
+// PsbtExtract returns the signed raw transaction of the finalized partially
+// signed transaction.
+func PsbtExtract(psbtStr string) (string, error) {
+	p, err := psbt.NewFromBase64(psbtStr)
+	if err != nil {
+		return "", err
+	}
+	tx, err := p.Extract()
+	if err != nil {
+		return "", err
+	}
+	return marshal.MessageToHex(tx)
+}
+
+func PsbtDecode(network string, psbtStr string) {
+	var param *params.Params
+	switch network {
+	case "mainnet":
+		param = &params.MainNetParams
+	case "testnet":
+		param = &params.TestNetParams
+	case "privnet":
+		param = &params.PrivNetParams
+	case "mixnet":
+		param = &params.MixNetParams
+	default:
+		ErrExit(fmt.Errorf("invalid network (mainnet|testnet|privnet|mixnet)"))
+	}
+	p, err := psbt.NewFromBase64(psbtStr)
+	if err != nil {
+		ErrExit(err)
+	}
+	inputs := []json.OrderedResult{}
+	for i, in := range p.Inputs {
+		class, have, required := p.InputStatus(i)
+		input := json.OrderedResult{}
+		if in.PrevOut != nil {
+			input = append(input,
+				json.KV{Key: "amount", Val: in.PrevOut.Amount.Value},
+				json.KV{Key: "coinid", Val: in.PrevOut.Amount.Id},
+				json.KV{Key: "type", Val: class.String()})
+		}
+		input = append(input, json.KV{Key: "final", Val: in.IsFinalized()})
+		if !in.IsFinalized() {
+			input = append(input,
+				json.KV{Key: "signatures", Val: have},
+				json.KV{Key: "required", Val: required})
+		}
+		inputs = append(inputs, input)
+	}
+	tx := p.UnsignedTx
+	jsonPsbt := &json.OrderedResult{
+		{Key: "txid", Val: tx.TxHash().String()},
+		{Key: "version", Val: int32(tx.Version)},
+		{Key: "locktime", Val: tx.LockTime},
+		{Key: "vin", Val: marshal.MarshJsonVin(tx)},
+		{Key: "vout", Val: marshal.MarshJsonVout(tx, nil, param)},
+		{Key: "inputs", Val: inputs},
+		{Key: "complete", Val: p.IsComplete()},
+	}
+	marshaled, err := jsonPsbt.MarshalJSON()
+	if err != nil {
+		ErrExit(err)
+	}
+	fmt.Printf("%s", marshaled)
+}
+
+func PsbtCreateSTDO(rawTxStr string) {
+	str, err := PsbtCreate(rawTxStr)
+	if err != nil {
+		ErrExit(err)
+	}
+	fmt.Printf("%s\n", str)
+}
+
+func PsbtUpdateSTDO(psbtStr string, prevOuts PsbtPrevOutsFlag, redeemScripts PsbtRedeemScriptsFlag) {
+	str, err := PsbtUpdate(psbtStr, prevOuts, redeemScripts)
+	if err != nil {
+		ErrExit(err)
+	}
+	fmt.Printf("%s\n", str)
+}
+
+func PsbtSignSTDO(privkeyStrs []string, psbtStr string) {
+	str, err := PsbtSign(privkeyStrs, psbtStr)
+	if err != nil {
+		ErrExit(err)
+	}
+	fmt.Printf("%s\n", str)
+}
+
+func PsbtCombineSTDO(psbtStrs []string) {
+	str, err := PsbtCombine(psbtStrs)
+	if err != nil {
+		ErrExit(err)
+	}
+	fmt.Printf("%s\n", str)
+}
+
+func PsbtFinalizeSTDO(psbtStr string) {
+	str, err := PsbtFinalize(psbtStr)
+	if err != nil {
+		ErrExit(err)
+	}
+	fmt.Printf("%s\n", str)
+}
+
+func PsbtExtractSTDO(psbtStr string) {
+	str, err := PsbtExtract(psbtStr)
+	if err != nil {
+		ErrExit(err)
+	}
+	fmt.Printf("%s\n", str)
+}
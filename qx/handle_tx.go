@@ -81,6 +81,7 @@ func TxEncode(version uint32, lockTime uint32, timestamp *time.Time, inputs []In
 		case *address.PubKeyHashAddress:
 		case *address.SecpPubKeyAddress:
 		case *address.ScriptHashAddress:
+		case *address.AddressTaproot:
 		default:
 			return "", fmt.Errorf("unsupport address type: %T", addr)
 		}
@@ -102,6 +103,22 @@ func TxEncode(version uint32, lockTime uint32, timestamp *time.Time, inputs []In
 			if err != nil {
 				return "", err
 			}
+		case txscript.WitnessTaprootTy:
+			if _, ok := addr.(*address.AddressTaproot); !ok {
+				return "", fmt.Errorf("locktype is %v but the out address is: %v , not the AddressTaproot", o.OutputType.String(), addr)
+			}
+			pkScript, err = txscript.PayToAddrScript(addr)
+			if err != nil {
+				return "", err
+			}
+		case txscript.ScriptHashTy:
+			if _, ok := addr.(*address.ScriptHashAddress); !ok {
+				return "", fmt.Errorf("locktype is %v but the out address is: %v , not the ScriptHashAddress", o.OutputType.String(), addr)
+			}
+			pkScript, err = txscript.PayToAddrScript(addr)
+			if err != nil {
+				return "", err
+			}
 		case txscript.PubKeyTy:
 			if _, ok := addr.(*address.SecpPubKeyAddress); !ok {
 				return "", fmt.Errorf("locktype is %v but the out address is: %v , not the SecpPubKeyAddress", o.OutputType.String(), addr)
@@ -1,10 +1,15 @@
 package qx
 
 import (
+	"encoding/hex"
 	"fmt"
+	"github.com/Qitmeer/qng/core/address"
 	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/crypto/ecc"
 	"github.com/Qitmeer/qng/engine/txscript"
+	"github.com/Qitmeer/qng/params"
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 	"time"
 )
@@ -152,3 +157,82 @@ func TestGPSToCompact(t *testing.T) {
 	// output :
 	// 36284416
 }
+
+func TestPsbt(t *testing.T) {
+	keys := []string{
+		"c39fb9103419af8be42385f3d6390b4c0c8f2cb67cf24dd43a059c4045d1a409",
+		"0101010101010101010101010101010101010101010101010101010101010101",
+		"0202020202020202020202020202020202020202020202020202020202020202",
+	}
+	pks := []*address.SecpPubKeyAddress{}
+	for _, k := range keys {
+		kb, _ := hex.DecodeString(k)
+		_, pub := ecc.Secp256k1.PrivKeyFromBytes(kb)
+		pk, err := address.NewSecpPubKeyAddress(pub.SerializeCompressed(), &params.MixNetParams)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pks = append(pks, pk)
+	}
+	redeemScript, err := txscript.MultiSigScript(pks, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2sh, err := address.NewScriptHashAddress(redeemScript, &params.MixNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Spend a 2-of-3 multisig output, the change is back to the multisig
+	raw, err := TxEncode(1, 0, nil, []Input{{
+		TxID:     "25517e3b3759365e80a164a3d4d2db2462c5d6888e4bd874c5fbfbb6fb130b41",
+		OutIndex: 0,
+	}}, []Output{{
+		TargetAddress: "TnTf7hM9kzm7ssvQ7RAcrjni5jGQbVykd2w",
+		Amount:        types.Amount{Value: 1e8},
+		OutputType:    txscript.PubKeyHashTy,
+	}, {
+		TargetAddress: p2sh.String(),
+		Amount:        types.Amount{Value: 8e8},
+		OutputType:    txscript.ScriptHashTy,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := PsbtCreate(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var prevOuts PsbtPrevOutsFlag
+	if err := prevOuts.Set("0:" + p2sh.String() + ":10:0"); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := PsbtUpdate(created, prevOuts, PsbtRedeemScriptsFlag{hex.EncodeToString(redeemScript)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := PsbtSign(keys[:1], updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := PsbtFinalize(first); err == nil {
+		t.Fatal("finalized with one signature")
+	}
+	second, err := PsbtSign(keys[2:], updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	combined, err := PsbtCombine([]string{first, second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	final, err := PsbtFinalize(combined)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := PsbtExtract(final)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, strings.HasPrefix(signed, strings.Split(raw, MTX_STR_SEPERATE)[0][:8]))
+}
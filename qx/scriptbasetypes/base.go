@@ -24,6 +24,8 @@ func NewTxSignObject(scripttype txscript.ScriptClass, lockTime int64) TxSignBase
 		}
 	case txscript.PubKeyTy:
 		s = &PubKeyScript{}
+	case txscript.WitnessTaprootTy:
+		s = &TaprootScript{}
 	case SPECIAL_CROSS_VAL:
 		s = &CrossImportScript{}
 	default:
@@ -41,6 +43,10 @@ func GetScriptType(scriptTyp string) txscript.ScriptClass {
 		return txscript.PubKeyTy
 	case txscript.CLTVPubKeyHashTy.String():
 		return txscript.CLTVPubKeyHashTy
+	case txscript.WitnessTaprootTy.String():
+		return txscript.WitnessTaprootTy
+	case txscript.ScriptHashTy.String():
+		return txscript.ScriptHashTy
 	case SPECIAL_CROSS_TYPE:
 		return SPECIAL_CROSS_VAL // special script
 	default:
@@ -0,0 +1,50 @@
+package scriptbasetypes
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/Qitmeer/qng/core/address"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/crypto/ecc"
+	"github.com/Qitmeer/qng/crypto/ecc/schnorr"
+	"github.com/Qitmeer/qng/crypto/ecc/secp256k1"
+	"github.com/Qitmeer/qng/engine/txscript"
+	"github.com/Qitmeer/qng/params"
+)
+
+// TaprootScript signs the key path spend of the taproot output, which is
+// tweaked without any script (BIP86).
+type TaprootScript struct {
+}
+
+func (this *TaprootScript) Sign(privKey string, mtx *types.Transaction, inputIndex int, param *params.Params) error {
+	privkeyByte, err := hex.DecodeString(privKey)
+	if err != nil {
+		return err
+	}
+	if len(privkeyByte) != 32 {
+		return fmt.Errorf("invaid ec private key bytes: %d", len(privkeyByte))
+	}
+	privateKey, pubkey := secp256k1.PrivKeyFromBytes(privkeyByte)
+	outputKey, err := txscript.ComputeTaprootKeyNoScript(pubkey)
+	if err != nil {
+		return err
+	}
+	addr, err := address.NewAddressTaproot(schnorr.SerializeXOnlyPubKey(outputKey), param)
+	if err != nil {
+		return err
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return err
+	}
+	var kdb txscript.KeyClosure = func(types.Address) (ecc.PrivateKey, bool, error) {
+		return privateKey, true, nil
+	}
+	sigScript, err := txscript.SignTxOutput(param, mtx, inputIndex, pkScript, txscript.SigHashDefault, kdb, nil, nil, ecc.ECDSA_Secp256k1)
+	if err != nil {
+		return err
+	}
+	mtx.TxIn[inputIndex].SignScript = sigScript
+	return nil
+}
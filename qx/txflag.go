@@ -173,3 +173,72 @@ func (of *TxOutputsFlag) Set(s string) error {
 		target, amount, coinid, scripttype, args})
 	return nil
 }
+
+// PsbtPrevOutsFlag is the set of previous outputs of the inputs of partially
+// signed transaction.
+type PsbtPrevOutsFlag struct {
+	prevOuts []psbtPrevOut
+}
+
+type psbtPrevOut struct {
+	index    int
+	target   string
+	amount   float64
+	coinid   int64
+	lockTime int64
+}
+
+func (p psbtPrevOut) String() string {
+	return fmt.Sprintf("%d:%s:%f:%d:%d", p.index, p.target, p.amount, p.coinid, p.lockTime)
+}
+
+func (pf PsbtPrevOutsFlag) String() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("{")
+	for _, p := range pf.prevOuts {
+		buffer.WriteString(p.String())
+	}
+	buffer.WriteString("}")
+	return buffer.String()
+}
+
+func (pf *PsbtPrevOutsFlag) Set(s string) error {
+	prevOut := strings.Split(s, ":")
+	if len(prevOut) < 4 {
+		return fmt.Errorf("error to parse previous output : %s", s)
+	}
+	index, err := strconv.ParseUint(prevOut[0], 10, 32)
+	if err != nil {
+		return err
+	}
+	amount, err := strconv.ParseFloat(prevOut[2], 64)
+	if err != nil {
+		return err
+	}
+	coinid, err := strconv.ParseInt(prevOut[3], 10, 64)
+	if err != nil {
+		return err
+	}
+	lockTime := int64(0)
+	if len(prevOut) >= 5 {
+		lockTime, err = strconv.ParseInt(prevOut[4], 10, 64)
+		if err != nil {
+			return err
+		}
+	}
+	pf.prevOuts = append(pf.prevOuts, psbtPrevOut{
+		int(index), prevOut[1], amount, coinid, lockTime})
+	return nil
+}
+
+// PsbtRedeemScriptsFlag is the set of redeem scripts in base16.
+type PsbtRedeemScriptsFlag []string
+
+func (v *PsbtRedeemScriptsFlag) Set(s string) error {
+	*v = append(*v, s)
+	return nil
+}
+
+func (v PsbtRedeemScriptsFlag) String() string {
+	return strings.Join(v, ",")
+}
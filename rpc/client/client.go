@@ -363,6 +363,23 @@ func (c *Client) handleNotification(ntfn *rawNotification) {
 
 		c.ntfnHandlers.OnTxAcceptedVerbose(c, rawTx)
 
+	// OnTxReplaced
+	case cmds.TxReplacedNtfnMethod:
+		// Ignore the notification if the client is not interested in
+		// it.
+		if c.ntfnHandlers.OnTxReplaced == nil {
+			return
+		}
+
+		txHash, replaced, err := parseTxReplacedNtfnParams(ntfn.Params)
+		if err != nil {
+			log.Warn(fmt.Sprintf("Received invalid tx replaced "+
+				"notification: %v", err))
+			return
+		}
+
+		c.ntfnHandlers.OnTxReplaced(txHash, replaced)
+
 	// OnTxConfirm
 	case cmds.TxConfirmNtfnMethod:
 		// Ignore the notification if the client is not interested in
@@ -412,6 +429,23 @@ func (c *Client) handleNotification(ntfn *rawNotification) {
 
 		c.ntfnHandlers.OnRescanFinish(rawTx)
 
+	// OnRescanJob
+	case cmds.RescanJobNtfnMethod:
+		// Ignore the notification if the client is not interested in
+		// it.
+		if c.ntfnHandlers.OnRescanJob == nil {
+			return
+		}
+
+		job, err := parseRescanJob(ntfn.Params)
+		if err != nil {
+			log.Warn(fmt.Sprintf("Received invalid rescan job "+
+				"notification: %v", err))
+			return
+		}
+
+		c.ntfnHandlers.OnRescanJob(job)
+
 	// OnNodeExit
 	case cmds.NodeExitMethod:
 		// Ignore the notification if the client is not interested in
@@ -854,7 +888,9 @@ out:
 }
 
 var ignoreResends = map[string]struct{}{
-	"rescan": {},
+	"rescan":          {},
+	"startRescanJob":  {},
+	"resumeRescanJob": {},
 }
 
 func (c *Client) resendRequests() {
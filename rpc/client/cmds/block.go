@@ -58,6 +58,31 @@ func NewRescanFinishedNtfn(hash, txHash string, order uint64, time int64) *Resca
 	}
 }
 
+// RescanJobNtfn defines the rescanjob JSON-RPC notification.
+//
+type RescanJobNtfn struct {
+	ID       uint64
+	State    string
+	Order    uint64
+	EndOrder uint64
+	Rate     float64
+	Matches  uint64
+}
+
+// NewRescanJobNtfn returns a new instance which can be used to issue a
+// rescanjob JSON-RPC notification.
+//
+func NewRescanJobNtfn(id uint64, state string, order uint64, endOrder uint64, rate float64, matches uint64) *RescanJobNtfn {
+	return &RescanJobNtfn{
+		ID:       id,
+		State:    state,
+		Order:    order,
+		EndOrder: endOrder,
+		Rate:     rate,
+		Matches:  matches,
+	}
+}
+
 // RedeemingTxNtfn defines the redeemingtx JSON-RPC notification.
 //
 type RedeemingTxNtfn struct {
@@ -21,6 +21,36 @@ func NotifyRescanCmd(beginBlock, endBlock uint64, addrs []string, op []OutPoint)
 	}
 }
 
+// StartRescanJobCmd defines the startRescanJob JSON-RPC command. It starts
+// a rescan in background, an EndBlock of zero means the current tip.
+type StartRescanJobCmd struct {
+	BeginBlock uint64
+	Addresses  []string
+	OutPoints  []OutPoint
+	EndBlock   uint64
+}
+
+func NewStartRescanJobCmd(beginBlock, endBlock uint64, addrs []string, op []OutPoint) *StartRescanJobCmd {
+	return &StartRescanJobCmd{
+		BeginBlock: beginBlock,
+		EndBlock:   endBlock,
+		Addresses:  addrs,
+		OutPoints:  op,
+	}
+}
+
+// ResumeRescanJobCmd defines the resumeRescanJob JSON-RPC command. It
+// resumes a paused rescan job from the last processed order.
+type ResumeRescanJobCmd struct {
+	ID uint64
+}
+
+func NewResumeRescanJobCmd(id uint64) *ResumeRescanJobCmd {
+	return &ResumeRescanJobCmd{
+		ID: id,
+	}
+}
+
 type SessionCmd struct{}
 
 func NewSessionCmd() *SessionCmd {
@@ -47,4 +77,6 @@ func init() {
 	MustRegisterCmd("stopNotifyBlocks", (*StopNotifyBlocksCmd)(nil), flags, NotifyNameSpace)
 	MustRegisterCmd("session", (*SessionCmd)(nil), flags, NotifyNameSpace)
 	MustRegisterCmd("rescan", (*RescanCmd)(nil), flags, NotifyNameSpace)
+	MustRegisterCmd("startRescanJob", (*StartRescanJobCmd)(nil), flags, NotifyNameSpace)
+	MustRegisterCmd("resumeRescanJob", (*ResumeRescanJobCmd)(nil), flags, NotifyNameSpace)
 }
@@ -174,4 +174,5 @@ type JsonRequestStatus struct {
 	MaxTimeReqID string `json:"maxtimereqid"`
 	MinTimeReqID string `json:"mintimereqid"`
 	RunningNum   int    `json:"runningnum"`
+	RateLimited  int    `json:"ratelimited"`
 }
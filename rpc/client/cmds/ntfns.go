@@ -12,9 +12,11 @@ const (
 	ReorganizationNtfnMethod    = "reorganization"
 	TxAcceptedNtfnMethod        = "txaccepted"
 	TxAcceptedVerboseNtfnMethod = "txacceptedverbose"
+	TxReplacedNtfnMethod        = "txreplaced"
 	TxConfirmNtfnMethod         = "txconfirm"
 	RescanProgressNtfnMethod    = "rescanprocess"
 	RescanCompleteNtfnMethod    = "rescancomplete"
+	RescanJobNtfnMethod         = "rescanjob"
 	NodeExitMethod              = "nodeexit"
 	BlockTemplateNtfnMethod     = "blocktemplate"
 )
@@ -93,6 +95,18 @@ func NewTxAcceptedNtfn(txHash string, amounts types.AmountGroup) *TxAcceptedNtfn
 	}
 }
 
+type TxReplacedNtfn struct {
+	TxID     string
+	Replaced []string
+}
+
+func NewTxReplacedNtfn(txHash string, replaced []string) *TxReplacedNtfn {
+	return &TxReplacedNtfn{
+		TxID:     txHash,
+		Replaced: replaced,
+	}
+}
+
 type TxConfirmResult struct {
 	Confirms uint64
 	Tx       string
@@ -128,9 +142,11 @@ func init() {
 	MustRegisterCmd(ReorganizationNtfnMethod, (*ReorganizationNtfn)(nil), flags, NotifyNameSpace)
 	MustRegisterCmd(TxAcceptedNtfnMethod, (*TxAcceptedNtfn)(nil), flags, NotifyNameSpace)
 	MustRegisterCmd(TxAcceptedVerboseNtfnMethod, (*TxAcceptedVerboseNtfn)(nil), flags, NotifyNameSpace)
+	MustRegisterCmd(TxReplacedNtfnMethod, (*TxReplacedNtfn)(nil), flags, NotifyNameSpace)
 	MustRegisterCmd(TxConfirmNtfnMethod, (*NotificationTxConfirmNtfn)(nil), flags, NotifyNameSpace)
 	MustRegisterCmd(RescanProgressNtfnMethod, (*RescanProgressNtfn)(nil), flags, NotifyNameSpace)
 	MustRegisterCmd(RescanCompleteNtfnMethod, (*RescanFinishedNtfn)(nil), flags, NotifyNameSpace)
+	MustRegisterCmd(RescanJobNtfnMethod, (*RescanJobNtfn)(nil), flags, NotifyNameSpace)
 	MustRegisterCmd(NodeExitMethod, (*NodeExitNtfn)(nil), flags, NotifyNameSpace)
 	MustRegisterCmd(BlockTemplateNtfnMethod, (*BlockTemplateNtfn)(nil), flags, NotifyNameSpace)
 }
@@ -23,9 +23,11 @@ type NotificationHandlers struct {
 	OnReorganization    func(hash *hash.Hash, order int64, olds []*hash.Hash)
 	OnTxAccepted        func(hash *hash.Hash, amounts types.AmountGroup)
 	OnTxAcceptedVerbose func(c *Client, tx *j.DecodeRawTransactionResult)
+	OnTxReplaced        func(hash *hash.Hash, replaced []*hash.Hash)
 	OnTxConfirm         func(txConfirm *cmds.TxConfirmResult)
 	OnRescanProgress    func(param *cmds.RescanProgressNtfn)
 	OnRescanFinish      func(param *cmds.RescanFinishedNtfn)
+	OnRescanJob         func(param *cmds.RescanJobNtfn)
 	OnNodeExit          func(nodeExit *cmds.NodeExitNtfn)
 	OnBlockTemplate     func(bt *j.RemoteGBTResult)
 
@@ -168,6 +170,39 @@ func parseTxAcceptedNtfnParams(params []json.RawMessage) (*hash.Hash,
 	return txHash, amouts, nil
 }
 
+func parseTxReplacedNtfnParams(params []json.RawMessage) (*hash.Hash,
+	[]*hash.Hash, error) {
+
+	if len(params) != 2 {
+		return nil, nil, wrongNumParams(len(params))
+	}
+
+	var txHashStr string
+	err := json.Unmarshal(params[0], &txHashStr)
+	if err != nil {
+		return nil, nil, err
+	}
+	txHash, err := hash.NewHashFromStr(txHashStr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var replacedStrs []string
+	err = json.Unmarshal(params[1], &replacedStrs)
+	if err != nil {
+		return nil, nil, err
+	}
+	replaced := make([]*hash.Hash, 0, len(replacedStrs))
+	for _, s := range replacedStrs {
+		h, err := hash.NewHashFromStr(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		replaced = append(replaced, h)
+	}
+	return txHash, replaced, nil
+}
+
 func parseTxAcceptedVerboseNtfnParams(params []json.RawMessage) (*j.DecodeRawTransactionResult,
 	error) {
 
@@ -226,6 +261,21 @@ func parseRescanProgress(params []json.RawMessage) (*cmds.RescanProgressNtfn,
 	}, nil
 }
 
+func parseRescanJob(params []json.RawMessage) (*cmds.RescanJobNtfn, error) {
+	if len(params) != 6 {
+		return nil, wrongNumParams(len(params))
+	}
+	var job cmds.RescanJobNtfn
+	fields := []interface{}{&job.ID, &job.State, &job.Order, &job.EndOrder, &job.Rate, &job.Matches}
+	for i, field := range fields {
+		err := json.Unmarshal(params[i], field)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &job, nil
+}
+
 func parseRescanFinish(params []json.RawMessage) (*cmds.RescanFinishedNtfn,
 	error) {
 
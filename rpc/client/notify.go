@@ -1,7 +1,9 @@
 package client
 
 import (
+	"encoding/json"
 	"errors"
+	j "github.com/Qitmeer/qng/core/json"
 	"github.com/Qitmeer/qng/core/types"
 	"github.com/Qitmeer/qng/rpc/client/cmds"
 )
@@ -122,6 +124,53 @@ func (c *Client) Rescan(beginBlock, endBlock uint64, addrs []string, op []cmds.O
 	return c.RescanAsync(beginBlock, endBlock, addrs, op).Receive()
 }
 
+type FutureRescanJobResult chan *response
+
+func (r FutureRescanJobResult) Receive() (*j.RescanJobResult, error) {
+	res, err := receiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+	var job j.RescanJobResult
+	err = json.Unmarshal(res, &job)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// StartRescanJobAsync starts a rescan job in background, the progress and
+// matches are sent by notifications.
+func (c *Client) StartRescanJobAsync(beginBlock, endBlock uint64, addrs []string, op []cmds.OutPoint) FutureRescanJobResult {
+	// Not supported in HTTP POST mode.
+	if c.config.HTTPPostMode {
+		return FutureRescanJobResult(newFutureError(ErrWebsocketsRequired))
+	}
+
+	cmd := cmds.NewStartRescanJobCmd(beginBlock, endBlock, addrs, op)
+	return c.sendCmd(cmd)
+}
+
+func (c *Client) StartRescanJob(beginBlock, endBlock uint64, addrs []string, op []cmds.OutPoint) (*j.RescanJobResult, error) {
+	return c.StartRescanJobAsync(beginBlock, endBlock, addrs, op).Receive()
+}
+
+// ResumeRescanJobAsync resumes a paused rescan job from the last processed
+// order.
+func (c *Client) ResumeRescanJobAsync(id uint64) FutureRescanJobResult {
+	// Not supported in HTTP POST mode.
+	if c.config.HTTPPostMode {
+		return FutureRescanJobResult(newFutureError(ErrWebsocketsRequired))
+	}
+
+	cmd := cmds.NewResumeRescanJobCmd(id)
+	return c.sendCmd(cmd)
+}
+
+func (c *Client) ResumeRescanJob(id uint64) (*j.RescanJobResult, error) {
+	return c.ResumeRescanJobAsync(id).Receive()
+}
+
 func (c *Client) NotifyTxsConfirmedAsync(txs []cmds.TxConfirm) FutureNotifyBlocksResult {
 	// Not supported in HTTP POST mode.
 	if c.config.HTTPPostMode {
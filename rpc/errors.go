@@ -28,6 +28,30 @@ func (e *methodNotFoundError) Error() string {
 	return fmt.Sprintf("The method %s%s%s does not exist/is not available", e.service, serviceMethodSeparator, e.method)
 }
 
+// the RPC user isn't allowed to call the method
+type unauthorizedError struct {
+	service string
+	method  string
+}
+
+func (e *unauthorizedError) ErrorCode() int { return -32001 }
+
+func (e *unauthorizedError) Error() string {
+	return fmt.Sprintf("The method %s%s%s is not allowed for the RPC user", e.service, serviceMethodSeparator, formatName(e.method))
+}
+
+// the client exceeds the rate limit of RPC requests
+type rateLimitedError struct {
+	service string
+	method  string
+}
+
+func (e *rateLimitedError) ErrorCode() int { return -32005 }
+
+func (e *rateLimitedError) Error() string {
+	return fmt.Sprintf("The rate limit is exceeded by %s%s%s, try again later", e.service, serviceMethodSeparator, e.method)
+}
+
 // received message isn't a valid request
 type invalidRequestError struct{ message string }
 
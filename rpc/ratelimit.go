@@ -0,0 +1,191 @@
+// Copyright (c) 2017-2018 The qitmeer developers
+
+package rpc
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Qitmeer/qng/config"
+	"golang.org/x/net/context"
+)
+
+const (
+	// The idle buckets are pruned by this interval
+	rateLimitPruneInterval = time.Minute
+
+	// defaultRPCMethodCost is the cost of the methods without cost weight
+	defaultRPCMethodCost = 1
+)
+
+// The default cost weights of the expensive methods
+var defaultRPCMethodCosts = map[string]float64{
+	"getRawTransactions":  10,
+	"getBlockhashByRange": 10,
+	"getTxOutSetInfo":     50,
+}
+
+// tokenBucket is refilled by the rate of limiter and up to its burst
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter limits the cost of RPC requests of every client IP and every
+// non-admin credential with the token buckets.
+type rateLimiter struct {
+	lock sync.Mutex
+
+	rate  float64
+	burst float64
+	costs map[string]float64
+
+	buckets   map[string]*tokenBucket
+	lastPrune time.Time
+
+	now func() time.Time
+}
+
+func newRateLimiter(cfg *config.Config) (*rateLimiter, error) {
+	if cfg.RPCRateLimit <= 0 {
+		return nil, nil
+	}
+	if cfg.RPCRateBurst <= 0 {
+		return nil, fmt.Errorf("The rpcrateburst must be greater than 0")
+	}
+	rl := &rateLimiter{
+		rate:    cfg.RPCRateLimit,
+		burst:   float64(cfg.RPCRateBurst),
+		costs:   map[string]float64{},
+		buckets: map[string]*tokenBucket{},
+		now:     time.Now,
+	}
+	for k, v := range defaultRPCMethodCosts {
+		rl.costs[k] = v
+	}
+	for _, mc := range cfg.RPCMethodCosts {
+		kv := strings.Split(mc, "=")
+		if len(kv) != 2 || len(kv[0]) <= 0 {
+			return nil, fmt.Errorf("Invalid rpcmethodcost (%s): expected <method>=<cost>", mc)
+		}
+		cost, err := strconv.ParseFloat(kv[1], 64)
+		if err != nil || cost < 0 {
+			return nil, fmt.Errorf("Invalid rpcmethodcost (%s): the cost must be a non-negative number", mc)
+		}
+		rl.costs[kv[0]] = cost
+	}
+	return rl, nil
+}
+
+// cost returns the cost weight of method, the weight of namespace_method
+// takes precedence over method. The cost is at most the burst, otherwise the
+// method can never be called.
+func (rl *rateLimiter) cost(namespace string, method string) float64 {
+	c, ok := rl.costs[namespace+serviceMethodSeparator+method]
+	if !ok {
+		c, ok = rl.costs[method]
+		if !ok {
+			c = defaultRPCMethodCost
+		}
+	}
+	if c > rl.burst {
+		return rl.burst
+	}
+	return c
+}
+
+// allow consumes the cost from all the buckets of keys, the request is
+// rejected without any consumption if one of them doesn't have enough tokens.
+func (rl *rateLimiter) allow(keys []string, cost float64) bool {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	now := rl.now()
+	rl.prune(now)
+	buckets := make([]*tokenBucket, 0, len(keys))
+	for _, key := range keys {
+		b, ok := rl.buckets[key]
+		if !ok {
+			b = &tokenBucket{tokens: rl.burst, last: now}
+			rl.buckets[key] = b
+		}
+		b.tokens += now.Sub(b.last).Seconds() * rl.rate
+		if b.tokens > rl.burst {
+			b.tokens = rl.burst
+		}
+		b.last = now
+		if b.tokens < cost {
+			return false
+		}
+		buckets = append(buckets, b)
+	}
+	for _, b := range buckets {
+		b.tokens -= cost
+	}
+	return true
+}
+
+// prune removes the buckets that are refilled, they are the same as new ones.
+func (rl *rateLimiter) prune(now time.Time) {
+	if now.Sub(rl.lastPrune) < rateLimitPruneInterval {
+		return
+	}
+	rl.lastPrune = now
+	for key, b := range rl.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*rl.rate >= rl.burst {
+			delete(rl.buckets, key)
+		}
+	}
+}
+
+// rateLimitKeys returns the bucket keys of the client of context, the admin
+// users aren't limited.
+func rateLimitKeys(ctx context.Context) []string {
+	keys := []string{}
+	user, ok := ctx.Value(rpcAuthKey{}).(*rpcUser)
+	if ok && user != nil {
+		if user.isAdmin() {
+			return nil
+		}
+		if len(user.name) > 0 {
+			keys = append(keys, "user:"+user.name)
+		}
+	}
+	if remote, ok := ctx.Value("remote").(string); ok && len(remote) > 0 {
+		host, _, err := net.SplitHostPort(remote)
+		if err != nil {
+			host = remote
+		}
+		keys = append(keys, "ip:"+host)
+	}
+	return keys
+}
+
+// rateLimit rejects the requests that exceed the rate limit of the client,
+// every request of a batch is counted.
+func (s *RpcServer) rateLimit(ctx context.Context, reqs []*serverRequest) {
+	if s.rateLimiter == nil {
+		return
+	}
+	keys := rateLimitKeys(ctx)
+	if len(keys) <= 0 {
+		return
+	}
+	for _, req := range reqs {
+		if req.err != nil || req.callb == nil {
+			continue
+		}
+		cost := s.rateLimiter.cost(req.svcname, formatName(req.callb.method.Name))
+		if s.rateLimiter.allow(keys, cost) {
+			continue
+		}
+		req.err = &rateLimitedError{req.svcname, formatName(req.callb.method.Name)}
+		s.AddRateLimitedStatus(req)
+		log.Debug("RPC request is rate limited", "client", strings.Join(keys, ","),
+			"method", req.svcname+serviceMethodSeparator+req.callb.method.Name)
+	}
+}
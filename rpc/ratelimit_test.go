@@ -0,0 +1,104 @@
+// Copyright (c) 2017-2018 The qitmeer developers
+
+package rpc
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Qitmeer/qng/config"
+	"github.com/Qitmeer/qng/rpc/api"
+	"golang.org/x/net/context"
+)
+
+func TestRateLimiter(t *testing.T) {
+	for _, mc := range []string{"getInfo", "=1", "getInfo=x", "getInfo=-1"} {
+		cfg := &config.Config{RPCRateLimit: 1, RPCRateBurst: 10, RPCMethodCosts: []string{mc}}
+		if _, err := newRateLimiter(cfg); err == nil {
+			t.Fatalf("expected error of %s", mc)
+		}
+	}
+	rl, err := newRateLimiter(&config.Config{})
+	if err != nil || rl != nil {
+		t.Fatal("the rate limiter should be disabled")
+	}
+
+	cfg := &config.Config{RPCRateLimit: 2, RPCRateBurst: 10,
+		RPCMethodCosts: []string{"getInfo=4", "test_getInfo=0", "heavy=100"}}
+	rl, err = newRateLimiter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rl.cost("qitmeer", "getInfo") != 4 || rl.cost("test", "getInfo") != 0 ||
+		rl.cost("qitmeer", "other") != defaultRPCMethodCost || rl.cost("qitmeer", "heavy") != 10 ||
+		rl.cost("qitmeer", "getRawTransactions") != 10 {
+		t.Fatal("unexpected method cost")
+	}
+
+	now := time.Unix(1000, 0)
+	rl.now = func() time.Time { return now }
+	keys := []string{"user:a", "ip:1.1.1.1"}
+	if !rl.allow(keys, 6) || !rl.allow(keys, 4) {
+		t.Fatal("the burst should be allowed")
+	}
+	if rl.allow(keys, 1) {
+		t.Fatal("the empty bucket should reject")
+	}
+	// Another user from the same IP shares the IP bucket
+	if rl.allow([]string{"user:b", "ip:1.1.1.1"}, 1) {
+		t.Fatal("the IP bucket should reject")
+	}
+	if !rl.allow([]string{"user:b", "ip:2.2.2.2"}, 1) {
+		t.Fatal("the other client should be allowed")
+	}
+	now = now.Add(time.Second)
+	if !rl.allow(keys, 2) || rl.allow(keys, 1) {
+		t.Fatal("the bucket should be refilled by the rate")
+	}
+	now = now.Add(rateLimitPruneInterval)
+	rl.allow([]string{"ip:3.3.3.3"}, 1)
+	if len(rl.buckets) != 1 {
+		t.Fatalf("the refilled buckets should be pruned, %d left", len(rl.buckets))
+	}
+}
+
+func TestRateLimitBatch(t *testing.T) {
+	cfg := &config.Config{RPCRateLimit: 0.001, RPCRateBurst: 3}
+	s, err := NewRPCServer(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RegisterAPI(api.API{NameSpace: "qitmeer", Service: &testPublicAPI{}, Public: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Service.Start(); err != nil {
+		t.Fatal(err)
+	}
+	call := func(user *rpcUser, body string) string {
+		buf := &testBuffer{Reader: strings.NewReader(body)}
+		ctx := context.WithValue(context.Background(), rpcAuthKey{}, user)
+		ctx = context.WithValue(ctx, "remote", "127.0.0.1:1234")
+		s.ServeSingleRequest(ctx, NewJSONCodec(buf), OptionMethodInvocation)
+		return buf.Buffer.String()
+	}
+	req := `{"jsonrpc":"2.0","id":1,"method":"qitmeer_getInfo","params":[]}`
+	batch := "[" + strings.Repeat(req+",", 3) + req + "]"
+
+	limited := &rpcUser{name: "limited", role: RPCRoleLimited}
+	result := call(limited, batch)
+	if strings.Count(result, `"result":"info"`) != 3 || strings.Count(result, `"code":-32005`) != 1 {
+		t.Fatalf("unexpected batch result %s", result)
+	}
+	if result := call(limited, req); !strings.Contains(result, `"code":-32005`) {
+		t.Fatalf("unexpected result %s", result)
+	}
+	// The admin users aren't limited
+	if result := call(&rpcUser{role: RPCRoleAdmin}, req); !strings.Contains(result, `"result":"info"`) {
+		t.Fatalf("unexpected result %s", result)
+	}
+	rs := s.ReqStatus["qitmeer_GetInfo"].ToJson()
+	if rs.TotalCalls != 4 || rs.RateLimited != 2 {
+		t.Fatalf("unexpected request status %v", rs)
+	}
+}
@@ -0,0 +1,618 @@
+package rpc
+
+import (
+	"context"
+	js "encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/core/json"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/rpc/api"
+	"github.com/Qitmeer/qng/rpc/client/cmds"
+)
+
+const (
+	// maxRescanJobs is the maximum number of rescan jobs which are kept,
+	// the oldest jobs which are done are removed first.
+	maxRescanJobs = 64
+
+	// maxRescanJobsPerUser is the maximum number of the jobs of a user which
+	// are running or paused.
+	maxRescanJobsPerUser = 8
+
+	// rescanJobChunkSize is the number of block hashes fetched at once.
+	rescanJobChunkSize = 1000
+
+	// rescanJobNotifyInterval is the interval of the progress notifications,
+	// the progress is persisted at the same time.
+	rescanJobNotifyInterval = time.Second * 2
+)
+
+// rescanJobsKey is the database key of the rescan jobs.
+var rescanJobsKey = []byte("rescanjobs")
+
+// rescanJobState is the state of rescan job.
+type rescanJobState int
+
+const (
+	rescanJobRunning rescanJobState = iota
+	rescanJobPaused
+	rescanJobFinished
+	rescanJobCanceled
+	rescanJobFailed
+)
+
+var rescanJobStateStrings = map[rescanJobState]string{
+	rescanJobRunning:  "running",
+	rescanJobPaused:   "paused",
+	rescanJobFinished: "finished",
+	rescanJobCanceled: "canceled",
+	rescanJobFailed:   "failed",
+}
+
+func (s rescanJobState) String() string {
+	if str, ok := rescanJobStateStrings[s]; ok {
+		return str
+	}
+	return fmt.Sprintf("Unknown rescanJobState (%d)", int(s))
+}
+
+// isDone returns true if the job can't be resumed any more.
+func (s rescanJobState) isDone() bool {
+	return s == rescanJobFinished || s == rescanJobCanceled
+}
+
+// rescanJob is a rescan which runs in background. It is persisted, so the
+// job is able to resume from the last processed order after the client
+// disconnects or the node restarts.
+type rescanJob struct {
+	ID         uint64          `json:"id"`
+	Owner      string          `json:"owner"`
+	Addresses  []string        `json:"addresses"`
+	OutPoints  []cmds.OutPoint `json:"outpoints,omitempty"`
+	BeginOrder uint64          `json:"begin"`
+	EndOrder   uint64          `json:"end"`
+	NextOrder  uint64          `json:"next"`
+	Matches    uint64          `json:"matches"`
+	State      rescanJobState  `json:"state"`
+	Err        string          `json:"error,omitempty"`
+	Created    int64           `json:"created"`
+	Updated    int64           `json:"updated"`
+
+	// The following fields are only for the running job.
+	cancel     chan struct{}
+	runOrder   uint64
+	runStarted time.Time
+}
+
+// accessible returns whether the user can see and control the job, only its
+// owner and the admins can.
+func (job *rescanJob) accessible(user *rpcUser) bool {
+	if user == nil {
+		return false
+	}
+	return user.isAdmin() || (len(job.Owner) > 0 && job.Owner == user.name)
+}
+
+// pruneKey is the key of the job to hold the pruning of blocks.
+func (job *rescanJob) pruneKey() string {
+	return fmt.Sprintf("rescanjob-%d", job.ID)
+}
+
+// rate returns the processed blocks per second of the current run.
+func (job *rescanJob) rate(now time.Time) float64 {
+	if job.State != rescanJobRunning || job.NextOrder <= job.runOrder {
+		return 0
+	}
+	elapsed := now.Sub(job.runStarted).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(job.NextOrder-job.runOrder) / elapsed
+}
+
+func (job *rescanJob) result(tipOrder uint64, now time.Time) *json.RescanJobResult {
+	// The progress is relative to the current tip if it's known
+	end := job.EndOrder
+	if end == 0 || (tipOrder > 0 && end > tipOrder) {
+		end = tipOrder
+	}
+	progress := float64(1)
+	if job.State != rescanJobFinished && end > job.BeginOrder {
+		progress = math.Min(float64(job.NextOrder-job.BeginOrder)/float64(end-job.BeginOrder), 1)
+	}
+	return &json.RescanJobResult{
+		ID:         job.ID,
+		Owner:      job.Owner,
+		State:      job.State.String(),
+		Addresses:  len(job.Addresses),
+		OutPoints:  len(job.OutPoints),
+		BeginOrder: job.BeginOrder,
+		EndOrder:   job.EndOrder,
+		NextOrder:  job.NextOrder,
+		Progress:   progress,
+		Rate:       job.rate(now),
+		Matches:    job.Matches,
+		Error:      job.Err,
+		Created:    job.Created,
+		Updated:    job.Updated,
+	}
+}
+
+func (job *rescanJob) lookups() (*rescanKeys, error) {
+	lookups := &rescanKeys{
+		addrs:   map[string]struct{}{},
+		unspent: map[types.TxOutPoint]struct{}{},
+	}
+	for _, addr := range job.Addresses {
+		lookups.addrs[addr] = struct{}{}
+	}
+	for _, op := range job.OutPoints {
+		h, err := hash.NewHashFromStr(op.Hash)
+		if err != nil {
+			return nil, rpcDecodeHexError(op.Hash)
+		}
+		lookups.unspent[*types.NewOutPoint(h, op.Index)] = struct{}{}
+	}
+	return lookups, nil
+}
+
+// rescanJobStore persists the rescan jobs.
+type rescanJobStore interface {
+	Get(key []byte) ([]byte, error)
+	Put(key []byte, value []byte) error
+}
+
+// rescanJobManager runs the rescan jobs in background and keeps their
+// progress.
+type rescanJobManager struct {
+	server *RpcServer
+	store  rescanJobStore
+
+	lock   sync.Mutex
+	jobs   map[uint64]*rescanJob
+	nextID uint64
+	wg     sync.WaitGroup
+}
+
+func newRescanJobManager(server *RpcServer) *rescanJobManager {
+	return &rescanJobManager{
+		server: server,
+		jobs:   map[uint64]*rescanJob{},
+		nextID: 1,
+	}
+}
+
+// load restores the jobs from the store, the jobs which were running are
+// paused until a client resumes them.
+func (m *rescanJobManager) load(store rescanJobStore) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.store = store
+	data, err := store.Get(rescanJobsKey)
+	if err != nil || len(data) <= 0 {
+		return nil
+	}
+	jobs := []*rescanJob{}
+	err = js.Unmarshal(data, &jobs)
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		if job.State == rescanJobRunning {
+			job.State = rescanJobPaused
+		}
+		m.jobs[job.ID] = job
+		if job.ID >= m.nextID {
+			m.nextID = job.ID + 1
+		}
+	}
+	if len(jobs) > 0 {
+		log.Info(fmt.Sprintf("Load %d rescan jobs", len(jobs)))
+	}
+	return nil
+}
+
+// save persists all the jobs.
+func (m *rescanJobManager) save() error {
+	m.lock.Lock()
+	if m.store == nil {
+		m.lock.Unlock()
+		return nil
+	}
+	jobs := make([]*rescanJob, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].ID < jobs[j].ID
+	})
+	data, err := js.Marshal(jobs)
+	store := m.store
+	m.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	return store.Put(rescanJobsKey, data)
+}
+
+// add adds a new job of the user which is paused.
+func (m *rescanJobManager) add(cmd *cmds.StartRescanJobCmd, user *rpcUser) (*rescanJob, error) {
+	if user == nil || len(user.name) <= 0 {
+		return nil, fmt.Errorf("The rescan jobs require an authenticated RPC user")
+	}
+	if len(cmd.Addresses) <= 0 && len(cmd.OutPoints) <= 0 {
+		return nil, fmt.Errorf("No addresses or outpoints to rescan")
+	}
+	end := cmd.EndBlock
+	if end >= math.MaxInt64 {
+		end = 0
+	}
+	if end != 0 && end <= cmd.BeginBlock {
+		return nil, fmt.Errorf("The end block %d must be greater than the begin block %d", end, cmd.BeginBlock)
+	}
+	now := time.Now().Unix()
+	job := &rescanJob{
+		Owner:      user.name,
+		Addresses:  cmd.Addresses,
+		OutPoints:  cmd.OutPoints,
+		BeginOrder: cmd.BeginBlock,
+		EndOrder:   end,
+		NextOrder:  cmd.BeginBlock,
+		State:      rescanJobPaused,
+		Created:    now,
+		Updated:    now,
+	}
+	_, err := job.lookups()
+	if err != nil {
+		return nil, err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	active := 0
+	for _, j := range m.jobs {
+		if j.Owner == user.name && !j.State.isDone() && j.State != rescanJobFailed {
+			active++
+		}
+	}
+	if active >= maxRescanJobsPerUser {
+		return nil, fmt.Errorf("Too many rescan jobs of %s (max %d)", user.name, maxRescanJobsPerUser)
+	}
+	if len(m.jobs) >= maxRescanJobs {
+		var oldest *rescanJob
+		for _, j := range m.jobs {
+			if !j.State.isDone() && j.State != rescanJobFailed {
+				continue
+			}
+			if oldest == nil || j.ID < oldest.ID {
+				oldest = j
+			}
+		}
+		if oldest == nil {
+			return nil, fmt.Errorf("Too many rescan jobs (max %d)", maxRescanJobs)
+		}
+		delete(m.jobs, oldest.ID)
+	}
+	job.ID = m.nextID
+	m.nextID++
+	m.jobs[job.ID] = job
+	return job, nil
+}
+
+// start runs the job in background, the matches and progress are sent to
+// the websocket client.
+func (m *rescanJobManager) start(id uint64, wsc *wsClient, user *rpcUser) error {
+	m.lock.Lock()
+	job, ok := m.jobs[id]
+	if !ok || !job.accessible(user) {
+		m.lock.Unlock()
+		return fmt.Errorf("No rescan job %d", id)
+	}
+	if job.State == rescanJobRunning || job.State.isDone() {
+		m.lock.Unlock()
+		return fmt.Errorf("The rescan job %d is %s", id, job.State.String())
+	}
+	lookups, err := job.lookups()
+	if err != nil {
+		m.lock.Unlock()
+		return err
+	}
+	// The blocks which are scanned aren't pruned until the job stops.
+	if m.server.BC != nil {
+		err = m.server.BC.HoldPruning(job.pruneKey(), job.NextOrder)
+		if err != nil {
+			m.lock.Unlock()
+			return err
+		}
+	}
+	job.State = rescanJobRunning
+	job.Err = ""
+	job.cancel = make(chan struct{})
+	job.runOrder = job.NextOrder
+	job.runStarted = time.Now()
+	job.Updated = job.runStarted.Unix()
+	m.wg.Add(1)
+	m.lock.Unlock()
+
+	log.Info(fmt.Sprintf("Start rescan job %d from order %d", id, job.runOrder))
+	go m.run(job, wsc, lookups)
+	return nil
+}
+
+// cancel stops the job, the canceled job can't be resumed.
+func (m *rescanJobManager) cancel(id uint64, user *rpcUser) (*rescanJob, error) {
+	m.lock.Lock()
+	job, ok := m.jobs[id]
+	if !ok || !job.accessible(user) {
+		m.lock.Unlock()
+		return nil, fmt.Errorf("No rescan job %d", id)
+	}
+	if job.State.isDone() {
+		m.lock.Unlock()
+		return nil, fmt.Errorf("The rescan job %d is %s", id, job.State.String())
+	}
+	if job.State == rescanJobRunning {
+		close(job.cancel)
+		job.cancel = nil
+	}
+	job.State = rescanJobCanceled
+	job.Updated = time.Now().Unix()
+	m.lock.Unlock()
+
+	log.Info(fmt.Sprintf("Cancel rescan job %d", id))
+	return job, m.save()
+}
+
+// stop waits for the running jobs which are paused by the server quit.
+func (m *rescanJobManager) stop() {
+	m.wg.Wait()
+	err := m.save()
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to save rescan jobs: %v", err))
+	}
+}
+
+func (m *rescanJobManager) tipOrder() uint64 {
+	if m.server.BC == nil {
+		return 0
+	}
+	return uint64(m.server.BC.BestSnapshot().GraphState.GetMainOrder()) + 1
+}
+
+func (m *rescanJobManager) getJob(id uint64, user *rpcUser) (*json.RescanJobResult, error) {
+	tip := m.tipOrder()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok || !job.accessible(user) {
+		return nil, fmt.Errorf("No rescan job %d", id)
+	}
+	return job.result(tip, time.Now()), nil
+}
+
+// getJobs returns the jobs which are accessible by the user.
+func (m *rescanJobManager) getJobs(user *rpcUser) []*json.RescanJobResult {
+	tip := m.tipOrder()
+	now := time.Now()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	result := make([]*json.RescanJobResult, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		if !job.accessible(user) {
+			continue
+		}
+		result = append(result, job.result(tip, now))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+// notify sends the progress of job to the websocket client.
+func (m *rescanJobManager) notify(job *rescanJob, wsc *wsClient) {
+	m.lock.Lock()
+	n := cmds.NewRescanJobNtfn(job.ID, job.State.String(), job.NextOrder, job.EndOrder, job.rate(time.Now()), job.Matches)
+	m.lock.Unlock()
+
+	mn, err := cmds.MarshalCmd(nil, n)
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to marshal rescan job notification: %v", err))
+		return
+	}
+	_ = wsc.QueueNotification(mn)
+}
+
+// finish records the final state of job if it isn't canceled.
+func (m *rescanJobManager) finish(job *rescanJob, state rescanJobState, err error) {
+	m.lock.Lock()
+	if job.State == rescanJobRunning {
+		job.State = state
+		if err != nil {
+			job.Err = err.Error()
+		}
+		job.cancel = nil
+		job.Updated = time.Now().Unix()
+	}
+	m.lock.Unlock()
+}
+
+func (m *rescanJobManager) run(job *rescanJob, wsc *wsClient, lookups *rescanKeys) {
+	defer m.wg.Done()
+	if m.server.BC != nil {
+		defer m.server.BC.ReleasePruning(job.pruneKey())
+	}
+
+	state, err := m.scan(job, wsc, lookups)
+	m.finish(job, state, err)
+	if err != nil {
+		log.Error(fmt.Sprintf("Rescan job %d failed: %v", job.ID, err))
+	} else {
+		log.Info(fmt.Sprintf("Rescan job %d is %s at order %d", job.ID, job.State.String(), job.NextOrder))
+	}
+	m.notify(job, wsc)
+	err = m.save()
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to save rescan jobs: %v", err))
+	}
+}
+
+// scan processes the blocks from the next order of job, and returns the
+// state of job when it stops.
+func (m *rescanJobManager) scan(job *rescanJob, wsc *wsClient, lookups *rescanKeys) (rescanJobState, error) {
+	chain := m.server.BC
+	if chain == nil {
+		return rescanJobFailed, fmt.Errorf("The chain isn't available")
+	}
+	m.lock.Lock()
+	cancel := job.cancel
+	next := job.NextOrder
+	end := job.EndOrder
+	m.lock.Unlock()
+
+	lastNotify := time.Now()
+	for {
+		stop := m.tipOrder()
+		if end != 0 && end < stop {
+			stop = end
+		}
+		if next >= stop {
+			return rescanJobFinished, nil
+		}
+		chunkEnd := next + rescanJobChunkSize
+		if chunkEnd > stop {
+			chunkEnd = stop
+		}
+		hashList, err := chain.OrderRange(next, chunkEnd)
+		if err != nil {
+			return rescanJobFailed, err
+		}
+		if len(hashList) == 0 {
+			return rescanJobFinished, nil
+		}
+		for i := range hashList {
+			select {
+			case <-cancel:
+				return rescanJobCanceled, nil
+			case <-wsc.quit:
+				return rescanJobPaused, nil
+			case <-m.server.quit:
+				return rescanJobPaused, nil
+			default:
+			}
+			blk, err := chain.FetchBlockByHash(&hashList[i])
+			if err != nil {
+				return rescanJobFailed, fmt.Errorf("order %d: %v", next, err)
+			}
+			_, matches := rescanBlock(wsc, lookups, blk)
+			next++
+
+			m.lock.Lock()
+			job.NextOrder = next
+			job.Matches += uint64(matches)
+			job.Updated = time.Now().Unix()
+			m.lock.Unlock()
+
+			if time.Since(lastNotify) >= rescanJobNotifyInterval {
+				lastNotify = time.Now()
+				m.notify(job, wsc)
+				err = m.save()
+				if err != nil {
+					log.Error(fmt.Sprintf("Failed to save rescan jobs: %v", err))
+				}
+			}
+		}
+	}
+}
+
+// handleStartRescanJob implements the startRescanJob command extension for
+// websocket connections. The job runs in background, so the command returns
+// the job at once.
+func handleStartRescanJob(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*cmds.StartRescanJobCmd)
+	if !ok {
+		return nil, cmds.ErrRPCInternal
+	}
+	m := wsc.server.rescanJobs
+	job, err := m.add(cmd, wsc.user)
+	if err != nil {
+		return nil, cmds.NewRPCError(cmds.ErrRPCInvalidParams.Code, err.Error())
+	}
+	err = m.start(job.ID, wsc, wsc.user)
+	if err != nil {
+		return nil, err
+	}
+	return m.getJob(job.ID, wsc.user)
+}
+
+// handleResumeRescanJob implements the resumeRescanJob command extension for
+// websocket connections.
+func handleResumeRescanJob(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*cmds.ResumeRescanJobCmd)
+	if !ok {
+		return nil, cmds.ErrRPCInternal
+	}
+	m := wsc.server.rescanJobs
+	err := m.start(cmd.ID, wsc, wsc.user)
+	if err != nil {
+		return nil, err
+	}
+	return m.getJob(cmd.ID, wsc.user)
+}
+
+// PrivateRescanJobAPI provides the status and cancel of the rescan jobs, the
+// users only see their own jobs unless they are admins.
+type PrivateRescanJobAPI struct {
+	m *rescanJobManager
+}
+
+func NewPrivateRescanJobAPI(m *rescanJobManager) *PrivateRescanJobAPI {
+	return &PrivateRescanJobAPI{m}
+}
+
+func rpcUserFromContext(ctx context.Context) *rpcUser {
+	user, _ := ctx.Value(rpcAuthKey{}).(*rpcUser)
+	return user
+}
+
+// GetRescanJobs returns all the rescan jobs of the user.
+func (api *PrivateRescanJobAPI) GetRescanJobs(ctx context.Context) (interface{}, error) {
+	return api.m.getJobs(rpcUserFromContext(ctx)), nil
+}
+
+// GetRescanJob returns the progress of the rescan job.
+func (api *PrivateRescanJobAPI) GetRescanJob(ctx context.Context, id uint64) (interface{}, error) {
+	return api.m.getJob(id, rpcUserFromContext(ctx))
+}
+
+// CancelRescanJob cancels the rescan job which is running or paused.
+func (api *PrivateRescanJobAPI) CancelRescanJob(ctx context.Context, id uint64) (interface{}, error) {
+	user := rpcUserFromContext(ctx)
+	_, err := api.m.cancel(id, user)
+	if err != nil {
+		return nil, err
+	}
+	return api.m.getJob(id, user)
+}
+
+func (s *RpcServer) APIs() []api.API {
+	return []api.API{
+		{
+			NameSpace: cmds.DefaultServiceNameSpace,
+			Service:   NewPrivateRescanJobAPI(s.rescanJobs),
+			Public:    false,
+		},
+	}
+}
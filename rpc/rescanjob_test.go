@@ -0,0 +1,165 @@
+package rpc
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Qitmeer/qng/rpc/client/cmds"
+)
+
+type testRescanJobStore struct {
+	data map[string][]byte
+}
+
+func (s *testRescanJobStore) Get(key []byte) ([]byte, error) {
+	return s.data[string(key)], nil
+}
+
+func (s *testRescanJobStore) Put(key []byte, value []byte) error {
+	s.data[string(key)] = value
+	return nil
+}
+
+var testRescanJobUser = &rpcUser{name: "user", role: RPCRoleLimited}
+
+func TestRescanJobs(t *testing.T) {
+	store := &testRescanJobStore{data: map[string][]byte{}}
+	m := newRescanJobManager(&RpcServer{})
+	if err := m.load(store); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.add(&cmds.StartRescanJobCmd{BeginBlock: 10}, testRescanJobUser); err == nil {
+		t.Fatal("the job without addresses is added")
+	}
+	if _, err := m.add(&cmds.StartRescanJobCmd{BeginBlock: 10, EndBlock: 5, Addresses: []string{"addr"}}, testRescanJobUser); err == nil {
+		t.Fatal("the job with invalid range is added")
+	}
+	if _, err := m.add(&cmds.StartRescanJobCmd{OutPoints: []cmds.OutPoint{{Hash: "zz"}}}, testRescanJobUser); err == nil {
+		t.Fatal("the job with invalid outpoint is added")
+	}
+	running, err := m.add(&cmds.StartRescanJobCmd{BeginBlock: 10, EndBlock: 110, Addresses: []string{"addr"}}, testRescanJobUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	canceled, err := m.add(&cmds.StartRescanJobCmd{Addresses: []string{"addr"}}, testRescanJobUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if canceled.EndOrder != 0 {
+		t.Fatalf("the open-ended job ends at %d", canceled.EndOrder)
+	}
+
+	// Simulate the job which is interrupted by the restart
+	running.State = rescanJobRunning
+	running.NextOrder = 60
+	running.Matches = 3
+	running.runOrder = 10
+	running.runStarted = time.Now().Add(-time.Second * 10)
+	result, err := m.getJob(running.ID, testRescanJobUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Progress != 0.5 || result.Rate < 4.9 || result.Rate > 5.1 {
+		t.Fatalf("unexpected progress %f and rate %f", result.Progress, result.Rate)
+	}
+	if _, err := m.cancel(canceled.ID, testRescanJobUser); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.cancel(canceled.ID, testRescanJobUser); err == nil {
+		t.Fatal("the canceled job is canceled again")
+	}
+	if err := m.start(canceled.ID, nil, testRescanJobUser); err == nil {
+		t.Fatal("the canceled job is resumed")
+	}
+
+	restored := newRescanJobManager(&RpcServer{})
+	if err := restored.load(store); err != nil {
+		t.Fatal(err)
+	}
+	jobs := restored.getJobs(testRescanJobUser)
+	if len(jobs) != 2 {
+		t.Fatalf("restored %d/2 jobs", len(jobs))
+	}
+	if jobs[0].State != rescanJobPaused.String() || jobs[0].NextOrder != 60 || jobs[0].Matches != 3 {
+		t.Fatalf("the interrupted job isn't restored: %+v", jobs[0])
+	}
+	if jobs[1].State != rescanJobCanceled.String() {
+		t.Fatalf("the canceled job isn't restored: %+v", jobs[1])
+	}
+	job, err := restored.add(&cmds.StartRescanJobCmd{Addresses: []string{"addr"}}, testRescanJobUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.ID != canceled.ID+1 {
+		t.Fatalf("the job id %d is reused", job.ID)
+	}
+}
+
+func TestRescanJobsLimit(t *testing.T) {
+	m := newRescanJobManager(&RpcServer{})
+	for i := 0; i < maxRescanJobs; i++ {
+		user := &rpcUser{name: fmt.Sprintf("user%d", i/maxRescanJobsPerUser)}
+		if _, err := m.add(&cmds.StartRescanJobCmd{Addresses: []string{"addr"}}, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := m.add(&cmds.StartRescanJobCmd{Addresses: []string{"addr"}}, testRescanJobUser); err == nil {
+		t.Fatal("the active jobs are removed")
+	}
+	m.jobs[5].State = rescanJobFinished
+	m.jobs[3].State = rescanJobFailed
+	if _, err := m.add(&cmds.StartRescanJobCmd{Addresses: []string{"addr"}}, testRescanJobUser); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.jobs[3]; ok {
+		t.Fatal("the oldest done job isn't removed")
+	}
+	if _, ok := m.jobs[5]; !ok {
+		t.Fatal("the newer done job is removed")
+	}
+}
+
+func TestRescanJobsOwner(t *testing.T) {
+	m := newRescanJobManager(&RpcServer{})
+	if _, err := m.add(&cmds.StartRescanJobCmd{Addresses: []string{"addr"}}, unauthenticatedUser); err == nil {
+		t.Fatal("the job of unauthenticated user is added")
+	}
+	job, err := m.add(&cmds.StartRescanJobCmd{Addresses: []string{"addr"}}, testRescanJobUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := &rpcUser{name: "other", role: RPCRoleLimited}
+	if _, err := m.getJob(job.ID, other); err == nil {
+		t.Fatal("the job is got by another user")
+	}
+	if _, err := m.cancel(job.ID, other); err == nil {
+		t.Fatal("the job is canceled by another user")
+	}
+	if err := m.start(job.ID, nil, other); err == nil {
+		t.Fatal("the job is resumed by another user")
+	}
+	if len(m.getJobs(other)) != 0 {
+		t.Fatal("the job is listed for another user")
+	}
+	admin := &rpcUser{name: "admin", role: RPCRoleAdmin}
+	if _, err := m.getJob(job.ID, admin); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.cancel(job.ID, admin); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i < maxRescanJobsPerUser; i++ {
+		if _, err := m.add(&cmds.StartRescanJobCmd{Addresses: []string{"addr"}}, testRescanJobUser); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The canceled job isn't counted
+	if _, err := m.add(&cmds.StartRescanJobCmd{Addresses: []string{"addr"}}, testRescanJobUser); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.add(&cmds.StartRescanJobCmd{Addresses: []string{"addr"}}, testRescanJobUser); err == nil {
+		t.Fatal("the jobs of user exceed the limit")
+	}
+}
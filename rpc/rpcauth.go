@@ -0,0 +1,235 @@
+// Copyright (c) 2017-2018 The qitmeer developers
+
+package rpc
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Qitmeer/qng/config"
+	"golang.org/x/net/context"
+)
+
+// The roles of RPC users
+const (
+	// RPCRoleAdmin can call all the registered methods
+	RPCRoleAdmin = "admin"
+
+	// RPCRoleLimited can only call the methods of public APIs
+	RPCRoleLimited = "limited"
+
+	// The namespaces of custom role are joined by this separator
+	rpcNamespaceSeparator = "+"
+
+	rpcAuthSaltSize = 16
+)
+
+// rpcAuthKey is the context key of the authenticated RPC user
+type rpcAuthKey struct{}
+
+// rpcUser is a credential of RPC connections and its permissions.
+type rpcUser struct {
+	name string
+	role string
+
+	// the allowed namespaces of custom role
+	namespaces map[string]bool
+
+	// sha256 of the basic authorization header of plaintext credential
+	authsha []byte
+
+	// salt and hmac-sha256(salt, password) of salted credential
+	salt string
+	hmac []byte
+}
+
+// unauthenticatedUser is used by the websocket clients that connect without
+// the authorization header.
+var unauthenticatedUser = &rpcUser{role: RPCRoleLimited}
+
+func (u *rpcUser) isAdmin() bool {
+	return u.role == RPCRoleAdmin
+}
+
+// allow returns whether the user can call the method in namespace.
+func (u *rpcUser) allow(namespace string, public bool) bool {
+	switch u.role {
+	case RPCRoleAdmin:
+		return true
+	case RPCRoleLimited:
+		return public
+	}
+	return u.namespaces[namespace]
+}
+
+// match checks the user and password, it's time-constant for the same user.
+func (u *rpcUser) match(authsha []byte, user string, pass string) bool {
+	if u.authsha != nil {
+		return subtle.ConstantTimeCompare(authsha, u.authsha) == 1
+	}
+	if subtle.ConstantTimeCompare([]byte(user), []byte(u.name)) != 1 {
+		return false
+	}
+	return hmac.Equal(rpcAuthHMAC(u.salt, pass), u.hmac)
+}
+
+func newPlainRPCUser(name string, pass string, role string) *rpcUser {
+	login := name + ":" + pass
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(login))
+	authsha := sha256.Sum256([]byte(auth))
+	return &rpcUser{name: name, role: role, authsha: authsha[:]}
+}
+
+func rpcAuthHMAC(salt string, pass string) []byte {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(pass))
+	return mac.Sum(nil)
+}
+
+// parseRPCAuth parses the salted credential:
+// <user>:<salt>$<hmac-sha256(salt,password)>[:<role>]
+func parseRPCAuth(rpcAuth string) (*rpcUser, error) {
+	fields := strings.Split(rpcAuth, ":")
+	if len(fields) != 2 && len(fields) != 3 {
+		return nil, fmt.Errorf("Invalid rpcauth (%s): expected <user>:<salt>$<hash>[:<role>]", rpcAuth)
+	}
+	if len(fields[0]) <= 0 {
+		return nil, fmt.Errorf("Invalid rpcauth (%s): empty user", rpcAuth)
+	}
+	saltHash := strings.Split(fields[1], "$")
+	if len(saltHash) != 2 || len(saltHash[0]) <= 0 {
+		return nil, fmt.Errorf("Invalid rpcauth (%s): expected <salt>$<hash>", rpcAuth)
+	}
+	mac, err := hex.DecodeString(saltHash[1])
+	if err != nil || len(mac) != sha256.Size {
+		return nil, fmt.Errorf("Invalid rpcauth (%s): the hash isn't hex encoded hmac-sha256", rpcAuth)
+	}
+	user := &rpcUser{name: fields[0], role: RPCRoleAdmin, salt: saltHash[0], hmac: mac}
+	if len(fields) == 3 {
+		switch fields[2] {
+		case RPCRoleAdmin, RPCRoleLimited:
+			user.role = fields[2]
+		default:
+			user.role = fields[2]
+			user.namespaces = map[string]bool{}
+			for _, ns := range strings.Split(fields[2], rpcNamespaceSeparator) {
+				if len(ns) <= 0 {
+					return nil, fmt.Errorf("Invalid rpcauth (%s): empty namespace", rpcAuth)
+				}
+				user.namespaces[ns] = true
+			}
+		}
+	}
+	return user, nil
+}
+
+// GenerateRPCAuth generates the salted credential with a random salt, which
+// can be used by the rpcauth option so that the password isn't stored.
+func GenerateRPCAuth(user string, pass string, role string) (string, error) {
+	if len(user) <= 0 || strings.Contains(user, ":") {
+		return "", fmt.Errorf("Invalid user name:%s", user)
+	}
+	salt := make([]byte, rpcAuthSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	saltHex := hex.EncodeToString(salt)
+	result := fmt.Sprintf("%s:%s$%s", user, saltHex, hex.EncodeToString(rpcAuthHMAC(saltHex, pass)))
+	if len(role) > 0 && role != RPCRoleAdmin {
+		result += ":" + role
+	}
+	return result, nil
+}
+
+// loadRPCUsers loads all the RPC credentials of config
+func loadRPCUsers(cfg *config.Config) ([]*rpcUser, error) {
+	users := []*rpcUser{}
+	if cfg.RPCUser != "" && cfg.RPCPass != "" {
+		users = append(users, newPlainRPCUser(cfg.RPCUser, cfg.RPCPass, RPCRoleAdmin))
+	}
+	if cfg.RPCLimitUser != "" && cfg.RPCLimitPass != "" {
+		if cfg.RPCLimitUser == cfg.RPCUser {
+			return nil, fmt.Errorf("The rpclimituser must be different from rpcuser")
+		}
+		users = append(users, newPlainRPCUser(cfg.RPCLimitUser, cfg.RPCLimitPass, RPCRoleLimited))
+	}
+	for _, ra := range cfg.RPCAuth {
+		user, err := parseRPCAuth(ra)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			if u.name == user.name {
+				return nil, fmt.Errorf("Duplicate RPC user:%s", user.name)
+			}
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// authenticate returns the user of HTTP Basic authorization header.
+func (s *RpcServer) authenticate(authhdr string) *rpcUser {
+	authsha := sha256.Sum256([]byte(authhdr))
+	var user, pass string
+	if strings.HasPrefix(authhdr, "Basic ") {
+		login, err := base64.StdEncoding.DecodeString(authhdr[len("Basic "):])
+		if err == nil {
+			parts := strings.SplitN(string(login), ":", 2)
+			if len(parts) == 2 {
+				user, pass = parts[0], parts[1]
+			}
+		}
+	}
+	// Check all the users so that it doesn't return early
+	var result *rpcUser
+	for _, u := range s.users {
+		if u.match(authsha[:], user, pass) && result == nil {
+			result = u
+		}
+	}
+	return result
+}
+
+// isAuthorized returns whether the user of context can call the method of
+// request. The requests without user are rejected.
+func isAuthorized(ctx context.Context, req *serverRequest) bool {
+	user, ok := ctx.Value(rpcAuthKey{}).(*rpcUser)
+	if !ok || user == nil {
+		return false
+	}
+	return user.allow(req.svcname, req.callb.public)
+}
+
+// checkAuth checks the HTTP Basic authentication supplied by a wallet or RPC
+// client in the HTTP request r.  If the supplied authentication does not match
+// any user, a non-nil error is returned. The websocket clients without the
+// authorization header are only allowed to call public APIs.
+func (s *RpcServer) checkAuth(r *http.Request, require bool) (*rpcUser, error) {
+	authhdr := r.Header["Authorization"]
+	if len(authhdr) <= 0 {
+		if require {
+			log.Warn("RPC authentication failure", "from", r.RemoteAddr,
+				"error", "no authorization header")
+			return nil, fmt.Errorf("auth failure")
+		}
+
+		return unauthenticatedUser, nil
+	}
+
+	user := s.authenticate(authhdr[0])
+	if user != nil {
+		return user, nil
+	}
+
+	// Request's auth doesn't match any user
+	log.Warn("RPC authentication failure", "from", r.RemoteAddr)
+	return nil, fmt.Errorf("auth failure")
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2017-2018 The qitmeer developers
+
+package rpc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/Qitmeer/qng/config"
+	"github.com/Qitmeer/qng/rpc/api"
+	"golang.org/x/net/context"
+)
+
+type testPublicAPI struct{}
+
+func (api *testPublicAPI) GetInfo() (interface{}, error) { return "info", nil }
+
+type testPrivateAPI struct{}
+
+func (api *testPrivateAPI) Stop() (interface{}, error) { return "stopped", nil }
+
+type testBuffer struct {
+	*strings.Reader
+	bytes.Buffer
+}
+
+func (b *testBuffer) Read(p []byte) (int, error) { return b.Reader.Read(p) }
+
+func (b *testBuffer) Write(p []byte) (int, error) { return b.Buffer.Write(p) }
+
+func (b *testBuffer) Close() error { return nil }
+
+func basicAuth(user string, pass string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+pass))
+}
+
+func TestRPCAuthUsers(t *testing.T) {
+	monitor, err := GenerateRPCAuth("monitor", "monitorpass", RPCRoleLimited)
+	if err != nil {
+		t.Fatal(err)
+	}
+	miner, err := GenerateRPCAuth("miner", "minerpass", "miner+qitmeer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops, err := GenerateRPCAuth("ops", "opspass", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		RPCUser:      "admin",
+		RPCPass:      "adminpass",
+		RPCLimitUser: "limit",
+		RPCLimitPass: "limitpass",
+		RPCAuth:      []string{monitor, miner, ops},
+	}
+	s, err := NewRPCServer(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		auth    string
+		name    string
+		public  bool
+		test    bool
+		miner   bool
+		qitmeer bool
+	}{
+		{basicAuth("admin", "adminpass"), "admin", true, true, true, true},
+		{basicAuth("limit", "limitpass"), "limit", true, false, false, false},
+		{basicAuth("monitor", "monitorpass"), "monitor", true, false, false, false},
+		{basicAuth("miner", "minerpass"), "miner", false, false, true, true},
+		{basicAuth("ops", "opspass"), "ops", true, true, true, true},
+	}
+	for _, test := range tests {
+		user := s.authenticate(test.auth)
+		if user == nil || user.name != test.name {
+			t.Fatalf("%s: authentication failed", test.name)
+		}
+		if user.allow("test", false) != test.test ||
+			user.allow("miner", false) != test.miner ||
+			user.allow("qitmeer", false) != test.qitmeer {
+			t.Fatalf("%s: unexpected namespace permissions", test.name)
+		}
+		if user.allow("p2p", true) != test.public {
+			t.Fatalf("%s: unexpected public permission", test.name)
+		}
+	}
+
+	for _, auth := range []string{basicAuth("admin", "limitpass"), basicAuth("monitor", "minerpass"),
+		basicAuth("nobody", "adminpass"), "Basic !!!", ""} {
+		if s.authenticate(auth) != nil {
+			t.Fatalf("unexpected authentication of %s", auth)
+		}
+	}
+}
+
+func TestParseRPCAuth(t *testing.T) {
+	invalid := []string{
+		"user",
+		"user:salt",
+		":salt$" + strings.Repeat("00", 32),
+		"user:$" + strings.Repeat("00", 32),
+		"user:salt$0011",
+		"user:salt$" + strings.Repeat("zz", 32),
+		"user:salt$" + strings.Repeat("00", 32) + ":ns++ns",
+		"user:salt$" + strings.Repeat("00", 32) + ":admin:extra",
+	}
+	for _, ra := range invalid {
+		if _, err := parseRPCAuth(ra); err == nil {
+			t.Fatalf("expected error of %s", ra)
+		}
+	}
+
+	ra := "user:salt$" + strings.Repeat("00", 32)
+	cfg := &config.Config{RPCUser: "user", RPCPass: "pass", RPCAuth: []string{ra}}
+	if _, err := loadRPCUsers(cfg); err == nil {
+		t.Fatal("expected error of duplicate user")
+	}
+	cfg = &config.Config{RPCUser: "user", RPCPass: "pass", RPCLimitUser: "user", RPCLimitPass: "limit"}
+	if _, err := loadRPCUsers(cfg); err == nil {
+		t.Fatal("expected error of the same limited user")
+	}
+}
+
+func TestRPCAuthorization(t *testing.T) {
+	s, err := NewRPCServer(&config.Config{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RegisterAPI(api.API{NameSpace: "qitmeer", Service: &testPublicAPI{}, Public: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RegisterAPI(api.API{NameSpace: "test", Service: &testPrivateAPI{}, Public: false}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Service.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	call := func(user *rpcUser, method string) string {
+		buf := &testBuffer{Reader: strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"` + method + `","params":[]}`)}
+		ctx := context.Background()
+		if user != nil {
+			ctx = context.WithValue(ctx, rpcAuthKey{}, user)
+		}
+		s.ServeSingleRequest(ctx, NewJSONCodec(buf), OptionMethodInvocation)
+		return buf.Buffer.String()
+	}
+	admin := &rpcUser{role: RPCRoleAdmin}
+	limited := &rpcUser{role: RPCRoleLimited}
+	custom := &rpcUser{role: "test", namespaces: map[string]bool{"test": true}}
+
+	tests := []struct {
+		user   *rpcUser
+		method string
+		result string
+	}{
+		{admin, "qitmeer_getInfo", `"result":"info"`},
+		{admin, "test_stop", `"result":"stopped"`},
+		{limited, "qitmeer_getInfo", `"result":"info"`},
+		{limited, "test_stop", `"code":-32001`},
+		{unauthenticatedUser, "test_stop", `"code":-32001`},
+		{custom, "qitmeer_getInfo", `"code":-32001`},
+		{custom, "test_stop", `"result":"stopped"`},
+		{nil, "qitmeer_getInfo", `"code":-32001`},
+	}
+	for _, test := range tests {
+		if result := call(test.user, test.method); !strings.Contains(result, test.result) {
+			t.Fatalf("%s: got %s, expected %s", test.method, result, test.result)
+		}
+	}
+}
@@ -3,19 +3,18 @@
 package rpc
 
 import (
-	"crypto/sha256"
-	"crypto/subtle"
-	"encoding/base64"
 	"fmt"
 	"github.com/Qitmeer/qng/config"
 	"github.com/Qitmeer/qng/consensus/model"
 	"github.com/Qitmeer/qng/core/blockchain"
 	ser "github.com/Qitmeer/qng/node/service"
 	"github.com/Qitmeer/qng/params"
+	"github.com/Qitmeer/qng/rpc/api"
 	"github.com/Qitmeer/qng/rpc/websocket"
 	"github.com/deckarep/golang-set"
 	"golang.org/x/net/context"
 	"io"
+	"math"
 	"net"
 	"net/http"
 	"reflect"
@@ -40,7 +39,8 @@ type RpcServer struct {
 	codecsMu sync.Mutex
 	codecs   mapset.Set
 
-	authsha                [sha256.Size]byte
+	users                  []*rpcUser
+	rateLimiter            *rateLimiter
 	numClients             int32
 	statusLines            map[int]string
 	requestProcessShutdown chan struct{}
@@ -49,6 +49,7 @@ type RpcServer struct {
 	reqStatusLock sync.RWMutex
 
 	ntfnMgr     *wsNotificationManager
+	rescanJobs  *rescanJobManager
 	BC          *blockchain.BlockChain
 	ChainParams *params.Params
 	listeners   []net.Listener
@@ -71,6 +72,7 @@ type callback struct {
 	hasCtx      bool           // method's first argument is a context (not included in argTypes)
 	errPos      int            // err return idx, of -1 when method cannot return error
 	isSubscribe bool           // indication if the callback is a subscription
+	public      bool           // indication if the callback belongs to a public API
 }
 
 // serviceRegistry is the collection of services by namespace
@@ -117,13 +119,17 @@ func NewRPCServer(cfg *config.Config, consensus model.Consensus) (*RpcServer, er
 		ReqStatus:              map[string]*RequestStatus{},
 	}
 
-	if cfg.RPCUser != "" && cfg.RPCPass != "" {
-		login := cfg.RPCUser + ":" + cfg.RPCPass
-		auth := "Basic " +
-			base64.StdEncoding.EncodeToString([]byte(login))
-		rpc.authsha = sha256.Sum256([]byte(auth))
+	users, err := loadRPCUsers(cfg)
+	if err != nil {
+		return nil, err
+	}
+	rpc.users = users
+	rpc.rateLimiter, err = newRateLimiter(cfg)
+	if err != nil {
+		return nil, err
 	}
 	rpc.ntfnMgr = newWsNotificationManager(&rpc)
+	rpc.rescanJobs = newRescanJobManager(&rpc)
 	if consensus != nil {
 		rpc.subscribe(consensus.Events())
 	}
@@ -140,6 +146,12 @@ func (s *RpcServer) Start() error {
 		return err
 	}
 	s.ntfnMgr.Start()
+	if s.BC != nil {
+		err = s.rescanJobs.load(s.BC.DB())
+		if err != nil {
+			log.Error(fmt.Sprintf("Failed to load rescan jobs: %v", err))
+		}
+	}
 	return nil
 }
 
@@ -169,6 +181,7 @@ func (s *RpcServer) Stop() error {
 
 	close(s.quit)
 	s.wg.Wait()
+	s.rescanJobs.stop()
 
 	return nil
 }
@@ -203,18 +216,18 @@ func (s *RpcServer) startHTTP(listenAddrs []string) error {
 		// Keep track of the number of connected clients.
 		s.incrementClients()
 		defer s.decrementClients()
-		_, err := s.checkAuth(r, true)
+		user, err := s.checkAuth(r, true)
 		if err != nil {
 			jsonAuthFail(w)
 			return
 		}
 		// Read and respond to the request.
-		s.jsonRPCRead(w, r)
+		s.jsonRPCRead(w, r.WithContext(context.WithValue(r.Context(), rpcAuthKey{}, user)))
 	})
 
 	// Websocket endpoint.
 	rpcServeMux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-		isAdmin, err := s.checkAuth(r, false)
+		user, err := s.checkAuth(r, false)
 		if err != nil {
 			jsonAuthFail(w)
 			return
@@ -230,7 +243,7 @@ func (s *RpcServer) startHTTP(listenAddrs []string) error {
 			http.Error(w, "400 Bad Request.", http.StatusBadRequest)
 			return
 		}
-		s.WebsocketHandler(ws, r.RemoteAddr, isAdmin)
+		s.WebsocketHandler(ws, r.RemoteAddr, user)
 	})
 
 	listeners, err := parseListeners(s.config, listenAddrs)
@@ -283,37 +296,6 @@ func (s *RpcServer) decrementClients() {
 	atomic.AddInt32(&s.numClients, -1)
 }
 
-// TODO, repalace Basic Authentication
-// checkAuth checks the HTTP Basic authentication supplied by a wallet or RPC
-// client in the HTTP request r.  If the supplied authentication does not match
-// the username and password expected, a non-nil error is returned.
-//
-// This check is time-constant.
-func (s *RpcServer) checkAuth(r *http.Request, require bool) (bool, error) {
-	authhdr := r.Header["Authorization"]
-	if len(authhdr) <= 0 {
-		if require {
-			log.Warn("RPC authentication failure", "from", r.RemoteAddr,
-				"error", "no authorization header")
-			return false, fmt.Errorf("auth failure")
-		}
-
-		return false, nil
-	}
-
-	authsha := sha256.Sum256([]byte(authhdr[0]))
-
-	// Check for auth
-	cmp := subtle.ConstantTimeCompare(authsha[:], s.authsha[:])
-	if cmp == 1 {
-		return true, nil
-	}
-
-	// Request's auth doesn't match either user
-	log.Warn("RPC authentication failure", "from", r.RemoteAddr)
-	return false, fmt.Errorf("auth failure")
-}
-
 // jsonAuthFail sends a message back to the client if the http auth is rejected.
 func jsonAuthFail(w http.ResponseWriter) {
 	w.Header().Add("WWW-Authenticate", `Basic realm="qitmeer RPC"`)
@@ -437,6 +419,7 @@ func (s *RpcServer) serveRequest(ctx context.Context, codec ServerCodec, singleS
 			}
 			return nil
 		}
+		s.rateLimit(ctx, reqs)
 		// If a single shot request is executing, run and return immediately
 		if singleShot {
 			if batch {
@@ -616,6 +599,10 @@ func (s *RpcServer) handle(ctx context.Context, codec ServerCodec, req *serverRe
 		return codec.CreateErrorResponse(&req.id, &invalidParamsError{"Expected subscription id as first argument"}), nil
 	}
 
+	if !isAuthorized(ctx, req) {
+		return codec.CreateErrorResponse(&req.id, &unauthorizedError{req.svcname, req.callb.method.Name}), nil
+	}
+
 	if req.callb.isSubscribe {
 		subid, err := s.createSubscription(ctx, codec, req)
 		if err != nil {
@@ -677,12 +664,21 @@ func (s *RpcServer) createSubscription(ctx context.Context, c ServerCodec, req *
 	return reply[0].Interface().(*Subscription).ID, nil
 }
 
+// RegisterAPI registers the service of API under its namespace, the methods of
+// public API are also available to the limited RPC users.
+func (s *RpcServer) RegisterAPI(a api.API) error {
+	return s.registerService(a.NameSpace, a.Service, a.Public)
+}
+
 // RegisterService will create a service for the given type under the given namespace.
 // When no methods on the given type match the criteria to be either a RPC method or
 // a subscription an error is returned. Otherwise a new service is created and added
-// to the service registry.
+// to the service registry. The methods are only available to the admin users.
 func (s *RpcServer) RegisterService(namespace string, regSvc interface{}) error {
+	return s.registerService(namespace, regSvc, false)
+}
 
+func (s *RpcServer) registerService(namespace string, regSvc interface{}, public bool) error {
 	typ := reflect.TypeOf(regSvc)
 	if namespace == "" {
 		return fmt.Errorf("no service namespace for type %s", typ.String())
@@ -691,6 +687,12 @@ func (s *RpcServer) RegisterService(namespace string, regSvc interface{}) error
 	// parse & build callbacks/subscriptions
 	value := reflect.ValueOf(regSvc)
 	calls, subs := suitableCallbacks(value, typ)
+	for _, c := range calls {
+		c.public = public
+	}
+	for _, c := range subs {
+		c.public = public
+	}
 
 	// if the namespace already registered, add callback/subscriptions & return
 	if foundSrv, nsExist := s.rpcSvcRegistry[namespace]; nsExist {
@@ -734,6 +736,20 @@ func (s *RpcServer) AddRequstStatus(sReq *serverRequest) {
 	}
 }
 
+// AddRateLimitedStatus counts the request that is rejected by the rate limit.
+func (s *RpcServer) AddRateLimitedStatus(sReq *serverRequest) {
+	s.reqStatusLock.Lock()
+	defer s.reqStatusLock.Unlock()
+	key := fmt.Sprintf("%s_%s", sReq.svcname, sReq.callb.method.Name)
+	rs, ok := s.ReqStatus[key]
+	if !ok {
+		rs = &RequestStatus{Service: sReq.svcname, Method: sReq.callb.method.Name,
+			MinTime: time.Duration(math.MaxInt64), Requests: []*serverRequest{}}
+		s.ReqStatus[rs.GetName()] = rs
+	}
+	rs.RateLimited++
+}
+
 func (s *RpcServer) RemoveRequstStatus(sReq *serverRequest) {
 	s.reqStatusLock.Lock()
 	defer s.reqStatusLock.Unlock()
@@ -31,6 +31,8 @@ func (s *RpcServer) subscribe(events *event.Feed) {
 					switch value := ev.Data.(type) {
 					case *blockchain.Notification:
 						s.handleNotifyMsg(value)
+					case *types.TxReplaced:
+						s.ntfnMgr.NotifyMempoolTx(value.Tx, false, value.Replaced...)
 					}
 				}
 				if ev.Ack != nil {
@@ -103,7 +105,7 @@ func (s *RpcServer) NotifyBlockTemplate(bt *json.RemoteGBTResult) {
 	s.ntfnMgr.NotifyBlockTemplate(bt)
 }
 
-func (s *RpcServer) WebsocketHandler(conn *websocket.Conn, remoteAddr string, isAdmin bool) {
+func (s *RpcServer) WebsocketHandler(conn *websocket.Conn, remoteAddr string, user *rpcUser) {
 	// Clear the read deadline that was set before the websocket hijacked
 	// the connection.
 	conn.SetReadDeadline(timeZeroVal)
@@ -119,7 +121,7 @@ func (s *RpcServer) WebsocketHandler(conn *websocket.Conn, remoteAddr string, is
 	// Create a new websocket client to handle the new websocket connection
 	// and wait for it to shutdown.  Once it has shutdown (and hence
 	// disconnected), remove it and any notifications it registered for.
-	client, err := newWebsocketClient(s, conn, remoteAddr, isAdmin)
+	client, err := newWebsocketClient(s, conn, remoteAddr, user)
 	if err != nil {
 		log.Error(fmt.Sprintf("Failed to serve client %s: %v", remoteAddr, err))
 		conn.Close()
@@ -144,6 +146,8 @@ var wsHandlersBeforeInit = map[string]wsCommandHandler{
 	"notifyTxsByAddr":           handleNotifyTxsByAddr,
 	"stopnotifyTxsByAddr":       handleStopNotifyTxsByAddr,
 	"rescan":                    handleRescan,
+	"startRescanJob":            handleStartRescanJob,
+	"resumeRescanJob":           handleResumeRescanJob,
 	"notifyTxsConfirmed":        handleNotifyTxsConfirmed,
 	"removeTxsConfirmed":        handleRemoveTxsConfirmed,
 }
@@ -193,7 +193,7 @@ fetchRange:
 				return nil, nil, nil, nil
 			default:
 				blk := chain.GetBlockNode(node)
-				h := rescanBlock(wsc, lookups, blk.GetBody())
+				h, _ := rescanBlock(wsc, lookups, blk.GetBody())
 				if h != nil {
 					lastTxHash = h
 				}
@@ -234,9 +234,11 @@ fetchRange:
 }
 
 // rescanBlock rescans all transactions in a single block.  This is a helper
-// function for handleRescan.
-func rescanBlock(wsc *wsClient, lookups *rescanKeys, blk *types.SerializedBlock) *hash.Hash {
+// function for handleRescan and the rescan jobs. It returns the last matched
+// transaction and the number of matched transactions.
+func rescanBlock(wsc *wsClient, lookups *rescanKeys, blk *types.SerializedBlock) (*hash.Hash, int) {
 	var lastTxHash *hash.Hash
+	matches := 0
 	for _, tx := range blk.Transactions() {
 		// notifySpend is a closure we'll use when we first detect that
 		// a transactions spends an outpoint/script in our filter list.
@@ -248,6 +250,10 @@ func rescanBlock(wsc *wsClient, lookups *rescanKeys, blk *types.SerializedBlock)
 		// We'll start by iterating over the transaction's inputs to
 		// determine if it spends an outpoint/script in our filter list.
 		for _, txin := range tx.Tx.TxIn {
+			if _, ok := lookups.unspent[txin.PreviousOut]; ok {
+				needNotifyTx = true
+				continue
+			}
 			// We'll also recompute the pkScript the input is
 			// attempting to spend to determine whether it is
 			// relevant to us.
@@ -282,7 +288,7 @@ func rescanBlock(wsc *wsClient, lookups *rescanKeys, blk *types.SerializedBlock)
 				// Stop the rescan early if the websocket client
 				// disconnected.
 				if err == ErrClientQuit {
-					return nil
+					return nil, matches
 				} else {
 					log.Error(fmt.Sprintf("Unable to notify "+
 						"redeeming transaction %v: %v",
@@ -291,7 +297,8 @@ func rescanBlock(wsc *wsClient, lookups *rescanKeys, blk *types.SerializedBlock)
 				}
 			}
 			lastTxHash = tx.Hash()
+			matches++
 		}
 	}
-	return lastTxHash
+	return lastTxHash, matches
 }
@@ -311,6 +311,7 @@ type RequestStatus struct {
 	MinTime      time.Duration
 	MaxTimeReqID string
 	MinTimeReqID string
+	RateLimited  uint
 
 	Requests []*serverRequest
 }
@@ -354,9 +355,12 @@ func (rs *RequestStatus) RemoveRequst(sReq *serverRequest) {
 
 func (rs *RequestStatus) ToJson() *cmds.JsonRequestStatus {
 	rsj := cmds.JsonRequestStatus{Name: rs.GetName(), TotalCalls: int(rs.TotalCalls),
-		TotalTime: rs.TotalTime.String(), AverageTime: "", RunningNum: len(rs.Requests)}
-	aTime := rs.TotalTime / time.Duration(rs.TotalCalls)
-	rsj.AverageTime = aTime.String()
+		TotalTime: rs.TotalTime.String(), AverageTime: "", RunningNum: len(rs.Requests),
+		RateLimited: int(rs.RateLimited)}
+	if rs.TotalCalls > 0 {
+		aTime := rs.TotalTime / time.Duration(rs.TotalCalls)
+		rsj.AverageTime = aTime.String()
+	}
 	rsj.MaxTime = rs.MaxTime.String()
 	rsj.MinTime = rs.MinTime.String()
 	rsj.MaxTimeReqID = rs.MaxTimeReqID
@@ -366,7 +370,7 @@ func (rs *RequestStatus) ToJson() *cmds.JsonRequestStatus {
 
 func NewRequestStatus(sReq *serverRequest) (*RequestStatus, error) {
 	rs := RequestStatus{sReq.svcname, sReq.callb.method.Name, 0,
-		0, time.Duration(0), time.Duration(math.MaxInt64), "", "", []*serverRequest{}}
+		0, time.Duration(0), time.Duration(math.MaxInt64), "", "", 0, []*serverRequest{}}
 	rs.AddRequst(sReq)
 	return &rs, nil
 }
@@ -48,6 +48,9 @@ type wsClient struct {
 	// false means its access is only to the limited set of RPC calls.
 	isAdmin bool
 
+	// user is the authenticated RPC user of the client.
+	user *rpcUser
+
 	// sessionID is a random ID generated for each client when connected.
 	// These IDs may be queried by a client using the session RPC.  A change
 	// to the session ID indicates that the client reconnected.
@@ -139,7 +142,8 @@ out:
 		c.serviceRequestSem.acquire()
 		go func() {
 			defer codec.Close()
-			ctx := context.Background()
+			ctx := context.WithValue(context.Background(), rpcAuthKey{}, c.user)
+			ctx = context.WithValue(ctx, "remote", c.addr)
 			c.server.ServeSingleRequest(ctx, codec, OptionMethodInvocation)
 
 			c.serviceRequestSem.release()
@@ -321,7 +325,7 @@ func (c *wsClient) QueueNotification(marshalledJSON []byte) error {
 }
 
 func newWebsocketClient(server *RpcServer, conn *websocket.Conn,
-	remoteAddr string, isAdmin bool) (*wsClient, error) {
+	remoteAddr string, user *rpcUser) (*wsClient, error) {
 
 	sessionID, err := serialization.RandomUint64()
 	if err != nil {
@@ -331,7 +335,8 @@ func newWebsocketClient(server *RpcServer, conn *websocket.Conn,
 	client := &wsClient{
 		conn:              conn,
 		addr:              remoteAddr,
-		isAdmin:           isAdmin,
+		isAdmin:           user.isAdmin(),
+		user:              user,
 		sessionID:         sessionID,
 		server:            server,
 		serviceRequestSem: makeSemaphore(server.config.RPCMaxConcurrentReqs),
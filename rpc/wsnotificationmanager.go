@@ -28,8 +28,9 @@ type notificationReorganization struct {
 }
 
 type notificationTxAcceptedByMempool struct {
-	isNew bool
-	tx    *types.Tx
+	isNew    bool
+	tx       *types.Tx
+	replaced []*types.Tx
 }
 
 type notificationTxByBlock struct {
@@ -134,6 +135,9 @@ out:
 				if n.isNew && len(txNotifications) != 0 {
 					m.notifyForNewTx(txNotifications, n.tx)
 				}
+				if len(n.replaced) != 0 && len(txNotifications) != 0 {
+					m.notifyTxReplaced(txNotifications, n.tx, n.replaced)
+				}
 			case *notificationBlockTemplate:
 				bt := (*json.RemoteGBTResult)(n)
 				if len(blockNotifications) != 0 {
@@ -344,10 +348,14 @@ func (m *wsNotificationManager) RegisterNotifyComplete(wsc *wsClient) {
 	m.queueNotification <- (*notificationScanComplete)(wsc)
 }
 
-func (m *wsNotificationManager) NotifyMempoolTx(tx *types.Tx, isNew bool) {
+// NotifyMempoolTx passes a transaction accepted by mempool to the
+// notification manager for transaction notification processing.  If the
+// transaction replaced transactions in the mempool, they are passed too.
+func (m *wsNotificationManager) NotifyMempoolTx(tx *types.Tx, isNew bool, replaced ...*types.Tx) {
 	n := &notificationTxAcceptedByMempool{
-		isNew: isNew,
-		tx:    tx,
+		isNew:    isNew,
+		tx:       tx,
+		replaced: replaced,
 	}
 
 	select {
@@ -512,6 +520,34 @@ func (m *wsNotificationManager) notifyForNewTx(clients map[chan struct{}]*wsClie
 	}
 }
 
+// notifyTxReplaced notifies the websocket clients which are interested in the
+// replacement transaction or in one of the transactions it replaced.
+func (m *wsNotificationManager) notifyTxReplaced(clients map[chan struct{}]*wsClient,
+	tx *types.Tx, replaced []*types.Tx) {
+
+	clientsToNotify := m.subscribedClients(tx, clients)
+	replacedHashes := make([]string, 0, len(replaced))
+	for _, rtx := range replaced {
+		replacedHashes = append(replacedHashes, rtx.Hash().String())
+		for quitChan := range m.subscribedClients(rtx, clients) {
+			clientsToNotify[quitChan] = struct{}{}
+		}
+	}
+	if len(clientsToNotify) <= 0 {
+		return
+	}
+
+	ntfn := cmds.NewTxReplacedNtfn(tx.Hash().String(), replacedHashes)
+	marshalledJSON, err := cmds.MarshalCmd(nil, ntfn)
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to marshal tx replaced notification: %s", err.Error()))
+		return
+	}
+	for quitChan := range clientsToNotify {
+		clients[quitChan].QueueNotification(marshalledJSON)
+	}
+}
+
 func (m *wsNotificationManager) notifyExit(clients map[chan struct{}]*wsClient) {
 	if len(clients) <= 0 {
 		return
@@ -335,6 +335,51 @@ function create_token_raw_tx(){
   get_result "$data"
 }
 
+function create_psbt() {
+  local input=$1
+  local data='{"jsonrpc":"2.0","method":"createPsbt","params":['$input'],"id":1}'
+  get_result "$data"
+}
+
+function update_psbt() {
+  local psbt=$1
+  local redeem_scripts=$2
+  if [ "$redeem_scripts" == "" ]; then
+    redeem_scripts="[]"
+  fi
+  local data='{"jsonrpc":"2.0","method":"updatePsbt","params":["'$psbt'",'$redeem_scripts'],"id":1}'
+  get_result "$data"
+}
+
+function sign_psbt() {
+  local psbt=$1
+  local private_keys=$2
+  local data='{"jsonrpc":"2.0","method":"test_signPsbt","params":["'$psbt'",'$private_keys'],"id":1}'
+  get_result "$data"
+}
+
+function combine_psbt() {
+  local psbts=$1
+  local data='{"jsonrpc":"2.0","method":"combinePsbt","params":['$psbts'],"id":1}'
+  get_result "$data"
+}
+
+function finalize_psbt() {
+  local psbt=$1
+  local extract=$2
+  if [ "$extract" == "" ]; then
+    extract="true"
+  fi
+  local data='{"jsonrpc":"2.0","method":"finalizePsbt","params":["'$psbt'",'$extract'],"id":1}'
+  get_result "$data"
+}
+
+function decode_psbt() {
+  local psbt=$1
+  local data='{"jsonrpc":"2.0","method":"decodePsbt","params":["'$psbt'"],"id":1}'
+  get_result "$data"
+}
+
 function decode_raw_tx(){
   local input=$1
   local data='{"jsonrpc":"2.0","method":"decodeRawTransaction","params":["'$input'"],"id":1}'
@@ -562,6 +607,50 @@ function listAccount() {
      get_result "$data"
 }
 
+function createHDWallet() {
+  local password=$1
+  local mnemonic=$2
+  local data='{"jsonrpc":"2.0","method":"wallet_createHDWallet","params":["'$password'"],"id":null}'
+  if [ "$mnemonic" != "" ]; then
+    data='{"jsonrpc":"2.0","method":"wallet_createHDWallet","params":["'$password'","'$mnemonic'"],"id":null}'
+  fi
+  get_result "$data"
+}
+
+function unlockHDWallet() {
+  local password=$1
+  local timeout=$2
+  local data='{"jsonrpc":"2.0","method":"wallet_unlockHDWallet","params":["'$password'",'$timeout'],"id":null}'
+  get_result "$data"
+}
+
+function lockHDWallet() {
+  local data='{"jsonrpc":"2.0","method":"wallet_lockHDWallet","params":[],"id":null}'
+  get_result "$data"
+}
+
+function getNewAddress() {
+  local data='{"jsonrpc":"2.0","method":"wallet_getNewAddress","params":[],"id":null}'
+  get_result "$data"
+}
+
+function discoverHDAddresses() {
+  local data='{"jsonrpc":"2.0","method":"wallet_discoverHDAddresses","params":[],"id":null}'
+  get_result "$data"
+}
+
+function listHDAddresses() {
+  local data='{"jsonrpc":"2.0","method":"wallet_listHDAddresses","params":[],"id":null}'
+  get_result "$data"
+}
+
+function sendFromHDWallet() {
+  local to=$1
+  local lockTime=$2
+  local data='{"jsonrpc":"2.0","method":"wallet_sendFromHDWallet","params":["'$to'",'$lockTime'],"id":null}'
+  get_result "$data"
+}
+
 function add_balance() {
   local address=$1
   local data='{"jsonrpc":"2.0","method":"addBalance","params":["'$address'"],"id":null}'
@@ -593,6 +682,72 @@ function get_amana_peerinfo(){
   get_result "$data"
 }
 
+function get_light_info(){
+  local data='{"jsonrpc":"2.0","method":"getLightInfo","params":[],"id":null}'
+  get_result "$data"
+}
+
+function get_light_header(){
+  local order=$1
+  local data='{"jsonrpc":"2.0","method":"getLightHeader","params":['$order'],"id":null}'
+  get_result "$data"
+}
+
+function get_light_txs(){
+  local data='{"jsonrpc":"2.0","method":"getLightTxs","params":[],"id":null}'
+  get_result "$data"
+}
+
+function get_cfilter(){
+  local hash=$1
+  local data='{"jsonrpc":"2.0","method":"getCFilter","params":["'$hash'"],"id":null}'
+  get_result "$data"
+}
+
+function get_cfilter_header(){
+  local hash=$1
+  local data='{"jsonrpc":"2.0","method":"getCFilterHeader","params":["'$hash'"],"id":null}'
+  get_result "$data"
+}
+
+function get_cfindex_info(){
+  local data='{"jsonrpc":"2.0","method":"getCFIndexInfo","params":[],"id":null}'
+  get_result "$data"
+}
+
+function get_address_summary(){
+  local address=$1
+  local data='{"jsonrpc":"2.0","method":"getAddressSummary","params":["'$address'"],"id":null}'
+  get_result "$data"
+}
+
+function get_balance_at_order(){
+  local address=$1
+  local coinid=$2
+  local order=$3
+  local data='{"jsonrpc":"2.0","method":"getBalanceAtOrder","params":["'$address'",'$coinid','$order'],"id":null}'
+  get_result "$data"
+}
+
+function get_rich_list(){
+  local coinid=$1
+  local count=$2
+  local skip=$3
+  if [ "$count" == "" ]; then
+    count=100
+  fi
+  if [ "$skip" == "" ]; then
+    skip=0
+  fi
+  local data='{"jsonrpc":"2.0","method":"getRichList","params":['$coinid','$count','$skip'],"id":null}'
+  get_result "$data"
+}
+
+function get_explorer_index_info(){
+  local data='{"jsonrpc":"2.0","method":"getExplorerIndexInfo","params":[],"id":null}'
+  get_result "$data"
+}
+
 function get_addresses(){
   local pkAddress=$1
   local data='{"jsonrpc":"2.0","method":"test_getAddresses","params":["'$pkAddress'"],"id":null}'
@@ -609,6 +764,46 @@ function get_orphans_total(){
   get_result "$data"
 }
 
+function get_deployment_info(){
+  local data='{"jsonrpc":"2.0","method":"getDeploymentInfo","params":[],"id":null}'
+  get_result "$data"
+}
+
+function get_prune_info(){
+  local data='{"jsonrpc":"2.0","method":"getPruneInfo","params":[],"id":null}'
+  get_result "$data"
+}
+
+function get_txout_set_info(){
+  local data='{"jsonrpc":"2.0","method":"getTxOutSetInfo","params":[],"id":null}'
+  get_result "$data"
+}
+
+function get_rescan_jobs(){
+  local data='{"jsonrpc":"2.0","method":"getRescanJobs","params":[],"id":null}'
+  get_result "$data"
+}
+
+function get_rescan_job(){
+  local data='{"jsonrpc":"2.0","method":"getRescanJob","params":['$1'],"id":null}'
+  get_result "$data"
+}
+
+function cancel_rescan_job(){
+  local data='{"jsonrpc":"2.0","method":"cancelRescanJob","params":['$1'],"id":null}'
+  get_result "$data"
+}
+
+function invalidate_block(){
+  local data='{"jsonrpc":"2.0","method":"test_invalidateBlock","params":["'$1'"],"id":null}'
+  get_result "$data"
+}
+
+function reconsider_block(){
+  local data='{"jsonrpc":"2.0","method":"test_reconsiderBlock","params":["'$1'"],"id":null}'
+  get_result "$data"
+}
+
 function stop_node(){
   local data='{"jsonrpc":"2.0","method":"test_stop","params":[],"id":null}'
   get_result "$data"
@@ -704,6 +899,16 @@ function get_tokeninfo(){
   get_result "$data"
 }
 
+function get_stakeinfo(){
+  local data='{"jsonrpc":"2.0","method":"getStakeInfo","params":[],"id":null}'
+  get_result "$data"
+}
+
+function get_stakepool(){
+  local data='{"jsonrpc":"2.0","method":"getStakePool","params":[],"id":null}'
+  get_result "$data"
+}
+
 function submit_block() {
   local input=$1
   local data='{"jsonrpc":"2.0","method":"submitBlock","params":["'$input'"],"id":1}'
@@ -849,12 +1054,24 @@ function usage(){
   echo "  rpcmax <max>"
   echo "  main  <hash>"
   echo "  stop"
+  echo "  invalidateblock <hash>"
+  echo "  reconsiderblock <hash>"
   echo "  loglevel [trace, debug, info, warn, error, critical]"
   echo "  timeinfo"
   echo "  subsidy"
   echo "  meerinfo"
   echo "  amanainfo"
   echo "  amanapeerinfo"
+  echo "  lightinfo"
+  echo "  lightheader <order>"
+  echo "  lighttxs"
+  echo "  cfilter <hash>"
+  echo "  cfilterheader <hash>"
+  echo "  cfindexinfo"
+  echo "  addresssummary <address>"
+  echo "  balanceatorder <address> <coinID> <order>"
+  echo "  richlist <coinID> <count> <skip>"
+  echo "  explorerindexinfo"
   echo "  acctinfo"
   echo "  getbalance <address> <coinID>"
   echo "  getbalanceinfo <address> <coinID>"
@@ -877,12 +1094,20 @@ function usage(){
   echo "  mainHeight"
   echo "  weight <hash>"
   echo "  orphanstotal"
+  echo "  pruneinfo"
+  echo "  txoutsetinfo"
+  echo "  rescanjobs"
+  echo "  rescanjob <id>"
+  echo "  cancelrescanjob <id>"
+  echo "  deploymentinfo"
   echo "  isblue <hash>   ;return [0:not blue;  1：blue  2：Cannot confirm]"
   echo "  tips"
   echo "  coinbase <hash>"
   echo "  fees <hash>"
   echo "  estimatefee <numblocks>"
   echo "  tokeninfo"
+  echo "  stakeinfo"
+  echo "  stakepool"
   echo "  stateroot"
   echo "tx     :"
   echo "  tx <id>"
@@ -897,6 +1122,12 @@ function usage(){
   echo "  createExportRawTxV2 <inputs> <outputs> <lockTime>"
   echo "  createImportRawTx <PKAdress> <amount>"
   echo "  txSign <rawTx>"
+  echo "  createPsbt <inputs,amounts,lockTime>"
+  echo "  updatePsbt <psbt> <[redeemScript,...]>"
+  echo "  signPsbt <psbt> <[privateKey,...]>"
+  echo "  combinePsbt <[psbt,...]>"
+  echo "  finalizePsbt <psbt> <extract,default=true>"
+  echo "  decodePsbt <psbt>"
   echo "  sendRawTx <signedRawTx>"
   echo "  getrawtxs <address>"
   echo "utxo   :"
@@ -934,6 +1165,13 @@ function usage(){
   echo "  sendtoaddress fromAddress addressAmounts({\"RmN6q2ZdNaCtgpq2BE5ZaUbfQxXwRU1yTYf\":{\"amount\":100000000,\"coinid\":0}}) locktime"
   echo "  importrawkey(privkey password)"
   echo "  listaccount"
+  echo "  createhdwallet password (mnemonic)"
+  echo "  unlockhdwallet password timeout"
+  echo "  lockhdwallet"
+  echo "  getnewaddress"
+  echo "  discoverhdaddresses"
+  echo "  listhdaddresses"
+  echo "  sendfromhdwallet addressAmounts({\"RmN6q2ZdNaCtgpq2BE5ZaUbfQxXwRU1yTYf\":{\"amount\":100000000,\"coinid\":0}}) locktime"
 }
 
 # -------------------
@@ -1306,6 +1544,38 @@ elif [ "$1" == "orphanstotal" ]; then
   shift
   get_orphans_total
 
+elif [ "$1" == "pruneinfo" ]; then
+  shift
+  get_prune_info
+
+elif [ "$1" == "txoutsetinfo" ]; then
+  shift
+  get_txout_set_info
+
+elif [ "$1" == "rescanjobs" ]; then
+  shift
+  get_rescan_jobs
+
+elif [ "$1" == "rescanjob" ]; then
+  shift
+  get_rescan_job $@
+
+elif [ "$1" == "cancelrescanjob" ]; then
+  shift
+  cancel_rescan_job $@
+
+elif [ "$1" == "deploymentinfo" ]; then
+  shift
+  get_deployment_info
+
+elif [ "$1" == "invalidateblock" ]; then
+  shift
+  invalidate_block $@
+
+elif [ "$1" == "reconsiderblock" ]; then
+  shift
+  reconsider_block $@
+
 elif [ "$1" == "stop" ]; then
   shift
   stop_node
@@ -1323,6 +1593,12 @@ elif [ "$1" == "modules" ]; then
 elif [ "$1" == "tokeninfo" ]; then
   shift
   get_tokeninfo | jq .
+elif [ "$1" == "stakeinfo" ]; then
+  shift
+  get_stakeinfo | jq .
+elif [ "$1" == "stakepool" ]; then
+  shift
+  get_stakepool | jq .
 elif [ "$1" == "coinbase" ]; then
   shift
   get_coinbase $@
@@ -1427,11 +1703,65 @@ elif [ "$1" == "amanainfo" ]; then
 elif [ "$1" == "amanapeerinfo" ]; then
     shift
     get_amana_peerinfo $@
+elif [ "$1" == "lightinfo" ]; then
+    shift
+    get_light_info $@
+elif [ "$1" == "lightheader" ]; then
+    shift
+    get_light_header $@
+elif [ "$1" == "lighttxs" ]; then
+    shift
+    get_light_txs $@
+elif [ "$1" == "cfilter" ]; then
+    shift
+    get_cfilter $@
+elif [ "$1" == "cfilterheader" ]; then
+    shift
+    get_cfilter_header $@
+elif [ "$1" == "cfindexinfo" ]; then
+    shift
+    get_cfindex_info $@
+elif [ "$1" == "addresssummary" ]; then
+    shift
+    get_address_summary $@
+elif [ "$1" == "balanceatorder" ]; then
+    shift
+    get_balance_at_order $@
+elif [ "$1" == "richlist" ]; then
+    shift
+    get_rich_list $@
+elif [ "$1" == "explorerindexinfo" ]; then
+    shift
+    get_explorer_index_info $@
 
 elif [ "$1" == "txSign" ]; then
   shift
   tx_sign $@
 
+elif [ "$1" == "createPsbt" ]; then
+  shift
+  create_psbt $@
+
+elif [ "$1" == "updatePsbt" ]; then
+  shift
+  update_psbt $@
+
+elif [ "$1" == "signPsbt" ]; then
+  shift
+  sign_psbt $@
+
+elif [ "$1" == "combinePsbt" ]; then
+  shift
+  combine_psbt $@
+
+elif [ "$1" == "finalizePsbt" ]; then
+  shift
+  finalize_psbt $@
+
+elif [ "$1" == "decodePsbt" ]; then
+  shift
+  decode_psbt $@
+
 ## UTXO
 elif [ "$1" == "getutxo" ]; then
   shift
@@ -1623,6 +1953,27 @@ elif [ "$1" == "importrawkey" ]; then
 elif [ "$1" == "listaccount" ]; then
   shift
   listAccount "$@"
+elif [ "$1" == "createhdwallet" ]; then
+  shift
+  createHDWallet "$@"
+elif [ "$1" == "unlockhdwallet" ]; then
+  shift
+  unlockHDWallet "$@"
+elif [ "$1" == "lockhdwallet" ]; then
+  shift
+  lockHDWallet "$@"
+elif [ "$1" == "getnewaddress" ]; then
+  shift
+  getNewAddress "$@"
+elif [ "$1" == "discoverhdaddresses" ]; then
+  shift
+  discoverHDAddresses "$@"
+elif [ "$1" == "listhdaddresses" ]; then
+  shift
+  listHDAddresses "$@"
+elif [ "$1" == "sendfromhdwallet" ]; then
+  shift
+  sendFromHDWallet "$@"
 
 elif [ "$1" == "list_command" ]; then
   usage
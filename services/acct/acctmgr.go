@@ -619,6 +619,44 @@ func (a *AccountManager) AddAddress(addr string) error {
 	}
 	return a.rebuild([]string{addr})
 }
+// AddAddresses tracks the addresses which aren't tracked yet, the account
+// index is rebuilt once for all of them.
+func (a *AccountManager) AddAddresses(addrs []string) error {
+	if !a.cfg.AcctMode {
+		return fmt.Errorf("Please enable --acctmode")
+	}
+	news := []string{}
+	for _, addr := range addrs {
+		if !address.IsForCurNetwork(addr) {
+			return fmt.Errorf("network error:%s", addr)
+		}
+		if a.info.Has(addr) {
+			continue
+		}
+		if _, exist := a.watchers[addr]; exist {
+			continue
+		}
+		news = append(news, addr)
+	}
+	if len(news) <= 0 {
+		return nil
+	}
+	err := a.db.Update(func(dbTx legacydb.Tx) error {
+		for _, addr := range news {
+			a.info.Add(addr)
+			err := a.cleanBalanceDB(dbTx, addr)
+			if err != nil {
+				return err
+			}
+		}
+		return DBPutACCTInfo(dbTx, a.info)
+	})
+	if err != nil {
+		return err
+	}
+	return a.rebuild(news)
+}
+
 func (a *AccountManager) GetChain() *blockchain.BlockChain {
 	return a.chain
 }
@@ -0,0 +1,92 @@
+/*
+ * Copyright (c) 2017-2020 The qitmeer developers
+ */
+
+package cf
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/core/json"
+	"github.com/Qitmeer/qng/rpc/api"
+	"github.com/Qitmeer/qng/rpc/client/cmds"
+	"github.com/Qitmeer/qng/services/cf/gcs"
+	"github.com/Qitmeer/qng/services/index"
+)
+
+func (s *Service) APIs() []api.API {
+	return []api.API{
+		{
+			NameSpace: cmds.DefaultServiceNameSpace,
+			Service:   NewPublicCFAPI(s),
+			Public:    true,
+		},
+	}
+}
+
+// PublicCFAPI provides the RPC of the committed filters.
+type PublicCFAPI struct {
+	s *Service
+}
+
+func NewPublicCFAPI(s *Service) *PublicCFAPI {
+	return &PublicCFAPI{s}
+}
+
+func (api *PublicCFAPI) cfIndex(filterType *uint32) (*index.CFIndex, error) {
+	if filterType != nil && gcs.FilterType(*filterType) != gcs.FilterTypeBasic {
+		return nil, fmt.Errorf("Unsupported filter type %d", *filterType)
+	}
+	cfIndex := api.s.CFIndex()
+	if cfIndex == nil {
+		return nil, fmt.Errorf("The committed filter index is disabled (--cfindex)")
+	}
+	return cfIndex, nil
+}
+
+// GetCFilter returns the serialized committed filter of the block.
+func (api *PublicCFAPI) GetCFilter(h hash.Hash, filterType *uint32) (interface{}, error) {
+	cfIndex, err := api.cfIndex(filterType)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := cfIndex.FilterByBlockHash(&h)
+	if err != nil {
+		return nil, err
+	}
+	return hex.EncodeToString(filter), nil
+}
+
+// GetCFilterHeader returns the committed filter header of the block.
+func (api *PublicCFAPI) GetCFilterHeader(h hash.Hash, filterType *uint32) (interface{}, error) {
+	cfIndex, err := api.cfIndex(filterType)
+	if err != nil {
+		return nil, err
+	}
+	header, err := cfIndex.FilterHeaderByBlockHash(&h)
+	if err != nil {
+		return nil, err
+	}
+	return header.String(), nil
+}
+
+// GetCFIndexInfo returns the state of the committed filter index.
+func (api *PublicCFAPI) GetCFIndexInfo() (interface{}, error) {
+	cfIndex, err := api.cfIndex(nil)
+	if err != nil {
+		return nil, err
+	}
+	tipHash, tipOrder, err := cfIndex.Tip()
+	if err != nil {
+		return nil, err
+	}
+	info := json.CFIndexInfo{TipHash: tipHash.String()}
+	if tipOrder != math.MaxUint32 {
+		info.Indexed = true
+		info.TipOrder = uint64(tipOrder)
+	}
+	return info, nil
+}
@@ -1 +1,35 @@
+/*
+ * Copyright (c) 2017-2020 The qitmeer developers
+ */
+
+// Package cf provides the committed filters (BIP157/158) of the blocks to the
+// light clients over the RPC.  The filters are built by the committed filter
+// index of services/index and served to the peers by p2p/synch.
 package cf
+
+import (
+	"github.com/Qitmeer/qng/consensus/model"
+	"github.com/Qitmeer/qng/node/service"
+	"github.com/Qitmeer/qng/services/index"
+)
+
+// Service is the committed filter service.
+type Service struct {
+	service.Service
+
+	consensus model.Consensus
+}
+
+// CFIndex returns the committed filter index, or nil if it is disabled.
+func (s *Service) CFIndex() *index.CFIndex {
+	im, ok := s.consensus.IndexManager().(*index.Manager)
+	if !ok {
+		return nil
+	}
+	return im.CFIndex()
+}
+
+// New returns the committed filter service.
+func New(consensus model.Consensus) *Service {
+	return &Service{consensus: consensus}
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2017-2018 The qitmeer developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gcs
+
+import "io"
+
+// bitWriter appends bits to a byte slice, most significant bit first.
+type bitWriter struct {
+	bytes []byte
+	// free is the number of unused bits in the last byte.
+	free uint
+}
+
+// writeBit appends a single bit.
+func (w *bitWriter) writeBit(bit bool) {
+	if w.free == 0 {
+		w.bytes = append(w.bytes, 0)
+		w.free = 8
+	}
+	w.free--
+	if bit {
+		w.bytes[len(w.bytes)-1] |= 1 << w.free
+	}
+}
+
+// writeBits appends the n least significant bits of the value, most
+// significant first.
+func (w *bitWriter) writeBits(value uint64, n uint) {
+	for n > 0 {
+		n--
+		w.writeBit(value&(1<<n) != 0)
+	}
+}
+
+// bitReader reads bits from a byte slice, most significant bit first.
+type bitReader struct {
+	bytes []byte
+	// pos is the index of the next bit to read.
+	pos uint
+}
+
+// readBit returns the next bit, or io.EOF once all of the bits were read.
+func (r *bitReader) readBit() (bool, error) {
+	index := r.pos / 8
+	if index >= uint(len(r.bytes)) {
+		return false, io.EOF
+	}
+	bit := r.bytes[index]&(0x80>>(r.pos%8)) != 0
+	r.pos++
+	return bit, nil
+}
+
+// readBits returns the value of the next n bits.
+func (r *bitReader) readBits(n uint) (uint64, error) {
+	var value uint64
+	for ; n > 0; n-- {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		value <<= 1
+		if bit {
+			value |= 1
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2017-2018 The qitmeer developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gcs
+
+import (
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/engine/txscript"
+)
+
+// FilterType is the type of a compact block filter.
+type FilterType uint8
+
+const (
+	// FilterTypeBasic is the basic filter type, which commits to the
+	// output scripts of a block and the scripts of the outputs it spends.
+	FilterTypeBasic FilterType = 0
+)
+
+const (
+	// DefaultP is the default collision probability (2^-19) of the basic
+	// filter.
+	DefaultP = 19
+
+	// DefaultM is the default modulus value of the basic filter, chosen to
+	// minimize its size for the collision probability.
+	DefaultM uint64 = 784931
+)
+
+// DeriveKey derives the SipHash key of the filter of a block from its hash.
+func DeriveKey(blockHash *hash.Hash) [KeySize]byte {
+	var key [KeySize]byte
+	copy(key[:], blockHash[:KeySize])
+	return key
+}
+
+// BuildBasicFilter builds the basic filter of the block, made of the scripts
+// of its outputs and of the previous outputs spent by its transactions.  The
+// provably unspendable outputs and the outputs of duplicate transactions are
+// not part of the filter.  The previous output scripts are the spent scripts
+// of the block from its spend journal.
+func BuildBasicFilter(block *types.SerializedBlock, prevOutScripts [][]byte) (*Filter, error) {
+	items := make(map[string]struct{})
+	for _, tx := range block.Transactions() {
+		if tx.IsDuplicate {
+			continue
+		}
+		for _, txOut := range tx.Tx.TxOut {
+			if len(txOut.PkScript) == 0 ||
+				txOut.PkScript[0] == txscript.OP_RETURN {
+				continue
+			}
+			items[string(txOut.PkScript)] = struct{}{}
+		}
+	}
+	for _, pkScript := range prevOutScripts {
+		if len(pkScript) == 0 {
+			continue
+		}
+		items[string(pkScript)] = struct{}{}
+	}
+
+	data := make([][]byte, 0, len(items))
+	for item := range items {
+		data = append(data, []byte(item))
+	}
+	return BuildGCSFilter(DefaultP, DefaultM, DeriveKey(block.Hash()), data)
+}
+
+// FilterHash returns the hash of the serialized filter.
+func FilterHash(filter []byte) hash.Hash {
+	return hash.DoubleHashH(filter)
+}
+
+// MakeHeaderForFilter makes the header of the serialized filter, which commits
+// to the filter and to the header of the filter of the previous block in the
+// order of the DAG.
+func MakeHeaderForFilter(filter []byte, prevHeader *hash.Hash) hash.Hash {
+	filterHash := FilterHash(filter)
+	return MakeHeaderForFilterHash(&filterHash, prevHeader)
+}
+
+// MakeHeaderForFilterHash makes the filter header from the hash of the filter
+// and the header of the filter of the previous block in the order of the DAG.
+func MakeHeaderForFilterHash(filterHash *hash.Hash, prevHeader *hash.Hash) hash.Hash {
+	var data [hash.HashSize * 2]byte
+	copy(data[:hash.HashSize], filterHash[:])
+	copy(data[hash.HashSize:], prevHeader[:])
+	return hash.DoubleHashH(data[:])
+}
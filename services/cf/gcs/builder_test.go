@@ -0,0 +1,48 @@
+// Copyright (c) 2017-2018 The qitmeer developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gcs
+
+import (
+	"testing"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/core/types/pow"
+	"github.com/Qitmeer/qng/engine/txscript"
+)
+
+func TestBuildBasicFilter(t *testing.T) {
+	outScript := []byte{txscript.OP_DUP, txscript.OP_HASH160, 0x01}
+	nullData := []byte{txscript.OP_RETURN, 0x01, 0x02}
+	prevScript := []byte{txscript.OP_DUP, txscript.OP_HASH160, 0x02}
+
+	tx := types.NewTransaction()
+	tx.AddTxIn(types.NewTxInput(types.NewOutPoint(&hash.Hash{0x01}, 0), nil))
+	tx.AddTxOut(types.NewTxOutput(types.Amount{Value: 1e8, Id: types.MEERA}, outScript))
+	tx.AddTxOut(types.NewTxOutput(types.Amount{Value: 0, Id: types.MEERA}, nullData))
+	block := &types.Block{}
+	block.AddTransaction(tx)
+	block.Header.Pow = pow.GetInstance(pow.BLAKE2BD, 0, []byte{})
+	sblock := types.NewBlock(block)
+
+	filter, err := BuildBasicFilter(sblock, [][]byte{prevScript, prevScript, nil})
+	if err != nil {
+		t.Fatalf("BuildBasicFilter: %v", err)
+	}
+	if filter.N() != 2 {
+		t.Fatalf("got N %d want 2", filter.N())
+	}
+	key := DeriveKey(sblock.Hash())
+	for _, script := range [][]byte{outScript, prevScript} {
+		match, err := filter.Match(key, script)
+		if err != nil || !match {
+			t.Fatalf("script %x is not matched: %v", script, err)
+		}
+	}
+	match, err := filter.Match(key, nullData)
+	if err != nil || match {
+		t.Fatalf("null data output is matched: %v", err)
+	}
+}
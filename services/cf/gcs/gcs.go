@@ -0,0 +1,228 @@
+// Copyright (c) 2017-2018 The qitmeer developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package gcs implements the Golomb-coded sets used by the compact block
+// filters, as described by BIP158.
+package gcs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"math/bits"
+	"sort"
+
+	"github.com/Qitmeer/qng/core/serialization"
+)
+
+// KeySize is the size of the SipHash key used to hash the filter items.
+const KeySize = 16
+
+var (
+	// ErrNTooBig signifies that the filter can't handle N items.
+	ErrNTooBig = errors.New("N is too big to fit in uint32")
+
+	// ErrPTooBig signifies that the filter can't handle `1/2**P`
+	// collision probability.
+	ErrPTooBig = errors.New("P is too big to fit in uint64")
+
+	// ErrMisserialized signifies a filter was misserialized and is missing
+	// the N and/or P parameters of a serialized filter.
+	ErrMisserialized = errors.New("misserialized filter")
+)
+
+// Filter describes an immutable filter that can be built from a set of data
+// elements, serialized, deserialized, and queried in a thread-safe manner.
+type Filter struct {
+	n          uint32
+	p          uint8
+	modulusNM  uint64
+	filterData []byte
+}
+
+// fastReduction maps the 64-bit hash uniformly to the range [0, nm) without
+// using a division.
+func fastReduction(v, nm uint64) uint64 {
+	hi, _ := bits.Mul64(v, nm)
+	return hi
+}
+
+// hashItem returns the value of the item in the range [0, nm) of the filter.
+func hashItem(key [KeySize]byte, item []byte, nm uint64) uint64 {
+	k0 := binary.LittleEndian.Uint64(key[0:8])
+	k1 := binary.LittleEndian.Uint64(key[8:16])
+	return fastReduction(SipHash(k0, k1, item), nm)
+}
+
+// BuildGCSFilter builds a new GCS filter with the collision probability of
+// `1/(2**P)`, the modulus of `N*M` and the key for the SipHash function.  The
+// data is the set of items to add to the filter.
+func BuildGCSFilter(P uint8, M uint64, key [KeySize]byte, data [][]byte) (*Filter, error) {
+	if uint64(len(data)) >= math.MaxUint32 {
+		return nil, ErrNTooBig
+	}
+	if P > 32 {
+		return nil, ErrPTooBig
+	}
+
+	f := &Filter{
+		n: uint32(len(data)),
+		p: P,
+	}
+	f.modulusNM = uint64(f.n) * M
+
+	// Hash all of the items into the range of the filter and sort them,
+	// so only the differences between them have to be encoded.
+	values := make([]uint64, 0, len(data))
+	for _, item := range data {
+		values = append(values, hashItem(key, item, f.modulusNM))
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return values[i] < values[j]
+	})
+
+	// Golomb-Rice code the differences: the quotient in unary followed by
+	// the P bits of the remainder.
+	var w bitWriter
+	var lastValue uint64
+	for _, v := range values {
+		delta := v - lastValue
+		lastValue = v
+		for q := delta >> f.p; q > 0; q-- {
+			w.writeBit(true)
+		}
+		w.writeBit(false)
+		w.writeBits(delta, uint(f.p))
+	}
+	f.filterData = w.bytes
+	return f, nil
+}
+
+// FromBytes deserializes a GCS filter from the known N, P, M and the
+// Golomb-Rice coded bytes.
+func FromBytes(N uint32, P uint8, M uint64, d []byte) (*Filter, error) {
+	if P > 32 {
+		return nil, ErrPTooBig
+	}
+	f := &Filter{
+		n:          N,
+		p:          P,
+		modulusNM:  uint64(N) * M,
+		filterData: make([]byte, len(d)),
+	}
+	copy(f.filterData, d)
+	return f, nil
+}
+
+// FromNBytes deserializes a GCS filter from the known P and M, and the bytes
+// of the filter prefixed by the variable length encoded N.
+func FromNBytes(P uint8, M uint64, d []byte) (*Filter, error) {
+	buffer := bytes.NewReader(d)
+	N, err := serialization.ReadVarInt(buffer, 0)
+	if err != nil {
+		return nil, ErrMisserialized
+	}
+	if N >= math.MaxUint32 {
+		return nil, ErrNTooBig
+	}
+	return FromBytes(uint32(N), P, M, d[len(d)-buffer.Len():])
+}
+
+// N returns the size of the data set used to build the filter.
+func (f *Filter) N() uint32 {
+	return f.n
+}
+
+// P returns the filter's collision probability as a negative power of 2.
+func (f *Filter) P() uint8 {
+	return f.p
+}
+
+// Bytes returns the Golomb-Rice coded bytes of the filter.
+func (f *Filter) Bytes() []byte {
+	filterData := make([]byte, len(f.filterData))
+	copy(filterData, f.filterData)
+	return filterData
+}
+
+// NBytes returns the serialized filter, which is the variable length encoded
+// N followed by the Golomb-Rice coded bytes of the filter.
+func (f *Filter) NBytes() []byte {
+	var buffer bytes.Buffer
+	buffer.Grow(serialization.VarIntSerializeSize(uint64(f.n)) + len(f.filterData))
+	serialization.WriteVarInt(&buffer, 0, uint64(f.n))
+	buffer.Write(f.filterData)
+	return buffer.Bytes()
+}
+
+// readFullUint64 reads the next Golomb-Rice coded difference from the stream.
+func (f *Filter) readFullUint64(r *bitReader) (uint64, error) {
+	var quotient uint64
+	for {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		if !bit {
+			break
+		}
+		quotient++
+	}
+	remainder, err := r.readBits(uint(f.p))
+	if err != nil {
+		return 0, err
+	}
+	return quotient<<f.p | remainder, nil
+}
+
+// Match checks whether a []byte value is likely (within collision probability)
+// to be a member of the set represented by the filter.
+func (f *Filter) Match(key [KeySize]byte, data []byte) (bool, error) {
+	return f.MatchAny(key, [][]byte{data})
+}
+
+// MatchAny checks whether any []byte value is likely (within collision
+// probability) to be a member of the set represented by the filter faster
+// than calling Match() for each value individually.
+func (f *Filter) MatchAny(key [KeySize]byte, data [][]byte) (bool, error) {
+	if f.n == 0 || len(data) == 0 {
+		return false, nil
+	}
+
+	values := make([]uint64, 0, len(data))
+	for _, item := range data {
+		values = append(values, hashItem(key, item, f.modulusNM))
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return values[i] < values[j]
+	})
+
+	// Walk both of the sorted sets until a common value is found or one of
+	// them is exhausted.
+	r := &bitReader{bytes: f.filterData}
+	var filterValue uint64
+	for i := uint32(0); i < f.n; i++ {
+		delta, err := f.readFullUint64(r)
+		if err != nil {
+			if err == io.EOF {
+				return false, nil
+			}
+			return false, err
+		}
+		filterValue += delta
+
+		for len(values) > 0 && values[0] < filterValue {
+			values = values[1:]
+		}
+		if len(values) == 0 {
+			return false, nil
+		}
+		if values[0] == filterValue {
+			return true, nil
+		}
+	}
+	return false, nil
+}
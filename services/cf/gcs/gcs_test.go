@@ -0,0 +1,111 @@
+// Copyright (c) 2017-2018 The qitmeer developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gcs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/Qitmeer/qng/common/hash"
+)
+
+func TestSipHash(t *testing.T) {
+	var key [16]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+	k0 := binary.LittleEndian.Uint64(key[0:8])
+	k1 := binary.LittleEndian.Uint64(key[8:16])
+
+	tests := []struct {
+		length int
+		want   uint64
+	}{
+		{0, 0x726fdb47dd0e0e31},
+		{8, 0x93f5f5799a932462},
+		{15, 0xa129ca6149be45e5},
+	}
+	for _, test := range tests {
+		msg := make([]byte, test.length)
+		for i := range msg {
+			msg[i] = byte(i)
+		}
+		if got := SipHash(k0, k1, msg); got != test.want {
+			t.Errorf("length %d: got %x want %x", test.length, got, test.want)
+		}
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	key := DeriveKey(&hash.Hash{0x01, 0x02, 0x03})
+	var contents [][]byte
+	for i := 0; i < 200; i++ {
+		contents = append(contents, []byte{0x76, 0xa9, byte(i), byte(i >> 8)})
+	}
+
+	filter, err := BuildGCSFilter(DefaultP, DefaultM, key, contents)
+	if err != nil {
+		t.Fatalf("BuildGCSFilter: %v", err)
+	}
+	if filter.N() != uint32(len(contents)) {
+		t.Fatalf("got N %d want %d", filter.N(), len(contents))
+	}
+	for _, item := range contents {
+		match, err := filter.Match(key, item)
+		if err != nil || !match {
+			t.Fatalf("item %x is not matched: %v", item, err)
+		}
+	}
+	match, err := filter.Match(key, []byte("not in the filter"))
+	if err != nil || match {
+		t.Fatalf("unexpected match: %v", err)
+	}
+	match, err = filter.MatchAny(key, [][]byte{[]byte("not in the filter"), contents[57]})
+	if err != nil || !match {
+		t.Fatalf("MatchAny does not match: %v", err)
+	}
+
+	// The filter deserialized from its bytes must match the same items.
+	decoded, err := FromNBytes(DefaultP, DefaultM, filter.NBytes())
+	if err != nil {
+		t.Fatalf("FromNBytes: %v", err)
+	}
+	if decoded.N() != filter.N() || !bytes.Equal(decoded.Bytes(), filter.Bytes()) {
+		t.Fatalf("decoded filter differs")
+	}
+	match, err = decoded.Match(key, contents[199])
+	if err != nil || !match {
+		t.Fatalf("decoded filter does not match: %v", err)
+	}
+}
+
+func TestEmptyFilter(t *testing.T) {
+	key := DeriveKey(&hash.ZeroHash)
+	filter, err := BuildGCSFilter(DefaultP, DefaultM, key, nil)
+	if err != nil {
+		t.Fatalf("BuildGCSFilter: %v", err)
+	}
+	if !bytes.Equal(filter.NBytes(), []byte{0x00}) {
+		t.Fatalf("got %x for the empty filter", filter.NBytes())
+	}
+	match, err := filter.Match(key, []byte{0x51})
+	if err != nil || match {
+		t.Fatalf("empty filter matches: %v", err)
+	}
+}
+
+func TestFilterHeaderChain(t *testing.T) {
+	filter := []byte{0x00}
+	header := MakeHeaderForFilter(filter, &hash.ZeroHash)
+	filterHash := FilterHash(filter)
+	if MakeHeaderForFilterHash(&filterHash, &hash.ZeroHash) != header {
+		t.Fatalf("headers from the filter and its hash differ")
+	}
+	next := MakeHeaderForFilter(filter, &header)
+	if next == header {
+		t.Fatalf("header does not commit to the previous header")
+	}
+}
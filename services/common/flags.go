@@ -28,6 +28,7 @@ const (
 	defaultMaxRPCClients          = 10
 	defaultMaxRPCWebsockets       = 25
 	defaultMaxRPCConcurrentReqs   = 20
+	defaultRPCRateBurst           = 100
 	defaultMaxPeers               = 50
 	defaultMiningStateSync        = false
 	defaultMaxInboundPeersPerHost = 25 // The default max total of inbound peer for host
@@ -39,15 +40,22 @@ const (
 	defaultRPCPass                = "test"
 	defaultMinBlockPruneSize      = 2000
 	defaultMinBlockDataCache      = 2000
+	defaultPruneDepth             = 0
+	defaultMetricsPort            = 6060
 	defaultMinRelayTxFee          = int64(1e4)
 	defaultObsoleteHeight         = 5
 	defaultGBTTimeout             = 800 // default gbt timeout 800 ms
+	defaultStratumDiff            = 1
+	defaultStratumShareTime       = 10 * time.Second
+	defaultGossip                 = "stream"
 )
 const (
 	defaultSigCacheMaxSize = 100000
 )
 const (
-	defaultMaxOrphanTxSize = 5000
+	defaultMaxOrphanTxSize      = 5000
+	defaultLimitAncestorCount   = 25
+	defaultLimitDescendantCount = 25
 )
 
 var (
@@ -76,6 +84,11 @@ var (
 	Whitelist         cli.StringSlice
 	Blacklist         cli.StringSlice
 	GBTNotify         cli.StringSlice
+	LightAddrs        cli.StringSlice
+	RPCAuth           cli.StringSlice
+	RPCMethodCosts    cli.StringSlice
+	EventWebhooks     cli.StringSlice
+	EventTopics       cli.StringSlice
 
 	Flags = []cli.Flag{
 		&cli.StringFlag{
@@ -162,6 +175,21 @@ var (
 			Value:       defaultRPCPass,
 			Destination: &cfg.RPCPass,
 		},
+		&cli.StringFlag{
+			Name:        "rpclimituser",
+			Usage:       "Username for limited RPC connections, which can only call the public APIs",
+			Destination: &cfg.RPCLimitUser,
+		},
+		&cli.StringFlag{
+			Name:        "rpclimitpass",
+			Usage:       "Password for limited RPC connections",
+			Destination: &cfg.RPCLimitPass,
+		},
+		&cli.StringSliceFlag{
+			Name:        "rpcauth",
+			Usage:       "Add a salted RPC credential <user>:<salt>$<hmac-sha256(salt,password)>[:<role>], the role is admin (default), limited or the allowed namespaces joined by +",
+			Destination: &RPCAuth,
+		},
 		&cli.StringFlag{
 			Name:        "rpccert",
 			Usage:       "File containing the certificate file",
@@ -215,6 +243,11 @@ var (
 			Usage:       "start as a qitmeer light node",
 			Destination: &cfg.LightNode,
 		},
+		&cli.StringSliceFlag{
+			Name:        "lightaddr",
+			Usage:       "Add an address to be watched by the light node",
+			Destination: &LightAddrs,
+		},
 		&cli.UintFlag{
 			Name:        "sigcachemaxsize",
 			Usage:       "The maximum number of entries in the signature verification cache",
@@ -291,11 +324,28 @@ var (
 			Destination: &cfg.AcceptNonStd,
 			Value:       true,
 		},
+		&cli.BoolFlag{
+			Name:        "rejectreplacement",
+			Usage:       "Reject transactions that attempt to replace existing transactions within the mempool through the Replace-By-Fee (RBF) signaling policy.",
+			Destination: &cfg.RejectReplacement,
+		},
 		&cli.IntFlag{
 			Name:        "maxorphantx",
 			Usage:       "Max number of orphan transactions to keep in memory",
 			Destination: &cfg.MaxOrphanTxs,
 		},
+		&cli.Int64Flag{
+			Name:        "limitancestorcount",
+			Usage:       "Do not accept transactions with more in-mempool ancestors than this, including the transaction itself (0 disables the limit)",
+			Value:       defaultLimitAncestorCount,
+			Destination: &cfg.LimitAncestorCount,
+		},
+		&cli.Int64Flag{
+			Name:        "limitdescendantcount",
+			Usage:       "Do not accept transactions if any in-mempool ancestor would have more descendants than this, including the ancestor itself (0 disables the limit)",
+			Value:       defaultLimitDescendantCount,
+			Destination: &cfg.LimitDescendantCount,
+		},
 		&cli.Int64Flag{
 			Name:        "mintxfee",
 			Usage:       "The minimum transaction fee in AtomMEER/kB.",
@@ -367,6 +417,22 @@ var (
 			Value:       defaultMaxRPCConcurrentReqs,
 			Destination: &cfg.RPCMaxConcurrentReqs,
 		},
+		&cli.Float64Flag{
+			Name:        "rpcratelimit",
+			Usage:       "Max cost of RPC requests per second for each client IP and non-admin user (0 to disable)",
+			Destination: &cfg.RPCRateLimit,
+		},
+		&cli.IntFlag{
+			Name:        "rpcrateburst",
+			Usage:       "Max cost of RPC requests that a client can burst",
+			Value:       defaultRPCRateBurst,
+			Destination: &cfg.RPCRateBurst,
+		},
+		&cli.StringSliceFlag{
+			Name:        "rpcmethodcost",
+			Usage:       "Set the rate limit cost of RPC method <method>=<cost>, the method may be prefixed by its namespace",
+			Destination: &RPCMethodCosts,
+		},
 		&cli.BoolFlag{
 			Name:        "blocksonly",
 			Usage:       "Do not accept transactions from remote peers",
@@ -438,6 +504,31 @@ var (
 			Usage:       "Enable publish raw transaction in <address>",
 			Destination: &cfg.Zmqpubrawtx,
 		},
+		&cli.StringSliceFlag{
+			Name:        "eventwebhook",
+			Usage:       "Post the events of event bus to the HTTP webhook <url>",
+			Destination: &EventWebhooks,
+		},
+		&cli.StringFlag{
+			Name:        "eventwebhooksecret",
+			Usage:       "The HMAC-SHA256 key signing the event webhook requests",
+			Destination: &cfg.EventWebhookSecret,
+		},
+		&cli.StringFlag{
+			Name:        "eventfile",
+			Usage:       "Append the events of event bus to the JSONL file",
+			Destination: &cfg.EventFile,
+		},
+		&cli.StringFlag{
+			Name:        "eventwslisten",
+			Usage:       "Stream the events of event bus to the websocket clients in <address>",
+			Destination: &cfg.EventWSListen,
+		},
+		&cli.StringSliceFlag{
+			Name:        "eventtopic",
+			Usage:       "Only publish the events of the topic (default all topics)",
+			Destination: &EventTopics,
+		},
 		&cli.BoolFlag{
 			Name:        "invalidtxindex",
 			Usage:       "invalid transaction index.",
@@ -448,6 +539,16 @@ var (
 			Usage:       "Cache transaction full hash.",
 			Destination: &cfg.TxHashIndex,
 		},
+		&cli.BoolFlag{
+			Name:        "cfindex",
+			Usage:       "Maintain the committed filters (BIP157/158) of the blocks for the light clients.",
+			Destination: &cfg.CFIndex,
+		},
+		&cli.BoolFlag{
+			Name:        "explorerindex",
+			Usage:       "Maintain the balance history and totals of the addresses for the block explorers.",
+			Destination: &cfg.ExplorerIndex,
+		},
 		&cli.BoolFlag{
 			Name:        "ntp",
 			Usage:       "Auto sync time.",
@@ -508,6 +609,18 @@ var (
 			Usage:       "maxbadresp is the maximum number of bad responses from a peer before we stop talking to it.",
 			Destination: &cfg.MaxBadResp,
 		},
+		&cli.IntFlag{
+			Name:        "banthreshold",
+			Usage:       "Misbehavior score of a peer at which it is banned",
+			Value:       100,
+			Destination: &cfg.BanThreshold,
+		},
+		&cli.DurationFlag{
+			Name:        "banduration",
+			Usage:       "How long to ban misbehaving peers. Valid time units are {s, m, h}. Minimum 1 second",
+			Value:       time.Hour * 24,
+			Destination: &cfg.BanDuration,
+		},
 		&cli.BoolFlag{
 			Name:        "circuit",
 			Usage:       "All peers will ignore dual channel mode detection",
@@ -534,6 +647,17 @@ var (
 			Usage:       "Enable support account system mode",
 			Destination: &cfg.AcctMode,
 		},
+		&cli.BoolFlag{
+			Name:        "archival",
+			Usage:       "Archival tells the consensus if it should not prune old blocks",
+			Destination: &cfg.IsArchival,
+		},
+		&cli.Uint64Flag{
+			Name:        "prunedepth",
+			Usage:       "The number of blocks below the latest checkpoint whose bodies are kept when the node is not archival, zero disables the pruning",
+			Value:       defaultPruneDepth,
+			Destination: &cfg.PruneDepth,
+		},
 		&cli.Uint64Flag{
 			Name:        "dagcachesize",
 			Usage:       "DAG block cache size",
@@ -562,6 +686,17 @@ var (
 			Destination: &cfg.Consistency,
 			Value:       true,
 		},
+		&cli.StringFlag{
+			Name:        "gossip",
+			Usage:       "How blocks, transactions and graph state are relayed {stream,both,gossip}",
+			Value:       defaultGossip,
+			Destination: &cfg.Gossip,
+		},
+		&cli.BoolFlag{
+			Name:        "dandelion",
+			Usage:       "Relay own transactions by Dandelion++ stem and fluff phases to hide their origin",
+			Destination: &cfg.Dandelion,
+		},
 		&cli.BoolFlag{
 			Name:        "metrics",
 			Usage:       "Enable metrics collection and reporting",
@@ -572,6 +707,17 @@ var (
 			Usage:       "Enable expensive metrics collection and reporting",
 			Destination: &cfg.MetricsExpensive,
 		},
+		&cli.StringFlag{
+			Name:        "metrics.addr",
+			Usage:       "Enable the Prometheus metrics HTTP server on the given listening interface",
+			Destination: &cfg.MetricsAddr,
+		},
+		&cli.IntFlag{
+			Name:        "metrics.port",
+			Usage:       "Prometheus metrics HTTP server listening port",
+			Value:       defaultMetricsPort,
+			Destination: &cfg.MetricsPort,
+		},
 		&cli.Uint64Flag{
 			Name:        "minfreedisk",
 			Usage:       "Minimum free disk space in MB, once reached triggers auto shut down (default = 512M, 0 = disabled)",
@@ -639,6 +785,28 @@ var (
 			Usage:       "Build block template timeout by Millisecond.(Can limit the number of transactions included in the block)",
 			Destination: &cfg.GBTTimeOut,
 		},
+		&cli.StringFlag{
+			Name:        "stratumlisten",
+			Usage:       "Listen for stratum mining connections on the address (empty to disable)",
+			Destination: &cfg.StratumListen,
+		},
+		&cli.StringFlag{
+			Name:        "stratumpass",
+			Usage:       "Password of stratum workers (empty to accept any worker)",
+			Destination: &cfg.StratumPass,
+		},
+		&cli.Float64Flag{
+			Name:        "stratumdiff",
+			Usage:       "Initial share difficulty of stratum workers",
+			Value:       defaultStratumDiff,
+			Destination: &cfg.StratumDiff,
+		},
+		&cli.DurationFlag{
+			Name:        "stratumsharetime",
+			Usage:       "Target interval between the shares of a stratum worker for the variable difficulty",
+			Value:       defaultStratumShareTime,
+			Destination: &cfg.StratumShareTime,
+		},
 	}
 )
 
@@ -656,15 +824,19 @@ func DefaultConfig(homeDir string) *config.Config {
 		RPCMaxClients:        defaultMaxRPCClients,
 		RPCMaxWebsockets:     defaultMaxRPCWebsockets,
 		RPCMaxConcurrentReqs: defaultMaxRPCConcurrentReqs,
+		RPCRateBurst:         defaultRPCRateBurst,
 		Generate:             defaultGenerate,
 		MaxPeers:             defaultMaxPeers,
 		MinTxFee:             defaultMinRelayTxFee,
+		LimitAncestorCount:   defaultLimitAncestorCount,
+		LimitDescendantCount: defaultLimitDescendantCount,
 		BlockMinSize:         defaultBlockMinSize,
 		BlockMaxSize:         defaultBlockMaxSize,
 		SigCacheMaxSize:      defaultSigCacheMaxSize,
 		MiningStateSync:      defaultMiningStateSync,
 		DAGType:              defaultDAGType,
 		Banning:              true,
+		Gossip:               defaultGossip,
 		MaxInbound:           defaultMaxInboundPeersPerHost,
 		InvalidTxIndex:       defaultInvalidTxIndex,
 		TxHashIndex:          defaultTxhashIndex,
@@ -677,6 +849,10 @@ func DefaultConfig(homeDir string) *config.Config {
 		SubmitNoSynced:       false,
 		DevNextGDB:           true,
 		GBTTimeOut:           defaultGBTTimeout,
+		StratumDiff:          defaultStratumDiff,
+		StratumShareTime:     defaultStratumShareTime,
+		PruneDepth:           defaultPruneDepth,
+		MetricsPort:          defaultMetricsPort,
 	}
 	if len(homeDir) > 0 {
 		hd, err := filepath.Abs(homeDir)
@@ -38,6 +38,11 @@ func LoadConfig(ctx *cli.Context, parsefile bool) (*config.Config, error) {
 	cfg.Whitelist = Whitelist.Value()
 	cfg.Blacklist = Blacklist.Value()
 	cfg.GBTNotify = GBTNotify.Value()
+	cfg.LightAddrs = LightAddrs.Value()
+	cfg.RPCAuth = RPCAuth.Value()
+	cfg.RPCMethodCosts = RPCMethodCosts.Value()
+	cfg.EventWebhooks = EventWebhooks.Value()
+	cfg.EventTopics = EventTopics.Value()
 
 	// Show the version and exit if the version flag was specified.
 	appName := filepath.Base(os.Args[0])
@@ -340,6 +345,19 @@ func SetupConfig(cfg *config.Config) error {
 		cfg.SetMiningAddrs(addr)
 	}
 
+	// Check the addresses watched by the light node are valid.
+	for _, strAddr := range cfg.LightAddrs {
+		addr, err := address.DecodeAddress(strAddr)
+		if err != nil {
+			str := "SetupConfig: light address '%s' failed to decode: %v"
+			return fmt.Errorf(str, strAddr, err)
+		}
+		if !address.IsForNetwork(addr, params.ActiveNetParams.Params) {
+			str := "SetupConfig: light address '%s' is on the wrong network"
+			return fmt.Errorf(str, strAddr)
+		}
+	}
+
 	if cfg.Generate {
 		cfg.Miner = true
 	}
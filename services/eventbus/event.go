@@ -0,0 +1,192 @@
+/*
+ * Copyright (c) 2017-2020 The qitmeer developers
+ */
+
+package eventbus
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/Qitmeer/qng/core/blockchain/token"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/meerdag"
+	etypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// The topics of the events
+const (
+	TopicBlockConnected    = "block.connected"
+	TopicBlockDisconnected = "block.disconnected"
+	TopicReorganization    = "chain.reorganization"
+	TopicMempoolAdd        = "mempool.add"
+	TopicMempoolRemove     = "mempool.remove"
+	TopicTokenState        = "token.state"
+	TopicEVMReceipts       = "evm.receipts"
+)
+
+// Topics are all the topics of the event bus.
+var Topics = []string{
+	TopicBlockConnected,
+	TopicBlockDisconnected,
+	TopicReorganization,
+	TopicMempoolAdd,
+	TopicMempoolRemove,
+	TopicTokenState,
+	TopicEVMReceipts,
+}
+
+func parseTopics(topics []string) (map[string]bool, error) {
+	if len(topics) == 0 {
+		return nil, nil
+	}
+	result := map[string]bool{}
+	for _, topic := range topics {
+		valid := false
+		for _, t := range Topics {
+			if t == topic {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("Unknown event topic %s, the valid topics are %v", topic, Topics)
+		}
+		result[topic] = true
+	}
+	return result, nil
+}
+
+// Event is the envelope of the data published to the sinks.  The sequence
+// number is increased one by one, the consumers use it to skip the events
+// which are delivered again.
+type Event struct {
+	Seq   uint64          `json:"seq"`
+	Topic string          `json:"topic"`
+	Time  int64           `json:"time"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// BlockEvent is the data of block.connected and block.disconnected.
+type BlockEvent struct {
+	Hash      string   `json:"hash"`
+	Order     uint64   `json:"order"`
+	Height    uint64   `json:"height"`
+	MainChain bool     `json:"mainchain"`
+	Parents   []string `json:"parents"`
+	Timestamp int64    `json:"timestamp"`
+	Txs       []string `json:"txs"`
+}
+
+func newBlockEvent(block *types.SerializedBlock, ib meerdag.IBlock, mainChain bool) *BlockEvent {
+	be := &BlockEvent{
+		Hash:      block.Hash().String(),
+		Order:     uint64(ib.GetOrder()),
+		Height:    uint64(ib.GetHeight()),
+		MainChain: mainChain,
+		Parents:   []string{},
+		Timestamp: block.Block().Header.Timestamp.Unix(),
+		Txs:       []string{},
+	}
+	for _, parent := range block.Block().Parents {
+		be.Parents = append(be.Parents, parent.String())
+	}
+	for _, tx := range block.Transactions() {
+		be.Txs = append(be.Txs, tx.Hash().String())
+	}
+	return be
+}
+
+// ReorganizationEvent is the data of chain.reorganization.
+type ReorganizationEvent struct {
+	OldBlocks []string `json:"oldblocks"`
+	NewBlock  string   `json:"newblock"`
+	NewOrder  uint64   `json:"neworder"`
+}
+
+// TxEvent is the data of mempool.add and mempool.remove, the raw transaction
+// is only in mempool.add.
+type TxEvent struct {
+	Hash string `json:"hash"`
+	Size int    `json:"size"`
+	Fee  int64  `json:"fee,omitempty"`
+	Raw  string `json:"raw,omitempty"`
+}
+
+func newTxEvent(tx *types.Tx, fee int64, raw bool) (*TxEvent, error) {
+	te := &TxEvent{
+		Hash: tx.Hash().String(),
+		Size: tx.Tx.SerializeSize(),
+		Fee:  fee,
+	}
+	if raw {
+		bs, err := tx.Tx.Serialize()
+		if err != nil {
+			return nil, err
+		}
+		te.Raw = hex.EncodeToString(bs)
+	}
+	return te, nil
+}
+
+// TokenBalance is the state of a token.
+type TokenBalance struct {
+	Id         uint16 `json:"id"`
+	Name       string `json:"name"`
+	Enable     bool   `json:"enable"`
+	UpLimit    uint64 `json:"uplimit"`
+	Balance    int64  `json:"balance"`
+	LockedMeer int64  `json:"lockedmeer"`
+}
+
+// TokenStateEvent is the data of token.state, it is published when the
+// connected block has the token transactions.
+type TokenStateEvent struct {
+	Block  string         `json:"block"`
+	Order  uint64         `json:"order"`
+	Txs    []string       `json:"txs"`
+	Tokens []TokenBalance `json:"tokens"`
+}
+
+func newTokenStateEvent(block *types.SerializedBlock, ib meerdag.IBlock, state *token.TokenState) *TokenStateEvent {
+	tse := &TokenStateEvent{
+		Block:  block.Hash().String(),
+		Order:  uint64(ib.GetOrder()),
+		Txs:    []string{},
+		Tokens: []TokenBalance{},
+	}
+	for _, tx := range block.Transactions() {
+		if !tx.IsDuplicate && types.IsTokenTx(tx.Tx) {
+			tse.Txs = append(tse.Txs, tx.Hash().String())
+		}
+	}
+	for id, tt := range state.Types {
+		tb := TokenBalance{
+			Id:      uint16(id),
+			Name:    tt.Name,
+			Enable:  tt.Enable,
+			UpLimit: tt.UpLimit,
+		}
+		if balance, ok := state.Balances[id]; ok {
+			tb.Balance = balance.Balance
+			tb.LockedMeer = balance.LockedMeer
+		}
+		tse.Tokens = append(tse.Tokens, tb)
+	}
+	sort.Slice(tse.Tokens, func(i, j int) bool {
+		return tse.Tokens[i].Id < tse.Tokens[j].Id
+	})
+	return tse
+}
+
+// EVMReceiptsEvent is the data of evm.receipts, it is published when the
+// connected block has the EVM transactions.
+type EVMReceiptsEvent struct {
+	Block     string            `json:"block"`
+	Order     uint64            `json:"order"`
+	EVMHash   string            `json:"evmhash"`
+	EVMNumber uint64            `json:"evmnumber"`
+	Receipts  []*etypes.Receipt `json:"receipts"`
+}
@@ -0,0 +1,391 @@
+/*
+ * Copyright (c) 2017-2020 The qitmeer developers
+ */
+
+// Package eventbus publishes the chain and mempool events to the pluggable
+// sinks without cgo, it is the pure-Go alternative of services/zmq for the
+// indexers.
+//
+// The events are appended to the journal in the data directory before they
+// are delivered, and every sink has a persisted cursor of the last event
+// which is delivered to it.  After the restart the sinks resume from their
+// cursors, so the events are delivered at least once and the consumers skip
+// the duplicates by the sequence numbers.  The events are removed from the
+// journal when all sinks have received them, the latest ones are retained for
+// the websocket clients to resume.
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Qitmeer/qng/config"
+	"github.com/Qitmeer/qng/consensus/model"
+	"github.com/Qitmeer/qng/core/blockchain"
+	"github.com/Qitmeer/qng/core/event"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/meerdag"
+	"github.com/Qitmeer/qng/meerevm/meer"
+	"github.com/Qitmeer/qng/node/service"
+)
+
+const (
+	// DirName is the directory of the journal and cursors in the data
+	// directory.
+	DirName     = "eventbus"
+	journalName = "events.jsonl"
+
+	// journalRetain is the number of the delivered events which are kept
+	// in the journal for the websocket clients.
+	journalRetain = 10000
+
+	pruneInterval = time.Minute * 10
+)
+
+// EventBus is the service of the event bus.
+type EventBus struct {
+	service.Service
+
+	consensus   model.Consensus
+	dir         string
+	topics      map[string]bool
+	journal     *journal
+	dispatchers []*dispatcher
+	wsListen    string
+	wsToken     string
+	ws          *wsServer
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// IsEnable returns true if any sink or the websocket stream is configured.
+func (eb *EventBus) IsEnable() bool {
+	return len(eb.dispatchers) > 0 || len(eb.wsListen) > 0
+}
+
+func (eb *EventBus) Start() error {
+	if !eb.IsEnable() {
+		return nil
+	}
+	if err := eb.Service.Start(); err != nil {
+		return err
+	}
+	log.Info("Start EventBus...", "sinks", len(eb.dispatchers), "next", eb.journal.Next())
+
+	if len(eb.wsListen) > 0 {
+		ws, err := newWSServer(eb.wsListen, eb.wsToken, eb.journal)
+		if err != nil {
+			return err
+		}
+		eb.ws = ws
+		eb.ws.start()
+	}
+	for _, d := range eb.dispatchers {
+		eb.wg.Add(1)
+		go func(d *dispatcher) {
+			defer eb.wg.Done()
+			d.run(eb.ctx)
+		}(d)
+	}
+	eb.wg.Add(1)
+	go eb.pruneHandler()
+
+	if eb.consensus != nil {
+		eb.subscribe()
+	}
+	return nil
+}
+
+func (eb *EventBus) Stop() error {
+	if !eb.IsEnable() {
+		return nil
+	}
+	if err := eb.Service.Stop(); err != nil {
+		return err
+	}
+	log.Info("Stop EventBus...")
+
+	eb.cancel()
+	if eb.ws != nil {
+		eb.ws.stop()
+	}
+	eb.wg.Wait()
+	for _, d := range eb.dispatchers {
+		if err := d.sink.Close(); err != nil {
+			log.Warn("Close event sink", "sink", d.sink.Name(), "error", err)
+		}
+	}
+	return eb.journal.close()
+}
+
+// Publish appends the event of the topic to the journal, the data is encoded
+// to JSON.
+func (eb *EventBus) Publish(topic string, data interface{}) error {
+	if eb.topics != nil && !eb.topics[topic] {
+		return nil
+	}
+	bs, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return eb.journal.append(&Event{Topic: topic, Time: time.Now().Unix(), Data: bs})
+}
+
+func (eb *EventBus) publish(topic string, data interface{}) {
+	err := eb.Publish(topic, data)
+	if err != nil {
+		log.Error("Publish event", "topic", topic, "error", err)
+	}
+}
+
+// prune removes the events which are delivered to all sinks.
+func (eb *EventBus) prune() error {
+	next := eb.journal.Next()
+	if next <= journalRetain+1 {
+		return nil
+	}
+	seq := next - journalRetain - 1
+	for _, d := range eb.dispatchers {
+		if d.cursor.seq < seq {
+			seq = d.cursor.seq
+		}
+	}
+	if seq < eb.journal.First() {
+		return nil
+	}
+	return eb.journal.prune(seq)
+}
+
+func (eb *EventBus) pruneHandler() {
+	defer eb.wg.Done()
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			err := eb.prune()
+			if err != nil {
+				log.Warn("Prune event journal", "error", err)
+			}
+		case <-eb.ctx.Done():
+			return
+		}
+	}
+}
+
+func (eb *EventBus) subscribe() {
+	ch := make(chan *event.Event)
+	sub := eb.consensus.Events().Subscribe(ch)
+	eb.wg.Add(1)
+	go func() {
+		defer eb.wg.Done()
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case ev := <-ch:
+				if ev.Data != nil {
+					switch value := ev.Data.(type) {
+					case *blockchain.Notification:
+						eb.handleNotification(value)
+					case *types.TxPoolAdded:
+						te, err := newTxEvent(value.Tx, value.Fee, true)
+						if err != nil {
+							log.Error(err.Error())
+							break
+						}
+						eb.publish(TopicMempoolAdd, te)
+					case *types.TxPoolRemoved:
+						te, _ := newTxEvent(value.Tx, 0, false)
+						eb.publish(TopicMempoolRemove, te)
+					}
+				}
+				if ev.Ack != nil {
+					ev.Ack <- struct{}{}
+				}
+			case <-eb.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (eb *EventBus) handleNotification(n *blockchain.Notification) {
+	switch n.Type {
+	case blockchain.BlockConnected:
+		blockSlice, ok := n.Data.([]interface{})
+		if !ok || len(blockSlice) != 3 {
+			log.Warn("Chain connected notification is not a block slice.")
+			break
+		}
+		block := blockSlice[0].(*types.SerializedBlock)
+		ib := blockSlice[2].(meerdag.IBlock)
+		eb.publish(TopicBlockConnected, newBlockEvent(block, ib, blockSlice[1].(bool)))
+		eb.publishTokenState(block, ib)
+		eb.publishEVMReceipts(block, ib)
+
+	case blockchain.BlockDisconnected:
+		blockSlice, ok := n.Data.([]interface{})
+		if !ok || len(blockSlice) != 2 {
+			log.Warn("Chain disconnected notification is not a block slice.")
+			break
+		}
+		block := blockSlice[0].(*types.SerializedBlock)
+		ib := blockSlice[1].(meerdag.IBlock)
+		eb.publish(TopicBlockDisconnected, newBlockEvent(block, ib, false))
+
+	case blockchain.Reorganization:
+		rnd, ok := n.Data.(*blockchain.ReorganizationNotifyData)
+		if !ok {
+			log.Warn("Chain reorganization notification is not ReorganizationNotifyData.")
+			break
+		}
+		re := &ReorganizationEvent{
+			OldBlocks: []string{},
+			NewBlock:  rnd.NewBlock.String(),
+			NewOrder:  rnd.NewOrder,
+		}
+		for _, h := range rnd.OldBlocks {
+			re.OldBlocks = append(re.OldBlocks, h.String())
+		}
+		eb.publish(TopicReorganization, re)
+	}
+}
+
+func (eb *EventBus) publishTokenState(block *types.SerializedBlock, ib meerdag.IBlock) {
+	if eb.topics != nil && !eb.topics[TopicTokenState] {
+		return
+	}
+	hasToken := false
+	for _, tx := range block.Transactions() {
+		if !tx.IsDuplicate && types.IsTokenTx(tx.Tx) {
+			hasToken = true
+			break
+		}
+	}
+	if !hasToken {
+		return
+	}
+	bc, ok := eb.consensus.BlockChain().(*blockchain.BlockChain)
+	if !ok {
+		return
+	}
+	state := bc.GetTokenState(uint32(ib.GetID()))
+	if state == nil {
+		return
+	}
+	eb.publish(TopicTokenState, newTokenStateEvent(block, ib, state))
+}
+
+func (eb *EventBus) publishEVMReceipts(block *types.SerializedBlock, ib meerdag.IBlock) {
+	if eb.topics != nil && !eb.topics[TopicEVMReceipts] {
+		return
+	}
+	hasVM := false
+	for _, tx := range block.Transactions() {
+		if !tx.IsDuplicate && types.IsCrossChainVMTx(tx.Tx) {
+			hasVM = true
+			break
+		}
+	}
+	if !hasVM || ib.GetState() == nil {
+		return
+	}
+	bc, ok := eb.consensus.BlockChain().(*blockchain.BlockChain)
+	if !ok {
+		return
+	}
+	mc, ok := bc.MeerChain().(*meer.MeerChain)
+	if !ok {
+		return
+	}
+	evmHash := ib.GetState().GetEVMHash()
+	receipts := mc.ETHChain().Ether().BlockChain().GetReceiptsByHash(evmHash)
+	if len(receipts) == 0 {
+		return
+	}
+	eb.publish(TopicEVMReceipts, &EVMReceiptsEvent{
+		Block:     block.Hash().String(),
+		Order:     uint64(ib.GetOrder()),
+		EVMHash:   evmHash.String(),
+		EVMNumber: ib.GetState().GetEVMNumber(),
+		Receipts:  receipts,
+	})
+}
+
+func newEventBus(dir string, topics []string, sinks []Sink, wsListen string, wsToken string) (*EventBus, error) {
+	if len(wsListen) > 0 && len(wsToken) == 0 && !isLoopback(wsListen) {
+		return nil, fmt.Errorf("--eventwslisten %s is not the loopback address, --eventwstoken is required", wsListen)
+	}
+	eb := &EventBus{dir: dir, wsListen: wsListen, wsToken: wsToken}
+	eb.ctx, eb.cancel = context.WithCancel(context.Background())
+	var err error
+	eb.topics, err = parseTopics(topics)
+	if err != nil {
+		return nil, err
+	}
+	if len(sinks) == 0 && len(wsListen) == 0 {
+		return eb, nil
+	}
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+	eb.journal, err = openJournal(filepath.Join(dir, journalName))
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]bool{}
+	for _, sink := range sinks {
+		if names[sink.Name()] {
+			eb.journal.close()
+			return nil, fmt.Errorf("Duplicate event sink %s", sink.Name())
+		}
+		names[sink.Name()] = true
+		d, err := newDispatcher(dir, sink, eb.journal)
+		if err != nil {
+			eb.journal.close()
+			return nil, err
+		}
+		eb.dispatchers = append(eb.dispatchers, d)
+	}
+	err = eb.prune()
+	if err != nil {
+		eb.journal.close()
+		return nil, err
+	}
+	return eb, nil
+}
+
+func New(cfg *config.Config, consensus model.Consensus) (*EventBus, error) {
+	sinks := []Sink{}
+	for _, url := range cfg.EventWebhooks {
+		sinks = append(sinks, NewWebhookSink(url, cfg.EventWebhookSecret))
+	}
+	if len(cfg.EventFile) > 0 {
+		fs, err := NewFileSink(cfg.ResolveDataPath(cfg.EventFile))
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, fs)
+	}
+	for _, url := range cfg.EventNATS {
+		ns, err := NewNATSSink(url, cfg.EventNATSSubject)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, ns)
+	}
+	eb, err := newEventBus(cfg.ResolveDataPath(DirName), cfg.EventTopics, sinks, cfg.EventWSListen, cfg.EventWSToken)
+	if err != nil {
+		return nil, err
+	}
+	eb.consensus = consensus
+	return eb, nil
+}
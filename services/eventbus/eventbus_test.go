@@ -0,0 +1,426 @@
+package eventbus
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Qitmeer/qng/rpc/websocket"
+)
+
+// memSink records the delivered events, the first failures are returned as
+// errors.
+type memSink struct {
+	lock     sync.Mutex
+	name     string
+	failures int
+	events   []*Event
+}
+
+func (ms *memSink) Name() string {
+	return ms.name
+}
+
+func (ms *memSink) Deliver(ctx context.Context, ev *Event) error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+	if ms.failures > 0 {
+		ms.failures--
+		return fmt.Errorf("failure")
+	}
+	ms.events = append(ms.events, ev)
+	return nil
+}
+
+func (ms *memSink) Close() error {
+	return nil
+}
+
+func (ms *memSink) seqs() []uint64 {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+	result := []uint64{}
+	for _, ev := range ms.events {
+		result = append(result, ev.Seq)
+	}
+	return result
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), journalName)
+	j, err := openJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		err := j.append(&Event{Topic: TopicMempoolAdd, Data: json.RawMessage(strconv.Itoa(i))})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The events are read after they're synced
+	if err := j.sync(); err != nil {
+		t.Fatal(err)
+	}
+	ev, err := j.read(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.Seq != 3 || string(ev.Data) != "2" {
+		t.Fatalf("unexpected event %d %s", ev.Seq, ev.Data)
+	}
+	j.close()
+
+	// The partial line of the crash is dropped
+	file, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	file.WriteString(`{"seq":6,"topic":`)
+	file.Close()
+	j, err = openJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.Next() != 6 {
+		t.Fatalf("unexpected next sequence %d", j.Next())
+	}
+	if err := j.append(&Event{Topic: TopicMempoolAdd, Data: json.RawMessage("5")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := j.prune(4); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.read(4); err != errEventPruned {
+		t.Fatalf("unexpected error %v", err)
+	}
+	j.close()
+	j, err = openJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.close()
+	if j.First() != 5 || j.Next() != 7 {
+		t.Fatalf("unexpected journal %d %d", j.First(), j.Next())
+	}
+	for seq := uint64(5); seq < 7; seq++ {
+		ev, err := j.read(seq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ev.Seq != seq || string(ev.Data) != strconv.Itoa(int(seq-1)) {
+			t.Fatalf("unexpected event %d %s", ev.Seq, ev.Data)
+		}
+	}
+}
+
+func TestEventBusCursor(t *testing.T) {
+	dir := t.TempDir()
+	sink := &memSink{name: "mem", failures: 1}
+	eb, err := newEventBus(dir, []string{TopicBlockConnected}, []Sink{sink}, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := eb.Start(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		eb.Publish(TopicBlockConnected, i)
+		// The topic isn't published
+		eb.Publish(TopicMempoolAdd, i)
+	}
+	waitFor(t, func() bool { return len(sink.seqs()) == 3 })
+	if err := eb.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(sink.seqs()) != "[1 2 3]" {
+		t.Fatalf("unexpected events %v", sink.seqs())
+	}
+
+	// The sink resumes from the cursor after the restart
+	sink = &memSink{name: "mem"}
+	other := &memSink{name: "other"}
+	eb, err = newEventBus(dir, nil, []Sink{sink, other}, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := eb.Start(); err != nil {
+		t.Fatal(err)
+	}
+	eb.Publish(TopicMempoolAdd, 3)
+	waitFor(t, func() bool { return len(sink.seqs()) == 1 && len(other.seqs()) == 4 })
+	eb.Stop()
+	if fmt.Sprint(sink.seqs()) != "[4]" {
+		t.Fatalf("unexpected events %v", sink.seqs())
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	secret := "secret"
+	var lock sync.Mutex
+	requests := 0
+	bodies := [][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		requests++
+		body, _ := io.ReadAll(r.Body)
+		if !VerifySignature([]byte(secret), body, r.Header.Get(HeaderSignature)) {
+			t.Errorf("bad signature %s", r.Header.Get(HeaderSignature))
+		}
+		if r.Header.Get(HeaderEvent) != TopicBlockConnected || r.Header.Get(HeaderSeq) != "7" {
+			t.Errorf("unexpected headers %v", r.Header)
+		}
+		if requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		bodies = append(bodies, body)
+	}))
+	defer server.Close()
+
+	ws := NewWebhookSink(server.URL, secret)
+	ws.backoff = time.Millisecond
+	ev := &Event{Seq: 7, Topic: TopicBlockConnected, Data: json.RawMessage(`{"hash":"0"}`)}
+	if err := ws.Deliver(context.Background(), ev); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 3 || len(bodies) != 1 {
+		t.Fatalf("unexpected requests %d", requests)
+	}
+	received := &Event{}
+	if err := json.Unmarshal(bodies[0], received); err != nil {
+		t.Fatal(err)
+	}
+	if received.Seq != ev.Seq || string(received.Data) != string(ev.Data) {
+		t.Fatalf("unexpected event %v", received)
+	}
+
+	ws.retries = 1
+	requests = -10
+	if err := ws.Deliver(context.Background(), ev); err == nil {
+		t.Fatal("the failed request is delivered")
+	}
+	if requests != -8 {
+		t.Fatalf("unexpected retries %d", requests+10)
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	for i := 0; i < 2; i++ {
+		fs, err := NewFileSink(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := fs.Deliver(context.Background(), &Event{Seq: uint64(i + 1), Data: json.RawMessage("{}")}); err != nil {
+			t.Fatal(err)
+		}
+		fs.Close()
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"seq":1,"topic":"","time":0,"data":{}}` + "\n" + `{"seq":2,"topic":"","time":0,"data":{}}` + "\n"
+	if string(data) != expected {
+		t.Fatalf("unexpected file %s", data)
+	}
+}
+
+func TestWebsocketStream(t *testing.T) {
+	eb, err := newEventBus(t.TempDir(), nil, nil, "127.0.0.1:0", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := eb.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer eb.Stop()
+	for i := 0; i < 3; i++ {
+		eb.Publish(TopicMempoolAdd, i)
+	}
+	eb.Publish(TopicMempoolRemove, 0)
+
+	url := fmt.Sprintf("ws://%s/?from=2&topics=%s", eb.ws.listener.Addr(), TopicMempoolAdd)
+	conn, _, err := (&websocket.Dialer{}).Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	eb.Publish(TopicMempoolAdd, 3)
+	for _, seq := range []uint64{2, 3, 5} {
+		conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ev := &Event{}
+		if err := json.Unmarshal(msg, ev); err != nil {
+			t.Fatal(err)
+		}
+		if ev.Seq != seq || ev.Topic != TopicMempoolAdd {
+			t.Fatalf("unexpected event %d %s", ev.Seq, ev.Topic)
+		}
+	}
+}
+
+func TestWebsocketToken(t *testing.T) {
+	if _, err := newEventBus(t.TempDir(), nil, nil, "0.0.0.0:0", ""); err == nil {
+		t.Fatal("the websocket server listens on all addresses without token")
+	}
+	eb, err := newEventBus(t.TempDir(), nil, nil, "127.0.0.1:0", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := eb.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer eb.Stop()
+	addr := eb.ws.listener.Addr()
+	_, resp, err := (&websocket.Dialer{}).Dial(fmt.Sprintf("ws://%s/", addr), nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatal("the client without token is accepted")
+	}
+	conn, _, err := (&websocket.Dialer{}).Dial(fmt.Sprintf("ws://%s/", addr), http.Header{"Authorization": {"Bearer token"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	conn, _, err = (&websocket.Dialer{}).Dial(fmt.Sprintf("ws://%s/?token=token", addr), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	// Without token only the browsers of the same origin are allowed
+	ws := &wsServer{}
+	r := httptest.NewRequest(http.MethodGet, "http://127.0.0.1:1234/", nil)
+	r.Header.Set("Origin", "http://evil.com")
+	if ws.checkOrigin(r) {
+		t.Fatal("the browser of the other origin is allowed")
+	}
+	r.Header.Set("Origin", "http://127.0.0.1:1234")
+	if !ws.checkOrigin(r) {
+		t.Fatal("the browser of the same origin isn't allowed")
+	}
+}
+
+// natsServer is the fake NATS server which records the published messages,
+// the first connection is closed after the message.
+type natsServer struct {
+	listener net.Listener
+	lock     sync.Mutex
+	conns    int
+	msgs     []string
+	connects []string
+}
+
+func newNATSServer(t *testing.T) *natsServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns := &natsServer{listener: listener}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			ns.lock.Lock()
+			ns.conns++
+			first := ns.conns == 1
+			ns.lock.Unlock()
+			go ns.serve(conn, first)
+		}
+	}()
+	return ns
+}
+
+func (ns *natsServer) serve(conn net.Conn, first bool) {
+	defer conn.Close()
+	conn.Write([]byte(`INFO {"server_id":"test","auth_required":true}` + "\r\n"))
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := readNATSLine(reader)
+		if err != nil {
+			return
+		}
+		switch {
+		case strings.HasPrefix(line, "CONNECT "):
+			ns.lock.Lock()
+			ns.connects = append(ns.connects, line[len("CONNECT "):])
+			ns.lock.Unlock()
+		case strings.HasPrefix(line, "PUB "):
+			fields := strings.Fields(line)
+			size, _ := strconv.Atoi(fields[2])
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return
+			}
+			if first {
+				return
+			}
+			ns.lock.Lock()
+			ns.msgs = append(ns.msgs, fields[1]+" "+string(payload[:size]))
+			ns.lock.Unlock()
+		case line == "PING":
+			conn.Write([]byte("PING\r\nPONG\r\n"))
+		case line == "PONG":
+		}
+	}
+}
+
+func TestNATSSink(t *testing.T) {
+	server := newNATSServer(t)
+	defer server.listener.Close()
+	if _, err := NewNATSSink("http://127.0.0.1", ""); err == nil {
+		t.Fatal("the bad url is accepted")
+	}
+	sink, err := NewNATSSink(fmt.Sprintf("nats://user:pass@%s", server.listener.Addr()), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	ev := &Event{Seq: 7, Topic: TopicBlockConnected, Data: json.RawMessage(`{"hash":"0"}`)}
+	// The connection is lost before PONG, the event isn't delivered
+	if err := sink.Deliver(context.Background(), ev); err == nil {
+		t.Fatal("the lost event is delivered")
+	}
+	if err := sink.Deliver(context.Background(), ev); err != nil {
+		t.Fatal(err)
+	}
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	if len(server.msgs) != 1 || !strings.HasPrefix(server.msgs[0], "qng.block.connected {\"seq\":7,") {
+		t.Fatalf("unexpected messages %v", server.msgs)
+	}
+	connect := natsConnect{}
+	if err := json.Unmarshal([]byte(server.connects[1]), &connect); err != nil {
+		t.Fatal(err)
+	}
+	if connect.User != "user" || connect.Pass != "pass" || connect.Verbose {
+		t.Fatalf("unexpected connect %s", server.connects[1])
+	}
+}
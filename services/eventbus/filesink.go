@@ -0,0 +1,56 @@
+/*
+ * Copyright (c) 2017-2020 The qitmeer developers
+ */
+
+package eventbus
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// FileSink appends the events to the file, one JSON event a line.
+type FileSink struct {
+	lock sync.Mutex
+	path string
+	file *os.File
+}
+
+func (fs *FileSink) Name() string {
+	h := sha256.Sum256([]byte(fs.path))
+	return "file-" + hex.EncodeToString(h[:8])
+}
+
+func (fs *FileSink) Deliver(ctx context.Context, ev *Event) error {
+	line, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+	_, err = fs.file.Write(line)
+	if err != nil {
+		return err
+	}
+	return fs.file.Sync()
+}
+
+func (fs *FileSink) Close() error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+	return fs.file.Close()
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{path: path, file: file}, nil
+}
@@ -0,0 +1,400 @@
+/*
+ * Copyright (c) 2017-2020 The qitmeer developers
+ */
+
+package eventbus
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	errJournalClosed = errors.New("The event journal is closed")
+	errEventPruned   = errors.New("The event is pruned from the journal")
+)
+
+// journal is the append-only file of the events, one JSON event a line.  The
+// events are kept until they are delivered to all sinks, so that they can be
+// delivered again after the restart.
+//
+// The appended events are synced to the disk in batches by the background
+// syncer, so the publishers don't wait for the disk.  Only the synced events
+// are read, so no sink receives the event which is lost by the crash.
+type journal struct {
+	lock sync.RWMutex
+	path string
+	file *os.File
+	size int64
+	// first is the sequence number of the first event in the file, the
+	// offset of the event seq is offsets[seq-first].
+	first   uint64
+	offsets []int64
+	// durable is the sequence number of the first event which isn't synced.
+	durable uint64
+	// notify is closed and replaced when the events are synced.
+	notify chan struct{}
+	closed bool
+
+	// syncLock serializes the syncs and the prunes, which replace the file.
+	syncLock sync.Mutex
+	dirty    chan struct{}
+	quit     chan struct{}
+	quitOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+func openJournal(path string) (*journal, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	j := &journal{
+		path:   path,
+		file:   file,
+		first:  1,
+		notify: make(chan struct{}),
+		dirty:  make(chan struct{}, 1),
+		quit:   make(chan struct{}),
+	}
+	err = j.load()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	j.durable = j.next()
+	j.wg.Add(1)
+	go j.syncHandler()
+	return j, nil
+}
+
+// load indexes the events of the file, the partial line left by the crash is
+// truncated.
+func (j *journal) load() error {
+	_, err := j.file.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(j.file)
+	offset := int64(0)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		ev := Event{}
+		err = json.Unmarshal(line, &ev)
+		if err != nil {
+			return fmt.Errorf("Bad event journal %s at offset %d: %v", j.path, offset, err)
+		}
+		if len(j.offsets) == 0 {
+			j.first = ev.Seq
+		} else if ev.Seq != j.next() {
+			return fmt.Errorf("Bad event journal %s: expected sequence %d, got %d", j.path, j.next(), ev.Seq)
+		}
+		j.offsets = append(j.offsets, offset)
+		offset += int64(len(line))
+	}
+	j.size = offset
+	err = j.file.Truncate(offset)
+	if err != nil {
+		return err
+	}
+	_, err = j.file.Seek(offset, io.SeekStart)
+	return err
+}
+
+// next returns the sequence number of the next event.
+func (j *journal) next() uint64 {
+	return j.first + uint64(len(j.offsets))
+}
+
+// Next returns the sequence number of the next event.
+func (j *journal) Next() uint64 {
+	j.lock.RLock()
+	defer j.lock.RUnlock()
+	return j.next()
+}
+
+// First returns the sequence number of the first event in the journal.
+func (j *journal) First() uint64 {
+	j.lock.RLock()
+	defer j.lock.RUnlock()
+	return j.first
+}
+
+// append assigns the sequence number to the event and writes it to the file,
+// the event is read after it's synced.
+func (j *journal) append(ev *Event) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if j.closed {
+		return errJournalClosed
+	}
+	ev.Seq = j.next()
+	line, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	_, err = j.file.Write(line)
+	if err != nil {
+		// Drop the partial line, or it breaks the journal.
+		j.file.Truncate(j.size)
+		j.file.Seek(j.size, io.SeekStart)
+		return err
+	}
+	j.offsets = append(j.offsets, j.size)
+	j.size += int64(len(line))
+
+	select {
+	case j.dirty <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// syncHandler syncs the appended events, the events which are appended
+// during the sync are synced together in the next one.
+func (j *journal) syncHandler() {
+	defer j.wg.Done()
+	for {
+		select {
+		case <-j.dirty:
+			err := j.sync()
+			if err != nil && err != errJournalClosed {
+				log.Error("Sync event journal", "error", err)
+			}
+		case <-j.quit:
+			return
+		}
+	}
+}
+
+// sync writes the appended events to the disk, and notifies the readers.
+func (j *journal) sync() error {
+	j.syncLock.Lock()
+	defer j.syncLock.Unlock()
+
+	j.lock.RLock()
+	if j.closed {
+		j.lock.RUnlock()
+		return errJournalClosed
+	}
+	next := j.next()
+	file := j.file
+	durable := j.durable
+	j.lock.RUnlock()
+	if next <= durable {
+		return nil
+	}
+	err := file.Sync()
+	if err != nil {
+		return err
+	}
+	j.lock.Lock()
+	j.setDurable(next)
+	j.lock.Unlock()
+	return nil
+}
+
+// setDurable MUST be called with the lock held.
+func (j *journal) setDurable(seq uint64) {
+	if seq <= j.durable {
+		return
+	}
+	j.durable = seq
+	close(j.notify)
+	j.notify = make(chan struct{})
+}
+
+// read returns the event of the sequence number.
+func (j *journal) read(seq uint64) (*Event, error) {
+	j.lock.RLock()
+	defer j.lock.RUnlock()
+	if j.closed {
+		return nil, errJournalClosed
+	}
+	if seq < j.first {
+		return nil, errEventPruned
+	}
+	if seq >= j.durable {
+		return nil, fmt.Errorf("No event %d in the journal", seq)
+	}
+	index := seq - j.first
+	end := j.size
+	if index+1 < uint64(len(j.offsets)) {
+		end = j.offsets[index+1]
+	}
+	line := make([]byte, end-j.offsets[index])
+	_, err := j.file.ReadAt(line, j.offsets[index])
+	if err != nil {
+		return nil, err
+	}
+	ev := &Event{}
+	err = json.Unmarshal(line, ev)
+	if err != nil {
+		return nil, err
+	}
+	return ev, nil
+}
+
+// wait blocks until the event of the sequence number is synced, it returns
+// false if the quit channel is closed or the journal is closed.
+func (j *journal) wait(seq uint64, quit <-chan struct{}) bool {
+	for {
+		j.lock.RLock()
+		if j.closed {
+			j.lock.RUnlock()
+			return false
+		}
+		if seq < j.durable {
+			j.lock.RUnlock()
+			return true
+		}
+		notify := j.notify
+		j.lock.RUnlock()
+
+		select {
+		case <-notify:
+		case <-quit:
+			return false
+		}
+	}
+}
+
+// prune removes the events up to the sequence number from the journal by
+// rewriting the rest of the file.
+func (j *journal) prune(seq uint64) error {
+	j.syncLock.Lock()
+	defer j.syncLock.Unlock()
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if j.closed {
+		return errJournalClosed
+	}
+	if seq < j.first {
+		return nil
+	}
+	if seq >= j.next() {
+		seq = j.next() - 1
+	}
+	count := seq - j.first + 1
+	start := j.size
+	if count < uint64(len(j.offsets)) {
+		start = j.offsets[count]
+	}
+
+	tmpPath := j.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmp, io.NewSectionReader(j.file, start, j.size-start))
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	err = os.Rename(tmpPath, j.path)
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	j.file.Close()
+	j.file = tmp
+
+	offsets := make([]int64, 0, uint64(len(j.offsets))-count)
+	for _, offset := range j.offsets[count:] {
+		offsets = append(offsets, offset-start)
+	}
+	j.offsets = offsets
+	j.first = seq + 1
+	j.size -= start
+	// The rest of the events are synced in the new file.
+	j.setDurable(j.next())
+	_, err = j.file.Seek(j.size, io.SeekStart)
+	return err
+}
+
+// close syncs the appended events and closes the file.
+func (j *journal) close() error {
+	j.quitOnce.Do(func() {
+		close(j.quit)
+	})
+	j.wg.Wait()
+	err := j.sync()
+	if err != nil && err != errJournalClosed {
+		log.Error("Sync event journal", "error", err)
+	}
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if j.closed {
+		return nil
+	}
+	j.closed = true
+	close(j.notify)
+	return j.file.Close()
+}
+
+// cursor is the persisted sequence number of the last event which is
+// delivered to the sink.
+type cursor struct {
+	path string
+	seq  uint64
+}
+
+func loadCursor(path string) (*cursor, error) {
+	c := &cursor{path: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c, nil
+		}
+		return nil, err
+	}
+	c.seq, err = strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Bad event cursor %s: %v", path, err)
+	}
+	return c, nil
+}
+
+// save replaces the cursor file atomically.
+func (c *cursor) save(seq uint64) error {
+	tmpPath := c.path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = file.WriteString(strconv.FormatUint(seq, 10))
+	if err == nil {
+		err = file.Sync()
+	}
+	file.Close()
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	err = os.Rename(tmpPath, c.path)
+	if err != nil {
+		return err
+	}
+	c.seq = seq
+	return nil
+}
@@ -0,0 +1,243 @@
+/*
+ * Copyright (c) 2017-2020 The qitmeer developers
+ */
+
+package eventbus
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultNATSSubject is the default subject prefix of the events.
+	DefaultNATSSubject = "qng"
+
+	natsDefaultPort = "4222"
+	natsTimeout     = time.Second * 10
+)
+
+// natsInfo is the part of the INFO message of the NATS server which is used.
+type natsInfo struct {
+	TLSRequired  bool `json:"tls_required"`
+	AuthRequired bool `json:"auth_required"`
+}
+
+// natsConnect is the CONNECT message of the NATS client.
+type natsConnect struct {
+	Verbose   bool   `json:"verbose"`
+	Pedantic  bool   `json:"pedantic"`
+	TLS       bool   `json:"tls_required"`
+	Name      string `json:"name"`
+	Lang      string `json:"lang"`
+	Version   string `json:"version"`
+	Protocol  int    `json:"protocol"`
+	User      string `json:"user,omitempty"`
+	Pass      string `json:"pass,omitempty"`
+	AuthToken string `json:"auth_token,omitempty"`
+}
+
+// NATSSink publishes the events to the NATS server by the core NATS protocol,
+// the event of the topic is published to the subject "<prefix>.<topic>".
+// Every event is followed by PING, it is delivered when the server responds
+// PONG, so the event is delivered again if the connection is lost before.
+//
+// The url is "nats://[user:pass@|token@]host[:port]", the scheme "tls"
+// requires TLS, which is also used when the server requires it.
+type NATSSink struct {
+	lock    sync.Mutex
+	url     *url.URL
+	subject string
+	conn    net.Conn
+	reader  *bufio.Reader
+}
+
+func (ns *NATSSink) Name() string {
+	h := sha256.Sum256([]byte(ns.url.String() + " " + ns.subject))
+	return "nats-" + hex.EncodeToString(h[:8])
+}
+
+func (ns *NATSSink) Deliver(ctx context.Context, ev *Event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	ns.lock.Lock()
+	defer ns.lock.Unlock()
+	if ns.conn == nil {
+		err = ns.connect(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	err = ns.publish(ns.subject+"."+ev.Topic, body)
+	if err != nil {
+		ns.closeConn()
+	}
+	return err
+}
+
+func (ns *NATSSink) publish(subject string, body []byte) error {
+	ns.conn.SetDeadline(time.Now().Add(natsTimeout))
+	msg := make([]byte, 0, len(body)+len(subject)+32)
+	msg = append(msg, fmt.Sprintf("PUB %s %d\r\n", subject, len(body))...)
+	msg = append(msg, body...)
+	msg = append(msg, "\r\nPING\r\n"...)
+	_, err := ns.conn.Write(msg)
+	if err != nil {
+		return err
+	}
+	return ns.waitPong()
+}
+
+// connect dials the server, upgrades the connection to TLS if it's required,
+// and sends CONNECT.
+func (ns *NATSSink) connect(ctx context.Context) error {
+	host := ns.url.Host
+	if len(ns.url.Port()) == 0 {
+		host = net.JoinHostPort(ns.url.Hostname(), natsDefaultPort)
+	}
+	dialer := &net.Dialer{Timeout: natsTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(natsTimeout))
+	reader := bufio.NewReader(conn)
+	line, err := readNATSLine(reader)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return fmt.Errorf("Unexpected NATS message %s", line)
+	}
+	info := natsInfo{}
+	err = json.Unmarshal([]byte(line[len("INFO "):]), &info)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("Bad NATS info: %v", err)
+	}
+	useTLS := ns.url.Scheme == "tls" || info.TLSRequired
+	if useTLS {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: ns.url.Hostname()})
+		err = tlsConn.HandshakeContext(ctx)
+		if err != nil {
+			conn.Close()
+			return err
+		}
+		conn = tlsConn
+		reader = bufio.NewReader(conn)
+	}
+
+	connect := natsConnect{
+		TLS:     useTLS,
+		Name:    "qng-eventbus",
+		Lang:    "go",
+		Version: "1.0.0",
+	}
+	if ns.url.User != nil {
+		pass, ok := ns.url.User.Password()
+		if ok {
+			connect.User = ns.url.User.Username()
+			connect.Pass = pass
+		} else {
+			connect.AuthToken = ns.url.User.Username()
+		}
+	}
+	bs, err := json.Marshal(connect)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	_, err = conn.Write([]byte("CONNECT " + string(bs) + "\r\nPING\r\n"))
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	ns.conn = conn
+	ns.reader = reader
+	err = ns.waitPong()
+	if err != nil {
+		ns.closeConn()
+		return err
+	}
+	return nil
+}
+
+// waitPong reads the messages of the server until PONG, the PING of the
+// server is answered.
+func (ns *NATSSink) waitPong() error {
+	for {
+		line, err := readNATSLine(ns.reader)
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			_, err = ns.conn.Write([]byte("PONG\r\n"))
+			if err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("NATS server %s responds %s", ns.url.Host, line)
+		}
+	}
+}
+
+func readNATSLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (ns *NATSSink) closeConn() {
+	if ns.conn == nil {
+		return
+	}
+	ns.conn.Close()
+	ns.conn = nil
+	ns.reader = nil
+}
+
+func (ns *NATSSink) Close() error {
+	ns.lock.Lock()
+	defer ns.lock.Unlock()
+	ns.closeConn()
+	return nil
+}
+
+func NewNATSSink(rawURL string, subject string) (*NATSSink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "nats" && u.Scheme != "tls" {
+		return nil, fmt.Errorf("Bad NATS url %s, the scheme must be nats or tls", rawURL)
+	}
+	if len(u.Hostname()) == 0 {
+		return nil, fmt.Errorf("Bad NATS url %s, no host", rawURL)
+	}
+	if len(subject) == 0 {
+		subject = DefaultNATSSubject
+	}
+	if strings.ContainsAny(subject, " \t\r\n*>") || strings.HasSuffix(subject, ".") {
+		return nil, fmt.Errorf("Bad NATS subject %s", subject)
+	}
+	return &NATSSink{url: u, subject: subject}, nil
+}
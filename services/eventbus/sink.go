@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2017-2020 The qitmeer developers
+ */
+
+package eventbus
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// retryMinInterval and retryMaxInterval are the range of the backoff
+	// before the failed event is delivered to the sink again.
+	retryMinInterval = time.Second
+	retryMaxInterval = time.Minute
+)
+
+// Sink is the destination of the events.  The events are delivered one by
+// one in sequence, the event is delivered again until no error is returned,
+// so the sink may receive the same event more than once.
+type Sink interface {
+	// Name identifies the cursor of the sink, it must not change between
+	// the restarts.
+	Name() string
+	Deliver(ctx context.Context, ev *Event) error
+	Close() error
+}
+
+// dispatcher delivers the events of the journal to the sink from its cursor.
+type dispatcher struct {
+	sink    Sink
+	journal *journal
+	cursor  *cursor
+}
+
+func newDispatcher(dir string, sink Sink, j *journal) (*dispatcher, error) {
+	c, err := loadCursor(filepath.Join(dir, sink.Name()+".cursor"))
+	if err != nil {
+		return nil, err
+	}
+	return &dispatcher{sink: sink, journal: j, cursor: c}, nil
+}
+
+func (d *dispatcher) run(ctx context.Context) {
+	for {
+		seq := d.cursor.seq + 1
+		if !d.journal.wait(seq, ctx.Done()) {
+			return
+		}
+		ev, err := d.journal.read(seq)
+		if err == errEventPruned {
+			// The events which are never delivered to the new sink
+			// are pruned, it starts from the first one.
+			d.cursor.seq = d.journal.First() - 1
+			continue
+		}
+		if err != nil {
+			log.Error("Read event journal", "sink", d.sink.Name(), "seq", seq, "error", err)
+			return
+		}
+		if !d.deliver(ctx, ev) {
+			return
+		}
+		err = d.cursor.save(seq)
+		if err != nil {
+			log.Error("Save event cursor", "sink", d.sink.Name(), "seq", seq, "error", err)
+			return
+		}
+	}
+}
+
+// deliver retries the event until it is delivered, it returns false if the
+// context is done.
+func (d *dispatcher) deliver(ctx context.Context, ev *Event) bool {
+	interval := retryMinInterval
+	for {
+		err := d.sink.Deliver(ctx, ev)
+		if err == nil {
+			return true
+		}
+		log.Warn("Deliver event", "sink", d.sink.Name(), "seq", ev.Seq, "topic", ev.Topic, "error", err, "retry", interval)
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return false
+		}
+		interval *= 2
+		if interval > retryMaxInterval {
+			interval = retryMaxInterval
+		}
+	}
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright (c) 2017-2020 The qitmeer developers
+ */
+
+package eventbus
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// The headers of the webhook requests
+	HeaderEvent     = "X-Qng-Event"
+	HeaderSeq       = "X-Qng-Seq"
+	HeaderSignature = "X-Qng-Signature"
+
+	webhookTimeout = time.Second * 10
+	webhookRetries = 3
+	webhookBackoff = time.Millisecond * 500
+)
+
+// WebhookSink posts the events to the HTTP endpoint.  The request body is
+// the JSON event, it is signed by the HMAC-SHA256 of the secret in the
+// X-Qng-Signature header as "sha256=<hex>".
+type WebhookSink struct {
+	url     string
+	secret  []byte
+	client  *http.Client
+	retries int
+	backoff time.Duration
+}
+
+func (ws *WebhookSink) Name() string {
+	h := sha256.Sum256([]byte(ws.url))
+	return "webhook-" + hex.EncodeToString(h[:8])
+}
+
+func (ws *WebhookSink) Deliver(ctx context.Context, ev *Event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	for i := 0; ; i++ {
+		err = ws.post(ctx, ev, body)
+		if err == nil || i >= ws.retries {
+			return err
+		}
+		select {
+		case <-time.After(ws.backoff << uint(i)):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (ws *WebhookSink) post(ctx context.Context, ev *Event, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ws.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, ev.Topic)
+	req.Header.Set(HeaderSeq, strconv.FormatUint(ev.Seq, 10))
+	if len(ws.secret) > 0 {
+		req.Header.Set(HeaderSignature, Sign(ws.secret, body))
+	}
+	resp, err := ws.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Webhook %s responds %s", ws.url, resp.Status)
+	}
+	return nil
+}
+
+func (ws *WebhookSink) Close() error {
+	ws.client.CloseIdleConnections()
+	return nil
+}
+
+// Sign returns the signature of the webhook request body.
+func Sign(secret []byte, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks the signature of the webhook request body for the
+// receivers.
+func VerifySignature(secret []byte, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+func NewWebhookSink(url string, secret string) *WebhookSink {
+	return &WebhookSink{
+		url:     url,
+		secret:  []byte(secret),
+		client:  &http.Client{},
+		retries: webhookRetries,
+		backoff: webhookBackoff,
+	}
+}
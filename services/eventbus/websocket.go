@@ -0,0 +1,195 @@
+/*
+ * Copyright (c) 2017-2020 The qitmeer developers
+ */
+
+package eventbus
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Qitmeer/qng/rpc/websocket"
+)
+
+const wsWriteTimeout = time.Second * 10
+
+// wsServer streams the events of the journal to the websocket clients.  The
+// clients keep their own cursors, they resume the stream by the query
+// "from=<seq>" with the sequence number of the next event, and may limit the
+// topics by "topics=<topic>,<topic>".  Without "from" only the new events are
+// streamed.
+//
+// Without the token the server only listens on the loopback address, and
+// only the browsers of the same origin are allowed.  With the token the
+// clients pass it by the header "Authorization: Bearer <token>" or the query
+// "token=<token>".
+type wsServer struct {
+	journal  *journal
+	token    string
+	listener net.Listener
+	server   *http.Server
+	upgrader websocket.Upgrader
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+func newWSServer(listen string, token string, j *journal) (*wsServer, error) {
+	if len(token) == 0 && !isLoopback(listen) {
+		return nil, fmt.Errorf("The event bus websocket server listens on %s without token, "+
+			"it must listen on the loopback address", listen)
+	}
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return nil, err
+	}
+	ws := &wsServer{
+		journal:  j,
+		token:    token,
+		listener: listener,
+	}
+	ws.upgrader = websocket.Upgrader{CheckOrigin: ws.checkOrigin}
+	ws.ctx, ws.cancel = context.WithCancel(context.Background())
+	ws.server = &http.Server{Handler: ws}
+	return ws, nil
+}
+
+func (ws *wsServer) start() {
+	ws.wg.Add(1)
+	go func() {
+		defer ws.wg.Done()
+		log.Info("Event bus websocket server listening", "addr", ws.listener.Addr())
+		err := ws.server.Serve(ws.listener)
+		if err != nil && err != http.ErrServerClosed {
+			log.Error("Event bus websocket server", "error", err)
+		}
+	}()
+}
+
+func (ws *wsServer) stop() {
+	ws.cancel()
+	ws.server.Close()
+	ws.wg.Wait()
+}
+
+// isLoopback returns true if the host of the address is the loopback
+// address or localhost.
+func isLoopback(listen string) bool {
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// checkOrigin allows the clients which aren't browsers, and the browsers of
+// the same origin if there is no token.
+func (ws *wsServer) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if len(origin) == 0 || len(ws.token) > 0 {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+func (ws *wsServer) authorized(r *http.Request) bool {
+	if len(ws.token) == 0 {
+		return true
+	}
+	token := r.URL.Query().Get("token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(ws.token)) == 1
+}
+
+func (ws *wsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !ws.authorized(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	from := ws.journal.Next()
+	if v := r.URL.Query().Get("from"); len(v) > 0 {
+		seq, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			http.Error(w, "Bad from sequence", http.StatusBadRequest)
+			return
+		}
+		from = seq
+	}
+	var topics map[string]bool
+	if v := r.URL.Query().Get("topics"); len(v) > 0 {
+		var err error
+		topics, err = parseTopics(strings.Split(v, ","))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	conn, err := ws.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	ws.wg.Add(1)
+	defer ws.wg.Done()
+	ws.stream(conn, from, topics)
+}
+
+func (ws *wsServer) stream(conn *websocket.Conn, from uint64, topics map[string]bool) {
+	ctx, cancel := context.WithCancel(ws.ctx)
+	defer cancel()
+	defer conn.Close()
+	// The client messages are discarded, the read loop handles the
+	// control frames and detects the closed connection.
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	seq := from
+	for ws.journal.wait(seq, ctx.Done()) {
+		ev, err := ws.journal.read(seq)
+		if err == errEventPruned {
+			seq = ws.journal.First()
+			continue
+		}
+		if err != nil {
+			log.Debug("Read event journal", "seq", seq, "error", err)
+			return
+		}
+		seq++
+		if topics != nil && !topics[ev.Topic] {
+			continue
+		}
+		msg, err := json.Marshal(ev)
+		if err != nil {
+			return
+		}
+		conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+		if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+			log.Debug("Write event", "remote", conn.RemoteAddr(), "error", err)
+			return
+		}
+	}
+}
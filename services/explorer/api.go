@@ -0,0 +1,209 @@
+/*
+ * Copyright (c) 2017-2020 The qitmeer developers
+ */
+
+package explorer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/Qitmeer/qng/core/address"
+	"github.com/Qitmeer/qng/core/json"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/rpc/api"
+	"github.com/Qitmeer/qng/rpc/client/cmds"
+	"github.com/Qitmeer/qng/services/index"
+)
+
+const (
+	defaultRichListCount = 100
+	maxRichListCount     = 1000
+	// maxRichListSkip bounds the entries which are iterated to skip.
+	maxRichListSkip = 100000
+)
+
+func (s *Service) APIs() []api.API {
+	return []api.API{
+		{
+			NameSpace: cmds.DefaultServiceNameSpace,
+			Service:   NewPublicExplorerAPI(s),
+			Public:    true,
+		},
+	}
+}
+
+// PublicExplorerAPI provides the RPC of the explorer index.
+type PublicExplorerAPI struct {
+	s *Service
+}
+
+func NewPublicExplorerAPI(s *Service) *PublicExplorerAPI {
+	return &PublicExplorerAPI{s}
+}
+
+func (api *PublicExplorerAPI) explorerIndex() (*index.ExplorerIndex, error) {
+	ei := api.s.ExplorerIndex()
+	if ei == nil {
+		return nil, fmt.Errorf("The explorer index is disabled (--explorerindex)")
+	}
+	return ei, nil
+}
+
+// normalizeAddress returns the address as it is indexed, the pay-to-pubkey
+// addresses are indexed by their pubkey hash addresses.
+func normalizeAddress(addr string) (string, error) {
+	a, err := address.DecodeAddress(addr)
+	if err != nil {
+		return "", err
+	}
+	return a.Encode(), nil
+}
+
+// GetAddressSummary returns the balances, the totals of the received and sent
+// amounts and the transaction counts of the address in all coins.
+func (api *PublicExplorerAPI) GetAddressSummary(addr string) (interface{}, error) {
+	ei, err := api.explorerIndex()
+	if err != nil {
+		return nil, err
+	}
+	addr, err = normalizeAddress(addr)
+	if err != nil {
+		return nil, err
+	}
+	balances, err := ei.AddressBalances(addr)
+	if err != nil {
+		return nil, err
+	}
+	result := json.AddressSummary{Address: addr, Coins: []json.AddressCoinSummary{}}
+	for coin, ab := range balances {
+		cs := json.AddressCoinSummary{
+			CoinId:        uint16(coin),
+			CoinName:      coin.Name(),
+			Balance:       ab.Balance,
+			Received:      ab.Received,
+			Sent:          ab.Sent,
+			ReceivedCount: ab.ReceivedCount,
+			SentCount:     ab.SentCount,
+		}
+		first, last, err := ei.BalanceRange(addr, coin)
+		if err != nil {
+			return nil, err
+		}
+		if first != nil {
+			cs.FirstOrder = uint64(first.Order)
+			cs.LastOrder = uint64(last.Order)
+		}
+		result.Coins = append(result.Coins, cs)
+	}
+	sort.Slice(result.Coins, func(i, j int) bool {
+		return result.Coins[i].CoinId < result.Coins[j].CoinId
+	})
+	return result, nil
+}
+
+// GetBalanceAtOrder returns the balance of the address in the coin after the
+// block of the order.
+func (api *PublicExplorerAPI) GetBalanceAtOrder(addr string, coinID types.CoinID, order uint64) (interface{}, error) {
+	ei, err := api.explorerIndex()
+	if err != nil {
+		return nil, err
+	}
+	if order >= math.MaxUint32 {
+		return nil, fmt.Errorf("Bad order %d", order)
+	}
+	addr, err = normalizeAddress(addr)
+	if err != nil {
+		return nil, err
+	}
+	_, tipOrder, err := ei.Tip()
+	if err != nil {
+		return nil, err
+	}
+	if tipOrder == math.MaxUint32 || order > uint64(tipOrder) {
+		return nil, fmt.Errorf("The order %d isn't indexed", order)
+	}
+	bc, err := ei.BalanceAtOrder(addr, coinID, uint(order))
+	if err != nil {
+		return nil, err
+	}
+	result := json.BalanceAtOrder{Address: addr, CoinId: uint16(coinID), Order: order}
+	if bc != nil {
+		lastOrder := uint64(bc.Order)
+		result.Balance = bc.Balance
+		result.LastOrder = &lastOrder
+	}
+	return result, nil
+}
+
+// GetRichList returns the addresses of the coin with the largest balances.
+func (api *PublicExplorerAPI) GetRichList(coinID types.CoinID, count *int, skip *int) (interface{}, error) {
+	ei, err := api.explorerIndex()
+	if err != nil {
+		return nil, err
+	}
+	c := defaultRichListCount
+	if count != nil {
+		c = *count
+	}
+	if c <= 0 || c > maxRichListCount {
+		return nil, fmt.Errorf("The count must be between 1 and %d", maxRichListCount)
+	}
+	s := 0
+	if skip != nil {
+		s = *skip
+	}
+	if s < 0 || s > maxRichListSkip {
+		return nil, fmt.Errorf("The skip must be between 0 and %d", maxRichListSkip)
+	}
+	entries, err := ei.RichList(coinID, s, c)
+	if err != nil {
+		return nil, err
+	}
+	result := []json.RichListEntry{}
+	for i, entry := range entries {
+		result = append(result, json.RichListEntry{
+			Rank:    s + i + 1,
+			Address: entry.Address,
+			Balance: entry.Balance,
+		})
+	}
+	return result, nil
+}
+
+// GetExplorerIndexInfo returns the state of the explorer index and the totals
+// of all coins.
+func (api *PublicExplorerAPI) GetExplorerIndexInfo() (interface{}, error) {
+	ei, err := api.explorerIndex()
+	if err != nil {
+		return nil, err
+	}
+	tipHash, tipOrder, err := ei.Tip()
+	if err != nil {
+		return nil, err
+	}
+	info := json.ExplorerIndexInfo{TipHash: tipHash.String(), Coins: []json.ExplorerCoinTotal{}}
+	if tipOrder != math.MaxUint32 {
+		info.Indexed = true
+		info.TipOrder = uint64(tipOrder)
+	}
+	totals, err := ei.CoinTotals()
+	if err != nil {
+		return nil, err
+	}
+	for coin, ct := range totals {
+		info.Coins = append(info.Coins, json.ExplorerCoinTotal{
+			CoinId:    uint16(coin),
+			CoinName:  coin.Name(),
+			Balance:   ct.Balance,
+			Received:  ct.Received,
+			Sent:      ct.Sent,
+			Addresses: ct.Addresses,
+		})
+	}
+	sort.Slice(info.Coins, func(i, j int) bool {
+		return info.Coins[i].CoinId < info.Coins[j].CoinId
+	})
+	return info, nil
+}
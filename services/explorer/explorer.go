@@ -0,0 +1,45 @@
+/*
+ * Copyright (c) 2017-2020 The qitmeer developers
+ */
+
+// Package explorer provides the balance history and the coin totals of the
+// explorer index of services/index over the RPC, so the block explorers
+// don't need to replay the chain.
+package explorer
+
+import (
+	"github.com/Qitmeer/qng/consensus/model"
+	"github.com/Qitmeer/qng/node/service"
+	"github.com/Qitmeer/qng/services/index"
+)
+
+// Service is the explorer service.
+type Service struct {
+	service.Service
+
+	consensus model.Consensus
+}
+
+func (s *Service) Stop() error {
+	if err := s.Service.Stop(); err != nil {
+		return err
+	}
+	if ei := s.ExplorerIndex(); ei != nil {
+		return ei.Close()
+	}
+	return nil
+}
+
+// ExplorerIndex returns the explorer index, or nil if it is disabled.
+func (s *Service) ExplorerIndex() *index.ExplorerIndex {
+	im, ok := s.consensus.IndexManager().(*index.Manager)
+	if !ok {
+		return nil
+	}
+	return im.ExplorerIndex()
+}
+
+// New returns the explorer service.
+func New(consensus model.Consensus) *Service {
+	return &Service{consensus: consensus}
+}
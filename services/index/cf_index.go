@@ -0,0 +1,202 @@
+// Copyright (c) 2017-2018 The qitmeer developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package index
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/consensus/model"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/services/cf/gcs"
+)
+
+const (
+	// CFIndexName is the human-readable name for the index.
+	CFIndexName = "committed filter index"
+)
+
+// CFIndex implements a committed filter (cf) by hash index.  It keeps the
+// basic compact filter (BIP158) of each block connected to the main chain,
+// along with the chain of filter headers following the order of the DAG.
+type CFIndex struct {
+	consensus model.Consensus
+}
+
+// Ensure the CFIndex type implements the Indexer interface.
+var _ Indexer = (*CFIndex)(nil)
+
+// Ensure the CFIndex type implements the NeedsInputser interface.
+var _ NeedsInputser = (*CFIndex)(nil)
+
+// NeedsInputs signals that the index requires the referenced inputs in order
+// to properly create the index.
+//
+// This implements the NeedsInputser interface.
+func (idx *CFIndex) NeedsInputs() bool {
+	return true
+}
+
+// Init catches up the index to the main chain, since the filter headers can
+// only be built in the order of the DAG.
+//
+// This is part of the Indexer interface.
+func (idx *CFIndex) Init() error {
+	tipHash, tiporder, err := idx.DB().GetCFIdxTip()
+	if err != nil {
+		return err
+	}
+	log.Info("Init", "index", idx.Name(), "tipHash", tipHash.String(), "tipOrder", tiporder)
+	return catchUpIndex(idx, idx.consensus, tiporder)
+}
+
+// Name returns the human-readable name of the index.
+//
+// This is part of the Indexer interface.
+func (idx *CFIndex) Name() string {
+	return CFIndexName
+}
+
+// ConnectBlock is invoked by the index manager when a new block has been
+// connected to the main chain.  This indexer builds the filter of the block
+// from its outputs and the outputs it spends, and chains its header to the
+// header of the previous block in the order.
+//
+// This is part of the Indexer interface.
+func (idx *CFIndex) ConnectBlock(sblock *types.SerializedBlock, block model.Block, stxos [][]byte) error {
+	tipHash, order, err := idx.DB().GetCFIdxTip()
+	if err != nil {
+		log.Error(err.Error())
+		return nil
+	}
+	if order != math.MaxUint32 && order+1 != block.GetOrder() ||
+		order == math.MaxUint32 && block.GetOrder() != 0 {
+		log.Warn(fmt.Sprintf("dbIndexConnectBlock must be "+
+			"called with a block that extends the current index "+
+			"tip (%s, tip %d, block %d)", idx.Name(),
+			order, block.GetOrder()))
+		return nil
+	}
+
+	// The transactions of the invalid blocks are not applied, so their
+	// filters are empty.
+	var filter *gcs.Filter
+	if block.GetState().GetStatus().KnownInvalid() {
+		filter, err = gcs.BuildGCSFilter(gcs.DefaultP, gcs.DefaultM,
+			gcs.DeriveKey(sblock.Hash()), nil)
+	} else {
+		filter, err = gcs.BuildBasicFilter(sblock, stxos)
+	}
+	if err != nil {
+		log.Error(err.Error())
+		return nil
+	}
+
+	prevHeader := &hash.ZeroHash
+	if order != math.MaxUint32 {
+		prevHeader, err = idx.DB().GetCFHeader(tipHash)
+		if err != nil {
+			log.Error(err.Error())
+			return nil
+		}
+	}
+	filterBytes := filter.NBytes()
+	header := gcs.MakeHeaderForFilter(filterBytes, prevHeader)
+	err = idx.DB().PutCFilter(sblock.Hash(), filterBytes, &header)
+	if err != nil {
+		log.Error(err.Error())
+		return nil
+	}
+	// Update the current index tip.
+	err = idx.DB().PutCFIdxTip(sblock.Hash(), block.GetOrder())
+	if err != nil {
+		log.Error(err.Error())
+	}
+	return nil
+}
+
+// DisconnectBlock is invoked by the index manager when a block has been
+// disconnected from the main chain.  This indexer removes the filter and the
+// filter header of the block.
+//
+// This is part of the Indexer interface.
+func (idx *CFIndex) DisconnectBlock(sblock *types.SerializedBlock, block model.Block, stxos [][]byte) error {
+	// Assert that the block being disconnected is the current tip of the
+	// index.
+	curTipHash, order, err := idx.DB().GetCFIdxTip()
+	if err != nil {
+		log.Error(err.Error())
+		return nil
+	}
+	if !curTipHash.IsEqual(sblock.Hash()) {
+		log.Warn(fmt.Sprintf("dbIndexDisconnectBlock must "+
+			"be called with the block at the current index tip "+
+			"(%s, tip %s, block %s)", idx.Name(),
+			curTipHash, sblock.Hash()))
+	}
+	if order == math.MaxUint32 {
+		log.Warn("Can't disconnect root index tip")
+		return nil
+	}
+	if err := idx.DB().DeleteCFilter(sblock.Hash()); err != nil {
+		log.Warn(err.Error())
+		return nil
+	}
+
+	// Update the current index tip.
+	var prevHash *hash.Hash
+	var preOrder uint
+	if order == 0 {
+		prevHash = &hash.ZeroHash
+		preOrder = math.MaxUint32
+	} else {
+		preOrder = order - 1
+
+		pblock := idx.consensus.BlockChain().GetBlockByOrder(uint64(preOrder))
+		if pblock == nil {
+			log.Warn(fmt.Sprintf("No block:%d", preOrder))
+			return nil
+		}
+		prevHash = pblock.GetHash()
+	}
+
+	err = idx.DB().PutCFIdxTip(prevHash, preOrder)
+	if err != nil {
+		log.Error(err.Error())
+	}
+	return nil
+}
+
+// Tip returns the hash and the order of the last block of the index.  The order
+// is math.MaxUint32 when the index is empty.
+func (idx *CFIndex) Tip() (*hash.Hash, uint, error) {
+	return idx.DB().GetCFIdxTip()
+}
+
+// FilterByBlockHash returns the serialized basic filter of the block.
+func (idx *CFIndex) FilterByBlockHash(bh *hash.Hash) ([]byte, error) {
+	return idx.DB().GetCFilter(bh)
+}
+
+// FilterHeaderByBlockHash returns the filter header of the block.
+func (idx *CFIndex) FilterHeaderByBlockHash(bh *hash.Hash) (*hash.Hash, error) {
+	return idx.DB().GetCFHeader(bh)
+}
+
+func (idx *CFIndex) DB() model.DataBase {
+	return idx.consensus.DatabaseContext()
+}
+
+// NewCFIndex returns a new instance of an indexer that is used to create a
+// mapping of the hashes of all blocks in the blockchain to their respective
+// committed filters.
+//
+// It implements the Indexer interface which plugs into the IndexManager that in
+// turn is used by the blockchain package.  This allows the index to be
+// seamlessly maintained along with the chain.
+func NewCFIndex(consensus model.Consensus) *CFIndex {
+	return &CFIndex{consensus: consensus}
+}
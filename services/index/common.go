@@ -10,8 +10,12 @@ package index
 import (
 	"encoding/binary"
 	"errors"
+	"fmt"
+	"github.com/Qitmeer/qng/common/system"
 	"github.com/Qitmeer/qng/consensus/model"
 	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/services/progresslog"
+	"math"
 )
 
 var (
@@ -49,3 +53,111 @@ type Indexer interface {
 	// block has been disconnected from the main chain.
 	DisconnectBlock(sblock *types.SerializedBlock, block model.Block, stxos [][]byte) error
 }
+
+// catchUpIndex rolls the index back to the main chain if its tip is beyond the
+// main order, then catches it up to the main order by connecting each of the
+// blocks it misses.  The tip order of an index without any entries is
+// math.MaxUint32.
+func catchUpIndex(idx Indexer, consensus model.Consensus, tiporder uint) error {
+	var err error
+	needsInputs := false
+	if ni, ok := idx.(NeedsInputser); ok {
+		needsInputs = ni.NeedsInputs()
+	}
+
+	chain := consensus.BlockChain()
+	bestOrder := chain.GetMainOrder()
+	backorder := tiporder
+	// Rollback indexes to the main chain if their tip is an orphaned fork.
+	// This is fairly unlikely, but it can happen if the chain is
+	// reorganized while the index is disabled.  This has to be done in
+	// reverse order because later indexes can depend on earlier ones.
+	var spentTxos [][]byte
+
+	// Nothing to do if the index does not have any entries yet.
+	if backorder != math.MaxUint32 {
+		var block *types.SerializedBlock
+		for backorder > bestOrder {
+			// Load the block for the height since it is required to index
+			// it.
+			block, err = chain.BlockByOrder(uint64(backorder))
+			if err != nil {
+				return err
+			}
+			spentTxos = nil
+			if needsInputs {
+				spentTxos, err = chain.FetchSpendJournalPKS(block)
+				if err != nil {
+					return err
+				}
+			}
+			err = idx.DisconnectBlock(block, nil, spentTxos)
+			if err != nil {
+				return err
+			}
+			log.Trace(fmt.Sprintf("%s rollback order= %d", idx.Name(), backorder))
+			backorder--
+			if system.InterruptRequested(consensus.Interrupt()) {
+				return errInterruptRequested
+			}
+		}
+	}
+
+	lowestOrder := int64(bestOrder)
+	if tiporder == math.MaxUint32 {
+		lowestOrder = -1
+	} else if int64(tiporder) < lowestOrder {
+		lowestOrder = int64(tiporder)
+	}
+
+	// Nothing to index if all of the indexes are caught up.
+	if lowestOrder == int64(bestOrder) {
+		return nil
+	}
+
+	// Create a progress logger for the indexing process below.
+	progressLogger := progresslog.NewBlockProgressLogger("Indexed", log)
+
+	// At this point, one or more indexes are behind the current best chain
+	// tip and need to be caught up, so log the details and loop through
+	// each block that needs to be indexed.
+	log.Info(fmt.Sprintf("Catching up indexes from order %d to %d", lowestOrder,
+		bestOrder))
+
+	for order := lowestOrder + 1; order <= int64(bestOrder); order++ {
+		if system.InterruptRequested(consensus.Interrupt()) {
+			return errInterruptRequested
+		}
+
+		var block *types.SerializedBlock
+		var blk model.Block
+		// Load the block for the height since it is required to index
+		// it.
+		block, blk, err = chain.FetchBlockByOrder(uint64(order))
+		if err != nil {
+			return err
+		}
+
+		if system.InterruptRequested(consensus.Interrupt()) {
+			return errInterruptRequested
+		}
+		chain.SetDAGDuplicateTxs(block, blk)
+		// Connect the block for all indexes that need it.
+		spentTxos = nil
+
+		if spentTxos == nil && needsInputs {
+			spentTxos, err = chain.FetchSpendJournalPKS(block)
+			if err != nil {
+				return err
+			}
+		}
+		err = idx.ConnectBlock(block, blk, spentTxos)
+		if err != nil {
+			return err
+		}
+		progressLogger.LogBlockOrder(blk.GetOrder(), block)
+	}
+
+	log.Info(fmt.Sprintf("Indexes caught up to order %d", bestOrder))
+	return nil
+}
@@ -6,6 +6,8 @@ type Config struct {
 	AddrIndex      bool
 	InvalidTxIndex bool
 	TxhashIndex    bool
+	CFIndex        bool
+	ExplorerIndex  bool
 }
 
 func DefaultConfig() *Config {
@@ -13,6 +15,8 @@ func DefaultConfig() *Config {
 		AddrIndex:      false,
 		InvalidTxIndex: false,
 		TxhashIndex:    false,
+		CFIndex:        false,
+		ExplorerIndex:  false,
 	}
 }
 
@@ -21,5 +25,7 @@ func ToConfig(cfg *config.Config) *Config {
 		AddrIndex:      cfg.AddrIndex,
 		InvalidTxIndex: cfg.InvalidTxIndex,
 		TxhashIndex:    cfg.TxHashIndex,
+		CFIndex:        cfg.CFIndex,
+		ExplorerIndex:  cfg.ExplorerIndex,
 	}
 }
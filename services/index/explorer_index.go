@@ -0,0 +1,851 @@
+// Copyright (c) 2017-2018 The qitmeer developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package index
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/consensus/model"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/database/legacydb"
+	"github.com/Qitmeer/qng/engine/txscript"
+	"github.com/Qitmeer/qng/params"
+)
+
+const (
+	// ExplorerIndexName is the human-readable name for the index.
+	ExplorerIndexName = "explorer index"
+
+	// explorerDBName is the directory of the index database in the data
+	// directory.
+	explorerDBName = "explorer"
+)
+
+var (
+	// explorerTipKey is the key of the hash and order of the index tip.
+	explorerTipKey = []byte("exptip")
+
+	// explorerOutputsBucket: outpoint -> coin id + amount + address of the
+	// unspent output.
+	explorerOutputsBucket = []byte("expoutputs")
+
+	// explorerUndoBucket: order -> the previous index tip and the outputs
+	// spent by the block, they are restored when the block is
+	// disconnected.
+	explorerUndoBucket = []byte("expundo")
+
+	// explorerAddrBucket: address + coin id -> address balance.
+	explorerAddrBucket = []byte("expaddr")
+
+	// explorerHistoryBucket: address + coin id + order -> balance change.
+	explorerHistoryBucket = []byte("exphistory")
+
+	// explorerRichBucket: coin id + ^balance + address -> nil, the addresses
+	// of a coin are iterated from the largest balance.
+	explorerRichBucket = []byte("exprich")
+
+	// explorerCoinBucket: coin id -> coin total.
+	explorerCoinBucket = []byte("expcoin")
+)
+
+// explorerUndoVersion is the version of the serialized undo of a block.
+const explorerUndoVersion = 1
+
+// AddressBalance is the balance of an address in a coin.  The counts are the
+// numbers of the transactions which pay to or spend from the address.
+type AddressBalance struct {
+	Balance       int64
+	Received      int64
+	Sent          int64
+	ReceivedCount uint32
+	SentCount     uint32
+}
+
+// BalanceChange is the change of the balance of an address in a coin by the
+// block of the order.  The balance is the one after the block.
+type BalanceChange struct {
+	Order    uint
+	Balance  int64
+	Received int64
+	Sent     int64
+}
+
+// CoinTotal is the total of all addresses in a coin, the addresses are the
+// ones with a positive balance.
+type CoinTotal struct {
+	Balance   int64
+	Received  int64
+	Sent      int64
+	Addresses uint64
+}
+
+// RichEntry is an address of the rich list.
+type RichEntry struct {
+	Address string
+	Balance int64
+}
+
+// ExplorerIndex keeps the balance history of the addresses by the order of
+// the blocks, along with the totals of each coin and the rich list.  The
+// balances are those of the standard outputs with a single address, and
+// the index lives in its own database since it is only wanted by the block
+// explorers.
+//
+// Every block records the outputs it spends, so it can be disconnected when
+// the blocks of the DAG are reordered.
+type ExplorerIndex struct {
+	consensus model.Consensus
+
+	lock sync.RWMutex
+	db   legacydb.DB
+}
+
+// Ensure the ExplorerIndex type implements the Indexer interface.
+var _ Indexer = (*ExplorerIndex)(nil)
+
+// Init opens the database of the index and catches it up to the main chain.
+//
+// This is part of the Indexer interface.
+func (idx *ExplorerIndex) Init() error {
+	db, err := openExplorerDB(idx.consensus.Config().ResolveDataPath(explorerDBName))
+	if err != nil {
+		return err
+	}
+	idx.lock.Lock()
+	idx.db = db
+	idx.lock.Unlock()
+
+	tipHash, tiporder, err := idx.Tip()
+	if err != nil {
+		return err
+	}
+	log.Info("Init", "index", idx.Name(), "tipHash", tipHash.String(), "tipOrder", tiporder)
+	return catchUpIndex(idx, idx.consensus, tiporder)
+}
+
+// Name returns the human-readable name of the index.
+//
+// This is part of the Indexer interface.
+func (idx *ExplorerIndex) Name() string {
+	return ExplorerIndexName
+}
+
+// ConnectBlock is invoked by the index manager when a new block has been
+// connected to the main chain.  This indexer credits the outputs of the
+// block to their addresses and debits the outputs it spends.
+//
+// This is part of the Indexer interface.
+func (idx *ExplorerIndex) ConnectBlock(sblock *types.SerializedBlock, block model.Block, stxos [][]byte) error {
+	// The transactions of the invalid blocks are not applied.
+	return idx.connectBlock(sblock, block.GetOrder(), !block.GetState().GetStatus().KnownInvalid())
+}
+
+func (idx *ExplorerIndex) connectBlock(sblock *types.SerializedBlock, order uint, apply bool) error {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+	if idx.db == nil {
+		// The index is closed, it is caught up on the next start.
+		return nil
+	}
+	return idx.db.Update(func(dbTx legacydb.Tx) error {
+		return dbExplorerConnectBlock(dbTx.Metadata(), sblock, order, apply)
+	})
+}
+
+// DisconnectBlock is invoked by the index manager when a block has been
+// disconnected from the main chain.  This indexer restores the outputs the
+// block spends and removes the balance changes of the block.
+//
+// This is part of the Indexer interface.
+func (idx *ExplorerIndex) DisconnectBlock(sblock *types.SerializedBlock, block model.Block, stxos [][]byte) error {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+	if idx.db == nil {
+		return nil
+	}
+	return idx.db.Update(func(dbTx legacydb.Tx) error {
+		meta := dbTx.Metadata()
+		// The block at the order of the index tip may be another one
+		// when the index is rolled back on start, so the indexed block
+		// is disconnected.
+		tipHash, _ := dbFetchExplorerTip(meta)
+		if !tipHash.IsEqual(sblock.Hash()) && idx.consensus != nil {
+			indexed, err := idx.consensus.BlockChain().FetchBlockByHash(tipHash)
+			if err != nil {
+				return err
+			}
+			sblock = indexed
+		}
+		return dbExplorerDisconnectBlock(meta, sblock)
+	})
+}
+
+// Close closes the database of the index.
+func (idx *ExplorerIndex) Close() error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+	if idx.db == nil {
+		return nil
+	}
+	err := idx.db.Close()
+	idx.db = nil
+	return err
+}
+
+func (idx *ExplorerIndex) view(fn func(meta legacydb.Bucket) error) error {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+	if idx.db == nil {
+		return fmt.Errorf("The %s is closed", idx.Name())
+	}
+	return idx.db.View(func(dbTx legacydb.Tx) error {
+		return fn(dbTx.Metadata())
+	})
+}
+
+// Tip returns the hash and the order of the last block of the index.  The order
+// is math.MaxUint32 when the index is empty.
+func (idx *ExplorerIndex) Tip() (*hash.Hash, uint, error) {
+	var tipHash *hash.Hash
+	var tipOrder uint
+	err := idx.view(func(meta legacydb.Bucket) error {
+		tipHash, tipOrder = dbFetchExplorerTip(meta)
+		return nil
+	})
+	return tipHash, tipOrder, err
+}
+
+// AddressBalances returns the balances of the address in all coins it has
+// ever received.
+func (idx *ExplorerIndex) AddressBalances(addr string) (map[types.CoinID]*AddressBalance, error) {
+	result := map[types.CoinID]*AddressBalance{}
+	err := idx.view(func(meta legacydb.Bucket) error {
+		prefix := explorerAddrKey(addr)
+		cursor := meta.Bucket(explorerAddrBucket).Cursor()
+		for ok := cursor.Seek(prefix); ok; ok = cursor.Next() {
+			key := cursor.Key()
+			if len(key) != len(prefix)+2 || string(key[:len(prefix)]) != string(prefix) {
+				break
+			}
+			coin := types.CoinID(binary.BigEndian.Uint16(key[len(prefix):]))
+			result[coin] = decodeAddressBalance(cursor.Value())
+		}
+		return nil
+	})
+	return result, err
+}
+
+// BalanceRange returns the first and the last balance changes of the address
+// in the coin, or nil if there is none.
+func (idx *ExplorerIndex) BalanceRange(addr string, coin types.CoinID) (*BalanceChange, *BalanceChange, error) {
+	var first, last *BalanceChange
+	err := idx.view(func(meta legacydb.Bucket) error {
+		prefix := explorerAddrCoinKey(addr, coin)
+		cursor := meta.Bucket(explorerHistoryBucket).Cursor()
+		if cursor.Seek(prefix) && hasPrefix(cursor.Key(), prefix) {
+			first = decodeBalanceChange(cursor.Key(), cursor.Value())
+		}
+		if first == nil {
+			return nil
+		}
+		last = dbFetchBalanceAtOrder(meta, prefix, math.MaxUint32)
+		return nil
+	})
+	return first, last, err
+}
+
+// BalanceAtOrder returns the balance change of the address in the coin by
+// the last block at or before the order, or nil if the address has no
+// balance then.
+func (idx *ExplorerIndex) BalanceAtOrder(addr string, coin types.CoinID, order uint) (*BalanceChange, error) {
+	var bc *BalanceChange
+	err := idx.view(func(meta legacydb.Bucket) error {
+		bc = dbFetchBalanceAtOrder(meta, explorerAddrCoinKey(addr, coin), order)
+		return nil
+	})
+	return bc, err
+}
+
+// RichList returns the addresses of the coin with the largest balances.
+func (idx *ExplorerIndex) RichList(coin types.CoinID, skip, count int) ([]*RichEntry, error) {
+	result := []*RichEntry{}
+	err := idx.view(func(meta legacydb.Bucket) error {
+		prefix := make([]byte, 2)
+		binary.BigEndian.PutUint16(prefix, uint16(coin))
+		cursor := meta.Bucket(explorerRichBucket).Cursor()
+		for ok := cursor.Seek(prefix); ok && len(result) < count; ok = cursor.Next() {
+			key := cursor.Key()
+			if !hasPrefix(key, prefix) || len(key) < 10 {
+				break
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+			result = append(result, &RichEntry{
+				Address: string(key[10:]),
+				Balance: int64(math.MaxUint64 - binary.BigEndian.Uint64(key[2:10])),
+			})
+		}
+		return nil
+	})
+	return result, err
+}
+
+// CoinTotals returns the totals of all coins.
+func (idx *ExplorerIndex) CoinTotals() (map[types.CoinID]*CoinTotal, error) {
+	result := map[types.CoinID]*CoinTotal{}
+	err := idx.view(func(meta legacydb.Bucket) error {
+		return meta.Bucket(explorerCoinBucket).ForEach(func(k, v []byte) error {
+			result[types.CoinID(binary.BigEndian.Uint16(k))] = decodeCoinTotal(v)
+			return nil
+		})
+	})
+	return result, err
+}
+
+// NewExplorerIndex returns a new instance of an indexer that is used to keep
+// the balance history of the addresses for the block explorers.
+//
+// It implements the Indexer interface which plugs into the IndexManager that in
+// turn is used by the blockchain package.  This allows the index to be
+// seamlessly maintained along with the chain.
+func NewExplorerIndex(consensus model.Consensus) *ExplorerIndex {
+	return &ExplorerIndex{consensus: consensus}
+}
+
+func openExplorerDB(dbPath string) (legacydb.DB, error) {
+	db, err := legacydb.Open("ffldb", dbPath, params.ActiveNetParams.Net)
+	if err != nil {
+		if dbErr, ok := err.(legacydb.Error); !ok || dbErr.ErrorCode !=
+			legacydb.ErrDbDoesNotExist {
+			return nil, err
+		}
+		err = os.MkdirAll(dbPath, 0700)
+		if err != nil {
+			return nil, err
+		}
+		db, err = legacydb.Create("ffldb", dbPath, params.ActiveNetParams.Net)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = db.Update(func(dbTx legacydb.Tx) error {
+		for _, name := range [][]byte{explorerOutputsBucket, explorerUndoBucket,
+			explorerAddrBucket, explorerHistoryBucket, explorerRichBucket, explorerCoinBucket} {
+			_, err := dbTx.Metadata().CreateBucketIfNotExists(name)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// explorerOutputAddress returns the address of the standard output script
+// with a single address, or an empty string.
+func explorerOutputAddress(pkScript []byte) string {
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(pkScript, params.ActiveNetParams.Params)
+	if err != nil || len(addrs) != 1 {
+		return ""
+	}
+	// The pay-to-pubkey outputs belong to the pubkey hash address.
+	return addrs[0].Encode()
+}
+
+// balanceDelta is the change of an address in a coin by a block.  The
+// transaction indexes count the transactions only once.
+type balanceDelta struct {
+	addr          string
+	coin          types.CoinID
+	received      int64
+	sent          int64
+	receivedCount uint32
+	sentCount     uint32
+	receivedTx    int
+	sentTx        int
+}
+
+type balanceDeltas struct {
+	deltas map[string]*balanceDelta
+	keys   []string
+}
+
+func (bds *balanceDeltas) get(addr string, coin types.CoinID) *balanceDelta {
+	key := string(explorerAddrCoinKey(addr, coin))
+	bd, ok := bds.deltas[key]
+	if !ok {
+		bd = &balanceDelta{addr: addr, coin: coin, receivedTx: -1, sentTx: -1}
+		bds.deltas[key] = bd
+		bds.keys = append(bds.keys, key)
+	}
+	return bd
+}
+
+func (bds *balanceDeltas) receive(txIdx int, out *explorerOutput) {
+	bd := bds.get(out.addr, out.coin)
+	bd.received += out.amount
+	if bd.receivedTx != txIdx {
+		bd.receivedTx = txIdx
+		bd.receivedCount++
+	}
+}
+
+func (bds *balanceDeltas) send(txIdx int, out *explorerOutput) {
+	bd := bds.get(out.addr, out.coin)
+	bd.sent += out.amount
+	if bd.sentTx != txIdx {
+		bd.sentTx = txIdx
+		bd.sentCount++
+	}
+}
+
+func newBalanceDeltas() *balanceDeltas {
+	return &balanceDeltas{deltas: map[string]*balanceDelta{}}
+}
+
+// dbExplorerConnectBlock credits the outputs of the block and debits the
+// outputs it spends, then makes the block the index tip.  The previous tip
+// and the spent outputs are kept for the disconnection, and the block isn't
+// applied to the balances unless apply is set.
+func dbExplorerConnectBlock(meta legacydb.Bucket, sblock *types.SerializedBlock, order uint, apply bool) error {
+	tipHash, tipOrder := dbFetchExplorerTip(meta)
+	if tipOrder != math.MaxUint32 && tipOrder+1 != order ||
+		tipOrder == math.MaxUint32 && order != 0 {
+		return fmt.Errorf("dbIndexConnectBlock must be called with a block "+
+			"that extends the current index tip (%s, tip %d, block %d)",
+			ExplorerIndexName, tipOrder, order)
+	}
+	undo := newExplorerUndo(tipHash, apply)
+	if !apply {
+		err := meta.Bucket(explorerUndoBucket).Put(explorerOrderKey(order), undo)
+		if err != nil {
+			return err
+		}
+		return dbPutExplorerTip(meta, sblock.Hash(), order)
+	}
+	outputs := meta.Bucket(explorerOutputsBucket)
+	bds := newBalanceDeltas()
+	for txIdx, tx := range sblock.Transactions() {
+		if tx.IsDuplicate {
+			continue
+		}
+		// The inputs which don't spend the tracked outputs are skipped,
+		// such as the coinbase and the token inputs.
+		for _, txIn := range tx.Tx.TxIn {
+			key := explorerOutpointKey(&txIn.PreviousOut)
+			data := outputs.Get(key)
+			if data == nil {
+				continue
+			}
+			out, err := decodeExplorerOutput(data)
+			if err != nil {
+				return err
+			}
+			undo = appendExplorerUndo(undo, key, data)
+			err = outputs.Delete(key)
+			if err != nil {
+				return err
+			}
+			bds.send(txIdx, out)
+		}
+		for i, txOut := range tx.Tx.TxOut {
+			addr := explorerOutputAddress(txOut.PkScript)
+			if len(addr) == 0 {
+				continue
+			}
+			out := &explorerOutput{addr: addr, coin: txOut.Amount.Id, amount: txOut.Amount.Value}
+			err := outputs.Put(explorerOutpointKey(types.NewOutPoint(tx.Hash(), uint32(i))), out.encode())
+			if err != nil {
+				return err
+			}
+			bds.receive(txIdx, out)
+		}
+	}
+	err := meta.Bucket(explorerUndoBucket).Put(explorerOrderKey(order), undo)
+	if err != nil {
+		return err
+	}
+	err = dbApplyBalanceDeltas(meta, bds, order, true)
+	if err != nil {
+		return err
+	}
+	return dbPutExplorerTip(meta, sblock.Hash(), order)
+}
+
+// dbExplorerDisconnectBlock removes the outputs of the block at the index tip
+// and restores the outputs it spends, in the reverse order of the
+// connection.  The previous tip becomes the index tip.
+func dbExplorerDisconnectBlock(meta legacydb.Bucket, sblock *types.SerializedBlock) error {
+	tipHash, order := dbFetchExplorerTip(meta)
+	if order == math.MaxUint32 {
+		return fmt.Errorf("Can't disconnect root index tip (%s)", ExplorerIndexName)
+	}
+	if !tipHash.IsEqual(sblock.Hash()) {
+		return fmt.Errorf("dbIndexDisconnectBlock must be called with the "+
+			"block at the current index tip (%s, tip %s, block %s)",
+			ExplorerIndexName, tipHash, sblock.Hash())
+	}
+	undoBucket := meta.Bucket(explorerUndoBucket)
+	undo := undoBucket.Get(explorerOrderKey(order))
+	if undo == nil {
+		return fmt.Errorf("No explorer undo at order %d", order)
+	}
+	prevHash, applied, spent, err := decodeExplorerUndo(undo)
+	if err != nil {
+		return err
+	}
+	prevOrder := uint(math.MaxUint32)
+	if order > 0 {
+		prevOrder = order - 1
+	}
+	if !applied {
+		err = undoBucket.Delete(explorerOrderKey(order))
+		if err != nil {
+			return err
+		}
+		return dbPutExplorerTip(meta, prevHash, prevOrder)
+	}
+	outputs := meta.Bucket(explorerOutputsBucket)
+	bds := newBalanceDeltas()
+	txs := sblock.Transactions()
+	for txIdx := len(txs) - 1; txIdx >= 0; txIdx-- {
+		tx := txs[txIdx]
+		if tx.IsDuplicate {
+			continue
+		}
+		for i, txOut := range tx.Tx.TxOut {
+			addr := explorerOutputAddress(txOut.PkScript)
+			if len(addr) == 0 {
+				continue
+			}
+			err := outputs.Delete(explorerOutpointKey(types.NewOutPoint(tx.Hash(), uint32(i))))
+			if err != nil {
+				return err
+			}
+			bds.receive(txIdx, &explorerOutput{addr: addr, coin: txOut.Amount.Id, amount: txOut.Amount.Value})
+		}
+		for _, txIn := range tx.Tx.TxIn {
+			key := explorerOutpointKey(&txIn.PreviousOut)
+			data, ok := spent[string(key)]
+			if !ok {
+				continue
+			}
+			out, err := decodeExplorerOutput(data)
+			if err != nil {
+				return err
+			}
+			err = outputs.Put(key, data)
+			if err != nil {
+				return err
+			}
+			bds.send(txIdx, out)
+		}
+	}
+	err = undoBucket.Delete(explorerOrderKey(order))
+	if err != nil {
+		return err
+	}
+	err = dbApplyBalanceDeltas(meta, bds, order, false)
+	if err != nil {
+		return err
+	}
+	return dbPutExplorerTip(meta, prevHash, prevOrder)
+}
+
+// dbApplyBalanceDeltas adds the deltas of the block to the balances, or
+// subtracts them when the block is disconnected.
+func dbApplyBalanceDeltas(meta legacydb.Bucket, bds *balanceDeltas, order uint, connect bool) error {
+	addrBucket := meta.Bucket(explorerAddrBucket)
+	historyBucket := meta.Bucket(explorerHistoryBucket)
+	richBucket := meta.Bucket(explorerRichBucket)
+	coinBucket := meta.Bucket(explorerCoinBucket)
+	for _, key := range bds.keys {
+		bd := bds.deltas[key]
+		addrKey := []byte(key)
+		ab := decodeAddressBalance(addrBucket.Get(addrKey))
+		coinKey := explorerCoinKey(bd.coin)
+		ct := decodeCoinTotal(coinBucket.Get(coinKey))
+		old := ab.Balance
+
+		historyKey := append(append([]byte{}, addrKey...), explorerOrderKey(order)...)
+		if connect {
+			ab.Balance += bd.received - bd.sent
+			ab.Received += bd.received
+			ab.Sent += bd.sent
+			ab.ReceivedCount += bd.receivedCount
+			ab.SentCount += bd.sentCount
+			ct.Received += bd.received
+			ct.Sent += bd.sent
+			bc := &BalanceChange{Balance: ab.Balance, Received: bd.received, Sent: bd.sent}
+			err := historyBucket.Put(historyKey, bc.encode())
+			if err != nil {
+				return err
+			}
+		} else {
+			ab.Balance -= bd.received - bd.sent
+			ab.Received -= bd.received
+			ab.Sent -= bd.sent
+			ab.ReceivedCount -= bd.receivedCount
+			ab.SentCount -= bd.sentCount
+			ct.Received -= bd.received
+			ct.Sent -= bd.sent
+			err := historyBucket.Delete(historyKey)
+			if err != nil {
+				return err
+			}
+		}
+		ct.Balance += ab.Balance - old
+		if old <= 0 && ab.Balance > 0 {
+			ct.Addresses++
+		} else if old > 0 && ab.Balance <= 0 {
+			ct.Addresses--
+		}
+
+		if old > 0 {
+			err := richBucket.Delete(explorerRichKey(bd.coin, old, bd.addr))
+			if err != nil {
+				return err
+			}
+		}
+		if ab.Balance > 0 {
+			err := richBucket.Put(explorerRichKey(bd.coin, ab.Balance, bd.addr), []byte{})
+			if err != nil {
+				return err
+			}
+		}
+		var err error
+		if *ab == (AddressBalance{}) {
+			err = addrBucket.Delete(addrKey)
+		} else {
+			err = addrBucket.Put(addrKey, ab.encode())
+		}
+		if err != nil {
+			return err
+		}
+		err = coinBucket.Put(coinKey, ct.encode())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dbFetchBalanceAtOrder(meta legacydb.Bucket, prefix []byte, order uint) *BalanceChange {
+	cursor := meta.Bucket(explorerHistoryBucket).Cursor()
+	// The seek key is the smallest one after the entry of the order.
+	seek := append(append([]byte{}, prefix...), explorerOrderKey(order)...)
+	seek = append(seek, 0)
+	var ok bool
+	if cursor.Seek(seek) {
+		ok = cursor.Prev()
+	} else {
+		ok = cursor.Last()
+	}
+	if !ok || !hasPrefix(cursor.Key(), prefix) || len(cursor.Key()) != len(prefix)+4 {
+		return nil
+	}
+	return decodeBalanceChange(cursor.Key(), cursor.Value())
+}
+
+func dbFetchExplorerTip(meta legacydb.Bucket) (*hash.Hash, uint) {
+	serialized := meta.Get(explorerTipKey)
+	if len(serialized) < hash.HashSize+4 {
+		return &hash.ZeroHash, math.MaxUint32
+	}
+	var h hash.Hash
+	copy(h[:], serialized[:hash.HashSize])
+	return &h, uint(binary.BigEndian.Uint32(serialized[hash.HashSize:]))
+}
+
+func dbPutExplorerTip(meta legacydb.Bucket, bh *hash.Hash, order uint) error {
+	serialized := make([]byte, hash.HashSize+4)
+	copy(serialized, bh[:])
+	binary.BigEndian.PutUint32(serialized[hash.HashSize:], uint32(order))
+	return meta.Put(explorerTipKey, serialized)
+}
+
+func hasPrefix(key []byte, prefix []byte) bool {
+	return len(key) >= len(prefix) && string(key[:len(prefix)]) == string(prefix)
+}
+
+// explorerAddrKey = len(address) + address
+func explorerAddrKey(addr string) []byte {
+	return append([]byte{byte(len(addr))}, addr...)
+}
+
+// explorerAddrCoinKey = len(address) + address + coin id
+func explorerAddrCoinKey(addr string, coin types.CoinID) []byte {
+	return append(explorerAddrKey(addr), explorerCoinKey(coin)...)
+}
+
+func explorerCoinKey(coin types.CoinID) []byte {
+	key := make([]byte, 2)
+	binary.BigEndian.PutUint16(key, uint16(coin))
+	return key
+}
+
+func explorerOrderKey(order uint) []byte {
+	key := make([]byte, 4)
+	binary.BigEndian.PutUint32(key, uint32(order))
+	return key
+}
+
+// explorerRichKey = coin id + ^balance + address
+func explorerRichKey(coin types.CoinID, balance int64, addr string) []byte {
+	key := make([]byte, 10, 10+len(addr))
+	binary.BigEndian.PutUint16(key, uint16(coin))
+	binary.BigEndian.PutUint64(key[2:], math.MaxUint64-uint64(balance))
+	return append(key, addr...)
+}
+
+func explorerOutpointKey(op *types.TxOutPoint) []byte {
+	key := make([]byte, hash.HashSize+4)
+	copy(key, op.Hash[:])
+	binary.BigEndian.PutUint32(key[hash.HashSize:], op.OutIndex)
+	return key
+}
+
+// explorerOutput is the tracked output: coin id + amount + address.
+type explorerOutput struct {
+	addr   string
+	coin   types.CoinID
+	amount int64
+}
+
+func (eo *explorerOutput) encode() []byte {
+	serialized := make([]byte, 10, 10+len(eo.addr))
+	byteOrder.PutUint16(serialized, uint16(eo.coin))
+	byteOrder.PutUint64(serialized[2:], uint64(eo.amount))
+	return append(serialized, eo.addr...)
+}
+
+func decodeExplorerOutput(serialized []byte) (*explorerOutput, error) {
+	if len(serialized) <= 10 {
+		return nil, fmt.Errorf("Bad explorer output")
+	}
+	return &explorerOutput{
+		coin:   types.CoinID(byteOrder.Uint16(serialized)),
+		amount: int64(byteOrder.Uint64(serialized[2:])),
+		addr:   string(serialized[10:]),
+	}, nil
+}
+
+// The undo of a block is the version byte, the applied flag, the previous
+// index tip and a list of outpoint + len(output) + output.
+func newExplorerUndo(prevTip *hash.Hash, applied bool) []byte {
+	undo := make([]byte, 2, 2+hash.HashSize)
+	undo[0] = explorerUndoVersion
+	if applied {
+		undo[1] = 1
+	}
+	return append(undo, prevTip[:]...)
+}
+
+func appendExplorerUndo(undo []byte, key []byte, output []byte) []byte {
+	undo = append(undo, key...)
+	undo = append(undo, byte(len(output)))
+	return append(undo, output...)
+}
+
+func decodeExplorerUndo(serialized []byte) (*hash.Hash, bool, map[string][]byte, error) {
+	headerSize := 2 + hash.HashSize
+	if len(serialized) < headerSize {
+		return nil, false, nil, fmt.Errorf("Bad explorer undo")
+	}
+	if serialized[0] != explorerUndoVersion {
+		return nil, false, nil, fmt.Errorf("Unsupported explorer undo version %d", serialized[0])
+	}
+	var prevTip hash.Hash
+	copy(prevTip[:], serialized[2:headerSize])
+	result := map[string][]byte{}
+	for offset := headerSize; offset < len(serialized); {
+		keySize := hash.HashSize + 4
+		if offset+keySize+1 > len(serialized) {
+			return nil, false, nil, fmt.Errorf("Bad explorer undo")
+		}
+		key := serialized[offset : offset+keySize]
+		size := int(serialized[offset+keySize])
+		offset += keySize + 1
+		if offset+size > len(serialized) {
+			return nil, false, nil, fmt.Errorf("Bad explorer undo")
+		}
+		result[string(key)] = append([]byte{}, serialized[offset:offset+size]...)
+		offset += size
+	}
+	return &prevTip, serialized[1] == 1, result, nil
+}
+
+func (ab *AddressBalance) encode() []byte {
+	serialized := make([]byte, 32)
+	byteOrder.PutUint64(serialized, uint64(ab.Balance))
+	byteOrder.PutUint64(serialized[8:], uint64(ab.Received))
+	byteOrder.PutUint64(serialized[16:], uint64(ab.Sent))
+	byteOrder.PutUint32(serialized[24:], ab.ReceivedCount)
+	byteOrder.PutUint32(serialized[28:], ab.SentCount)
+	return serialized
+}
+
+func decodeAddressBalance(serialized []byte) *AddressBalance {
+	if len(serialized) < 32 {
+		return &AddressBalance{}
+	}
+	return &AddressBalance{
+		Balance:       int64(byteOrder.Uint64(serialized)),
+		Received:      int64(byteOrder.Uint64(serialized[8:])),
+		Sent:          int64(byteOrder.Uint64(serialized[16:])),
+		ReceivedCount: byteOrder.Uint32(serialized[24:]),
+		SentCount:     byteOrder.Uint32(serialized[28:]),
+	}
+}
+
+func (bc *BalanceChange) encode() []byte {
+	serialized := make([]byte, 24)
+	byteOrder.PutUint64(serialized, uint64(bc.Balance))
+	byteOrder.PutUint64(serialized[8:], uint64(bc.Received))
+	byteOrder.PutUint64(serialized[16:], uint64(bc.Sent))
+	return serialized
+}
+
+func decodeBalanceChange(key []byte, serialized []byte) *BalanceChange {
+	bc := &BalanceChange{Order: uint(binary.BigEndian.Uint32(key[len(key)-4:]))}
+	if len(serialized) >= 24 {
+		bc.Balance = int64(byteOrder.Uint64(serialized))
+		bc.Received = int64(byteOrder.Uint64(serialized[8:]))
+		bc.Sent = int64(byteOrder.Uint64(serialized[16:]))
+	}
+	return bc
+}
+
+func (ct *CoinTotal) encode() []byte {
+	serialized := make([]byte, 32)
+	byteOrder.PutUint64(serialized, uint64(ct.Balance))
+	byteOrder.PutUint64(serialized[8:], uint64(ct.Received))
+	byteOrder.PutUint64(serialized[16:], uint64(ct.Sent))
+	byteOrder.PutUint64(serialized[24:], ct.Addresses)
+	return serialized
+}
+
+func decodeCoinTotal(serialized []byte) *CoinTotal {
+	if len(serialized) < 32 {
+		return &CoinTotal{}
+	}
+	return &CoinTotal{
+		Balance:   int64(byteOrder.Uint64(serialized)),
+		Received:  int64(byteOrder.Uint64(serialized[8:])),
+		Sent:      int64(byteOrder.Uint64(serialized[16:])),
+		Addresses: byteOrder.Uint64(serialized[24:]),
+	}
+}
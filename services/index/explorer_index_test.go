@@ -0,0 +1,283 @@
+package index
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/core/address"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/core/types/pow"
+	"github.com/Qitmeer/qng/crypto/ecc"
+	"github.com/Qitmeer/qng/database/legacydb"
+	_ "github.com/Qitmeer/qng/database/legacydb/ffldb"
+	"github.com/Qitmeer/qng/engine/txscript"
+	"github.com/Qitmeer/qng/params"
+)
+
+func explorerTestAddress(t *testing.T, b byte) (string, []byte) {
+	pkHash := make([]byte, 20)
+	pkHash[0] = b
+	addr, err := address.NewPubKeyHashAddress(pkHash, params.ActiveNetParams.Params, ecc.ECDSA_Secp256k1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return addr.Encode(), pkScript
+}
+
+func explorerTestTx(prevOuts []*types.TxOutPoint, outs ...*types.TxOutput) *types.Transaction {
+	tx := types.NewTransaction()
+	for _, op := range prevOuts {
+		tx.AddTxIn(types.NewTxInput(op, nil))
+	}
+	for _, out := range outs {
+		tx.AddTxOut(out)
+	}
+	return tx
+}
+
+func explorerTestBlock(txs ...*types.Transaction) *types.SerializedBlock {
+	// The tx root makes the hashes of the blocks differ
+	return types.NewBlock(&types.Block{
+		Header: types.BlockHeader{
+			TxRoot: txs[0].TxHash(),
+			Pow:    pow.GetInstance(pow.MEERXKECCAKV1, 0, []byte{}),
+		},
+		Transactions: txs,
+	})
+}
+
+func explorerTestUpdate(t *testing.T, db legacydb.DB, fn func(meta legacydb.Bucket) error) {
+	err := db.Update(func(dbTx legacydb.Tx) error {
+		return fn(dbTx.Metadata())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExplorerIndex(t *testing.T) {
+	db, err := openExplorerDB(filepath.Join(t.TempDir(), explorerDBName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := &ExplorerIndex{db: db}
+	defer idx.Close()
+
+	addrA, scriptA := explorerTestAddress(t, 1)
+	addrB, scriptB := explorerTestAddress(t, 2)
+
+	// The inputs of the first transaction aren't tracked
+	tx0 := explorerTestTx([]*types.TxOutPoint{types.NewOutPoint(&hash.ZeroHash, math.MaxUint32)},
+		types.NewTxOutput(types.Amount{Value: 100, Id: types.MEERA}, scriptA),
+		types.NewTxOutput(types.Amount{Value: 50, Id: types.MEERA}, scriptB))
+	block0 := explorerTestBlock(tx0)
+	tx1 := explorerTestTx([]*types.TxOutPoint{types.NewOutPoint(explorerTestHash(tx0), 0)},
+		types.NewTxOutput(types.Amount{Value: 30, Id: types.MEERA}, scriptB),
+		types.NewTxOutput(types.Amount{Value: 70, Id: types.MEERA}, scriptA),
+		types.NewTxOutput(types.Amount{Value: 5, Id: types.MEERB}, scriptA))
+	block1 := explorerTestBlock(tx1)
+
+	explorerTestUpdate(t, db, func(meta legacydb.Bucket) error {
+		if err := dbExplorerConnectBlock(meta, block0, 0, true); err != nil {
+			return err
+		}
+		return dbExplorerConnectBlock(meta, block1, 1, true)
+	})
+
+	balances, err := idx.AddressBalances(addrA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := AddressBalance{Balance: 70, Received: 170, Sent: 100, ReceivedCount: 2, SentCount: 1}
+	if len(balances) != 2 || *balances[types.MEERA] != expected {
+		t.Fatalf("unexpected balance %v", balances[types.MEERA])
+	}
+	if *balances[types.MEERB] != (AddressBalance{Balance: 5, Received: 5, ReceivedCount: 1}) {
+		t.Fatalf("unexpected balance %v", balances[types.MEERB])
+	}
+	first, last, err := idx.BalanceRange(addrA, types.MEERA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Order != 0 || last.Order != 1 {
+		t.Fatalf("unexpected range %d %d", first.Order, last.Order)
+	}
+	for order, balance := range []int64{100, 70} {
+		bc, err := idx.BalanceAtOrder(addrA, types.MEERA, uint(order))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bc == nil || bc.Balance != balance || bc.Order != uint(order) {
+			t.Fatalf("unexpected balance at %d %v", order, bc)
+		}
+	}
+	bc, err := idx.BalanceAtOrder(addrB, types.MEERB, 1)
+	if err != nil || bc != nil {
+		t.Fatalf("unexpected balance %v %v", bc, err)
+	}
+
+	rich, err := idx.RichList(types.MEERA, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rich) != 2 || *rich[0] != (RichEntry{addrB, 80}) || *rich[1] != (RichEntry{addrA, 70}) {
+		t.Fatalf("unexpected rich list %v", rich)
+	}
+	rich, _ = idx.RichList(types.MEERA, 1, 10)
+	if len(rich) != 1 || rich[0].Address != addrA {
+		t.Fatalf("unexpected rich list %v", rich)
+	}
+
+	totals, err := idx.CoinTotals()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *totals[types.MEERA] != (CoinTotal{Balance: 150, Received: 250, Sent: 100, Addresses: 2}) {
+		t.Fatalf("unexpected total %v", totals[types.MEERA])
+	}
+
+	// The reordered block is disconnected
+	explorerTestUpdate(t, db, func(meta legacydb.Bucket) error {
+		return dbExplorerDisconnectBlock(meta, block1)
+	})
+	balances, _ = idx.AddressBalances(addrA)
+	if len(balances) != 1 || *balances[types.MEERA] != (AddressBalance{Balance: 100, Received: 100, ReceivedCount: 1}) {
+		t.Fatalf("unexpected balances %v", balances)
+	}
+	bc, _ = idx.BalanceAtOrder(addrA, types.MEERA, 1)
+	if bc == nil || bc.Order != 0 || bc.Balance != 100 {
+		t.Fatalf("unexpected balance %v", bc)
+	}
+	totals, _ = idx.CoinTotals()
+	if *totals[types.MEERA] != (CoinTotal{Balance: 150, Received: 150, Addresses: 2}) ||
+		*totals[types.MEERB] != (CoinTotal{}) {
+		t.Fatalf("unexpected totals %v %v", totals[types.MEERA], totals[types.MEERB])
+	}
+
+	// The spent output is restored, so the block can be connected again
+	explorerTestUpdate(t, db, func(meta legacydb.Bucket) error {
+		return dbExplorerConnectBlock(meta, block1, 1, true)
+	})
+	balances, _ = idx.AddressBalances(addrA)
+	if *balances[types.MEERA] != expected {
+		t.Fatalf("unexpected balance %v", balances[types.MEERA])
+	}
+
+	explorerTestUpdate(t, db, func(meta legacydb.Bucket) error {
+		if err := dbExplorerDisconnectBlock(meta, block1); err != nil {
+			return err
+		}
+		return dbExplorerDisconnectBlock(meta, block0)
+	})
+	for _, addr := range []string{addrA, addrB} {
+		balances, _ = idx.AddressBalances(addr)
+		if len(balances) != 0 {
+			t.Fatalf("unexpected balances %v", balances)
+		}
+	}
+	rich, _ = idx.RichList(types.MEERA, 0, 10)
+	if len(rich) != 0 {
+		t.Fatalf("unexpected rich list %v", rich)
+	}
+}
+
+func TestExplorerIndexTip(t *testing.T) {
+	db, err := openExplorerDB(filepath.Join(t.TempDir(), explorerDBName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := &ExplorerIndex{db: db}
+	_, order, err := idx.Tip()
+	if err != nil || order != math.MaxUint32 {
+		t.Fatalf("unexpected tip %d %v", order, err)
+	}
+	explorerTestUpdate(t, db, func(meta legacydb.Bucket) error {
+		return dbPutExplorerTip(meta, &hash.ZeroHash, 7)
+	})
+	_, order, _ = idx.Tip()
+	if order != 7 {
+		t.Fatalf("unexpected tip %d", order)
+	}
+	if err := idx.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := idx.Tip(); err == nil {
+		t.Fatal("the closed index is read")
+	}
+}
+
+func TestExplorerIndexDisconnect(t *testing.T) {
+	db, err := openExplorerDB(filepath.Join(t.TempDir(), explorerDBName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := &ExplorerIndex{db: db}
+	defer idx.Close()
+
+	addrA, scriptA := explorerTestAddress(t, 1)
+	tx0 := explorerTestTx([]*types.TxOutPoint{types.NewOutPoint(&hash.ZeroHash, math.MaxUint32)},
+		types.NewTxOutput(types.Amount{Value: 100, Id: types.MEERA}, scriptA))
+	block0 := explorerTestBlock(tx0)
+	// The invalid block isn't applied
+	tx1 := explorerTestTx([]*types.TxOutPoint{types.NewOutPoint(explorerTestHash(tx0), 0)},
+		types.NewTxOutput(types.Amount{Value: 60, Id: types.MEERA}, scriptA))
+	block1 := explorerTestBlock(tx1)
+	tx2 := explorerTestTx([]*types.TxOutPoint{types.NewOutPoint(explorerTestHash(tx0), 0)},
+		types.NewTxOutput(types.Amount{Value: 40, Id: types.MEERA}, scriptA))
+	block2 := explorerTestBlock(tx2)
+
+	for i, test := range []struct {
+		block *types.SerializedBlock
+		apply bool
+	}{{block0, true}, {block1, false}, {block2, true}} {
+		if err := idx.connectBlock(test.block, uint(i), test.apply); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := idx.connectBlock(block2, 5, true); err == nil {
+		t.Fatal("the block which doesn't extend the tip is connected")
+	}
+	balances, _ := idx.AddressBalances(addrA)
+	if balances[types.MEERA].Balance != 40 {
+		t.Fatalf("unexpected balance %v", balances[types.MEERA])
+	}
+
+	if err := idx.DisconnectBlock(block1, nil, nil); err == nil {
+		t.Fatal("the block which isn't the tip is disconnected")
+	}
+	for _, block := range []*types.SerializedBlock{block2, block1} {
+		if err := idx.DisconnectBlock(block, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tipHash, order, _ := idx.Tip()
+	if order != 0 || !tipHash.IsEqual(block0.Hash()) {
+		t.Fatalf("unexpected tip %s %d", tipHash, order)
+	}
+	balances, _ = idx.AddressBalances(addrA)
+	if *balances[types.MEERA] != (AddressBalance{Balance: 100, Received: 100, ReceivedCount: 1}) {
+		t.Fatalf("unexpected balance %v", balances[types.MEERA])
+	}
+
+	if err := idx.DisconnectBlock(block0, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	tipHash, order, _ = idx.Tip()
+	if order != math.MaxUint32 || !tipHash.IsEqual(&hash.ZeroHash) {
+		t.Fatalf("unexpected tip %s %d", tipHash, order)
+	}
+	if err := idx.DisconnectBlock(block0, nil, nil); err == nil {
+		t.Fatal("the empty index is disconnected")
+	}
+}
+
+func explorerTestHash(tx *types.Transaction) *hash.Hash {
+	h := tx.TxHash()
+	return &h
+}
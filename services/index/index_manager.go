@@ -43,6 +43,12 @@ func NewManager(cfg *Config, consensus model.Consensus) *Manager {
 	if cfg.TxhashIndex {
 		indexers = append(indexers, NewTxHashIndex(consensus))
 	}
+	if cfg.CFIndex {
+		indexers = append(indexers, NewCFIndex(consensus))
+	}
+	if cfg.ExplorerIndex {
+		indexers = append(indexers, NewExplorerIndex(consensus))
+	}
 	for _, indexer := range indexers {
 		log.Info(fmt.Sprintf("%s is enabled", indexer.Name()))
 	}
@@ -172,6 +178,22 @@ func (m *Manager) TxHashIndex() *TxHashIndex {
 	return nil
 }
 
+func (m *Manager) CFIndex() *CFIndex {
+	indexer := m.GetIndex(CFIndexName)
+	if indexer != nil {
+		return indexer.(*CFIndex)
+	}
+	return nil
+}
+
+func (m *Manager) ExplorerIndex() *ExplorerIndex {
+	indexer := m.GetIndex(ExplorerIndexName)
+	if indexer != nil {
+		return indexer.(*ExplorerIndex)
+	}
+	return nil
+}
+
 func (m *Manager) GetIndex(name string) Indexer {
 	for _, index := range m.enabledIndexes {
 		if index.Name() == name {
@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2017-2020 The qitmeer developers
+ */
+
+package light
+
+import (
+	"fmt"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/core/json"
+	"github.com/Qitmeer/qng/core/types/pow"
+	"github.com/Qitmeer/qng/rpc/api"
+	"github.com/Qitmeer/qng/rpc/client/cmds"
+)
+
+func (s *Sync) APIs() []api.API {
+	return []api.API{
+		{
+			NameSpace: cmds.DefaultServiceNameSpace,
+			Service:   NewPublicLightAPI(s),
+			Public:    true,
+		},
+	}
+}
+
+// PublicLightAPI provides the RPC of the light node.
+type PublicLightAPI struct {
+	s *Sync
+}
+
+func NewPublicLightAPI(s *Sync) *PublicLightAPI {
+	return &PublicLightAPI{s}
+}
+
+// GetLightInfo returns the sync state of the light node.
+func (api *PublicLightAPI) GetLightInfo() (interface{}, error) {
+	chain := api.s.chain
+	info := json.LightInfo{
+		TipOrder:        chain.TipOrder(),
+		CheckpointOrder: chain.CheckpointOrder(),
+		Peers:           len(api.s.syncPeers()),
+		MatchedBlocks:   len(chain.Matched()),
+	}
+	if th := chain.TipHash(); th != nil {
+		info.TipHash = th.String()
+	}
+	if pe := api.s.SyncPeer(); pe != nil {
+		info.SyncPeer = pe.GetID().String()
+		if gs := pe.GraphState(); gs != nil {
+			info.PeerOrder = uint64(gs.GetMainOrder())
+		}
+	}
+	for _, addr := range api.s.addrs {
+		info.Addrs = append(info.Addrs, addr.String())
+	}
+	return info, nil
+}
+
+// GetLightHeader returns the header of the block at the order.
+func (api *PublicLightAPI) GetLightHeader(order uint64) (interface{}, error) {
+	h := api.s.chain.HashByOrder(order)
+	if h == nil {
+		return nil, fmt.Errorf("No header at order %d", order)
+	}
+	return api.getHeader(h)
+}
+
+// GetLightHeaderByHash returns the header of the block.
+func (api *PublicLightAPI) GetLightHeaderByHash(h hash.Hash) (interface{}, error) {
+	return api.getHeader(&h)
+}
+
+func (api *PublicLightAPI) getHeader(h *hash.Hash) (interface{}, error) {
+	header, order, err := api.s.chain.Header(h)
+	if err != nil {
+		return nil, err
+	}
+	result := json.LightHeader{
+		Hash:       h.String(),
+		Order:      order,
+		Version:    header.Version,
+		ParentRoot: header.ParentRoot.String(),
+		TxRoot:     header.TxRoot.String(),
+		StateRoot:  header.StateRoot.String(),
+		Difficulty: header.Difficulty,
+		Timestamp:  header.Timestamp.Unix(),
+		Nonce:      header.Pow.GetNonce(),
+	}
+	if name, ok := pow.PowMapString[header.Pow.GetPowType()].(string); ok {
+		result.PowType = name
+	}
+	return result, nil
+}
+
+// GetLightTxs returns the transactions matched by the filter of the watched
+// addresses.
+func (api *PublicLightAPI) GetLightTxs() (interface{}, error) {
+	result := []json.LightMatchedBlock{}
+	for _, mb := range api.s.chain.Matched() {
+		lmb := json.LightMatchedBlock{
+			Hash:  mb.Hash.String(),
+			Order: mb.Order,
+			Txs:   make([]string, 0, len(mb.Txs)),
+		}
+		for _, tx := range mb.Txs {
+			lmb.Txs = append(lmb.Txs, tx.String())
+		}
+		result = append(result, lmb)
+	}
+	return result, nil
+}
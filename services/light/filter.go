@@ -0,0 +1,52 @@
+/*
+ * Copyright (c) 2017-2020 The qitmeer developers
+ */
+
+package light
+
+import (
+	"fmt"
+
+	"github.com/Qitmeer/qng/common/bloom"
+	"github.com/Qitmeer/qng/core/address"
+	"github.com/Qitmeer/qng/core/types"
+)
+
+const (
+	// MaxFilterSize is the maximum size in bytes of the filter which can
+	// be sent in a filter load message.
+	MaxFilterSize = 256
+
+	// filterFPRate is the false positive rate of the filter.
+	filterFPRate = 0.0001
+)
+
+// decodeAddrs decodes the watched addresses.
+func decodeAddrs(addrs []string) ([]types.Address, error) {
+	result := make([]types.Address, 0, len(addrs))
+	for _, a := range addrs {
+		addr, err := address.DecodeAddress(a)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid light address %s:%v", a, err)
+		}
+		result = append(result, addr)
+	}
+	return result, nil
+}
+
+// newAddrFilter returns a filter matching the outputs paying to the
+// addresses. Peers add the outpoints of matched outputs to their copy of the
+// filter, so the transactions spending them are matched too.
+func newAddrFilter(addrs []types.Address, tweak uint32) (*bloom.Filter, error) {
+	if len(addrs) <= 0 {
+		return nil, fmt.Errorf("No address to watch")
+	}
+	filter := bloom.NewFilter(uint32(len(addrs)), tweak, filterFPRate, types.BloomUpdateAll)
+	for _, addr := range addrs {
+		filter.Add(addr.Script())
+	}
+	if len(filter.MsgFilterLoad().Filter) > MaxFilterSize {
+		return nil, fmt.Errorf("Too many light addresses (%d), the filter is larger than %d bytes", len(addrs), MaxFilterSize)
+	}
+	return filter, nil
+}
@@ -0,0 +1,708 @@
+/*
+ * Copyright (c) 2017-2020 The qitmeer developers
+ */
+
+package light
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/consensus/model"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/core/types/pow"
+	"github.com/Qitmeer/qng/core/types/pow/difficultymanager"
+	"github.com/Qitmeer/qng/meerdag"
+	"github.com/Qitmeer/qng/params"
+)
+
+var (
+	// headerPrefix + block hash -> order + main height + layer + main
+	// parent hash + serialized header
+	headerPrefix = []byte("lightheader")
+	// orderPrefix + order -> block hash
+	orderPrefix = []byte("lightorder")
+	// stateKey -> tip order + checkpoint order
+	stateKey = []byte("lightstate")
+	// matchedKey -> all the blocks with transactions matched by the filter
+	matchedKey = []byte("lightmatched")
+)
+
+// headerBlockDataLen is the length of the header data used for the block
+// hash, it is what peers send in merkle blocks.
+const headerBlockDataLen = 4 + hash.HashSize*3 + 4 + 4 + pow.POW_LENGTH - pow.PROOFDATA_LENGTH
+
+// headerRecordLen is the length of the data stored before the header.
+const headerRecordLen = 8*3 + hash.HashSize
+
+// MatchedBlock is a block containing transactions matched by the filter of
+// the light node.
+type MatchedBlock struct {
+	Order uint64
+	Hash  hash.Hash
+	Txs   []*hash.Hash
+}
+
+// HeaderData is the header of a block with the parents of the block, and
+// the transactions of the block which were matched by the filter.
+type HeaderData struct {
+	Header  *types.BlockHeader
+	Parents []*hash.Hash
+	Txs     []*hash.Hash
+}
+
+// headerRecord is the header with its position in the DAG. The main parent
+// is the first parent of the block, the main height and the layer are
+// derived from the parents like MeerDAG does.
+type headerRecord struct {
+	hash       hash.Hash
+	order      uint64
+	height     uint64
+	layer      uint64
+	mainParent hash.Hash
+	header     types.BlockHeader
+}
+
+// HeaderChain keeps the block headers in MeerDAG order. It does not store
+// any block body, only the hashes of the transactions which were matched
+// by the filter. Every header must follow its parents in the chain and
+// have the difficulty required after its main parent.
+type HeaderChain struct {
+	db   model.DataBase
+	lock sync.RWMutex
+
+	tipOrder uint64
+	// The order of the latest checkpoint which was found in the chain,
+	// headers can not be rewound past it.
+	checkpointOrder uint64
+
+	checkpoints        map[hash.Hash]params.Checkpoint
+	checkpointsByLayer map[uint64]params.Checkpoint
+	matched            []*MatchedBlock
+	diffManager        model.DifficultyManager
+}
+
+// NewHeaderChain loads the header chain from the database, the genesis
+// header is stored if the chain is empty.
+func NewHeaderChain(db model.DataBase) (*HeaderChain, error) {
+	hc := &HeaderChain{
+		db:                 db,
+		checkpoints:        map[hash.Hash]params.Checkpoint{},
+		checkpointsByLayer: map[uint64]params.Checkpoint{},
+		matched:            []*MatchedBlock{},
+	}
+	for _, cp := range params.ActiveNetParams.Checkpoints {
+		hc.checkpoints[*cp.Hash] = cp
+		hc.checkpointsByLayer[cp.Layer] = cp
+	}
+	hc.diffManager = difficultymanager.NewDiffManager(&diffConsensus{bc: &diffChain{hc: hc}},
+		params.ActiveNetParams.Params)
+	err := hc.load()
+	if err != nil {
+		return nil, err
+	}
+	return hc, nil
+}
+
+func (hc *HeaderChain) load() error {
+	state := hc.get(stateKey)
+	if len(state) < 16 {
+		genesis := &headerRecord{header: params.ActiveNetParams.GenesisBlock.Block().Header}
+		genesis.hash = genesis.header.BlockHash()
+		if err := hc.putHeader(genesis); err != nil {
+			return err
+		}
+		hc.tipOrder = 0
+		hc.checkpointOrder = 0
+		return hc.saveState()
+	}
+	hc.tipOrder = binary.LittleEndian.Uint64(state[0:8])
+	hc.checkpointOrder = binary.LittleEndian.Uint64(state[8:16])
+
+	matched, err := decodeMatchedBlocks(hc.get(matchedKey))
+	if err != nil {
+		return err
+	}
+	hc.matched = matched
+	log.Info(fmt.Sprintf("Load light header chain: tip order=%d hash=%s matched blocks=%d",
+		hc.tipOrder, hc.hashByOrder(hc.tipOrder), len(hc.matched)))
+	return nil
+}
+
+// get returns nil if the key does not exist, whatever the database backend.
+func (hc *HeaderChain) get(key []byte) []byte {
+	v, err := hc.db.Get(key)
+	if err != nil || len(v) == 0 {
+		return nil
+	}
+	return v
+}
+
+func (hc *HeaderChain) saveState() error {
+	state := make([]byte, 16)
+	binary.LittleEndian.PutUint64(state[0:8], hc.tipOrder)
+	binary.LittleEndian.PutUint64(state[8:16], hc.checkpointOrder)
+	return hc.db.Put(stateKey, state)
+}
+
+func (hc *HeaderChain) putHeader(rec *headerRecord) error {
+	var buf bytes.Buffer
+	var b [headerRecordLen]byte
+	binary.LittleEndian.PutUint64(b[0:8], rec.order)
+	binary.LittleEndian.PutUint64(b[8:16], rec.height)
+	binary.LittleEndian.PutUint64(b[16:24], rec.layer)
+	copy(b[24:], rec.mainParent[:])
+	buf.Write(b[:])
+	if err := rec.header.Serialize(&buf); err != nil {
+		return err
+	}
+	if err := hc.db.Put(headerKey(&rec.hash), buf.Bytes()); err != nil {
+		return err
+	}
+	return hc.db.Put(orderKey(rec.order), rec.hash.Bytes())
+}
+
+func headerKey(h *hash.Hash) []byte {
+	key := make([]byte, 0, len(headerPrefix)+hash.HashSize)
+	key = append(key, headerPrefix...)
+	return append(key, h.Bytes()...)
+}
+
+func orderKey(order uint64) []byte {
+	key := make([]byte, len(orderPrefix)+8)
+	copy(key, orderPrefix)
+	binary.BigEndian.PutUint64(key[len(orderPrefix):], order)
+	return key
+}
+
+func (hc *HeaderChain) hashByOrder(order uint64) *hash.Hash {
+	if order > hc.tipOrder {
+		return nil
+	}
+	v := hc.get(orderKey(order))
+	if v == nil {
+		return nil
+	}
+	h, err := hash.NewHash(v)
+	if err != nil {
+		return nil
+	}
+	return h
+}
+
+// readRecord returns the stored header record of the block, whether it is
+// in the current chain or not.
+func (hc *HeaderChain) readRecord(h *hash.Hash) (*headerRecord, error) {
+	v := hc.get(headerKey(h))
+	if len(v) < headerRecordLen {
+		return nil, fmt.Errorf("No header:%s", h)
+	}
+	rec := &headerRecord{
+		hash:   *h,
+		order:  binary.LittleEndian.Uint64(v[0:8]),
+		height: binary.LittleEndian.Uint64(v[8:16]),
+		layer:  binary.LittleEndian.Uint64(v[16:24]),
+	}
+	copy(rec.mainParent[:], v[24:headerRecordLen])
+	if err := rec.header.Deserialize(bytes.NewReader(v[headerRecordLen:])); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// record returns the header record of the block if it is in the current
+// chain.
+func (hc *HeaderChain) record(h *hash.Hash) (*headerRecord, error) {
+	rec, err := hc.readRecord(h)
+	if err != nil {
+		return nil, err
+	}
+	oh := hc.hashByOrder(rec.order)
+	if oh == nil || !oh.IsEqual(h) {
+		return nil, fmt.Errorf("No header:%s", h)
+	}
+	return rec, nil
+}
+
+// header returns the header and the order of the block if it is in the
+// current chain.
+func (hc *HeaderChain) header(h *hash.Hash) (*types.BlockHeader, uint64, error) {
+	rec, err := hc.record(h)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &rec.header, rec.order, nil
+}
+
+// TipOrder returns the order of the last header in the chain.
+func (hc *HeaderChain) TipOrder() uint64 {
+	hc.lock.RLock()
+	defer hc.lock.RUnlock()
+
+	return hc.tipOrder
+}
+
+// TipHash returns the hash of the last header in the chain.
+func (hc *HeaderChain) TipHash() *hash.Hash {
+	hc.lock.RLock()
+	defer hc.lock.RUnlock()
+
+	return hc.hashByOrder(hc.tipOrder)
+}
+
+// CheckpointOrder returns the order of the latest checkpoint in the chain.
+func (hc *HeaderChain) CheckpointOrder() uint64 {
+	hc.lock.RLock()
+	defer hc.lock.RUnlock()
+
+	return hc.checkpointOrder
+}
+
+// HashByOrder returns the block hash of the order, or nil if the chain is
+// not that long.
+func (hc *HeaderChain) HashByOrder(order uint64) *hash.Hash {
+	hc.lock.RLock()
+	defer hc.lock.RUnlock()
+
+	return hc.hashByOrder(order)
+}
+
+// Header returns the header and the order of the block.
+func (hc *HeaderChain) Header(h *hash.Hash) (*types.BlockHeader, uint64, error) {
+	hc.lock.RLock()
+	defer hc.lock.RUnlock()
+
+	return hc.header(h)
+}
+
+// HasHeader returns whether the block is in the current chain.
+func (hc *HeaderChain) HasHeader(h *hash.Hash) bool {
+	_, _, err := hc.Header(h)
+	return err == nil
+}
+
+// Matched returns the blocks which have transactions matched by the filter.
+func (hc *HeaderChain) Matched() []*MatchedBlock {
+	hc.lock.RLock()
+	defer hc.lock.RUnlock()
+
+	result := make([]*MatchedBlock, len(hc.matched))
+	copy(result, hc.matched)
+	return result
+}
+
+// Locator returns the block hashes used to find the fork point with a peer,
+// they are ordered from the oldest to the newest.
+func (hc *HeaderChain) Locator() []*hash.Hash {
+	hc.lock.RLock()
+	defer hc.lock.RUnlock()
+
+	orders := []uint64{}
+	step := uint64(1)
+	for order := hc.tipOrder; len(orders) < meerdag.MaxMainLocatorNum-1; {
+		orders = append(orders, order)
+		if order == 0 {
+			break
+		}
+		if len(orders) >= 10 {
+			step *= 2
+		}
+		if order < step {
+			order = 0
+		} else {
+			order -= step
+		}
+	}
+	if orders[len(orders)-1] != 0 {
+		orders = append(orders, 0)
+	}
+	locator := make([]*hash.Hash, 0, len(orders))
+	for i := len(orders) - 1; i >= 0; i-- {
+		h := hc.hashByOrder(orders[i])
+		if h == nil {
+			continue
+		}
+		locator = append(locator, h)
+	}
+	return locator
+}
+
+// ConnectHeaders adds the headers from the order on. If other blocks are
+// already at those orders, they are replaced only if the headers have more
+// work, and never past a checkpoint. The replaced blocks are restored if any
+// header is invalid. It returns the number of the added headers.
+func (hc *HeaderChain) ConnectHeaders(order uint64, hds []*HeaderData) (int, error) {
+	hc.lock.Lock()
+	defer hc.lock.Unlock()
+
+	if order == 0 || order > hc.tipOrder+1 {
+		return 0, fmt.Errorf("Header order %d is not connected to the tip %d", order, hc.tipOrder)
+	}
+	// Skip the headers which are already in the chain.
+	for len(hds) > 0 && order <= hc.tipOrder {
+		h := hds[0].Header.BlockHash()
+		cur := hc.hashByOrder(order)
+		if cur == nil || !cur.IsEqual(&h) {
+			break
+		}
+		hds = hds[1:]
+		order++
+	}
+	if len(hds) <= 0 {
+		return 0, nil
+	}
+	if order > hc.tipOrder {
+		for i, hd := range hds {
+			if err := hc.connectHeader(order+uint64(i), hd); err != nil {
+				return i, err
+			}
+		}
+		return len(hds), nil
+	}
+
+	if order <= hc.checkpointOrder {
+		return 0, fmt.Errorf("Can't rewind to order %d before the checkpoint at order %d", order-1, hc.checkpointOrder)
+	}
+	newWork := new(big.Int)
+	for _, hd := range hds {
+		if hd.Header.Pow == nil {
+			return 0, fmt.Errorf("No pow in header")
+		}
+		newWork.Add(newWork, pow.CalcWork(hd.Header.Difficulty, hd.Header.Pow.GetPowType()))
+	}
+	olds := []*hash.Hash{}
+	oldWork := new(big.Int)
+	for o := order; o <= hc.tipOrder; o++ {
+		h := hc.hashByOrder(o)
+		if h == nil {
+			return 0, fmt.Errorf("No header at order %d", o)
+		}
+		rec, err := hc.record(h)
+		if err != nil {
+			return 0, err
+		}
+		olds = append(olds, h)
+		oldWork.Add(oldWork, pow.CalcWork(rec.header.Difficulty, rec.header.Pow.GetPowType()))
+	}
+	if newWork.Cmp(oldWork) <= 0 {
+		return 0, fmt.Errorf("The headers from order %d have no more work than the chain", order)
+	}
+	matched := hc.matched
+	if err := hc.rewind(order - 1); err != nil {
+		return 0, err
+	}
+	for i, hd := range hds {
+		err := hc.connectHeader(order+uint64(i), hd)
+		if err == nil {
+			continue
+		}
+		if rerr := hc.restore(order, olds, matched); rerr != nil {
+			log.Error(fmt.Sprintf("Failed to restore light header chain:%v", rerr))
+		}
+		return 0, err
+	}
+	return len(hds), nil
+}
+
+// connectHeader adds the header after the tip, the parents of the block
+// must be in the chain.
+func (hc *HeaderChain) connectHeader(order uint64, hd *HeaderData) error {
+	header := hd.Header
+	if len(hd.Parents) <= 0 || len(hd.Parents) > types.MaxParentsPerBlock {
+		return fmt.Errorf("Header has %d parents", len(hd.Parents))
+	}
+	if root := types.GetParentsRoot(hd.Parents); !root.IsEqual(&header.ParentRoot) {
+		return fmt.Errorf("The parents don't match the parent root %s", header.ParentRoot)
+	}
+	rec := &headerRecord{hash: header.BlockHash(), order: order, header: *header}
+	var mainParent *headerRecord
+	for i, p := range hd.Parents {
+		prec, err := hc.record(p)
+		if err != nil {
+			return fmt.Errorf("Unknown parent %s of header %s", p, rec.hash)
+		}
+		if i == 0 {
+			mainParent = prec
+		}
+		if prec.layer > rec.layer {
+			rec.layer = prec.layer
+		}
+	}
+	rec.layer++
+	rec.height = mainParent.height + 1
+	rec.mainParent = mainParent.hash
+
+	// The checkpoints are pinned to their layers.
+	if cp, ok := hc.checkpointsByLayer[rec.layer]; ok && !cp.Hash.IsEqual(&rec.hash) {
+		return fmt.Errorf("Header %s at layer %d does not match the checkpoint %s", rec.hash, rec.layer, cp.Hash)
+	}
+	if cp, ok := hc.checkpoints[rec.hash]; ok && cp.Layer != rec.layer {
+		return fmt.Errorf("Checkpoint %s is at layer %d, not %d", rec.hash, rec.layer, cp.Layer)
+	}
+	if err := CheckHeader(header, rec.height); err != nil {
+		return err
+	}
+	if err := hc.checkDifficulty(header, mainParent); err != nil {
+		return err
+	}
+	if err := hc.putHeader(rec); err != nil {
+		return err
+	}
+	hc.tipOrder = order
+	if cp, ok := hc.checkpoints[rec.hash]; ok {
+		hc.checkpointOrder = order
+		log.Info(fmt.Sprintf("Verified checkpoint at layer %d/block %s (order %d)", cp.Layer, rec.hash, order))
+	}
+	if len(hd.Txs) > 0 {
+		hc.matched = append(hc.matched, &MatchedBlock{Order: order, Hash: rec.hash, Txs: hd.Txs})
+		if err := hc.db.Put(matchedKey, encodeMatchedBlocks(hc.matched)); err != nil {
+			return err
+		}
+	}
+	return hc.saveState()
+}
+
+// checkDifficulty verifies that the difficulty of the header is the one
+// required after its main parent.
+func (hc *HeaderChain) checkDifficulty(header *types.BlockHeader, mainParent *headerRecord) error {
+	if params.ActiveNetParams.Params.IsDevelopDiff() {
+		return nil
+	}
+	instance := pow.GetInstance(header.Pow.GetPowType(), 0, []byte{})
+	instance.SetMainHeight(pow.MainHeight(mainParent.height + 1))
+	instance.SetParams(params.ActiveNetParams.Params.PowConfig)
+	expDiff, err := hc.diffManager.RequiredDifficulty(hc.block(mainParent), header.Timestamp, instance)
+	if err != nil {
+		return err
+	}
+	if header.Difficulty != expDiff {
+		return fmt.Errorf("Header difficulty of %d is not the expected value of %d", header.Difficulty, expDiff)
+	}
+	return nil
+}
+
+// restore puts the replaced blocks back from the order after a failed
+// reorganization.
+func (hc *HeaderChain) restore(order uint64, olds []*hash.Hash, matched []*MatchedBlock) error {
+	for i, h := range olds {
+		rec, err := hc.readRecord(h)
+		if err != nil {
+			return err
+		}
+		rec.order = order + uint64(i)
+		if err := hc.putHeader(rec); err != nil {
+			return err
+		}
+	}
+	hc.tipOrder = order + uint64(len(olds)) - 1
+	hc.matched = matched
+	if err := hc.db.Put(matchedKey, encodeMatchedBlocks(hc.matched)); err != nil {
+		return err
+	}
+	return hc.saveState()
+}
+
+// Rewind removes all headers after the order.
+func (hc *HeaderChain) Rewind(order uint64) error {
+	hc.lock.Lock()
+	defer hc.lock.Unlock()
+
+	return hc.rewind(order)
+}
+
+func (hc *HeaderChain) rewind(order uint64) error {
+	if order >= hc.tipOrder {
+		return nil
+	}
+	if order < hc.checkpointOrder {
+		return fmt.Errorf("Can't rewind to order %d before the checkpoint at order %d", order, hc.checkpointOrder)
+	}
+	log.Info(fmt.Sprintf("Rewind light header chain from order %d to %d", hc.tipOrder, order))
+	// The stale entries after the tip are never read, so there is no need
+	// to remove them from the database.
+	hc.tipOrder = order
+	matched := []*MatchedBlock{}
+	for _, mb := range hc.matched {
+		if mb.Order <= order {
+			matched = append(matched, mb)
+		}
+	}
+	if len(matched) != len(hc.matched) {
+		hc.matched = matched
+		if err := hc.db.Put(matchedKey, encodeMatchedBlocks(hc.matched)); err != nil {
+			return err
+		}
+	}
+	return hc.saveState()
+}
+
+// CheckHeader verifies the proof of work of the header at the main height
+// against the difficulty of the header, the difficulty itself is checked
+// by the header chain.
+func CheckHeader(header *types.BlockHeader, mainHeight uint64) error {
+	if header.Pow == nil {
+		return fmt.Errorf("No pow in header")
+	}
+	if params.ActiveNetParams.Params.IsDevelopDiff() {
+		return nil
+	}
+	header.Pow.SetParams(params.ActiveNetParams.Params.PowConfig)
+	header.Pow.SetMainHeight(pow.MainHeight(mainHeight))
+	return header.Pow.Verify(header.BlockData(), header.BlockHash(), header.Difficulty)
+}
+
+// DecodeHeader decodes the header which was sent in a merkle block. The
+// proof data is not part of the block hash for the hash based pow, so peers
+// may only send the block data.
+func DecodeHeader(data []byte) (*types.BlockHeader, error) {
+	if len(data) == headerBlockDataLen {
+		data = append(append([]byte{}, data...), make([]byte, pow.PROOFDATA_LENGTH)...)
+	}
+	var header types.BlockHeader
+	if err := header.Deserialize(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return &header, nil
+}
+
+// block returns the block of the record for the difficulty manager.
+func (hc *HeaderChain) block(rec *headerRecord) model.Block {
+	lb := &lightBlock{hc: hc, hash: rec.hash, order: rec.order, height: rec.height}
+	if rec.order > 0 {
+		mp := rec.mainParent
+		lb.mainParent = &mp
+	}
+	return lb
+}
+
+// lightBlock is a block of the header chain for the difficulty manager, its
+// id is its order.
+type lightBlock struct {
+	hc         *HeaderChain
+	hash       hash.Hash
+	order      uint64
+	height     uint64
+	mainParent *hash.Hash
+}
+
+func (lb *lightBlock) GetID() uint {
+	return uint(lb.order)
+}
+
+func (lb *lightBlock) GetHash() *hash.Hash {
+	return &lb.hash
+}
+
+func (lb *lightBlock) GetState() model.BlockState {
+	return nil
+}
+
+func (lb *lightBlock) GetOrder() uint {
+	return uint(lb.order)
+}
+
+func (lb *lightBlock) HasParents() bool {
+	return lb.mainParent != nil
+}
+
+func (lb *lightBlock) GetMainParent() uint {
+	if lb.mainParent == nil {
+		return meerdag.MaxId
+	}
+	rec, err := lb.hc.record(lb.mainParent)
+	if err != nil {
+		return meerdag.MaxId
+	}
+	return uint(rec.order)
+}
+
+func (lb *lightBlock) GetHeight() uint {
+	return uint(lb.height)
+}
+
+// diffChain serves the header chain to the difficulty manager, which only
+// walks the main parents. It is used with the lock of the chain held.
+type diffChain struct {
+	model.BlockChain
+	hc *HeaderChain
+}
+
+func (dc *diffChain) GetBlockById(id uint) model.Block {
+	h := dc.hc.hashByOrder(uint64(id))
+	if h == nil {
+		return nil
+	}
+	rec, err := dc.hc.record(h)
+	if err != nil {
+		return nil
+	}
+	return dc.hc.block(rec)
+}
+
+func (dc *diffChain) GetBlockHeader(block model.Block) *types.BlockHeader {
+	rec, err := dc.hc.record(block.GetHash())
+	if err != nil {
+		return nil
+	}
+	return &rec.header
+}
+
+func (dc *diffChain) ForeachBlueBlocks(start model.Block, depth uint, powType pow.PowType, fn func(block model.Block, header *types.BlockHeader) error) error {
+	return fmt.Errorf("The blue blocks are not known by the light node")
+}
+
+// diffConsensus provides the header chain to the difficulty manager.
+type diffConsensus struct {
+	model.Consensus
+	bc model.BlockChain
+}
+
+func (dc *diffConsensus) BlockChain() model.BlockChain {
+	return dc.bc
+}
+
+func encodeMatchedBlocks(mbs []*MatchedBlock) []byte {
+	var buf bytes.Buffer
+	var b [8]byte
+	for _, mb := range mbs {
+		binary.LittleEndian.PutUint64(b[:], mb.Order)
+		buf.Write(b[:])
+		buf.Write(mb.Hash.Bytes())
+		binary.LittleEndian.PutUint32(b[:4], uint32(len(mb.Txs)))
+		buf.Write(b[:4])
+		for _, tx := range mb.Txs {
+			buf.Write(tx.Bytes())
+		}
+	}
+	return buf.Bytes()
+}
+
+func decodeMatchedBlocks(data []byte) ([]*MatchedBlock, error) {
+	result := []*MatchedBlock{}
+	for len(data) > 0 {
+		if len(data) < 8+hash.HashSize+4 {
+			return nil, fmt.Errorf("Invalid matched blocks data")
+		}
+		mb := &MatchedBlock{Order: binary.LittleEndian.Uint64(data[0:8])}
+		copy(mb.Hash[:], data[8:8+hash.HashSize])
+		data = data[8+hash.HashSize:]
+		num := int(binary.LittleEndian.Uint32(data[0:4]))
+		data = data[4:]
+		if len(data) < num*hash.HashSize {
+			return nil, fmt.Errorf("Invalid matched blocks data")
+		}
+		mb.Txs = make([]*hash.Hash, num)
+		for i := 0; i < num; i++ {
+			var tx hash.Hash
+			copy(tx[:], data[:hash.HashSize])
+			mb.Txs[i] = &tx
+			data = data[hash.HashSize:]
+		}
+		result = append(result, mb)
+	}
+	return result, nil
+}
@@ -0,0 +1,212 @@
+package light
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/consensus/model"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/core/types/pow"
+	"github.com/Qitmeer/qng/params"
+)
+
+// memDB is a database only supporting the generic key value methods.
+type memDB struct {
+	model.DataBase
+	kv map[string][]byte
+}
+
+func newMemDB() *memDB {
+	return &memDB{kv: map[string][]byte{}}
+}
+
+func (db *memDB) Get(key []byte) ([]byte, error) {
+	v, ok := db.kv[string(key)]
+	if !ok {
+		return nil, fmt.Errorf("not found")
+	}
+	return v, nil
+}
+
+func (db *memDB) Put(key []byte, value []byte) error {
+	db.kv[string(key)] = append([]byte{}, value...)
+	return nil
+}
+
+// mineHeader returns a header after the parents with a valid proof of work
+// for the privnet.
+func mineHeader(t *testing.T, parents []*hash.Hash, seed byte, difficulty uint32) *types.BlockHeader {
+	header := &types.BlockHeader{
+		Version:    1,
+		ParentRoot: types.GetParentsRoot(parents),
+		TxRoot:     hash.Hash{seed, 1},
+		Difficulty: difficulty,
+		Timestamp:  time.Unix(int64(1600000000+int(seed)), 0),
+	}
+	for nonce := uint64(0); nonce < 1000; nonce++ {
+		header.Pow = pow.GetInstance(pow.BLAKE2BD, nonce, []byte{})
+		if CheckHeader(header, 1) == nil {
+			return header
+		}
+	}
+	t.Fatalf("failed to mine header %d", seed)
+	return nil
+}
+
+// headerData returns the data of a header mined after the parents.
+func headerData(t *testing.T, parents []*hash.Hash, seed byte) *HeaderData {
+	header := mineHeader(t, parents, seed, params.PrivNetParam.PowConfig.Blake2bdPowLimitBits)
+	return &HeaderData{Header: header, Parents: parents}
+}
+
+// headerHash returns the block hash of the header data.
+func headerHash(hd *HeaderData) *hash.Hash {
+	h := hd.Header.BlockHash()
+	return &h
+}
+
+func TestDecodeHeader(t *testing.T) {
+	params.ActiveNetParams = &params.PrivNetParam
+	header := mineHeader(t, []*hash.Hash{params.PrivNetParam.GenesisHash}, 1, params.PrivNetParam.PowConfig.Blake2bdPowLimitBits)
+	decoded, err := DecodeHeader(header.BlockData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.BlockHash() != header.BlockHash() {
+		t.Fatalf("decoded hash %s, want %s", decoded.BlockHash(), header.BlockHash())
+	}
+}
+
+func TestHeaderChain(t *testing.T) {
+	params.ActiveNetParams = &params.PrivNetParam
+	db := newMemDB()
+	hc, err := NewHeaderChain(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hc.TipOrder() != 0 || !hc.TipHash().IsEqual(params.ActiveNetParams.GenesisHash) {
+		t.Fatalf("new chain tip is %d %s, want genesis", hc.TipOrder(), hc.TipHash())
+	}
+
+	hds := []*HeaderData{}
+	prev := params.ActiveNetParams.GenesisHash
+	for i := 1; i <= 5; i++ {
+		hd := headerData(t, []*hash.Hash{prev}, byte(i))
+		if i == 3 {
+			hd.Txs = []*hash.Hash{{0x03}}
+		}
+		hds = append(hds, hd)
+		prev = headerHash(hd)
+	}
+	if n, err := hc.ConnectHeaders(1, hds[:3]); err != nil || n != 3 {
+		t.Fatalf("connect headers: %d %v", n, err)
+	}
+	// The headers already in the chain are skipped.
+	if n, err := hc.ConnectHeaders(2, hds[1:]); err != nil || n != 2 {
+		t.Fatalf("connect headers: %d %v", n, err)
+	}
+	if _, err := hc.ConnectHeaders(7, []*HeaderData{headerData(t, []*hash.Hash{prev}, 7)}); err == nil {
+		t.Fatal("connected a header which is not next to the tip")
+	}
+	if hc.TipOrder() != 5 {
+		t.Fatalf("tip order is %d, want 5", hc.TipOrder())
+	}
+	locator := hc.Locator()
+	if !locator[0].IsEqual(params.ActiveNetParams.GenesisHash) ||
+		!locator[len(locator)-1].IsEqual(hc.TipHash()) {
+		t.Fatalf("locator must start with the genesis and end with the tip")
+	}
+	if _, order, err := hc.Header(headerHash(hds[2])); err != nil || order != 3 {
+		t.Fatalf("header at order 3: %d %v", order, err)
+	}
+	rec, err := hc.record(headerHash(hds[4]))
+	if err != nil || rec.height != 5 || rec.layer != 5 || !rec.mainParent.IsEqual(headerHash(hds[3])) {
+		t.Fatalf("header record at order 5 is wrong")
+	}
+
+	// Invalid headers are refused.
+	bad := headerData(t, []*hash.Hash{prev}, 60)
+	bad.Parents = []*hash.Hash{headerHash(hds[3])}
+	if _, err := hc.ConnectHeaders(6, []*HeaderData{bad}); err == nil {
+		t.Fatal("connected a header with the wrong parents")
+	}
+	unknown := []*hash.Hash{{0x61}}
+	if _, err := hc.ConnectHeaders(6, []*HeaderData{headerData(t, unknown, 61)}); err == nil {
+		t.Fatal("connected a header with an unknown parent")
+	}
+	hard := &HeaderData{Header: mineHeader(t, []*hash.Hash{prev}, 62, 0x203fffff), Parents: []*hash.Hash{prev}}
+	if _, err := hc.ConnectHeaders(6, []*HeaderData{hard}); err == nil {
+		t.Fatal("connected a header with the wrong difficulty")
+	}
+	cp := headerData(t, []*hash.Hash{prev}, 63)
+	hc.checkpointsByLayer[6] = params.Checkpoint{Layer: 6, Hash: &hash.Hash{0x63}}
+	if _, err := hc.ConnectHeaders(6, []*HeaderData{cp}); err == nil {
+		t.Fatal("connected a header which does not match the checkpoint")
+	}
+	delete(hc.checkpointsByLayer, 6)
+
+	// The blocks at orders 4 and 5 are only replaced by more work.
+	fork := []*HeaderData{}
+	prev = headerHash(hds[2])
+	for i := 40; i < 43; i++ {
+		hd := headerData(t, []*hash.Hash{prev}, byte(i))
+		fork = append(fork, hd)
+		prev = headerHash(hd)
+	}
+	if _, err := hc.ConnectHeaders(4, fork[:2]); err == nil {
+		t.Fatal("rewound the chain for the same work")
+	}
+	broken := append(append([]*HeaderData{}, fork[:2]...), headerData(t, unknown, 43))
+	if _, err := hc.ConnectHeaders(4, broken); err == nil {
+		t.Fatal("connected a header with an unknown parent")
+	}
+	if hc.TipOrder() != 5 || !hc.HasHeader(headerHash(hds[4])) || hc.HasHeader(headerHash(fork[0])) {
+		t.Fatalf("the chain is not restored after the failed rewind")
+	}
+	if n, err := hc.ConnectHeaders(4, fork); err != nil || n != 3 {
+		t.Fatalf("connect fork: %d %v", n, err)
+	}
+	if hc.TipOrder() != 6 {
+		t.Fatalf("tip order is %d after the rewind, want 6", hc.TipOrder())
+	}
+	if hc.HasHeader(headerHash(hds[4])) {
+		t.Fatalf("the rewound header is still in the chain")
+	}
+	if len(hc.Matched()) != 1 {
+		t.Fatalf("got %d matched blocks, want 1", len(hc.Matched()))
+	}
+
+	// The chain is loaded from the database.
+	hc, err = NewHeaderChain(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hc.TipOrder() != 6 || !hc.TipHash().IsEqual(prev) {
+		t.Fatalf("loaded tip is %d %s", hc.TipOrder(), hc.TipHash())
+	}
+	matched := hc.Matched()
+	if len(matched) != 1 || matched[0].Order != 3 || !matched[0].Txs[0].IsEqual(&hash.Hash{0x03}) {
+		t.Fatalf("loaded matched blocks are wrong")
+	}
+
+	// Merging two tips gives the layer after both.
+	side := headerData(t, []*hash.Hash{headerHash(fork[0])}, 70)
+	merge := headerData(t, []*hash.Hash{prev, headerHash(side)}, 71)
+	if n, err := hc.ConnectHeaders(7, []*HeaderData{side, merge}); err != nil || n != 2 {
+		t.Fatalf("connect merge: %d %v", n, err)
+	}
+	if rec, err := hc.record(headerHash(merge)); err != nil || rec.layer != 7 || rec.height != 7 {
+		t.Fatalf("merge header record is wrong")
+	}
+
+	// Rewinding past a checkpoint is not allowed.
+	hc.checkpointOrder = 2
+	if err := hc.Rewind(1); err == nil {
+		t.Fatal("rewound past the checkpoint")
+	}
+	if _, err := hc.ConnectHeaders(2, fork); err == nil {
+		t.Fatal("replaced the checkpoint")
+	}
+}
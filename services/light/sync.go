@@ -0,0 +1,741 @@
+/*
+ * Copyright (c) 2017-2020 The qitmeer developers
+ */
+
+package light
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Qitmeer/qng/common/bloom"
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/common/roughtime"
+	"github.com/Qitmeer/qng/config"
+	pv "github.com/Qitmeer/qng/core/protocol"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/node/service"
+	"github.com/Qitmeer/qng/p2p"
+	"github.com/Qitmeer/qng/p2p/common"
+	"github.com/Qitmeer/qng/p2p/encoder"
+	"github.com/Qitmeer/qng/p2p/peers"
+	pb "github.com/Qitmeer/qng/p2p/proto/v1"
+	"github.com/Qitmeer/qng/p2p/synch"
+	"github.com/Qitmeer/qng/params"
+	"github.com/libp2p/go-libp2p"
+	dht "github.com/libp2p/go-libp2p-kad-dht"
+	libp2pcore "github.com/libp2p/go-libp2p/core"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+)
+
+const (
+	// The services of the light node.
+	lightServices = pv.Light | pv.Bloom
+
+	// The services which peers must provide to be synced from.
+	wantServices = pv.Full | pv.Bloom
+
+	// The number of merkle blocks requested at once.
+	maxMerkleBlocksPerMsg = 500
+
+	// The interval to check the peers and the sync state.
+	syncInterval = 30 * time.Second
+
+	// The number of sync peers the light node tries to keep.
+	minSyncPeers = 2
+)
+
+// Sync is the service of the light node. It connects to full nodes over the
+// p2p/synch protocols, syncs the block headers in MeerDAG order and finds
+// the transactions of the watched addresses through merkle blocks.
+type Sync struct {
+	service.Service
+
+	cfg      *config.Config
+	chain    *HeaderChain
+	addrs    []types.Address
+	filter   *bloom.Filter
+	encoding encoder.NetworkEncoding
+
+	host    host.Host
+	dht     *dht.IpfsDHT
+	peers   *peers.Status
+	running int32
+	wg      sync.WaitGroup
+
+	syncPeer   *peers.Peer
+	syncPeerMu sync.RWMutex
+	syncCh     chan struct{}
+	// The peers which the filter was loaded to.
+	filterPeers sync.Map
+}
+
+// New returns the light sync service.
+func New(cfg *config.Config, chain *HeaderChain) (*Sync, error) {
+	addrs, err := decodeAddrs(cfg.LightAddrs)
+	if err != nil {
+		return nil, err
+	}
+	s := &Sync{
+		cfg:      cfg,
+		chain:    chain,
+		addrs:    addrs,
+		encoding: &encoder.SszNetworkEncoder{UseSnappyCompression: true},
+		syncCh:   make(chan struct{}, 1),
+	}
+	if len(addrs) > 0 {
+		s.filter, err = newAddrFilter(addrs, uint32(time.Now().UnixNano()))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		log.Warn("No light address to watch, only the block headers will be synced")
+	}
+	s.peers = peers.NewStatus(s)
+	return s, nil
+}
+
+func (s *Sync) Start() error {
+	if err := s.Service.Start(); err != nil {
+		return err
+	}
+	log.Info("Start light sync")
+
+	opts := []libp2p.Option{}
+	pk, err := p2p.PrivateKey(s.cfg.DataDir, "", 0600)
+	if err != nil {
+		return err
+	}
+	opts = append(opts, libp2p.Identity(pk))
+	if s.cfg.DisableListen {
+		opts = append(opts, libp2p.NoListenAddrs)
+	} else {
+		ip := "0.0.0.0"
+		if len(s.cfg.Listener) > 0 {
+			ip = s.cfg.Listener
+		}
+		listen, err := multiaddr.NewMultiaddr(fmt.Sprintf("/ip4/%s/tcp/%d", ip, s.cfg.P2PTCPPort))
+		if err != nil {
+			return err
+		}
+		opts = append(opts, libp2p.ListenAddrs(listen))
+	}
+	s.host, err = libp2p.New(opts...)
+	if err != nil {
+		return err
+	}
+	s.registerHandlers()
+	atomic.StoreInt32(&s.running, 1)
+
+	if !s.cfg.NoDiscovery {
+		s.dht, err = dht.New(s.Context(), s.host, dht.V1ProtocolOverride(p2p.ProtocolDHT()), dht.Mode(dht.ModeClient))
+		if err != nil {
+			return err
+		}
+		if err = s.dht.Bootstrap(s.Context()); err != nil {
+			return err
+		}
+	}
+	log.Info(fmt.Sprintf("Light node ID:%s tip order:%d", s.host.ID(), s.chain.TipOrder()))
+
+	s.wg.Add(1)
+	go s.handler()
+	return nil
+}
+
+func (s *Sync) Stop() error {
+	log.Info("Stop light sync")
+	if err := s.Service.Stop(); err != nil {
+		return err
+	}
+	atomic.StoreInt32(&s.running, 0)
+	s.wg.Wait()
+	if s.dht != nil {
+		s.dht.Close()
+	}
+	if s.host != nil {
+		return s.host.Close()
+	}
+	return nil
+}
+
+func (s *Sync) registerHandlers() {
+	s.host.Network().Notify(&network.NotifyBundle{
+		ConnectedF: func(net network.Network, conn network.Conn) {
+			pe := s.peers.Fetch(conn.RemotePeer())
+			pe.UpdateAddrDir(nil, conn.RemoteMultiaddr(), conn.Stat().Direction)
+			// Inbound peers will send their chain state.
+			if conn.Stat().Direction == network.DirOutbound {
+				go s.handshake(pe)
+			}
+		},
+		DisconnectedF: func(net network.Network, conn network.Conn) {
+			pe := s.peers.Get(conn.RemotePeer())
+			if pe == nil {
+				return
+			}
+			s.disconnected(pe)
+		},
+	})
+
+	synch.RegisterRPC(s, synch.RPCChainState, &pb.ChainState{}, s.chainStateHandler)
+	synch.RegisterRPC(s, synch.RPCGoodByeTopic, new(uint64), s.goodbyeHandler)
+	synch.RegisterRPC(s, synch.RPCPingTopic, new(uint64), s.pingHandler)
+	synch.RegisterRPC(s, synch.RPCMetaDataTopic, nil, s.metaDataHandler)
+	synch.RegisterRPC(s, synch.RPCGraphState, &pb.GraphState{}, s.graphStateHandler)
+	synch.RegisterRPC(s, synch.RPCInventory, &pb.Inventory{}, s.inventoryHandler)
+}
+
+func (s *Sync) handler() {
+	ticker := time.NewTicker(syncInterval)
+	defer func() {
+		ticker.Stop()
+		s.wg.Done()
+	}()
+	s.connectPeers()
+	for {
+		select {
+		case <-s.Context().Done():
+			return
+		case <-ticker.C:
+			s.connectPeers()
+			s.sync()
+		case <-s.syncCh:
+			s.sync()
+		}
+	}
+}
+
+// notifySync asks the handler to sync with the best peer.
+func (s *Sync) notifySync() {
+	select {
+	case s.syncCh <- struct{}{}:
+	default:
+	}
+}
+
+// connectPeers connects to the static peers and the bootstrap nodes, and to
+// the peers found by the discovery if there are not enough sync peers.
+func (s *Sync) connectPeers() {
+	if len(s.syncPeers()) >= minSyncPeers {
+		return
+	}
+	addrs := append([]string{}, s.cfg.AddPeers...)
+	if len(s.cfg.BootstrapNodes) > 0 {
+		addrs = append(addrs, s.cfg.BootstrapNodes...)
+	} else {
+		addrs = append(addrs, params.ActiveNetParams.Bootstrap...)
+	}
+	infos := []peer.AddrInfo{}
+	for _, addr := range addrs {
+		info, err := peer.AddrInfoFromString(addr)
+		if err != nil {
+			log.Debug(fmt.Sprintf("Ignore the peer address %s:%v", addr, err))
+			continue
+		}
+		infos = append(infos, *info)
+	}
+	if s.dht != nil {
+		for _, pid := range s.dht.RoutingTable().ListPeers() {
+			infos = append(infos, s.host.Peerstore().PeerInfo(pid))
+		}
+	}
+	for _, info := range infos {
+		if info.ID == s.host.ID() ||
+			s.host.Network().Connectedness(info.ID) == network.Connected {
+			continue
+		}
+		go func(info peer.AddrInfo) {
+			ctx, cancel := context.WithTimeout(s.Context(), synch.ReqTimeout)
+			defer cancel()
+			if err := s.host.Connect(ctx, info); err != nil {
+				log.Trace(fmt.Sprintf("Could not connect with peer %s :%v", info.String(), err))
+			}
+		}(info)
+	}
+}
+
+// handshake sends our chain state to the outbound peer and checks the one it
+// replies.
+func (s *Sync) handshake(pe *peers.Peer) {
+	ctx, cancel := context.WithTimeout(s.Context(), synch.ReqTimeout)
+	defer cancel()
+
+	stream, e := synch.Send(ctx, s, s.chainState(), synch.RPCChainState, pe)
+	if e != nil && !e.Code.IsSuccess() {
+		log.Trace(fmt.Sprintf("%s Handshake failed (%s)", pe.IDWithAddress(), e.Error.Error()))
+		return
+	}
+	defer stream.Close()
+
+	e = synch.ReadRspCode(stream, s)
+	if !e.Code.IsSuccess() && !e.Code.IsDAGConsensus() {
+		log.Trace(fmt.Sprintf("%s Handshake failed (%s)", pe.IDWithAddress(), e.Error.Error()))
+		return
+	}
+	msg := &pb.ChainState{}
+	if err := synch.DecodeMessage(stream, s, msg); err != nil {
+		log.Trace(fmt.Sprintf("%s Handshake failed (%v)", pe.IDWithAddress(), err))
+		return
+	}
+	if !e.Code.IsSuccess() {
+		log.Debug(fmt.Sprintf("%s rejected the light node (%s)", pe.IDWithAddress(), e.Error.Error()))
+		return
+	}
+	if err := s.validateChainState(msg); err != nil {
+		log.Debug(fmt.Sprintf("%s %v", pe.IDWithAddress(), err))
+		s.Disconnect(pe.GetID())
+		return
+	}
+	s.connected(pe, msg)
+}
+
+func (s *Sync) validateChainState(msg *pb.ChainState) error {
+	if msg.GenesisHash == nil || msg.GraphState == nil {
+		return fmt.Errorf("invalid chain state")
+	}
+	genesis, err := hash.NewHash(msg.GenesisHash.Hash)
+	if err != nil || !genesis.IsEqual(s.GetGenesisHash()) {
+		return fmt.Errorf("invalid genesis")
+	}
+	if msg.ProtocolVersion < uint32(pv.InitialProcotolVersion) {
+		return fmt.Errorf("protocol version must be %d or greater", pv.InitialProcotolVersion)
+	}
+	return nil
+}
+
+func (s *Sync) connected(pe *peers.Peer, cs *pb.ChainState) {
+	pe.SetChainState(cs)
+	if pe.IsConnected() {
+		return
+	}
+	pe.SetConnectionState(peers.PeerConnected)
+	log.Info(fmt.Sprintf("%s direction:%s (%s) Peer Connected", pe.IDWithAddress(), pe.Direction(), pe.Services().String()))
+	if canSync(pe) {
+		s.notifySync()
+	}
+}
+
+func (s *Sync) disconnected(pe *peers.Peer) {
+	if !pe.IsConnected() {
+		return
+	}
+	pe.SetConnectionState(peers.PeerDisconnected)
+	s.filterPeers.Delete(pe.GetID())
+	s.syncPeerMu.Lock()
+	if s.syncPeer == pe {
+		s.syncPeer = nil
+	}
+	s.syncPeerMu.Unlock()
+	log.Debug(fmt.Sprintf("Disconnect:%v", pe.IDWithAddress()))
+}
+
+func (s *Sync) chainState() *pb.ChainState {
+	genesis := s.GetGenesisHash()
+	return &pb.ChainState{
+		GenesisHash:     &pb.Hash{Hash: genesis.Bytes()},
+		ProtocolVersion: pv.ProtocolVersion,
+		Timestamp:       uint64(roughtime.Now().Unix()),
+		Services:        uint64(lightServices),
+		GraphState:      s.graphState(),
+		UserAgent:       []byte(p2p.BuildUserAgent("QNG-Light")),
+		DisableRelayTx:  true,
+	}
+}
+
+// graphState describes the header chain, the light node does not know the
+// layers and the main heights of the blocks.
+func (s *Sync) graphState() *pb.GraphState {
+	tip := s.chain.TipOrder()
+	gs := &pb.GraphState{
+		Total:     uint32(tip + 1),
+		MainOrder: uint32(tip),
+		Tips:      []*pb.Hash{},
+	}
+	if th := s.chain.TipHash(); th != nil {
+		gs.Tips = append(gs.Tips, &pb.Hash{Hash: th.Bytes()})
+	}
+	return gs
+}
+
+func (s *Sync) chainStateHandler(ctx context.Context, msg interface{}, stream libp2pcore.Stream, pe *peers.Peer) *common.Error {
+	m, ok := msg.(*pb.ChainState)
+	if !ok {
+		return synch.ErrMessage(fmt.Errorf("message is not type *pb.ChainState"))
+	}
+	if err := s.validateChainState(m); err != nil {
+		pe.SetChainState(m)
+		if e := synch.EncodeResponseMsg(s, stream, s.chainState(), common.ErrDAGConsensus); e != nil {
+			return e
+		}
+		return common.NewError(common.ErrDAGConsensus, err)
+	}
+	s.connected(pe, m)
+	return synch.EncodeResponseMsg(s, stream, s.chainState(), common.ErrNone)
+}
+
+func (s *Sync) goodbyeHandler(ctx context.Context, msg interface{}, stream libp2pcore.Stream, pe *peers.Peer) *common.Error {
+	m, ok := msg.(*uint64)
+	if !ok {
+		return synch.ErrMessage(fmt.Errorf("wrong message type for goodbye, got %T, wanted *uint64", msg))
+	}
+	log.Debug(fmt.Sprintf("Peer receive a goodbye message:%s (Reason:%s)", pe.IDWithAddress(), common.ErrorCode(*m).String()))
+	go s.Disconnect(pe.GetID())
+	return nil
+}
+
+func (s *Sync) pingHandler(ctx context.Context, msg interface{}, stream libp2pcore.Stream, pe *peers.Peer) *common.Error {
+	seq := uint64(0)
+	return synch.EncodeResponseMsg(s, stream, &seq, common.ErrNone)
+}
+
+func (s *Sync) metaDataHandler(ctx context.Context, msg interface{}, stream libp2pcore.Stream, pe *peers.Peer) *common.Error {
+	return synch.EncodeResponseMsg(s, stream, &pb.MetaData{}, common.ErrNone)
+}
+
+func (s *Sync) graphStateHandler(ctx context.Context, msg interface{}, stream libp2pcore.Stream, pe *peers.Peer) *common.Error {
+	m, ok := msg.(*pb.GraphState)
+	if !ok {
+		return synch.ErrMessage(fmt.Errorf("message is not type *pb.GraphState"))
+	}
+	pe.UpdateGraphState(m)
+	if uint64(m.MainOrder) > s.chain.TipOrder() {
+		s.notifySync()
+	}
+	return synch.EncodeResponseMsg(s, stream, s.graphState(), common.ErrNone)
+}
+
+func (s *Sync) inventoryHandler(ctx context.Context, msg interface{}, stream libp2pcore.Stream, pe *peers.Peer) *common.Error {
+	m, ok := msg.(*pb.Inventory)
+	if !ok {
+		return synch.ErrMessage(fmt.Errorf("message is not type *pb.Inventory"))
+	}
+	for _, inv := range m.Invs {
+		if synch.InvType(inv.Type) == synch.InvTypeBlock {
+			s.notifySync()
+			break
+		}
+	}
+	return synch.EncodeResponseMsg(s, stream, nil, common.ErrNone)
+}
+
+// canSync returns whether the peer can serve merkle blocks with the parents
+// of the blocks.
+func canSync(pe *peers.Peer) bool {
+	cs := pe.ChainState()
+	return cs != nil && cs.ProtocolVersion >= pv.MerkleParentsProtocolVersion &&
+		pv.HasServices(pe.Services(), wantServices)
+}
+
+// syncPeers returns the connected peers which can serve merkle blocks.
+func (s *Sync) syncPeers() []*peers.Peer {
+	result := []*peers.Peer{}
+	for _, pe := range s.peers.ConnectedPeers() {
+		if canSync(pe) {
+			result = append(result, pe)
+		}
+	}
+	return result
+}
+
+func (s *Sync) bestPeer() *peers.Peer {
+	var best *peers.Peer
+	for _, pe := range s.syncPeers() {
+		gs := pe.GraphState()
+		if gs == nil {
+			continue
+		}
+		if best == nil || gs.GetMainOrder() > best.GraphState().GetMainOrder() {
+			best = pe
+		}
+	}
+	return best
+}
+
+// SyncPeer returns the peer which the light node is syncing from.
+func (s *Sync) SyncPeer() *peers.Peer {
+	s.syncPeerMu.RLock()
+	defer s.syncPeerMu.RUnlock()
+
+	return s.syncPeer
+}
+
+func (s *Sync) sync() {
+	pe := s.bestPeer()
+	if pe == nil {
+		return
+	}
+	if uint64(pe.GraphState().GetMainOrder()) <= s.chain.TipOrder() {
+		return
+	}
+	s.syncPeerMu.Lock()
+	s.syncPeer = pe
+	s.syncPeerMu.Unlock()
+
+	if err := s.prepare(pe); err != nil {
+		log.Warn(fmt.Sprintf("Failed to prepare the sync peer %s:%v", pe.IDWithAddress(), err))
+		s.Disconnect(pe.GetID())
+		return
+	}
+	for atomic.LoadInt32(&s.running) == 1 {
+		added, err := s.syncHeaders(pe)
+		if err != nil {
+			log.Warn(fmt.Sprintf("Failed to sync headers from %s:%v", pe.IDWithAddress(), err))
+			return
+		}
+		if added <= 0 {
+			break
+		}
+	}
+	log.Debug(fmt.Sprintf("Light sync finished: tip order=%d", s.chain.TipOrder()))
+}
+
+// prepare loads the filter to the peer and makes sure the peer knows all the
+// checkpoints. The header chain pins the checkpoints to their layers when the
+// headers are connected.
+func (s *Sync) prepare(pe *peers.Peer) error {
+	if _, ok := s.filterPeers.Load(pe.GetID()); ok {
+		return nil
+	}
+	if s.filter != nil {
+		fl := s.filter.MsgFilterLoad()
+		err := s.sendNoResponse(pe, synch.RPCFilterLoad, &pb.FilterLoadRequest{
+			Filter:    fl.Filter,
+			HashFuncs: uint64(fl.HashFuncs),
+			Tweak:     uint64(fl.Tweak),
+			Flags:     uint64(fl.Flags),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cps := params.ActiveNetParams.Checkpoints
+	if len(cps) > 0 {
+		hashes := make([]*hash.Hash, 0, len(cps))
+		for _, cp := range cps {
+			hashes = append(hashes, cp.Hash)
+		}
+		mbs, err := s.getMerkleBlocks(pe, hashes)
+		if err != nil {
+			return fmt.Errorf("checkpoints: %v", err)
+		}
+		for i, mb := range mbs {
+			header, err := DecodeHeader(mb.Header)
+			if err != nil {
+				return err
+			}
+			if h := header.BlockHash(); !h.IsEqual(hashes[i]) {
+				return fmt.Errorf("checkpoint %s mismatch", hashes[i])
+			}
+		}
+	}
+	s.filterPeers.Store(pe.GetID(), struct{}{})
+	return nil
+}
+
+// syncHeaders gets the next headers in MeerDAG order from the peer and
+// connects them to the header chain.
+func (s *Sync) syncHeaders(pe *peers.Peer) (int, error) {
+	locator := s.chain.Locator()
+	ret, err := s.request(pe, synch.RPCSyncDAG, &pb.SyncDAG{
+		MainLocator: changeHashsToPBHashs(locator),
+		GraphState:  s.graphState(),
+	}, &pb.SubDAG{})
+	if err != nil {
+		return 0, err
+	}
+	sd := ret.(*pb.SubDAG)
+	if sd.SyncPoint == nil || sd.GraphState == nil {
+		return 0, fmt.Errorf("invalid sub DAG")
+	}
+	pe.UpdateGraphState(sd.GraphState)
+	point, err := hash.NewHash(sd.SyncPoint.Hash)
+	if err != nil {
+		return 0, err
+	}
+	_, pointOrder, err := s.chain.Header(point)
+	if err != nil {
+		return 0, fmt.Errorf("unknown sync point %s", point)
+	}
+	// The blocks after the main order of the peer are not ordered yet.
+	ordered := int64(sd.GraphState.MainOrder) - int64(pointOrder)
+	if ordered > int64(len(sd.Blocks)) {
+		ordered = int64(len(sd.Blocks))
+	}
+	if ordered <= 0 {
+		return 0, nil
+	}
+	blocks := changePBHashsToHashs(sd.Blocks[:ordered])
+	hds := make([]*HeaderData, 0, len(blocks))
+	for start := 0; start < len(blocks); start += maxMerkleBlocksPerMsg {
+		end := start + maxMerkleBlocksPerMsg
+		if end > len(blocks) {
+			end = len(blocks)
+		}
+		mbs, err := s.getMerkleBlocks(pe, blocks[start:end])
+		if err != nil {
+			return 0, err
+		}
+		for i, mb := range mbs {
+			hd, err := processMerkleBlock(mb, blocks[start+i])
+			if err != nil {
+				return 0, err
+			}
+			hds = append(hds, hd)
+		}
+	}
+	// The headers are connected at once, so they only replace the blocks
+	// at the same orders if they have more work.
+	added, err := s.chain.ConnectHeaders(pointOrder+1, hds)
+	for i := 0; i < added; i++ {
+		if len(hds[i].Txs) > 0 {
+			log.Info(fmt.Sprintf("Found %d transactions in block %s", len(hds[i].Txs), hds[i].Header.BlockHash()))
+		}
+	}
+	if err != nil {
+		return added, err
+	}
+	log.Info(fmt.Sprintf("Synced light headers: tip order=%d peer order=%d", s.chain.TipOrder(), sd.GraphState.MainOrder))
+	return added, nil
+}
+
+// processMerkleBlock verifies the merkle block and returns its header with
+// the parents and the matched transactions.
+func processMerkleBlock(mb *pb.MerkleBlock, expect *hash.Hash) (*HeaderData, error) {
+	header, err := DecodeHeader(mb.Header)
+	if err != nil {
+		return nil, err
+	}
+	if h := header.BlockHash(); !h.IsEqual(expect) {
+		return nil, fmt.Errorf("merkle block %s is not the requested %s", h, expect)
+	}
+	msg := &types.MsgMerkleBlock{
+		Header:       *header,
+		Transactions: uint32(mb.Transactions),
+		Hashes:       changePBHashsToHashs(mb.Hashes),
+		Flags:        mb.Flags,
+	}
+	matches, err := bloom.ExtractMatches(msg)
+	if err != nil {
+		return nil, fmt.Errorf("merkle block %s: %v", expect, err)
+	}
+	return &HeaderData{
+		Header:  header,
+		Parents: changePBHashsToHashs(mb.Parents),
+		Txs:     matches,
+	}, nil
+}
+
+func (s *Sync) getMerkleBlocks(pe *peers.Peer, blocks []*hash.Hash) ([]*pb.MerkleBlock, error) {
+	ret, err := s.request(pe, synch.RPCGetMerkleBlocks,
+		&pb.MerkleBlockRequest{Hashes: changeHashsToPBHashs(blocks)}, &pb.MerkleBlockResponse{})
+	if err != nil {
+		return nil, err
+	}
+	mbs := ret.(*pb.MerkleBlockResponse).Data
+	if len(mbs) != len(blocks) {
+		return nil, fmt.Errorf("requested %d merkle blocks, but got %d", len(blocks), len(mbs))
+	}
+	return mbs, nil
+}
+
+// request sends the message to the peer and decodes the response into rsp.
+func (s *Sync) request(pe *peers.Peer, topic string, msg interface{}, rsp interface{}) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(s.Context(), synch.ReqTimeout)
+	defer cancel()
+
+	stream, e := synch.Send(ctx, s, msg, topic, pe)
+	if e != nil && !e.Code.IsSuccess() {
+		return nil, e.ToError()
+	}
+	defer stream.Close()
+
+	e = synch.ReadRspCode(stream, s)
+	if !e.Code.IsSuccess() {
+		return nil, e.ToError()
+	}
+	if err := synch.DecodeMessage(stream, s, rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// sendNoResponse sends a message which the peer does not respond.
+func (s *Sync) sendNoResponse(pe *peers.Peer, topic string, msg interface{}) error {
+	ctx, cancel := context.WithTimeout(s.Context(), synch.ReqTimeout)
+	defer cancel()
+
+	stream, e := synch.Send(ctx, s, msg, topic, pe)
+	if e != nil && !e.Code.IsSuccess() {
+		return e.ToError()
+	}
+	return stream.Close()
+}
+
+func (s *Sync) Encoding() encoder.NetworkEncoding {
+	return s.encoding
+}
+
+func (s *Sync) Host() host.Host {
+	return s.host
+}
+
+func (s *Sync) Disconnect(pid peer.ID) error {
+	return s.host.Network().ClosePeer(pid)
+}
+
+func (s *Sync) IncreaseBytesSent(pid peer.ID, size int) {
+	pe := s.peers.Get(pid)
+	if pe != nil {
+		pe.IncreaseBytesSent(size)
+	}
+}
+
+func (s *Sync) IncreaseBytesRecv(pid peer.ID, size int) {
+	pe := s.peers.Get(pid)
+	if pe != nil {
+		pe.IncreaseBytesRecv(size)
+	}
+}
+
+func (s *Sync) Peers() *peers.Status {
+	return s.peers
+}
+
+func (s *Sync) IsRunning() bool {
+	return atomic.LoadInt32(&s.running) == 1
+}
+
+func (s *Sync) GetGenesisHash() *hash.Hash {
+	return params.ActiveNetParams.GenesisHash
+}
+
+func (s *Sync) Chain() *HeaderChain {
+	return s.chain
+}
+
+func changeHashsToPBHashs(hs []*hash.Hash) []*pb.Hash {
+	result := make([]*pb.Hash, 0, len(hs))
+	for _, h := range hs {
+		result = append(result, &pb.Hash{Hash: h.Bytes()})
+	}
+	return result
+}
+
+func changePBHashsToHashs(hs []*pb.Hash) []*hash.Hash {
+	result := make([]*hash.Hash, 0, len(hs))
+	for _, h := range hs {
+		ph, err := hash.NewHash(h.Hash)
+		if err != nil {
+			continue
+		}
+		result = append(result, ph)
+	}
+	return result
+}
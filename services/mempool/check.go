@@ -277,22 +277,35 @@ func isDust(txOut *types.TxOutput, minRelayTxFee types.Amount) bool {
 
 // checkPoolDoubleSpend checks whether or not the passed transaction is
 // attempting to spend coins already spent by other transactions in the pool.
-// Note it does not check for double spends against transactions already in the
-// main chain.
+// If it does, we'll check whether each of those transactions are signaling for
+// replacement.  If just one of them isn't, an error is returned.  Otherwise, a
+// boolean is returned signaling that the transaction is a replacement.  Note it
+// does not check for double spends against transactions already in the main
+// chain.
 //
 // This function MUST be called with the mempool lock held (for reads).
-func (mp *TxPool) checkPoolDoubleSpend(tx *types.Tx) error {
+func (mp *TxPool) checkPoolDoubleSpend(tx *types.Tx) (bool, error) {
 	mp.mtx.RLock()
 	defer mp.mtx.RUnlock()
+
+	var isReplacement bool
 	for _, txIn := range tx.Transaction().TxIn {
 		txR, exists := mp.outpoints[txIn.PreviousOut]
-		if exists {
+		if !exists {
+			continue
+		}
+
+		// Reject the transaction if the replacement policy is disabled
+		// or the transactions can not be replaced.
+		if mp.cfg.Policy.RejectReplacement || !isReplaceableType(tx) ||
+			!isReplaceableType(txR) || !mp.signalsReplacement(txR, nil) {
 			str := fmt.Sprintf("transaction %v in the pool "+
 				"already spends the same coins", txR.Hash())
-			return txRuleError(message.RejectDuplicate, str)
+			return false, txRuleError(message.RejectDuplicate, str)
 		}
+		isReplacement = true
 	}
-	return nil
+	return isReplacement, nil
 }
 
 // checkInputsStandard performs a series of checks on a transaction's inputs
@@ -205,6 +205,18 @@ func (ef *FeeEstimator) ObserveTransaction(t *TxDesc) {
 	}
 }
 
+// RemoveTransaction is called when an observed transaction is evicted from
+// the mempool without being mined, such as when it is replaced.  The
+// transaction will never be mined, so it must not stay in the observed set.
+func (ef *FeeEstimator) RemoveTransaction(h *hash.Hash) {
+	ef.mtx.Lock()
+	defer ef.mtx.Unlock()
+
+	if o, ok := ef.observed[*h]; ok && o.mined == UnminedHeight {
+		delete(ef.observed, *h)
+	}
+}
+
 // RegisterBlock informs the fee estimator of a new block to take into account.
 func (ef *FeeEstimator) RegisterBlock(block *types.SerializedBlock, mainheight uint) error {
 	ef.mtx.Lock()
@@ -19,9 +19,12 @@ import (
 	"github.com/Qitmeer/qng/core/blockchain"
 	"github.com/Qitmeer/qng/core/blockchain/opreturn"
 	"github.com/Qitmeer/qng/core/blockchain/utxo"
+	"github.com/Qitmeer/qng/core/event"
 	"github.com/Qitmeer/qng/core/message"
 	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/engine/txscript"
 	"github.com/Qitmeer/qng/meerevm/meer"
+	"github.com/Qitmeer/qng/params"
 )
 
 // TxPool is used as a source of transactions that need to be mined into blocks
@@ -71,6 +74,20 @@ type TxDesc struct {
 	// StartingPriority is the priority of the transaction when it was added
 	// to the pool.
 	StartingPriority float64
+
+	// AncestorCount, AncestorSize and AncestorFees are the number of
+	// transactions, the total serialized size and the total fees of the
+	// transaction along with all of its unconfirmed ancestors in the pool.
+	AncestorCount int64
+	AncestorSize  int64
+	AncestorFees  int64
+
+	// DescendantCount, DescendantSize and DescendantFees are the number of
+	// transactions, the total serialized size and the total fees of the
+	// transaction along with all of its descendants in the pool.
+	DescendantCount int64
+	DescendantSize  int64
+	DescendantFees  int64
 }
 
 // TxDescs returns a slice of descriptors for all the transactions in the pool.
@@ -136,13 +153,26 @@ func (mp *TxPool) removeTransaction(theTx *types.Tx, removeRedeemers bool) {
 		if aIndex := mp.cfg.IndexManager.AddrIndex(); aIndex != nil {
 			aIndex.RemoveUnconfirmedTx(txHash)
 		}
-		// Mark the referenced outpoints as unspent by the pool.
+		// Mark the referenced outpoints as unspent by the pool, and update
+		// the package statistics of the remaining ancestors and
+		// descendants.
 		mp.mtx.Lock()
+		ancestors := mp.txAncestors(theTx, nil)
+		descendants := mp.txDescendants(theTx, nil)
 		for _, txIn := range txDesc.Tx.Transaction().TxIn {
 			delete(mp.outpoints, txIn.PreviousOut)
 		}
 		delete(mp.pool, *txHash)
+		for _, ancestor := range ancestors {
+			mp.updateDescendantStats(ancestor)
+		}
+		for h := range descendants {
+			if descendant, ok := mp.pool[h]; ok {
+				mp.updateAncestorStats(descendant)
+			}
+		}
 		mp.mtx.Unlock()
+		mempoolBytes.Dec(int64(txDesc.Tx.Tx.SerializeSize()))
 
 		// stats daily tx count
 		if mp.LastUpdated().Day() != time.Now().Day() {
@@ -150,6 +180,10 @@ func (mp *TxPool) removeTransaction(theTx *types.Tx, removeRedeemers bool) {
 		}
 		atomic.StoreInt64(&mp.lastUpdated, roughtime.Now().Unix())
 		log.Trace(fmt.Sprintf("TxPool:remove tx %s", txHash))
+
+		if mp.cfg.Events != nil {
+			go mp.cfg.Events.Send(event.New(&types.TxPoolRemoved{Tx: theTx}))
+		}
 	}
 }
 
@@ -189,12 +223,16 @@ func (mp *TxPool) RemoveDoubleSpends(tx *types.Tx) {
 		types.IsTokenNewTx(tx.Tx) ||
 		types.IsTokenRenewTx(tx.Tx) ||
 		types.IsTokenInvalidateTx(tx.Tx) ||
-		types.IsTokenValidateTx(tx.Tx) {
+		types.IsTokenValidateTx(tx.Tx) ||
+		types.IsStakebaseTx(tx.Tx) {
 		return
 	}
 	// Protect concurrent access.
 	mp.procmtx.Lock()
-	for _, txIn := range tx.Transaction().TxIn {
+	for txIdx, txIn := range tx.Transaction().TxIn {
+		if txIdx == 0 && types.IsStakeTx(tx.Tx) {
+			continue
+		}
 		mp.mtx.RLock()
 		txRedeemer, ok := mp.outpoints[txIn.PreviousOut]
 		mp.mtx.RUnlock()
@@ -235,6 +273,7 @@ func (mp *TxPool) addTransaction(utxoView *utxo.UtxoViewpoint,
 		mp.mtx.Lock()
 		mp.pool[*tx.Hash()] = txD
 		mp.mtx.Unlock()
+		mempoolBytes.Inc(int64(tx.Tx.SerializeSize()))
 	}
 
 	if !types.IsCrossChainVMTx(tx.Tx) &&
@@ -246,13 +285,23 @@ func (mp *TxPool) addTransaction(utxoView *utxo.UtxoViewpoint,
 
 		mp.mtx.Lock()
 		for txIdx, txIn := range msgTx.TxIn {
-			if txIdx == 0 && (types.IsTokenMintTx(tx.Tx) || types.IsTokenUnmintTx(tx.Tx)) {
+			if txIdx == 0 && (types.IsTokenMintTx(tx.Tx) || types.IsTokenUnmintTx(tx.Tx) || types.IsStakeTx(tx.Tx)) {
 				continue
 			}
 			mp.outpoints[txIn.PreviousOut] = tx
 		}
 		mp.mtx.Unlock()
 	}
+
+	if !types.IsCrossChainVMTx(tx.Tx) {
+		mp.mtx.Lock()
+		mp.updatePackageStats(txD)
+		mp.mtx.Unlock()
+
+		if mp.cfg.Events != nil {
+			go mp.cfg.Events.Send(event.New(&types.TxPoolAdded{Tx: tx, Fee: fee}))
+		}
+	}
 	if mp.LastUpdated().Day() == time.Now().Day() {
 		newDailyTxCount.Inc(1)
 	} else {
@@ -284,10 +333,11 @@ func (mp *TxPool) AddTransaction(tx *types.Tx, height uint64, fee int64) {
 
 // maybeAcceptTransaction is the internal function which implements the public
 // MaybeAcceptTransaction.  See the comment for MaybeAcceptTransaction for
-// more details.
+// more details.  When dryRun is set, the transaction is only checked and the
+// pool isn't changed, the returned descriptor is nil.
 //
 // This function MUST be called with the mempool lock held (for writes).
-func (mp *TxPool) maybeAcceptTransaction(tx *types.Tx, isNew, rateLimit, allowHighFees bool) ([]*hash.Hash, *TxDesc, error) {
+func (mp *TxPool) maybeAcceptTransaction(tx *types.Tx, isNew, rateLimit, allowHighFees, dryRun bool) ([]*hash.Hash, *TxDesc, error) {
 	msgTx := tx.Transaction()
 	txHash := tx.Hash()
 	start := time.Now()
@@ -385,7 +435,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *types.Tx, isNew, rateLimit, allowHi
 	// at this point.  There is a more in-depth check that happens later
 	// after fetching the referenced transaction inputs from the main chain
 	// which examines the actual spend data and prevents double spends.
-	err = mp.checkPoolDoubleSpend(tx)
+	isReplacement, err := mp.checkPoolDoubleSpend(tx)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -397,6 +447,13 @@ func (mp *TxPool) maybeAcceptTransaction(tx *types.Tx, isNew, rateLimit, allowHi
 	if err != nil {
 		return nil, nil, err
 	}
+	taproot, err := mp.cfg.BC.IsDeploymentActive(params.DeploymentTaproot)
+	if err != nil {
+		return nil, nil, err
+	}
+	if taproot {
+		flags |= txscript.ScriptVerifyTaproot
+	}
 	// Don't allow transactions with fees too low to get into a mined block.
 	serializedSize := int64(msgTx.SerializeSize())
 
@@ -438,6 +495,55 @@ func (mp *TxPool) maybeAcceptTransaction(tx *types.Tx, isNew, rateLimit, allowHi
 			return nil, nil, err
 		}
 
+		if dryRun {
+			return nil, nil, nil
+		}
+		// Add to transaction pool.
+		txD := mp.addTransaction(utxoView, tx, nextBlockHeight, 0)
+
+		log.Debug("Accepted transaction", "txHash", txHash, "pool size", len(mp.pool))
+
+		return nil, txD, nil
+	} else if types.IsStakeTx(tx.Tx) {
+		if types.IsStakebaseTx(tx.Tx) {
+			str := fmt.Sprintf("transaction %v is an individual stakebase",
+				txHash)
+			return nil, nil, txRuleError(message.RejectInvalid, str)
+		}
+		err = mp.cfg.BC.CheckStakeTransaction(tx, uint64(nextBlockHeight))
+		if err != nil {
+			return nil, nil, err
+		}
+		utxoView := utxo.NewUtxoViewpoint()
+		if types.IsStakeLockTx(tx.Tx) {
+			utxoView, err = mp.fetchInputUtxos(tx)
+			if err != nil {
+				return nil, nil, err
+			}
+			err = mp.cfg.BC.CheckStakeTransactionInputs(tx, utxoView)
+			if err != nil {
+				return nil, nil, err
+			}
+		} else if types.IsStakeDisposeTx(tx.Tx) {
+			pkscript, err := mp.cfg.BC.GetCurStakeHolder(&tx.Tx.TxIn[0].PreviousOut.Hash)
+			if err != nil {
+				return nil, nil, err
+			}
+			utxoView.AddTokenTxOut(tx.Tx.TxIn[0].PreviousOut, pkscript)
+		}
+
+		err = blockchain.ValidateTransactionScripts(tx, utxoView, flags,
+			mp.cfg.SigCache, int64(nextBlockHeight))
+		if err != nil {
+			if cerr, ok := err.(blockchain.RuleError); ok {
+				return nil, nil, chainRuleError(cerr)
+			}
+			return nil, nil, err
+		}
+
+		if dryRun {
+			return nil, nil, nil
+		}
 		// Add to transaction pool.
 		txD := mp.addTransaction(utxoView, tx, nextBlockHeight, 0)
 
@@ -490,6 +596,9 @@ func (mp *TxPool) maybeAcceptTransaction(tx *types.Tx, isNew, rateLimit, allowHi
 			}
 		}
 
+		if dryRun {
+			return nil, nil, nil
+		}
 		// Add to transaction pool.
 		txD := mp.addTransaction(utxoView, tx, nextBlockHeight, fee)
 
@@ -499,6 +608,11 @@ func (mp *TxPool) maybeAcceptTransaction(tx *types.Tx, isNew, rateLimit, allowHi
 		if mp.cfg.BC.HasTx(txHash) {
 			return nil, nil, fmt.Errorf("Already have transaction %v", txHash)
 		}
+		// The meerevm transaction is checked by the meer pool when it's added.
+		if dryRun {
+			str := fmt.Sprintf("meerevm transaction %v can't be checked without adding it", txHash)
+			return nil, nil, txRuleError(message.RejectNonstandard, str)
+		}
 		fee, err := mp.cfg.BC.MeerChain().(*meer.MeerChain).MeerPool().AddTx(tx, false)
 		if err != nil {
 			return nil, nil, err
@@ -680,12 +794,14 @@ func (mp *TxPool) maybeAcceptTransaction(tx *types.Tx, isNew, rateLimit, allowHi
 				"by the rate limiter due to low fees", txHash)
 			return nil, nil, txRuleError(message.RejectInsufficientFee, str)
 		}
-		oldTotal := mp.pennyTotal
+		if !dryRun {
+			oldTotal := mp.pennyTotal
 
-		mp.pennyTotal += float64(serializedSize)
-		log.Trace("rate limit: curTotal %v, nextTotal: %v, "+
-			"limit %v", oldTotal, mp.pennyTotal,
-			mp.cfg.Policy.FreeTxRelayLimit*10*1000)
+			mp.pennyTotal += float64(serializedSize)
+			log.Trace("rate limit: curTotal %v, nextTotal: %v, "+
+				"limit %v", oldTotal, mp.pennyTotal,
+				mp.cfg.Policy.FreeTxRelayLimit*10*1000)
+		}
 	}
 
 	// Check whether allowHighFees is set to false (default), if so, then make
@@ -704,9 +820,25 @@ func (mp *TxPool) maybeAcceptTransaction(tx *types.Tx, isNew, rateLimit, allowHi
 		}
 	}
 
+	// If the transaction has any conflicts and we've made it this far,
+	// then we're processing a potential replacement.
+	var replacedTxs map[hash.Hash]*types.Tx
+	if isReplacement {
+		replacedTxs, err = mp.validateReplacement(tx, txFee.Value)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Don't allow the transaction to extend the chains of unconfirmed
+	// transactions beyond the ancestor and descendant limits.
+	err = mp.checkPackageLimits(tx)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Verify crypto signatures for each input and reject the transaction if
 	// any don't verify.
-
 	err = blockchain.ValidateTransactionScripts(tx, utxoView, flags,
 		mp.cfg.SigCache, int64(nextBlockHeight))
 	if err != nil {
@@ -716,6 +848,17 @@ func (mp *TxPool) maybeAcceptTransaction(tx *types.Tx, isNew, rateLimit, allowHi
 		return nil, nil, err
 	}
 
+	if dryRun {
+		return nil, nil, nil
+	}
+
+	// Now that we've deemed the transaction as valid, we can add it to the
+	// mempool.  If it ended up replacing any transactions, we'll remove them
+	// first.
+	if len(replacedTxs) > 0 {
+		mp.replaceTransactions(tx, replacedTxs)
+	}
+
 	// Add to transaction pool.
 	txD := mp.addTransaction(utxoView, tx, nextBlockHeight, txFee.Value)
 
@@ -780,7 +923,7 @@ func (mp *TxPool) ProcessTransaction(tx *types.Tx, allowOrphan, rateLimit, allow
 	// Potentially accept the transaction to the memory pool.
 	var missingParents []*hash.Hash
 	missingParents, txD, err := mp.maybeAcceptTransaction(tx, true, rateLimit,
-		allowHighFees)
+		allowHighFees, false)
 	if err != nil {
 		return nil, err
 	}
@@ -827,6 +970,30 @@ func (mp *TxPool) ProcessTransaction(tx *types.Tx, allowOrphan, rateLimit, allow
 	return nil, err
 }
 
+// CheckTransaction checks the passed transaction against all of the rules
+// of ProcessTransaction without adding it to the memory pool.  The orphan
+// transactions are rejected.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) CheckTransaction(tx *types.Tx, rateLimit, allowHighFees bool) error {
+	// Protect concurrent access.
+	mp.procmtx.Lock()
+	defer mp.procmtx.Unlock()
+
+	missingParents, _, err := mp.maybeAcceptTransaction(tx, true, rateLimit,
+		allowHighFees, true)
+	if err != nil {
+		return err
+	}
+	if len(missingParents) > 0 {
+		str := fmt.Sprintf("orphan transaction %v references "+
+			"outputs of unknown or fully-spent "+
+			"transaction %v", tx.Hash(), missingParents[0])
+		return txRuleError(message.RejectDuplicate, str)
+	}
+	return nil
+}
+
 // maybeAddOrphan potentially adds an orphan to the orphan pool.
 //
 // This function MUST be called with the mempool lock held (for writes).
@@ -866,7 +1033,7 @@ func (mp *TxPool) maybeAddOrphan(tx *types.Tx) error {
 func (mp *TxPool) MaybeAcceptTransaction(tx *types.Tx, isNew, rateLimit bool) ([]*hash.Hash, error) {
 	// Protect concurrent access.
 	mp.procmtx.Lock()
-	hashes, _, err := mp.maybeAcceptTransaction(tx, isNew, rateLimit, true)
+	hashes, _, err := mp.maybeAcceptTransaction(tx, isNew, rateLimit, true, false)
 	mp.procmtx.Unlock()
 
 	return hashes, err
@@ -910,6 +1077,7 @@ func (mp *TxPool) removeOrphan(txHash *hash.Hash) {
 // This function is safe for concurrent access.
 func (mp *TxPool) RemoveOrphan(tx *types.Tx) {
 	if types.IsTokenTx(tx.Tx) ||
+		types.IsStakeTx(tx.Tx) ||
 		types.IsCrossChainImportTx(tx.Tx) ||
 		opreturn.IsMeerEVMTx(tx.Tx) {
 		return
@@ -963,7 +1131,7 @@ func (mp *TxPool) processOrphans(h *hash.Hash) []*TxDesc {
 			// Potentially accept the transaction into the
 			// transaction pool.
 			missingParents, txD, err := mp.maybeAcceptTransaction(tx,
-				true, true, true)
+				true, true, true, false)
 			if err != nil {
 				// TODO: Remove orphans that depend on this
 				// failed transaction.
@@ -1082,6 +1250,14 @@ func (mp *TxPool) FetchTransaction(txHash *hash.Hash) (*types.Tx, error) {
 	return nil, er
 }
 
+// MeerTxs returns the transactions of the meer pool, including the ones which
+// aren't in the template of the meer pool yet.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) MeerTxs() []*types.Tx {
+	return mp.cfg.BC.MeerChain().(*meer.MeerChain).MeerPool().GetRemoteTxs()
+}
+
 func (mp *TxPool) FetchTransactions(txHashs []*hash.Hash) ([]*types.Tx, error) {
 	// Protect concurrent access.
 	result := []*types.Tx{}
@@ -1201,6 +1377,18 @@ func (mp *TxPool) IsTransactionInPool(hash *hash.Hash, all bool) bool {
 	return mp.isTransactionInPool(hash, all)
 }
 
+// CheckSpend checks whether the passed outpoint is already spent by a
+// transaction in the mempool. If that's the case the spending transaction
+// will be returned, if not nil will be returned.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) CheckSpend(op types.TxOutPoint) *types.Tx {
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+
+	return mp.outpoints[op]
+}
+
 // isOrphanInPool returns whether or not the passed transaction already exists
 // in the orphan pool.
 //
@@ -15,4 +15,6 @@ var (
 	mempoolHaveTransaction          = metrics.NewRegisteredTimer("mempool/haveTransaction", nil)
 	newDailyTxCount                 = metrics.NewRegisteredGauge("mempool/addtx", nil)
 	newDailyAllTxCount              = metrics.NewRegisteredGauge("mempool/alltx", nil)
+	mempoolBytes                    = metrics.NewRegisteredGauge("mempool/bytes", nil)
+	mempoolReplacedTxs              = metrics.NewRegisteredCounter("mempool/replaced", nil)
 )
@@ -0,0 +1,143 @@
+// Copyright (c) 2017-2018 The qitmeer developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"fmt"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/core/message"
+	"github.com/Qitmeer/qng/core/types"
+)
+
+// txAncestors returns the descriptors of all of the unconfirmed transactions
+// in the pool whose outputs are spent by the transaction, recursively.
+//
+// The cache is optional and can be used to avoid visiting the same ancestors
+// more than once.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) txAncestors(tx *types.Tx, cache map[hash.Hash]*TxDesc) map[hash.Hash]*TxDesc {
+	if cache == nil {
+		cache = make(map[hash.Hash]*TxDesc)
+	}
+	for _, txIn := range tx.Tx.TxIn {
+		h := txIn.PreviousOut.Hash
+		if _, visited := cache[h]; visited {
+			continue
+		}
+		parent, ok := mp.pool[h]
+		if !ok {
+			continue
+		}
+		cache[h] = parent
+		mp.txAncestors(parent.Tx, cache)
+	}
+	return cache
+}
+
+// updateAncestorStats recalculates the ancestor statistics of the transaction
+// from its unconfirmed ancestors in the pool.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) updateAncestorStats(txD *TxDesc) {
+	txD.AncestorCount = 1
+	txD.AncestorSize = int64(txD.Tx.Tx.SerializeSize())
+	txD.AncestorFees = txD.Fee
+	for _, ancestor := range mp.txAncestors(txD.Tx, nil) {
+		txD.AncestorCount++
+		txD.AncestorSize += int64(ancestor.Tx.Tx.SerializeSize())
+		txD.AncestorFees += ancestor.Fee
+	}
+}
+
+// updateDescendantStats recalculates the descendant statistics of the
+// transaction from its descendants in the pool.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) updateDescendantStats(txD *TxDesc) {
+	txD.DescendantCount = 1
+	txD.DescendantSize = int64(txD.Tx.Tx.SerializeSize())
+	txD.DescendantFees = txD.Fee
+	for h := range mp.txDescendants(txD.Tx, nil) {
+		descendant, ok := mp.pool[h]
+		if !ok {
+			continue
+		}
+		txD.DescendantCount++
+		txD.DescendantSize += int64(descendant.Tx.Tx.SerializeSize())
+		txD.DescendantFees += descendant.Fee
+	}
+}
+
+// updatePackageStats recalculates the statistics of the transaction, the
+// descendant statistics of its ancestors and the ancestor statistics of its
+// descendants.  It is called once the transaction was added to the pool.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) updatePackageStats(txD *TxDesc) {
+	mp.updateAncestorStats(txD)
+	mp.updateDescendantStats(txD)
+	for _, ancestor := range mp.txAncestors(txD.Tx, nil) {
+		mp.updateDescendantStats(ancestor)
+	}
+	for h := range mp.txDescendants(txD.Tx, nil) {
+		if descendant, ok := mp.pool[h]; ok {
+			mp.updateAncestorStats(descendant)
+		}
+	}
+}
+
+// checkPackageLimits ensures that adding the transaction to the pool keeps
+// the chains of unconfirmed transactions within the ancestor and descendant
+// limits of the policy.  A zero limit is not enforced.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) checkPackageLimits(tx *types.Tx) error {
+	policy := &mp.cfg.Policy
+	txHash := tx.Hash()
+	txSize := int64(tx.Tx.SerializeSize())
+
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+
+	ancestors := mp.txAncestors(tx, nil)
+	ancestorCount := int64(len(ancestors)) + 1
+	if policy.MaxAncestorCount > 0 && ancestorCount > policy.MaxAncestorCount {
+		str := fmt.Sprintf("transaction %v has too many unconfirmed "+
+			"ancestors: %d, max is %d", txHash, ancestorCount,
+			policy.MaxAncestorCount)
+		return txRuleError(message.RejectNonstandard, str)
+	}
+
+	ancestorSize := txSize
+	for _, ancestor := range ancestors {
+		ancestorSize += int64(ancestor.Tx.Tx.SerializeSize())
+	}
+	if policy.MaxAncestorSize > 0 && ancestorSize > policy.MaxAncestorSize {
+		str := fmt.Sprintf("transaction %v has too large unconfirmed "+
+			"ancestors: %d bytes, max is %d", txHash, ancestorSize,
+			policy.MaxAncestorSize)
+		return txRuleError(message.RejectNonstandard, str)
+	}
+
+	for ancestorHash, ancestor := range ancestors {
+		if policy.MaxDescendantCount > 0 &&
+			ancestor.DescendantCount+1 > policy.MaxDescendantCount {
+			str := fmt.Sprintf("transaction %v would exceed the "+
+				"descendant count limit of %v: max is %d", txHash,
+				ancestorHash, policy.MaxDescendantCount)
+			return txRuleError(message.RejectNonstandard, str)
+		}
+		if policy.MaxDescendantSize > 0 &&
+			ancestor.DescendantSize+txSize > policy.MaxDescendantSize {
+			str := fmt.Sprintf("transaction %v would exceed the "+
+				"descendant size limit of %v: max is %d bytes", txHash,
+				ancestorHash, policy.MaxDescendantSize)
+			return txRuleError(message.RejectNonstandard, str)
+		}
+	}
+	return nil
+}
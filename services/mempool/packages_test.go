@@ -0,0 +1,54 @@
+// Copyright (c) 2017-2018 The qitmeer developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"testing"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/core/types"
+)
+
+func TestPackageStats(t *testing.T) {
+	mp := New(&Config{})
+	parent := newTestTx(types.MaxTxInSequenceNum, 1, types.NewOutPoint(&hash.Hash{0x04}, 0))
+	child := newTestTx(types.MaxTxInSequenceNum, 1, types.NewOutPoint(parent.Hash(), 0))
+	grandchild := newTestTx(types.MaxTxInSequenceNum, 1, types.NewOutPoint(child.Hash(), 0))
+	for i, tx := range []*types.Tx{parent, child, grandchild} {
+		addTestTx(mp, tx, int64(i+1)*1000)
+		mp.updatePackageStats(mp.pool[*tx.Hash()])
+	}
+	size := int64(parent.Tx.SerializeSize() + child.Tx.SerializeSize() +
+		grandchild.Tx.SerializeSize())
+
+	desc := mp.pool[*grandchild.Hash()]
+	if desc.AncestorCount != 3 || desc.AncestorFees != 6000 || desc.AncestorSize != size {
+		t.Errorf("got ancestor stats %d/%d/%d, want 3/6000/%d",
+			desc.AncestorCount, desc.AncestorFees, desc.AncestorSize, size)
+	}
+	desc = mp.pool[*parent.Hash()]
+	if desc.DescendantCount != 3 || desc.DescendantFees != 6000 || desc.DescendantSize != size {
+		t.Errorf("got descendant stats %d/%d/%d, want 3/6000/%d",
+			desc.DescendantCount, desc.DescendantFees, desc.DescendantSize, size)
+	}
+
+	tx := newTestTx(types.MaxTxInSequenceNum, 1, types.NewOutPoint(grandchild.Hash(), 0))
+	if err := mp.checkPackageLimits(tx); err != nil {
+		t.Fatalf("rejected without limits: %v", err)
+	}
+	mp.cfg.Policy.MaxAncestorCount = 3
+	if err := mp.checkPackageLimits(tx); err == nil {
+		t.Fatalf("accepted a fourth ancestor while the limit is 3")
+	}
+	mp.cfg.Policy.MaxAncestorCount = 4
+	mp.cfg.Policy.MaxDescendantCount = 3
+	if err := mp.checkPackageLimits(tx); err == nil {
+		t.Fatalf("accepted a fourth descendant while the limit is 3")
+	}
+	mp.cfg.Policy.MaxDescendantCount = 4
+	if err := mp.checkPackageLimits(tx); err != nil {
+		t.Fatalf("rejected within the limits: %v", err)
+	}
+}
@@ -90,6 +90,25 @@ const (
 	// when it has not yet been mined into a block.
 	UnminedLayer = 0x7fffffff
 
+	// DefaultMaxAncestorCount is the default maximum number of unconfirmed
+	// transactions in the pool, including the transaction itself, which a
+	// transaction can descend from.
+	DefaultMaxAncestorCount = 25
+
+	// DefaultMaxAncestorSize is the default maximum total size in bytes of a
+	// transaction along with all of its unconfirmed ancestors in the pool.
+	DefaultMaxAncestorSize = 101000
+
+	// DefaultMaxDescendantCount is the default maximum number of
+	// transactions in the pool, including the transaction itself, which can
+	// descend from an unconfirmed transaction.
+	DefaultMaxDescendantCount = 25
+
+	// DefaultMaxDescendantSize is the default maximum total size in bytes of
+	// an unconfirmed transaction along with all of its descendants in the
+	// pool.
+	DefaultMaxDescendantSize = 101000
+
 	// MinHighPriority is the minimum priority value that allows a
 	// transaction to be considered high priority.
 	MinHighPriority = types.AtomsPerCoin * 144.0 / 250
@@ -113,6 +132,11 @@ type Policy struct {
 	// network. Otherwise, all non-standard transactions will be rejected.
 	AcceptNonStd bool
 
+	// RejectReplacement, if true, rejects accepting replacement
+	// transactions using the Replace-By-Fee (RBF) signaling policy into
+	// the mempool.
+	RejectReplacement bool
+
 	// FreeTxRelayLimit defines the given amount in thousands of bytes
 	// per minute that transactions with no fee are rate limited to.
 	FreeTxRelayLimit float64
@@ -126,6 +150,18 @@ type Policy struct {
 	// of big orphans.
 	MaxOrphanTxSize int
 
+	// MaxAncestorCount and MaxAncestorSize limit the number of transactions
+	// and the total size of a transaction along with its unconfirmed
+	// ancestors in the pool.
+	MaxAncestorCount int64
+	MaxAncestorSize  int64
+
+	// MaxDescendantCount and MaxDescendantSize limit the number of
+	// transactions and the total size of an unconfirmed transaction along
+	// with its descendants in the pool.
+	MaxDescendantCount int64
+	MaxDescendantSize  int64
+
 	// MaxSigOpsPerTx is the maximum number of signature operations
 	// in a single transaction we will relay or mine.  It is a fraction
 	// of the max signature operations for a block.
@@ -0,0 +1,275 @@
+// Copyright (c) 2017-2018 The qitmeer developers
+// Copyright (c) 2013-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"fmt"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/core/blockchain/opreturn"
+	"github.com/Qitmeer/qng/core/event"
+	"github.com/Qitmeer/qng/core/message"
+	"github.com/Qitmeer/qng/core/types"
+)
+
+const (
+	// MaxRBFSequence is the maximum sequence number an input can use to
+	// signal that the transaction spending it can be replaced using the
+	// Replace-By-Fee (RBF) policy.
+	MaxRBFSequence = 0xfffffffd
+
+	// MaxReplacementEvictions is the maximum number of transactions that
+	// can be evicted from the mempool when accepting a transaction
+	// replacement.
+	MaxReplacementEvictions = 100
+)
+
+// isReplaceableType returns whether the type of the transaction takes part
+// in the Replace-By-Fee (RBF) policy.  The special transactions use the
+// sequence of their inputs to carry their type, so only the regular UTXO
+// transactions can signal replacement.
+func isReplaceableType(tx *types.Tx) bool {
+	return types.DetermineTxType(tx.Tx) == types.TxTypeRegular &&
+		!opreturn.IsMeerEVMTx(tx.Tx)
+}
+
+// signalsReplacement determines if a transaction is signaling that it can be
+// replaced using the Replace-By-Fee (RBF) policy.  This policy specifies two
+// ways a transaction can signal that it is replaceable:
+//
+// Explicit signaling: A transaction is considered to have opted in to allowing
+// replacement of itself if any of its inputs have a sequence number less than
+// or equal to MaxRBFSequence.
+//
+// Inherited signaling: Transactions that don't explicitly signal replaceability
+// are replaceable under this policy for as long as any one of their ancestors
+// signals replaceability and remains unconfirmed.
+//
+// The cache is optional and can be used to avoid visiting the same ancestors
+// more than once.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) signalsReplacement(tx *types.Tx, cache map[hash.Hash]struct{}) bool {
+	if cache == nil {
+		cache = make(map[hash.Hash]struct{})
+	}
+	for _, txIn := range tx.Tx.TxIn {
+		if txIn.Sequence <= MaxRBFSequence {
+			return true
+		}
+
+		h := txIn.PreviousOut.Hash
+		if _, visited := cache[h]; visited {
+			continue
+		}
+		cache[h] = struct{}{}
+
+		parent, ok := mp.pool[h]
+		if !ok {
+			continue
+		}
+		if mp.signalsReplacement(parent.Tx, cache) {
+			return true
+		}
+	}
+	return false
+}
+
+// txDescendants returns all of the unconfirmed transactions in the pool which
+// spend the outputs of the transaction, recursively.
+//
+// The cache is optional and can be used to avoid visiting the same
+// descendants more than once.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) txDescendants(tx *types.Tx, cache map[hash.Hash]*types.Tx) map[hash.Hash]*types.Tx {
+	if cache == nil {
+		cache = make(map[hash.Hash]*types.Tx)
+	}
+	prevOut := types.TxOutPoint{Hash: *tx.Hash()}
+	for i := range tx.Tx.TxOut {
+		prevOut.OutIndex = uint32(i)
+		child, ok := mp.outpoints[prevOut]
+		if !ok {
+			continue
+		}
+		if _, visited := cache[*child.Hash()]; visited {
+			continue
+		}
+		cache[*child.Hash()] = child
+		mp.txDescendants(child, cache)
+	}
+	return cache
+}
+
+// txConflicts returns the transactions in the pool which spend the same
+// outputs as the transaction.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) txConflicts(tx *types.Tx) map[hash.Hash]*types.Tx {
+	conflicts := make(map[hash.Hash]*types.Tx)
+	for _, txIn := range tx.Tx.TxIn {
+		conflict, ok := mp.outpoints[txIn.PreviousOut]
+		if !ok {
+			continue
+		}
+		conflicts[*conflict.Hash()] = conflict
+	}
+	return conflicts
+}
+
+// isFeeRateHigher returns whether the fee rate of the first fee and size is
+// strictly higher than the second one.  The sizes are cross multiplied so no
+// precision is lost.
+func isFeeRateHigher(fee int64, size int64, otherFee int64, otherSize int64) bool {
+	return fee*otherSize > otherFee*size
+}
+
+// validateReplacement determines whether a transaction is deemed as a valid
+// replacement of all of its conflicts according to the Replace-By-Fee (RBF)
+// policy.  If it is valid, all of the transactions that must be evicted from
+// the mempool are returned, which are the conflicts and all of their
+// descendants.  The rules are:
+//
+//  1. All of the conflicts signal replacement, explicitly or by inheritance.
+//  2. The replacement does not spend new unconfirmed outputs, only the ones
+//     which were already spent by the conflicts.
+//  3. The fee rate of the replacement is higher than the fee rate of each
+//     conflict.
+//  4. The number of evicted transactions does not exceed
+//     MaxReplacementEvictions.
+//  5. The replacement does not spend the outputs of an evicted transaction.
+//  6. The absolute fee of the replacement is higher than the fees of all of
+//     the evicted transactions.
+//  7. The additional fee pays for the relay of the replacement at the
+//     minimum relay fee rate.
+//
+// This function MUST be called with the process lock held, it takes the
+// mempool lock (for reads) itself so the caller must not hold it.
+func (mp *TxPool) validateReplacement(tx *types.Tx, txFee int64) (map[hash.Hash]*types.Tx, error) {
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+
+	txHash := tx.Hash()
+	txSize := int64(tx.Tx.SerializeSize())
+	conflicts := mp.txConflicts(tx)
+
+	// The set of unconfirmed parents of the conflicts, the replacement can
+	// only spend these unconfirmed outputs.
+	conflictParents := make(map[hash.Hash]struct{})
+	cache := make(map[hash.Hash]struct{})
+	for conflictHash, conflict := range conflicts {
+		if !isReplaceableType(conflict) ||
+			!mp.signalsReplacement(conflict, cache) {
+			str := fmt.Sprintf("transaction %v in the pool already "+
+				"spends the same coins and does not signal replacement",
+				conflictHash)
+			return nil, txRuleError(message.RejectDuplicate, str)
+		}
+
+		conflictDesc, ok := mp.pool[conflictHash]
+		if !ok {
+			continue
+		}
+		conflictSize := int64(conflict.Tx.SerializeSize())
+		if !isFeeRateHigher(txFee, txSize, conflictDesc.Fee, conflictSize) {
+			str := fmt.Sprintf("replacement transaction %v has an "+
+				"insufficient fee rate: needs more than %v/kB, has %v/kB",
+				txHash, conflictDesc.Fee*1000/conflictSize, txFee*1000/txSize)
+			return nil, txRuleError(message.RejectInsufficientFee, str)
+		}
+
+		for _, txIn := range conflict.Tx.TxIn {
+			if _, ok := mp.pool[txIn.PreviousOut.Hash]; ok {
+				conflictParents[txIn.PreviousOut.Hash] = struct{}{}
+			}
+		}
+	}
+
+	for _, txIn := range tx.Tx.TxIn {
+		parentHash := txIn.PreviousOut.Hash
+		if _, ok := mp.pool[parentHash]; !ok {
+			continue
+		}
+		if _, ok := conflictParents[parentHash]; !ok {
+			str := fmt.Sprintf("replacement transaction %v spends new "+
+				"unconfirmed output of %v", txHash, parentHash)
+			return nil, txRuleError(message.RejectNonstandard, str)
+		}
+	}
+
+	// Collect all of the transactions which would be evicted, the
+	// descendants are shared by the cache.
+	evicted := make(map[hash.Hash]*types.Tx)
+	for conflictHash, conflict := range conflicts {
+		evicted[conflictHash] = conflict
+		mp.txDescendants(conflict, evicted)
+		if len(evicted) > MaxReplacementEvictions {
+			str := fmt.Sprintf("replacement transaction %v evicts more "+
+				"transactions than permitted: max is %v", txHash,
+				MaxReplacementEvictions)
+			return nil, txRuleError(message.RejectNonstandard, str)
+		}
+	}
+
+	var evictedFees int64
+	for evictedHash, evictedTx := range evicted {
+		for _, txIn := range tx.Tx.TxIn {
+			if txIn.PreviousOut.Hash == evictedHash {
+				str := fmt.Sprintf("replacement transaction %v spends "+
+					"output of the replaced transaction %v", txHash,
+					evictedHash)
+				return nil, txRuleError(message.RejectInvalid, str)
+			}
+		}
+		if desc, ok := mp.pool[*evictedTx.Hash()]; ok {
+			evictedFees += desc.Fee
+		}
+	}
+
+	if txFee < evictedFees {
+		str := fmt.Sprintf("replacement transaction %v has an insufficient "+
+			"absolute fee: needs %v, has %v", txHash, evictedFees, txFee)
+		return nil, txRuleError(message.RejectInsufficientFee, str)
+	}
+
+	// The additional fee must pay for the relay of the replacement itself,
+	// otherwise the network could be flooded by cheap replacements.
+	minRelayFee := CalcFee(txSize, mp.cfg.Policy.MinRelayTxFee)
+	if txFee-evictedFees < minRelayFee {
+		str := fmt.Sprintf("replacement transaction %v has an insufficient "+
+			"fee increment: needs %v, has %v", txHash, minRelayFee,
+			txFee-evictedFees)
+		return nil, txRuleError(message.RejectInsufficientFee, str)
+	}
+
+	return evicted, nil
+}
+
+// replaceTransactions evicts the transactions replaced by the transaction
+// from the mempool, and announces the replacement.
+//
+// This function MUST be called with the process lock held, removeTransaction
+// takes the mempool lock (for writes) so the caller must not hold it.
+func (mp *TxPool) replaceTransactions(tx *types.Tx, evicted map[hash.Hash]*types.Tx) {
+	replaced := make([]*types.Tx, 0, len(evicted))
+	for _, evictedTx := range evicted {
+		log.Debug(fmt.Sprintf("Replacing transaction %v with %v",
+			evictedTx.Hash(), tx.Hash()))
+		mp.removeTransaction(evictedTx, true)
+		if mp.cfg.FeeEstimator != nil {
+			mp.cfg.FeeEstimator.RemoveTransaction(evictedTx.Hash())
+		}
+		replaced = append(replaced, evictedTx)
+	}
+	mempoolReplacedTxs.Inc(int64(len(replaced)))
+
+	// The event is sent without holding up the mempool, the subscribers may
+	// be waiting for it.
+	if mp.cfg.Events != nil {
+		go mp.cfg.Events.Send(event.New(&types.TxReplaced{Tx: tx, Replaced: replaced}))
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2017-2018 The qitmeer developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"testing"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/core/types"
+)
+
+// newTestTx returns a transaction spending the outputs with the sequence.
+func newTestTx(sequence uint32, outs int, prevOuts ...*types.TxOutPoint) *types.Tx {
+	tx := types.NewTransaction()
+	for _, prevOut := range prevOuts {
+		in := types.NewTxInput(prevOut, nil)
+		in.Sequence = sequence
+		tx.AddTxIn(in)
+	}
+	for i := 0; i < outs; i++ {
+		tx.AddTxOut(types.NewTxOutput(types.Amount{Value: 1e8, Id: types.MEERA}, []byte{0x51}))
+	}
+	return types.NewTx(tx)
+}
+
+// addTestTx adds the transaction to the pool without any validation.
+func addTestTx(mp *TxPool, tx *types.Tx, fee int64) {
+	mp.pool[*tx.Hash()] = &TxDesc{TxDesc: types.TxDesc{Tx: tx, Fee: fee}}
+	for _, txIn := range tx.Tx.TxIn {
+		mp.outpoints[txIn.PreviousOut] = tx
+	}
+}
+
+func TestSignalsReplacement(t *testing.T) {
+	mp := New(&Config{})
+	confirmed := types.NewOutPoint(&hash.Hash{0x01}, 0)
+
+	final := newTestTx(types.MaxTxInSequenceNum, 2, confirmed)
+	addTestTx(mp, final, 1000)
+	if mp.signalsReplacement(final, nil) {
+		t.Fatalf("final transaction signals replacement")
+	}
+
+	optIn := newTestTx(MaxRBFSequence, 1, types.NewOutPoint(final.Hash(), 0))
+	addTestTx(mp, optIn, 1000)
+	if !mp.signalsReplacement(optIn, nil) {
+		t.Fatalf("opt-in transaction does not signal replacement")
+	}
+
+	child := newTestTx(types.MaxTxInSequenceNum, 1, types.NewOutPoint(optIn.Hash(), 0))
+	addTestTx(mp, child, 1000)
+	if !mp.signalsReplacement(child, nil) {
+		t.Fatalf("child of an opt-in transaction does not inherit the signal")
+	}
+
+	descendants := mp.txDescendants(final, nil)
+	if len(descendants) != 2 {
+		t.Fatalf("got %d descendants, want 2", len(descendants))
+	}
+	for _, h := range []*hash.Hash{optIn.Hash(), child.Hash()} {
+		if _, ok := descendants[*h]; !ok {
+			t.Errorf("missing descendant %v", h)
+		}
+	}
+}
+
+func TestCheckPoolDoubleSpend(t *testing.T) {
+	mp := New(&Config{})
+	prevOut := types.NewOutPoint(&hash.Hash{0x02}, 0)
+	addTestTx(mp, newTestTx(MaxRBFSequence, 1, prevOut), 1000)
+
+	replacement := newTestTx(types.MaxTxInSequenceNum, 1, prevOut)
+	isReplacement, err := mp.checkPoolDoubleSpend(replacement)
+	if err != nil || !isReplacement {
+		t.Fatalf("got %v, %v: want a replacement", isReplacement, err)
+	}
+
+	mp.cfg.Policy.RejectReplacement = true
+	if _, err := mp.checkPoolDoubleSpend(replacement); err == nil {
+		t.Fatalf("replacement accepted while the policy rejects it")
+	}
+
+	finalOut := types.NewOutPoint(&hash.Hash{0x03}, 0)
+	addTestTx(mp, newTestTx(types.MaxTxInSequenceNum, 1, finalOut), 1000)
+	mp.cfg.Policy.RejectReplacement = false
+	if _, err := mp.checkPoolDoubleSpend(newTestTx(types.MaxTxInSequenceNum, 1, finalOut)); err == nil {
+		t.Fatalf("replaced a transaction which does not signal replacement")
+	}
+}
+
+func TestIsFeeRateHigher(t *testing.T) {
+	tests := []struct {
+		fee, size, otherFee, otherSize int64
+		want                           bool
+	}{
+		{2000, 200, 1000, 200, true},
+		{1000, 200, 1000, 200, false},
+		{1500, 300, 1000, 200, false},
+		{1501, 300, 1000, 200, true},
+	}
+	for i, test := range tests {
+		got := isFeeRateHigher(test.fee, test.size, test.otherFee, test.otherSize)
+		if got != test.want {
+			t.Errorf("#%d: got %v want %v", i, got, test.want)
+		}
+	}
+}
@@ -82,6 +82,11 @@ func (api *PublicMinerAPI) GetBlockTemplate(capabilities []string, powType byte)
 
 // GetMiningStats func (api *PublicMinerAPI) GetMiningStats() (interface{}, error){
 func (api *PrivateMinerAPI) GetMiningStats() (interface{}, error) {
+	api.miner.Lock()
+	defer api.miner.Unlock()
+	if api.miner.stratum != nil {
+		api.miner.stats.Stratum = api.miner.stratum.Stats()
+	}
 	b, err := api.miner.stats.MarshalJSON()
 	return string(b), err
 }
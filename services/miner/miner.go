@@ -79,6 +79,8 @@ type MiningStats struct {
 	MempoolEmptyWarns                 int64     `json:"mempool_empty_warns"`
 	Lastest100MempoolEmptyDuration    []float64 `json:"lastest_100_mempool_empty_duration"`
 	Lastest100MempoolEmptyAvgDuration float64   `json:"lastest_100_mempool_empty_avg_duration"`
+
+	Stratum *StratumStats `json:"stratum,omitempty"`
 }
 
 func (ms *MiningStats) MarshalJSON() ([]byte, error) {
@@ -163,6 +165,8 @@ type Miner struct {
 	RpcSer *rpc.RpcServer
 	p2pSer model.P2PService
 	stats  MiningStats
+
+	stratum *StratumServer
 }
 
 func (m *Miner) StatsEmptyGbt() {
@@ -292,6 +296,19 @@ func (m *Miner) Start() error {
 	//
 	log.Info("Start Miner...")
 
+	// The stratum server is started before the handler, so nothing is
+	// left running if it fails.
+	if len(m.cfg.StratumListen) > 0 {
+		if len(m.cfg.GetMinningAddrs()) <= 0 {
+			return fmt.Errorf("No payment addresses specified via --miningaddr for stratum.")
+		}
+		m.stratum = NewStratumServer(m)
+		if err := m.stratum.Start(); err != nil {
+			m.stratum = nil
+			return err
+		}
+	}
+
 	m.subscribe()
 
 	m.wg.Add(1)
@@ -309,6 +326,9 @@ func (m *Miner) Stop() error {
 	}
 	log.Info("Stop Miner...")
 
+	if m.stratum != nil {
+		m.stratum.Stop()
+	}
 	close(m.quit)
 	m.wg.Wait()
 	m.reqWG.Wait()
@@ -447,6 +467,26 @@ out:
 				worker.Update()
 				worker.GetRequest(msg.powType, msg.coinbaseFlags, msg.reply)
 
+			case *StratumMiningMsg:
+				if m.worker != nil && m.worker.GetType() != RemoteWorkerType {
+					log.Info(fmt.Sprintf("Stop %s for the stratum mining", m.worker.GetType()))
+					m.worker.Stop()
+					m.worker = nil
+				}
+				if m.worker == nil {
+					m.worker = NewRemoteWorker(m)
+					if err := m.worker.Start(); err != nil {
+						log.Error(err.Error())
+						m.worker = nil
+						continue
+					}
+				}
+				m.powType = msg.powType
+				m.coinbaseFlags = mining.CoinbaseFlagsDynamic
+				if m.updateBlockTemplate(true) == nil {
+					m.worker.Update()
+				}
+
 			default:
 				log.Warn("Invalid message type in task handler: %T", msg)
 			}
@@ -861,9 +901,27 @@ func (m *Miner) RemoteMining(powType pow.PowType, coinbaseFlags mining.CoinbaseF
 	return nil
 }
 
+// StratumMining creates the template with dynamic coinbase by remote worker,
+// the stratum job is created when the template is notified.
+func (m *Miner) StratumMining(powType pow.PowType) error {
+	// Ignore if we are shutting down.
+	if m.IsShutdown() {
+		return fmt.Errorf("Miner is shutdown")
+	}
+	if err := m.CanMining(); err != nil {
+		return err
+	}
+
+	m.msgChan <- &StratumMiningMsg{powType: powType}
+	return nil
+}
+
 func (m *Miner) notifyBlockTemplate() {
 	var err error
 	var bt *json.RemoteGBTResult
+	if m.stratum != nil {
+		m.stratum.notify(m.template)
+	}
 	if m.RpcSer != nil {
 		if m.worker.GetType() == RemoteWorkerType {
 			bt = m.worker.(*RemoteWorker).GetRemoteGBTResult()
@@ -37,3 +37,7 @@ type RemoteMiningMsg struct {
 	coinbaseFlags mining.CoinbaseFlags
 	reply         chan *gbtResponse
 }
+
+type StratumMiningMsg struct {
+	powType pow.PowType
+}
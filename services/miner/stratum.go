@@ -0,0 +1,842 @@
+package miner
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	ejson "encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/core/blockchain"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/core/types/pow"
+	"github.com/Qitmeer/qng/params"
+	"github.com/Qitmeer/qng/services/mining"
+)
+
+const (
+	// stratumExtraNonce2Size is the number of bytes of extranonce2 which is
+	// rolled by the worker. The extranonce of coinbase is extranonce1 of
+	// the connection followed by extranonce2.
+	stratumExtraNonce2Size = 4
+
+	// stratumMaxJobs is the maximum number of jobs which are kept for the
+	// late shares when the parents don't change.
+	stratumMaxJobs = 8
+
+	// stratumMaxMessageSize is the maximum size of a stratum message.
+	stratumMaxMessageSize = 4096
+
+	// stratumIdleTimeout disconnects the workers which send nothing.
+	stratumIdleTimeout = 10 * time.Minute
+
+	// stratumWriteTimeout is the timeout of writing a message to worker.
+	stratumWriteTimeout = 5 * time.Second
+
+	// stratumHashrateWindow is the duration of shares which the hashrate is
+	// estimated from.
+	stratumHashrateWindow = 10 * time.Minute
+
+	// stratumMinDiff is the minimum share difficulty, the share target is
+	// the pow limit at this difficulty.
+	stratumMinDiff = 1
+
+	// stratumRetargetShares is the number of target intervals or shares
+	// after which the variable difficulty is retargeted.
+	stratumRetargetShares = 8
+
+	// stratumMaxRetarget is the maximum factor of one retarget.
+	stratumMaxRetarget = 4.0
+
+	// stratumRetargetVariance is the tolerated relative deviation of the
+	// share rate from the target.
+	stratumRetargetVariance = 0.3
+)
+
+// The error codes of stratum replies.
+const (
+	stratumErrOther         = 20
+	stratumErrJobNotFound   = 21
+	stratumErrDuplicate     = 22
+	stratumErrLowDifficulty = 23
+	stratumErrUnauthorized  = 24
+	stratumErrNotSubscribed = 25
+)
+
+func stratumError(code int, msg string) []interface{} {
+	return []interface{}{code, msg, nil}
+}
+
+// isHashPow returns true if the proof of work is a hash below the target,
+// the shares of stratum are only supported for these algorithms.
+func isHashPow(powType pow.PowType) bool {
+	switch powType {
+	case pow.BLAKE2BD, pow.X8R16, pow.X16RV3, pow.QITMEERKECCAK256, pow.MEERXKECCAKV1:
+		return true
+	}
+	return false
+}
+
+// shareTarget returns the target of the share difficulty, it's the pow limit
+// divided by the difficulty.
+func shareTarget(powLimit *big.Int, diff float64) *big.Int {
+	if diff < stratumMinDiff {
+		diff = stratumMinDiff
+	}
+	return new(big.Int).Div(powLimit, big.NewInt(int64(math.Floor(diff))))
+}
+
+// shareWork returns the expected number of hashes of a share.
+func shareWork(target *big.Int) float64 {
+	work := new(big.Int).Div(pow.OneLsh256, new(big.Int).Add(target, big.NewInt(1)))
+	f, _ := new(big.Float).SetInt(work).Float64()
+	return f
+}
+
+type stratumRequest struct {
+	ID     interface{}   `json:"id"`
+	Method string        `json:"method"`
+	Params []interface{} `json:"params"`
+}
+
+type stratumResponse struct {
+	ID     interface{} `json:"id"`
+	Result interface{} `json:"result"`
+	Error  interface{} `json:"error"`
+}
+
+type stratumNotification struct {
+	ID     interface{}   `json:"id"`
+	Method string        `json:"method"`
+	Params []interface{} `json:"params"`
+}
+
+// stratumJob is the work of a block template. The header, coinbase and merkle
+// path are sent to workers who set the extranonce of coinbase, the tx root,
+// the time and the nonce of header.
+type stratumJob struct {
+	id          string
+	powType     pow.PowType
+	height      uint64
+	block       *types.Block
+	coinbase    []byte
+	merklePath  []*hash.Hash
+	witnessRoot *hash.Hash
+	params      []interface{}
+	submitted   map[string]struct{}
+}
+
+func newStratumJob(id string, template *types.BlockTemplate) (*stratumJob, error) {
+	if len(template.Block.Transactions) <= 0 ||
+		!mining.IsSupportCoinbaseFlagsDynamic(template.Block.Transactions[0]) {
+		return nil, fmt.Errorf("The coinbase of template doesn't support %s", mining.CoinbaseFlagsDynamic)
+	}
+	powType := template.Block.Header.Pow.GetPowType()
+	if !isHashPow(powType) {
+		return nil, fmt.Errorf("Not support pow %s", pow.GetPowName(powType))
+	}
+	block, err := template.Block.Clone()
+	if err != nil {
+		return nil, err
+	}
+	coinbase, err := block.Transactions[0].Serialize()
+	if err != nil {
+		return nil, err
+	}
+	var headerBuf bytes.Buffer
+	err = block.Header.Serialize(&headerBuf)
+	if err != nil {
+		return nil, err
+	}
+	merklePath := []string{}
+	for _, h := range template.TxMerklePath {
+		merklePath = append(merklePath, h.String())
+	}
+	var witnessRoot string
+	if template.TxWitnessRoot != nil && !template.TxWitnessRoot.IsEqual(&hash.ZeroHash) {
+		witnessRoot = template.TxWitnessRoot.String()
+	}
+	return &stratumJob{
+		id:          id,
+		powType:     powType,
+		height:      template.Height,
+		block:       block,
+		coinbase:    coinbase,
+		merklePath:  template.TxMerklePath,
+		witnessRoot: template.TxWitnessRoot,
+		params: []interface{}{id, hex.EncodeToString(headerBuf.Bytes()),
+			hex.EncodeToString(coinbase), merklePath, witnessRoot},
+		submitted: map[string]struct{}{},
+	}, nil
+}
+
+// solve returns the header and coinbase of the work which is submitted by the
+// worker.
+func (j *stratumJob) solve(extraNonce uint64, ntime uint32, nonce uint64) (*types.BlockHeader, *types.Transaction, error) {
+	var coinbase types.Transaction
+	err := coinbase.Deserialize(bytes.NewReader(j.coinbase))
+	if err != nil {
+		return nil, nil, err
+	}
+	txRoot, err := mining.DoCalculateTransactionsRoot(&coinbase, j.merklePath, j.witnessRoot, extraNonce)
+	if err != nil {
+		return nil, nil, err
+	}
+	header := j.block.Header
+	header.TxRoot = *txRoot
+	header.Timestamp = time.Unix(int64(ntime), 0)
+	instance := pow.GetInstance(j.powType, nonce, []byte{})
+	instance.SetMainHeight(pow.MainHeight(j.height))
+	instance.SetParams(params.ActiveNetParams.Params.PowConfig)
+	header.Pow = instance
+	return &header, &coinbase, nil
+}
+
+// varDiff adjusts the share difficulty of a connection so that its shares
+// arrive at the target interval.
+type varDiff struct {
+	target       time.Duration
+	lastRetarget time.Time
+	shares       int
+}
+
+// retarget returns the new difficulty, it's unchanged until enough shares or
+// time are observed to measure the share rate.
+func (v *varDiff) retarget(diff float64, now time.Time) float64 {
+	elapsed := now.Sub(v.lastRetarget)
+	if v.target <= 0 ||
+		(v.shares < stratumRetargetShares && elapsed < v.target*stratumRetargetShares) {
+		return diff
+	}
+	var ratio float64
+	if v.shares <= 0 {
+		ratio = 1 / stratumMaxRetarget
+	} else if elapsed <= 0 {
+		ratio = stratumMaxRetarget
+	} else {
+		ratio = float64(v.target) * float64(v.shares) / float64(elapsed)
+	}
+	ratio = math.Max(1/stratumMaxRetarget, math.Min(stratumMaxRetarget, ratio))
+	v.lastRetarget = now
+	v.shares = 0
+	if math.Abs(ratio-1) < stratumRetargetVariance {
+		return diff
+	}
+	return math.Max(stratumMinDiff, math.Floor(diff*ratio))
+}
+
+type stratumShare struct {
+	time time.Time
+	work float64
+}
+
+// pruneShares removes the shares which are out of the hashrate window.
+func pruneShares(shares []stratumShare, now time.Time) []stratumShare {
+	i := 0
+	for ; i < len(shares) && now.Sub(shares[i].time) > stratumHashrateWindow; i++ {
+	}
+	return shares[i:]
+}
+
+// hashrate returns the hashes per second of the shares in the window, the
+// expired shares are removed.
+func hashrate(shares []stratumShare, since time.Time, now time.Time) (float64, []stratumShare) {
+	shares = pruneShares(shares, now)
+	sum := float64(0)
+	for _, s := range shares {
+		sum += s.work
+	}
+	elapsed := now.Sub(since)
+	if elapsed > stratumHashrateWindow {
+		elapsed = stratumHashrateWindow
+	}
+	if elapsed < time.Second {
+		elapsed = time.Second
+	}
+	return sum / elapsed.Seconds(), shares
+}
+
+type stratumConn struct {
+	conn        net.Conn
+	extraNonce1 uint32
+	connected   time.Time
+	writeLock   sync.Mutex
+
+	// The following fields are protected by the lock of server.
+	subscribed bool
+	authorized bool
+	worker     string
+	diff       float64
+	prevDiff   float64
+	vardiff    varDiff
+	accepted   int64
+	rejected   int64
+	lastShare  time.Time
+	shares     []stratumShare
+}
+
+func (c *stratumConn) send(msg interface{}) error {
+	b, err := ejson.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(stratumWriteTimeout))
+	_, err = c.conn.Write(append(b, '\n'))
+	return err
+}
+
+func (c *stratumConn) reply(id interface{}, result interface{}, e interface{}) {
+	err := c.send(&stratumResponse{ID: id, Result: result, Error: e})
+	if err != nil {
+		log.Debug("Stratum reply failed", "addr", c.conn.RemoteAddr(), "error", err)
+	}
+}
+
+func (c *stratumConn) setDifficulty(diff float64) error {
+	return c.send(&stratumNotification{Method: "mining.set_difficulty", Params: []interface{}{diff}})
+}
+
+func (c *stratumConn) notify(job *stratumJob, clean bool) error {
+	params := make([]interface{}, 0, len(job.params)+1)
+	params = append(params, job.params...)
+	return c.send(&stratumNotification{Method: "mining.notify", Params: append(params, clean)})
+}
+
+// StratumWorkerStats is the share statistics of a stratum connection.
+type StratumWorkerStats struct {
+	Worker     string  `json:"worker"`
+	Address    string  `json:"address"`
+	Difficulty float64 `json:"difficulty"`
+	Accepted   int64   `json:"accepted"`
+	Rejected   int64   `json:"rejected"`
+	Hashrate   float64 `json:"hashrate"`
+	LastShare  string  `json:"last_share,omitempty"`
+}
+
+// StratumStats is the share statistics of the stratum server.
+type StratumStats struct {
+	Listen          string                `json:"listen"`
+	Connections     int                   `json:"connections"`
+	Job             string                `json:"job"`
+	Height          uint64                `json:"height"`
+	AcceptedShares  int64                 `json:"accepted_shares"`
+	RejectedShares  int64                 `json:"rejected_shares"`
+	StaleShares     int64                 `json:"stale_shares"`
+	DuplicateShares int64                 `json:"duplicate_shares"`
+	BlocksFound     int64                 `json:"blocks_found"`
+	Hashrate        float64               `json:"hashrate"`
+	Workers         []*StratumWorkerStats `json:"workers"`
+}
+
+// StratumServer is a stratum v1 server for the solo mining of the node. The
+// jobs are the dynamic coinbase templates of remote worker, the extranonce of
+// coinbase is partitioned by connections and the share difficulty of each
+// connection is adjusted to its hashrate.
+type StratumServer struct {
+	miner     *Miner
+	listen    string
+	password  string
+	diff      float64
+	shareTime time.Duration
+	powType   pow.PowType
+
+	listener net.Listener
+	wg       sync.WaitGroup
+	quit     chan struct{}
+	started  time.Time
+
+	sync.Mutex
+	conns       map[*stratumConn]struct{}
+	jobs        []*stratumJob
+	jobID       uint64
+	extraNonce1 uint32
+	shares      []stratumShare
+	stats       StratumStats
+}
+
+func (s *StratumServer) Start() error {
+	listener, err := net.Listen("tcp", s.listen)
+	if err != nil {
+		return err
+	}
+	log.Info(fmt.Sprintf("Stratum server listening on %s", listener.Addr()))
+	s.listener = listener
+	s.started = time.Now()
+	s.wg.Add(2)
+	go s.acceptHandler()
+	go s.jobHandler()
+	return nil
+}
+
+func (s *StratumServer) Stop() {
+	log.Info("Stop stratum server...")
+	close(s.quit)
+	s.listener.Close()
+	s.Lock()
+	for c := range s.conns {
+		c.conn.Close()
+	}
+	s.Unlock()
+	s.wg.Wait()
+}
+
+func (s *StratumServer) isQuit() bool {
+	select {
+	case <-s.quit:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *StratumServer) acceptHandler() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if s.isQuit() {
+				return
+			}
+			log.Warn("Stratum accept failed", "error", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		s.Lock()
+		s.extraNonce1++
+		c := &stratumConn{
+			conn:        conn,
+			extraNonce1: s.extraNonce1,
+			connected:   time.Now(),
+			diff:        math.Max(stratumMinDiff, s.diff),
+			vardiff:     varDiff{target: s.shareTime, lastRetarget: time.Now()},
+		}
+		s.conns[c] = struct{}{}
+		s.Unlock()
+		log.Debug("Stratum worker connected", "addr", conn.RemoteAddr())
+		s.wg.Add(1)
+		go s.connHandler(c)
+	}
+}
+
+// jobHandler requests the job until the template is available and lowers the
+// difficulty of idle workers.
+func (s *StratumServer) jobHandler() {
+	defer s.wg.Done()
+	if s.currentJob() == nil {
+		s.requestJob()
+	}
+	ticker := time.NewTicker(s.shareTime)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if s.currentJob() == nil {
+				s.requestJob()
+			}
+			s.retargetIdle()
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+func (s *StratumServer) requestJob() {
+	if s.miner == nil {
+		return
+	}
+	err := s.miner.StratumMining(s.powType)
+	if err != nil {
+		log.Debug("Stratum job is unavailable", "error", err)
+	}
+}
+
+func (s *StratumServer) currentJob() *stratumJob {
+	s.Lock()
+	defer s.Unlock()
+	if len(s.jobs) <= 0 {
+		return nil
+	}
+	return s.jobs[len(s.jobs)-1]
+}
+
+// notify creates the job of template and sends it to the workers, the old
+// jobs are cleaned if the parents are changed.
+func (s *StratumServer) notify(template *types.BlockTemplate) {
+	s.Lock()
+	s.jobID++
+	job, err := newStratumJob(fmt.Sprintf("%x", s.jobID), template)
+	if err != nil {
+		s.Unlock()
+		log.Debug("Stratum job is skipped", "error", err)
+		return
+	}
+	clean := len(s.jobs) <= 0 ||
+		!s.jobs[len(s.jobs)-1].block.Header.ParentRoot.IsEqual(&job.block.Header.ParentRoot)
+	if clean {
+		s.jobs = nil
+	} else if len(s.jobs) >= stratumMaxJobs {
+		s.jobs = s.jobs[len(s.jobs)-stratumMaxJobs+1:]
+	}
+	s.jobs = append(s.jobs, job)
+	s.stats.Job = job.id
+	s.stats.Height = job.height
+	conns := []*stratumConn{}
+	for c := range s.conns {
+		c.prevDiff = 0
+		if c.authorized {
+			conns = append(conns, c)
+		}
+	}
+	s.Unlock()
+
+	go func() {
+		for _, c := range conns {
+			if err := c.notify(job, clean); err != nil {
+				log.Debug("Stratum notify failed", "addr", c.conn.RemoteAddr(), "error", err)
+			}
+		}
+	}()
+}
+
+func (s *StratumServer) connHandler(c *stratumConn) {
+	defer s.wg.Done()
+	defer func() {
+		c.conn.Close()
+		s.Lock()
+		delete(s.conns, c)
+		s.Unlock()
+		log.Debug("Stratum worker disconnected", "addr", c.conn.RemoteAddr())
+	}()
+	reader := bufio.NewReaderSize(c.conn, stratumMaxMessageSize)
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(stratumIdleTimeout))
+		line, isPrefix, err := reader.ReadLine()
+		if err != nil {
+			return
+		}
+		if isPrefix {
+			log.Debug("Stratum message is too large", "addr", c.conn.RemoteAddr())
+			return
+		}
+		if len(bytes.TrimSpace(line)) <= 0 {
+			continue
+		}
+		var req stratumRequest
+		err = ejson.Unmarshal(line, &req)
+		if err != nil {
+			log.Debug("Invalid stratum message", "addr", c.conn.RemoteAddr(), "error", err)
+			return
+		}
+		switch req.Method {
+		case "mining.subscribe":
+			s.handleSubscribe(c, &req)
+		case "mining.authorize":
+			s.handleAuthorize(c, &req)
+		case "mining.submit":
+			s.handleSubmit(c, &req)
+		default:
+			c.reply(req.ID, nil, stratumError(stratumErrOther, "Unknown method"))
+		}
+	}
+}
+
+func (s *StratumServer) handleSubscribe(c *stratumConn, req *stratumRequest) {
+	s.Lock()
+	c.subscribed = true
+	s.Unlock()
+	extraNonce1 := fmt.Sprintf("%08x", c.extraNonce1)
+	c.reply(req.ID, []interface{}{
+		[]interface{}{
+			[]string{"mining.set_difficulty", extraNonce1},
+			[]string{"mining.notify", extraNonce1},
+		},
+		extraNonce1,
+		stratumExtraNonce2Size,
+	}, nil)
+}
+
+func (s *StratumServer) handleAuthorize(c *stratumConn, req *stratumRequest) {
+	var user, password string
+	if len(req.Params) > 0 {
+		user, _ = req.Params[0].(string)
+	}
+	if len(req.Params) > 1 {
+		password, _ = req.Params[1].(string)
+	}
+	s.Lock()
+	if !c.subscribed {
+		s.Unlock()
+		c.reply(req.ID, false, stratumError(stratumErrNotSubscribed, "Not subscribed"))
+		return
+	}
+	if len(s.password) > 0 && subtle.ConstantTimeCompare([]byte(password), []byte(s.password)) != 1 {
+		s.Unlock()
+		c.reply(req.ID, false, stratumError(stratumErrUnauthorized, "Unauthorized worker"))
+		return
+	}
+	c.authorized = true
+	c.worker = user
+	diff := c.diff
+	s.Unlock()
+	log.Info("Stratum worker authorized", "worker", user, "addr", c.conn.RemoteAddr())
+
+	c.reply(req.ID, true, nil)
+	if err := c.setDifficulty(diff); err != nil {
+		return
+	}
+	if job := s.currentJob(); job != nil {
+		c.notify(job, true)
+	}
+}
+
+// parseHexUint parses the big-endian hex number of the size.
+func parseHexUint(param interface{}, size int) (uint64, error) {
+	str, ok := param.(string)
+	if !ok || len(str) != size*2 {
+		return 0, fmt.Errorf("invalid hex number %v", param)
+	}
+	b, err := hex.DecodeString(str)
+	if err != nil {
+		return 0, err
+	}
+	var buf [8]byte
+	copy(buf[8-size:], b)
+	return binary.BigEndian.Uint64(buf[:]), nil
+}
+
+// handleSubmit validates the share of params [worker, job id, extranonce2,
+// ntime, nonce], the share is submitted as a block if it meets the target of
+// block.
+func (s *StratumServer) handleSubmit(c *stratumConn, req *stratumRequest) {
+	s.Lock()
+	authorized := c.authorized
+	s.Unlock()
+	if !authorized {
+		c.reply(req.ID, false, stratumError(stratumErrUnauthorized, "Unauthorized worker"))
+		return
+	}
+	reject := func(code int, msg string) {
+		s.Lock()
+		c.rejected++
+		s.stats.RejectedShares++
+		switch code {
+		case stratumErrJobNotFound:
+			s.stats.StaleShares++
+		case stratumErrDuplicate:
+			s.stats.DuplicateShares++
+		}
+		s.Unlock()
+		c.reply(req.ID, false, stratumError(code, msg))
+	}
+	if len(req.Params) < 5 {
+		reject(stratumErrOther, "Invalid params")
+		return
+	}
+	jobID, _ := req.Params[1].(string)
+	extraNonce2, err := parseHexUint(req.Params[2], stratumExtraNonce2Size)
+	if err != nil {
+		reject(stratumErrOther, "Invalid extranonce2")
+		return
+	}
+	ntime, err := parseHexUint(req.Params[3], 4)
+	if err != nil {
+		reject(stratumErrOther, "Invalid ntime")
+		return
+	}
+	nonce, err := parseHexUint(req.Params[4], 8)
+	if err != nil {
+		reject(stratumErrOther, "Invalid nonce")
+		return
+	}
+
+	s.Lock()
+	var job *stratumJob
+	for _, j := range s.jobs {
+		if j.id == jobID {
+			job = j
+			break
+		}
+	}
+	if job == nil {
+		s.Unlock()
+		reject(stratumErrJobNotFound, "Job not found")
+		return
+	}
+	key := fmt.Sprintf("%08x%08x%08x%016x", c.extraNonce1, extraNonce2, ntime, nonce)
+	if _, ok := job.submitted[key]; ok {
+		s.Unlock()
+		reject(stratumErrDuplicate, "Duplicate share")
+		return
+	}
+	job.submitted[key] = struct{}{}
+	diff := c.diff
+	if c.prevDiff > 0 && c.prevDiff < diff {
+		diff = c.prevDiff
+	}
+	s.Unlock()
+
+	if int64(ntime) < job.block.Header.Timestamp.Unix() ||
+		int64(ntime) > time.Now().Unix()+blockchain.MaxTimeOffsetSeconds {
+		reject(stratumErrOther, "Invalid ntime")
+		return
+	}
+	extraNonce := uint64(c.extraNonce1)<<32 | extraNonce2
+	header, coinbase, err := job.solve(extraNonce, uint32(ntime), nonce)
+	if err != nil {
+		reject(stratumErrOther, err.Error())
+		return
+	}
+	target := shareTarget(header.Pow.GetSafeDiff(0), diff)
+	err = header.Pow.Verify(header.BlockData(), header.BlockHash(), pow.BigToCompact(target))
+	if err != nil {
+		reject(stratumErrLowDifficulty, "Low difficulty share")
+		return
+	}
+	if header.Pow.Verify(header.BlockData(), header.BlockHash(), header.Difficulty) == nil {
+		s.submitBlock(c, job, header, coinbase)
+	}
+
+	now := time.Now()
+	s.Lock()
+	work := shareWork(target)
+	c.accepted++
+	c.lastShare = now
+	c.shares = append(pruneShares(c.shares, now), stratumShare{time: now, work: work})
+	s.shares = append(pruneShares(s.shares, now), stratumShare{time: now, work: work})
+	s.stats.AcceptedShares++
+	c.vardiff.shares++
+	newDiff := c.vardiff.retarget(c.diff, now)
+	changed := newDiff != c.diff
+	if changed {
+		c.prevDiff = c.diff
+		c.diff = newDiff
+	}
+	s.Unlock()
+
+	c.reply(req.ID, true, nil)
+	if changed {
+		s.sendDifficulty(c, newDiff)
+	}
+}
+
+func (s *StratumServer) submitBlock(c *stratumConn, job *stratumJob, header *types.BlockHeader, coinbase *types.Transaction) {
+	if s.miner == nil {
+		return
+	}
+	block, err := job.block.Clone()
+	if err != nil {
+		log.Error(err.Error())
+		return
+	}
+	block.Header = *header
+	block.Transactions[0] = coinbase
+	start := time.Now()
+	_, err = s.miner.submitBlock(types.NewBlock(block))
+	if err != nil {
+		log.Warn("Stratum block is rejected", "hash", header.BlockHash(), "error", err)
+		return
+	}
+	s.miner.StatsSubmit(start, header.BlockHash().String(), len(block.Transactions)-1)
+	s.Lock()
+	s.stats.BlocksFound++
+	worker := c.worker
+	s.Unlock()
+	log.Info("Stratum block is found", "hash", header.BlockHash(), "height", job.height, "worker", worker)
+}
+
+// sendDifficulty sends the new difficulty and the current job which the
+// difficulty applies to.
+func (s *StratumServer) sendDifficulty(c *stratumConn, diff float64) {
+	log.Debug("Stratum difficulty is retargeted", "addr", c.conn.RemoteAddr(), "difficulty", diff)
+	if err := c.setDifficulty(diff); err != nil {
+		return
+	}
+	if job := s.currentJob(); job != nil {
+		c.notify(job, false)
+	}
+}
+
+// retargetIdle lowers the difficulty of the workers whose shares are too
+// slow to be retargeted by shares.
+func (s *StratumServer) retargetIdle() {
+	now := time.Now()
+	changed := map[*stratumConn]float64{}
+	s.Lock()
+	for c := range s.conns {
+		if !c.authorized {
+			continue
+		}
+		newDiff := c.vardiff.retarget(c.diff, now)
+		if newDiff != c.diff {
+			c.prevDiff = c.diff
+			c.diff = newDiff
+			changed[c] = newDiff
+		}
+	}
+	s.Unlock()
+	for c, diff := range changed {
+		s.sendDifficulty(c, diff)
+	}
+}
+
+// Stats returns the share statistics of the server and its workers.
+func (s *StratumServer) Stats() *StratumStats {
+	now := time.Now()
+	s.Lock()
+	defer s.Unlock()
+	stats := s.stats
+	stats.Listen = s.listener.Addr().String()
+	stats.Connections = len(s.conns)
+	stats.Hashrate, s.shares = hashrate(s.shares, s.started, now)
+	stats.Workers = []*StratumWorkerStats{}
+	for c := range s.conns {
+		if !c.authorized {
+			continue
+		}
+		ws := &StratumWorkerStats{
+			Worker:     c.worker,
+			Address:    c.conn.RemoteAddr().String(),
+			Difficulty: c.diff,
+			Accepted:   c.accepted,
+			Rejected:   c.rejected,
+		}
+		ws.Hashrate, c.shares = hashrate(c.shares, c.connected, now)
+		if !c.lastShare.IsZero() {
+			ws.LastShare = c.lastShare.Format("2006-01-02 15:04:05")
+		}
+		stats.Workers = append(stats.Workers, ws)
+	}
+	return &stats
+}
+
+func newStratumServer(listen string, password string, diff float64, shareTime time.Duration) *StratumServer {
+	if shareTime <= 0 {
+		shareTime = 10 * time.Second
+	}
+	return &StratumServer{
+		listen:    listen,
+		password:  password,
+		diff:      diff,
+		shareTime: shareTime,
+		powType:   pow.MEERXKECCAKV1,
+		quit:      make(chan struct{}),
+		conns:     map[*stratumConn]struct{}{},
+	}
+}
+
+func NewStratumServer(m *Miner) *StratumServer {
+	s := newStratumServer(m.cfg.StratumListen, m.cfg.StratumPass, m.cfg.StratumDiff, m.cfg.StratumShareTime)
+	s.miner = m
+	s.powType = m.powType
+	return s
+}
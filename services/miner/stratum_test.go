@@ -0,0 +1,239 @@
+package miner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/core/types/pow"
+	"github.com/Qitmeer/qng/params"
+	"github.com/Qitmeer/qng/services/mining"
+)
+
+func TestVarDiff(t *testing.T) {
+	now := time.Now()
+	v := varDiff{target: 10 * time.Second, lastRetarget: now}
+	// Too few shares to measure
+	v.shares = stratumRetargetShares - 1
+	if d := v.retarget(16, now.Add(time.Second)); d != 16 {
+		t.Fatalf("retargeted to %v", d)
+	}
+	// Shares are 8 times faster than the target
+	v.shares = stratumRetargetShares
+	if d := v.retarget(16, now.Add(10*time.Second)); d != 64 {
+		t.Fatalf("unexpected difficulty %v", d)
+	}
+	// The share rate is on the target
+	v.shares = stratumRetargetShares
+	if d := v.retarget(64, v.lastRetarget.Add(85*time.Second)); d != 64 {
+		t.Fatalf("unexpected difficulty %v", d)
+	}
+	// No share in the retarget window
+	if d := v.retarget(64, v.lastRetarget.Add(80*time.Second)); d != 16 {
+		t.Fatalf("unexpected difficulty %v", d)
+	}
+	if d := v.retarget(2, v.lastRetarget.Add(80*time.Second)); d != stratumMinDiff {
+		t.Fatalf("unexpected difficulty %v", d)
+	}
+}
+
+func testTemplate(t *testing.T, parent byte) *types.BlockTemplate {
+	script, err := mining.StandardCoinbaseScript(10, 0, "", mining.CoinbaseFlagsDynamic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coinbase := types.NewTransaction()
+	coinbase.AddTxIn(types.NewTxInput(types.NewOutPoint(&hash.ZeroHash, math.MaxUint32), script))
+	coinbase.AddTxOut(types.NewTxOutput(types.Amount{Value: 1e8, Id: types.MEERA}, []byte{0x51}))
+	block := &types.Block{
+		Header: types.BlockHeader{
+			Version:    1,
+			ParentRoot: hash.HashH([]byte{parent}),
+			// The target is too low to find a block
+			Difficulty: 0x03000001,
+			Timestamp:  time.Unix(time.Now().Unix()-10, 0),
+			Pow:        pow.GetInstance(pow.MEERXKECCAKV1, 0, []byte{}),
+		},
+		Parents:      []*hash.Hash{},
+		Transactions: []*types.Transaction{coinbase},
+	}
+	return &types.BlockTemplate{Block: block, Height: 10}
+}
+
+type testStratumClient struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+	id     int
+}
+
+func (c *testStratumClient) request(method string, params ...interface{}) {
+	c.id++
+	b, err := json.Marshal(map[string]interface{}{"id": c.id, "method": method, "params": params})
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	_, err = c.conn.Write(append(b, '\n'))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *testStratumClient) read() map[string]interface{} {
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	line, err := c.reader.ReadBytes('\n')
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	msg := map[string]interface{}{}
+	err = json.Unmarshal(line, &msg)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return msg
+}
+
+// errorCode returns the error code of reply, it's 0 if the reply is true.
+func (c *testStratumClient) errorCode() int {
+	msg := c.read()
+	if msg["result"] == true {
+		return 0
+	}
+	e, ok := msg["error"].([]interface{})
+	if !ok {
+		c.t.Fatalf("unexpected reply %v", msg)
+	}
+	return int(e[0].(float64))
+}
+
+// mine returns the nonce whose share meets or misses the target of the job.
+func mine(t *testing.T, notify []interface{}, extraNonce uint64, ntime uint32, meet bool) uint64 {
+	merklePath := []string{}
+	for _, h := range notify[3].([]interface{}) {
+		merklePath = append(merklePath, h.(string))
+	}
+	txRoot, err := mining.CalculateTransactionsRoot(notify[2].(string), merklePath, notify[4].(string), extraNonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := hex.DecodeString(notify[1].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var header types.BlockHeader
+	err = header.Deserialize(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	header.TxRoot = *txRoot
+	header.Timestamp = time.Unix(int64(ntime), 0)
+	target := pow.CompactToBig(params.PrivNetParams.PowConfig.MeerXKeccakV1PowLimitBits)
+	for nonce := uint64(0); ; nonce++ {
+		header.Pow = pow.GetInstance(pow.MEERXKECCAKV1, nonce, []byte{})
+		h := hash.HashMeerXKeccakV1(header.BlockData())
+		if (pow.HashToBig(&h).Cmp(target) <= 0) == meet {
+			return nonce
+		}
+	}
+}
+
+func TestStratumServer(t *testing.T) {
+	old := params.ActiveNetParams
+	defer func() {
+		params.ActiveNetParams = old
+	}()
+	params.ActiveNetParams = &params.PrivNetParam
+
+	s := newStratumServer("127.0.0.1:0", "secret", 1, time.Hour)
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	s.notify(testTemplate(t, 1))
+
+	conn, err := net.Dial("tcp", s.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	c := &testStratumClient{t: t, conn: conn, reader: bufio.NewReader(conn)}
+
+	c.request("mining.subscribe", "test")
+	result := c.read()["result"].([]interface{})
+	extraNonce1 := result[1].(string)
+	if len(extraNonce1) != 8 || result[2].(float64) != stratumExtraNonce2Size {
+		t.Fatalf("unexpected subscribe result %v", result)
+	}
+	c.request("mining.submit", "worker", "1", "00000000", "00000000", "0000000000000000")
+	if code := c.errorCode(); code != stratumErrUnauthorized {
+		t.Fatalf("unexpected error code %d", code)
+	}
+	c.request("mining.authorize", "worker", "wrong")
+	if code := c.errorCode(); code != stratumErrUnauthorized {
+		t.Fatalf("unexpected error code %d", code)
+	}
+	c.request("mining.authorize", "worker", "secret")
+	if code := c.errorCode(); code != 0 {
+		t.Fatalf("unexpected error code %d", code)
+	}
+	if msg := c.read(); msg["method"] != "mining.set_difficulty" {
+		t.Fatalf("unexpected message %v", msg)
+	}
+	msg := c.read()
+	if msg["method"] != "mining.notify" {
+		t.Fatalf("unexpected message %v", msg)
+	}
+	notify := msg["params"].([]interface{})
+	if notify[5] != true {
+		t.Fatal("the first job isn't clean")
+	}
+
+	var en1 uint64
+	fmt.Sscanf(extraNonce1, "%x", &en1)
+	extraNonce := en1<<32 | 7
+	ntime := uint32(time.Now().Unix())
+	ntimeHex := fmt.Sprintf("%08x", ntime)
+	nonce := mine(t, notify, extraNonce, ntime, true)
+	c.request("mining.submit", "worker", notify[0], "00000007", ntimeHex, fmt.Sprintf("%016x", nonce))
+	if code := c.errorCode(); code != 0 {
+		t.Fatalf("the valid share is rejected %d", code)
+	}
+	c.request("mining.submit", "worker", notify[0], "00000007", ntimeHex, fmt.Sprintf("%016x", nonce))
+	if code := c.errorCode(); code != stratumErrDuplicate {
+		t.Fatalf("unexpected error code %d", code)
+	}
+	nonce = mine(t, notify, extraNonce, ntime, false)
+	c.request("mining.submit", "worker", notify[0], "00000007", ntimeHex, fmt.Sprintf("%016x", nonce))
+	if code := c.errorCode(); code != stratumErrLowDifficulty {
+		t.Fatalf("unexpected error code %d", code)
+	}
+
+	// The jobs of old parents are stale
+	s.notify(testTemplate(t, 2))
+	msg = c.read()
+	if msg["method"] != "mining.notify" || msg["params"].([]interface{})[5] != true {
+		t.Fatalf("unexpected message %v", msg)
+	}
+	c.request("mining.submit", "worker", notify[0], "00000008", ntimeHex, "0000000000000000")
+	if code := c.errorCode(); code != stratumErrJobNotFound {
+		t.Fatalf("unexpected error code %d", code)
+	}
+
+	stats := s.Stats()
+	if stats.AcceptedShares != 1 || stats.RejectedShares != 3 || stats.StaleShares != 1 ||
+		stats.DuplicateShares != 1 || stats.Connections != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if len(stats.Workers) != 1 || stats.Workers[0].Worker != "worker" || stats.Workers[0].Accepted != 1 ||
+		stats.Hashrate <= 0 {
+		t.Fatalf("unexpected worker stats %+v", stats.Workers)
+	}
+}
@@ -1,6 +1,7 @@
 package mining
 
 import (
+	"container/heap"
 	"fmt"
 	"time"
 
@@ -8,6 +9,7 @@ import (
 	"github.com/Qitmeer/qng/consensus/model"
 	"github.com/Qitmeer/qng/core/address"
 	"github.com/Qitmeer/qng/core/blockchain"
+	"github.com/Qitmeer/qng/core/blockchain/stake"
 	"github.com/Qitmeer/qng/core/blockchain/utxo"
 	"github.com/Qitmeer/qng/core/merkle"
 	s "github.com/Qitmeer/qng/core/serialization"
@@ -21,6 +23,20 @@ import (
 	"golang.org/x/net/context"
 )
 
+// activeDeployments returns whether the taproot and stake deployments are
+// active for the block built on the main parent.
+func activeDeployments(bc *blockchain.BlockChain, mainp meerdag.IBlock) (bool, bool, error) {
+	taproot, err := bc.IsDeploymentActiveAfter(mainp, params.DeploymentTaproot)
+	if err != nil {
+		return false, false, err
+	}
+	stake, err := bc.IsDeploymentActiveAfter(mainp, params.DeploymentStake)
+	if err != nil {
+		return false, false, err
+	}
+	return taproot, stake, nil
+}
+
 // NewBlockTemplate returns a new block template that is ready to be solved
 // using the transactions from the passed transaction source pool and a coinbase
 // that either pays to the passed address if it is not nil, or a coinbase that
@@ -38,24 +54,19 @@ import (
 // higher fee per kilobyte are preferred.  Finally, the block generation related
 // policy settings are all taken into account.
 //
-// Transactions which only spend outputs from other transactions already in the
-// block chain are immediately added to a priority queue which either
-// prioritizes based on the priority (then fee per kilobyte) or the fee per
-// kilobyte (then priority) depending on whether or not the BlockPrioritySize
-// policy setting allots space for high-priority transactions.  Transactions
-// which spend outputs from other transactions in the source pool are added to a
-// dependency map so they can be added to the priority queue once the
-// transactions they depend on have been included.
-//
-// Once the high-priority area (if configured) has been filled with
-// transactions, or the priority falls below what is considered high-priority,
-// the priority queue is updated to prioritize by fees per kilobyte (then
-// priority).
+// Transactions which spend outputs from other transactions in the source pool
+// are linked to those ancestors, and each transaction is prioritized by the fee
+// per kilobyte of its package, which is the transaction along with all of its
+// ancestors that have not been included in the block yet.  Selecting a
+// transaction includes its whole package, ancestors first, so a transaction
+// paying a high fee pulls its low-fee ancestors into the block
+// (child-pays-for-parent).  Once a transaction has been included, the packages
+// of its descendants are updated accordingly.
 //
-// When the fees per kilobyte drop below the TxMinFreeFee policy setting, the
-// transaction will be skipped unless the BlockMinSize policy setting is
-// nonzero, in which case the block will be filled with the low-fee/free
-// transactions until the block size reaches that minimum size.
+// When the package fees per kilobyte drop below the TxMinFreeFee policy
+// setting, the transaction will be skipped unless the BlockMinSize policy
+// setting is nonzero, in which case the block will be filled with the
+// low-fee/free transactions until the block size reaches that minimum size.
 //
 // Any transactions which would cause the block to exceed the BlockMaxSize
 // policy setting, exceed the maximum allowed signature operations per block, or
@@ -135,6 +146,13 @@ func NewBlockTemplate(policy *Policy, params *params.Params,
 	}
 
 	nextBlockHeight = uint64(mainp.GetHeight() + 1)
+	taproot, stakeEnabled, err := activeDeployments(bc, mainp)
+	if err != nil {
+		return nil, err
+	}
+	if taproot {
+		scriptFlags |= txscript.ScriptVerifyTaproot
+	}
 
 	coinbaseScript, err := StandardCoinbaseScript(nextBlockHeight, extraNonce, policy.CoinbaseGenerator.BuildExtraData(int64(nextBlockHeight)), coinbaseFlags)
 	if err != nil {
@@ -152,15 +170,12 @@ func NewBlockTemplate(policy *Policy, params *params.Params,
 		return nil, err
 	}
 	coinbaseSigOpCost := int64(blockchain.CountSigOps(coinbaseTx))
-	// Get the current source transactions and create a priority queue to
-	// hold the transactions which are ready for inclusion into a block
-	// along with some priority related and fee metadata.  Reserve the same
-	// number of items that are available for the priority queue.  Also,
-	// choose the initial sort order for the priority queue based on whether
-	// or not there is an area allocated for high-priority transactions.
+	// Get the current source transactions and collect the transactions
+	// which are candidates for inclusion into a block along with some fee
+	// and package related metadata.
 	sourceTxns := txpool.MiningDescs()
 	sortedByFee := policy.BlockPrioritySize == 0
-	weightedRandQueue := newWeightedRandQueue(len(sourceTxns))
+	candidates := make(map[hash.Hash]*txPrioItem, len(sourceTxns))
 	// Create a slice to hold the transactions to be included in the
 	// generated block with reserved space.  Also create a utxo view to
 	// house all of the input transactions so multiple lookups can be
@@ -174,12 +189,6 @@ func NewBlockTemplate(policy *Policy, params *params.Params,
 		blockUtxos.SetViewpoints(parents)
 	}
 
-	// dependers is used to track transactions which depend on another
-	// transaction in the source pool.  This, in conjunction with the
-	// dependsOn map kept with each dependent transaction helps quickly
-	// determine which dependent transactions are now eligible for inclusion
-	// in the block once each transaction has been included.
-	dependers := make(map[hash.Hash]map[hash.Hash]*WeightedRandTx)
 	// Create slices to hold the fees and number of signature operations
 	// for each of the selected transactions and add an entry for the
 	// coinbase.  This allows the code below to simply append details about
@@ -214,6 +223,34 @@ func NewBlockTemplate(policy *Policy, params *params.Params,
 		}
 		return true, tokenSOC
 	}
+	// The stake transactions are applied to the stake state in order, so the
+	// block never contains conflicting stake transactions.
+	stakeState := bc.GetCurStakeState()
+	applyStakeTx := func(tx *types.Tx) bool {
+		update, err := stake.NewUpdateFromTx(tx.Tx)
+		if err != nil {
+			return false
+		}
+		stakeState.Updates = []*stake.StakeUpdate{update}
+		err = stakeState.Update(nextBlockHeight, bc.StakeMaturity())
+		if err != nil {
+			log.Debug("Skipping stake tx", "hash", tx.Hash().String(), "error", err.Error())
+			return false
+		}
+		return true
+	}
+	if stakeEnabled {
+		stakebase := stakeState.BuildStakebase(nextBlockHeight, bc.StakeMaturity())
+		if stakebase != nil {
+			tx := types.NewTx(stakebase)
+			if applyStakeTx(tx) {
+				blockTxns = append(blockTxns, tx)
+				txFees = append(txFees, 0)
+				txSigOpCosts = append(txSigOpCosts, 0)
+				blockSize += uint32(stakebase.SerializeSize())
+			}
+		}
+	}
 
 	log.Debug("Inclusion to new block", "transactions", len(sourceTxns))
 
@@ -257,6 +294,41 @@ precheck:
 			tokenSigOpCost += tokenSOC
 			blockSize += txSize
 			continue
+		} else if types.IsStakeTx(tx.Tx) {
+			if !stakeEnabled {
+				log.Trace("Skipping stake tx: stake transactions are not enabled", "hash", tx.Hash())
+				continue
+			}
+			if types.IsStakebaseTx(tx.Tx) {
+				log.Trace("Skipping stake tx: stakebase is added by the template", "hash", tx.Hash())
+				continue
+			}
+			if bc.HasTx(tx.Hash()) {
+				log.Debug("Ignore stake tx: is duplicate", "hash", tx.Hash())
+				continue
+			}
+			txSize := uint32(tx.Transaction().SerializeSize())
+			blockPlusTxSize := blockSize + txSize
+			if blockPlusTxSize < blockSize || blockPlusTxSize >= policy.BlockMaxSize {
+				log.Trace(fmt.Sprintf("Ignore tx %s (size %v) because it "+
+					"would exceed the max block size; cur block "+
+					"size %v, cur num tx %v", tx.Hash(), txSize,
+					blockSize, len(blockTxns)))
+				break
+			}
+			ok, tokenSOC := checkSpecialSigOpCost(tx)
+			if !ok {
+				continue
+			}
+			if !applyStakeTx(tx) {
+				continue
+			}
+			blockTxns = append(blockTxns, tx)
+			txFees = append(txFees, 0)
+			txSigOpCosts = append(txSigOpCosts, tokenSOC)
+			tokenSigOpCost += tokenSOC
+			blockSize += txSize
+			continue
 		} else if types.IsCrossChainImportTx(tx.Tx) || types.IsCrossChainVMTx(tx.Tx) {
 			_, ok := payToAddress.(*address.SecpPubKeyAddress)
 			if !ok {
@@ -310,26 +382,29 @@ precheck:
 			hasCrossTx = true
 		}
 
-		// Setup dependencies for any transactions which reference
-		// other transactions in the mempool so they can be properly
-		// ordered below.
-		weirandItem := &WeightedRandTx{tx: tx}
-		weirandItem.feePerKB = txDesc.FeePerKB
-		weirandItem.fee = txDesc.Fee
-		weightedRandQueue.Push(weirandItem)
+		candidates[*tx.Hash()] = &txPrioItem{
+			tx:        tx,
+			fee:       txDesc.Fee,
+			feePerKB:  txDesc.FeePerKB,
+			size:      int64(tx.Transaction().SerializeSize()),
+			sigOpCost: int64(blockchain.CountSigOps(tx)),
+			index:     -1,
+		}
 	}
-	log.Trace(fmt.Sprintf("Weighted random queue len %d, dependers len %d",
-		weightedRandQueue.Len(), len(dependers)))
+
+	// Setup the packages of the transactions which reference other
+	// transactions in the mempool so they can be properly prioritized and
+	// ordered below.
+	priorityQueue := newTxPackageQueue(candidates)
+	log.Trace(fmt.Sprintf("Package priority queue len %d", priorityQueue.Len()))
 
 	blockSigOpCost := coinbaseSigOpCost + tokenSigOpCost
 	totalFees := int64(0)
 	blockFeesMap := types.AmountMap{}
 
-	fetchUtxo := map[string]struct{}{}
-
 	// Choose which transactions make it into the block.
 mempool:
-	for weightedRandQueue.Len() > 0 {
+	for priorityQueue.Len() > 0 {
 		//
 		select {
 		case <-ctx.Done():
@@ -337,167 +412,127 @@ mempool:
 			break mempool
 		default:
 		}
-		// Grab the highest priority (or highest fee per kilobyte
-		// depending on the sort order) transaction.
-		weirandItem := weightedRandQueue.Pop()
-		tx := weirandItem.tx
-
-		//
-		_, ok := fetchUtxo[tx.Hash().String()]
-		if !ok {
-			fetchUtxo[tx.Hash().String()] = struct{}{}
-			utxos, err := bc.FetchUtxoView(tx)
-			if err != nil {
-				log.Warn(fmt.Sprintf("Unable to fetch utxo view for tx %s: %v",
-					tx.Hash(), err))
-				continue
-			}
-
-			for _, txIn := range tx.Tx.TxIn {
-				originHash := &txIn.PreviousOut.Hash
-				entry := utxos.LookupEntry(txIn.PreviousOut)
-				if entry == nil || entry.IsSpent() {
-					if !txpool.HaveTransactionUTXO(originHash) {
-						log.Trace(fmt.Sprintf("Skipping tx %s because it "+
-							"references unspent output %v "+
-							"which is not available",
-							tx.Hash(), txIn.PreviousOut))
-						continue
-					}
-
-					// The transaction is referencing another
-					// transaction in the source pool, so setup an
-					// ordering dependency.
-					deps, exists := dependers[*originHash]
-					if !exists {
-						deps = make(map[hash.Hash]*WeightedRandTx)
-						dependers[*originHash] = deps
-					}
-					deps[*weirandItem.tx.Hash()] = weirandItem
-					if weirandItem.dependsOn == nil {
-						weirandItem.dependsOn = make(
-							map[hash.Hash]struct{})
-					}
-					weirandItem.dependsOn[*originHash] = struct{}{}
-
-					// Skip the check below. We already know the
-					// referenced transaction is available.
-					continue
-				}
-			}
-			// Merge the referenced outputs from the input transactions to
-			// this transaction into the block utxo view.  This allows the
-			// code below to avoid a second lookup.
-			mergeUtxoView(blockUtxos, utxos)
-		}
-		// Grab any transactions which depend on this one.
-		deps := dependers[*tx.Hash()]
-
-		// Enforce maximum block size.  Also check for overflow.
-		txSize := uint32(tx.Transaction().SerializeSize())
-		blockPlusTxSize := blockSize + txSize
-		if blockPlusTxSize < blockSize || blockPlusTxSize >= policy.BlockMaxSize {
-			log.Trace(fmt.Sprintf("Skipping tx %s (size %v) because it "+
+		// Grab the transaction with the highest fee per kilobyte of its
+		// package, along with the ancestors which have to be included
+		// before it.
+		prioItem := heap.Pop(priorityQueue).(*txPrioItem)
+		pkg := prioItem.packageItems()
+
+		// Enforce maximum block size for the whole package.  Also check
+		// for overflow.
+		pkgSize := uint32(prioItem.ancestorSize)
+		blockPlusPkgSize := blockSize + pkgSize
+		if blockPlusPkgSize < blockSize || blockPlusPkgSize >= policy.BlockMaxSize {
+			log.Trace(fmt.Sprintf("Skipping tx %s (package size %v) because it "+
 				"would exceed the max block size; cur block "+
-				"size %v, cur num tx %v", tx.Hash(), txSize,
+				"size %v, cur num tx %v", prioItem.tx.Hash(), pkgSize,
 				blockSize, len(blockTxns)))
-			logSkippedDeps(tx, deps)
+			priorityQueue.dropItem(prioItem)
 			continue
 		}
 
-		// Enforce maximum signature operation cost per block.  Also
-		// check for overflow.
-		sigOpCost := blockchain.CountSigOps(tx)
-		if blockSigOpCost+int64(sigOpCost) < blockSigOpCost ||
-			blockSigOpCost+int64(sigOpCost) > blockchain.MaxSigOpsPerBlock {
-			log.Trace(fmt.Sprintf("Skipping tx %s because it would "+
-				"exceed the maximum sigops per block", tx.Hash()))
-			logSkippedDeps(tx, deps)
+		// Enforce maximum signature operation cost per block for the
+		// whole package.  Also check for overflow.
+		pkgSigOpCost := int64(0)
+		for _, item := range pkg {
+			pkgSigOpCost += item.sigOpCost
+		}
+		if blockSigOpCost+pkgSigOpCost < blockSigOpCost ||
+			blockSigOpCost+pkgSigOpCost > blockchain.MaxSigOpsPerBlock {
+			log.Trace(fmt.Sprintf("Skipping tx %s because its package would "+
+				"exceed the maximum sigops per block", prioItem.tx.Hash()))
+			priorityQueue.dropItem(prioItem)
 			continue
 		}
 
-		// Skip free transactions once the block is larger than the
-		// minimum block size.
+		// Skip free packages once the block is larger than the minimum
+		// block size.
 		if sortedByFee &&
-			weirandItem.feePerKB < int64(policy.TxMinFreeFee) &&
-			(blockPlusTxSize >= policy.BlockMinSize) {
-			log.Trace(fmt.Sprintf("Skipping tx %s with feePerKB %.2d "+
+			prioItem.ancestorFeePerKB() < int64(policy.TxMinFreeFee) &&
+			(blockPlusPkgSize >= policy.BlockMinSize) {
+			log.Trace(fmt.Sprintf("Skipping tx %s with package feePerKB %.2d "+
 				"< TxMinFreeFee %d and block size %d >= "+
-				"minBlockSize %d", tx.Hash(), weirandItem.feePerKB,
-				policy.TxMinFreeFee, blockPlusTxSize,
-				policy.BlockMinSize))
-			logSkippedDeps(tx, deps)
+				"minBlockSize %d", prioItem.tx.Hash(),
+				prioItem.ancestorFeePerKB(), policy.TxMinFreeFee,
+				blockPlusPkgSize, policy.BlockMinSize))
+			priorityQueue.dropItem(prioItem)
 			continue
 		}
 
-		// Skip transactions once the tx time is invalid
-		// minimum block size.
-		if !tx.Tx.ValidTime(policy.TxTimeScope) {
-			log.Trace(fmt.Sprintf("Skipping tx %s with tx time %s is invalid", tx.Hash().String(),
-				tx.Tx.Timestamp.Format(time.RFC3339)))
-			logSkippedDeps(tx, deps)
-			continue
-		}
+		for _, item := range pkg {
+			tx := item.tx
 
-		// Ensure the transaction inputs pass all of the necessary
-		// preconditions before allowing it to be added to the block.
-		txFeesMap, err := bc.CheckTransactionInputs(tx, blockUtxos)
-		if err != nil {
-			log.Trace(fmt.Sprintf("Skipping tx %s due to error in "+
-				"CheckTransactionInputs: %v", tx.Hash(), err))
-			logSkippedDeps(tx, deps)
-			continue
-		}
-		err = blockchain.ValidateTransactionScripts(tx, blockUtxos,
-			scriptFlags, sigCache, int64(nextBlockHeight))
-		if err != nil {
-			log.Trace(fmt.Sprintf("Skipping tx %s due to error in "+
-				"ValidateTransactionScripts: %v", tx.Hash(), err))
-			logSkippedDeps(tx, deps)
-			continue
-		}
+			// Merge the referenced outputs from the input transactions
+			// to this transaction into the block utxo view.  The
+			// outputs of the ancestors in the source pool are already
+			// there since they were included first.
+			utxos, err := bc.FetchUtxoView(tx)
+			if err != nil {
+				log.Warn(fmt.Sprintf("Unable to fetch utxo view for tx %s: %v",
+					tx.Hash(), err))
+				priorityQueue.dropItem(item)
+				continue mempool
+			}
+			mergeUtxoView(blockUtxos, utxos)
 
-		// Spend the transaction inputs in the block utxo view and add
-		// an entry for it to ensure any transactions which reference
-		// this one have it available as an input and can ensure they
-		// aren't double spending.
-		err = spendTransaction(blockUtxos, tx, &hash.ZeroHash)
-		if err != nil {
-			log.Warn(fmt.Sprintf("Unable to spend transaction %v in the preliminary "+
-				"UTXO view for the block template: %v",
-				tx.Hash(), err))
-		}
-		// Add the transaction to the block, increment counters, and
-		// save the fees and signature operation counts to the block
-		// template.
-		blockTxns = append(blockTxns, tx)
-		blockSize += txSize
-		blockSigOpCost += int64(sigOpCost)
-		totalFees += weirandItem.fee
-		txFees = append(txFees, weirandItem.fee)
-		txSigOpCosts = append(txSigOpCosts, int64(sigOpCost))
-		lastBFMSize := len(blockFeesMap)
-		blockFeesMap.Add(txFeesMap)
-		addBFMSize := len(blockFeesMap) - lastBFMSize
-		if addBFMSize <= 0 {
-			addBFMSize = 0
-		}
-		if addBFMSize > 0 {
-			blockSigOpCost += int64(addBFMSize)
-		}
-		log.Trace(fmt.Sprintf("Adding tx %s (feePerKB %.2d)",
-			weirandItem.tx.Hash(), weirandItem.feePerKB))
-
-		// Add transactions which depend on this one (and also do not
-		// have any other unsatisified dependencies) to the priority
-		// queue.
-		for _, item := range deps {
-			// Add the transaction to the priority queue if there
-			// are no more dependencies after this one.
-			delete(item.dependsOn, *tx.Hash())
-			if len(item.dependsOn) == 0 {
-				weightedRandQueue.Push(item)
+			// Skip transactions once the tx time is invalid
+			// minimum block size.
+			if !tx.Tx.ValidTime(policy.TxTimeScope) {
+				log.Trace(fmt.Sprintf("Skipping tx %s with tx time %s is invalid", tx.Hash().String(),
+					tx.Tx.Timestamp.Format(time.RFC3339)))
+				priorityQueue.dropItem(item)
+				continue mempool
+			}
+
+			// Ensure the transaction inputs pass all of the necessary
+			// preconditions before allowing it to be added to the block.
+			txFeesMap, err := bc.CheckTransactionInputs(tx, blockUtxos)
+			if err != nil {
+				log.Trace(fmt.Sprintf("Skipping tx %s due to error in "+
+					"CheckTransactionInputs: %v", tx.Hash(), err))
+				priorityQueue.dropItem(item)
+				continue mempool
+			}
+			err = blockchain.ValidateTransactionScripts(tx, blockUtxos,
+				scriptFlags, sigCache, int64(nextBlockHeight))
+			if err != nil {
+				log.Trace(fmt.Sprintf("Skipping tx %s due to error in "+
+					"ValidateTransactionScripts: %v", tx.Hash(), err))
+				priorityQueue.dropItem(item)
+				continue mempool
 			}
+
+			// Spend the transaction inputs in the block utxo view and add
+			// an entry for it to ensure any transactions which reference
+			// this one have it available as an input and can ensure they
+			// aren't double spending.
+			err = spendTransaction(blockUtxos, tx, &hash.ZeroHash)
+			if err != nil {
+				log.Warn(fmt.Sprintf("Unable to spend transaction %v in the preliminary "+
+					"UTXO view for the block template: %v",
+					tx.Hash(), err))
+			}
+			// Add the transaction to the block, increment counters, and
+			// save the fees and signature operation counts to the block
+			// template.
+			blockTxns = append(blockTxns, tx)
+			blockSize += uint32(item.size)
+			blockSigOpCost += item.sigOpCost
+			totalFees += item.fee
+			txFees = append(txFees, item.fee)
+			txSigOpCosts = append(txSigOpCosts, item.sigOpCost)
+			lastBFMSize := len(blockFeesMap)
+			blockFeesMap.Add(txFeesMap)
+			addBFMSize := len(blockFeesMap) - lastBFMSize
+			if addBFMSize > 0 {
+				blockSigOpCost += int64(addBFMSize)
+			}
+			log.Trace(fmt.Sprintf("Adding tx %s (feePerKB %.2d)",
+				tx.Hash(), item.feePerKB))
+
+			// Remove the transaction from the packages of the
+			// transactions which depend on it.
+			priorityQueue.includeItem(item)
 		}
 	}
 	// Fill outputs
@@ -635,20 +670,6 @@ func mergeUtxoView(viewA *utxo.UtxoViewpoint, viewB *utxo.UtxoViewpoint) {
 	}
 }
 
-// TODO, move the log logic
-// logSkippedDeps logs any dependencies which are also skipped as a result of
-// skipping a transaction while generating a block template at the trace level.
-func logSkippedDeps(tx *types.Tx, deps map[hash.Hash]*WeightedRandTx) {
-	if deps == nil {
-		return
-	}
-
-	for _, item := range deps {
-		log.Trace(fmt.Sprintf("Skipping tx %s since it depends on %s\n",
-			item.tx.Hash(), tx.Hash()))
-	}
-}
-
 // spendTransaction updates the passed view by marking the inputs to the passed
 // transaction as spent.  It also adds all outputs in the passed transaction
 // which are not provably unspendable as available unspent transaction outputs.
@@ -0,0 +1,128 @@
+package mining
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/log"
+)
+
+// ancestorFeePerKB returns the fee per kilobyte of the transaction along with
+// its ancestors which are not included in the block yet.
+func (item *txPrioItem) ancestorFeePerKB() int64 {
+	if item.ancestorSize <= 0 {
+		return 0
+	}
+	return item.ancestorFee * 1000 / item.ancestorSize
+}
+
+// packageItems returns the transactions which have to be included in the block
+// for the transaction to be included, which are its ancestors not included yet
+// followed by the transaction itself.  The ancestors are sorted so every
+// transaction comes after the ones it depends on.
+func (item *txPrioItem) packageItems() []*txPrioItem {
+	items := make([]*txPrioItem, 0, len(item.ancestors)+1)
+	for _, ancestor := range item.ancestors {
+		items = append(items, ancestor)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].depth < items[j].depth
+	})
+	return append(items, item)
+}
+
+// linkTxPackages sets up the ancestors and descendants of the transactions
+// which depend on other transactions of the passed set, along with the total
+// fees and size of their packages.
+func linkTxPackages(items map[hash.Hash]*txPrioItem) {
+	var visit func(item *txPrioItem)
+	visit = func(item *txPrioItem) {
+		if item.ancestors != nil {
+			return
+		}
+		item.ancestors = make(map[hash.Hash]*txPrioItem)
+		for _, txIn := range item.tx.Tx.TxIn {
+			parentHash := txIn.PreviousOut.Hash
+			parent, ok := items[parentHash]
+			if !ok || parent == item {
+				continue
+			}
+			visit(parent)
+			item.ancestors[parentHash] = parent
+			for h, ancestor := range parent.ancestors {
+				item.ancestors[h] = ancestor
+			}
+		}
+	}
+
+	for _, item := range items {
+		visit(item)
+		if item.descendants == nil {
+			item.descendants = make(map[hash.Hash]*txPrioItem)
+		}
+	}
+	for h, item := range items {
+		item.depth = len(item.ancestors)
+		item.ancestorFee = item.fee
+		item.ancestorSize = item.size
+		for _, ancestor := range item.ancestors {
+			ancestor.descendants[h] = item
+			item.ancestorFee += ancestor.fee
+			item.ancestorSize += ancestor.size
+		}
+	}
+}
+
+// newTxPackageQueue returns a priority queue of the passed transactions sorted
+// by the fees per kilobyte of their packages.
+func newTxPackageQueue(items map[hash.Hash]*txPrioItem) *txPriorityQueue {
+	linkTxPackages(items)
+	pq := newTxPriorityQueue(len(items), txPQByAncestorFeeRate)
+	for _, item := range items {
+		heap.Push(pq, item)
+	}
+	return pq
+}
+
+// includeItem removes the transaction which was included in the block from the
+// queue and from the packages of its descendants, whose fees per kilobyte are
+// updated accordingly.
+func (pq *txPriorityQueue) includeItem(item *txPrioItem) {
+	if item.index >= 0 {
+		heap.Remove(pq, item.index)
+	}
+	txHash := *item.tx.Hash()
+	for _, ancestor := range item.ancestors {
+		delete(ancestor.descendants, txHash)
+	}
+	for _, descendant := range item.descendants {
+		delete(descendant.ancestors, txHash)
+		descendant.ancestorFee -= item.fee
+		descendant.ancestorSize -= item.size
+		if descendant.index >= 0 {
+			heap.Fix(pq, descendant.index)
+		}
+	}
+}
+
+// dropItem removes the transaction which can't be included in the block from
+// the queue along with all of its descendants, since they depend on it.
+func (pq *txPriorityQueue) dropItem(item *txPrioItem) {
+	drop := []*txPrioItem{item}
+	for _, descendant := range item.descendants {
+		log.Trace(fmt.Sprintf("Skipping tx %s since it depends on %s",
+			descendant.tx.Hash(), item.tx.Hash()))
+		drop = append(drop, descendant)
+	}
+	for _, dropped := range drop {
+		if dropped.index >= 0 {
+			heap.Remove(pq, dropped.index)
+		}
+		txHash := *dropped.tx.Hash()
+		for _, ancestor := range dropped.ancestors {
+			delete(ancestor.descendants, txHash)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package mining
+
+import (
+	"container/heap"
+	"testing"
+
+	"github.com/Qitmeer/qng/common/hash"
+	"github.com/Qitmeer/qng/core/types"
+)
+
+// newTestPrioItem returns a queue item for a transaction spending the outputs.
+func newTestPrioItem(fee int64, prevOuts ...*types.TxOutPoint) *txPrioItem {
+	tx := types.NewTransaction()
+	for _, prevOut := range prevOuts {
+		tx.AddTxIn(types.NewTxInput(prevOut, nil))
+	}
+	tx.AddTxOut(types.NewTxOutput(types.Amount{Value: 1e8, Id: types.MEERA}, []byte{0x51}))
+	size := int64(tx.SerializeSize())
+	return &txPrioItem{
+		tx:       types.NewTx(tx),
+		fee:      fee,
+		feePerKB: fee * 1000 / size,
+		size:     size,
+		index:    -1,
+	}
+}
+
+func TestTxPackageQueue(t *testing.T) {
+	parent := newTestPrioItem(0, types.NewOutPoint(&hash.Hash{0x01}, 0))
+	child := newTestPrioItem(10000, types.NewOutPoint(parent.tx.Hash(), 0))
+	single := newTestPrioItem(1000, types.NewOutPoint(&hash.Hash{0x02}, 0))
+	items := map[hash.Hash]*txPrioItem{}
+	for _, item := range []*txPrioItem{parent, child, single} {
+		items[*item.tx.Hash()] = item
+	}
+	pq := newTxPackageQueue(items)
+
+	// The child pays for its parent, so its package comes first.
+	item := heap.Pop(pq).(*txPrioItem)
+	if item != child {
+		t.Fatalf("got tx %v, want the child", item.tx.Hash())
+	}
+	pkg := item.packageItems()
+	if len(pkg) != 2 || pkg[0] != parent || pkg[1] != child {
+		t.Fatalf("got package of %d txs, want the parent then the child", len(pkg))
+	}
+	for _, item := range pkg {
+		pq.includeItem(item)
+	}
+	if pq.Len() != 1 || heap.Pop(pq).(*txPrioItem) != single {
+		t.Fatalf("the package was not removed from the queue")
+	}
+}
+
+func TestTxPackageQueueDrop(t *testing.T) {
+	parent := newTestPrioItem(1000, types.NewOutPoint(&hash.Hash{0x01}, 0))
+	child := newTestPrioItem(1000, types.NewOutPoint(parent.tx.Hash(), 0))
+	grandchild := newTestPrioItem(1000, types.NewOutPoint(child.tx.Hash(), 0))
+	items := map[hash.Hash]*txPrioItem{}
+	for _, item := range []*txPrioItem{parent, child, grandchild} {
+		items[*item.tx.Hash()] = item
+	}
+	pq := newTxPackageQueue(items)
+	if grandchild.depth != 2 || grandchild.ancestorFee != 3000 {
+		t.Fatalf("got depth %d and package fee %d, want 2 and 3000",
+			grandchild.depth, grandchild.ancestorFee)
+	}
+
+	pq.includeItem(parent)
+	if grandchild.ancestorFee != 2000 || len(grandchild.ancestors) != 1 {
+		t.Fatalf("the included parent was not removed from the package")
+	}
+	pq.dropItem(child)
+	if pq.Len() != 0 {
+		t.Fatalf("got %d txs in the queue, want the descendants dropped", pq.Len())
+	}
+}
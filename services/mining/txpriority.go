@@ -10,16 +10,35 @@ import (
 // transaction to be prioritized and track dependencies on other transactions
 // which have not been mined into a block yet.
 type txPrioItem struct {
-	tx       *types.Tx
-	fee      int64
-	priority float64
-	feePerKB int64
-
-	// dependsOn holds a map of transaction hashes which this one depends
-	// on.  It will only be set when the transaction references other
-	// transactions in the source pool and hence must come after them in
-	// a block.
-	dependsOn map[hash.Hash]struct{}
+	tx        *types.Tx
+	fee       int64
+	priority  float64
+	feePerKB  int64
+	size      int64
+	sigOpCost int64
+
+	// index is the position of the item in the priority queue, it is -1
+	// once the item is no longer queued.
+	index int
+
+	// ancestors holds the transactions in the source pool which this one
+	// depends on, recursively, and which have not been included in the
+	// block yet.  They must come before it in a block.
+	ancestors map[hash.Hash]*txPrioItem
+
+	// descendants holds the transactions in the source pool which depend
+	// on this one, recursively.
+	descendants map[hash.Hash]*txPrioItem
+
+	// ancestorFee and ancestorSize are the total fees and size of the
+	// transaction along with its ancestors, which is the package that has
+	// to be included in the block for the transaction to be included.
+	ancestorFee  int64
+	ancestorSize int64
+
+	// depth is the number of the ancestors of the transaction in the source
+	// pool, ancestors always have a lower depth than their descendants.
+	depth int
 }
 
 // txPriorityQueueLessFunc describes a function that can be used as a compare
@@ -50,12 +69,16 @@ func (pq *txPriorityQueue) Less(i, j int) bool {
 // part of the heap.Interface implementation.
 func (pq *txPriorityQueue) Swap(i, j int) {
 	pq.items[i], pq.items[j] = pq.items[j], pq.items[i]
+	pq.items[i].index = i
+	pq.items[j].index = j
 }
 
 // Push pushes the passed item onto the priority queue.  It is part of the
 // heap.Interface implementation.
 func (pq *txPriorityQueue) Push(x interface{}) {
-	pq.items = append(pq.items, x.(*txPrioItem))
+	item := x.(*txPrioItem)
+	item.index = len(pq.items)
+	pq.items = append(pq.items, item)
 }
 
 // Pop removes the highest priority item (according to Less) from the priority
@@ -63,6 +86,7 @@ func (pq *txPriorityQueue) Push(x interface{}) {
 func (pq *txPriorityQueue) Pop() interface{} {
 	n := len(pq.items)
 	item := pq.items[n-1]
+	item.index = -1
 	pq.items[n-1] = nil
 	pq.items = pq.items[0 : n-1]
 	return item
@@ -113,3 +137,18 @@ func txPQByPriority(pq *txPriorityQueue, i, j int) bool {
 	return pq.items[i].priority > pq.items[j].priority
 
 }
+
+// txPQByAncestorFeeRate sorts a txPriorityQueue by the fees per kilobyte of
+// the packages of the transactions, which are the transactions along with
+// their ancestors that are not included in the block yet, and then by the fees
+// per kilobyte of the transactions themselves.  A transaction paying a high
+// fee therefore pulls its low-fee ancestors into the block, which is known as
+// child-pays-for-parent (CPFP).
+func txPQByAncestorFeeRate(pq *txPriorityQueue, i, j int) bool {
+	feePerKBI := pq.items[i].ancestorFeePerKB()
+	feePerKBJ := pq.items[j].ancestorFeePerKB()
+	if feePerKBI == feePerKBJ {
+		return pq.items[i].feePerKB > pq.items[j].feePerKB
+	}
+	return feePerKBI > feePerKBJ
+}
@@ -84,6 +84,15 @@ func (ntmgr *NotifyMgr) BroadcastMessage(data interface{}) {
 	ntmgr.Server.BroadcastMessage(data)
 }
 
+// StemTransaction passes the own transaction to Dandelion++ stem, it's added
+// to the mempool when it's diffused.
+func (ntmgr *NotifyMgr) StemTransaction(tx *types.Tx) bool {
+	if ntmgr.IsShutdown() {
+		return false
+	}
+	return ntmgr.Server.StemTransaction(tx)
+}
+
 func (ntmgr *NotifyMgr) AddRebroadcastInventory(newTxs []*types.TxDesc) {
 	if ntmgr.IsShutdown() {
 		return
@@ -860,6 +860,13 @@ func (api *PrivateTxAPI) TxSign(privkeyStr string, rawTxStr string, tokenPrivkey
 				return nil, fmt.Errorf("error:%d", len(tprivkeyByte))
 			}
 			tokenPrivkey, _ = ecc.Secp256k1.PrivKeyFromBytes(tprivkeyByte)
+		} else if types.IsStakeDisposeTx(&redeemTx) {
+			// The stake holder signs the marker input of the stake_dispose.
+			tokenPkScript, err = api.txManager.GetChain().GetCurStakeHolder(&redeemTx.TxIn[0].PreviousOut.Hash)
+			if err != nil {
+				return nil, err
+			}
+			tokenPrivkey = privateKey
 		}
 		for i := 0; i < len(redeemTx.TxIn); i++ {
 			if i == 0 && len(tokenPkScript) > 0 {
@@ -0,0 +1,249 @@
+package tx
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/Qitmeer/qng/common/marshal"
+	"github.com/Qitmeer/qng/core/json"
+	"github.com/Qitmeer/qng/core/types"
+	"github.com/Qitmeer/qng/core/types/psbt"
+	"github.com/Qitmeer/qng/params"
+	"github.com/Qitmeer/qng/rpc"
+)
+
+// fillPrevOuts sets the previous outputs of the inputs which are unknown by
+// the packet, they're looked up from the mempool and the UTXO set.
+func (tm *TxManager) fillPrevOuts(p *psbt.Packet) error {
+	for i, in := range p.Inputs {
+		if in.PrevOut != nil || in.IsFinalized() {
+			continue
+		}
+		op := p.UnsignedTx.TxIn[i].PreviousOut
+		tx, _ := tm.txMemPool.FetchTransaction(&op.Hash)
+		if tx != nil {
+			if op.OutIndex >= uint32(len(tx.Tx.TxOut)) {
+				return fmt.Errorf("input %d: output index %d out of range", i, op.OutIndex)
+			}
+			out := tx.Tx.TxOut[op.OutIndex]
+			err := p.AddPrevOut(i, out.Amount, out.PkScript)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		entry, err := tm.GetChain().FetchUtxoEntry(op)
+		if err != nil {
+			return err
+		}
+		if entry == nil || entry.IsSpent() {
+			return fmt.Errorf("input %d: %s:%d is not an unspent output", i, op.Hash.String(), op.OutIndex)
+		}
+		err = p.AddPrevOut(i, entry.Amount(), entry.PkScript())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodePsbt(str string) (*psbt.Packet, error) {
+	p, err := psbt.NewFromBase64(str)
+	if err != nil {
+		return nil, rpc.RpcDeserializationError("Could not decode partially signed transaction: %v", err)
+	}
+	return p, nil
+}
+
+// CreatePsbt creates a partially signed transaction of the inputs and
+// outputs, the previous outputs of inputs are filled by the UTXO set.
+func (api *PublicTxAPI) CreatePsbt(inputs []json.TransactionInput, amounts json.AdreesAmount, lockTime *int64) (interface{}, error) {
+	raw, err := api.txManager.CreateRawTransactionV2(inputs, amounts, lockTime)
+	if err != nil {
+		return nil, err
+	}
+	serializedTx, err := hex.DecodeString(raw.(string))
+	if err != nil {
+		return nil, err
+	}
+	var tx types.Transaction
+	err = tx.Deserialize(bytes.NewReader(serializedTx))
+	if err != nil {
+		return nil, err
+	}
+	p, err := psbt.New(&tx)
+	if err != nil {
+		return nil, err
+	}
+	err = api.txManager.fillPrevOuts(p)
+	if err != nil {
+		return nil, err
+	}
+	return p.B64Encode()
+}
+
+// UpdatePsbt fills the previous outputs of inputs and adds the redeem
+// scripts to the pay-to-script-hash inputs.
+func (api *PublicTxAPI) UpdatePsbt(psbtStr string, redeemScripts *[]string) (interface{}, error) {
+	p, err := decodePsbt(psbtStr)
+	if err != nil {
+		return nil, err
+	}
+	err = api.txManager.fillPrevOuts(p)
+	if err != nil {
+		return nil, err
+	}
+	if redeemScripts == nil {
+		return p.B64Encode()
+	}
+	for _, rs := range *redeemScripts {
+		script, err := hex.DecodeString(rs)
+		if err != nil {
+			return nil, rpc.RpcDecodeHexError(rs)
+		}
+		if p.AddRedeemScript(script) <= 0 {
+			return nil, fmt.Errorf("No input matches the redeem script %s", rs)
+		}
+	}
+	return p.B64Encode()
+}
+
+// CombinePsbt merges the signatures of the partially signed transactions.
+func (api *PublicTxAPI) CombinePsbt(psbts []string) (interface{}, error) {
+	if len(psbts) <= 0 {
+		return nil, fmt.Errorf("No partially signed transaction")
+	}
+	packets := make([]*psbt.Packet, 0, len(psbts))
+	for _, str := range psbts {
+		p, err := decodePsbt(str)
+		if err != nil {
+			return nil, err
+		}
+		packets = append(packets, p)
+	}
+	err := packets[0].Combine(packets[1:]...)
+	if err != nil {
+		return nil, err
+	}
+	return packets[0].B64Encode()
+}
+
+// FinalizePsbt builds the signature scripts of inputs, the signed
+// transaction is returned if all the inputs are finalized and extract is
+// true by default.
+func (api *PublicTxAPI) FinalizePsbt(psbtStr string, extract *bool) (interface{}, error) {
+	p, err := decodePsbt(psbtStr)
+	if err != nil {
+		return nil, err
+	}
+	ferr := p.Finalize()
+	result := json.OrderedResult{}
+	str, err := p.B64Encode()
+	if err != nil {
+		return nil, err
+	}
+	result = append(result, json.KV{Key: "psbt", Val: str})
+	if p.IsComplete() && (extract == nil || *extract) {
+		tx, err := p.Extract()
+		if err != nil {
+			return nil, err
+		}
+		mtxHex, err := marshal.MessageToHex(tx)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, json.KV{Key: "hex", Val: mtxHex})
+	}
+	result = append(result, json.KV{Key: "complete", Val: p.IsComplete()})
+	if ferr != nil {
+		result = append(result, json.KV{Key: "error", Val: ferr.Error()})
+	}
+	return result, nil
+}
+
+// DecodePsbt returns the transaction and the signing status of inputs.
+func (api *PublicTxAPI) DecodePsbt(psbtStr string) (interface{}, error) {
+	p, err := decodePsbt(psbtStr)
+	if err != nil {
+		return nil, err
+	}
+	tx := p.UnsignedTx
+	inputs := []json.OrderedResult{}
+	in := int64(0)
+	known := true
+	for i, pin := range p.Inputs {
+		class, have, required := p.InputStatus(i)
+		input := json.OrderedResult{}
+		if pin.PrevOut != nil {
+			input = append(input,
+				json.KV{Key: "amount", Val: pin.PrevOut.Amount.Value},
+				json.KV{Key: "coinid", Val: pin.PrevOut.Amount.Id},
+				json.KV{Key: "pkscript", Val: hex.EncodeToString(pin.PrevOut.PkScript)},
+				json.KV{Key: "type", Val: class.String()})
+			if pin.PrevOut.Amount.Id == types.MEERA {
+				in += pin.PrevOut.Amount.Value
+			}
+		} else {
+			known = false
+		}
+		if len(pin.RedeemScript) > 0 {
+			input = append(input, json.KV{Key: "redeemscript", Val: hex.EncodeToString(pin.RedeemScript)})
+		}
+		input = append(input, json.KV{Key: "final", Val: pin.IsFinalized()})
+		if !pin.IsFinalized() {
+			input = append(input,
+				json.KV{Key: "signatures", Val: have},
+				json.KV{Key: "required", Val: required})
+		}
+		inputs = append(inputs, input)
+	}
+	result := json.OrderedResult{
+		{Key: "txid", Val: tx.TxHash().String()},
+		{Key: "version", Val: int32(tx.Version)},
+		{Key: "locktime", Val: tx.LockTime},
+		{Key: "vin", Val: marshal.MarshJsonVin(tx)},
+		{Key: "vout", Val: marshal.MarshJsonVout(tx, nil, params.ActiveNetParams.Params)},
+		{Key: "inputs", Val: inputs},
+	}
+	if known {
+		out := int64(0)
+		for _, o := range tx.TxOut {
+			if o.Amount.Id == types.MEERA {
+				out += o.Amount.Value
+			}
+		}
+		result = append(result, json.KV{Key: "fee", Val: in - out})
+	}
+	result = append(result, json.KV{Key: "complete", Val: p.IsComplete()})
+	return result, nil
+}
+
+// SignPsbt adds the signatures of the private keys to the partially signed
+// transaction.
+func (api *PrivateTxAPI) SignPsbt(psbtStr string, privkeys []string) (interface{}, error) {
+	p, err := decodePsbt(psbtStr)
+	if err != nil {
+		return nil, err
+	}
+	signed := 0
+	for _, k := range privkeys {
+		key, err := hex.DecodeString(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid private key")
+		}
+		n, err := p.Sign(key)
+		if err != nil {
+			return nil, err
+		}
+		signed += n
+	}
+	str, err := p.B64Encode()
+	if err != nil {
+		return nil, err
+	}
+	return json.OrderedResult{
+		{Key: "psbt", Val: str},
+		{Key: "signed", Val: signed},
+	}, nil
+}
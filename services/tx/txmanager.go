@@ -11,6 +11,7 @@ import (
 	"github.com/Qitmeer/qng/consensus/model"
 	"github.com/Qitmeer/qng/core/address"
 	"github.com/Qitmeer/qng/core/blockchain"
+	"github.com/Qitmeer/qng/core/blockchain/opreturn"
 	"github.com/Qitmeer/qng/core/json"
 	"github.com/Qitmeer/qng/core/message"
 	"github.com/Qitmeer/qng/core/types"
@@ -251,9 +252,14 @@ func NewTxManager(consensus model.Consensus, ntmgr model.Notify) (*TxManager, er
 			MaxTxVersion:         2,
 			DisableRelayPriority: cfg.NoRelayPriority,
 			AcceptNonStd:         cfg.AcceptNonStd,
+			RejectReplacement:    cfg.RejectReplacement,
 			FreeTxRelayLimit:     cfg.FreeTxRelayLimit,
 			MaxOrphanTxs:         cfg.MaxOrphanTxs,
 			MaxOrphanTxSize:      mempool.DefaultMaxOrphanTxSize,
+			MaxAncestorCount:     cfg.LimitAncestorCount,
+			MaxAncestorSize:      mempool.DefaultMaxAncestorSize,
+			MaxDescendantCount:   cfg.LimitDescendantCount,
+			MaxDescendantSize:    mempool.DefaultMaxDescendantSize,
 			MaxSigOpsPerTx:       blockchain.MaxSigOpsPerBlock / 5,
 			MaxTxSize:            int64(cfg.BlockMaxSize - types.MaxBlockHeaderPayload),
 			MinRelayTxFee:        *amt,
@@ -302,8 +308,17 @@ func (tm *TxManager) ProcessRawTx(serializedTx []byte, highFees bool) (string, e
 	}
 
 	tx := types.NewTx(msgtx)
-	acceptedTxs, err := tm.txMemPool.ProcessTransaction(tx, false,
-		false, highFees)
+	// The transaction in Dandelion++ stem phase is only checked, it's added
+	// to the mempool when it's diffused. The meerevm transactions can't be
+	// checked without adding them.
+	stem := tm.consensus.Config().Dandelion && !opreturn.IsMeerEVMTx(msgtx)
+	var acceptedTxs []*types.TxDesc
+	if stem {
+		err = tm.txMemPool.CheckTransaction(tx, false, highFees)
+	} else {
+		acceptedTxs, err = tm.txMemPool.ProcessTransaction(tx, false,
+			false, highFees)
+	}
 	if err != nil {
 		// When the error is a rule error, it means the transaction was
 		// simply rejected as opposed to something actually going
@@ -332,6 +347,12 @@ func (tm *TxManager) ProcessRawTx(serializedTx []byte, highFees bool) (string, e
 			tx.Hash(), err)
 		return "", rpc.RpcDeserializationError("rejected: %v", err)
 	}
+	if stem {
+		if !tm.ntmgr.StemTransaction(tx) {
+			return "", rpc.RpcDuplicateTxError("already have transaction %v", tx.Hash())
+		}
+		return tx.Hash().String(), nil
+	}
 	tm.ntmgr.AnnounceNewTransactions(acceptedTxs, nil)
 	tm.ntmgr.AddRebroadcastInventory(acceptedTxs)
 	return tx.Hash().String(), nil
@@ -10,6 +10,7 @@ import (
 
 	"github.com/Qitmeer/qng/core/address"
 	"github.com/Qitmeer/qng/core/json"
+	"github.com/Qitmeer/qng/log"
 	"github.com/ethereum/go-ethereum/cmd/utils"
 )
 
@@ -116,3 +117,132 @@ func (api *PrivateWalletManagerAPI) SendToAddress(fromAddress string, to string,
 
 	return api.a.sendTx(fromAddress, amounts, 0, lockTime)
 }
+
+// CreateHDWallet creates the HD wallet whose seed is encrypted by the
+// passphrase. A new mnemonic is generated if it's empty, the mnemonic is
+// returned and must be backed up.
+func (api *PrivateWalletManagerAPI) CreateHDWallet(passphrase string, mnemonic *string) (interface{}, error) {
+	m := ""
+	if mnemonic != nil {
+		m = *mnemonic
+	}
+	m, err := api.a.hd.Create(m, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if api.a.cfg.AcctMode {
+		go func() {
+			err := api.a.discoverHDAddresses()
+			if err != nil {
+				log.Error("HD wallet address discovery", "error", err)
+			}
+		}()
+	}
+	return map[string]interface{}{
+		"mnemonic": m,
+		"xpub":     api.a.hd.XPub(),
+	}, nil
+}
+
+// UnlockHDWallet decrypts the seed of HD wallet for timeout seconds, it's
+// unlocked until LockHDWallet if the timeout is zero.
+func (api *PrivateWalletManagerAPI) UnlockHDWallet(passphrase string, timeout time.Duration) error {
+	return api.a.hd.Unlock(passphrase, timeout*time.Second)
+}
+
+func (api *PrivateWalletManagerAPI) LockHDWallet() error {
+	api.a.hd.Lock()
+	return nil
+}
+
+// GetNewAddress returns the next unused external address of HD wallet.
+func (api *PrivateWalletManagerAPI) GetNewAddress() (string, error) {
+	addr, err := api.a.hd.NewAddress(hdExternalBranch)
+	if err != nil {
+		return "", err
+	}
+	if api.a.cfg.AcctMode {
+		err = api.a.am.AddAddresses([]string{addr})
+		if err != nil {
+			return "", err
+		}
+	}
+	return addr, nil
+}
+
+// DiscoverHDAddresses adds the used addresses of HD wallet and the following
+// gap limit addresses to the account manager.
+func (api *PrivateWalletManagerAPI) DiscoverHDAddresses() (interface{}, error) {
+	err := api.a.discoverHDAddresses()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		hdBranchNames[hdExternalBranch]: api.a.hd.Next(hdExternalBranch),
+		hdBranchNames[hdInternalBranch]: api.a.hd.Next(hdInternalBranch),
+	}, nil
+}
+
+// ListHDAddresses returns the addresses of HD wallet which are used or
+// issued.
+func (api *PrivateWalletManagerAPI) ListHDAddresses() (interface{}, error) {
+	res := []map[string]interface{}{}
+	for branch := hdExternalBranch; branch <= hdInternalBranch; branch++ {
+		addrs, err := api.a.hd.Addresses(branch, 0, api.a.hd.Next(branch))
+		if err != nil {
+			return nil, err
+		}
+		for i, addr := range addrs {
+			res = append(res, map[string]interface{}{
+				"address": addr,
+				"branch":  hdBranchNames[branch],
+				"path":    api.a.hd.PathString(hdKeyPath{Branch: branch, Index: uint32(i)}),
+			})
+		}
+	}
+	return res, nil
+}
+
+// SendFromHDWallet sends to the addresses by the outputs of all the addresses
+// of HD wallet. The change is sent to a new internal address.
+func (api *PrivateWalletManagerAPI) SendFromHDWallet(to string, lockTime int64) (string, error) {
+	var amounts json.AddressAmountV3
+	err := ejson.Unmarshal([]byte(to), &amounts)
+	if err != nil {
+		return "", err
+	}
+	for _, a := range amounts {
+		if a.Amount <= 0 {
+			return "", fmt.Errorf("amount must be positive")
+		}
+	}
+	if api.a.hd.IsLocked() {
+		return "", fmt.Errorf("please unlock HD wallet first")
+	}
+	addrs := []string{}
+	for branch := hdExternalBranch; branch <= hdInternalBranch; branch++ {
+		as, err := api.a.hd.Addresses(branch, 0, api.a.hd.Next(branch)+api.a.hd.GapLimit())
+		if err != nil {
+			return "", err
+		}
+		addrs = append(addrs, as...)
+	}
+	candidates, err := api.a.getCandidates(addrs)
+	if err != nil {
+		return "", err
+	}
+	if len(candidates) <= 0 {
+		return "", fmt.Errorf("HD wallet balance not enough")
+	}
+	change, err := api.a.hd.NewAddress(hdInternalBranch)
+	if err != nil {
+		return "", err
+	}
+	if api.a.cfg.AcctMode {
+		err = api.a.am.AddAddresses([]string{change})
+		if err != nil {
+			return "", err
+		}
+	}
+	return api.a.sendWithCoinSelection(candidates, amounts, change, 0, lockTime)
+}
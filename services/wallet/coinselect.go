@@ -0,0 +1,237 @@
+package wallet
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/Qitmeer/qng/core/types"
+)
+
+// The serialized sizes which are used to estimate the fee before the
+// transaction is signed. The input is a pay-to-pubkey-hash input with a
+// compressed public key, which is the largest standard input of wallet.
+const (
+	estimateTxOverhead = 20
+	estimateInputSize  = 149
+	estimateOutputSize = 36
+
+	// dustSpendSize is the size of the typical input which spends an
+	// output, it's the same as the dust rule of mempool.
+	dustSpendSize = 165
+
+	// bnbMaxTries is the maximum number of branches which are visited by
+	// the branch-and-bound search.
+	bnbMaxTries = 100000
+
+	// knapsackIterations is the number of random passes of the knapsack
+	// approximation.
+	knapsackIterations = 1000
+)
+
+// coinCandidate is an unspent output which can be spent by wallet.
+type coinCandidate struct {
+	Address  string
+	TxID     string
+	OutIndex uint32
+	Amount   types.Amount
+}
+
+// coinSelection is the inputs which are selected to pay a target amount of
+// one coin.
+type coinSelection struct {
+	Inputs []*coinCandidate
+	Total  int64
+	Fee    int64
+	Change int64
+}
+
+// feeForSize returns the fee of size bytes by the fee rate in atoms/kB.
+func feeForSize(size int, feeRate int64) int64 {
+	return int64(size) * feeRate / 1000
+}
+
+// dustLimit returns the minimum change amount which isn't dust by the
+// minimum relay fee rate.
+func dustLimit(minRelayFee int64) int64 {
+	return 3 * feeForSize(estimateOutputSize+dustSpendSize, minRelayFee)
+}
+
+// selectCoins selects the inputs to pay the target by the fee rate. The
+// baseSize is the size of transaction except the inputs and the change of
+// this coin. The change which is less than minChange is added to the fee.
+//
+// The branch-and-bound search is tried first, it finds the inputs which
+// don't need a change output. The knapsack approximation is used if there
+// are no such inputs.
+func selectCoins(candidates []*coinCandidate, target int64, baseSize int, feeRate int64, minChange int64, rnd *rand.Rand) (*coinSelection, error) {
+	if target < 0 {
+		return nil, fmt.Errorf("Invalid target amount %d", target)
+	}
+	inputFee := feeForSize(estimateInputSize, feeRate)
+	changeFee := feeForSize(estimateOutputSize, feeRate)
+	baseFee := feeForSize(baseSize, feeRate)
+
+	// The inputs whose value can't pay for themselves are ignored
+	useful := make([]*coinCandidate, 0, len(candidates))
+	for _, c := range candidates {
+		if c.Amount.Value-inputFee > 0 {
+			useful = append(useful, c)
+		}
+	}
+	sort.SliceStable(useful, func(i, j int) bool {
+		return useful[i].Amount.Value > useful[j].Amount.Value
+	})
+	effs := make([]int64, len(useful))
+	for i, c := range useful {
+		effs[i] = c.Amount.Value - inputFee
+	}
+
+	result := func(indexes []int, change int64) *coinSelection {
+		cs := &coinSelection{Change: change}
+		for _, i := range indexes {
+			cs.Inputs = append(cs.Inputs, useful[i])
+			cs.Total += useful[i].Amount.Value
+		}
+		cs.Fee = cs.Total - target - change
+		return cs
+	}
+
+	// The excess is given to the fee if it costs less than a change output
+	// which is created and spent later.
+	if indexes := selectBnB(effs, target+baseFee, changeFee+inputFee); indexes != nil {
+		return result(indexes, 0), nil
+	}
+	need := target + baseFee + changeFee
+	indexes := selectKnapsack(effs, need, rnd)
+	if indexes == nil {
+		return nil, fmt.Errorf("Insufficient funds: need %d", need)
+	}
+	sum := int64(0)
+	for _, i := range indexes {
+		sum += effs[i]
+	}
+	change := sum - need
+	if change < minChange {
+		change = 0
+	}
+	return result(indexes, change), nil
+}
+
+// selectBnB searches the inputs whose effective value is in the range of
+// [target, target+costOfChange], and returns the one with the least waste.
+// The effective values must be sorted in descending order.
+func selectBnB(effs []int64, target int64, costOfChange int64) []int {
+	remaining := int64(0)
+	for _, v := range effs {
+		remaining += v
+	}
+	if remaining < target {
+		return nil
+	}
+	var best []int
+	bestWaste := int64(math.MaxInt64)
+	tries := 0
+
+	var search func(i int, value int64, remaining int64, selected []int)
+	search = func(i int, value int64, remaining int64, selected []int) {
+		tries++
+		if tries > bnbMaxTries {
+			return
+		}
+		if value > target+costOfChange || value+remaining < target {
+			return
+		}
+		if value >= target {
+			if waste := value - target; waste < bestWaste {
+				bestWaste = waste
+				best = append([]int{}, selected...)
+			}
+			return
+		}
+		if i >= len(effs) {
+			return
+		}
+		remaining -= effs[i]
+		search(i+1, value+effs[i], remaining, append(selected, i))
+		if bestWaste == 0 {
+			return
+		}
+		search(i+1, value, remaining, selected)
+	}
+	search(0, 0, remaining, nil)
+	return best
+}
+
+// selectKnapsack returns the inputs whose effective value is at least the
+// target. It prefers an exact match, then the best subset of the smaller
+// inputs and the smallest larger input.
+func selectKnapsack(effs []int64, target int64, rnd *rand.Rand) []int {
+	lower := []int{}
+	lowerSum := int64(0)
+	larger := -1
+	for i, v := range effs {
+		if v == target {
+			return []int{i}
+		}
+		if v < target {
+			lower = append(lower, i)
+			lowerSum += v
+		} else if larger < 0 || v < effs[larger] {
+			larger = i
+		}
+	}
+	if lowerSum == target {
+		return lower
+	}
+	if lowerSum < target {
+		if larger < 0 {
+			return nil
+		}
+		return []int{larger}
+	}
+
+	// Approximate the best subset of the smaller inputs by random passes
+	best := make([]bool, len(lower))
+	for i := range best {
+		best[i] = true
+	}
+	bestSum := lowerSum
+	included := make([]bool, len(lower))
+	for rep := 0; rep < knapsackIterations && bestSum != target; rep++ {
+		for i := range included {
+			included[i] = false
+		}
+		sum := int64(0)
+		reached := false
+		for pass := 0; pass < 2 && !reached; pass++ {
+			for i, idx := range lower {
+				if pass == 0 && rnd.Intn(2) == 0 || pass == 1 && included[i] {
+					continue
+				}
+				sum += effs[idx]
+				included[i] = true
+				if sum >= target {
+					reached = true
+					if sum < bestSum {
+						bestSum = sum
+						copy(best, included)
+					}
+					sum -= effs[idx]
+					included[i] = false
+				}
+			}
+		}
+	}
+	if larger >= 0 && (bestSum != target && effs[larger] <= bestSum) {
+		return []int{larger}
+	}
+	result := []int{}
+	for i, idx := range lower {
+		if best[i] {
+			result = append(result, idx)
+		}
+	}
+	return result
+}
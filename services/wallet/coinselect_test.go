@@ -0,0 +1,87 @@
+package wallet
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/Qitmeer/qng/core/types"
+)
+
+func testCandidates(coin types.CoinID, values ...int64) []*coinCandidate {
+	result := make([]*coinCandidate, 0, len(values))
+	for i, v := range values {
+		result = append(result, &coinCandidate{
+			TxID:     "tx",
+			OutIndex: uint32(i),
+			Amount:   types.Amount{Value: v, Id: coin},
+		})
+	}
+	return result
+}
+
+func TestSelectCoins(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	feeRate := int64(1000)
+	baseSize := estimateTxOverhead + estimateOutputSize
+	baseFee := feeForSize(baseSize, feeRate)
+	inputFee := feeForSize(estimateInputSize, feeRate)
+	minChange := dustLimit(feeRate)
+
+	// Two inputs pay the target and the fee exactly, no change is needed
+	target := int64(3e8)
+	candidates := testCandidates(types.MEERA, 5e8, 1e8+inputFee, 2e8+inputFee+baseFee, 7e8)
+	cs, err := selectCoins(candidates, target, baseSize, feeRate, minChange, rnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cs.Inputs) != 2 || cs.Change != 0 || cs.Fee != baseFee+2*inputFee {
+		t.Fatalf("unexpected selection: inputs %d change %d fee %d", len(cs.Inputs), cs.Change, cs.Fee)
+	}
+
+	// The change output is created when there is no exact match
+	candidates = testCandidates(types.MEERA, 5e8, 7e8)
+	cs, err = selectCoins(candidates, target, baseSize, feeRate, minChange, rnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cs.Inputs) != 1 || cs.Total != 5e8 || cs.Change <= 0 {
+		t.Fatalf("unexpected selection: inputs %d total %d change %d", len(cs.Inputs), cs.Total, cs.Change)
+	}
+	if cs.Total != target+cs.Change+cs.Fee || cs.Fee < baseFee+inputFee {
+		t.Fatalf("unbalanced selection: total %d change %d fee %d", cs.Total, cs.Change, cs.Fee)
+	}
+
+	// The dust change is added to the fee
+	changeFee := feeForSize(estimateOutputSize, feeRate)
+	dust := target + baseFee + inputFee + changeFee + minChange - 1
+	candidates = testCandidates(types.MEERA, dust+changeFee+inputFee+1, dust)
+	cs, err = selectCoins(candidates, target, baseSize, feeRate, minChange, rnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cs.Change != 0 || cs.Total != dust || cs.Fee != dust-target {
+		t.Fatalf("the dust change isn't added to fee: total %d change %d fee %d", cs.Total, cs.Change, cs.Fee)
+	}
+
+	_, err = selectCoins(testCandidates(types.MEERA, 1e8, 1e8), target, baseSize, feeRate, minChange, rnd)
+	if err == nil {
+		t.Fatal("the insufficient funds are selected")
+	}
+
+	// The inputs of token don't pay any fee
+	candidates = testCandidates(types.CoinID(256), 40, 25, 10)
+	cs, err = selectCoins(candidates, 35, 0, 0, 1, rnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cs.Total != 35 || cs.Fee != 0 || cs.Change != 0 {
+		t.Fatalf("unexpected token selection: total %d change %d fee %d", cs.Total, cs.Change, cs.Fee)
+	}
+	cs, err = selectCoins(candidates, 70, 0, 0, 1, rnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cs.Total != 75 || cs.Change != 5 || cs.Fee != 0 {
+		t.Fatalf("unexpected token change: total %d change %d fee %d", cs.Total, cs.Change, cs.Fee)
+	}
+}
@@ -0,0 +1,409 @@
+package wallet
+
+import (
+	"encoding/hex"
+	ejson "encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Qitmeer/qng/core/address"
+	"github.com/Qitmeer/qng/crypto/bip32"
+	"github.com/Qitmeer/qng/crypto/bip39"
+	"github.com/Qitmeer/qng/params"
+	qwallet "github.com/Qitmeer/qng/wallet"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+)
+
+const (
+	// hdWalletFile is the file of the encrypted seed and the state of HD
+	// wallet, it's in the data directory.
+	hdWalletFile = "hdwallet.json"
+
+	// DefaultGapLimit is the number of unused addresses in a row which stops
+	// the address discovery.
+	DefaultGapLimit = 20
+
+	// hdSeedEntropyBits is the entropy size of the new mnemonic.
+	hdSeedEntropyBits = 256
+)
+
+// The branches of BIP44 account.
+const (
+	hdExternalBranch = uint32(0)
+	hdInternalBranch = uint32(1)
+)
+
+var hdBranchNames = []string{"external", "internal"}
+
+// hdKeyPath is the branch and index of the address in the BIP44 account.
+type hdKeyPath struct {
+	Branch uint32
+	Index  uint32
+}
+
+// hdWalletState is the persisted state of HD wallet. The seed is encrypted,
+// the account extended public key is kept in plain so that the addresses
+// can be derived while the wallet is locked.
+type hdWalletState struct {
+	Crypto   keystore.CryptoJSON `json:"crypto"`
+	XPub     string              `json:"xpub"`
+	CoinType uint32              `json:"cointype"`
+	Account  uint32              `json:"account"`
+	GapLimit uint32              `json:"gaplimit"`
+	Next     [2]uint32           `json:"next"`
+}
+
+// hdWallet derives all the addresses of wallet from a single seed by
+// BIP32/BIP44, the path is m/44'/coin'/account'/branch/index.
+type hdWallet struct {
+	path      string
+	params    *params.Params
+	scryptN   int
+	scryptP   int
+	lock      sync.RWMutex
+	state     *hdWalletState
+	acctPub   *bip32.Key
+	acctPriv  *bip32.Key
+	lockTimer *time.Timer
+	addrs     map[string]hdKeyPath
+	derived   [2][]string
+	version   bip32.Bip32Version
+}
+
+func newHDWallet(dataDir string, p *params.Params, scryptN, scryptP int) *hdWallet {
+	return &hdWallet{
+		path:    filepath.Join(dataDir, hdWalletFile),
+		params:  p,
+		scryptN: scryptN,
+		scryptP: scryptP,
+		addrs:   map[string]hdKeyPath{},
+		version: bip32.Bip32Version{
+			PrivKeyVersion: p.HDPrivateKeyID[:],
+			PubKeyVersion:  p.HDPublicKeyID[:],
+		},
+	}
+}
+
+// accountKey derives the BIP44 account key from the seed.
+func (w *hdWallet) accountKey(seed []byte, coinType, account uint32) (*bip32.Key, error) {
+	key, err := bip32.NewMasterKey2(seed, w.version)
+	if err != nil {
+		return nil, err
+	}
+	for _, idx := range []uint32{44, coinType, account} {
+		key, err = key.NewChildKey(bip32.FirstHardenedChild + idx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// Exists returns true if the wallet is created.
+func (w *hdWallet) Exists() bool {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	return w.state != nil
+}
+
+// Load loads the wallet if it's created.
+func (w *hdWallet) Load() error {
+	data, err := os.ReadFile(w.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	state := &hdWalletState{}
+	err = ejson.Unmarshal(data, state)
+	if err != nil {
+		return err
+	}
+	acctPub, err := bip32.B58Deserialize(state.XPub, w.version)
+	if err != nil {
+		return err
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.state = state
+	w.acctPub = acctPub
+	return nil
+}
+
+// Create creates the wallet from the mnemonic, a new mnemonic is generated
+// if it's empty. It returns the mnemonic which must be backed up.
+func (w *hdWallet) Create(mnemonic string, passphrase string) (string, error) {
+	if len(passphrase) <= 0 {
+		return "", fmt.Errorf("The passphrase is required to encrypt the seed")
+	}
+	if len(mnemonic) <= 0 {
+		entropy, err := bip39.NewEntropy(hdSeedEntropyBits)
+		if err != nil {
+			return "", err
+		}
+		mnemonic, err = bip39.NewMnemonic(entropy)
+		if err != nil {
+			return "", err
+		}
+	}
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, "")
+	if err != nil {
+		return "", err
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.state != nil {
+		return "", fmt.Errorf("The HD wallet already exists: %s", w.path)
+	}
+	acctPriv, err := w.accountKey(seed, w.params.SLIP0044CoinType, 0)
+	if err != nil {
+		return "", err
+	}
+	cj, err := keystore.EncryptDataV3(seed, []byte(passphrase), w.scryptN, w.scryptP)
+	if err != nil {
+		return "", err
+	}
+	acctPub := acctPriv.PublicKey()
+	state := &hdWalletState{
+		Crypto:   cj,
+		XPub:     acctPub.B58Serialize(),
+		CoinType: w.params.SLIP0044CoinType,
+		GapLimit: DefaultGapLimit,
+	}
+	err = w.save(state)
+	if err != nil {
+		return "", err
+	}
+	w.state = state
+	w.acctPub = acctPub
+	return mnemonic, nil
+}
+
+// save writes the state to the file, it must be called with the lock held.
+func (w *hdWallet) save(state *hdWalletState) error {
+	data, err := ejson.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := w.path + ".tmp"
+	err = os.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, w.path)
+}
+
+// Unlock decrypts the seed, the wallet is locked again after the timeout if
+// it's positive.
+func (w *hdWallet) Unlock(passphrase string, timeout time.Duration) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.state == nil {
+		return fmt.Errorf("The HD wallet doesn't exist, please create one")
+	}
+	seed, err := keystore.DecryptDataV3(w.state.Crypto, passphrase)
+	if err != nil {
+		return err
+	}
+	acctPriv, err := w.accountKey(seed, w.state.CoinType, w.state.Account)
+	if err != nil {
+		return err
+	}
+	if acctPriv.PublicKey().B58Serialize() != w.state.XPub {
+		return fmt.Errorf("The seed doesn't match the account public key")
+	}
+	w.acctPriv = acctPriv
+	if w.lockTimer != nil {
+		w.lockTimer.Stop()
+		w.lockTimer = nil
+	}
+	if timeout > 0 {
+		w.lockTimer = time.AfterFunc(timeout, w.Lock)
+	}
+	return nil
+}
+
+// Lock removes the private key from memory.
+func (w *hdWallet) Lock() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.acctPriv = nil
+	if w.lockTimer != nil {
+		w.lockTimer.Stop()
+		w.lockTimer = nil
+	}
+}
+
+// XPub returns the extended public key of the account.
+func (w *hdWallet) XPub() string {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	if w.state == nil {
+		return ""
+	}
+	return w.state.XPub
+}
+
+func (w *hdWallet) IsLocked() bool {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	return w.acctPriv == nil
+}
+
+// derive returns the address of the path, it must be called with the lock
+// held.
+func (w *hdWallet) derive(branch, index uint32) (string, error) {
+	derived := w.derived[branch]
+	if int(index) < len(derived) {
+		return derived[index], nil
+	}
+	branchKey, err := w.acctPub.NewChildKey(branch)
+	if err != nil {
+		return "", err
+	}
+	for i := uint32(len(derived)); i <= index; i++ {
+		key, err := branchKey.NewChildKey(i)
+		if err != nil {
+			return "", err
+		}
+		pk, err := address.NewSecpPubKeyAddress(key.Key, w.params)
+		if err != nil {
+			return "", err
+		}
+		addr := pk.PKHAddress().String()
+		derived = append(derived, addr)
+		w.addrs[addr] = hdKeyPath{Branch: branch, Index: i}
+	}
+	w.derived[branch] = derived
+	return derived[index], nil
+}
+
+// Addresses returns the addresses of branch in [start, end).
+func (w *hdWallet) Addresses(branch, start, end uint32) ([]string, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.state == nil {
+		return nil, fmt.Errorf("The HD wallet doesn't exist, please create one")
+	}
+	result := []string{}
+	for i := start; i < end; i++ {
+		addr, err := w.derive(branch, i)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, addr)
+	}
+	return result, nil
+}
+
+// NewAddress returns the next unused address of branch.
+func (w *hdWallet) NewAddress(branch uint32) (string, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.state == nil {
+		return "", fmt.Errorf("The HD wallet doesn't exist, please create one")
+	}
+	addr, err := w.derive(branch, w.state.Next[branch])
+	if err != nil {
+		return "", err
+	}
+	w.state.Next[branch]++
+	return addr, w.save(w.state)
+}
+
+// SetUsed records that the address of index is used, the next address is
+// after it.
+func (w *hdWallet) SetUsed(branch, index uint32) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.state == nil || index < w.state.Next[branch] {
+		return nil
+	}
+	w.state.Next[branch] = index + 1
+	return w.save(w.state)
+}
+
+func (w *hdWallet) GapLimit() uint32 {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	if w.state == nil || w.state.GapLimit <= 0 {
+		return DefaultGapLimit
+	}
+	return w.state.GapLimit
+}
+
+// Next returns the next unused index of branch.
+func (w *hdWallet) Next(branch uint32) uint32 {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	if w.state == nil {
+		return 0
+	}
+	return w.state.Next[branch]
+}
+
+// KeyPath returns the path of the address if it's derived.
+func (w *hdWallet) KeyPath(addr string) (hdKeyPath, bool) {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	kp, ok := w.addrs[addr]
+	return kp, ok
+}
+
+// PathString returns the full derivation path of the key path.
+func (w *hdWallet) PathString(kp hdKeyPath) string {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	if w.state == nil {
+		return ""
+	}
+	return qwallet.DerivationPath{
+		bip32.FirstHardenedChild + 44,
+		bip32.FirstHardenedChild + w.state.CoinType,
+		bip32.FirstHardenedChild + w.state.Account,
+		kp.Branch, kp.Index,
+	}.String()
+}
+
+// PrivateKey returns the hex private key of the address.
+func (w *hdWallet) PrivateKey(addr string) (string, error) {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	kp, ok := w.addrs[addr]
+	if !ok {
+		return "", fmt.Errorf("%s isn't an address of HD wallet", addr)
+	}
+	if w.acctPriv == nil {
+		return "", fmt.Errorf("please unlock HD wallet first")
+	}
+	key, err := w.acctPriv.NewChildKey(kp.Branch)
+	if err != nil {
+		return "", err
+	}
+	key, err = key.NewChildKey(kp.Index)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key.Key), nil
+}
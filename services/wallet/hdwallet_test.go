@@ -0,0 +1,98 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/Qitmeer/qng/core/address"
+	"github.com/Qitmeer/qng/crypto/ecc/secp256k1"
+	"github.com/Qitmeer/qng/params"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+)
+
+const testMnemonic = "legal winner thank year wave sausage worth useful legal winner thank yellow"
+
+func TestHDWallet(t *testing.T) {
+	dir := t.TempDir()
+	p := &params.TestNetParams
+	w := newHDWallet(dir, p, keystore.LightScryptN, keystore.LightScryptP)
+	if err := w.Load(); err != nil || w.Exists() {
+		t.Fatal("the HD wallet exists before creation")
+	}
+	if _, err := w.Create(testMnemonic, ""); err == nil {
+		t.Fatal("the HD wallet is created without passphrase")
+	}
+	m, err := w.Create(testMnemonic, "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m != testMnemonic {
+		t.Fatalf("unexpected mnemonic %s", m)
+	}
+	if _, err := w.Create("", "pass"); err == nil {
+		t.Fatal("the HD wallet is created again")
+	}
+	first, err := w.NewAddress(hdExternalBranch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	change, err := w.NewAddress(hdInternalBranch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == change {
+		t.Fatal("the branches derive the same address")
+	}
+	if err := w.SetUsed(hdExternalBranch, 4); err != nil {
+		t.Fatal(err)
+	}
+	if w.PathString(hdKeyPath{Branch: hdInternalBranch, Index: 2}) != "m/44'/813'/0'/1/2" {
+		t.Fatalf("unexpected path %s", w.PathString(hdKeyPath{Branch: hdInternalBranch, Index: 2}))
+	}
+
+	// The addresses are derived from the public key after restart
+	restored := newHDWallet(dir, p, keystore.LightScryptN, keystore.LightScryptP)
+	if err := restored.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if !restored.IsLocked() || restored.Next(hdExternalBranch) != 5 || restored.Next(hdInternalBranch) != 1 {
+		t.Fatal("the state of HD wallet isn't restored")
+	}
+	addrs, err := restored.Addresses(hdExternalBranch, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addrs[0] != first || addrs[1] == first {
+		t.Fatalf("unexpected addresses %v", addrs)
+	}
+	if _, err := restored.PrivateKey(first); err == nil {
+		t.Fatal("the private key is returned by the locked wallet")
+	}
+	if err := restored.Unlock("wrong", 0); err == nil {
+		t.Fatal("the HD wallet is unlocked by wrong passphrase")
+	}
+	if err := restored.Unlock("pass", time.Millisecond*50); err != nil {
+		t.Fatal(err)
+	}
+	key, err := restored.PrivateKey(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kb, err := hex.DecodeString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, pub := secp256k1.PrivKeyFromBytes(kb)
+	pk, err := address.NewSecpPubKeyAddress(pub.SerializeCompressed(), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pk.PKHAddress().String() != first {
+		t.Fatal("the private key doesn't match the address")
+	}
+	time.Sleep(time.Millisecond * 200)
+	if !restored.IsLocked() {
+		t.Fatal("the HD wallet isn't locked after timeout")
+	}
+}
@@ -4,8 +4,11 @@ import (
 	"encoding/hex"
 	ejson "encoding/json"
 	"fmt"
+	"math/rand"
+	"sort"
 	"time"
 
+	"github.com/Qitmeer/qng/common/hash"
 	"github.com/Qitmeer/qng/core/address"
 	"github.com/Qitmeer/qng/core/event"
 	"github.com/Qitmeer/qng/core/json"
@@ -79,59 +82,241 @@ func (a *WalletManager) CollectUtxoToEvm() {
 	log.Debug("Wallet CollectUtxoToEvm Start")
 }
 
-func (a *WalletManager) getAvailableUtxos(addr string, amount int64) ([]acct.UTXOResult, int64, error) {
-	otxoList := make([]acct.UTXOResult, 0)
-	utxos, err := a.am.GetUTXOs(addr)
+// defaultFeeConfTarget is the number of blocks in which the transaction of
+// wallet is expected to be confirmed by the estimated fee rate.
+const defaultFeeConfTarget = 6
+
+// feeRate returns the fee rate in atoms/kB, it's estimated by the fee
+// estimator and no less than the minimum transaction fee.
+func (a *WalletManager) feeRate() int64 {
+	rate := a.cfg.MinTxFee
+	fe, ok := a.tm.FeeEstimator().(*mempool.FeeEstimator)
+	if !ok || fe == nil {
+		return rate
+	}
+	est, err := fe.EstimateFee(defaultFeeConfTarget)
 	if err != nil {
-		return nil, 0, err
+		log.Trace("Use the minimum fee rate", "reason", err)
+		return rate
+	}
+	estRate := int64(float64(est) * types.AtomsPerCoin)
+	if estRate > rate {
+		rate = estRate
 	}
-	sum := uint64(0)
-	for _, utxo := range utxos {
-		if utxo.Status != "unlocked" && utxo.Status != "valid" {
-			continue
+	return rate
+}
+
+// getCandidates returns the spendable outputs of the addresses. The outputs
+// which are spent in mempool are excluded.
+func (a *WalletManager) getCandidates(addrs []string) ([]*coinCandidate, error) {
+	if !a.cfg.AcctMode {
+		return nil, fmt.Errorf("The wallet requires the account mode (--acctmode) to find the unspent outputs")
+	}
+	chain := a.am.GetChain()
+	txPool, _ := a.tm.MemPool().(*mempool.TxPool)
+	result := []*coinCandidate{}
+	for _, addr := range addrs {
+		utxos, err := a.am.GetUTXOs(addr)
+		if err != nil {
+			return nil, err
 		}
-		sum += utxo.Amount
-		otxoList = append(otxoList, utxo)
-		if sum > uint64(amount) {
-			break
+		for _, u := range utxos {
+			if u.Status != "unlocked" && u.Status != "valid" {
+				continue
+			}
+			h, err := hash.NewHashFromStr(u.PreTxHash)
+			if err != nil {
+				return nil, err
+			}
+			op := types.NewOutPoint(h, u.PreOutIdx)
+			entry, err := chain.FetchUtxoEntry(*op)
+			if err != nil {
+				return nil, err
+			}
+			if entry == nil || entry.IsSpent() {
+				continue
+			}
+			if txPool != nil && txPool.CheckSpend(*op) != nil {
+				continue
+			}
+			result = append(result, &coinCandidate{
+				Address:  addr,
+				TxID:     u.PreTxHash,
+				OutIndex: u.PreOutIdx,
+				Amount:   entry.Amount(),
+			})
 		}
 	}
-	return otxoList, int64(sum), err
+	return result, nil
 }
 
-func (a *WalletManager) sendTx(fromAddress string, amounts json.AddressAmountV3, targetLockTime, lockTime int64) (string, error) {
-	amount := int64(0)
-	outputs := make([]qx.Output, 0)
+// privateKey returns the hex private key of the unlocked address.
+func (a *WalletManager) privateKey(addr string) (string, error) {
+	a.qks.mu.RLock()
+	u, ok := a.qks.unlocked[addr]
+	a.qks.mu.RUnlock()
+	if ok {
+		return hex.EncodeToString(u.PrivateKey.D.Bytes()), nil
+	}
+	if _, ok := a.hd.KeyPath(addr); ok {
+		return a.hd.PrivateKey(addr)
+	}
+	return "", fmt.Errorf("please unlock %s first", addr)
+}
+
+func outputType(addr types.Address) txscript.ScriptClass {
+	switch addr.(type) {
+	case *address.SecpPubKeyAddress:
+		return txscript.PubKeyTy
+	}
+	return txscript.PubKeyHashTy
+}
+
+// sendWithCoinSelection spends the candidates to pay the amounts. The inputs
+// of every coin are selected separately, the fee is paid by MEER and the
+// changes are sent to the change address.
+func (a *WalletManager) sendWithCoinSelection(candidates []*coinCandidate, amounts json.AddressAmountV3, changeAddr string, targetLockTime, lockTime int64) (string, error) {
+	outputs := make([]qx.Output, 0, len(amounts)+1)
+	targets := map[types.CoinID]int64{}
 	for addres, v := range amounts {
-		amount += v.Amount
-		typ := txscript.PubkeyHashAltTy
 		addr, err := address.DecodeAddress(addres)
 		if err != nil {
 			return "", err
 		}
-		switch addr.(type) {
-		case *address.SecpPubKeyAddress:
+		typ := txscript.PubkeyHashAltTy
+		if outputType(addr) == txscript.PubKeyTy {
 			typ = txscript.PubKeyTy
 		}
+		coin := types.CoinID(v.CoinId)
+		targets[coin] += v.Amount
 		outputs = append(outputs, qx.Output{
 			TargetAddress:  addres,
-			Amount:         types.Amount{Value: v.Amount, Id: types.CoinID(v.CoinId)},
+			Amount:         types.Amount{Value: v.Amount, Id: coin},
 			OutputType:     typ,
 			TargetLockTime: targetLockTime,
 		})
 	}
-	uxtoList, sum, err := a.getAvailableUtxos(fromAddress, amount)
+	change, err := address.DecodeAddress(changeAddr)
 	if err != nil {
 		return "", err
 	}
-	if len(uxtoList) < 1 {
-		return "", fmt.Errorf("%s balance not enough", fromAddress)
+	coins := map[types.CoinID][]*coinCandidate{}
+	for _, c := range candidates {
+		coins[c.Amount.Id] = append(coins[c.Amount.Id], c)
+	}
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	// The tokens are selected first, their inputs and changes are paid by
+	// the fee of MEER.
+	selected := []*coinCandidate{}
+	extraSize := 0
+	tokens := make([]types.CoinID, 0, len(targets))
+	for coin := range targets {
+		if coin != types.MEERA {
+			tokens = append(tokens, coin)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i] < tokens[j]
+	})
+	for _, coin := range tokens {
+		cs, err := selectCoins(coins[coin], targets[coin], 0, 0, 1, rnd)
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", coin.Name(), err)
+		}
+		selected = append(selected, cs.Inputs...)
+		extraSize += len(cs.Inputs) * estimateInputSize
+		if cs.Change > 0 {
+			outputs = append(outputs, qx.Output{
+				TargetAddress: changeAddr,
+				Amount:        types.Amount{Value: cs.Change, Id: coin},
+				OutputType:    outputType(change),
+			})
+			extraSize += estimateOutputSize
+		}
+	}
+	feeRate := a.feeRate()
+	minChange := dustLimit(a.cfg.MinTxFee)
+	baseSize := estimateTxOverhead + len(outputs)*estimateOutputSize + extraSize
+	cs, err := selectCoins(coins[types.MEERA], targets[types.MEERA], baseSize, feeRate, minChange, rnd)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", types.MEERA.Name(), err)
+	}
+	selected = append(selected, cs.Inputs...)
+	changeIdx := -1
+	if cs.Change > 0 {
+		changeIdx = len(outputs)
+		outputs = append(outputs, qx.Output{
+			TargetAddress: changeAddr,
+			Amount:        types.Amount{Value: cs.Change, Id: types.MEERA},
+			OutputType:    outputType(change),
+		})
 	}
-	if sum <= amount {
-		return "", fmt.Errorf("%s balance not enough , current:%d,need more than:%d", fromAddress, sum, amount)
+
+	inputs := make([]qx.Input, 0, len(selected))
+	priKeyList := make([]string, 0, len(selected))
+	for _, c := range selected {
+		addr, err := address.DecodeAddress(c.Address)
+		if err != nil {
+			return "", err
+		}
+		key, err := a.privateKey(c.Address)
+		if err != nil {
+			return "", err
+		}
+		inputs = append(inputs, qx.Input{
+			TxID:      c.TxID,
+			InputType: outputType(addr),
+			OutIndex:  c.OutIndex,
+		})
+		priKeyList = append(priKeyList, key)
 	}
+	timeNow := time.Now()
+	sign := func() ([]byte, error) {
+		raw, err := qx.TxEncode(1, uint32(lockTime), &timeNow, inputs, outputs)
+		if err != nil {
+			return nil, err
+		}
+		signedRaw, err := qx.TxSign(priKeyList, raw, params.ActiveNetParams.Params.Name)
+		if err != nil {
+			return nil, err
+		}
+		serializedTx, err := hex.DecodeString(signedRaw)
+		if err != nil {
+			return nil, rpc.RpcDecodeHexError(signedRaw)
+		}
+		return serializedTx, nil
+	}
+	serializedTx, err := sign()
+	if err != nil {
+		return "", err
+	}
+	// The estimated size may be less than the signed one
+	required := mempool.CalcFee(int64(len(serializedTx)), types.Amount{Value: feeRate, Id: types.MEERA})
+	if cs.Fee < required {
+		short := required - cs.Fee
+		if changeIdx < 0 || outputs[changeIdx].Amount.Value-short < minChange {
+			return "", fmt.Errorf("Insufficient funds for fee %d", required)
+		}
+		outputs[changeIdx].Amount.Value -= short
+		serializedTx, err = sign()
+		if err != nil {
+			return "", err
+		}
+	}
+	log.Debug("Wallet send transaction", "inputs", len(inputs), "outputs", len(outputs), "feerate", feeRate)
+	return a.tm.ProcessRawTx(serializedTx, false)
+}
 
-	return a.sendTxWithUtxos(fromAddress, amount, outputs, lockTime, uxtoList, sum)
+func (a *WalletManager) sendTx(fromAddress string, amounts json.AddressAmountV3, targetLockTime, lockTime int64) (string, error) {
+	candidates, err := a.getCandidates([]string{fromAddress})
+	if err != nil {
+		return "", err
+	}
+	if len(candidates) < 1 {
+		return "", fmt.Errorf("%s balance not enough", fromAddress)
+	}
+	return a.sendWithCoinSelection(candidates, amounts, fromAddress, targetLockTime, lockTime)
 }
 
 func (a *WalletManager) sendTxWithUtxos(fromAddress string, amount int64, outputs []qx.Output, lockTime int64, uxtoList []acct.UTXOResult, sum int64) (string, error) {
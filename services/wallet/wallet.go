@@ -6,13 +6,16 @@ import (
 	"strconv"
 
 	"github.com/Qitmeer/qng/config"
+	"github.com/Qitmeer/qng/core/address"
 	"github.com/Qitmeer/qng/core/event"
 	"github.com/Qitmeer/qng/log"
 	"github.com/Qitmeer/qng/meerevm/meer"
 	"github.com/Qitmeer/qng/node/service"
+	"github.com/Qitmeer/qng/params"
 	"github.com/Qitmeer/qng/rpc/api"
 	"github.com/Qitmeer/qng/rpc/client/cmds"
 	"github.com/Qitmeer/qng/services/acct"
+	"github.com/Qitmeer/qng/services/index"
 	"github.com/Qitmeer/qng/services/tx"
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
@@ -22,6 +25,7 @@ import (
 type WalletManager struct {
 	service.Service
 	qks       *QngKeyStore
+	hd        *hdWallet
 	am        *acct.AccountManager
 	tm        *tx.TxManager
 	cfg       *config.Config
@@ -75,6 +79,12 @@ func New(cfg *config.Config, evm *meer.MeerChain, _am *acct.AccountManager, _tm
 		events:    _events,
 	}
 	a.qks = NewQngKeyStore(ks)
+
+	dataDir := cfg.DataDir
+	if len(dataDir) <= 0 {
+		dataDir = cfg.HomeDir
+	}
+	a.hd = newHDWallet(dataDir, params.ActiveNetParams.Params, n, p)
 	return &a, nil
 }
 
@@ -150,12 +160,101 @@ func (a *WalletManager) Start() error {
 	if a.cfg.AutoCollectEvm {
 		go a.CollectUtxoToEvm()
 	}
+	err := a.hd.Load()
+	if err != nil {
+		return err
+	}
+	if a.hd.Exists() && a.cfg.AcctMode {
+		go func() {
+			err := a.discoverHDAddresses()
+			if err != nil {
+				log.Error("HD wallet address discovery", "error", err)
+			}
+		}()
+	}
 	if err := a.Service.Start(); err != nil {
 		return err
 	}
 	return nil
 }
 
+// discoverHDAddresses adds the addresses of HD wallet to the account manager
+// until there are gap limit unused addresses in a row in every branch. The
+// address is used if it has any transaction, so the spent ones count too.
+func (a *WalletManager) discoverHDAddresses() error {
+	if !a.cfg.AcctMode {
+		return fmt.Errorf("The address discovery requires the account mode (--acctmode)")
+	}
+	used, err := a.addressUsedFunc()
+	if err != nil {
+		return err
+	}
+	gap := a.hd.GapLimit()
+	for branch := hdExternalBranch; branch <= hdInternalBranch; branch++ {
+		start := uint32(0)
+		unused := uint32(0)
+		for unused < gap {
+			addrs, err := a.hd.Addresses(branch, start, start+gap)
+			if err != nil {
+				return err
+			}
+			err = a.am.AddAddresses(addrs)
+			if err != nil {
+				return err
+			}
+			for i, addr := range addrs {
+				ok, err := used(addr)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					unused++
+					continue
+				}
+				unused = 0
+				err = a.hd.SetUsed(branch, start+uint32(i))
+				if err != nil {
+					return err
+				}
+			}
+			start += gap
+		}
+		log.Info("HD wallet address discovery", "branch", hdBranchNames[branch], "next", a.hd.Next(branch))
+	}
+	return nil
+}
+
+// addressUsedFunc returns the function which tells whether the address has
+// any transaction by the address index or the explorer index.
+func (a *WalletManager) addressUsedFunc() (func(addr string) (bool, error), error) {
+	im, ok := a.am.GetChain().IndexManager().(*index.Manager)
+	if ok && im.AddrIndex() != nil {
+		ai := im.AddrIndex()
+		return func(addr string) (bool, error) {
+			ad, err := address.DecodeAddress(addr)
+			if err != nil {
+				return false, err
+			}
+			txs, _, err := ai.TxRegionsForAddress(ad, 0, 1, false)
+			if err != nil {
+				return false, err
+			}
+			return len(txs) > 0 || len(ai.UnconfirmedTxnsForAddress(ad)) > 0, nil
+		}, nil
+	}
+	if ok && im.ExplorerIndex() != nil {
+		ei := im.ExplorerIndex()
+		return func(addr string) (bool, error) {
+			balances, err := ei.AddressBalances(addr)
+			if err != nil {
+				return false, err
+			}
+			return len(balances) > 0, nil
+		}, nil
+	}
+	return nil, fmt.Errorf("The address discovery requires the address index (--addrindex) or the explorer index (--explorerindex)")
+}
+
 func (a *WalletManager) Stop() error {
 	log.Info("WalletManager stop")
 	if a.cfg.AutoCollectEvm {
@@ -2,7 +2,9 @@ package simulator
 
 import (
 	"encoding/json"
+	"github.com/Qitmeer/qng/common/hash"
 	"github.com/Qitmeer/qng/config"
+	"github.com/Qitmeer/qng/core/blockchain"
 	qjson "github.com/Qitmeer/qng/core/json"
 	"github.com/Qitmeer/qng/core/types/pow"
 	"testing"
@@ -62,3 +64,85 @@ func TestOverrideCfg(t *testing.T) {
 	}
 	defer node.Stop()
 }
+
+func TestInvalidateBlock(t *testing.T) {
+	miner, err := StartMockNode(func(cfg *config.Config) error {
+		cfg.HomeDir = t.TempDir()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer miner.Stop()
+	node, err := StartMockNode(func(cfg *config.Config) error {
+		cfg.HomeDir = t.TempDir()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node.Stop()
+
+	blocks := GenerateBlock(t, miner, 5)
+	if len(blocks) != 5 {
+		t.Fatalf("generate block number error: %d != 5", len(blocks))
+	}
+	mbc := miner.n.GetQitmeerFull().GetBlockChain()
+	bc := node.n.GetQitmeerFull().GetBlockChain()
+	processBlock := func(h *hash.Hash) {
+		block, err := mbc.FetchBlockByHash(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = bc.ProcessBlock(block, blockchain.BFP2PAdd, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, h := range blocks[:4] {
+		processBlock(h)
+	}
+	AssertBlockOrderAndHeight(t, node, 5, 5, 4)
+
+	// The block and its descendants lose their orders
+	err = bc.InvalidateBlock(blocks[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertBlockOrderAndHeight(t, node, 3, 5, 2)
+	if !bc.BestSnapshot().Hash.IsEqual(blocks[1]) {
+		t.Fatalf("The main chain tip is %s, expect %s", bc.BestSnapshot().Hash, blocks[1])
+	}
+	err = bc.InvalidateBlock(blocks[3])
+	if err == nil {
+		t.Fatal("The descendant of invalidated block can be invalidated again")
+	}
+
+	// The descendant arriving later is invalidated too
+	processBlock(blocks[4])
+	AssertBlockOrderAndHeight(t, node, 3, 6, 2)
+	ib := bc.BlockDAG().GetBlock(blocks[4])
+	if ib == nil || !ib.GetState().GetStatus().IsInvalidated() || ib.IsOrdered() {
+		t.Fatalf("The descendant %s of invalidated block isn't invalidated", blocks[4])
+	}
+	err = bc.ReconsiderBlock(blocks[3])
+	if err == nil {
+		t.Fatal("The block with invalidated parent can be reconsidered")
+	}
+
+	// All of them are ordered again
+	err = bc.ReconsiderBlock(blocks[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertBlockOrderAndHeight(t, node, 6, 6, 5)
+	if !bc.BestSnapshot().Hash.IsEqual(blocks[4]) {
+		t.Fatalf("The main chain tip is %s, expect %s", bc.BestSnapshot().Hash, blocks[4])
+	}
+	for i, h := range blocks {
+		ib := bc.BlockDAG().GetBlock(h)
+		if ib.GetState().GetStatus().IsInvalidated() || ib.GetOrder() != uint(i+1) {
+			t.Fatalf("The block %s is %s with order %d, expect valid with order %d", h, ib.GetState().GetStatus(), ib.GetOrder(), i+1)
+		}
+	}
+}